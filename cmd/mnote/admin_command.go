@@ -0,0 +1,495 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/xxxsen/mnote/internal/config"
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/repo"
+	"github.com/xxxsen/mnote/internal/service"
+)
+
+type adminCommandRuntime struct {
+	config  *config.Config
+	db      *sql.DB
+	repos   serverRepos
+	runtime service.Runtime
+	admin   *service.AdminService
+}
+
+var (
+	errAdminUserRequired    = errors.New("--user is required")
+	errAdminEmailRequired   = errors.New("--email is required")
+	errAdminOutputRequired  = errors.New("--output is required")
+	errAdminDeleteUnconfirm = errors.New("deleting a user removes all of its data; pass --yes to confirm")
+	errAdminImportFailed    = errors.New("import job failed")
+)
+
+const adminImportPollInterval = 500 * time.Millisecond
+
+func newAdminCommand() *cobra.Command {
+	var configPath string
+	command := &cobra.Command{
+		Use:   "admin",
+		Short: "manage users and data directly in the database",
+	}
+	command.PersistentFlags().StringVar(
+		&configPath,
+		"config",
+		"",
+		"path to config.json",
+	)
+	command.AddCommand(
+		newAdminUserCommand(&configPath),
+		newAdminDocCommand(&configPath),
+		newAdminStatsCommand(&configPath),
+		newAdminVacuumVersionsCommand(&configPath),
+		newAdminBackfillCommand(&configPath),
+	)
+	return command
+}
+
+func newAdminUserCommand(configPath *string) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "user",
+		Short: "list, create, disable, promote, reset or delete users",
+	}
+	command.AddCommand(
+		newAdminUserListCommand(configPath),
+		newAdminUserCreateCommand(configPath),
+		newAdminUserDisableCommand(configPath, "disable", true),
+		newAdminUserDisableCommand(configPath, "enable", false),
+		newAdminUserRoleCommand(configPath, "promote", model.UserRoleAdmin),
+		newAdminUserRoleCommand(configPath, "demote", model.UserRoleUser),
+		newAdminUserResetPasswordCommand(configPath),
+		newAdminUserDeleteCommand(configPath),
+	)
+	return command
+}
+
+func newAdminUserListCommand(configPath *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "list all users",
+		Args:  cobra.NoArgs,
+		RunE: func(command *cobra.Command, _ []string) error {
+			return withAdminRuntime(command, *configPath, func(runtime *adminCommandRuntime) error {
+				users, err := runtime.admin.ListUsers(command.Context())
+				if err != nil {
+					return err
+				}
+				return writeCommandJSON(command, users)
+			})
+		},
+	}
+}
+
+func newAdminUserCreateCommand(configPath *string) *cobra.Command {
+	var email string
+	var password string
+	command := &cobra.Command{
+		Use:   "create",
+		Short: "create a password account, generating the password when omitted",
+		Args:  cobra.NoArgs,
+		RunE: func(command *cobra.Command, _ []string) error {
+			if email == "" {
+				return errAdminEmailRequired
+			}
+			return withAdminRuntime(command, *configPath, func(runtime *adminCommandRuntime) error {
+				user, plain, err := runtime.admin.CreateUser(command.Context(), email, password)
+				if err != nil {
+					return fmt.Errorf("create user: %w", err)
+				}
+				return writeCommandJSON(command, map[string]any{"user": user, "password": plain})
+			})
+		},
+	}
+	command.Flags().StringVar(&email, "email", "", "email of the new user")
+	command.Flags().StringVar(&password, "password", "", "password of the new user")
+	return command
+}
+
+func newAdminUserDisableCommand(configPath *string, use string, disabled bool) *cobra.Command {
+	short := "allow a disabled user to sign in again"
+	if disabled {
+		short = "block a user from signing in and revoke its sessions"
+	}
+	return &cobra.Command{
+		Use:   use + " <user-id|email>",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(command *cobra.Command, args []string) error {
+			return withAdminRuntime(command, *configPath, func(runtime *adminCommandRuntime) error {
+				user, err := runtime.admin.SetDisabled(command.Context(), args[0], disabled)
+				if err != nil {
+					return fmt.Errorf("%s user: %w", use, err)
+				}
+				return writeCommandJSON(command, user)
+			})
+		},
+	}
+}
+
+func newAdminUserRoleCommand(configPath *string, use, role string) *cobra.Command {
+	short := "remove the admin role from a user"
+	if role == model.UserRoleAdmin {
+		short = "give a user the admin role"
+	}
+	return &cobra.Command{
+		Use:   use + " <user-id|email>",
+		Short: short,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(command *cobra.Command, args []string) error {
+			return withAdminRuntime(command, *configPath, func(runtime *adminCommandRuntime) error {
+				user, err := runtime.admin.SetRole(command.Context(), args[0], role)
+				if err != nil {
+					return fmt.Errorf("%s user: %w", use, err)
+				}
+				return writeCommandJSON(command, user)
+			})
+		},
+	}
+}
+
+func newAdminUserResetPasswordCommand(configPath *string) *cobra.Command {
+	var password string
+	command := &cobra.Command{
+		Use:   "reset-password <user-id|email>",
+		Short: "set a new password, generating it when omitted",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(command *cobra.Command, args []string) error {
+			return withAdminRuntime(command, *configPath, func(runtime *adminCommandRuntime) error {
+				user, plain, err := runtime.admin.ResetPassword(command.Context(), args[0], password)
+				if err != nil {
+					return fmt.Errorf("reset password: %w", err)
+				}
+				return writeCommandJSON(command, map[string]any{"user": user, "password": plain})
+			})
+		},
+	}
+	command.Flags().StringVar(&password, "password", "", "new password")
+	return command
+}
+
+func newAdminUserDeleteCommand(configPath *string) *cobra.Command {
+	var confirmed bool
+	command := &cobra.Command{
+		Use:   "delete <user-id|email>",
+		Short: "delete a user with all of its documents, assets and settings",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(command *cobra.Command, args []string) error {
+			if !confirmed {
+				return errAdminDeleteUnconfirm
+			}
+			return withAdminRuntime(command, *configPath, func(runtime *adminCommandRuntime) error {
+				result, err := runtime.admin.DeleteUser(command.Context(), args[0])
+				if err != nil {
+					return fmt.Errorf("delete user: %w", err)
+				}
+				return writeCommandJSON(command, result)
+			})
+		},
+	}
+	command.Flags().BoolVar(&confirmed, "yes", false, "confirm the deletion")
+	return command
+}
+
+func newAdminDocCommand(configPath *string) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "doc",
+		Short: "export or import the documents of a user",
+	}
+	command.AddCommand(
+		newAdminDocExportCommand(configPath),
+		newAdminDocImportCommand(configPath),
+	)
+	return command
+}
+
+func newAdminDocExportCommand(configPath *string) *cobra.Command {
+	var userRef string
+	var output string
+	command := &cobra.Command{
+		Use:   "export",
+		Short: "write the documents of a user to a notes zip",
+		Args:  cobra.NoArgs,
+		RunE: func(command *cobra.Command, _ []string) error {
+			if userRef == "" {
+				return errAdminUserRequired
+			}
+			if output == "" {
+				return errAdminOutputRequired
+			}
+			return withAdminRuntime(command, *configPath, func(runtime *adminCommandRuntime) error {
+				user, err := runtime.admin.ResolveUser(command.Context(), userRef)
+				if err != nil {
+					return fmt.Errorf("resolve user: %w", err)
+				}
+				exports := service.NewExportService(
+					runtime.repos.doc, runtime.repos.version, runtime.repos.tag, runtime.repos.docTag,
+					runtime.repos.folder,
+				)
+				path, err := exports.ExportNotesZip(command.Context(), user.ID)
+				if err != nil {
+					return fmt.Errorf("export notes: %w", err)
+				}
+				defer func() { _ = os.Remove(path) }()
+				size, err := copyCommandFile(path, output)
+				if err != nil {
+					return err
+				}
+				return writeCommandJSON(command, map[string]any{
+					"user_id": user.ID,
+					"output":  output,
+					"bytes":   size,
+				})
+			})
+		},
+	}
+	command.Flags().StringVar(&userRef, "user", "", "user id or email")
+	command.Flags().StringVarP(&output, "output", "o", "", "path of the zip to write")
+	return command
+}
+
+func newAdminDocImportCommand(configPath *string) *cobra.Command {
+	var userRef string
+	var mode string
+	command := &cobra.Command{
+		Use:   "import <file.zip>",
+		Short: "import a notes zip into the account of a user",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(command *cobra.Command, args []string) error {
+			if userRef == "" {
+				return errAdminUserRequired
+			}
+			return withAdminRuntime(command, *configPath, func(runtime *adminCommandRuntime) error {
+				user, err := runtime.admin.ResolveUser(command.Context(), userRef)
+				if err != nil {
+					return fmt.Errorf("resolve user: %w", err)
+				}
+				job, err := runAdminImport(command.Context(), runtime, user.ID, args[0], mode)
+				if err != nil {
+					return err
+				}
+				if err := writeCommandJSON(command, map[string]any{
+					"job_id":    job.ID,
+					"user_id":   user.ID,
+					"status":    job.Status,
+					"total":     job.Total,
+					"processed": job.Processed,
+					"report":    job.Report,
+				}); err != nil {
+					return err
+				}
+				if job.Status == model.ImportStatusFailed {
+					return fmt.Errorf("%w: %s", errAdminImportFailed, job.LastError)
+				}
+				return nil
+			})
+		},
+	}
+	command.Flags().StringVar(&userRef, "user", "", "user id or email")
+	command.Flags().StringVar(&mode, "mode", string(model.ImportModeAppend),
+		"how to handle titles that already exist: append, skip or overwrite")
+	return command
+}
+
+// runAdminImport stages the zip as an import job and runs an import worker in
+// this process for that job alone until it finishes. A running server may pick
+// the job up as well; either way the command waits for the final status.
+func runAdminImport(
+	ctx context.Context, runtime *adminCommandRuntime, userID, path, mode string,
+) (*model.ImportJob, error) {
+	imports := newCommandImportService(runtime)
+	job, err := imports.CreateNotesJob(ctx, userID, path)
+	if err != nil {
+		return nil, fmt.Errorf("create import job: %w", err)
+	}
+	if err := imports.Confirm(ctx, userID, job.ID, mode); err != nil {
+		return nil, fmt.Errorf("confirm import job: %w", err)
+	}
+	workerCtx, cancel := context.WithCancel(ctx)
+	worker := service.NewImportWorker(imports, runtime.repos.importJob, runtime.repos.importJobNote)
+	stopped := make(chan error, 1)
+	go func() { stopped <- worker.RunJob(workerCtx, job.ID) }()
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+	ticker := time.NewTicker(adminImportPollInterval)
+	defer ticker.Stop()
+	for {
+		current, err := imports.Status(ctx, userID, job.ID)
+		if err != nil {
+			return nil, fmt.Errorf("read import job: %w", err)
+		}
+		if current.Status == model.ImportStatusDone || current.Status == model.ImportStatusFailed {
+			return current, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("wait for import job: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func newAdminStatsCommand(configPath *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "stats",
+		Short: "show documents, versions, assets and bytes per user",
+		Args:  cobra.NoArgs,
+		RunE: func(command *cobra.Command, _ []string) error {
+			return withAdminRuntime(command, *configPath, func(runtime *adminCommandRuntime) error {
+				stats, err := runtime.admin.Stats(command.Context())
+				if err != nil {
+					return err
+				}
+				return writeCommandJSON(command, stats)
+			})
+		},
+	}
+}
+
+func newAdminVacuumVersionsCommand(configPath *string) *cobra.Command {
+	var keep int
+	var userRef string
+	command := &cobra.Command{
+		Use:   "vacuum-versions",
+		Short: "prune document history down to the newest versions",
+		Args:  cobra.NoArgs,
+		RunE: func(command *cobra.Command, _ []string) error {
+			return withAdminRuntime(command, *configPath, func(runtime *adminCommandRuntime) error {
+				deleted, err := runtime.admin.VacuumVersions(command.Context(), keep, userRef)
+				if err != nil {
+					return fmt.Errorf("vacuum versions: %w", err)
+				}
+				return writeCommandJSON(command, map[string]any{"deleted": deleted})
+			})
+		},
+	}
+	command.Flags().IntVar(&keep, "keep", 0, "versions to keep per document (default: version_max_keep)")
+	command.Flags().StringVar(&userRef, "user", "", "only prune the documents of this user id or email")
+	return command
+}
+
+func newAdminBackfillCommand(configPath *string) *cobra.Command {
+	command := &cobra.Command{
+		Use:   "backfill",
+		Short: "recompute data derived from document content for existing documents",
+	}
+	command.AddCommand(&cobra.Command{
+		Use:   "stats",
+		Short: "recompute the reading statistics of every document",
+		Args:  cobra.NoArgs,
+		RunE: func(command *cobra.Command, _ []string) error {
+			return withAdminRuntime(command, *configPath, func(runtime *adminCommandRuntime) error {
+				backfill := service.NewDocumentBackfillService(runtime.repos.doc, nil)
+				updated, err := backfill.BackfillStats(command.Context())
+				if err != nil {
+					return fmt.Errorf("backfill stats: %w", err)
+				}
+				return writeCommandJSON(command, map[string]any{"updated": updated})
+			})
+		},
+	}, &cobra.Command{
+		Use:   "properties",
+		Short: "record the front matter properties of documents that have none",
+		Args:  cobra.NoArgs,
+		RunE: func(command *cobra.Command, _ []string) error {
+			return withAdminRuntime(command, *configPath, func(runtime *adminCommandRuntime) error {
+				backfill := service.NewDocumentBackfillService(runtime.repos.doc, newCommandDocumentService(runtime))
+				updated, err := backfill.BackfillProperties(command.Context())
+				if err != nil {
+					return fmt.Errorf("backfill properties: %w", err)
+				}
+				return writeCommandJSON(command, map[string]any{"updated": updated})
+			})
+		},
+	})
+	return command
+}
+
+func withAdminRuntime(
+	command *cobra.Command, configPath string, fn func(runtime *adminCommandRuntime) error,
+) error {
+	runtime, err := openAdminCommandRuntime(command.Context(), configPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = runtime.db.Close() }()
+	return fn(runtime)
+}
+
+func openAdminCommandRuntime(ctx context.Context, configPath string) (*adminCommandRuntime, error) {
+	cfg, database, err := openCommandDatabase(ctx, configPath)
+	if err != nil {
+		return nil, err
+	}
+	store, err := newCommandFileStore(cfg)
+	if err != nil {
+		_ = database.Close()
+		return nil, err
+	}
+	repos := newServerRepos(database)
+	runtime := newServiceRuntime(cfg, database)
+	return &adminCommandRuntime{
+		config:  cfg,
+		db:      database,
+		repos:   repos,
+		runtime: runtime,
+		admin: service.NewAdminService(
+			repos.user, repo.NewAdminRepo(database), store, cfg.VersionMaxKeep, runtime,
+		),
+	}, nil
+}
+
+// newCommandDocumentService wires the document service without the embedding
+// providers; the server indexes the documents written by a command once it runs.
+func newCommandDocumentService(runtime *adminCommandRuntime) *service.DocumentService {
+	r := runtime.repos
+	assets := service.NewAssetService(r.asset, r.documentAsset, runtime.runtime)
+	return service.NewDocumentService(
+		runtime.runtime, r.doc, r.version, r.docTag, r.share,
+		r.tag, r.user, nil, runtime.config.VersionMaxKeep, assets,
+	)
+}
+
+// newCommandImportService wires the import service on top of
+// newCommandDocumentService.
+func newCommandImportService(runtime *adminCommandRuntime) *service.ImportService {
+	r := runtime.repos
+	documents := newCommandDocumentService(runtime)
+	tags := service.NewTagService(runtime.runtime, r.tag, r.docTag, r.template)
+	folders := service.NewFolderService(runtime.runtime, r.folder, r.doc)
+	return service.NewImportService(documents, tags, folders, r.importJob, r.importJobNote, runtime.runtime)
+}
+
+func copyCommandFile(src, dst string) (int64, error) {
+	in, err := os.Open(src)
+	if err != nil {
+		return 0, fmt.Errorf("open %s: %w", src, err)
+	}
+	defer func() { _ = in.Close() }()
+	out, err := os.Create(dst)
+	if err != nil {
+		return 0, fmt.Errorf("create %s: %w", dst, err)
+	}
+	size, err := io.Copy(out, in)
+	if err != nil {
+		_ = out.Close()
+		return 0, fmt.Errorf("write %s: %w", dst, err)
+	}
+	if err := out.Close(); err != nil {
+		return 0, fmt.Errorf("close %s: %w", dst, err)
+	}
+	return size, nil
+}
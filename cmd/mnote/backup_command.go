@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/xxxsen/mnote/internal/backup"
+	"github.com/xxxsen/mnote/internal/config"
+	"github.com/xxxsen/mnote/internal/filestore"
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/repo"
+)
+
+var errBackupOutputRequired = errors.New("--output is required")
+
+func newBackupCommand() *cobra.Command {
+	var configPath string
+	command := &cobra.Command{
+		Use:   "backup",
+		Short: "create, verify or restore a full instance backup",
+	}
+	command.PersistentFlags().StringVar(
+		&configPath,
+		"config",
+		"",
+		"path to config.json",
+	)
+	command.AddCommand(
+		newBackupCreateCommand(&configPath),
+		newBackupVerifyCommand(),
+		newBackupRestoreCommand(&configPath),
+	)
+	return command
+}
+
+func newBackupCreateCommand(configPath *string) *cobra.Command {
+	var output string
+	command := &cobra.Command{
+		Use:   "create",
+		Short: "write all users' data and stored files to one archive",
+		Args:  cobra.NoArgs,
+		RunE: func(command *cobra.Command, _ []string) error {
+			if output == "" {
+				return errBackupOutputRequired
+			}
+			return withBackupRuntime(command, *configPath,
+				func(database *sql.DB, store filestore.Store) error {
+					manifest, err := createBackupFile(command.Context(), database, store, output)
+					if err != nil {
+						return err
+					}
+					return writeCommandJSON(command, backupSummary(output, manifest))
+				})
+		},
+	}
+	command.Flags().StringVarP(&output, "output", "o", "", "path of the archive to write")
+	return command
+}
+
+func newBackupVerifyCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify <archive>",
+		Short: "check the checksums of an archive without restoring it",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(command *cobra.Command, args []string) error {
+			manifest, err := backup.Verify(args[0])
+			if err != nil {
+				return fmt.Errorf("verify backup: %w", err)
+			}
+			return writeCommandJSON(command, backupSummary(args[0], manifest))
+		},
+	}
+}
+
+func newBackupRestoreCommand(configPath *string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore <archive>",
+		Short: "restore an archive into an empty database and file store",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(command *cobra.Command, args []string) error {
+			return withBackupRuntime(command, *configPath,
+				func(database *sql.DB, store filestore.Store) error {
+					result, err := backup.Restore(command.Context(), repo.NewBackupRepo(database), store, args[0])
+					if err != nil {
+						return fmt.Errorf("restore backup: %w", err)
+					}
+					summary := backupSummary(args[0], result.Manifest)
+					summary["restored_rows"] = result.Rows
+					summary["restored_files"] = result.Files
+					return writeCommandJSON(command, summary)
+				})
+		},
+	}
+}
+
+func withBackupRuntime(
+	command *cobra.Command, configPath string, fn func(database *sql.DB, store filestore.Store) error,
+) error {
+	cfg, database, err := openCommandDatabase(command.Context(), configPath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = database.Close() }()
+	store, err := newCommandFileStore(cfg)
+	if err != nil {
+		return err
+	}
+	return fn(database, store)
+}
+
+func newCommandFileStore(cfg *config.Config) (filestore.Store, error) {
+	store, err := filestore.New(filestore.Config{
+		Type: cfg.FileStore.Type,
+		Data: cfg.FileStore.Data,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("init file store: %w", err)
+	}
+	return store, nil
+}
+
+// createBackupFile writes the archive next to output first and renames it
+// once complete, so an interrupted run never leaves a truncated archive at
+// output.
+func createBackupFile(
+	ctx context.Context, database *sql.DB, store filestore.Store, output string,
+) (*model.BackupManifest, error) {
+	tmp, err := os.CreateTemp(filepath.Dir(output), ".mnote-backup-*")
+	if err != nil {
+		return nil, fmt.Errorf("create temp archive: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+	manifest, err := backup.Create(ctx, repo.NewBackupRepo(database), store, tmp, time.Now())
+	if err != nil {
+		_ = tmp.Close()
+		return nil, fmt.Errorf("create backup: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, fmt.Errorf("close archive: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), output); err != nil {
+		return nil, fmt.Errorf("move archive to %s: %w", output, err)
+	}
+	return manifest, nil
+}
+
+func backupSummary(path string, manifest *model.BackupManifest) map[string]any {
+	tables := make(map[string]int64, len(manifest.Tables))
+	for _, table := range manifest.Tables {
+		tables[table.Name] = table.Rows
+	}
+	return map[string]any{
+		"archive":        path,
+		"created_at":     manifest.CreatedAt,
+		"schema_version": manifest.SchemaVersion,
+		"tables":         tables,
+		"files":          len(manifest.Files),
+		"missing_files":  manifest.MissingFiles,
+	}
+}
@@ -25,7 +25,7 @@ type embeddingCommandRuntime struct {
 }
 
 var (
-	errEmbeddingConfigRequired      = errors.New("--config is required")
+	errCommandConfigRequired        = errors.New("--config is required")
 	errEmbeddingProfileRequired     = errors.New("--profile is required")
 	errEmbeddingGenerationRequired  = errors.New("--generation is required")
 	errEmbeddingReasonInvalid       = errors.New("--reason must be initial, model_change, rechunk, or manual_repair")
@@ -502,12 +502,27 @@ func openEmbeddingCommandRuntime(
 	ctx context.Context,
 	configPath string,
 ) (*embeddingCommandRuntime, error) {
+	cfg, database, err := openCommandDatabase(ctx, configPath)
+	if err != nil {
+		return nil, err
+	}
+	return &embeddingCommandRuntime{
+		config: cfg,
+		db:     database,
+		repo:   repo.NewEmbeddingV2Repo(database),
+		cache:  repo.NewEmbeddingCacheV2Repo(database),
+	}, nil
+}
+
+// openCommandDatabase loads the config and opens the migrated database for
+// the offline subcommands.
+func openCommandDatabase(ctx context.Context, configPath string) (*config.Config, *sql.DB, error) {
 	if configPath == "" {
-		return nil, errEmbeddingConfigRequired
+		return nil, nil, errCommandConfigRequired
 	}
 	cfg, err := loadValidatedConfig(configPath)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	database, err := db.Open(ctx, db.Config{
 		DSN:             cfg.Database.DSN,
@@ -523,18 +538,13 @@ func openEmbeddingCommandRuntime(
 		ConnMaxIdleTime: time.Duration(cfg.Database.ConnMaxIdleTimeSeconds) * time.Second,
 	})
 	if err != nil {
-		return nil, fmt.Errorf("open db: %w", err)
+		return nil, nil, fmt.Errorf("open db: %w", err)
 	}
 	if err := db.ApplyMigrationsContext(ctx, database); err != nil {
 		_ = database.Close()
-		return nil, fmt.Errorf("migrations: %w", err)
+		return nil, nil, fmt.Errorf("migrations: %w", err)
 	}
-	return &embeddingCommandRuntime{
-		config: cfg,
-		db:     database,
-		repo:   repo.NewEmbeddingV2Repo(database),
-		cache:  repo.NewEmbeddingCacheV2Repo(database),
-	}, nil
+	return cfg, database, nil
 }
 
 func writeCommandJSON(command *cobra.Command, value any) error {
@@ -27,6 +27,7 @@ import (
 	"github.com/xxxsen/mnote/internal/db"
 	"github.com/xxxsen/mnote/internal/embedcache"
 	"github.com/xxxsen/mnote/internal/filestore"
+	"github.com/xxxsen/mnote/internal/gallery"
 	"github.com/xxxsen/mnote/internal/handler"
 	"github.com/xxxsen/mnote/internal/job"
 	"github.com/xxxsen/mnote/internal/metrics"
@@ -114,6 +115,8 @@ func main() {
 	rootCmd.AddCommand(runCmd)
 	rootCmd.AddCommand(configCmd)
 	rootCmd.AddCommand(newEmbeddingCommand())
+	rootCmd.AddCommand(newAdminCommand())
+	rootCmd.AddCommand(newBackupCommand())
 
 	if err := rootCmd.Execute(); err != nil {
 		_, _ = fmt.Fprintln(os.Stderr, "startup error:", err)
@@ -197,9 +200,38 @@ func initOAuthProviders(cfg *config.Config) (map[string]oauth.Provider, error) {
 		}
 		providers[e.name] = p
 	}
+	for _, item := range cfg.OAuth.OIDC {
+		p, err := oauth.NewProvider("oidc", oauth.ProviderArgs{
+			Name: item.Name,
+			Config: oauth.ProviderConfig{
+				ClientID: item.ClientID, ClientSecret: item.ClientSecret,
+				RedirectURL: item.RedirectURL, Scopes: item.Scopes,
+			},
+			OIDC: oauth.OIDCConfig{
+				Issuer:               item.Issuer,
+				SubjectClaim:         item.Claims.Subject,
+				EmailClaim:           item.Claims.Email,
+				EmailVerifiedClaim:   item.Claims.EmailVerified,
+				AllowUnverifiedEmail: item.AllowUnverifiedEmail,
+			},
+			Client: client,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("init %s oidc: %w", item.Name, err)
+		}
+		providers[item.Name] = p
+	}
 	return providers, nil
 }
 
+func oidcProperties(items []config.OIDCProviderConfig) []handler.OIDCProviderProperty {
+	out := make([]handler.OIDCProviderProperty, 0, len(items))
+	for _, item := range items {
+		out = append(out, handler.OIDCProviderProperty{Name: item.Name, DisplayName: item.DisplayName})
+	}
+	return out
+}
+
 func initEmbeddingProviders(
 	ctx context.Context,
 	cfg *config.Config,
@@ -492,8 +524,10 @@ type serverRepos struct {
 	version          *repo.VersionRepo
 	oauth            *repo.OAuthRepo
 	emailCode        *repo.EmailVerificationRepo
+	invite           *repo.InviteRepo
 	tag              *repo.TagRepo
 	docTag           *repo.DocumentTagRepo
+	folder           *repo.FolderRepo
 	share            *repo.ShareRepo
 	embedding        *repo.EmbeddingRepo
 	embeddingCache   *repo.EmbeddingCacheRepo
@@ -502,9 +536,13 @@ type serverRepos struct {
 	importJob        *repo.ImportJobRepo
 	importJobNote    *repo.ImportJobNoteRepo
 	template         *repo.TemplateRepo
+	templateGallery  *repo.TemplateGalleryRepo
 	asset            *repo.AssetRepo
 	documentAsset    *repo.DocumentAssetRepo
 	todo             *repo.TodoRepo
+	templateSchedule *repo.TemplateScheduleRepo
+	admin            *repo.AdminRepo
+	workspace        *repo.WorkspaceRepo
 }
 
 func newServerRepos(db *sql.DB) serverRepos {
@@ -514,8 +552,10 @@ func newServerRepos(db *sql.DB) serverRepos {
 		version:          repo.NewVersionRepo(db),
 		oauth:            repo.NewOAuthRepo(db),
 		emailCode:        repo.NewEmailVerificationRepo(db),
+		invite:           repo.NewInviteRepo(db),
 		tag:              repo.NewTagRepo(db),
 		docTag:           repo.NewDocumentTagRepo(db),
+		folder:           repo.NewFolderRepo(db),
 		share:            repo.NewShareRepo(db),
 		embedding:        repo.NewEmbeddingRepo(db),
 		embeddingCache:   repo.NewEmbeddingCacheRepo(db),
@@ -524,12 +564,20 @@ func newServerRepos(db *sql.DB) serverRepos {
 		importJob:        repo.NewImportJobRepo(db),
 		importJobNote:    repo.NewImportJobNoteRepo(db),
 		template:         repo.NewTemplateRepo(db),
+		templateGallery:  repo.NewTemplateGalleryRepo(db),
 		asset:            repo.NewAssetRepo(db),
 		documentAsset:    repo.NewDocumentAssetRepo(db),
 		todo:             repo.NewTodoRepo(db),
+		templateSchedule: repo.NewTemplateScheduleRepo(db),
+		admin:            repo.NewAdminRepo(db),
+		workspace:        repo.NewWorkspaceRepo(db),
 	}
 }
 
+// collabSaveInterval is how often live editing sessions write their content
+// back through the regular document save.
+const collabSaveInterval = 5 * time.Second
+
 func runServer(cfg *config.Config, db *sql.DB) error {
 	logutil.GetLogger(context.Background()).Info(
 		"starting server",
@@ -550,9 +598,11 @@ func runServer(cfg *config.Config, db *sql.DB) error {
 	if err != nil {
 		return err
 	}
+	if err := seedTemplateGallery(context.Background(), services.templates); err != nil {
+		return err
+	}
 	deps, store, err := buildRouterDeps(
-		cfg, services.auth, services.oauth, services.documents, services.tags,
-		services.assets, services.imports, r, services.runtime,
+		cfg, services, r,
 	)
 	if err != nil {
 		return err
@@ -579,6 +629,7 @@ func runServer(cfg *config.Config, db *sql.DB) error {
 	workers := []mnoteapp.Worker{
 		service.NewImportWorker(services.imports, r.importJob, r.importJobNote),
 		service.NewAssetCleanupWorker(r.asset, store, services.runtime),
+		services.collab,
 	}
 	if services.embeddingV2Worker != nil {
 		workers = append(workers, services.embeddingV2Worker)
@@ -587,12 +638,29 @@ func runServer(cfg *config.Config, db *sql.DB) error {
 		workers = append(workers, services.embeddingV2BootstrapWorker)
 	}
 	return startServer(
-		cfg, db, engine, services.embedding,
+		cfg, db, engine, services.embedding, services.templateSchedules,
 		workers,
 		r,
 	)
 }
 
+// seedTemplateGallery stores the built-in templates embedded in the binary,
+// refreshing those whose version was bumped.
+func seedTemplateGallery(ctx context.Context, templates *service.TemplateService) error {
+	items, err := gallery.Load()
+	if err != nil {
+		return fmt.Errorf("load template gallery: %w", err)
+	}
+	written, err := templates.SeedGallery(ctx, items)
+	if err != nil {
+		return fmt.Errorf("seed template gallery: %w", err)
+	}
+	logutil.GetLogger(ctx).Info(
+		"template gallery seeded", zap.Int("templates", len(items)), zap.Int("updated", written),
+	)
+	return nil
+}
+
 func responseCompression() gin.HandlerFunc {
 	return gzip.Gzip(
 		gzip.DefaultCompression,
@@ -603,13 +671,19 @@ func responseCompression() gin.HandlerFunc {
 type serverServices struct {
 	auth                       *service.AuthService
 	oauth                      *service.OAuthService
+	registration               *service.RegistrationService
 	embedding                  *service.EmbeddingService
 	embeddingV2Worker          *service.EmbeddingV2Worker
 	embeddingV2BootstrapWorker *service.EmbeddingV2BootstrapWorker
 	documents                  *service.DocumentService
 	tags                       *service.TagService
+	folders                    *service.FolderService
 	assets                     *service.AssetService
 	imports                    *service.ImportService
+	templates                  *service.TemplateService
+	templateSchedules          *service.TemplateScheduleService
+	collab                     *service.CollabHub
+	workspaces                 *service.WorkspaceService
 	runtime                    service.Runtime
 }
 
@@ -628,43 +702,63 @@ func buildServerServices(
 	if err != nil {
 		return serverServices{}, err
 	}
-	runtime := service.NewRuntime(repo.NewTransactor(database))
-	runtime.Limits = service.Limits{
-		MaxDocumentBytes: int(cfg.MaxDocumentSize),
-		MaxTemplateBytes: int(cfg.MaxTemplateSize),
-		MaxJSONBodyBytes: cfg.MaxJSONBodySize,
-	}
+	runtime := newServiceRuntime(cfg, database)
 	verify := service.NewEmailVerificationService(
 		repos.emailCode, newMailSender(cfg.Mail), runtime,
 	)
+	registration := service.NewRegistrationService(service.RegistrationPolicy{
+		Mode:           cfg.Registration.Mode,
+		AllowedDomains: cfg.Registration.AllowedDomains,
+	}, repos.invite, runtime)
 	auth := service.NewAuthService(
 		repos.user, verify, []byte(cfg.JWTSecret),
 		time.Hour*time.Duration(cfg.JWTTTLHours),
 		cfg.Properties.EnableUserRegister && cfg.Properties.EnableEmailRegister,
-		runtime,
+		registration, runtime,
 	)
 	oauthService := service.NewOAuthService(
 		repos.user, repos.oauth, []byte(cfg.JWTSecret),
-		time.Hour*time.Duration(cfg.JWTTTLHours), oauthProviders, runtime,
+		time.Hour*time.Duration(cfg.JWTTTLHours), oauthProviders, registration, runtime,
 	)
 	assets := service.NewAssetService(repos.asset, repos.documentAsset, runtime)
 	documents := service.NewDocumentService(
 		runtime, repos.doc, repos.version, repos.docTag, repos.share,
 		repos.tag, repos.user, embeddingService, cfg.VersionMaxKeep, assets,
 	)
-	tags := service.NewTagService(runtime, repos.tag, repos.docTag)
+	tags := service.NewTagService(runtime, repos.tag, repos.docTag, repos.template)
+	folders := service.NewFolderService(runtime, repos.folder, repos.doc)
+	workspaces := service.NewWorkspaceService(repos.workspace, repos.user, runtime)
+	templates := service.NewTemplateService(
+		repos.template, documents, repos.tag, repos.user, repos.templateGallery, runtime,
+	)
 	return serverServices{
-		auth: auth, oauth: oauthService, embedding: embeddingService,
+		auth: auth, oauth: oauthService, registration: registration, embedding: embeddingService,
 		embeddingV2Worker:          embeddingV2Setup.worker,
 		embeddingV2BootstrapWorker: embeddingV2Setup.bootstrapWorker,
-		documents:                  documents, tags: tags, assets: assets,
+		documents:                  documents, tags: tags, folders: folders, assets: assets,
 		imports: service.NewImportService(
-			documents, tags, repos.importJob, repos.importJobNote, runtime,
+			documents, tags, folders, repos.importJob, repos.importJobNote, runtime,
 		),
-		runtime: runtime,
+		templates: templates,
+		templateSchedules: service.NewTemplateScheduleService(
+			repos.templateSchedule, templates, repos.tag, runtime,
+		),
+		collab:     service.NewCollabHub(documents, workspaces, collabSaveInterval, runtime),
+		workspaces: workspaces,
+		runtime:    runtime,
 	}, nil
 }
 
+func newServiceRuntime(cfg *config.Config, database *sql.DB) service.Runtime {
+	runtime := service.NewRuntime(repo.NewTransactor(database))
+	runtime.Limits = service.Limits{
+		MaxDocumentBytes: int(cfg.MaxDocumentSize),
+		MaxTemplateBytes: int(cfg.MaxTemplateSize),
+		MaxJSONBodyBytes: cfg.MaxJSONBodySize,
+	}
+	return runtime
+}
+
 func buildEmbeddingServices(
 	ctx context.Context,
 	cfg *config.Config,
@@ -919,11 +1013,9 @@ func newMailSender(mail config.MailConfig) service.EmailSender {
 }
 
 func buildRouterDeps(
-	cfg *config.Config,
-	authSvc *service.AuthService, oauthSvc *service.OAuthService,
-	docSvc *service.DocumentService, tagSvc *service.TagService, assetSvc *service.AssetService,
-	importSvc *service.ImportService, r serverRepos, runtime service.Runtime,
+	cfg *config.Config, services serverServices, r serverRepos,
 ) (handler.RouterDeps, filestore.Store, error) {
+	docSvc := services.documents
 	store, err := filestore.New(filestore.Config{
 		Type: cfg.FileStore.Type,
 		Data: cfg.FileStore.Data,
@@ -931,11 +1023,12 @@ func buildRouterDeps(
 	if err != nil {
 		return handler.RouterDeps{}, nil, fmt.Errorf("init file store: %w", err)
 	}
-	fileHandler := handler.NewFileHandler(store, cfg.MaxUploadSize, assetSvc)
+	fileHandler := handler.NewFileHandler(store, cfg.MaxUploadSize, services.assets)
+	exportSvc := service.NewExportService(r.doc, r.version, r.tag, r.docTag, r.folder)
 
 	return handler.RouterDeps{
-		Auth:  handler.NewAuthHandler(authSvc),
-		OAuth: handler.NewOAuthHandler(oauthSvc),
+		Auth:  handler.NewAuthHandler(services.auth),
+		OAuth: handler.NewOAuthHandler(services.oauth),
 		Properties: handler.NewPropertiesHandler(
 			handler.Properties{
 				EnableGithubOauth:   cfg.Properties.EnableGithubOauth,
@@ -943,6 +1036,8 @@ func buildRouterDeps(
 				EnableUserRegister:  cfg.Properties.EnableUserRegister,
 				EnableEmailRegister: cfg.Properties.EnableEmailRegister,
 				EnableTestMode:      cfg.Properties.EnableTestMode,
+				OIDCProviders:       oidcProperties(cfg.OAuth.OIDC),
+				RegistrationMode:    services.registration.Mode(),
 			},
 			handler.BannerConfig{
 				Enable:   cfg.Banner.Enable,
@@ -954,18 +1049,25 @@ func buildRouterDeps(
 		Documents: handler.NewDocumentHandler(docSvc),
 		Versions:  handler.NewVersionHandler(docSvc),
 		Shares:    handler.NewShareHandler(docSvc),
-		Tags:      handler.NewTagHandler(tagSvc),
-		Export: handler.NewExportHandler(
-			service.NewExportService(r.doc, r.version, r.tag, r.docTag),
+		Collab:    handler.NewCollabHandler(services.collab, cfg.CORS.AllowOrigins),
+		Tags:      handler.NewTagHandler(services.tags),
+		Folders:   handler.NewFolderHandler(services.folders),
+		Bulk: handler.NewBulkHandler(
+			service.NewBulkService(services.runtime, docSvc, services.folders, exportSvc),
 		),
-		Files:          fileHandler,
-		SemanticSearch: handler.NewSemanticSearchHandler(docSvc),
-		Import:         handler.NewImportHandler(importSvc, cfg.MaxUploadSize, service.SaveTempFile),
-		Templates: handler.NewTemplateHandler(
-			service.NewTemplateService(r.template, docSvc, r.tag, runtime),
+		Export:            handler.NewExportHandler(exportSvc),
+		Files:             fileHandler,
+		SemanticSearch:    handler.NewSemanticSearchHandler(docSvc),
+		Import:            handler.NewImportHandler(services.imports, cfg.MaxUploadSize, service.SaveTempFile),
+		Templates:         handler.NewTemplateHandler(services.templates),
+		TemplateSchedules: handler.NewTemplateScheduleHandler(services.templateSchedules),
+		Assets:            handler.NewAssetHandler(services.assets),
+		Todos:             handler.NewTodoHandler(service.NewTodoService(r.todo, r.doc, docSvc, services.runtime)),
+		Admin: handler.NewAdminHandler(
+			service.NewAdminService(r.user, r.admin, store, cfg.VersionMaxKeep, services.runtime),
+			services.registration,
 		),
-		Assets:          handler.NewAssetHandler(assetSvc),
-		Todos:           handler.NewTodoHandler(service.NewTodoService(r.todo, runtime)),
+		Workspaces:      handler.NewWorkspaceHandler(services.workspaces),
 		JWTSecret:       []byte(cfg.JWTSecret),
 		MaxJSONBodySize: cfg.MaxJSONBodySize,
 	}, store, nil
@@ -974,6 +1076,7 @@ func buildRouterDeps(
 func startServer(
 	cfg *config.Config, database *sql.DB, engine webapi.IWebEngine,
 	embeddingSvc *service.EmbeddingService,
+	templateSchedules *service.TemplateScheduleService,
 	workers []mnoteapp.Worker,
 	r serverRepos,
 ) error {
@@ -985,7 +1088,7 @@ func startServer(
 	defer stop()
 
 	scheduler := schedule.NewCronScheduler()
-	if err := addScheduledJobs(scheduler, cfg, embeddingSvc, r); err != nil {
+	if err := addScheduledJobs(scheduler, cfg, embeddingSvc, templateSchedules, r); err != nil {
 		return err
 	}
 	instance, err := mnoteapp.New(mnoteapp.Config{
@@ -1005,6 +1108,7 @@ func startServer(
 func addScheduledJobs(
 	s schedule.Scheduler, cfg *config.Config,
 	embeddingSvc *service.EmbeddingService,
+	templateSchedules *service.TemplateScheduleService,
 	r serverRepos,
 ) error {
 	type entry struct {
@@ -1015,6 +1119,9 @@ func addScheduledJobs(
 	jobs := []entry{
 		{job.NewImportCleanupJob(r.importJob, r.importJobNote, 24*time.Hour), "0 * * * *", "import_cleanup"},
 	}
+	if templateSchedules != nil {
+		jobs = append(jobs, entry{job.NewTemplateScheduleJob(templateSchedules), "* * * * *", "template_schedule"})
+	}
 	if cfg.AI.IsEnabled() && hasLegacyEmbeddingConfig(cfg.AI) {
 		jobs = append(jobs,
 			entry{job.NewAIEmbeddingJob(embeddingSvc, cfg.AIJob.EmbeddingDelaySeconds), "*/1 * * * *", "ai_embedding"},
@@ -2,8 +2,11 @@ package main
 
 import (
 	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -14,6 +17,7 @@ import (
 	"github.com/xxxsen/mnote/internal/config"
 	"github.com/xxxsen/mnote/internal/model"
 	"github.com/xxxsen/mnote/internal/schedule"
+	"github.com/xxxsen/mnote/internal/service"
 )
 
 func boolPointer(value bool) *bool {
@@ -396,7 +400,7 @@ func TestAddScheduledJobs_RespectsEmbeddingSwitch(t *testing.T) {
 		scheduler := &recordingScheduler{}
 		cfg := &config.Config{AI: config.AIConfig{Enabled: boolPointer(false)}}
 
-		require.NoError(t, addScheduledJobs(scheduler, cfg, nil, serverRepos{}))
+		require.NoError(t, addScheduledJobs(scheduler, cfg, nil, nil, serverRepos{}))
 		assert.Equal(t, []string{"import_cleanup"}, scheduler.names)
 	})
 
@@ -411,7 +415,7 @@ func TestAddScheduledJobs_RespectsEmbeddingSwitch(t *testing.T) {
 			AIJob: config.AIJobConfig{EmbeddingDelaySeconds: 30},
 		}
 
-		require.NoError(t, addScheduledJobs(scheduler, cfg, nil, serverRepos{}))
+		require.NoError(t, addScheduledJobs(scheduler, cfg, nil, nil, serverRepos{}))
 		assert.ElementsMatch(
 			t,
 			[]string{"import_cleanup", "ai_embedding", "embedding_cache_cleanup"},
@@ -430,11 +434,78 @@ func TestAddScheduledJobs_RespectsEmbeddingSwitch(t *testing.T) {
 			},
 		}
 
-		require.NoError(t, addScheduledJobs(scheduler, cfg, nil, serverRepos{}))
+		require.NoError(t, addScheduledJobs(scheduler, cfg, nil, nil, serverRepos{}))
 		assert.Equal(
 			t,
 			[]string{"import_cleanup", "embedding_v2_maintenance"},
 			scheduler.names,
 		)
 	})
+
+	t.Run("template schedules", func(t *testing.T) {
+		scheduler := &recordingScheduler{}
+		cfg := &config.Config{AI: config.AIConfig{Enabled: boolPointer(false)}}
+		schedules := &service.TemplateScheduleService{}
+
+		require.NoError(t, addScheduledJobs(scheduler, cfg, nil, schedules, serverRepos{}))
+		assert.Equal(t, []string{"import_cleanup", "template_schedule"}, scheduler.names)
+	})
+}
+
+func TestAdminCommand_ValidatesFlagsBeforeOpeningDatabase(t *testing.T) {
+	cases := []struct {
+		name string
+		args []string
+		want error
+	}{
+		{name: "config", args: []string{"stats"}, want: errCommandConfigRequired},
+		{name: "create email", args: []string{"user", "create"}, want: errAdminEmailRequired},
+		{name: "delete confirm", args: []string{"user", "delete", "u1"}, want: errAdminDeleteUnconfirm},
+		{name: "export user", args: []string{"doc", "export", "-o", "out.zip"}, want: errAdminUserRequired},
+		{name: "export output", args: []string{"doc", "export", "--user", "u1"}, want: errAdminOutputRequired},
+		{name: "import user", args: []string{"doc", "import", "notes.zip"}, want: errAdminUserRequired},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			command := newAdminCommand()
+			command.SetArgs(tc.args)
+			command.SetOut(io.Discard)
+			command.SetErr(io.Discard)
+			assert.ErrorIs(t, command.Execute(), tc.want)
+		})
+	}
+}
+
+func TestCopyCommandFile(t *testing.T) {
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src.zip")
+	require.NoError(t, os.WriteFile(src, []byte("zip-bytes"), 0o600))
+
+	size, err := copyCommandFile(src, filepath.Join(dir, "dst.zip"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(9), size)
+	data, err := os.ReadFile(filepath.Join(dir, "dst.zip"))
+	require.NoError(t, err)
+	assert.Equal(t, "zip-bytes", string(data))
+}
+
+func TestBackupCommand_ValidatesFlagsBeforeOpeningDatabase(t *testing.T) {
+	cases := []struct {
+		name string
+		args []string
+		want error
+	}{
+		{name: "create output", args: []string{"create"}, want: errBackupOutputRequired},
+		{name: "create config", args: []string{"create", "-o", "out.zip"}, want: errCommandConfigRequired},
+		{name: "restore config", args: []string{"restore", "out.zip"}, want: errCommandConfigRequired},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			command := newBackupCommand()
+			command.SetArgs(tc.args)
+			command.SetOut(io.Discard)
+			command.SetErr(io.Discard)
+			assert.ErrorIs(t, command.Execute(), tc.want)
+		})
+	}
 }
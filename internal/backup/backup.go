@@ -0,0 +1,79 @@
+// Package backup writes and restores instance backups. An archive is a zip
+// holding manifest.json, one JSON-lines file per table under tables/ and the
+// stored objects of every asset under files/. The manifest records the
+// migration ledger of the source database and the SHA-256 of every entry, so
+// an archive is verified completely before a restore writes anything.
+//
+// Derived and transient data is left out: embeddings are rebuilt by the
+// server, and verification codes, one-time tokens and import staging expire
+// anyway. The template gallery is seeded on startup.
+package backup
+
+import (
+	"context"
+	"errors"
+
+	"github.com/xxxsen/mnote/internal/model"
+)
+
+const (
+	manifestPath = "manifest.json"
+	tablesDir    = "tables/"
+	filesDir     = "files/"
+)
+
+// Tables lists the backed up tables, parents before children so a restore
+// can insert them in order.
+var Tables = []string{
+	"users",
+	"invite_codes",
+	"workspaces",
+	"workspace_members",
+	"oauth_accounts",
+	"tags",
+	"folders",
+	"documents",
+	"document_collaborators",
+	"document_versions",
+	"document_tags",
+	"document_links",
+	"document_wikilinks",
+	"document_broken_links",
+	"document_properties",
+	"assets",
+	"document_assets",
+	"shares",
+	"share_comments",
+	"templates",
+	"template_schedules",
+	"todos",
+}
+
+// selfReferences lists columns that point at rows of the same table. They
+// are written after all rows of the table exist.
+var selfReferences = map[string][]string{
+	"share_comments": {"root_id", "reply_to_id"},
+}
+
+var (
+	ErrInvalidArchive   = errors.New("invalid backup archive")
+	ErrChecksumMismatch = errors.New("backup checksum mismatch")
+	ErrSchemaMismatch   = errors.New("backup schema is not compatible with this build")
+	ErrTargetNotEmpty   = errors.New("restore target is not empty")
+)
+
+// Source is the database a backup is read from.
+type Source interface {
+	Snapshot(ctx context.Context, fn func(ctx context.Context) error) error
+	AppliedMigrations(ctx context.Context) ([]model.SchemaMigration, error)
+	DumpTable(ctx context.Context, table string, fn func(row map[string]any) error) error
+}
+
+// Target is the database a backup is restored into.
+type Target interface {
+	WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error
+	AppliedMigrations(ctx context.Context) ([]model.SchemaMigration, error)
+	TableHasRows(ctx context.Context, table string) (bool, error)
+	InsertRows(ctx context.Context, table string, rows []map[string]any) error
+	UpdateRow(ctx context.Context, table, id string, values map[string]any) error
+}
@@ -0,0 +1,295 @@
+package backup
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/filestore"
+	"github.com/xxxsen/mnote/internal/model"
+)
+
+var errInsert = errors.New("insert failed")
+
+type memoryDB struct {
+	migrations []model.SchemaMigration
+	tables     map[string][]map[string]any
+	updates    int
+	insertErr  error
+}
+
+func newMemoryDB() *memoryDB {
+	return &memoryDB{
+		migrations: []model.SchemaMigration{
+			{Version: "001_init", Checksum: "c1"},
+			{Version: "002_next", Checksum: "c2"},
+		},
+		tables: make(map[string][]map[string]any),
+	}
+}
+
+func (m *memoryDB) Snapshot(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func (m *memoryDB) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(ctx)
+}
+
+func (m *memoryDB) AppliedMigrations(context.Context) ([]model.SchemaMigration, error) {
+	return m.migrations, nil
+}
+
+func (m *memoryDB) DumpTable(_ context.Context, table string, fn func(row map[string]any) error) error {
+	for _, row := range m.tables[table] {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *memoryDB) TableHasRows(_ context.Context, table string) (bool, error) {
+	return len(m.tables[table]) > 0, nil
+}
+
+func (m *memoryDB) InsertRows(_ context.Context, table string, rows []map[string]any) error {
+	if m.insertErr != nil {
+		return m.insertErr
+	}
+	for _, row := range rows {
+		copied := make(map[string]any, len(row))
+		for key, value := range row {
+			copied[key] = value
+		}
+		m.tables[table] = append(m.tables[table], copied)
+	}
+	return nil
+}
+
+func (m *memoryDB) UpdateRow(_ context.Context, table, id string, values map[string]any) error {
+	for _, row := range m.tables[table] {
+		if row["id"] == id {
+			for key, value := range values {
+				row[key] = value
+			}
+			m.updates++
+		}
+	}
+	return nil
+}
+
+func newLocalStore(t *testing.T) filestore.Store {
+	t.Helper()
+	store, err := filestore.New(filestore.Config{Type: "local", Data: map[string]any{"dir": t.TempDir()}})
+	require.NoError(t, err)
+	return store
+}
+
+func saveObject(t *testing.T, store filestore.Store, key, content string) {
+	t.Helper()
+	tmp, err := os.CreateTemp(t.TempDir(), "object")
+	require.NoError(t, err)
+	_, err = tmp.WriteString(content)
+	require.NoError(t, err)
+	_, err = tmp.Seek(0, io.SeekStart)
+	require.NoError(t, err)
+	require.NoError(t, store.Save(context.Background(), key, tmp, int64(len(content))))
+}
+
+func seedSource(t *testing.T) (*memoryDB, filestore.Store) {
+	t.Helper()
+	source := newMemoryDB()
+	source.tables["users"] = []map[string]any{{"id": "u1", "email": "a@example.com", "ctime": int64(1)}}
+	source.tables["documents"] = []map[string]any{{"id": "d1", "user_id": "u1", "content": "# 标题"}}
+	source.tables["assets"] = []map[string]any{
+		{"id": "a1", "user_id": "u1", "file_key": "u1_img.png"},
+		{"id": "a2", "user_id": "u1", "file_key": "u1_gone.png"},
+	}
+	source.tables["share_comments"] = []map[string]any{
+		{"id": "c1", "root_id": nil, "reply_to_id": nil},
+		{"id": "c2", "root_id": "c1", "reply_to_id": "c1"},
+	}
+	store := newLocalStore(t)
+	saveObject(t, store, "u1_img.png", "png-bytes")
+	return source, store
+}
+
+func writeArchive(t *testing.T, source Source, store filestore.ReadableStore) (string, *model.BackupManifest) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "backup.zip")
+	file, err := os.Create(path)
+	require.NoError(t, err)
+	manifest, err := Create(context.Background(), source, store, file, time.Unix(100, 0))
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	return path, manifest
+}
+
+func TestCreateAndRestore(t *testing.T) {
+	source, store := seedSource(t)
+	path, manifest := writeArchive(t, source, store)
+	assert.Equal(t, "002_next", manifest.SchemaVersion)
+	assert.Equal(t, int64(100), manifest.CreatedAt)
+	assert.Len(t, manifest.Tables, len(Tables))
+	require.Len(t, manifest.Files, 1)
+	assert.Equal(t, int64(len("png-bytes")), manifest.Files[0].Size)
+	assert.Equal(t, []string{"u1_gone.png"}, manifest.MissingFiles)
+
+	verified, err := Verify(path)
+	require.NoError(t, err)
+	assert.Equal(t, manifest.Tables, verified.Tables)
+
+	target := newMemoryDB()
+	targetStore := newLocalStore(t)
+	result, err := Restore(context.Background(), target, targetStore, path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), result.Rows)
+	assert.Equal(t, 1, result.Files)
+	assert.Equal(t, "# 标题", target.tables["documents"][0]["content"])
+	assert.Equal(t, json.Number("1"), target.tables["users"][0]["ctime"])
+	assert.Equal(t, "c1", target.tables["share_comments"][1]["reply_to_id"])
+	assert.Equal(t, 1, target.updates)
+
+	in, err := targetStore.Open(context.Background(), "u1_img.png")
+	require.NoError(t, err)
+	data, err := io.ReadAll(in)
+	require.NoError(t, err)
+	_ = in.Close()
+	assert.Equal(t, "png-bytes", string(data))
+}
+
+// rewriteEntry copies the archive at path, replacing the content of name.
+func rewriteEntry(t *testing.T, path, name string, content []byte) string {
+	t.Helper()
+	reader, err := zip.OpenReader(path)
+	require.NoError(t, err)
+	defer func() { _ = reader.Close() }()
+	out := filepath.Join(t.TempDir(), "tampered.zip")
+	file, err := os.Create(out)
+	require.NoError(t, err)
+	writer := zip.NewWriter(file)
+	for _, entry := range reader.File {
+		dst, err := writer.Create(entry.Name)
+		require.NoError(t, err)
+		if entry.Name == name {
+			_, err = dst.Write(content)
+			require.NoError(t, err)
+			continue
+		}
+		src, err := entry.Open()
+		require.NoError(t, err)
+		_, err = io.Copy(dst, src)
+		require.NoError(t, err)
+		_ = src.Close()
+	}
+	require.NoError(t, writer.Close())
+	require.NoError(t, file.Close())
+	return out
+}
+
+func TestVerify_DetectsTampering(t *testing.T) {
+	source, store := seedSource(t)
+	path, _ := writeArchive(t, source, store)
+
+	tampered := rewriteEntry(t, path, "files/u1_img.png", []byte("evil-byte"))
+	_, err := Verify(tampered)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+
+	tampered = rewriteEntry(t, path, "tables/users.jsonl", []byte(`{"id":"u2"}`+"\n"))
+	_, err = Verify(tampered)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+
+	target := newMemoryDB()
+	_, err = Restore(context.Background(), target, newLocalStore(t), tampered)
+	assert.ErrorIs(t, err, ErrChecksumMismatch)
+	assert.Empty(t, target.tables)
+}
+
+func TestVerify_RejectsUnknownFormat(t *testing.T) {
+	source, store := seedSource(t)
+	path, _ := writeArchive(t, source, store)
+	tampered := rewriteEntry(t, path, "manifest.json", []byte(`{"format":"other","version":1}`))
+	_, err := Verify(tampered)
+	assert.ErrorIs(t, err, ErrInvalidArchive)
+
+	_, err = Verify(filepath.Join(t.TempDir(), "missing.zip"))
+	assert.ErrorIs(t, err, ErrInvalidArchive)
+}
+
+func TestRestore_RequiresEmptyTarget(t *testing.T) {
+	source, store := seedSource(t)
+	path, _ := writeArchive(t, source, store)
+
+	target := newMemoryDB()
+	target.tables["tags"] = []map[string]any{{"id": "t1"}}
+	_, err := Restore(context.Background(), target, newLocalStore(t), path)
+	assert.ErrorIs(t, err, ErrTargetNotEmpty)
+
+	occupied := newLocalStore(t)
+	saveObject(t, occupied, "u1_img.png", "other")
+	_, err = Restore(context.Background(), newMemoryDB(), occupied, path)
+	assert.ErrorIs(t, err, ErrTargetNotEmpty)
+}
+
+func TestRestore_RequiresMatchingSchema(t *testing.T) {
+	source, store := seedSource(t)
+	path, _ := writeArchive(t, source, store)
+
+	older := newMemoryDB()
+	older.migrations = older.migrations[:1]
+	_, err := Restore(context.Background(), older, newLocalStore(t), path)
+	assert.ErrorIs(t, err, ErrSchemaMismatch)
+
+	changed := newMemoryDB()
+	changed.migrations = []model.SchemaMigration{
+		{Version: "001_init", Checksum: "c1"},
+		{Version: "002_next", Checksum: "other"},
+	}
+	_, err = Restore(context.Background(), changed, newLocalStore(t), path)
+	assert.ErrorIs(t, err, ErrSchemaMismatch)
+}
+
+func TestRestore_RemovesFilesWhenRowsFail(t *testing.T) {
+	source, store := seedSource(t)
+	path, _ := writeArchive(t, source, store)
+
+	target := newMemoryDB()
+	target.insertErr = errInsert
+	targetStore := newLocalStore(t)
+	_, err := Restore(context.Background(), target, targetStore, path)
+	assert.ErrorIs(t, err, errInsert)
+	_, err = targetStore.Stat(context.Background(), "u1_img.png")
+	assert.ErrorIs(t, err, filestore.ErrObjectNotFound)
+}
+
+func TestCreate_WritesOneJSONObjectPerRow(t *testing.T) {
+	source, store := seedSource(t)
+	var buf bytes.Buffer
+	_, err := Create(context.Background(), source, store, &buf, time.Unix(1, 0))
+	require.NoError(t, err)
+	reader, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	for _, entry := range reader.File {
+		if entry.Name != "tables/share_comments.jsonl" {
+			continue
+		}
+		in, err := entry.Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(in)
+		require.NoError(t, err)
+		_ = in.Close()
+		assert.Equal(t, 2, strings.Count(string(data), "\n"))
+	}
+}
@@ -0,0 +1,157 @@
+package backup
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/xxxsen/mnote/internal/filestore"
+	"github.com/xxxsen/mnote/internal/model"
+)
+
+// Create writes a backup of source and of the asset objects in store to w
+// and returns its manifest. All tables are read from one snapshot. An
+// object that no longer exists is listed in MissingFiles instead of failing
+// the backup, because assets may be removed while it runs.
+func Create(
+	ctx context.Context, source Source, store filestore.ReadableStore, w io.Writer, now time.Time,
+) (*model.BackupManifest, error) {
+	archive := zip.NewWriter(w)
+	manifest := &model.BackupManifest{
+		Format:       model.BackupFormat,
+		Version:      model.BackupVersion,
+		CreatedAt:    now.Unix(),
+		Tables:       make([]model.BackupTableEntry, 0, len(Tables)),
+		Files:        make([]model.BackupFileEntry, 0),
+		MissingFiles: make([]string, 0),
+	}
+	err := source.Snapshot(ctx, func(ctx context.Context) error {
+		migrations, err := source.AppliedMigrations(ctx)
+		if err != nil {
+			return fmt.Errorf("read migrations: %w", err)
+		}
+		if len(migrations) == 0 {
+			return fmt.Errorf("%w: source has no migration ledger", ErrSchemaMismatch)
+		}
+		manifest.Migrations = migrations
+		manifest.SchemaVersion = migrations[len(migrations)-1].Version
+		keys := make(map[string]struct{})
+		for _, table := range Tables {
+			entry, err := writeTable(ctx, archive, source, table, keys)
+			if err != nil {
+				return err
+			}
+			manifest.Tables = append(manifest.Tables, *entry)
+		}
+		return writeFiles(ctx, archive, store, keys, manifest)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := writeManifest(archive, manifest); err != nil {
+		return nil, err
+	}
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("close archive: %w", err)
+	}
+	return manifest, nil
+}
+
+func writeTable(
+	ctx context.Context, archive *zip.Writer, source Source, table string, keys map[string]struct{},
+) (*model.BackupTableEntry, error) {
+	path := tablesDir + table + ".jsonl"
+	out, err := archive.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("create %s: %w", path, err)
+	}
+	hash := sha256.New()
+	encoder := json.NewEncoder(io.MultiWriter(out, hash))
+	entry := &model.BackupTableEntry{Name: table, Path: path}
+	err = source.DumpTable(ctx, table, func(row map[string]any) error {
+		if table == "assets" {
+			if key, ok := row["file_key"].(string); ok && key != "" {
+				keys[key] = struct{}{}
+			}
+		}
+		entry.Rows++
+		if err := encoder.Encode(row); err != nil {
+			return fmt.Errorf("write %s row: %w", table, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("dump %s: %w", table, err)
+	}
+	entry.SHA256 = hex.EncodeToString(hash.Sum(nil))
+	return entry, nil
+}
+
+func writeFiles(
+	ctx context.Context, archive *zip.Writer, store filestore.ReadableStore,
+	keys map[string]struct{}, manifest *model.BackupManifest,
+) error {
+	sorted := make([]string, 0, len(keys))
+	for key := range keys {
+		sorted = append(sorted, key)
+	}
+	sort.Strings(sorted)
+	for _, key := range sorted {
+		entry, err := writeFile(ctx, archive, store, key)
+		if errors.Is(err, filestore.ErrObjectNotFound) || errors.Is(err, filestore.ErrInvalidFileKey) {
+			manifest.MissingFiles = append(manifest.MissingFiles, key)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		manifest.Files = append(manifest.Files, *entry)
+	}
+	return nil
+}
+
+func writeFile(
+	ctx context.Context, archive *zip.Writer, store filestore.ReadableStore, key string,
+) (*model.BackupFileEntry, error) {
+	if err := filestore.ValidateFileKey(key); err != nil {
+		return nil, fmt.Errorf("file %q: %w", key, err)
+	}
+	in, err := store.Open(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("open file %s: %w", key, err)
+	}
+	defer func() { _ = in.Close() }()
+	path := filesDir + key
+	out, err := archive.CreateHeader(&zip.FileHeader{Name: path, Method: zip.Store})
+	if err != nil {
+		return nil, fmt.Errorf("create %s: %w", path, err)
+	}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(out, hash), in)
+	if err != nil {
+		return nil, fmt.Errorf("copy file %s: %w", key, err)
+	}
+	return &model.BackupFileEntry{
+		Key: key, Path: path, Size: size, SHA256: hex.EncodeToString(hash.Sum(nil)),
+	}, nil
+}
+
+func writeManifest(archive *zip.Writer, manifest *model.BackupManifest) error {
+	out, err := archive.Create(manifestPath)
+	if err != nil {
+		return fmt.Errorf("create manifest: %w", err)
+	}
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(manifest); err != nil {
+		return fmt.Errorf("write manifest: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,354 @@
+package backup
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/xxxsen/common/logutil"
+	"go.uber.org/zap"
+
+	"github.com/xxxsen/mnote/internal/filestore"
+	"github.com/xxxsen/mnote/internal/model"
+)
+
+const (
+	restoreBatchSize = 200
+	maxManifestBytes = 64 << 20
+)
+
+// RestoreResult reports what a restore wrote.
+type RestoreResult struct {
+	Manifest *model.BackupManifest `json:"manifest"`
+	Rows     int64                 `json:"rows"`
+	Files    int                   `json:"files"`
+}
+
+// Verify checks every entry of the archive at path against the manifest and
+// returns the manifest.
+func Verify(path string) (*model.BackupManifest, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+	}
+	defer func() { _ = archive.Close() }()
+	return verifyArchive(&archive.Reader)
+}
+
+// Restore loads the archive at path into an empty target and store. The
+// archive is verified first, and the target must already carry every
+// migration of the source with the same checksum. Objects are written
+// before the rows; if the rows cannot be written the objects are removed
+// again.
+func Restore(ctx context.Context, target Target, store filestore.Store, path string) (*RestoreResult, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidArchive, err)
+	}
+	defer func() { _ = archive.Close() }()
+	manifest, err := verifyArchive(&archive.Reader)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSchema(ctx, target, manifest); err != nil {
+		return nil, err
+	}
+	if err := checkEmpty(ctx, target, store, manifest); err != nil {
+		return nil, err
+	}
+	entries := archiveEntries(&archive.Reader)
+	saved := make([]string, 0, len(manifest.Files))
+	for _, file := range manifest.Files {
+		if err := restoreFile(ctx, store, entries[file.Path], file); err != nil {
+			removeFiles(ctx, store, saved)
+			return nil, err
+		}
+		saved = append(saved, file.Key)
+	}
+	result := &RestoreResult{Manifest: manifest, Files: len(saved)}
+	tables := make(map[string]model.BackupTableEntry, len(manifest.Tables))
+	for _, table := range manifest.Tables {
+		tables[table.Name] = table
+	}
+	err = target.WithinTransaction(ctx, func(txCtx context.Context) error {
+		for _, name := range Tables {
+			table, ok := tables[name]
+			if !ok {
+				continue
+			}
+			rows, err := restoreTable(txCtx, target, entries[table.Path], name)
+			if err != nil {
+				return err
+			}
+			result.Rows += rows
+		}
+		return nil
+	})
+	if err != nil {
+		removeFiles(ctx, store, saved)
+		return nil, err
+	}
+	return result, nil
+}
+
+func archiveEntries(archive *zip.Reader) map[string]*zip.File {
+	entries := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		entries[file.Name] = file
+	}
+	return entries
+}
+
+func verifyArchive(archive *zip.Reader) (*model.BackupManifest, error) {
+	entries := archiveEntries(archive)
+	manifest, err := readManifest(entries[manifestPath])
+	if err != nil {
+		return nil, err
+	}
+	known := make(map[string]struct{}, len(Tables))
+	for _, name := range Tables {
+		known[name] = struct{}{}
+	}
+	seen := make(map[string]struct{}, len(manifest.Tables)+len(manifest.Files))
+	for _, table := range manifest.Tables {
+		if _, ok := known[table.Name]; !ok || table.Path != tablesDir+table.Name+".jsonl" {
+			return nil, fmt.Errorf("%w: unexpected table %q", ErrInvalidArchive, table.Name)
+		}
+		if err := markSeen(seen, table.Path); err != nil {
+			return nil, err
+		}
+		sum, lines, err := hashEntry(entries[table.Path], table.Path)
+		if err != nil {
+			return nil, err
+		}
+		if sum != table.SHA256 || lines != table.Rows {
+			return nil, fmt.Errorf("%w: %s", ErrChecksumMismatch, table.Path)
+		}
+	}
+	for _, file := range manifest.Files {
+		if err := filestore.ValidateFileKey(file.Key); err != nil || file.Path != filesDir+file.Key {
+			return nil, fmt.Errorf("%w: unexpected file %q", ErrInvalidArchive, file.Key)
+		}
+		if err := markSeen(seen, file.Path); err != nil {
+			return nil, err
+		}
+		entry := entries[file.Path]
+		sum, _, err := hashEntry(entry, file.Path)
+		if err != nil {
+			return nil, err
+		}
+		if sum != file.SHA256 || int64(entry.UncompressedSize64) != file.Size {
+			return nil, fmt.Errorf("%w: %s", ErrChecksumMismatch, file.Path)
+		}
+	}
+	return manifest, nil
+}
+
+func readManifest(entry *zip.File) (*model.BackupManifest, error) {
+	if entry == nil {
+		return nil, fmt.Errorf("%w: missing %s", ErrInvalidArchive, manifestPath)
+	}
+	in, err := entry.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: open manifest: %w", ErrInvalidArchive, err)
+	}
+	defer func() { _ = in.Close() }()
+	var manifest model.BackupManifest
+	if err := json.NewDecoder(io.LimitReader(in, maxManifestBytes)).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("%w: decode manifest: %w", ErrInvalidArchive, err)
+	}
+	if manifest.Format != model.BackupFormat || manifest.Version <= 0 || manifest.Version > model.BackupVersion {
+		return nil, fmt.Errorf("%w: unsupported format %q version %d",
+			ErrInvalidArchive, manifest.Format, manifest.Version)
+	}
+	if len(manifest.Migrations) == 0 {
+		return nil, fmt.Errorf("%w: manifest has no migrations", ErrInvalidArchive)
+	}
+	return &manifest, nil
+}
+
+func markSeen(seen map[string]struct{}, path string) error {
+	if _, ok := seen[path]; ok {
+		return fmt.Errorf("%w: duplicate entry %s", ErrInvalidArchive, path)
+	}
+	seen[path] = struct{}{}
+	return nil
+}
+
+// hashEntry returns the SHA-256 and the number of lines of an entry.
+func hashEntry(entry *zip.File, path string) (string, int64, error) {
+	if entry == nil {
+		return "", 0, fmt.Errorf("%w: missing %s", ErrInvalidArchive, path)
+	}
+	in, err := entry.Open()
+	if err != nil {
+		return "", 0, fmt.Errorf("%w: open %s: %w", ErrInvalidArchive, path, err)
+	}
+	defer func() { _ = in.Close() }()
+	hash := sha256.New()
+	lines := int64(0)
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := in.Read(buf)
+		if n > 0 {
+			_, _ = hash.Write(buf[:n])
+			lines += int64(bytes.Count(buf[:n], []byte{'\n'}))
+		}
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// zip reports a CRC mismatch here for corrupted entries.
+			return "", 0, fmt.Errorf("%w: read %s: %w", ErrChecksumMismatch, path, err)
+		}
+	}
+	return hex.EncodeToString(hash.Sum(nil)), lines, nil
+}
+
+func checkSchema(ctx context.Context, target Target, manifest *model.BackupManifest) error {
+	applied, err := target.AppliedMigrations(ctx)
+	if err != nil {
+		return fmt.Errorf("read target migrations: %w", err)
+	}
+	checksums := make(map[string]string, len(applied))
+	for _, item := range applied {
+		checksums[item.Version] = item.Checksum
+	}
+	for _, item := range manifest.Migrations {
+		checksum, ok := checksums[item.Version]
+		if !ok {
+			return fmt.Errorf("%w: migration %s is unknown", ErrSchemaMismatch, item.Version)
+		}
+		if checksum != item.Checksum {
+			return fmt.Errorf("%w: migration %s has a different checksum", ErrSchemaMismatch, item.Version)
+		}
+	}
+	return nil
+}
+
+func checkEmpty(ctx context.Context, target Target, store filestore.Store, manifest *model.BackupManifest) error {
+	for _, table := range Tables {
+		hasRows, err := target.TableHasRows(ctx, table)
+		if err != nil {
+			return fmt.Errorf("check target table: %w", err)
+		}
+		if hasRows {
+			return fmt.Errorf("%w: table %s has rows", ErrTargetNotEmpty, table)
+		}
+	}
+	for _, file := range manifest.Files {
+		_, err := store.Stat(ctx, file.Key)
+		if err == nil {
+			return fmt.Errorf("%w: file %s exists", ErrTargetNotEmpty, file.Key)
+		}
+		if !errors.Is(err, filestore.ErrObjectNotFound) {
+			return fmt.Errorf("check target file %s: %w", file.Key, err)
+		}
+	}
+	return nil
+}
+
+// restoreFile spools the entry to a temporary file because the store needs
+// a seekable reader.
+func restoreFile(ctx context.Context, store filestore.Store, entry *zip.File, file model.BackupFileEntry) error {
+	in, err := entry.Open()
+	if err != nil {
+		return fmt.Errorf("open %s: %w", file.Path, err)
+	}
+	defer func() { _ = in.Close() }()
+	tmp, err := os.CreateTemp("", "mnote-restore-*")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+	}()
+	if _, err := io.Copy(tmp, in); err != nil {
+		return fmt.Errorf("extract %s: %w", file.Path, err)
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("rewind %s: %w", file.Path, err)
+	}
+	if err := store.Save(ctx, file.Key, tmp, file.Size); err != nil {
+		return fmt.Errorf("save file %s: %w", file.Key, err)
+	}
+	return nil
+}
+
+func restoreTable(ctx context.Context, target Target, entry *zip.File, table string) (int64, error) {
+	in, err := entry.Open()
+	if err != nil {
+		return 0, fmt.Errorf("open %s: %w", entry.Name, err)
+	}
+	defer func() { _ = in.Close() }()
+	decoder := json.NewDecoder(in)
+	decoder.UseNumber()
+	type pendingUpdate struct {
+		id     string
+		values map[string]any
+	}
+	updates := make([]pendingUpdate, 0)
+	batch := make([]map[string]any, 0, restoreBatchSize)
+	count := int64(0)
+	flush := func() error {
+		if err := target.InsertRows(ctx, table, batch); err != nil {
+			return fmt.Errorf("restore %s: %w", table, err)
+		}
+		batch = batch[:0]
+		return nil
+	}
+	for {
+		var row map[string]any
+		if err := decoder.Decode(&row); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return 0, fmt.Errorf("%w: decode %s: %w", ErrInvalidArchive, entry.Name, err)
+		}
+		if columns := selfReferences[table]; len(columns) > 0 {
+			values := make(map[string]any)
+			for _, column := range columns {
+				if row[column] != nil {
+					values[column] = row[column]
+					row[column] = nil
+				}
+			}
+			id, _ := row["id"].(string)
+			if len(values) > 0 && id != "" {
+				updates = append(updates, pendingUpdate{id: id, values: values})
+			}
+		}
+		batch = append(batch, row)
+		count++
+		if len(batch) == restoreBatchSize {
+			if err := flush(); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return 0, err
+	}
+	for _, update := range updates {
+		if err := target.UpdateRow(ctx, table, update.id, update.values); err != nil {
+			return 0, fmt.Errorf("restore %s references: %w", table, err)
+		}
+	}
+	return count, nil
+}
+
+func removeFiles(ctx context.Context, store filestore.Store, keys []string) {
+	for _, key := range keys {
+		if err := store.Delete(ctx, key); err != nil {
+			logutil.GetLogger(ctx).Warn("remove restored file failed", zap.String("file_key", key), zap.Error(err))
+		}
+	}
+}
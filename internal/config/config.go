@@ -8,7 +8,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/url"
 	"os"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -33,6 +36,7 @@ type Config struct {
 	OAuth           OAuthConfig        `json:"oauth"`
 	Mail            MailConfig         `json:"mail"`
 	Properties      Properties         `json:"properties"`
+	Registration    RegistrationConfig `json:"registration"`
 	Banner          BannerConfig       `json:"banner"`
 	AIProvider      []AIProviderConfig `json:"ai_provider"`
 }
@@ -169,6 +173,31 @@ func (c *AIJobConfig) UnmarshalJSON(data []byte) error {
 type OAuthConfig struct {
 	Github OAuthProviderConfig `json:"github"`
 	Google OAuthProviderConfig `json:"google"`
+	// OIDC lists generic OpenID Connect providers. Every entry is enabled
+	// and its name is the provider key in URLs and account bindings.
+	OIDC []OIDCProviderConfig `json:"oidc"`
+}
+
+type OIDCProviderConfig struct {
+	Name         string   `json:"name"`
+	DisplayName  string   `json:"display_name"`
+	Issuer       string   `json:"issuer"`
+	ClientID     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	RedirectURL  string   `json:"redirect_url"`
+	Scopes       []string `json:"scopes"`
+	// Claims maps profile fields to ID token claims; empty fields use the
+	// standard claim names.
+	Claims OIDCClaimsConfig `json:"claims"`
+	// AllowUnverifiedEmail accepts logins whose email_verified claim is
+	// missing or false.
+	AllowUnverifiedEmail bool `json:"allow_unverified_email"`
+}
+
+type OIDCClaimsConfig struct {
+	Subject       string `json:"subject"`
+	Email         string `json:"email"`
+	EmailVerified string `json:"email_verified"`
 }
 
 type OAuthProviderConfig struct {
@@ -194,6 +223,15 @@ type Properties struct {
 	EnableTestMode      bool `json:"enable_test_mode"`
 }
 
+// RegistrationConfig controls who may create an account. Mode is "open",
+// "invite" (a single-use invite code is required) or "closed". When
+// AllowedDomains is set, only emails of those domains may register. The
+// policy applies to password registration and to the first OAuth login.
+type RegistrationConfig struct {
+	Mode           string   `json:"mode"`
+	AllowedDomains []string `json:"allowed_domains"`
+}
+
 type BannerConfig struct {
 	Enable   bool   `json:"enable"`
 	Title    string `json:"title"`
@@ -211,6 +249,8 @@ var (
 	errInvalidAIConfig       = errors.New("invalid embedding configuration")
 	errIncompleteMailConfig  = errors.New("mail host, port, and from are required when email registration is enabled")
 	errIncompleteOAuthConfig = errors.New("oauth client id, secret, and redirect URL are required")
+	errInvalidOIDCConfig     = errors.New("invalid oidc provider configuration")
+	errInvalidRegistration   = errors.New("invalid registration configuration")
 )
 
 func Load(path string) (*Config, error) {
@@ -462,6 +502,12 @@ func (c *Config) validateOptionalFeatures() error {
 			return err
 		}
 	}
+	if err := c.validateOIDCProviders(); err != nil {
+		return err
+	}
+	if err := c.validateRegistration(); err != nil {
+		return err
+	}
 	if c.Properties.EnableEmailRegister &&
 		(strings.TrimSpace(c.Mail.Host) == "" || c.Mail.Port <= 0 || strings.TrimSpace(c.Mail.From) == "") {
 		return errIncompleteMailConfig
@@ -478,6 +524,52 @@ func validateOAuthProvider(name string, provider OAuthProviderConfig) error {
 	return nil
 }
 
+var registrationDomainPattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)+$`)
+
+func (c *Config) validateRegistration() error {
+	switch c.Registration.Mode {
+	case "open", "invite", "closed":
+	default:
+		return fmt.Errorf("%w: unknown mode %q", errInvalidRegistration, c.Registration.Mode)
+	}
+	for _, domain := range c.Registration.AllowedDomains {
+		if !registrationDomainPattern.MatchString(domain) {
+			return fmt.Errorf("%w: invalid domain %q", errInvalidRegistration, domain)
+		}
+	}
+	return nil
+}
+
+var oidcProviderNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{0,31}$`)
+
+// validateOIDCProviders checks the OIDC entries. The client secret is
+// optional because public clients authenticate with PKCE alone.
+func (c *Config) validateOIDCProviders() error {
+	seen := map[string]struct{}{"github": {}, "google": {}}
+	for _, provider := range c.OAuth.OIDC {
+		name := provider.Name
+		if !oidcProviderNamePattern.MatchString(name) {
+			return fmt.Errorf("%w: invalid name %q", errInvalidOIDCConfig, name)
+		}
+		if _, ok := seen[name]; ok {
+			return fmt.Errorf("%w: duplicate name %q", errInvalidOIDCConfig, name)
+		}
+		seen[name] = struct{}{}
+		issuer, err := url.Parse(strings.TrimSpace(provider.Issuer))
+		if err != nil || (issuer.Scheme != "https" && issuer.Scheme != "http") || issuer.Host == "" ||
+			issuer.RawQuery != "" || issuer.Fragment != "" {
+			return fmt.Errorf("%w: %s: issuer must be an http(s) URL", errInvalidOIDCConfig, name)
+		}
+		if strings.TrimSpace(provider.ClientID) == "" || strings.TrimSpace(provider.RedirectURL) == "" {
+			return fmt.Errorf("%w: %s", errIncompleteOAuthConfig, name)
+		}
+		if !slices.Contains(provider.Scopes, "openid") {
+			return fmt.Errorf("%w: %s: scopes must include openid", errInvalidOIDCConfig, name)
+		}
+	}
+	return nil
+}
+
 func (c *Config) applyDefaults() {
 	if c.JWTTTLHours == 0 {
 		c.JWTTTLHours = 72
@@ -520,6 +612,18 @@ func (c *Config) applyDefaults() {
 	}
 	c.applyAIDefaults()
 	c.applyOAuthDefaults()
+	c.applyRegistrationDefaults()
+}
+
+func (c *Config) applyRegistrationDefaults() {
+	c.Registration.Mode = strings.ToLower(strings.TrimSpace(c.Registration.Mode))
+	if c.Registration.Mode == "" {
+		c.Registration.Mode = "open"
+	}
+	for index, domain := range c.Registration.AllowedDomains {
+		domain = strings.ToLower(strings.TrimSpace(domain))
+		c.Registration.AllowedDomains[index] = strings.TrimPrefix(domain, "@")
+	}
 }
 
 func (c *Config) applyAIDefaults() {
@@ -683,4 +787,14 @@ func (c *Config) applyOAuthDefaults() {
 	if c.Properties.EnableGoogleOauth && len(c.OAuth.Google.Scopes) == 0 {
 		c.OAuth.Google.Scopes = []string{"openid", "email", "profile"}
 	}
+	for index := range c.OAuth.OIDC {
+		provider := &c.OAuth.OIDC[index]
+		provider.Name = strings.ToLower(strings.TrimSpace(provider.Name))
+		if strings.TrimSpace(provider.DisplayName) == "" {
+			provider.DisplayName = provider.Name
+		}
+		if len(provider.Scopes) == 0 {
+			provider.Scopes = []string{"openid", "email", "profile"}
+		}
+	}
 }
@@ -43,6 +43,7 @@ func TestLoad_Defaults(t *testing.T) {
 	assert.Equal(t, "info", cfg.LogConfig.Level)
 	assert.Equal(t, "local", cfg.FileStore.Type)
 	assert.Equal(t, int64(300), cfg.AIJob.EmbeddingDelaySeconds)
+	assert.Equal(t, "open", cfg.Registration.Mode)
 }
 
 func TestLoad_FileNotFound(t *testing.T) {
@@ -493,3 +494,86 @@ func TestApplyOAuthDefaults_CustomScopes(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, []string{"repo"}, cfg.OAuth.Github.Scopes)
 }
+
+func TestLoad_OIDCProviders(t *testing.T) {
+	j := `{
+		"database": {"host":"h"}, "jwt_secret": "s", "port": 80,
+		"oauth": {"oidc": [
+			{"name": " Keycloak ", "issuer": "https://sso.example.test/realms/main",
+			 "client_id": "mnote", "client_secret": "secret",
+			 "redirect_url": "https://example.test/api/v1/auth/oauth/keycloak/callback",
+			 "claims": {"email": "attributes.mail"}},
+			{"name": "authentik", "display_name": "Company SSO",
+			 "issuer": "https://auth.example.test/application/o/mnote/",
+			 "client_id": "public", "redirect_url": "https://example.test/api/v1/auth/oauth/authentik/callback"}
+		]}
+	}`
+	cfg, err := Load(writeConfig(t, j))
+	require.NoError(t, err)
+	require.Len(t, cfg.OAuth.OIDC, 2)
+	assert.Equal(t, "keycloak", cfg.OAuth.OIDC[0].Name)
+	assert.Equal(t, "keycloak", cfg.OAuth.OIDC[0].DisplayName)
+	assert.Equal(t, []string{"openid", "email", "profile"}, cfg.OAuth.OIDC[0].Scopes)
+	assert.Equal(t, "attributes.mail", cfg.OAuth.OIDC[0].Claims.Email)
+	assert.Equal(t, "Company SSO", cfg.OAuth.OIDC[1].DisplayName)
+}
+
+func TestLoad_InvalidOIDCProviders(t *testing.T) {
+	entry := func(name, issuer, extra string) string {
+		return `{"name": "` + name + `", "issuer": "` + issuer + `", "client_id": "c",
+			"redirect_url": "https://example.test/cb"` + extra + `}`
+	}
+	tests := []struct {
+		name      string
+		providers string
+		want      error
+	}{
+		{name: "bad name", providers: entry("corp sso", "https://sso.test", ""), want: errInvalidOIDCConfig},
+		{name: "builtin name", providers: entry("github", "https://sso.test", ""), want: errInvalidOIDCConfig},
+		{
+			name:      "duplicate",
+			providers: entry("corp", "https://sso.test", "") + "," + entry("corp", "https://sso.test", ""),
+			want:      errInvalidOIDCConfig,
+		},
+		{name: "issuer", providers: entry("corp", "sso.test", ""), want: errInvalidOIDCConfig},
+		{
+			name:      "scopes",
+			providers: entry("corp", "https://sso.test", `, "scopes": ["email"]`),
+			want:      errInvalidOIDCConfig,
+		},
+		{
+			name:      "client",
+			providers: `{"name": "corp", "issuer": "https://sso.test", "redirect_url": "https://example.test/cb"}`,
+			want:      errIncompleteOAuthConfig,
+		},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			j := `{"database": {"host":"h"}, "jwt_secret": "s", "port": 80,
+				"oauth": {"oidc": [` + testCase.providers + `]}}`
+			_, err := Load(writeConfig(t, j))
+			assert.ErrorIs(t, err, testCase.want)
+		})
+	}
+}
+
+func TestLoad_Registration(t *testing.T) {
+	j := `{"database": {"host":"h"}, "jwt_secret": "s", "port": 80,
+		"registration": {"mode": " Invite ", "allowed_domains": ["Example.com", "@corp.example.org"]}}`
+	cfg, err := Load(writeConfig(t, j))
+	require.NoError(t, err)
+	assert.Equal(t, "invite", cfg.Registration.Mode)
+	assert.Equal(t, []string{"example.com", "corp.example.org"}, cfg.Registration.AllowedDomains)
+}
+
+func TestLoad_InvalidRegistration(t *testing.T) {
+	for _, registration := range []string{
+		`{"mode": "approval"}`,
+		`{"allowed_domains": ["localhost"]}`,
+		`{"allowed_domains": ["*.example.com"]}`,
+	} {
+		j := `{"database": {"host":"h"}, "jwt_secret": "s", "port": 80, "registration": ` + registration + `}`
+		_, err := Load(writeConfig(t, j))
+		assert.ErrorIs(t, err, errInvalidRegistration, registration)
+	}
+}
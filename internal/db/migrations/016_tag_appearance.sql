@@ -0,0 +1,14 @@
+-- Tag appearance: optional color (#rgb/#rrggbb), free-form description and an
+-- icon (emoji or icon name). Empty strings mean "not set" so existing rows
+-- need no backfill.
+ALTER TABLE tags
+    ADD COLUMN IF NOT EXISTS color TEXT NOT NULL DEFAULT '';
+ALTER TABLE tags
+    ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '';
+ALTER TABLE tags
+    ADD COLUMN IF NOT EXISTS icon TEXT NOT NULL DEFAULT '';
+
+-- Notes imports stage the appearance of exported tags on the job so that
+-- tags created by the import keep their color, description and icon.
+ALTER TABLE import_jobs
+    ADD COLUMN IF NOT EXISTS tag_meta_json TEXT NOT NULL DEFAULT '[]';
@@ -0,0 +1,17 @@
+-- Recurring todos: recurrence_json holds the rule (empty = one-off), every
+-- occurrence of a series shares series_id and carries its 1-based index, and
+-- next_id points at the occurrence generated when this one was completed.
+ALTER TABLE todos
+    ADD COLUMN IF NOT EXISTS recurrence_json TEXT NOT NULL DEFAULT '';
+ALTER TABLE todos
+    ADD COLUMN IF NOT EXISTS series_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE todos
+    ADD COLUMN IF NOT EXISTS occurrence INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE todos
+    ADD COLUMN IF NOT EXISTS next_id TEXT NOT NULL DEFAULT '';
+
+-- Series heads (recurring, nothing generated yet) are the rows expanded into
+-- virtual occurrences for calendar ranges.
+CREATE INDEX IF NOT EXISTS idx_todos_user_recurring_head
+    ON todos(user_id, due_date)
+    WHERE recurrence_json <> '' AND next_id = '';
@@ -0,0 +1,21 @@
+-- Todo priority (0 none .. 3 high), an optional link to the document the
+-- todo came from, and one level of parent/child subtasks. Empty strings mean
+-- "not linked" so existing rows need no backfill.
+ALTER TABLE todos
+    ADD COLUMN IF NOT EXISTS priority INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE todos
+    ADD COLUMN IF NOT EXISTS document_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE todos
+    ADD COLUMN IF NOT EXISTS parent_id TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE todos
+    DROP CONSTRAINT IF EXISTS chk_todos_priority;
+ALTER TABLE todos
+    ADD CONSTRAINT chk_todos_priority CHECK (priority BETWEEN 0 AND 3);
+
+CREATE INDEX IF NOT EXISTS idx_todos_user_document
+    ON todos(user_id, document_id)
+    WHERE document_id <> '';
+CREATE INDEX IF NOT EXISTS idx_todos_user_parent
+    ON todos(user_id, parent_id)
+    WHERE parent_id <> '';
@@ -0,0 +1,25 @@
+-- Per-user schedules that create documents from templates. next_run_at is
+-- the next cron slot still to be produced; a run claims a slot by moving it
+-- forward in the same transaction that creates the document, so retries and
+-- restarts never create a slot twice.
+CREATE TABLE IF NOT EXISTS template_schedules (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    template_id TEXT NOT NULL,
+    cron_spec TEXT NOT NULL,
+    title_pattern TEXT NOT NULL DEFAULT '',
+    tag_ids_json TEXT NOT NULL DEFAULT '[]',
+    enabled INTEGER NOT NULL DEFAULT 1,
+    next_run_at BIGINT NOT NULL DEFAULT 0,
+    last_run_at BIGINT NOT NULL DEFAULT 0,
+    last_document_id TEXT NOT NULL DEFAULT '',
+    last_error TEXT NOT NULL DEFAULT '',
+    ctime BIGINT NOT NULL,
+    mtime BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_template_schedules_user_ctime
+    ON template_schedules(user_id, ctime);
+CREATE INDEX IF NOT EXISTS idx_template_schedules_due
+    ON template_schedules(next_run_at)
+    WHERE enabled = 1;
@@ -0,0 +1,21 @@
+-- Built-in templates shipped with the binary. Rows are seeded at startup and
+-- only replaced by a higher version, so upgrades refresh them. Templates
+-- copied from the gallery remember their source slug and version.
+CREATE TABLE IF NOT EXISTS template_gallery (
+    slug TEXT PRIMARY KEY,
+    version INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    description TEXT NOT NULL DEFAULT '',
+    content TEXT NOT NULL,
+    variables_json TEXT NOT NULL DEFAULT '[]',
+    tag_names_json TEXT NOT NULL DEFAULT '[]',
+    ctime BIGINT NOT NULL,
+    mtime BIGINT NOT NULL
+);
+
+ALTER TABLE templates
+    ADD COLUMN IF NOT EXISTS built_in INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE templates
+    ADD COLUMN IF NOT EXISTS source_slug TEXT NOT NULL DEFAULT '';
+ALTER TABLE templates
+    ADD COLUMN IF NOT EXISTS source_version INTEGER NOT NULL DEFAULT 0;
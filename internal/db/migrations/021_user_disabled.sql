@@ -0,0 +1,4 @@
+-- Operators can disable an account from the admin CLI. Disabled users can
+-- neither sign in nor use tokens issued before they were disabled.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS disabled INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,28 @@
+-- Users get a role so that instance administrators can manage accounts from
+-- the web API. Invite codes back the invite-only registration policy; only
+-- the SHA-256 of a code is stored. OAuth states carry the digest of the
+-- invite entered before the provider redirect.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'user';
+
+ALTER TABLE users
+    DROP CONSTRAINT IF EXISTS chk_users_role;
+ALTER TABLE users
+    ADD CONSTRAINT chk_users_role CHECK (role IN ('user', 'admin'));
+
+CREATE TABLE IF NOT EXISTS invite_codes (
+    id TEXT PRIMARY KEY,
+    code_digest TEXT NOT NULL UNIQUE,
+    created_by TEXT NOT NULL,
+    note TEXT NOT NULL DEFAULT '',
+    expires_at BIGINT NOT NULL DEFAULT 0,
+    used_by TEXT NOT NULL DEFAULT '',
+    used_at BIGINT NOT NULL DEFAULT 0,
+    ctime BIGINT NOT NULL,
+    CONSTRAINT chk_invite_codes_note_length CHECK (char_length(note) <= 200)
+);
+
+CREATE INDEX IF NOT EXISTS idx_invite_codes_ctime ON invite_codes(ctime DESC);
+
+ALTER TABLE oauth_one_time_tokens
+    ADD COLUMN IF NOT EXISTS invite_digest TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,25 @@
+-- Team workspaces let several users co-own notes. Rows of documents, tags,
+-- templates and assets (and everything hanging off them) keep their owning
+-- scope in user_id: the user's own ID for the personal workspace, the
+-- workspace ID for a team workspace. The personal workspace is implicit and
+-- has no row here, so existing data needs no backfill.
+CREATE TABLE IF NOT EXISTS workspaces (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    owner_id TEXT NOT NULL,
+    ctime BIGINT NOT NULL,
+    mtime BIGINT NOT NULL,
+    CONSTRAINT chk_workspaces_name_length CHECK (char_length(name) BETWEEN 1 AND 100)
+);
+
+CREATE TABLE IF NOT EXISTS workspace_members (
+    workspace_id TEXT NOT NULL REFERENCES workspaces(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL,
+    role TEXT NOT NULL,
+    ctime BIGINT NOT NULL,
+    mtime BIGINT NOT NULL,
+    PRIMARY KEY (workspace_id, user_id),
+    CONSTRAINT chk_workspace_members_role CHECK (role IN ('owner', 'editor', 'viewer'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_workspace_members_user ON workspace_members(user_id);
@@ -0,0 +1,25 @@
+-- Document collaborators give another registered user view or edit access to
+-- a single document without sharing a whole workspace. owner_id is the
+-- owning scope of the document (see 023), user_id the invited user.
+CREATE TABLE IF NOT EXISTS document_collaborators (
+    document_id TEXT NOT NULL,
+    owner_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    role TEXT NOT NULL,
+    ctime BIGINT NOT NULL,
+    mtime BIGINT NOT NULL,
+    PRIMARY KEY (document_id, user_id),
+    CONSTRAINT fk_document_collaborators_document
+        FOREIGN KEY (owner_id, document_id) REFERENCES documents(user_id, id) ON DELETE CASCADE,
+    CONSTRAINT chk_document_collaborators_role CHECK (role IN ('editor', 'viewer'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_collaborators_user ON document_collaborators(user_id);
+
+-- author_id records the user who wrote a version. The backfill below sets it
+-- only for versions in a personal scope, whose user_id is a user and who was
+-- the only possible author; any other version keeps an empty author.
+ALTER TABLE document_versions ADD COLUMN IF NOT EXISTS author_id TEXT NOT NULL DEFAULT '';
+
+UPDATE document_versions v SET author_id = v.user_id
+WHERE v.author_id = '' AND EXISTS (SELECT 1 FROM users u WHERE u.id = v.user_id);
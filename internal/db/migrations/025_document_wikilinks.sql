@@ -0,0 +1,26 @@
+-- Wikilinks ([[Title]], [[Title|alias]], [[Title#Heading]]) resolve by title
+-- inside the owner's scope. anchor keeps the heading or ^block anchor of the
+-- wikilink that produced a link; /docs/<id> links leave it empty.
+ALTER TABLE document_links ADD COLUMN IF NOT EXISTS anchor TEXT NOT NULL DEFAULT '';
+
+-- document_wikilinks records every wikilink of a document with its outcome,
+-- so that creating or renaming a document can re-resolve the missing and
+-- ambiguous titles that now match it. title_key is the case-folded title.
+CREATE TABLE IF NOT EXISTS document_wikilinks (
+    source_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    title TEXT NOT NULL,
+    title_key TEXT NOT NULL,
+    anchor TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL,
+    target_id TEXT NOT NULL DEFAULT '',
+    ctime BIGINT NOT NULL,
+    PRIMARY KEY (source_id, title_key, anchor),
+    CONSTRAINT fk_document_wikilinks_source
+        FOREIGN KEY (user_id, source_id) REFERENCES documents(user_id, id) ON DELETE CASCADE,
+    CONSTRAINT chk_document_wikilinks_status CHECK (status IN ('resolved', 'missing', 'ambiguous'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_wikilinks_title ON document_wikilinks(user_id, title_key);
+CREATE INDEX IF NOT EXISTS idx_document_wikilinks_target ON document_wikilinks(user_id, target_id);
+CREATE INDEX IF NOT EXISTS idx_documents_user_title_key ON documents(user_id, lower(btrim(title)));
@@ -0,0 +1,15 @@
+-- document_broken_links keeps the /docs/<id> references a save could not turn
+-- into document_links because the target is deleted, missing or belongs to
+-- another scope. They are rebuilt with the links on every save and only feed
+-- the broken link report; target_id carries no foreign key on purpose.
+CREATE TABLE IF NOT EXISTS document_broken_links (
+    source_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    target_id TEXT NOT NULL,
+    ctime BIGINT NOT NULL,
+    PRIMARY KEY (source_id, target_id),
+    CONSTRAINT fk_document_broken_links_source
+        FOREIGN KEY (user_id, source_id) REFERENCES documents(user_id, id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_broken_links_user ON document_broken_links(user_id);
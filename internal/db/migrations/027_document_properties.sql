@@ -0,0 +1,21 @@
+-- document_properties holds the YAML front matter of a document, one row per
+-- scalar value: a list field contributes one row per item, all sharing key.
+-- position keeps the front matter order. key is case-folded; aliases rows
+-- also name the document for wikilink and mention resolution.
+CREATE TABLE IF NOT EXISTS document_properties (
+    document_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    key TEXT NOT NULL,
+    value_type TEXT NOT NULL,
+    value TEXT NOT NULL,
+    ctime BIGINT NOT NULL,
+    PRIMARY KEY (document_id, position),
+    CONSTRAINT fk_document_properties_document
+        FOREIGN KEY (user_id, document_id) REFERENCES documents(user_id, id) ON DELETE CASCADE,
+    CONSTRAINT chk_document_properties_type
+        CHECK (value_type IN ('string', 'number', 'bool', 'date', 'list'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_document_properties_value
+    ON document_properties(user_id, key, lower(btrim(value)));
@@ -0,0 +1,7 @@
+-- word_count records the words of each saved version so that activity can
+-- report words added and removed per day without reading version bodies.
+-- Versions saved before this migration keep NULL and count as edits only.
+ALTER TABLE document_versions ADD COLUMN IF NOT EXISTS word_count INTEGER;
+
+CREATE INDEX IF NOT EXISTS idx_document_versions_user_ctime
+    ON document_versions(user_id, ctime);
@@ -0,0 +1,12 @@
+-- Task list items of a document are synced into todos on save. source_anchor
+-- identifies the item within its document and stays put while lines move;
+-- source_line is its 1-based line as of the last save. Todos created by hand
+-- keep an empty anchor, so existing rows need no backfill.
+ALTER TABLE todos
+    ADD COLUMN IF NOT EXISTS source_anchor TEXT NOT NULL DEFAULT '';
+ALTER TABLE todos
+    ADD COLUMN IF NOT EXISTS source_line INTEGER NOT NULL DEFAULT 0;
+
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_todos_document_anchor
+    ON todos(user_id, document_id, source_anchor)
+    WHERE source_anchor <> '';
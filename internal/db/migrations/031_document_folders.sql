@@ -0,0 +1,25 @@
+-- folders arrange the documents of a user in a tree. parent_id is empty for a
+-- top-level folder and position orders the children of one parent. Names are
+-- unique among siblings regardless of case.
+CREATE TABLE IF NOT EXISTS folders (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    parent_id TEXT NOT NULL DEFAULT '',
+    name TEXT NOT NULL,
+    position INTEGER NOT NULL DEFAULT 0,
+    ctime BIGINT NOT NULL,
+    mtime BIGINT NOT NULL
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS uniq_folders_user_parent_name
+    ON folders(user_id, parent_id, lower(name));
+CREATE INDEX IF NOT EXISTS idx_folders_user_parent_position
+    ON folders(user_id, parent_id, position);
+
+-- A document belongs to at most one folder; an empty folder_id keeps it at
+-- the top level, so existing rows need no backfill.
+ALTER TABLE documents
+    ADD COLUMN IF NOT EXISTS folder_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_documents_user_folder
+    ON documents(user_id, folder_id);
@@ -0,0 +1,87 @@
+// Package gallery holds the built-in templates shipped with the binary.
+// templates/manifest.json lists every template with its metadata and the
+// markdown file holding its content. Bump a template's version whenever its
+// content or metadata changes so existing installations pick it up on the
+// next start.
+package gallery
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+
+	"github.com/xxxsen/mnote/internal/model"
+)
+
+//go:embed templates/*
+var templatesFS embed.FS
+
+const (
+	templatesDir = "templates"
+	manifestFile = "manifest.json"
+)
+
+var (
+	errInvalidEntry  = errors.New("invalid gallery entry")
+	errDuplicateSlug = errors.New("duplicate gallery slug")
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(?:-[a-z0-9]+)*$`)
+
+type manifestEntry struct {
+	Slug        string                   `json:"slug"`
+	Version     int                      `json:"version"`
+	Name        string                   `json:"name"`
+	Description string                   `json:"description"`
+	File        string                   `json:"file"`
+	Variables   []model.TemplateVariable `json:"variables"`
+	Tags        []string                 `json:"tags"`
+}
+
+// Load returns the built-in templates in manifest order.
+func Load() ([]model.GalleryTemplate, error) {
+	raw, err := templatesFS.ReadFile(path.Join(templatesDir, manifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("read gallery manifest: %w", err)
+	}
+	var entries []manifestEntry
+	if err := json.Unmarshal(raw, &entries); err != nil {
+		return nil, fmt.Errorf("decode gallery manifest: %w", err)
+	}
+	items := make([]model.GalleryTemplate, 0, len(entries))
+	seen := make(map[string]struct{}, len(entries))
+	for _, entry := range entries {
+		if !slugPattern.MatchString(entry.Slug) || entry.Version <= 0 || entry.Name == "" || entry.File == "" {
+			return nil, fmt.Errorf("%w: %q", errInvalidEntry, entry.Slug)
+		}
+		if _, ok := seen[entry.Slug]; ok {
+			return nil, fmt.Errorf("%w: %s", errDuplicateSlug, entry.Slug)
+		}
+		seen[entry.Slug] = struct{}{}
+		content, err := templatesFS.ReadFile(path.Join(templatesDir, entry.File))
+		if err != nil {
+			return nil, fmt.Errorf("read gallery template %s: %w", entry.Slug, err)
+		}
+		variables := entry.Variables
+		if variables == nil {
+			variables = []model.TemplateVariable{}
+		}
+		tags := entry.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		items = append(items, model.GalleryTemplate{
+			Slug:        entry.Slug,
+			Version:     entry.Version,
+			Name:        entry.Name,
+			Description: entry.Description,
+			Content:     string(content),
+			Variables:   variables,
+			Tags:        tags,
+		})
+	}
+	return items, nil
+}
@@ -0,0 +1,22 @@
+package gallery
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	items, err := Load()
+	require.NoError(t, err)
+	require.NotEmpty(t, items)
+	for _, item := range items {
+		assert.Positive(t, item.Version, item.Slug)
+		assert.NotEmpty(t, strings.TrimSpace(item.Content), item.Slug)
+		assert.NotNil(t, item.Variables, item.Slug)
+		assert.NotNil(t, item.Tags, item.Slug)
+	}
+	assert.Equal(t, "daily-note", items[0].Slug)
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/errcode"
+	"github.com/xxxsen/mnote/internal/pkg/response"
+)
+
+// AdminHandler serves the user management API. Its routes are only reachable
+// by users with the admin role.
+type AdminHandler struct {
+	admin   IAdminHandlerService
+	invites IInviteService
+}
+
+func NewAdminHandler(admin IAdminHandlerService, invites IInviteService) *AdminHandler {
+	return &AdminHandler{admin: admin, invites: invites}
+}
+
+func (h *AdminHandler) ListUsers(c *gin.Context) {
+	users, err := h.admin.ListUsers(c.Request.Context())
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, users)
+}
+
+func (h *AdminHandler) DisableUser(c *gin.Context) {
+	h.setDisabled(c, true)
+}
+
+func (h *AdminHandler) EnableUser(c *gin.Context) {
+	h.setDisabled(c, false)
+}
+
+func (h *AdminHandler) setDisabled(c *gin.Context, disabled bool) {
+	userID := c.Param("id")
+	if disabled && userID == getUserID(c) {
+		response.Error(c, errcode.ErrInvalid, "cannot disable yourself")
+		return
+	}
+	user, err := h.admin.SetDisabled(c.Request.Context(), userID, disabled)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, user)
+}
+
+func (h *AdminHandler) Usage(c *gin.Context) {
+	stats, err := h.admin.Stats(c.Request.Context())
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, stats)
+}
+
+type createInviteRequest struct {
+	Note         string `json:"note"`
+	ExpiresHours int    `json:"expires_hours"`
+}
+
+type inviteResponse struct {
+	model.InviteCode
+	Code string `json:"code"`
+}
+
+func (h *AdminHandler) CreateInvite(c *gin.Context) {
+	var req createInviteRequest
+	if err := bindJSON(c, &req); err != nil || req.ExpiresHours < 0 {
+		response.Error(c, errcode.ErrInvalid, "invalid request")
+		return
+	}
+	invite, code, err := h.invites.CreateInvite(
+		c.Request.Context(), getUserID(c), req.Note, time.Duration(req.ExpiresHours)*time.Hour,
+	)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, inviteResponse{InviteCode: *invite, Code: code})
+}
+
+func (h *AdminHandler) ListInvites(c *gin.Context) {
+	invites, err := h.invites.ListInvites(c.Request.Context())
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, invites)
+}
+
+func (h *AdminHandler) DeleteInvite(c *gin.Context) {
+	if err := h.invites.DeleteInvite(c.Request.Context(), c.Param("id")); err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, gin.H{"ok": true})
+}
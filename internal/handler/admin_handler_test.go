@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/errcode"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+func TestAdminHandler_RequireAdmin(t *testing.T) {
+	auth := &AuthHandler{auth: &mockAuthService{
+		ensureAdminFn: func(_ context.Context, userID string) error {
+			if userID == "admin" {
+				return nil
+			}
+			return appErr.ErrForbidden
+		},
+	}}
+	h := NewAdminHandler(&mockAdminHandlerService{
+		listUsersFn: func(context.Context) ([]model.User, error) {
+			return []model.User{{ID: "u1", Role: model.UserRoleUser}}, nil
+		},
+	}, &mockInviteService{})
+	for userID, code := range map[string]uint32{"admin": 0, "u1": errcode.ErrForbidden} {
+		r := newTestRouter()
+		r.GET("/admin/users", withUserID(userID), auth.RequireAdmin, h.ListUsers)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/admin/users", nil))
+		assert.Equal(t, float64(code), parseResponseT(t, w)["code"], userID)
+	}
+}
+
+func TestAdminHandler_DisableUser(t *testing.T) {
+	var got string
+	h := NewAdminHandler(&mockAdminHandlerService{
+		setDisabledFn: func(_ context.Context, ref string, disabled bool) (*model.User, error) {
+			assert.True(t, disabled)
+			got = ref
+			return &model.User{ID: ref, Disabled: 1}, nil
+		},
+	}, &mockInviteService{})
+	r := newTestRouter()
+	r.POST("/admin/users/:id/disable", withUserID("admin"), h.DisableUser)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/admin/users/u2/disable", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "u2", got)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/admin/users/admin/disable", nil))
+	assert.NotEqual(t, float64(0), parseResponseT(t, w)["code"], "admins cannot disable themselves")
+}
+
+func TestAdminHandler_Usage(t *testing.T) {
+	h := NewAdminHandler(&mockAdminHandlerService{
+		statsFn: func(context.Context) ([]model.UserStats, error) {
+			return []model.UserStats{{UserID: "u1", Documents: 3}}, nil
+		},
+	}, &mockInviteService{})
+	r := newTestRouter()
+	r.GET("/admin/usage", withUserID("admin"), h.Usage)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/admin/usage", nil))
+	resp := parseResponseT(t, w)
+	require.Equal(t, float64(0), resp["code"])
+	items, ok := resp["data"].([]any)
+	require.True(t, ok)
+	assert.Len(t, items, 1)
+}
+
+func TestAdminHandler_CreateInvite(t *testing.T) {
+	h := NewAdminHandler(&mockAdminHandlerService{}, &mockInviteService{
+		createFn: func(_ context.Context, createdBy, note string, ttl time.Duration) (*model.InviteCode, string, error) {
+			assert.Equal(t, "admin", createdBy)
+			assert.Equal(t, "for bob", note)
+			assert.Equal(t, 48*time.Hour, ttl)
+			return &model.InviteCode{ID: "i1", CodeDigest: "digest", Note: note}, "plain-code", nil
+		},
+	})
+	r := newTestRouter()
+	r.POST("/admin/invites", withUserID("admin"), h.CreateInvite)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, "POST", "/admin/invites", map[string]any{"note": "for bob", "expires_hours": 48}))
+	resp := parseResponseT(t, w)
+	require.Equal(t, float64(0), resp["code"])
+	data, ok := resp["data"].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "plain-code", data["code"])
+	assert.Equal(t, "i1", data["id"])
+	assert.NotContains(t, data, "code_digest")
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, "POST", "/admin/invites", map[string]any{"expires_hours": -1}))
+	assert.NotEqual(t, float64(0), parseResponseT(t, w)["code"])
+}
+
+func TestAdminHandler_DeleteInvite(t *testing.T) {
+	h := NewAdminHandler(&mockAdminHandlerService{}, &mockInviteService{
+		deleteFn: func(_ context.Context, id string) error {
+			if id == "i1" {
+				return nil
+			}
+			return appErr.ErrNotFound
+		},
+	})
+	r := newTestRouter()
+	r.DELETE("/admin/invites/:id", withUserID("admin"), h.DeleteInvite)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("DELETE", "/admin/invites/i1", nil))
+	assert.Equal(t, float64(0), parseResponseT(t, w)["code"])
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("DELETE", "/admin/invites/missing", nil))
+	assert.Equal(t, float64(errcode.ErrNotFound), parseResponseT(t, w)["code"])
+}
@@ -21,6 +21,7 @@ type authRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 	Code     string `json:"code"`
+	Invite   string `json:"invite"`
 }
 
 type sendCodeRequest struct {
@@ -51,7 +52,7 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		response.Error(c, errcode.ErrInvalid, "invalid request")
 		return
 	}
-	user, token, err := h.auth.Register(c.Request.Context(), req.Email, req.Password, req.Code)
+	user, token, err := h.auth.Register(c.Request.Context(), req.Email, req.Password, req.Code, req.Invite)
 	if err != nil {
 		handleError(c, err)
 		return
@@ -108,6 +109,17 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	response.Success(c, gin.H{"ok": true})
 }
 
+// RequireActive runs after JWT authentication and rejects requests of users
+// that were deleted or disabled since their token was issued.
+func (h *AuthHandler) RequireActive(c *gin.Context) {
+	if err := h.auth.EnsureActive(c.Request.Context(), getUserID(c)); err != nil {
+		handleError(c, err)
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
 func (h *AuthHandler) UpdatePassword(c *gin.Context) {
 	var req passwordUpdateRequest
 	if err := bindJSON(c, &req); err != nil {
@@ -124,3 +136,14 @@ func (h *AuthHandler) UpdatePassword(c *gin.Context) {
 func isEmailValid(email string) bool {
 	return strings.Count(email, "@") == 1
 }
+
+// RequireAdmin runs after RequireActive and rejects users without the admin
+// role.
+func (h *AuthHandler) RequireAdmin(c *gin.Context) {
+	if err := h.auth.EnsureAdmin(c.Request.Context(), getUserID(c)); err != nil {
+		handleError(c, err)
+		c.Abort()
+		return
+	}
+	c.Next()
+}
@@ -7,15 +7,18 @@ import (
 	"net/http/httptest"
 	"testing"
 
+	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
 	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/errcode"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
 )
 
 func TestAuthHandler_Register_Success(t *testing.T) {
 	mock := &mockAuthService{
-		registerFn: func(_ context.Context, email, _, _ string) (*model.User, string, error) {
+		registerFn: func(_ context.Context, email, _, _, _ string) (*model.User, string, error) {
 			return &model.User{ID: "u1", Email: email}, "jwt-token", nil
 		},
 	}
@@ -100,7 +103,7 @@ func TestAuthHandler_Register_MissingCode(t *testing.T) {
 
 func TestAuthHandler_Register_ServiceError(t *testing.T) {
 	mock := &mockAuthService{
-		registerFn: func(_ context.Context, _, _, _ string) (*model.User, string, error) {
+		registerFn: func(_ context.Context, _, _, _, _ string) (*model.User, string, error) {
 			return nil, "", errors.New("conflict")
 		},
 	}
@@ -355,3 +358,27 @@ func TestAuthHandler_UpdatePassword_ServiceError(t *testing.T) {
 	resp := parseResponseT(t, w)
 	assert.NotEqual(t, float64(0), resp["code"])
 }
+
+func TestAuthHandler_RequireActive(t *testing.T) {
+	mock := &mockAuthService{
+		ensureActiveFn: func(_ context.Context, userID string) error {
+			if userID == "disabled" {
+				return appErr.ErrForbidden
+			}
+			return nil
+		},
+	}
+	h := &AuthHandler{auth: mock}
+	r := newTestRouter()
+	r.GET("/active", withUserID("u1"), h.RequireActive, func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	r.GET("/disabled", withUserID("disabled"), h.RequireActive, func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/active", nil))
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/disabled", nil))
+	resp := parseResponseT(t, w)
+	assert.Equal(t, float64(errcode.ErrForbidden), resp["code"])
+}
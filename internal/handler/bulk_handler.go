@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/xxxsen/common/logutil"
+	"go.uber.org/zap"
+
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/errcode"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+	"github.com/xxxsen/mnote/internal/pkg/response"
+	"github.com/xxxsen/mnote/internal/service"
+)
+
+type BulkHandler struct {
+	bulk IBulkService
+}
+
+func NewBulkHandler(bulk IBulkService) *BulkHandler {
+	return &BulkHandler{bulk: bulk}
+}
+
+// bulkQueryRequest takes the filters of the document list. FolderID is a
+// pointer because an empty folder_id selects the top level while a missing
+// one spans all folders.
+type bulkQueryRequest struct {
+	Query     string              `json:"q"`
+	TagID     string              `json:"tag_id"`
+	Starred   *int                `json:"starred"`
+	FolderID  *string             `json:"folder_id"`
+	Recursive bool                `json:"recursive"`
+	Props     map[string][]string `json:"props"`
+}
+
+type bulkRequest struct {
+	Op       string            `json:"op"`
+	IDs      []string          `json:"ids"`
+	Query    *bulkQueryRequest `json:"query"`
+	TagIDs   []string          `json:"tag_ids"`
+	FolderID string            `json:"folder_id"`
+}
+
+func (req *bulkQueryRequest) toQuery() (*service.BulkQuery, error) {
+	if req.Starred != nil && *req.Starred != 0 && *req.Starred != 1 {
+		return nil, appErr.ErrInvalid
+	}
+	query := &service.BulkQuery{Query: req.Query, TagID: req.TagID, Starred: req.Starred}
+	if req.FolderID != nil {
+		query.Folder = &model.FolderFilter{ID: *req.FolderID, Recursive: req.Recursive}
+	}
+	keys := make([]string, 0, len(req.Props))
+	for key := range req.Props {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		query.Props = append(query.Props, model.PropertyFilter{Key: key, Values: req.Props[key]})
+	}
+	return query, nil
+}
+
+// Apply runs one operation over a list of document IDs or over every
+// document matching a list query. The export operation answers with a notes
+// zip of the selection; the others report a result per document.
+func (h *BulkHandler) Apply(c *gin.Context) {
+	var req bulkRequest
+	if err := bindJSON(c, &req); err != nil {
+		response.Error(c, errcode.ErrInvalid, "invalid request")
+		return
+	}
+	selection := service.BulkSelection{IDs: req.IDs}
+	if req.Query != nil {
+		query, err := req.Query.toQuery()
+		if err != nil {
+			response.Error(c, errcode.ErrInvalid, "invalid query")
+			return
+		}
+		selection.Query = query
+	}
+	if req.Op == service.BulkOpExport {
+		h.export(c, selection)
+		return
+	}
+	userID := getUserID(c)
+	results, err := h.bulk.Apply(c.Request.Context(), userID, service.BulkRequest{
+		Op: req.Op, BulkSelection: selection, TagIDs: req.TagIDs, FolderID: req.FolderID,
+	})
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	for _, result := range results {
+		if result.Err != nil && appErr.Normalize(result.Err).Code() == errcode.ErrInternal {
+			logutil.GetLogger(c.Request.Context()).Error(
+				"bulk operation failed",
+				zap.String("user_id", userID),
+				zap.String("op", req.Op),
+				zap.String("document_id", result.ID),
+				zap.Error(result.Err),
+			)
+		}
+	}
+	response.Success(c, toBulkResponse(req.Op, results))
+}
+
+func (h *BulkHandler) export(c *gin.Context, selection service.BulkSelection) {
+	path, err := h.bulk.Export(c.Request.Context(), getUserID(c), selection)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	defer func() {
+		_ = os.Remove(path)
+	}()
+	fileName := fmt.Sprintf("mnote-notes-%s.zip", time.Now().Format("20060102-150405"))
+	c.FileAttachment(path, fileName)
+}
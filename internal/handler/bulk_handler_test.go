@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/errcode"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+	"github.com/xxxsen/mnote/internal/service"
+)
+
+func TestBulkHandler_Apply(t *testing.T) {
+	var got service.BulkRequest
+	mock := &mockBulkService{
+		applyFn: func(_ context.Context, _ string, req service.BulkRequest) ([]service.BulkItemResult, error) {
+			got = req
+			return []service.BulkItemResult{
+				{ID: "d1"},
+				{ID: "d2", Err: appErr.ErrNotFound},
+				{ID: "d3", Err: errors.New("db down")},
+			}, nil
+		},
+	}
+	h := &BulkHandler{bulk: mock}
+	r := newTestRouter()
+	r.POST("/documents/bulk", withUserID("u1"), h.Apply)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, "POST", "/documents/bulk", map[string]any{
+		"op": "add_tags", "ids": []string{"d1", "d2", "d3"}, "tag_ids": []string{"t1"},
+	}))
+	resp := parseResponseT(t, w)
+	require.Equal(t, float64(0), resp["code"])
+	assert.Equal(t, service.BulkOpAddTags, got.Op)
+	assert.Equal(t, []string{"d1", "d2", "d3"}, got.IDs)
+	assert.Nil(t, got.Query)
+	assert.Equal(t, []string{"t1"}, got.TagIDs)
+
+	data, ok := resp["data"].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, float64(3), data["total"])
+	assert.Equal(t, float64(1), data["succeeded"])
+	assert.Equal(t, float64(2), data["failed"])
+	items, ok := data["items"].([]any)
+	require.True(t, ok)
+	require.Len(t, items, 3)
+	assert.Equal(t, map[string]any{"id": "d1", "ok": true}, items[0])
+	assert.Equal(t, map[string]any{
+		"id": "d2", "ok": false, "code": float64(errcode.ErrNotFound), "message": "not found",
+	}, items[1])
+	assert.Equal(t, map[string]any{
+		"id": "d3", "ok": false, "code": float64(errcode.ErrInternal), "message": "internal error",
+	}, items[2])
+}
+
+func TestBulkHandler_Apply_Query(t *testing.T) {
+	var got service.BulkRequest
+	mock := &mockBulkService{
+		applyFn: func(_ context.Context, _ string, req service.BulkRequest) ([]service.BulkItemResult, error) {
+			got = req
+			return []service.BulkItemResult{}, nil
+		},
+	}
+	h := &BulkHandler{bulk: mock}
+	r := newTestRouter()
+	r.POST("/documents/bulk", withUserID("u1"), h.Apply)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, "POST", "/documents/bulk", map[string]any{
+		"op": "move", "folder_id": "f2",
+		"query": map[string]any{
+			"q": "meeting", "starred": 1, "folder_id": "", "recursive": true,
+			"props": map[string][]string{"status": {"draft"}, "owner": {"me"}},
+		},
+	}))
+	require.Equal(t, float64(0), parseResponseT(t, w)["code"])
+	require.NotNil(t, got.Query)
+	assert.Equal(t, "f2", got.FolderID)
+	assert.Equal(t, "meeting", got.Query.Query)
+	require.NotNil(t, got.Query.Starred)
+	assert.Equal(t, 1, *got.Query.Starred)
+	assert.Equal(t, &model.FolderFilter{Recursive: true}, got.Query.Folder)
+	assert.Equal(t, []model.PropertyFilter{
+		{Key: "owner", Values: []string{"me"}},
+		{Key: "status", Values: []string{"draft"}},
+	}, got.Query.Props)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, "POST", "/documents/bulk", map[string]any{
+		"op": "pin", "query": map[string]any{"starred": 2},
+	}))
+	assert.Equal(t, float64(errcode.ErrInvalid), parseResponseT(t, w)["code"])
+}
+
+func TestBulkHandler_Apply_Error(t *testing.T) {
+	mock := &mockBulkService{
+		applyFn: func(context.Context, string, service.BulkRequest) ([]service.BulkItemResult, error) {
+			return nil, appErr.ErrInvalid
+		},
+	}
+	h := &BulkHandler{bulk: mock}
+	r := newTestRouter()
+	r.POST("/documents/bulk", withUserID("u1"), h.Apply)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, "POST", "/documents/bulk", map[string]any{"op": "rename", "ids": []string{"d1"}}))
+	assert.Equal(t, float64(errcode.ErrInvalid), parseResponseT(t, w)["code"])
+}
+
+func TestBulkHandler_Export(t *testing.T) {
+	tmp, err := os.CreateTemp("", "bulk-export-*.zip")
+	require.NoError(t, err)
+	_, err = tmp.WriteString("zip")
+	require.NoError(t, err)
+	require.NoError(t, tmp.Close())
+
+	mock := &mockBulkService{
+		exportFn: func(_ context.Context, _ string, selection service.BulkSelection) (string, error) {
+			assert.Equal(t, []string{"d1"}, selection.IDs)
+			return tmp.Name(), nil
+		},
+	}
+	h := &BulkHandler{bulk: mock}
+	r := newTestRouter()
+	r.POST("/documents/bulk", withUserID("u1"), h.Apply)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, "POST", "/documents/bulk", map[string]any{"op": "export", "ids": []string{"d1"}}))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "mnote-notes-")
+	assert.Equal(t, "zip", w.Body.String())
+	_, err = os.Stat(tmp.Name())
+	assert.True(t, os.IsNotExist(err))
+}
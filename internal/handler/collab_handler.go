@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/xxxsen/common/logutil"
+	"go.uber.org/zap"
+
+	"github.com/xxxsen/mnote/internal/pkg/errcode"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+	"github.com/xxxsen/mnote/internal/pkg/ot"
+	"github.com/xxxsen/mnote/internal/service"
+)
+
+const (
+	collabWriteWait  = 10 * time.Second
+	collabPongWait   = 60 * time.Second
+	collabPingPeriod = collabPongWait * 9 / 10
+)
+
+type CollabHandler struct {
+	hub      ICollabHub
+	upgrader websocket.Upgrader
+}
+
+// NewCollabHandler builds the handler. allowOrigins lists the origins
+// besides the site itself whose pages may open a session, as configured for
+// CORS.
+func NewCollabHandler(hub ICollabHub, allowOrigins []string) *CollabHandler {
+	allowed := make(map[string]struct{}, len(allowOrigins))
+	for _, origin := range allowOrigins {
+		if trimmed := strings.TrimSpace(origin); trimmed != "" {
+			allowed[trimmed] = struct{}{}
+		}
+	}
+	return &CollabHandler{
+		hub: hub,
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool { return collabOriginAllowed(r, allowed) },
+		},
+	}
+}
+
+// collabOriginAllowed accepts handshakes from the site itself and from the
+// allowed origins, and those without an Origin, which only non-browser
+// clients leave out.
+func collabOriginAllowed(r *http.Request, allowed map[string]struct{}) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if _, ok := allowed[origin]; ok {
+		return true
+	}
+	parsed, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(parsed.Host, r.Host)
+}
+
+// collabRequest is sent by a client: operation submits an edit made on top
+// of Revision, cursor moves the client's selection.
+type collabRequest struct {
+	Type      string                `json:"type"`
+	Revision  int                   `json:"revision"`
+	Operation *ot.Operation         `json:"operation"`
+	Cursor    *service.CollabCursor `json:"cursor"`
+}
+
+// Connect upgrades the request to a WebSocket joined to the document's
+// editing session. A rejected request is answered with an error message and
+// the connection is closed; the client reconnects to get a fresh snapshot.
+func (h *CollabHandler) Connect(c *gin.Context) {
+	client, err := h.hub.Join(c.Request.Context(), getUserID(c), c.Param("id"))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		h.hub.Leave(client)
+		return
+	}
+	defer func() { _ = conn.Close() }()
+	conn.SetReadLimit(maxJSONBodySize(c))
+
+	done := make(chan struct{})
+	go h.writeLoop(conn, client, done)
+	failure := h.readLoop(conn, client)
+	h.hub.Leave(client)
+	<-done
+
+	closeCode := websocket.CloseNormalClosure
+	if failure != nil {
+		normalized := appErr.Normalize(failure)
+		logutil.GetLogger(c.Request.Context()).Debug("collab request rejected",
+			zap.String("document_id", c.Param("id")),
+			zap.Uint32("error_code", normalized.Code()),
+			zap.Error(failure),
+		)
+		_ = conn.SetWriteDeadline(time.Now().Add(collabWriteWait))
+		_ = conn.WriteJSON(service.CollabMessage{
+			Type: service.CollabMessageError, Code: normalized.Code(), Error: normalized.Message(),
+		})
+		closeCode = websocket.ClosePolicyViolation
+		if normalized.Code() == errcode.ErrInternal {
+			closeCode = websocket.CloseInternalServerErr
+		}
+	}
+	_ = conn.WriteControl(websocket.CloseMessage,
+		websocket.FormatCloseMessage(closeCode, ""), time.Now().Add(collabWriteWait))
+}
+
+// readLoop handles client requests until the connection ends. It returns the
+// error that made the server reject a request, or nil when the peer left.
+func (h *CollabHandler) readLoop(conn *websocket.Conn, client *service.CollabClient) error {
+	_ = conn.SetReadDeadline(time.Now().Add(collabPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(collabPongWait))
+	})
+	for {
+		_, data, err := conn.ReadMessage()
+		if err != nil {
+			if errors.Is(err, websocket.ErrReadLimit) {
+				return appErr.ErrInvalid
+			}
+			return nil
+		}
+		var req collabRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			return appErr.ErrInvalid
+		}
+		switch req.Type {
+		case service.CollabMessageOperation:
+			if req.Operation == nil {
+				return appErr.ErrInvalid
+			}
+			if err := h.hub.Submit(client, req.Revision, *req.Operation); err != nil {
+				return err
+			}
+		case service.CollabMessageCursor:
+			if req.Cursor == nil {
+				return appErr.ErrInvalid
+			}
+			h.hub.MoveCursor(client, *req.Cursor)
+		default:
+			return appErr.ErrInvalid
+		}
+	}
+}
+
+// writeLoop is the only writer while the connection is open. It relays hub
+// messages and pings the peer until the hub closes the client's queue. On
+// the way out it unblocks readLoop, which matters when a write failed or the
+// hub dropped a client that fell behind.
+func (h *CollabHandler) writeLoop(conn *websocket.Conn, client *service.CollabClient, done chan<- struct{}) {
+	defer close(done)
+	defer func() { _ = conn.SetReadDeadline(time.Now()) }()
+	ticker := time.NewTicker(collabPingPeriod)
+	defer ticker.Stop()
+	for {
+		select {
+		case msg, ok := <-client.Messages():
+			if !ok {
+				return
+			}
+			_ = conn.SetWriteDeadline(time.Now().Add(collabWriteWait))
+			if err := conn.WriteJSON(msg); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(collabWriteWait)); err != nil {
+				return
+			}
+		}
+	}
+}
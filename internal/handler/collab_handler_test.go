@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/middleware"
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/errcode"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+	"github.com/xxxsen/mnote/internal/service"
+)
+
+type directTransactor struct{}
+
+func (directTransactor) WithinTransaction(ctx context.Context, fn func(context.Context) error) error {
+	return fn(ctx)
+}
+
+// collabStore serves a single document that u1 may edit and u2 may view.
+type collabStore struct{}
+
+func (collabStore) OpenForEditing(_ context.Context, userID, docID string) (*model.Document, bool, error) {
+	if docID != "d1" || (userID != "u1" && userID != "u2") {
+		return nil, false, appErr.ErrNotFound
+	}
+	return &model.Document{ID: "d1", UserID: "u1", Title: "Notes", Content: "ab", ContentRevision: 1}, userID == "u1", nil
+}
+
+func (collabStore) Save(
+	_ context.Context, _, _ string, _ service.DocumentUpdateInput,
+) (*model.SaveDocumentResult, error) {
+	return &model.SaveDocumentResult{Accepted: true, ContentRevision: 2}, nil
+}
+
+func newTestCollabHub() *service.CollabHub {
+	return service.NewCollabHub(collabStore{}, nil, time.Minute, service.NewRuntime(directTransactor{}))
+}
+
+func newCollabServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	h := NewCollabHandler(newTestCollabHub(), []string{"https://notes.example.com"})
+	r := newTestRouter()
+	r.GET("/documents/:id/collab", func(c *gin.Context) {
+		c.Set(middleware.ContextUserIDKey, c.Query("user"))
+		c.Next()
+	}, h.Connect)
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func dialCollab(t *testing.T, server *httptest.Server, userID string) *websocket.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/documents/d1/collab?user=" + userID
+	conn, resp, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	_ = resp.Body.Close()
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func readCollab(t *testing.T, conn *websocket.Conn, msgType string) service.CollabMessage {
+	t.Helper()
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	for {
+		var msg service.CollabMessage
+		require.NoError(t, conn.ReadJSON(&msg))
+		if msg.Type == msgType {
+			return msg
+		}
+	}
+}
+
+func TestCollabHandler_RelaysOperations(t *testing.T) {
+	server := newCollabServer(t)
+	editor := dialCollab(t, server, "u1")
+	snapshot := readCollab(t, editor, service.CollabMessageSnapshot)
+	assert.Equal(t, "ab", *snapshot.Content)
+	assert.True(t, snapshot.Writable)
+
+	viewer := dialCollab(t, server, "u2")
+	assert.False(t, readCollab(t, viewer, service.CollabMessageSnapshot).Writable)
+	readCollab(t, editor, service.CollabMessagePresence)
+
+	require.NoError(t, editor.WriteJSON(map[string]any{"type": "operation", "revision": 0, "operation": []any{2, "c"}}))
+	assert.Equal(t, 1, readCollab(t, editor, service.CollabMessageAck).Revision)
+	relayed := readCollab(t, viewer, service.CollabMessageOperation)
+	assert.Equal(t, 1, relayed.Revision)
+	assert.Equal(t, "u1", relayed.UserID)
+
+	require.NoError(t, viewer.WriteJSON(map[string]any{"type": "cursor", "cursor": map[string]int{"anchor": 1, "head": 3}}))
+	cursor := readCollab(t, editor, service.CollabMessageCursor)
+	assert.Equal(t, service.CollabCursor{Anchor: 1, Head: 3}, *cursor.Cursor)
+}
+
+func TestCollabHandler_RejectedRequestClosesConnection(t *testing.T) {
+	server := newCollabServer(t)
+	viewer := dialCollab(t, server, "u2")
+	readCollab(t, viewer, service.CollabMessageSnapshot)
+
+	require.NoError(t, viewer.WriteJSON(map[string]any{"type": "operation", "revision": 0, "operation": []any{2, "c"}}))
+	msg := readCollab(t, viewer, service.CollabMessageError)
+	assert.Equal(t, errcode.ErrForbidden, msg.Code)
+	_, _, err := viewer.ReadMessage()
+	assert.True(t, websocket.IsCloseError(err, websocket.ClosePolicyViolation), "unexpected error: %v", err)
+}
+
+func TestCollabHandler_JoinErrorIsPlainResponse(t *testing.T) {
+	server := newCollabServer(t)
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet,
+		server.URL+"/documents/d9/collab?user=u1", nil)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer func() { _ = resp.Body.Close() }()
+
+	var body struct {
+		Code uint32 `json:"code"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, errcode.ErrNotFound, body.Code)
+}
+
+func TestCollabHandler_ChecksOrigin(t *testing.T) {
+	server := newCollabServer(t)
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/documents/d1/collab?user=u1"
+	dial := func(origin string) (*websocket.Conn, *http.Response, error) {
+		return websocket.DefaultDialer.Dial(url, http.Header{"Origin": []string{origin}})
+	}
+
+	_, resp, err := dial("https://evil.example.com")
+	require.Error(t, err)
+	require.NotNil(t, resp)
+	_ = resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	for _, origin := range []string{"https://notes.example.com", server.URL} {
+		conn, resp, err := dial(origin)
+		require.NoError(t, err, origin)
+		_ = resp.Body.Close()
+		readCollab(t, conn, service.CollabMessageSnapshot)
+		_ = conn.Close()
+	}
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/xxxsen/mnote/internal/pkg/response"
+	"github.com/xxxsen/mnote/internal/service"
+)
+
+type activityDocumentResponse struct {
+	DocumentID   string `json:"document_id"`
+	Title        string `json:"title"`
+	Created      bool   `json:"created"`
+	Versions     int    `json:"versions"`
+	WordsAdded   int    `json:"words_added"`
+	WordsRemoved int    `json:"words_removed"`
+	LastCtime    int64  `json:"last_ctime"`
+}
+
+type activityDayResponse struct {
+	Date         string                     `json:"date"`
+	Created      int                        `json:"created"`
+	Edited       int                        `json:"edited"`
+	WordsAdded   int                        `json:"words_added"`
+	WordsRemoved int                        `json:"words_removed"`
+	Documents    []activityDocumentResponse `json:"documents"`
+}
+
+type activityResponse struct {
+	From     string                `json:"from"`
+	To       string                `json:"to"`
+	Timezone string                `json:"timezone"`
+	Days     []activityDayResponse `json:"days"`
+}
+
+func toActivityResponse(activity *service.Activity) activityResponse {
+	days := make([]activityDayResponse, 0, len(activity.Days))
+	for _, day := range activity.Days {
+		docs := make([]activityDocumentResponse, 0, len(day.Documents))
+		for _, doc := range day.Documents {
+			docs = append(docs, activityDocumentResponse{
+				DocumentID: doc.DocumentID, Title: doc.Title, Created: doc.Created, Versions: doc.Versions,
+				WordsAdded: doc.WordsAdded, WordsRemoved: doc.WordsRemoved, LastCtime: doc.LastCtime,
+			})
+		}
+		days = append(days, activityDayResponse{
+			Date: day.Date, Created: day.Created, Edited: day.Edited,
+			WordsAdded: day.WordsAdded, WordsRemoved: day.WordsRemoved, Documents: docs,
+		})
+	}
+	return activityResponse{From: activity.From, To: activity.To, Timezone: activity.Timezone, Days: days}
+}
+
+// Activity answers GET /activity?from=&to=&tz= with per-day writing counts
+// and the documents changed each day.
+func (h *DocumentHandler) Activity(c *gin.Context) {
+	activity, err := h.documents.Activity(
+		c.Request.Context(), getUserID(c), c.Query("from"), c.Query("to"), c.Query("tz"),
+	)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, toActivityResponse(activity))
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/pkg/errcode"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+	"github.com/xxxsen/mnote/internal/service"
+)
+
+func TestDocumentHandler_Activity(t *testing.T) {
+	mock := newDocMock()
+	mock.activityFn = func(_ context.Context, userID, from, to, timezone string) (*service.Activity, error) {
+		assert.Equal(t, "u1", userID)
+		assert.Equal(t, "2024-05-01", from)
+		assert.Equal(t, "2024-05-07", to)
+		assert.Equal(t, "Asia/Shanghai", timezone)
+		return &service.Activity{
+			From: from, To: to, Timezone: timezone,
+			Days: []service.ActivityDay{{
+				Date: "2024-05-01", Created: 1, WordsAdded: 40,
+				Documents: []service.ActivityDocument{{DocumentID: "d1", Title: "New", Created: true, Versions: 2}},
+			}},
+		}, nil
+	}
+	h := &DocumentHandler{documents: mock}
+	r := newTestRouter()
+	r.GET("/activity", withUserID("u1"), h.Activity)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/activity?from=2024-05-01&to=2024-05-07&tz=Asia/Shanghai", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	data := parseResponseT(t, w)["data"].(map[string]any)
+	assert.Equal(t, "Asia/Shanghai", data["timezone"])
+	days := data["days"].([]any)
+	require.Len(t, days, 1)
+	day := days[0].(map[string]any)
+	assert.Equal(t, float64(40), day["words_added"])
+	docs := day["documents"].([]any)
+	require.Len(t, docs, 1)
+	assert.Equal(t, true, docs[0].(map[string]any)["created"])
+}
+
+func TestDocumentHandler_Activity_Invalid(t *testing.T) {
+	mock := newDocMock()
+	mock.activityFn = func(context.Context, string, string, string, string) (*service.Activity, error) {
+		return nil, appErr.ErrInvalid
+	}
+	h := &DocumentHandler{documents: mock}
+	r := newTestRouter()
+	r.GET("/activity", withUserID("u1"), h.Activity)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/activity?from=bad", nil))
+	assert.Equal(t, float64(errcode.ErrInvalid), parseResponseT(t, w)["code"])
+}
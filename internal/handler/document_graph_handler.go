@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/xxxsen/mnote/internal/pkg/errcode"
+	"github.com/xxxsen/mnote/internal/pkg/response"
+	"github.com/xxxsen/mnote/internal/service"
+)
+
+type graphNodeResponse struct {
+	ID      string   `json:"id"`
+	Title   string   `json:"title"`
+	TagIDs  []string `json:"tag_ids"`
+	Degree  int      `json:"degree"`
+	Pinned  int      `json:"pinned"`
+	Starred int      `json:"starred"`
+	Mtime   int64    `json:"mtime"`
+}
+
+type graphEdgeResponse struct {
+	Source string  `json:"source"`
+	Target string  `json:"target"`
+	Kind   string  `json:"kind"`
+	Score  float32 `json:"score,omitempty"`
+}
+
+type graphResponse struct {
+	Nodes          []graphNodeResponse `json:"nodes"`
+	Edges          []graphEdgeResponse `json:"edges"`
+	SemanticStatus string              `json:"semantic_status,omitempty"`
+}
+
+func parseGraphQuery(c *gin.Context) (service.GraphQuery, bool) {
+	query := service.GraphQuery{TagID: c.Query("tag_id"), RootID: c.Query("root_id")}
+	if raw, exists := c.GetQuery("depth"); exists {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 || value > service.MaxGraphDepth || query.RootID == "" {
+			return query, false
+		}
+		query.Depth = value
+	}
+	if raw, exists := c.GetQuery("semantic"); exists {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return query, false
+		}
+		query.Semantic = value
+	}
+	if raw, exists := c.GetQuery("min_score"); exists {
+		value, err := strconv.ParseFloat(raw, 32)
+		if err != nil || value < 0 || value > 1 || !query.Semantic {
+			return query, false
+		}
+		query.MinScore = float32(value)
+	}
+	return query, true
+}
+
+func (h *DocumentHandler) Graph(c *gin.Context) {
+	query, ok := parseGraphQuery(c)
+	if !ok {
+		response.Error(c, errcode.ErrInvalid, "invalid request")
+		return
+	}
+	graph, err := h.documents.Graph(c.Request.Context(), getUserID(c), query)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	result := graphResponse{
+		Nodes:          make([]graphNodeResponse, 0, len(graph.Nodes)),
+		Edges:          make([]graphEdgeResponse, 0, len(graph.Edges)),
+		SemanticStatus: graph.SemanticStatus,
+	}
+	for _, node := range graph.Nodes {
+		result.Nodes = append(result.Nodes, graphNodeResponse{
+			ID:      node.ID,
+			Title:   node.Title,
+			TagIDs:  node.TagIDs,
+			Degree:  node.Degree,
+			Pinned:  node.Pinned,
+			Starred: node.Starred,
+			Mtime:   node.Mtime,
+		})
+	}
+	for _, edge := range graph.Edges {
+		result.Edges = append(result.Edges, graphEdgeResponse{
+			Source: edge.Source, Target: edge.Target, Kind: edge.Kind, Score: edge.Score,
+		})
+	}
+	response.Success(c, result)
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/errcode"
+	"github.com/xxxsen/mnote/internal/service"
+)
+
+func TestDocumentHandler_Graph(t *testing.T) {
+	mock := newDocMock()
+	mock.graphFn = func(_ context.Context, userID string, query service.GraphQuery) (*service.DocumentGraph, error) {
+		assert.Equal(t, "u1", userID)
+		assert.Equal(t, service.GraphQuery{TagID: "t", RootID: "a", Depth: 2, Semantic: true, MinScore: 0.75}, query)
+		return &service.DocumentGraph{
+			Nodes: []service.GraphNode{
+				{GraphDocument: model.GraphDocument{ID: "a", Title: "A", Pinned: 1}, TagIDs: []string{"t"}, Degree: 1},
+				{GraphDocument: model.GraphDocument{ID: "b", Title: "B"}, TagIDs: []string{}, Degree: 1},
+			},
+			Edges:          []service.GraphEdge{{Source: "a", Target: "b", Kind: service.GraphEdgeSemantic, Score: 0.8}},
+			SemanticStatus: "ready",
+		}, nil
+	}
+	h := &DocumentHandler{documents: mock}
+	r := newTestRouter()
+	r.GET("/graph", withUserID("u1"), h.Graph)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/graph?tag_id=t&root_id=a&depth=2&semantic=true&min_score=0.75", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	data := parseResponseT(t, w)["data"].(map[string]any)
+	assert.Equal(t, "ready", data["semantic_status"])
+	nodes := data["nodes"].([]any)
+	require.Len(t, nodes, 2)
+	assert.Equal(t, map[string]any{
+		"id": "a", "title": "A", "tag_ids": []any{"t"}, "degree": 1.0, "pinned": 1.0, "starred": 0.0, "mtime": 0.0,
+	}, nodes[0])
+	edges := data["edges"].([]any)
+	require.Len(t, edges, 1)
+	assert.Equal(t, "semantic", edges[0].(map[string]any)["kind"])
+}
+
+func TestDocumentHandler_Graph_InvalidQuery(t *testing.T) {
+	h := &DocumentHandler{documents: newDocMock()}
+	r := newTestRouter()
+	r.GET("/graph", withUserID("u1"), h.Graph)
+
+	for _, query := range []string{"depth=1", "root_id=a&depth=9", "semantic=maybe", "min_score=0.5", "semantic=1&min_score=2"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/graph?"+query, nil))
+		assert.Equal(t, float64(errcode.ErrInvalid), parseResponseT(t, w)["code"], query)
+	}
+}
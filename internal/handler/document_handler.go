@@ -2,10 +2,13 @@ package handler
 
 import (
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/xxxsen/common/logutil"
+	"go.uber.org/zap"
 
 	"github.com/xxxsen/mnote/internal/model"
 	"github.com/xxxsen/mnote/internal/pkg/errcode"
@@ -93,10 +96,21 @@ func (h *DocumentHandler) Create(c *gin.Context) {
 	response.Success(c, toDocumentResponse(*doc))
 }
 
+// documentListOrders maps the order query parameter to a sort; any other
+// value keeps the default.
+var documentListOrders = map[string]string{
+	"mtime":      "mtime desc",
+	"words":      "word_count desc",
+	"reading":    "reading_minutes desc",
+	"open_tasks": "(task_total - task_done) desc",
+}
+
 type listParams struct {
 	query       string
 	tagID       string
 	starred     *int
+	props       []model.PropertyFilter
+	folder      *model.FolderFilter
 	limit       uint
 	offset      uint
 	orderBy     string
@@ -115,14 +129,16 @@ func parseListParams(c *gin.Context) (listParams, error) {
 		}
 		p.starred = &parsed
 	}
+	p.props = parsePropertyFilters(c)
+	p.folder = parseFolderFilter(c)
 	page, err := parsePage(c, 50, 200)
 	if err != nil {
 		return listParams{}, err
 	}
 	p.limit = safeconv.IntToUint(page.Limit)
 	p.offset = safeconv.IntToUint(page.Offset)
-	if c.Query("order") == "mtime" {
-		p.orderBy = "mtime desc"
+	if orderBy, ok := documentListOrders[c.Query("order")]; ok {
+		p.orderBy = orderBy
 	}
 	if value := c.Query("include"); value != "" {
 		for _, part := range strings.Split(value, ",") {
@@ -135,6 +151,35 @@ func parseListParams(c *gin.Context) (listParams, error) {
 	return p, nil
 }
 
+// parseFolderFilter reads folder_id, where an empty value means the top
+// level, and recursive=1 to take in the subfolders. Without folder_id the
+// listing spans all folders.
+func parseFolderFilter(c *gin.Context) *model.FolderFilter {
+	folderID, exists := c.GetQuery("folder_id")
+	if !exists {
+		return nil
+	}
+	return &model.FolderFilter{ID: folderID, Recursive: c.Query("recursive") == "1"}
+}
+
+// parsePropertyFilters reads prop.<key>=<value> query parameters. Repeating
+// a key matches any of its values; different keys must all match.
+func parsePropertyFilters(c *gin.Context) []model.PropertyFilter {
+	query := c.Request.URL.Query()
+	keys := make([]string, 0)
+	for name := range query {
+		if key, ok := strings.CutPrefix(name, "prop."); ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	filters := make([]model.PropertyFilter, 0, len(keys))
+	for _, key := range keys {
+		filters = append(filters, model.PropertyFilter{Key: key, Values: query["prop."+key]})
+	}
+	return filters
+}
+
 func buildListItems(
 	docs []model.Document, tagMap map[string][]string,
 	tagIndex map[string]model.Tag, includeTags bool,
@@ -172,7 +217,7 @@ func (h *DocumentHandler) List(c *gin.Context) {
 	}
 	docs, err := h.documents.Search(
 		c.Request.Context(), userID, p.query, p.tagID,
-		p.starred, p.limit, p.offset, p.orderBy,
+		p.starred, p.props, p.folder, p.limit, p.offset, p.orderBy,
 	)
 	if err != nil {
 		handleError(c, err)
@@ -242,6 +287,10 @@ func (h *DocumentHandler) Get(c *gin.Context) {
 			}
 		}
 	}
+	if section, ok := c.GetQuery("section"); ok {
+		h.getSection(c, userID, section)
+		return
+	}
 	doc, err := h.documents.Get(c.Request.Context(), userID, c.Param("id"))
 	if err != nil {
 		handleError(c, err)
@@ -253,7 +302,8 @@ func (h *DocumentHandler) Get(c *gin.Context) {
 		return
 	}
 	if includeTags && len(tagIDs) > 0 {
-		tags, err := h.documents.ListTagsByIDs(c.Request.Context(), userID, tagIDs)
+		// Collaborators see the tags of the document's owner.
+		tags, err := h.documents.ListTagsByIDs(c.Request.Context(), doc.UserID, tagIDs)
 		if err != nil {
 			handleError(c, err)
 			return
@@ -402,6 +452,10 @@ func (h *DocumentHandler) Summary(c *gin.Context) {
 		"tag_counts":    result.TagCounts,
 		"total":         result.Total,
 		"starred_total": result.StarredTotal,
+		"stats": documentStatsTotalsResponse{
+			documentStatsResponse: toDocumentStatsResponse(result.Stats.DocumentStats),
+			EditedDocuments:       result.Stats.EditedDocuments,
+		},
 	})
 }
 
@@ -567,3 +621,92 @@ func (h *DocumentHandler) Similar(c *gin.Context) {
 		"index_status": result.IndexStatus,
 	})
 }
+
+type tagSuggestionResponse struct {
+	Tag            tagResponse `json:"tag"`
+	Score          float32     `json:"score"`
+	NeighbourScore float32     `json:"neighbour_score"`
+	CentroidScore  float32     `json:"centroid_score"`
+	NeighbourCount int         `json:"neighbour_count"`
+}
+
+func (h *DocumentHandler) TagSuggestions(c *gin.Context) {
+	page, err := parsePage(c, 5, 20)
+	if err != nil || page.Offset != 0 {
+		response.Error(c, errcode.ErrInvalid, "invalid pagination")
+		return
+	}
+	result, err := h.documents.SuggestTags(
+		c.Request.Context(),
+		getUserID(c),
+		c.Param("id"),
+		page.Limit,
+	)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	items := make([]tagSuggestionResponse, 0, len(result.Suggestions))
+	for _, suggestion := range result.Suggestions {
+		items = append(items, tagSuggestionResponse{
+			Tag:            toTagResponse(suggestion.Tag),
+			Score:          suggestion.Score,
+			NeighbourScore: suggestion.NeighbourScore,
+			CentroidScore:  suggestion.CentroidScore,
+			NeighbourCount: suggestion.NeighbourCount,
+		})
+	}
+	response.Success(c, gin.H{
+		"items":        items,
+		"index_status": result.IndexStatus,
+	})
+}
+
+type tagSuggestionApplyRequest struct {
+	IDs      []string `json:"ids"`
+	MinScore float32  `json:"min_score"`
+	Limit    int      `json:"limit"`
+}
+
+type tagSuggestionApplyItem struct {
+	bulkItemResponse
+	TagIDs []string `json:"tag_ids,omitempty"`
+}
+
+// ApplyTagSuggestions adds the suggested tags to each listed document: the
+// top limit suggestions, those scoring at least min_score, or both.
+func (h *DocumentHandler) ApplyTagSuggestions(c *gin.Context) {
+	var req tagSuggestionApplyRequest
+	if err := bindJSON(c, &req); err != nil {
+		response.Error(c, errcode.ErrInvalid, "invalid request")
+		return
+	}
+	userID := getUserID(c)
+	results, err := h.documents.ApplyTagSuggestions(c.Request.Context(), userID, service.TagSuggestionApply{
+		DocIDs: req.IDs, MinScore: req.MinScore, Limit: req.Limit,
+	})
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	outcomes := make([]service.BulkItemResult, 0, len(results))
+	for _, result := range results {
+		outcomes = append(outcomes, result.BulkItemResult)
+		if result.Err != nil && appErr.Normalize(result.Err).Code() == errcode.ErrInternal {
+			logutil.GetLogger(c.Request.Context()).Error(
+				"apply tag suggestions failed",
+				zap.String("user_id", userID),
+				zap.String("document_id", result.ID),
+				zap.Error(result.Err),
+			)
+		}
+	}
+	resp := toBulkResponse("apply_tag_suggestions", outcomes)
+	items := make([]tagSuggestionApplyItem, 0, len(results))
+	for i, item := range resp.Items {
+		items = append(items, tagSuggestionApplyItem{bulkItemResponse: item, TagIDs: results[i].TagIDs})
+	}
+	response.Success(c, gin.H{
+		"total": resp.Total, "succeeded": resp.Succeeded, "failed": resp.Failed, "items": items,
+	})
+}
@@ -129,7 +129,7 @@ func TestDocumentHandler_Get_Error(t *testing.T) {
 
 func TestDocumentHandler_List_Success(t *testing.T) {
 	mock := newDocMock()
-	mock.searchFn = func(_ context.Context, _, _, _ string, _ *int, _, _ uint, _ string) ([]model.Document, error) {
+	mock.searchFn = func(_ context.Context, _, _, _ string, _ *int, _ []model.PropertyFilter, _ *model.FolderFilter, _, _ uint, _ string) ([]model.Document, error) {
 		return []model.Document{{ID: "d1", Title: "Doc1"}}, nil
 	}
 	mock.listTagIDsByDocIDsFn = func(_ context.Context, _ string, _ []string) (map[string][]string, error) {
@@ -148,7 +148,7 @@ func TestDocumentHandler_List_Success(t *testing.T) {
 
 func TestDocumentHandler_List_WithIncludeTags(t *testing.T) {
 	mock := newDocMock()
-	mock.searchFn = func(_ context.Context, _, _, _ string, _ *int, _, _ uint, _ string) ([]model.Document, error) {
+	mock.searchFn = func(_ context.Context, _, _, _ string, _ *int, _ []model.PropertyFilter, _ *model.FolderFilter, _, _ uint, _ string) ([]model.Document, error) {
 		return []model.Document{{ID: "d1"}}, nil
 	}
 	mock.listTagIDsByDocIDsFn = func(_ context.Context, _ string, _ []string) (map[string][]string, error) {
@@ -608,7 +608,7 @@ func TestDocumentHandler_Create_InvalidJSON(t *testing.T) {
 
 func TestDocumentHandler_List_WithStarredAndOrder(t *testing.T) {
 	mock := newDocMock()
-	mock.searchFn = func(_ context.Context, _, _, _ string, starred *int, _, _ uint, orderBy string) ([]model.Document, error) {
+	mock.searchFn = func(_ context.Context, _, _, _ string, starred *int, _ []model.PropertyFilter, _ *model.FolderFilter, _, _ uint, orderBy string) ([]model.Document, error) {
 		assert.NotNil(t, starred)
 		assert.Equal(t, 1, *starred)
 		assert.Equal(t, "mtime desc", orderBy)
@@ -630,7 +630,7 @@ func TestDocumentHandler_List_WithStarredAndOrder(t *testing.T) {
 
 func TestDocumentHandler_List_SearchError(t *testing.T) {
 	mock := newDocMock()
-	mock.searchFn = func(_ context.Context, _, _, _ string, _ *int, _, _ uint, _ string) ([]model.Document, error) {
+	mock.searchFn = func(_ context.Context, _, _, _ string, _ *int, _ []model.PropertyFilter, _ *model.FolderFilter, _, _ uint, _ string) ([]model.Document, error) {
 		return nil, errors.New("db error")
 	}
 	h := &DocumentHandler{documents: mock}
@@ -647,7 +647,7 @@ func TestDocumentHandler_List_SearchError(t *testing.T) {
 
 func TestDocumentHandler_List_TagMapError(t *testing.T) {
 	mock := newDocMock()
-	mock.searchFn = func(_ context.Context, _, _, _ string, _ *int, _, _ uint, _ string) ([]model.Document, error) {
+	mock.searchFn = func(_ context.Context, _, _, _ string, _ *int, _ []model.PropertyFilter, _ *model.FolderFilter, _, _ uint, _ string) ([]model.Document, error) {
 		return []model.Document{{ID: "d1"}}, nil
 	}
 	mock.listTagIDsByDocIDsFn = func(_ context.Context, _ string, _ []string) (map[string][]string, error) {
@@ -667,7 +667,7 @@ func TestDocumentHandler_List_TagMapError(t *testing.T) {
 
 func TestDocumentHandler_List_IncludeTagsError(t *testing.T) {
 	mock := newDocMock()
-	mock.searchFn = func(_ context.Context, _, _, _ string, _ *int, _, _ uint, _ string) ([]model.Document, error) {
+	mock.searchFn = func(_ context.Context, _, _, _ string, _ *int, _ []model.PropertyFilter, _ *model.FolderFilter, _, _ uint, _ string) ([]model.Document, error) {
 		return []model.Document{{ID: "d1"}}, nil
 	}
 	mock.listTagIDsByDocIDsFn = func(_ context.Context, _ string, _ []string) (map[string][]string, error) {
@@ -690,7 +690,7 @@ func TestDocumentHandler_List_IncludeTagsError(t *testing.T) {
 
 func TestDocumentHandler_List_IncludeTagsEmptyMap(t *testing.T) {
 	mock := newDocMock()
-	mock.searchFn = func(_ context.Context, _, _, _ string, _ *int, _, _ uint, _ string) ([]model.Document, error) {
+	mock.searchFn = func(_ context.Context, _, _, _ string, _ *int, _ []model.PropertyFilter, _ *model.FolderFilter, _, _ uint, _ string) ([]model.Document, error) {
 		return []model.Document{{ID: "d1"}}, nil
 	}
 	mock.listTagIDsByDocIDsFn = func(_ context.Context, _ string, _ []string) (map[string][]string, error) {
@@ -1059,7 +1059,7 @@ func TestDocumentHandler_Summary_InvalidLimit(t *testing.T) {
 
 func TestDocumentHandler_List_IncludeNonTags(t *testing.T) {
 	mock := newDocMock()
-	mock.searchFn = func(_ context.Context, _, _, _ string, _ *int, _, _ uint, _ string) ([]model.Document, error) {
+	mock.searchFn = func(_ context.Context, _, _, _ string, _ *int, _ []model.PropertyFilter, _ *model.FolderFilter, _, _ uint, _ string) ([]model.Document, error) {
 		return []model.Document{{ID: "d1"}}, nil
 	}
 	mock.listTagIDsByDocIDsFn = func(_ context.Context, _ string, _ []string) (map[string][]string, error) {
@@ -1165,3 +1165,77 @@ func TestDocumentHandler_Similar_PendingAndInvalidPagination(t *testing.T) {
 	payload = parseResponseT(t, w)
 	assert.NotEqual(t, float64(0), payload["code"])
 }
+
+func TestDocumentHandler_TagSuggestions(t *testing.T) {
+	mock := newDocMock()
+	mock.suggestTagsFn = func(
+		_ context.Context,
+		userID, docID string,
+		limit int,
+	) (*service.TagSuggestionList, error) {
+		assert.Equal(t, "u1", userID)
+		assert.Equal(t, "source", docID)
+		assert.Equal(t, 3, limit)
+		return &service.TagSuggestionList{
+			Suggestions: []service.TagSuggestion{{
+				Tag:            model.Tag{ID: "t1", Name: "go"},
+				Score:          0.5,
+				NeighbourScore: 0.75,
+				CentroidScore:  0.25,
+				NeighbourCount: 2,
+			}},
+			IndexStatus: "ready",
+		}, nil
+	}
+	h := &DocumentHandler{documents: mock}
+	r := newTestRouter()
+	r.GET("/documents/:id/tag-suggestions", withUserID("u1"), h.TagSuggestions)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/documents/source/tag-suggestions?limit=3", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	data := parseResponseT(t, w)["data"].(map[string]any)
+	assert.Equal(t, "ready", data["index_status"])
+	items := data["items"].([]any)
+	require.Len(t, items, 1)
+	item := items[0].(map[string]any)
+	assert.Equal(t, "go", item["tag"].(map[string]any)["name"])
+	assert.Equal(t, 0.5, item["score"])
+	assert.Equal(t, float64(2), item["neighbour_count"])
+}
+
+func TestDocumentHandler_ApplyTagSuggestions(t *testing.T) {
+	mock := newDocMock()
+	mock.applyTagSuggestionsFn = func(
+		_ context.Context, _ string, req service.TagSuggestionApply,
+	) ([]service.TagSuggestionApplyResult, error) {
+		if len(req.DocIDs) == 0 {
+			return nil, appErr.ErrInvalid
+		}
+		assert.Equal(t, service.TagSuggestionApply{DocIDs: []string{"d1", "d2"}, MinScore: 0.5}, req)
+		return []service.TagSuggestionApplyResult{
+			{BulkItemResult: service.BulkItemResult{ID: "d1"}, TagIDs: []string{"t2"}},
+			{BulkItemResult: service.BulkItemResult{ID: "d2", Err: appErr.ErrNotFound}},
+		}, nil
+	}
+	h := &DocumentHandler{documents: mock}
+	r := newTestRouter()
+	r.POST("/documents/tag-suggestions/apply", withUserID("u1"), h.ApplyTagSuggestions)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, "POST", "/documents/tag-suggestions/apply",
+		map[string]any{"ids": []string{"d1", "d2"}, "min_score": 0.5}))
+	assert.Equal(t, http.StatusOK, w.Code)
+	data := parseResponseT(t, w)["data"].(map[string]any)
+	assert.Equal(t, float64(1), data["succeeded"])
+	assert.Equal(t, float64(1), data["failed"])
+	items := data["items"].([]any)
+	require.Len(t, items, 2)
+	assert.Equal(t, []any{"t2"}, items[0].(map[string]any)["tag_ids"])
+	assert.Equal(t, false, items[1].(map[string]any)["ok"])
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, "POST", "/documents/tag-suggestions/apply", map[string]any{"ids": []string{}}))
+	assert.NotEqual(t, float64(0), parseResponseT(t, w)["code"])
+}
@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/xxxsen/mnote/internal/pkg/errcode"
+	"github.com/xxxsen/mnote/internal/pkg/response"
+	"github.com/xxxsen/mnote/internal/service"
+)
+
+type unlinkedMentionResponse struct {
+	ID       string   `json:"id"`
+	Title    string   `json:"title"`
+	Mtime    int64    `json:"mtime"`
+	Count    int      `json:"count"`
+	Snippets []string `json:"snippets"`
+}
+
+type convertMentionsRequest struct {
+	SourceID     string `json:"source_id"`
+	BaseRevision int64  `json:"base_revision"`
+}
+
+type convertMentionsResponse struct {
+	Converted       int    `json:"converted"`
+	Accepted        bool   `json:"accepted"`
+	Reason          string `json:"reason,omitempty"`
+	ContentRevision int64  `json:"content_revision,omitempty"`
+}
+
+type brokenLinkResponse struct {
+	SourceID    string `json:"source_id"`
+	SourceTitle string `json:"source_title"`
+	SourceMtime int64  `json:"source_mtime"`
+	Kind        string `json:"kind"`
+	TargetID    string `json:"target_id,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Anchor      string `json:"anchor,omitempty"`
+	Reason      string `json:"reason"`
+}
+
+func (h *DocumentHandler) UnlinkedMentions(c *gin.Context) {
+	page, err := parsePage(c, service.DefaultUnlinkedMentionsLimit, service.MaxUnlinkedMentionsLimit)
+	if err != nil || page.Offset != 0 {
+		response.Error(c, errcode.ErrInvalid, "invalid pagination")
+		return
+	}
+	mentions, err := h.documents.UnlinkedMentions(c.Request.Context(), getUserID(c), c.Param("id"), page.Limit)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	items := make([]unlinkedMentionResponse, 0, len(mentions))
+	for _, mention := range mentions {
+		items = append(items, unlinkedMentionResponse{
+			ID: mention.ID, Title: mention.Title, Mtime: mention.Mtime,
+			Count: mention.Count, Snippets: mention.Snippets,
+		})
+	}
+	response.Success(c, items)
+}
+
+func (h *DocumentHandler) ConvertMentions(c *gin.Context) {
+	var req convertMentionsRequest
+	if err := bindJSON(c, &req); err != nil || req.SourceID == "" || req.BaseRevision < 0 {
+		response.Error(c, errcode.ErrInvalid, "invalid request")
+		return
+	}
+	conversion, err := h.documents.ConvertMentions(
+		c.Request.Context(), getUserID(c), c.Param("id"), req.SourceID, req.BaseRevision,
+	)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	result := convertMentionsResponse{Converted: conversion.Converted}
+	if conversion.Result != nil {
+		result.Accepted = conversion.Result.Accepted
+		result.Reason = string(conversion.Result.Reason)
+		result.ContentRevision = conversion.Result.ContentRevision
+	}
+	response.Success(c, result)
+}
+
+func (h *DocumentHandler) BrokenLinks(c *gin.Context) {
+	page, err := parsePage(c, service.DefaultBrokenLinksLimit, service.MaxBrokenLinksLimit)
+	if err != nil {
+		response.Error(c, errcode.ErrInvalid, "invalid pagination")
+		return
+	}
+	links, err := h.documents.ListBrokenLinks(c.Request.Context(), getUserID(c), page)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	items := make([]brokenLinkResponse, 0, len(links))
+	for _, link := range links {
+		items = append(items, brokenLinkResponse{
+			SourceID: link.SourceID, SourceTitle: link.SourceTitle, SourceMtime: link.SourceMtime,
+			Kind: link.Kind, TargetID: link.TargetID, Title: link.Title, Anchor: link.Anchor, Reason: link.Reason,
+		})
+	}
+	response.Success(c, items)
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/errcode"
+	"github.com/xxxsen/mnote/internal/service"
+)
+
+func TestDocumentHandler_UnlinkedMentions(t *testing.T) {
+	mock := newDocMock()
+	mock.unlinkedMentionsFn = func(_ context.Context, userID, docID string, limit int) ([]service.UnlinkedMention, error) {
+		assert.Equal(t, "u1", userID)
+		assert.Equal(t, "d1", docID)
+		assert.Equal(t, 5, limit)
+		return []service.UnlinkedMention{{ID: "d2", Title: "Notes", Mtime: 7, Count: 2, Snippets: []string{"the Go Guide"}}}, nil
+	}
+	h := &DocumentHandler{documents: mock}
+	r := newTestRouter()
+	r.GET("/documents/:id/unlinked-mentions", withUserID("u1"), h.UnlinkedMentions)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/documents/d1/unlinked-mentions?limit=5", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	items := parseResponseT(t, w)["data"].([]any)
+	require.Len(t, items, 1)
+	assert.Equal(t, map[string]any{
+		"id": "d2", "title": "Notes", "mtime": 7.0, "count": 2.0, "snippets": []any{"the Go Guide"},
+	}, items[0])
+
+	for _, query := range []string{"limit=0", "limit=51", "offset=1"} {
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/documents/d1/unlinked-mentions?"+query, nil))
+		assert.Equal(t, float64(errcode.ErrInvalid), parseResponseT(t, w)["code"], query)
+	}
+}
+
+func TestDocumentHandler_ConvertMentions(t *testing.T) {
+	mock := newDocMock()
+	mock.convertMentionsFn = func(
+		_ context.Context, userID, docID, sourceID string, baseRevision int64,
+	) (*service.MentionConversion, error) {
+		assert.Equal(t, "u1", userID)
+		assert.Equal(t, "d1", docID)
+		assert.Equal(t, "d2", sourceID)
+		assert.Equal(t, int64(3), baseRevision)
+		return &service.MentionConversion{
+			Converted: 2,
+			Result:    &model.SaveDocumentResult{ID: "d2", Accepted: true, ContentRevision: 4},
+		}, nil
+	}
+	h := &DocumentHandler{documents: mock}
+	r := newTestRouter()
+	r.POST("/documents/:id/unlinked-mentions/convert", withUserID("u1"), h.ConvertMentions)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/documents/d1/unlinked-mentions/convert",
+		strings.NewReader(`{"source_id":"d2","base_revision":3}`)))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, map[string]any{"converted": 2.0, "accepted": true, "content_revision": 4.0},
+		parseResponseT(t, w)["data"])
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/documents/d1/unlinked-mentions/convert",
+		strings.NewReader(`{"base_revision":3}`)))
+	assert.Equal(t, float64(errcode.ErrInvalid), parseResponseT(t, w)["code"])
+}
+
+func TestDocumentHandler_ConvertMentions_NothingToConvert(t *testing.T) {
+	mock := newDocMock()
+	mock.convertMentionsFn = func(context.Context, string, string, string, int64) (*service.MentionConversion, error) {
+		return &service.MentionConversion{}, nil
+	}
+	h := &DocumentHandler{documents: mock}
+	r := newTestRouter()
+	r.POST("/documents/:id/unlinked-mentions/convert", withUserID("u1"), h.ConvertMentions)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/documents/d1/unlinked-mentions/convert",
+		strings.NewReader(`{"source_id":"d2"}`)))
+	assert.Equal(t, map[string]any{"converted": 0.0, "accepted": false}, parseResponseT(t, w)["data"])
+}
+
+func TestDocumentHandler_BrokenLinks(t *testing.T) {
+	mock := newDocMock()
+	mock.listBrokenLinksFn = func(_ context.Context, userID string, page service.Page) ([]model.BrokenLink, error) {
+		assert.Equal(t, "u1", userID)
+		assert.Equal(t, service.Page{Limit: 10, Offset: 20}, page)
+		return []model.BrokenLink{
+			{SourceID: "d1", SourceTitle: "Notes", SourceMtime: 5, Kind: model.BrokenLinkKindDocument,
+				TargetID: "gone", Reason: model.BrokenLinkReasonDeleted},
+			{SourceID: "d1", SourceTitle: "Notes", SourceMtime: 5, Kind: model.BrokenLinkKindWikilink,
+				Title: "Alpha", Anchor: "Intro", Reason: model.BrokenLinkReasonMissing},
+		}, nil
+	}
+	h := &DocumentHandler{documents: mock}
+	r := newTestRouter()
+	r.GET("/links/broken", withUserID("u1"), h.BrokenLinks)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/links/broken?limit=10&offset=20", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	items := parseResponseT(t, w)["data"].([]any)
+	require.Len(t, items, 2)
+	assert.Equal(t, map[string]any{
+		"source_id": "d1", "source_title": "Notes", "source_mtime": 5.0,
+		"kind": "document", "target_id": "gone", "reason": "deleted",
+	}, items[0])
+	assert.Equal(t, "Alpha", items[1].(map[string]any)["title"])
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/links/broken?limit=201", nil))
+	assert.Equal(t, float64(errcode.ErrInvalid), parseResponseT(t, w)["code"])
+}
@@ -0,0 +1,54 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/xxxsen/mnote/internal/pkg/response"
+	"github.com/xxxsen/mnote/internal/service"
+)
+
+type outlineSectionResponse struct {
+	Level     int                      `json:"level"`
+	Title     string                   `json:"title"`
+	Slug      string                   `json:"slug"`
+	Line      int                      `json:"line"`
+	EndLine   int                      `json:"end_line"`
+	WordCount int                      `json:"word_count"`
+	Children  []outlineSectionResponse `json:"children"`
+}
+
+func toOutlineSectionResponse(section service.OutlineSection) outlineSectionResponse {
+	return outlineSectionResponse{
+		Level: section.Level, Title: section.Title, Slug: section.Slug,
+		Line: section.Line, EndLine: section.EndLine, WordCount: section.WordCount,
+		Children: toOutlineSectionResponses(section.Children),
+	}
+}
+
+func toOutlineSectionResponses(sections []service.OutlineSection) []outlineSectionResponse {
+	items := make([]outlineSectionResponse, 0, len(sections))
+	for _, section := range sections {
+		items = append(items, toOutlineSectionResponse(section))
+	}
+	return items
+}
+
+func (h *DocumentHandler) Outline(c *gin.Context) {
+	sections, err := h.documents.Outline(c.Request.Context(), getUserID(c), c.Param("id"))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, toOutlineSectionResponses(sections))
+}
+
+// getSection answers GET /documents/:id?section=<slug> with the document cut
+// down to one section. Tags are left out; the full document carries them.
+func (h *DocumentHandler) getSection(c *gin.Context, userID, section string) {
+	doc, found, err := h.documents.GetSection(c.Request.Context(), userID, c.Param("id"), section)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, gin.H{"document": toDocumentResponse(*doc), "section": toOutlineSectionResponse(*found)})
+}
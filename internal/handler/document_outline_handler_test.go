@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/errcode"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+	"github.com/xxxsen/mnote/internal/service"
+)
+
+func TestDocumentHandler_Outline(t *testing.T) {
+	mock := newDocMock()
+	mock.outlineFn = func(_ context.Context, userID, docID string) ([]service.OutlineSection, error) {
+		assert.Equal(t, "u1", userID)
+		assert.Equal(t, "d1", docID)
+		return []service.OutlineSection{{
+			Level: 1, Title: "Intro", Slug: "intro", Line: 1, EndLine: 4, WordCount: 3,
+			Children: []service.OutlineSection{{Level: 2, Title: "Setup", Slug: "setup", Line: 3, EndLine: 4, WordCount: 1}},
+		}}, nil
+	}
+	h := &DocumentHandler{documents: mock}
+	r := newTestRouter()
+	r.GET("/documents/:id/outline", withUserID("u1"), h.Outline)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/documents/d1/outline", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	items := parseResponseT(t, w)["data"].([]any)
+	require.Len(t, items, 1)
+	intro := items[0].(map[string]any)
+	assert.Equal(t, "intro", intro["slug"])
+	assert.Equal(t, float64(4), intro["end_line"])
+	children := intro["children"].([]any)
+	require.Len(t, children, 1)
+	assert.Equal(t, "setup", children[0].(map[string]any)["slug"])
+	assert.Equal(t, []any{}, children[0].(map[string]any)["children"])
+}
+
+func TestDocumentHandler_Get_Section(t *testing.T) {
+	mock := newDocMock()
+	mock.getSectionFn = func(
+		_ context.Context, _, docID, section string,
+	) (*model.Document, *service.OutlineSection, error) {
+		assert.Equal(t, "d1", docID)
+		assert.Equal(t, "setup", section)
+		return &model.Document{ID: "d1", Content: "## Setup\nbody"},
+			&service.OutlineSection{Level: 2, Title: "Setup", Slug: "setup", Line: 3, EndLine: 4}, nil
+	}
+	h := &DocumentHandler{documents: mock}
+	r := newTestRouter()
+	r.GET("/documents/:id", withUserID("u1"), h.Get)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/documents/d1?section=setup", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	data := parseResponseT(t, w)["data"].(map[string]any)
+	assert.Equal(t, "## Setup\nbody", data["document"].(map[string]any)["content"])
+	assert.Equal(t, "setup", data["section"].(map[string]any)["slug"])
+}
+
+func TestDocumentHandler_Get_SectionNotFound(t *testing.T) {
+	mock := newDocMock()
+	mock.getSectionFn = func(context.Context, string, string, string) (*model.Document, *service.OutlineSection, error) {
+		return nil, nil, appErr.ErrNotFound
+	}
+	h := &DocumentHandler{documents: mock}
+	r := newTestRouter()
+	r.GET("/documents/:id", withUserID("u1"), h.Get)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/documents/d1?section=missing", nil))
+	assert.Equal(t, float64(errcode.ErrNotFound), parseResponseT(t, w)["code"])
+}
+
+func TestShareHandler_PublicGet_Section(t *testing.T) {
+	mock := newShareDocMock()
+	mock.getShareSectionFn = func(
+		_ context.Context, token, _, section string,
+	) (*service.PublicShareDetail, *service.OutlineSection, error) {
+		assert.Equal(t, "tok123", token)
+		assert.Equal(t, "Setup", section)
+		return &service.PublicShareDetail{Document: &model.Document{ID: "d1", Content: "## Setup"}},
+			&service.OutlineSection{Level: 2, Title: "Setup", Slug: "setup"}, nil
+	}
+	h := &ShareHandler{documents: mock}
+	r := newTestRouter()
+	r.GET("/public/share/:token", h.PublicGet)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/public/share/tok123?section=Setup", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	data := parseResponseT(t, w)["data"].(map[string]any)
+	assert.Equal(t, "setup", data["section"].(map[string]any)["slug"])
+}
@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/response"
+)
+
+type documentPropertyResponse struct {
+	Key    string   `json:"key"`
+	Type   string   `json:"type"`
+	Value  string   `json:"value,omitempty"`
+	Values []string `json:"values,omitempty"`
+}
+
+func (h *DocumentHandler) Properties(c *gin.Context) {
+	props, err := h.documents.ListProperties(c.Request.Context(), getUserID(c), c.Param("id"))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	items := make([]documentPropertyResponse, 0, len(props))
+	for _, prop := range props {
+		item := documentPropertyResponse{Key: prop.Key, Type: prop.Type, Value: prop.Value}
+		if prop.Type == model.PropertyTypeList {
+			item.Values = prop.Values
+		}
+		items = append(items, item)
+	}
+	response.Success(c, items)
+}
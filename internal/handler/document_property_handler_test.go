@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/errcode"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+func TestDocumentHandler_Properties(t *testing.T) {
+	mock := newDocMock()
+	mock.listPropertiesFn = func(_ context.Context, userID, docID string) ([]model.DocumentProperty, error) {
+		assert.Equal(t, "u1", userID)
+		assert.Equal(t, "d1", docID)
+		return []model.DocumentProperty{
+			{Key: "status", Type: model.PropertyTypeString, Value: "draft"},
+			{Key: "aliases", Type: model.PropertyTypeList, Values: []string{"A", "B"}},
+		}, nil
+	}
+	h := &DocumentHandler{documents: mock}
+	r := newTestRouter()
+	r.GET("/documents/:id/properties", withUserID("u1"), h.Properties)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/documents/d1/properties", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	items := parseResponseT(t, w)["data"].([]any)
+	require.Len(t, items, 2)
+	assert.Equal(t, map[string]any{"key": "status", "type": "string", "value": "draft"}, items[0])
+	assert.Equal(t, map[string]any{"key": "aliases", "type": "list", "values": []any{"A", "B"}}, items[1])
+}
+
+func TestDocumentHandler_Properties_NotFound(t *testing.T) {
+	mock := newDocMock()
+	mock.listPropertiesFn = func(context.Context, string, string) ([]model.DocumentProperty, error) {
+		return nil, appErr.ErrNotFound
+	}
+	h := &DocumentHandler{documents: mock}
+	r := newTestRouter()
+	r.GET("/documents/:id/properties", withUserID("u1"), h.Properties)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/documents/d1/properties", nil))
+	assert.Equal(t, float64(errcode.ErrNotFound), parseResponseT(t, w)["code"])
+}
+
+func TestDocumentHandler_List_PropertyFilters(t *testing.T) {
+	mock := newDocMock()
+	mock.searchFn = func(
+		_ context.Context, _, _, _ string, _ *int, props []model.PropertyFilter, _ *model.FolderFilter, _, _ uint, _ string,
+	) ([]model.Document, error) {
+		assert.Equal(t, []model.PropertyFilter{
+			{Key: "priority", Values: []string{"1"}},
+			{Key: "status", Values: []string{"draft", "review"}},
+		}, props)
+		return []model.Document{}, nil
+	}
+	mock.listTagIDsByDocIDsFn = func(context.Context, string, []string) (map[string][]string, error) {
+		return map[string][]string{}, nil
+	}
+	h := &DocumentHandler{documents: mock}
+	r := newTestRouter()
+	r.GET("/documents", withUserID("u1"), h.List)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/documents?prop.status=draft&prop.priority=1&prop.status=review", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, float64(0), parseResponseT(t, w)["code"])
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/service"
+)
+
+func TestDocumentHandler_List_OrderByStats(t *testing.T) {
+	cases := map[string]string{
+		"words":      "word_count desc",
+		"reading":    "reading_minutes desc",
+		"open_tasks": "(task_total - task_done) desc",
+		"bogus":      "",
+	}
+	for order, want := range cases {
+		t.Run(order, func(t *testing.T) {
+			mock := newDocMock()
+			mock.searchFn = func(
+				_ context.Context, _, _, _ string, _ *int, _ []model.PropertyFilter, _ *model.FolderFilter, _, _ uint, orderBy string,
+			) ([]model.Document, error) {
+				assert.Equal(t, want, orderBy)
+				return []model.Document{{ID: "d1", Stats: model.DocumentStats{WordCount: 12, TasksTotal: 2}}}, nil
+			}
+			mock.listTagIDsByDocIDsFn = func(context.Context, string, []string) (map[string][]string, error) {
+				return map[string][]string{}, nil
+			}
+			h := &DocumentHandler{documents: mock}
+			r := newTestRouter()
+			r.GET("/documents", withUserID("u1"), h.List)
+
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest("GET", "/documents?order="+order, nil))
+			assert.Equal(t, http.StatusOK, w.Code)
+			items := parseResponseT(t, w)["data"].([]any)
+			require.Len(t, items, 1)
+			stats := items[0].(map[string]any)["stats"].(map[string]any)
+			assert.Equal(t, float64(12), stats["word_count"])
+			assert.Equal(t, float64(2), stats["tasks_total"])
+		})
+	}
+}
+
+func TestDocumentHandler_Summary_Stats(t *testing.T) {
+	mock := newDocMock()
+	mock.overviewFn = func(context.Context, string, uint) (*service.DocumentOverview, error) {
+		return &service.DocumentOverview{
+			Recent: []model.Document{}, TagCounts: map[string]int{},
+			Stats: model.DocumentStatsTotals{
+				DocumentStats:   model.DocumentStats{WordCount: 900, ReadingMinutes: 5, TasksDone: 3},
+				EditedDocuments: 4,
+			},
+		}, nil
+	}
+	h := &DocumentHandler{documents: mock}
+	r := newTestRouter()
+	r.GET("/documents/summary", withUserID("u1"), h.Summary)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/documents/summary", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	stats := parseResponseT(t, w)["data"].(map[string]any)["stats"].(map[string]any)
+	assert.Equal(t, float64(900), stats["word_count"])
+	assert.Equal(t, float64(5), stats["reading_minutes"])
+	assert.Equal(t, float64(3), stats["tasks_done"])
+	assert.Equal(t, float64(4), stats["edited_documents"])
+}
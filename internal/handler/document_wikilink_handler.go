@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/xxxsen/mnote/internal/pkg/response"
+)
+
+type wikilinkCandidateResponse struct {
+	ID    string `json:"id"`
+	Title string `json:"title"`
+	Mtime int64  `json:"mtime"`
+}
+
+type wikilinkResponse struct {
+	Title      string                      `json:"title"`
+	Anchor     string                      `json:"anchor"`
+	Status     string                      `json:"status"`
+	TargetID   string                      `json:"target_id,omitempty"`
+	Candidates []wikilinkCandidateResponse `json:"candidates,omitempty"`
+}
+
+func (h *DocumentHandler) Wikilinks(c *gin.Context) {
+	links, err := h.documents.ListWikilinks(c.Request.Context(), getUserID(c), c.Param("id"))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	items := make([]wikilinkResponse, 0, len(links))
+	for _, link := range links {
+		item := wikilinkResponse{
+			Title: link.Title, Anchor: link.Anchor, Status: link.Status, TargetID: link.TargetID,
+		}
+		for _, candidate := range link.Candidates {
+			item.Candidates = append(item.Candidates, wikilinkCandidateResponse{
+				ID: candidate.ID, Title: candidate.Title, Mtime: candidate.Mtime,
+			})
+		}
+		items = append(items, item)
+	}
+	response.Success(c, items)
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/errcode"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+	"github.com/xxxsen/mnote/internal/service"
+)
+
+func TestDocumentHandler_Wikilinks(t *testing.T) {
+	mock := newDocMock()
+	mock.listWikilinksFn = func(_ context.Context, userID, docID string) ([]service.Wikilink, error) {
+		assert.Equal(t, "u1", userID)
+		assert.Equal(t, "d1", docID)
+		return []service.Wikilink{
+			{DocumentWikilink: model.DocumentWikilink{
+				Title: "Alpha", Anchor: "Intro", Status: model.WikilinkStatusResolved, TargetID: "d2",
+			}},
+			{
+				DocumentWikilink: model.DocumentWikilink{Title: "Beta", Status: model.WikilinkStatusAmbiguous},
+				Candidates:       []model.DocumentTitle{{ID: "d4", Title: "Beta", Mtime: 5}, {ID: "d5", Title: "beta"}},
+			},
+		}, nil
+	}
+	h := &DocumentHandler{documents: mock}
+	r := newTestRouter()
+	r.GET("/documents/:id/wikilinks", withUserID("u1"), h.Wikilinks)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/documents/d1/wikilinks", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	items := parseResponseT(t, w)["data"].([]any)
+	require.Len(t, items, 2)
+	assert.Equal(t, map[string]any{
+		"title": "Alpha", "anchor": "Intro", "status": "resolved", "target_id": "d2",
+	}, items[0])
+	ambiguous := items[1].(map[string]any)
+	assert.Equal(t, "ambiguous", ambiguous["status"])
+	assert.Len(t, ambiguous["candidates"], 2)
+}
+
+func TestDocumentHandler_Wikilinks_NotFound(t *testing.T) {
+	mock := newDocMock()
+	mock.listWikilinksFn = func(context.Context, string, string) ([]service.Wikilink, error) {
+		return nil, appErr.ErrNotFound
+	}
+	h := &DocumentHandler{documents: mock}
+	r := newTestRouter()
+	r.GET("/documents/:id/wikilinks", withUserID("u1"), h.Wikilinks)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/documents/d1/wikilinks", nil))
+	assert.Equal(t, float64(errcode.ErrNotFound), parseResponseT(t, w)["code"])
+}
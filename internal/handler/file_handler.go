@@ -17,6 +17,7 @@ import (
 	"github.com/xxxsen/mnote/internal/filestore"
 
 	"github.com/xxxsen/mnote/internal/pkg/errcode"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
 	"github.com/xxxsen/mnote/internal/pkg/response"
 )
 
@@ -105,6 +106,10 @@ func (h *FileHandler) persistUpload(
 			c.Request.Context(), userID, key, fileURL,
 			filename, contentType, size,
 		); err != nil {
+			if errors.Is(err, appErr.ErrForbidden) {
+				handleError(c, err)
+				return
+			}
 			logutil.GetLogger(c.Request.Context()).Error(
 				"create pending asset failed",
 				zap.String("user_id", userID),
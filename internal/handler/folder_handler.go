@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/xxxsen/mnote/internal/pkg/errcode"
+	"github.com/xxxsen/mnote/internal/pkg/response"
+)
+
+type FolderHandler struct {
+	folders IFolderService
+}
+
+func NewFolderHandler(folders IFolderService) *FolderHandler {
+	return &FolderHandler{folders: folders}
+}
+
+type folderCreateRequest struct {
+	ParentID string `json:"parent_id"`
+	Name     string `json:"name"`
+}
+
+type folderRenameRequest struct {
+	Name string `json:"name"`
+}
+
+// folderMoveRequest places a folder under ParentID, empty for the top level.
+// Without Position it goes after its new siblings.
+type folderMoveRequest struct {
+	ParentID string `json:"parent_id"`
+	Position *int   `json:"position"`
+}
+
+type documentFolderRequest struct {
+	FolderID string `json:"folder_id"`
+}
+
+// List returns the folder tree with the number of documents in each folder.
+func (h *FolderHandler) List(c *gin.Context) {
+	nodes, err := h.folders.Tree(c.Request.Context(), getUserID(c))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, toFolderNodeResponses(nodes))
+}
+
+func (h *FolderHandler) Create(c *gin.Context) {
+	var req folderCreateRequest
+	if err := bindJSON(c, &req); err != nil {
+		response.Error(c, errcode.ErrInvalid, "invalid request")
+		return
+	}
+	if req.Name == "" {
+		response.Error(c, errcode.ErrInvalid, "name required")
+		return
+	}
+	folder, err := h.folders.Create(c.Request.Context(), getUserID(c), req.ParentID, req.Name)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, toFolderResponse(*folder))
+}
+
+func (h *FolderHandler) Rename(c *gin.Context) {
+	var req folderRenameRequest
+	if err := bindJSON(c, &req); err != nil {
+		response.Error(c, errcode.ErrInvalid, "invalid request")
+		return
+	}
+	if req.Name == "" {
+		response.Error(c, errcode.ErrInvalid, "name required")
+		return
+	}
+	folder, err := h.folders.Rename(c.Request.Context(), getUserID(c), c.Param("id"), req.Name)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, toFolderResponse(*folder))
+}
+
+func (h *FolderHandler) Move(c *gin.Context) {
+	var req folderMoveRequest
+	if err := bindJSON(c, &req); err != nil {
+		response.Error(c, errcode.ErrInvalid, "invalid request")
+		return
+	}
+	position := -1
+	if req.Position != nil {
+		position = *req.Position
+	}
+	folder, err := h.folders.Move(c.Request.Context(), getUserID(c), c.Param("id"), req.ParentID, position)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, toFolderResponse(*folder))
+}
+
+func (h *FolderHandler) Delete(c *gin.Context) {
+	if err := h.folders.Delete(c.Request.Context(), getUserID(c), c.Param("id")); err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, gin.H{"ok": true})
+}
+
+// MoveDocument files a document in a folder; an empty folder_id moves it to
+// the top level.
+func (h *FolderHandler) MoveDocument(c *gin.Context) {
+	var req documentFolderRequest
+	if err := bindJSON(c, &req); err != nil {
+		response.Error(c, errcode.ErrInvalid, "invalid request")
+		return
+	}
+	if err := h.folders.MoveDocument(c.Request.Context(), getUserID(c), c.Param("id"), req.FolderID); err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, gin.H{"ok": true})
+}
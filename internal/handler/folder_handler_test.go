@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/errcode"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+	"github.com/xxxsen/mnote/internal/service"
+)
+
+func TestFolderHandler_List(t *testing.T) {
+	mock := &mockFolderService{
+		treeFn: func(context.Context, string) ([]service.FolderNode, error) {
+			return []service.FolderNode{{
+				Folder:        model.Folder{ID: "f1", Name: "Work"},
+				DocumentCount: 2,
+				Children: []service.FolderNode{{
+					Folder: model.Folder{ID: "f2", ParentID: "f1", Name: "2024"},
+				}},
+			}}, nil
+		},
+	}
+	h := &FolderHandler{folders: mock}
+	r := newTestRouter()
+	r.GET("/folders", withUserID("u1"), h.List)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/folders", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	data, ok := parseResponseT(t, w)["data"].([]any)
+	require.True(t, ok)
+	require.Len(t, data, 1)
+	root, ok := data[0].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "Work", root["name"])
+	assert.Equal(t, float64(2), root["document_count"])
+	children, ok := root["children"].([]any)
+	require.True(t, ok)
+	require.Len(t, children, 1)
+	child, ok := children[0].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "f1", child["parent_id"])
+	assert.Equal(t, []any{}, child["children"])
+}
+
+func TestFolderHandler_Create(t *testing.T) {
+	mock := &mockFolderService{
+		createFn: func(_ context.Context, _, parentID, name string) (*model.Folder, error) {
+			assert.Equal(t, "f1", parentID)
+			return &model.Folder{ID: "f2", ParentID: parentID, Name: name}, nil
+		},
+	}
+	h := &FolderHandler{folders: mock}
+	r := newTestRouter()
+	r.POST("/folders", withUserID("u1"), h.Create)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, "POST", "/folders", map[string]string{"parent_id": "f1", "name": "Notes"}))
+	assert.Equal(t, float64(0), parseResponseT(t, w)["code"])
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, "POST", "/folders", map[string]string{"name": ""}))
+	assert.Equal(t, float64(errcode.ErrInvalid), parseResponseT(t, w)["code"])
+}
+
+func TestFolderHandler_Move(t *testing.T) {
+	var gotPosition int
+	mock := &mockFolderService{
+		moveFn: func(_ context.Context, _, folderID, parentID string, position int) (*model.Folder, error) {
+			gotPosition = position
+			return &model.Folder{ID: folderID, ParentID: parentID, Position: position}, nil
+		},
+	}
+	h := &FolderHandler{folders: mock}
+	r := newTestRouter()
+	r.PUT("/folders/:id/move", withUserID("u1"), h.Move)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, "PUT", "/folders/f2/move", map[string]any{"parent_id": "f1", "position": 0}))
+	assert.Equal(t, float64(0), parseResponseT(t, w)["code"])
+	assert.Equal(t, 0, gotPosition)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, "PUT", "/folders/f2/move", map[string]any{"parent_id": ""}))
+	assert.Equal(t, float64(0), parseResponseT(t, w)["code"])
+	assert.Equal(t, -1, gotPosition)
+}
+
+func TestFolderHandler_MoveDocument_NotFound(t *testing.T) {
+	mock := &mockFolderService{
+		moveDocumentFn: func(_ context.Context, _, docID, folderID string) error {
+			assert.Equal(t, "d1", docID)
+			assert.Equal(t, "missing", folderID)
+			return appErr.ErrNotFound
+		},
+	}
+	h := &FolderHandler{folders: mock}
+	r := newTestRouter()
+	r.PUT("/documents/:id/folder", withUserID("u1"), h.MoveDocument)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, "PUT", "/documents/d1/folder", map[string]string{"folder_id": "missing"}))
+	assert.Equal(t, float64(errcode.ErrNotFound), parseResponseT(t, w)["code"])
+}
+
+func TestDocumentHandler_List_FolderFilter(t *testing.T) {
+	var got *model.FolderFilter
+	mock := newDocMock()
+	mock.searchFn = func(
+		_ context.Context, _, _, _ string, _ *int, _ []model.PropertyFilter, folder *model.FolderFilter, _, _ uint, _ string,
+	) ([]model.Document, error) {
+		got = folder
+		return []model.Document{{ID: "d1", FolderID: "f1"}}, nil
+	}
+	mock.listTagIDsByDocIDsFn = func(context.Context, string, []string) (map[string][]string, error) {
+		return map[string][]string{}, nil
+	}
+	h := &DocumentHandler{documents: mock}
+	r := newTestRouter()
+	r.GET("/documents", withUserID("u1"), h.List)
+
+	tests := []struct {
+		query string
+		want  *model.FolderFilter
+	}{
+		{query: "", want: nil},
+		{query: "?folder_id=", want: &model.FolderFilter{}},
+		{query: "?folder_id=f1&recursive=1", want: &model.FolderFilter{ID: "f1", Recursive: true}},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/documents"+tt.query, nil))
+		assert.Equal(t, float64(0), parseResponseT(t, w)["code"], tt.query)
+		assert.Equal(t, tt.want, got, tt.query)
+	}
+}
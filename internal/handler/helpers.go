@@ -53,14 +53,17 @@ func parsePage(c *gin.Context, defaultLimit, maxLimit int) (service.Page, error)
 	return page, nil
 }
 
-func bindJSON(c *gin.Context, target any) error {
-	maxBodySize := defaultMaxJSONBodySize
+func maxJSONBodySize(c *gin.Context) int64 {
 	if configured, exists := c.Get(maxJSONBodySizeContextKey); exists {
 		if value, ok := configured.(int64); ok && value > 0 {
-			maxBodySize = value
+			return value
 		}
 	}
-	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBodySize)
+	return defaultMaxJSONBodySize
+}
+
+func bindJSON(c *gin.Context, target any) error {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxJSONBodySize(c))
 	decoder := json.NewDecoder(c.Request.Body)
 	decoder.DisallowUnknownFields()
 	if err := decoder.Decode(target); err != nil {
@@ -60,18 +60,23 @@ func setupRouter(t *testing.T) (http.Handler, func(), func(email, code string) e
 	jwtSecret := []byte("test-secret")
 	runtime := service.NewRuntime(repo.NewTransactor(db))
 	verifyService := service.NewEmailVerificationService(emailCodeRepo, noopSender{}, runtime)
-	authService := service.NewAuthService(userRepo, verifyService, jwtSecret, time.Hour, true, runtime)
+	authService := service.NewAuthService(userRepo, verifyService, jwtSecret, time.Hour, true, nil, runtime)
 	oauthService := service.NewOAuthService(
-		userRepo, oauthRepo, jwtSecret, time.Hour, map[string]oauth.Provider{}, runtime,
+		userRepo, oauthRepo, jwtSecret, time.Hour, map[string]oauth.Provider{}, nil, runtime,
 	)
 	assetService := service.NewAssetService(assetRepo, documentAssetRepo, runtime)
 	documentService := service.NewDocumentService(
 		runtime, docRepo, versionRepo, docTagRepo, shareRepo,
 		tagRepo, userRepo, nil, 10, assetService,
 	)
-	tagService := service.NewTagService(runtime, tagRepo, docTagRepo)
-	exportService := service.NewExportService(docRepo, versionRepo, tagRepo, docTagRepo)
-	templateService := service.NewTemplateService(templateRepo, documentService, tagRepo, runtime)
+	tagService := service.NewTagService(runtime, tagRepo, docTagRepo, templateRepo)
+	folderRepo := repo.NewFolderRepo(db)
+	exportService := service.NewExportService(docRepo, versionRepo, tagRepo, docTagRepo, folderRepo)
+	folderService := service.NewFolderService(runtime, folderRepo, docRepo)
+	workspaceService := service.NewWorkspaceService(repo.NewWorkspaceRepo(db), userRepo, runtime)
+	templateService := service.NewTemplateService(
+		templateRepo, documentService, tagRepo, userRepo, repo.NewTemplateGalleryRepo(db), runtime,
+	)
 
 	tmpDir, err := os.MkdirTemp("", "mnote-upload-*")
 	require.NoError(t, err)
@@ -85,20 +90,33 @@ func setupRouter(t *testing.T) (http.Handler, func(), func(email, code string) e
 	require.NoError(t, err)
 
 	deps := handler.RouterDeps{
-		Auth:            handler.NewAuthHandler(authService),
-		OAuth:           handler.NewOAuthHandler(oauthService),
-		Properties:      handler.NewPropertiesHandler(handler.Properties{}, handler.BannerConfig{}),
-		Documents:       handler.NewDocumentHandler(documentService),
-		Versions:        handler.NewVersionHandler(documentService),
-		Shares:          handler.NewShareHandler(documentService),
-		Tags:            handler.NewTagHandler(tagService),
-		Export:          handler.NewExportHandler(exportService),
-		Files:           handler.NewFileHandler(store, 20*1024*1024),
-		SemanticSearch:  handler.NewSemanticSearchHandler(documentService),
-		Import:          handler.NewImportHandler(nil, 20*1024*1024, service.SaveTempFile),
-		Templates:       handler.NewTemplateHandler(templateService),
-		Assets:          handler.NewAssetHandler(assetService),
-		Todos:           handler.NewTodoHandler(service.NewTodoService(todoRepo, runtime)),
+		Auth:       handler.NewAuthHandler(authService),
+		OAuth:      handler.NewOAuthHandler(oauthService),
+		Properties: handler.NewPropertiesHandler(handler.Properties{}, handler.BannerConfig{}),
+		Documents:  handler.NewDocumentHandler(documentService),
+		Versions:   handler.NewVersionHandler(documentService),
+		Shares:     handler.NewShareHandler(documentService),
+		Collab:     handler.NewCollabHandler(service.NewCollabHub(documentService, workspaceService, time.Minute, runtime), nil),
+		Tags:       handler.NewTagHandler(tagService),
+		Folders:    handler.NewFolderHandler(folderService),
+		Bulk: handler.NewBulkHandler(
+			service.NewBulkService(runtime, documentService, folderService, exportService),
+		),
+		Export:         handler.NewExportHandler(exportService),
+		Files:          handler.NewFileHandler(store, 20*1024*1024),
+		SemanticSearch: handler.NewSemanticSearchHandler(documentService),
+		Import:         handler.NewImportHandler(nil, 20*1024*1024, service.SaveTempFile),
+		Templates:      handler.NewTemplateHandler(templateService),
+		TemplateSchedules: handler.NewTemplateScheduleHandler(service.NewTemplateScheduleService(
+			repo.NewTemplateScheduleRepo(db), templateService, tagRepo, runtime,
+		)),
+		Assets: handler.NewAssetHandler(assetService),
+		Todos:  handler.NewTodoHandler(service.NewTodoService(todoRepo, docRepo, documentService, runtime)),
+		Admin: handler.NewAdminHandler(
+			service.NewAdminService(userRepo, repo.NewAdminRepo(db), store, 10, runtime),
+			service.NewRegistrationService(service.RegistrationPolicy{}, repo.NewInviteRepo(db), runtime),
+		),
+		Workspaces:      handler.NewWorkspaceHandler(workspaceService),
 		JWTSecret:       jwtSecret,
 		MaxJSONBodySize: 2 << 20,
 	}
@@ -3,6 +3,7 @@ package handler
 import (
 	"context"
 	"io"
+	"time"
 
 	"github.com/xxxsen/mnote/internal/filestore"
 	"github.com/xxxsen/mnote/internal/model"
@@ -14,17 +15,21 @@ import (
 // --- IAuthService mock ---
 
 type mockAuthService struct {
-	registerFn       func(ctx context.Context, email, password, code string) (*model.User, string, error)
+	registerFn       func(ctx context.Context, email, password, code, invite string) (*model.User, string, error)
 	loginFn          func(ctx context.Context, email, password string) (*model.User, string, error)
 	sendRegCodeFn    func(ctx context.Context, email string) error
 	updatePasswordFn func(ctx context.Context, userID, current, newPass string) error
+	ensureActiveFn   func(ctx context.Context, userID string) error
+	ensureAdminFn    func(ctx context.Context, userID string) error
 }
 
-func (m *mockAuthService) Register(ctx context.Context, email, password, code string) (*model.User, string, error) {
+func (m *mockAuthService) Register(
+	ctx context.Context, email, password, code, invite string,
+) (*model.User, string, error) {
 	if m.registerFn == nil {
 		panic("mockAuthService.Register not configured")
 	}
-	return m.registerFn(ctx, email, password, code)
+	return m.registerFn(ctx, email, password, code, invite)
 }
 
 func (m *mockAuthService) Login(ctx context.Context, email, password string) (*model.User, string, error) {
@@ -48,22 +53,36 @@ func (m *mockAuthService) UpdatePassword(ctx context.Context, userID, current, n
 	return m.updatePasswordFn(ctx, userID, current, newPass)
 }
 
+func (m *mockAuthService) EnsureActive(ctx context.Context, userID string) error {
+	if m.ensureActiveFn == nil {
+		panic("mockAuthService.EnsureActive not configured")
+	}
+	return m.ensureActiveFn(ctx, userID)
+}
+
+func (m *mockAuthService) EnsureAdmin(ctx context.Context, userID string) error {
+	if m.ensureAdminFn == nil {
+		panic("mockAuthService.EnsureAdmin not configured")
+	}
+	return m.ensureAdminFn(ctx, userID)
+}
+
 // --- IOAuthService mock ---
 
 type mockOAuthService struct {
 	getAuthURLFn      func(provider, state string) (string, error)
-	createStateFn     func(ctx context.Context, provider, purpose, userID, returnTo string) (string, error)
+	createStateFn     func(ctx context.Context, provider, purpose, userID, returnTo, invite string) (string, error)
 	consumeStateFn    func(ctx context.Context, state string) (*service.OAuthState, error)
-	exchangeCodeFn    func(ctx context.Context, provider, code string) (*oauth.Profile, error)
+	exchangeCodeFn    func(ctx context.Context, provider, code, verifier string) (*oauth.Profile, error)
 	bindFn            func(ctx context.Context, userID string, profile *oauth.Profile) error
-	loginOrCreateFn   func(ctx context.Context, profile *oauth.Profile) (*model.User, string, error)
+	loginOrCreateFn   func(ctx context.Context, profile *oauth.Profile, inviteDigest string) (*model.User, string, error)
 	createExchangeFn  func(ctx context.Context, user *model.User) (string, error)
 	consumeExchangeFn func(ctx context.Context, code string) (*service.OAuthExchange, error)
 	listBindingsFn    func(ctx context.Context, userID string) ([]model.OAuthAccount, error)
 	unbindFn          func(ctx context.Context, userID, provider string) error
 }
 
-func (m *mockOAuthService) GetAuthURL(provider, state string) (string, error) {
+func (m *mockOAuthService) GetAuthURL(_ context.Context, provider, state string) (string, error) {
 	if m.getAuthURLFn == nil {
 		panic("mockOAuthService.GetAuthURL not configured")
 	}
@@ -71,12 +90,12 @@ func (m *mockOAuthService) GetAuthURL(provider, state string) (string, error) {
 }
 
 func (m *mockOAuthService) CreateState(
-	ctx context.Context, provider, purpose, userID, returnTo string,
+	ctx context.Context, provider, purpose, userID, returnTo, invite string,
 ) (string, error) {
 	if m.createStateFn == nil {
 		return "test-state", nil
 	}
-	return m.createStateFn(ctx, provider, purpose, userID, returnTo)
+	return m.createStateFn(ctx, provider, purpose, userID, returnTo, invite)
 }
 
 func (m *mockOAuthService) ConsumeState(ctx context.Context, state string) (*service.OAuthState, error) {
@@ -86,11 +105,13 @@ func (m *mockOAuthService) ConsumeState(ctx context.Context, state string) (*ser
 	return m.consumeStateFn(ctx, state)
 }
 
-func (m *mockOAuthService) ExchangeCode(ctx context.Context, provider, code string) (*oauth.Profile, error) {
+func (m *mockOAuthService) ExchangeCode(
+	ctx context.Context, provider, code, verifier string,
+) (*oauth.Profile, error) {
 	if m.exchangeCodeFn == nil {
 		panic("mockOAuthService.ExchangeCode not configured")
 	}
-	return m.exchangeCodeFn(ctx, provider, code)
+	return m.exchangeCodeFn(ctx, provider, code, verifier)
 }
 
 func (m *mockOAuthService) Bind(ctx context.Context, userID string, profile *oauth.Profile) error {
@@ -100,11 +121,13 @@ func (m *mockOAuthService) Bind(ctx context.Context, userID string, profile *oau
 	return m.bindFn(ctx, userID, profile)
 }
 
-func (m *mockOAuthService) LoginOrCreate(ctx context.Context, profile *oauth.Profile) (*model.User, string, error) {
+func (m *mockOAuthService) LoginOrCreate(
+	ctx context.Context, profile *oauth.Profile, inviteDigest string,
+) (*model.User, string, error) {
 	if m.loginOrCreateFn == nil {
 		panic("mockOAuthService.LoginOrCreate not configured")
 	}
-	return m.loginOrCreateFn(ctx, profile)
+	return m.loginOrCreateFn(ctx, profile, inviteDigest)
 }
 
 func (m *mockOAuthService) CreateExchange(ctx context.Context, user *model.User) (string, error) {
@@ -138,8 +161,18 @@ func (m *mockOAuthService) Unbind(ctx context.Context, userID, provider string)
 // --- IDocumentService mock ---
 
 type mockDocumentService struct {
+	graphFn                          func(ctx context.Context, userID string, query service.GraphQuery) (*service.DocumentGraph, error)
+	activityFn                       func(ctx context.Context, userID, from, to, timezone string) (*service.Activity, error)
+	listWikilinksFn                  func(ctx context.Context, userID, docID string) ([]service.Wikilink, error)
+	unlinkedMentionsFn               func(ctx context.Context, userID, docID string, limit int) ([]service.UnlinkedMention, error)
+	convertMentionsFn                func(ctx context.Context, userID, docID, sourceID string, baseRevision int64) (*service.MentionConversion, error)
+	listBrokenLinksFn                func(ctx context.Context, userID string, page service.Page) ([]model.BrokenLink, error)
 	createFn                         func(ctx context.Context, userID string, input service.DocumentCreateInput) (*model.Document, error)
-	searchFn                         func(ctx context.Context, userID, query, tagID string, starred *int, limit, offset uint, orderBy string) ([]model.Document, error)
+	listPropertiesFn                 func(ctx context.Context, userID, docID string) ([]model.DocumentProperty, error)
+	outlineFn                        func(ctx context.Context, userID, docID string) ([]service.OutlineSection, error)
+	getSectionFn                     func(ctx context.Context, userID, docID, section string) (*model.Document, *service.OutlineSection, error)
+	getShareSectionFn                func(ctx context.Context, token, password, section string) (*service.PublicShareDetail, *service.OutlineSection, error)
+	searchFn                         func(ctx context.Context, userID, query, tagID string, starred *int, props []model.PropertyFilter, folder *model.FolderFilter, limit, offset uint, orderBy string) ([]model.Document, error)
 	getFn                            func(ctx context.Context, userID, docID string) (*model.Document, error)
 	updateFn                         func(ctx context.Context, userID, docID string, input service.DocumentUpdateInput) error
 	saveFn                           func(ctx context.Context, userID, docID string, input service.DocumentUpdateInput) (*model.SaveDocumentResult, error)
@@ -164,9 +197,17 @@ type mockDocumentService struct {
 	listShareCommentRepliesByTokenFn func(ctx context.Context, token, password, rootID string, limit, offset int) ([]model.ShareComment, error)
 	createShareCommentByTokenFn      func(ctx context.Context, input service.CreateShareCommentInput) (*model.ShareComment, error)
 	listSharedDocumentsFn            func(ctx context.Context, userID, query string) ([]service.SharedDocumentListItem, error)
+	listCollaboratorsFn              func(ctx context.Context, userID, docID string) ([]model.DocumentCollaborator, error)
+	addCollaboratorFn                func(ctx context.Context, userID, docID, email, role string) (*model.DocumentCollaborator, error)
+	removeCollaboratorFn             func(ctx context.Context, userID, docID, collaboratorID string) error
+	listSharedWithMeFn               func(ctx context.Context, userID string) ([]service.SharedWithMeItem, error)
 	semanticSearchFn                 func(ctx context.Context, userID, query, tagID string, starred *int, limit, offset uint, orderBy, excludeID string) ([]model.Document, []float32, error)
 	semanticSearchDetailedFn         func(ctx context.Context, userID, query string, limit uint, excludeID string) ([]service.SemanticDocumentResult, error)
 	similarDocumentsFn               func(ctx context.Context, userID, documentID string, limit int) (*service.SimilarDocumentList, error)
+	suggestTagsFn                    func(ctx context.Context, userID, docID string, limit int) (*service.TagSuggestionList, error)
+	applyTagSuggestionsFn            func(
+		ctx context.Context, userID string, req service.TagSuggestionApply,
+	) ([]service.TagSuggestionApplyResult, error)
 }
 
 func (m *mockDocumentService) SemanticSearchDetailed(
@@ -213,11 +254,11 @@ func (m *mockDocumentService) Create(ctx context.Context, userID string, input s
 	return m.createFn(ctx, userID, input)
 }
 
-func (m *mockDocumentService) Search(ctx context.Context, userID, query, tagID string, starred *int, limit, offset uint, orderBy string) ([]model.Document, error) {
+func (m *mockDocumentService) Search(ctx context.Context, userID, query, tagID string, starred *int, props []model.PropertyFilter, folder *model.FolderFilter, limit, offset uint, orderBy string) ([]model.Document, error) {
 	if m.searchFn == nil {
 		panic("mockDocumentService.Search not configured")
 	}
-	return m.searchFn(ctx, userID, query, tagID, starred, limit, offset, orderBy)
+	return m.searchFn(ctx, userID, query, tagID, starred, props, folder, limit, offset, orderBy)
 }
 
 func (m *mockDocumentService) Get(ctx context.Context, userID, docID string) (*model.Document, error) {
@@ -413,6 +454,36 @@ func (m *mockDocumentService) ListSharedDocuments(ctx context.Context, userID, q
 	return m.listSharedDocumentsFn(ctx, userID, query)
 }
 
+func (m *mockDocumentService) ListCollaborators(ctx context.Context, userID, docID string) ([]model.DocumentCollaborator, error) {
+	if m.listCollaboratorsFn == nil {
+		panic("mockDocumentService.ListCollaborators not configured")
+	}
+	return m.listCollaboratorsFn(ctx, userID, docID)
+}
+
+func (m *mockDocumentService) AddCollaborator(
+	ctx context.Context, userID, docID, email, role string,
+) (*model.DocumentCollaborator, error) {
+	if m.addCollaboratorFn == nil {
+		panic("mockDocumentService.AddCollaborator not configured")
+	}
+	return m.addCollaboratorFn(ctx, userID, docID, email, role)
+}
+
+func (m *mockDocumentService) RemoveCollaborator(ctx context.Context, userID, docID, collaboratorID string) error {
+	if m.removeCollaboratorFn == nil {
+		panic("mockDocumentService.RemoveCollaborator not configured")
+	}
+	return m.removeCollaboratorFn(ctx, userID, docID, collaboratorID)
+}
+
+func (m *mockDocumentService) ListSharedWithMe(ctx context.Context, userID string) ([]service.SharedWithMeItem, error) {
+	if m.listSharedWithMeFn == nil {
+		panic("mockDocumentService.ListSharedWithMe not configured")
+	}
+	return m.listSharedWithMeFn(ctx, userID)
+}
+
 func (m *mockDocumentService) SemanticSearch(
 	ctx context.Context, userID, query, tagID string, starred *int, limit, offset uint, orderBy, excludeID string,
 ) ([]model.Document, []float32, error) {
@@ -433,6 +504,112 @@ func (m *mockDocumentService) SimilarDocuments(
 	return m.similarDocumentsFn(ctx, userID, documentID, limit)
 }
 
+func (m *mockDocumentService) Graph(
+	ctx context.Context, userID string, query service.GraphQuery,
+) (*service.DocumentGraph, error) {
+	if m.graphFn == nil {
+		panic("mockDocumentService.Graph not configured")
+	}
+	return m.graphFn(ctx, userID, query)
+}
+
+func (m *mockDocumentService) Activity(
+	ctx context.Context, userID, from, to, timezone string,
+) (*service.Activity, error) {
+	if m.activityFn == nil {
+		panic("mockDocumentService.Activity not configured")
+	}
+	return m.activityFn(ctx, userID, from, to, timezone)
+}
+
+func (m *mockDocumentService) ListWikilinks(ctx context.Context, userID, docID string) ([]service.Wikilink, error) {
+	if m.listWikilinksFn == nil {
+		panic("mockDocumentService.ListWikilinks not configured")
+	}
+	return m.listWikilinksFn(ctx, userID, docID)
+}
+
+func (m *mockDocumentService) Outline(ctx context.Context, userID, docID string) ([]service.OutlineSection, error) {
+	if m.outlineFn == nil {
+		panic("mockDocumentService.Outline not configured")
+	}
+	return m.outlineFn(ctx, userID, docID)
+}
+
+func (m *mockDocumentService) GetSection(
+	ctx context.Context, userID, docID, section string,
+) (*model.Document, *service.OutlineSection, error) {
+	if m.getSectionFn == nil {
+		panic("mockDocumentService.GetSection not configured")
+	}
+	return m.getSectionFn(ctx, userID, docID, section)
+}
+
+func (m *mockDocumentService) GetShareSection(
+	ctx context.Context, token, password, section string,
+) (*service.PublicShareDetail, *service.OutlineSection, error) {
+	if m.getShareSectionFn == nil {
+		panic("mockDocumentService.GetShareSection not configured")
+	}
+	return m.getShareSectionFn(ctx, token, password, section)
+}
+
+func (m *mockDocumentService) ListProperties(ctx context.Context, userID, docID string) ([]model.DocumentProperty, error) {
+	if m.listPropertiesFn == nil {
+		panic("mockDocumentService.ListProperties not configured")
+	}
+	return m.listPropertiesFn(ctx, userID, docID)
+}
+
+func (m *mockDocumentService) UnlinkedMentions(
+	ctx context.Context, userID, docID string, limit int,
+) ([]service.UnlinkedMention, error) {
+	if m.unlinkedMentionsFn == nil {
+		panic("mockDocumentService.UnlinkedMentions not configured")
+	}
+	return m.unlinkedMentionsFn(ctx, userID, docID, limit)
+}
+
+func (m *mockDocumentService) ConvertMentions(
+	ctx context.Context, userID, docID, sourceID string, baseRevision int64,
+) (*service.MentionConversion, error) {
+	if m.convertMentionsFn == nil {
+		panic("mockDocumentService.ConvertMentions not configured")
+	}
+	return m.convertMentionsFn(ctx, userID, docID, sourceID, baseRevision)
+}
+
+func (m *mockDocumentService) ListBrokenLinks(
+	ctx context.Context, userID string, page service.Page,
+) ([]model.BrokenLink, error) {
+	if m.listBrokenLinksFn == nil {
+		panic("mockDocumentService.ListBrokenLinks not configured")
+	}
+	return m.listBrokenLinksFn(ctx, userID, page)
+}
+
+func (m *mockDocumentService) SuggestTags(
+	ctx context.Context,
+	userID, docID string,
+	limit int,
+) (*service.TagSuggestionList, error) {
+	if m.suggestTagsFn == nil {
+		panic("mockDocumentService.SuggestTags not configured")
+	}
+	return m.suggestTagsFn(ctx, userID, docID, limit)
+}
+
+func (m *mockDocumentService) ApplyTagSuggestions(
+	ctx context.Context,
+	userID string,
+	req service.TagSuggestionApply,
+) ([]service.TagSuggestionApplyResult, error) {
+	if m.applyTagSuggestionsFn == nil {
+		panic("mockDocumentService.ApplyTagSuggestions not configured")
+	}
+	return m.applyTagSuggestionsFn(ctx, userID, req)
+}
+
 // --- ITagService mock ---
 
 type mockTagService struct {
@@ -444,6 +621,9 @@ type mockTagService struct {
 	listSummaryFn  func(ctx context.Context, userID, query string, limit, offset int) ([]model.TagSummary, error)
 	deleteFn       func(ctx context.Context, userID, tagID string) error
 	updatePinnedFn func(ctx context.Context, userID, tagID string, pinned int) error
+	updateFn       func(ctx context.Context, userID, tagID string, input service.TagUpdateInput) (*model.Tag, error)
+	renameFn       func(ctx context.Context, userID, tagID, name string) (*model.Tag, error)
+	mergeFn        func(ctx context.Context, userID, sourceID, targetID string) (*model.Tag, error)
 }
 
 func (m *mockTagService) Create(ctx context.Context, userID, name string) (*model.Tag, error) {
@@ -502,6 +682,105 @@ func (m *mockTagService) UpdatePinned(ctx context.Context, userID, tagID string,
 	return m.updatePinnedFn(ctx, userID, tagID, pinned)
 }
 
+func (m *mockTagService) Update(
+	ctx context.Context, userID, tagID string, input service.TagUpdateInput,
+) (*model.Tag, error) {
+	if m.updateFn == nil {
+		panic("mockTagService.Update not configured")
+	}
+	return m.updateFn(ctx, userID, tagID, input)
+}
+
+func (m *mockTagService) Rename(ctx context.Context, userID, tagID, name string) (*model.Tag, error) {
+	if m.renameFn == nil {
+		panic("mockTagService.Rename not configured")
+	}
+	return m.renameFn(ctx, userID, tagID, name)
+}
+
+func (m *mockTagService) Merge(ctx context.Context, userID, sourceID, targetID string) (*model.Tag, error) {
+	if m.mergeFn == nil {
+		panic("mockTagService.Merge not configured")
+	}
+	return m.mergeFn(ctx, userID, sourceID, targetID)
+}
+
+type mockFolderService struct {
+	treeFn         func(ctx context.Context, userID string) ([]service.FolderNode, error)
+	createFn       func(ctx context.Context, userID, parentID, name string) (*model.Folder, error)
+	renameFn       func(ctx context.Context, userID, folderID, name string) (*model.Folder, error)
+	moveFn         func(ctx context.Context, userID, folderID, parentID string, position int) (*model.Folder, error)
+	deleteFn       func(ctx context.Context, userID, folderID string) error
+	moveDocumentFn func(ctx context.Context, userID, docID, folderID string) error
+}
+
+func (m *mockFolderService) Tree(ctx context.Context, userID string) ([]service.FolderNode, error) {
+	if m.treeFn == nil {
+		panic("mockFolderService.Tree not configured")
+	}
+	return m.treeFn(ctx, userID)
+}
+
+func (m *mockFolderService) Create(ctx context.Context, userID, parentID, name string) (*model.Folder, error) {
+	if m.createFn == nil {
+		panic("mockFolderService.Create not configured")
+	}
+	return m.createFn(ctx, userID, parentID, name)
+}
+
+func (m *mockFolderService) Rename(ctx context.Context, userID, folderID, name string) (*model.Folder, error) {
+	if m.renameFn == nil {
+		panic("mockFolderService.Rename not configured")
+	}
+	return m.renameFn(ctx, userID, folderID, name)
+}
+
+func (m *mockFolderService) Move(
+	ctx context.Context, userID, folderID, parentID string, position int,
+) (*model.Folder, error) {
+	if m.moveFn == nil {
+		panic("mockFolderService.Move not configured")
+	}
+	return m.moveFn(ctx, userID, folderID, parentID, position)
+}
+
+func (m *mockFolderService) Delete(ctx context.Context, userID, folderID string) error {
+	if m.deleteFn == nil {
+		panic("mockFolderService.Delete not configured")
+	}
+	return m.deleteFn(ctx, userID, folderID)
+}
+
+func (m *mockFolderService) MoveDocument(ctx context.Context, userID, docID, folderID string) error {
+	if m.moveDocumentFn == nil {
+		panic("mockFolderService.MoveDocument not configured")
+	}
+	return m.moveDocumentFn(ctx, userID, docID, folderID)
+}
+
+// --- IBulkService mock ---
+
+type mockBulkService struct {
+	applyFn  func(ctx context.Context, userID string, req service.BulkRequest) ([]service.BulkItemResult, error)
+	exportFn func(ctx context.Context, userID string, selection service.BulkSelection) (string, error)
+}
+
+func (m *mockBulkService) Apply(
+	ctx context.Context, userID string, req service.BulkRequest,
+) ([]service.BulkItemResult, error) {
+	if m.applyFn == nil {
+		panic("mockBulkService.Apply not configured")
+	}
+	return m.applyFn(ctx, userID, req)
+}
+
+func (m *mockBulkService) Export(ctx context.Context, userID string, selection service.BulkSelection) (string, error) {
+	if m.exportFn == nil {
+		panic("mockBulkService.Export not configured")
+	}
+	return m.exportFn(ctx, userID, selection)
+}
+
 // --- IExportService mock ---
 
 type mockExportService struct {
@@ -586,6 +865,10 @@ type mockTemplateHandlerService struct {
 	updateFn    func(ctx context.Context, userID, id string, input service.UpdateTemplateInput) error
 	deleteFn    func(ctx context.Context, userID, id string) error
 	createDocFn func(ctx context.Context, userID string, input service.CreateDocumentFromTemplateInput) (*model.Document, error)
+	exportFn    func(ctx context.Context, userID string, ids []string) (*model.TemplateBundle, error)
+	importFn    func(ctx context.Context, userID string, bundle *model.TemplateBundle) (*service.TemplateImportResult, error)
+	galleryFn   func(ctx context.Context) ([]model.GalleryTemplate, error)
+	copyFn      func(ctx context.Context, userID, slug, name string) (*model.Template, error)
 }
 
 func (m *mockTemplateHandlerService) List(ctx context.Context, userID string) ([]model.Template, error) {
@@ -643,6 +926,40 @@ func (m *mockTemplateHandlerService) CreateDocumentFromTemplate(
 	return m.createDocFn(ctx, userID, input)
 }
 
+func (m *mockTemplateHandlerService) ExportBundle(
+	ctx context.Context, userID string, ids []string,
+) (*model.TemplateBundle, error) {
+	if m.exportFn == nil {
+		panic("mockTemplateHandlerService.ExportBundle not configured")
+	}
+	return m.exportFn(ctx, userID, ids)
+}
+
+func (m *mockTemplateHandlerService) ImportBundle(
+	ctx context.Context, userID string, bundle *model.TemplateBundle,
+) (*service.TemplateImportResult, error) {
+	if m.importFn == nil {
+		panic("mockTemplateHandlerService.ImportBundle not configured")
+	}
+	return m.importFn(ctx, userID, bundle)
+}
+
+func (m *mockTemplateHandlerService) ListGallery(ctx context.Context) ([]model.GalleryTemplate, error) {
+	if m.galleryFn == nil {
+		panic("mockTemplateHandlerService.ListGallery not configured")
+	}
+	return m.galleryFn(ctx)
+}
+
+func (m *mockTemplateHandlerService) CopyFromGallery(
+	ctx context.Context, userID, slug, name string,
+) (*model.Template, error) {
+	if m.copyFn == nil {
+		panic("mockTemplateHandlerService.CopyFromGallery not configured")
+	}
+	return m.copyFn(ctx, userID, slug, name)
+}
+
 // --- IAssetHandlerService mock ---
 
 type mockAssetHandlerService struct {
@@ -677,18 +994,36 @@ func (m *mockAssetHandlerService) RecordUpload(
 // --- ITodoHandlerService mock ---
 
 type mockTodoHandlerService struct {
-	createFn     func(ctx context.Context, userID, content, dueDate string, done bool) (*model.Todo, error)
+	createFn     func(ctx context.Context, userID string, input service.TodoCreateInput) (*model.Todo, error)
 	listByDateFn func(ctx context.Context, userID, start, end string) ([]model.Todo, error)
+	listTodosFn  func(ctx context.Context, userID string, query model.TodoListQuery) ([]model.Todo, error)
 	toggleDoneFn func(ctx context.Context, userID, todoID string, done bool) error
 	updateFn     func(ctx context.Context, userID, todoID, content string) (*model.Todo, error)
+	recurrenceFn func(ctx context.Context, userID, todoID string, recurrence *model.TodoRecurrence) (*model.Todo, error)
+	attributesFn func(ctx context.Context, userID, todoID string, input service.TodoAttributesInput) (*model.Todo, error)
 	deleteFn     func(ctx context.Context, userID, todoID string) error
 }
 
-func (m *mockTodoHandlerService) CreateTodo(ctx context.Context, userID, content, dueDate string, done bool) (*model.Todo, error) {
+func (m *mockTodoHandlerService) CreateTodo(
+	ctx context.Context,
+	userID string,
+	input service.TodoCreateInput,
+) (*model.Todo, error) {
 	if m.createFn == nil {
 		panic("mockTodoHandlerService.CreateTodo not configured")
 	}
-	return m.createFn(ctx, userID, content, dueDate, done)
+	return m.createFn(ctx, userID, input)
+}
+
+func (m *mockTodoHandlerService) ListTodos(
+	ctx context.Context,
+	userID string,
+	query model.TodoListQuery,
+) ([]model.Todo, error) {
+	if m.listTodosFn == nil {
+		panic("mockTodoHandlerService.ListTodos not configured")
+	}
+	return m.listTodosFn(ctx, userID, query)
 }
 
 func (m *mockTodoHandlerService) ListByDateRange(ctx context.Context, userID, start, end string) ([]model.Todo, error) {
@@ -712,6 +1047,28 @@ func (m *mockTodoHandlerService) UpdateContent(ctx context.Context, userID, todo
 	return m.updateFn(ctx, userID, todoID, content)
 }
 
+func (m *mockTodoHandlerService) UpdateRecurrence(
+	ctx context.Context,
+	userID, todoID string,
+	recurrence *model.TodoRecurrence,
+) (*model.Todo, error) {
+	if m.recurrenceFn == nil {
+		panic("mockTodoHandlerService.UpdateRecurrence not configured")
+	}
+	return m.recurrenceFn(ctx, userID, todoID, recurrence)
+}
+
+func (m *mockTodoHandlerService) UpdateAttributes(
+	ctx context.Context,
+	userID, todoID string,
+	input service.TodoAttributesInput,
+) (*model.Todo, error) {
+	if m.attributesFn == nil {
+		panic("mockTodoHandlerService.UpdateAttributes not configured")
+	}
+	return m.attributesFn(ctx, userID, todoID, input)
+}
+
 func (m *mockTodoHandlerService) DeleteTodo(ctx context.Context, userID, todoID string) error {
 	if m.deleteFn == nil {
 		panic("mockTodoHandlerService.DeleteTodo not configured")
@@ -785,3 +1142,171 @@ func (m *mockFileStore) PublicURL(key string) string {
 	}
 	return resolveFileURL(key)
 }
+
+type mockTemplateScheduleHandlerService struct {
+	listFn   func(ctx context.Context, userID string) ([]model.TemplateSchedule, error)
+	createFn func(ctx context.Context, userID string, input service.TemplateScheduleInput) (*model.TemplateSchedule, error)
+	updateFn func(
+		ctx context.Context, userID, scheduleID string, input service.TemplateScheduleInput,
+	) (*model.TemplateSchedule, error)
+	deleteFn func(ctx context.Context, userID, scheduleID string) error
+}
+
+func (m *mockTemplateScheduleHandlerService) List(
+	ctx context.Context, userID string,
+) ([]model.TemplateSchedule, error) {
+	if m.listFn == nil {
+		panic("mockTemplateScheduleHandlerService.List not configured")
+	}
+	return m.listFn(ctx, userID)
+}
+
+func (m *mockTemplateScheduleHandlerService) Create(
+	ctx context.Context, userID string, input service.TemplateScheduleInput,
+) (*model.TemplateSchedule, error) {
+	if m.createFn == nil {
+		panic("mockTemplateScheduleHandlerService.Create not configured")
+	}
+	return m.createFn(ctx, userID, input)
+}
+
+func (m *mockTemplateScheduleHandlerService) Update(
+	ctx context.Context, userID, scheduleID string, input service.TemplateScheduleInput,
+) (*model.TemplateSchedule, error) {
+	if m.updateFn == nil {
+		panic("mockTemplateScheduleHandlerService.Update not configured")
+	}
+	return m.updateFn(ctx, userID, scheduleID, input)
+}
+
+func (m *mockTemplateScheduleHandlerService) Delete(ctx context.Context, userID, scheduleID string) error {
+	if m.deleteFn == nil {
+		panic("mockTemplateScheduleHandlerService.Delete not configured")
+	}
+	return m.deleteFn(ctx, userID, scheduleID)
+}
+
+// --- IAdminHandlerService mock ---
+
+type mockAdminHandlerService struct {
+	listUsersFn   func(ctx context.Context) ([]model.User, error)
+	setDisabledFn func(ctx context.Context, ref string, disabled bool) (*model.User, error)
+	statsFn       func(ctx context.Context) ([]model.UserStats, error)
+}
+
+func (m *mockAdminHandlerService) ListUsers(ctx context.Context) ([]model.User, error) {
+	if m.listUsersFn == nil {
+		panic("mockAdminHandlerService.ListUsers not configured")
+	}
+	return m.listUsersFn(ctx)
+}
+
+func (m *mockAdminHandlerService) SetDisabled(ctx context.Context, ref string, disabled bool) (*model.User, error) {
+	if m.setDisabledFn == nil {
+		panic("mockAdminHandlerService.SetDisabled not configured")
+	}
+	return m.setDisabledFn(ctx, ref, disabled)
+}
+
+func (m *mockAdminHandlerService) Stats(ctx context.Context) ([]model.UserStats, error) {
+	if m.statsFn == nil {
+		panic("mockAdminHandlerService.Stats not configured")
+	}
+	return m.statsFn(ctx)
+}
+
+// --- IInviteService mock ---
+
+type mockInviteService struct {
+	createFn func(ctx context.Context, createdBy, note string, ttl time.Duration) (*model.InviteCode, string, error)
+	listFn   func(ctx context.Context) ([]model.InviteCode, error)
+	deleteFn func(ctx context.Context, id string) error
+}
+
+func (m *mockInviteService) CreateInvite(
+	ctx context.Context, createdBy, note string, ttl time.Duration,
+) (*model.InviteCode, string, error) {
+	if m.createFn == nil {
+		panic("mockInviteService.CreateInvite not configured")
+	}
+	return m.createFn(ctx, createdBy, note, ttl)
+}
+
+func (m *mockInviteService) ListInvites(ctx context.Context) ([]model.InviteCode, error) {
+	if m.listFn == nil {
+		panic("mockInviteService.ListInvites not configured")
+	}
+	return m.listFn(ctx)
+}
+
+func (m *mockInviteService) DeleteInvite(ctx context.Context, id string) error {
+	if m.deleteFn == nil {
+		panic("mockInviteService.DeleteInvite not configured")
+	}
+	return m.deleteFn(ctx, id)
+}
+
+type mockWorkspaceService struct {
+	authorizeFn    func(ctx context.Context, userID, workspaceID string) (service.WorkspaceAccess, error)
+	listFn         func(ctx context.Context, userID string) ([]model.Workspace, error)
+	createFn       func(ctx context.Context, userID, name string) (*model.Workspace, error)
+	addMemberFn    func(ctx context.Context, userID, workspaceID, email, role string) (*model.WorkspaceMember, error)
+	removeMemberFn func(ctx context.Context, userID, workspaceID, memberID string) error
+}
+
+func (m *mockWorkspaceService) Authorize(
+	ctx context.Context, userID, workspaceID string,
+) (service.WorkspaceAccess, error) {
+	if m.authorizeFn == nil {
+		panic("mockWorkspaceService.Authorize not configured")
+	}
+	return m.authorizeFn(ctx, userID, workspaceID)
+}
+
+func (m *mockWorkspaceService) List(ctx context.Context, userID string) ([]model.Workspace, error) {
+	if m.listFn == nil {
+		panic("mockWorkspaceService.List not configured")
+	}
+	return m.listFn(ctx, userID)
+}
+
+func (m *mockWorkspaceService) Create(ctx context.Context, userID, name string) (*model.Workspace, error) {
+	if m.createFn == nil {
+		panic("mockWorkspaceService.Create not configured")
+	}
+	return m.createFn(ctx, userID, name)
+}
+
+func (m *mockWorkspaceService) Rename(context.Context, string, string, string) (*model.Workspace, error) {
+	panic("mockWorkspaceService.Rename not configured")
+}
+
+func (m *mockWorkspaceService) Delete(context.Context, string, string) error {
+	panic("mockWorkspaceService.Delete not configured")
+}
+
+func (m *mockWorkspaceService) ListMembers(context.Context, string, string) ([]model.WorkspaceMember, error) {
+	panic("mockWorkspaceService.ListMembers not configured")
+}
+
+func (m *mockWorkspaceService) AddMember(
+	ctx context.Context, userID, workspaceID, email, role string,
+) (*model.WorkspaceMember, error) {
+	if m.addMemberFn == nil {
+		panic("mockWorkspaceService.AddMember not configured")
+	}
+	return m.addMemberFn(ctx, userID, workspaceID, email, role)
+}
+
+func (m *mockWorkspaceService) UpdateMemberRole(
+	context.Context, string, string, string, string,
+) (*model.WorkspaceMember, error) {
+	panic("mockWorkspaceService.UpdateMemberRole not configured")
+}
+
+func (m *mockWorkspaceService) RemoveMember(ctx context.Context, userID, workspaceID, memberID string) error {
+	if m.removeMemberFn == nil {
+		panic("mockWorkspaceService.RemoveMember not configured")
+	}
+	return m.removeMemberFn(ctx, userID, workspaceID, memberID)
+}
@@ -25,12 +25,14 @@ func NewOAuthHandler(oauth IOAuthService) *OAuthHandler {
 
 func (h *OAuthHandler) AuthURL(c *gin.Context) {
 	provider := strings.ToLower(c.Param("provider"))
-	state, err := h.oauth.CreateState(c.Request.Context(), provider, "login", "", "/docs")
+	state, err := h.oauth.CreateState(
+		c.Request.Context(), provider, "login", "", "/docs", c.Query("invite"),
+	)
 	if err != nil {
 		handleError(c, err)
 		return
 	}
-	authURL, err := h.oauth.GetAuthURL(provider, state)
+	authURL, err := h.oauth.GetAuthURL(c.Request.Context(), provider, state)
 	if err != nil {
 		handleError(c, err)
 		return
@@ -42,13 +44,13 @@ func (h *OAuthHandler) BindURL(c *gin.Context) {
 	provider := strings.ToLower(c.Param("provider"))
 	returnTo := c.Query("return")
 	state, err := h.oauth.CreateState(
-		c.Request.Context(), provider, "bind", getUserID(c), returnTo,
+		c.Request.Context(), provider, "bind", getUserID(c), returnTo, "",
 	)
 	if err != nil {
 		handleError(c, err)
 		return
 	}
-	authURL, err := h.oauth.GetAuthURL(provider, state)
+	authURL, err := h.oauth.GetAuthURL(c.Request.Context(), provider, state)
 	if err != nil {
 		handleError(c, err)
 		return
@@ -72,7 +74,7 @@ func (h *OAuthHandler) Callback(c *gin.Context) {
 		h.redirectAuthError(c, "invalid", stored.Provider)
 		return
 	}
-	profile, err := h.oauth.ExchangeCode(c.Request.Context(), stored.Provider, code)
+	profile, err := h.oauth.ExchangeCode(c.Request.Context(), stored.Provider, code, stored.CodeVerifier)
 	if err != nil {
 		h.redirectAuthError(c, mapOAuthError(err), stored.Provider)
 		return
@@ -85,7 +87,7 @@ func (h *OAuthHandler) Callback(c *gin.Context) {
 		h.redirectBindResult(c, stored.ReturnTo, "bound", stored.Provider)
 		return
 	}
-	user, _, err := h.oauth.LoginOrCreate(c.Request.Context(), profile)
+	user, _, err := h.oauth.LoginOrCreate(c.Request.Context(), profile, stored.InviteDigest)
 	if err != nil {
 		h.redirectAuthError(c, mapOAuthError(err), stored.Provider)
 		return
@@ -194,6 +196,8 @@ func mapOAuthError(err error) string {
 		return "invalid"
 	case stderrors.Is(err, appErr.ErrNotFound):
 		return "not_found"
+	case stderrors.Is(err, appErr.ErrForbidden):
+		return "forbidden"
 	default:
 		return "internal"
 	}
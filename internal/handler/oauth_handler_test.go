@@ -22,11 +22,12 @@ func newOAuthHandler(mock *mockOAuthService) *OAuthHandler {
 
 func TestOAuthHandlerAuthURL(t *testing.T) {
 	mock := &mockOAuthService{
-		createStateFn: func(_ context.Context, provider, purpose, userID, returnTo string) (string, error) {
+		createStateFn: func(_ context.Context, provider, purpose, userID, returnTo, invite string) (string, error) {
 			assert.Equal(t, "github", provider)
 			assert.Equal(t, "login", purpose)
 			assert.Empty(t, userID)
 			assert.Equal(t, "/docs", returnTo)
+			assert.Equal(t, "code-1", invite)
 			return "state-1", nil
 		},
 		getAuthURLFn: func(provider, state string) (string, error) {
@@ -40,7 +41,7 @@ func TestOAuthHandlerAuthURL(t *testing.T) {
 	router.GET("/auth/oauth/:provider/url", handler.AuthURL)
 
 	recorder := httptest.NewRecorder()
-	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/auth/oauth/github/url", nil))
+	router.ServeHTTP(recorder, httptest.NewRequest("GET", "/auth/oauth/github/url?invite=code-1", nil))
 
 	require.Equal(t, http.StatusOK, recorder.Code)
 	data := parseResponseT(t, recorder)["data"].(map[string]any)
@@ -49,7 +50,7 @@ func TestOAuthHandlerAuthURL(t *testing.T) {
 
 func TestOAuthHandlerBindURL(t *testing.T) {
 	mock := &mockOAuthService{
-		createStateFn: func(_ context.Context, provider, purpose, userID, returnTo string) (string, error) {
+		createStateFn: func(_ context.Context, provider, purpose, userID, returnTo, _ string) (string, error) {
 			assert.Equal(t, "bind", purpose)
 			assert.Equal(t, "u1", userID)
 			assert.Equal(t, "/settings", returnTo)
@@ -74,16 +75,17 @@ func TestOAuthHandlerCallbackLoginUsesPersistentTokens(t *testing.T) {
 	mock := &mockOAuthService{
 		consumeStateFn: func(_ context.Context, raw string) (*service.OAuthState, error) {
 			assert.Equal(t, "state-1", raw)
-			return &service.OAuthState{Provider: "github", Purpose: "login"}, nil
+			return &service.OAuthState{Provider: "github", Purpose: "login", CodeVerifier: "verifier-1"}, nil
 		},
-		exchangeCodeFn: func(_ context.Context, provider, code string) (*oauth.Profile, error) {
+		exchangeCodeFn: func(_ context.Context, provider, code, verifier string) (*oauth.Profile, error) {
 			assert.Equal(t, "github", provider)
 			assert.Equal(t, "provider-code", code)
+			assert.Equal(t, "verifier-1", verifier)
 			return &oauth.Profile{
 				Provider: "github", ProviderUserID: "gh-1", Email: "user@example.com",
 			}, nil
 		},
-		loginOrCreateFn: func(_ context.Context, _ *oauth.Profile) (*model.User, string, error) {
+		loginOrCreateFn: func(_ context.Context, _ *oauth.Profile, _ string) (*model.User, string, error) {
 			return &model.User{ID: "u1", Email: "user@example.com"}, "", nil
 		},
 		createExchangeFn: func(_ context.Context, user *model.User) (string, error) {
@@ -112,7 +114,7 @@ func TestOAuthHandlerCallbackBindAndSafeRedirect(t *testing.T) {
 				ReturnTo: "//evil.example",
 			}, nil
 		},
-		exchangeCodeFn: func(context.Context, string, string) (*oauth.Profile, error) {
+		exchangeCodeFn: func(context.Context, string, string, string) (*oauth.Profile, error) {
 			return &oauth.Profile{
 				Provider: "github", ProviderUserID: "gh-1", Email: "user@example.com",
 			}, nil
@@ -212,7 +214,7 @@ func TestOAuthHandlerBindings(t *testing.T) {
 
 func TestOAuthHandlerErrors(t *testing.T) {
 	handler := newOAuthHandler(&mockOAuthService{
-		createStateFn: func(context.Context, string, string, string, string) (string, error) {
+		createStateFn: func(context.Context, string, string, string, string, string) (string, error) {
 			return "", errors.New("db unavailable")
 		},
 	})
@@ -7,11 +7,19 @@ import (
 )
 
 type Properties struct {
-	EnableGithubOauth   bool `json:"enable_github_oauth"`
-	EnableGoogleOauth   bool `json:"enable_google_oauth"`
-	EnableUserRegister  bool `json:"enable_user_register"`
-	EnableEmailRegister bool `json:"enable_email_register"`
-	EnableTestMode      bool `json:"enable_test_mode"`
+	EnableGithubOauth   bool                   `json:"enable_github_oauth"`
+	EnableGoogleOauth   bool                   `json:"enable_google_oauth"`
+	EnableUserRegister  bool                   `json:"enable_user_register"`
+	EnableEmailRegister bool                   `json:"enable_email_register"`
+	EnableTestMode      bool                   `json:"enable_test_mode"`
+	OIDCProviders       []OIDCProviderProperty `json:"oidc_providers"`
+	RegistrationMode    string                 `json:"registration_mode"`
+}
+
+// OIDCProviderProperty describes a configured OpenID Connect login button.
+type OIDCProviderProperty struct {
+	Name        string `json:"name"`
+	DisplayName string `json:"display_name"`
 }
 
 type BannerConfig struct {
@@ -2,6 +2,7 @@ package handler
 
 import (
 	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
 	"github.com/xxxsen/mnote/internal/service"
 )
 
@@ -11,6 +12,7 @@ import (
 type userResponse struct {
 	ID    string `json:"id"`
 	Email string `json:"email"`
+	Role  string `json:"role"`
 	Ctime int64  `json:"ctime"`
 	Mtime int64  `json:"mtime"`
 }
@@ -20,23 +22,49 @@ func toUserResponse(user *model.User) *userResponse {
 		return nil
 	}
 	return &userResponse{
-		ID: user.ID, Email: user.Email, Ctime: user.Ctime, Mtime: user.Mtime,
+		ID: user.ID, Email: user.Email, Role: user.Role, Ctime: user.Ctime, Mtime: user.Mtime,
 	}
 }
 
 type documentResponse struct {
-	ID              string `json:"id"`
-	UserID          string `json:"user_id"`
-	Title           string `json:"title"`
-	Content         string `json:"content"`
-	State           int    `json:"state"`
-	Pinned          int    `json:"pinned"`
-	Starred         int    `json:"starred"`
-	Ctime           int64  `json:"ctime"`
-	Mtime           int64  `json:"mtime"`
-	ContentHash     string `json:"content_hash"`
-	ContentMtime    int64  `json:"content_mtime"`
-	ContentRevision int64  `json:"content_revision"`
+	ID              string                `json:"id"`
+	UserID          string                `json:"user_id"`
+	Title           string                `json:"title"`
+	Content         string                `json:"content"`
+	State           int                   `json:"state"`
+	Pinned          int                   `json:"pinned"`
+	Starred         int                   `json:"starred"`
+	FolderID        string                `json:"folder_id"`
+	Ctime           int64                 `json:"ctime"`
+	Mtime           int64                 `json:"mtime"`
+	ContentHash     string                `json:"content_hash"`
+	ContentMtime    int64                 `json:"content_mtime"`
+	ContentRevision int64                 `json:"content_revision"`
+	Stats           documentStatsResponse `json:"stats"`
+}
+
+type documentStatsResponse struct {
+	WordCount      int `json:"word_count"`
+	CharCount      int `json:"char_count"`
+	ReadingMinutes int `json:"reading_minutes"`
+	CodeBlocks     int `json:"code_blocks"`
+	Images         int `json:"images"`
+	Links          int `json:"links"`
+	TasksTotal     int `json:"tasks_total"`
+	TasksDone      int `json:"tasks_done"`
+}
+
+type documentStatsTotalsResponse struct {
+	documentStatsResponse
+	EditedDocuments int `json:"edited_documents"`
+}
+
+func toDocumentStatsResponse(stats model.DocumentStats) documentStatsResponse {
+	return documentStatsResponse{
+		WordCount: stats.WordCount, CharCount: stats.CharCount, ReadingMinutes: stats.ReadingMinutes,
+		CodeBlocks: stats.CodeBlocks, Images: stats.Images, Links: stats.Links,
+		TasksTotal: stats.TasksTotal, TasksDone: stats.TasksDone,
+	}
 }
 
 func toDocumentResponse(doc model.Document) documentResponse {
@@ -48,11 +76,13 @@ func toDocumentResponse(doc model.Document) documentResponse {
 		State:           doc.State,
 		Pinned:          doc.Pinned,
 		Starred:         doc.Starred,
+		FolderID:        doc.FolderID,
 		Ctime:           doc.Ctime,
 		Mtime:           doc.Mtime,
 		ContentHash:     doc.ContentHash,
 		ContentMtime:    doc.ContentMtime,
 		ContentRevision: doc.ContentRevision,
+		Stats:           toDocumentStatsResponse(doc.Stats),
 	}
 }
 
@@ -65,18 +95,22 @@ func toDocumentResponses(docs []model.Document) []documentResponse {
 }
 
 type tagResponse struct {
-	ID     string `json:"id"`
-	UserID string `json:"user_id"`
-	Name   string `json:"name"`
-	Pinned int    `json:"pinned"`
-	Ctime  int64  `json:"ctime"`
-	Mtime  int64  `json:"mtime"`
+	ID          string `json:"id"`
+	UserID      string `json:"user_id"`
+	Name        string `json:"name"`
+	Color       string `json:"color"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"`
+	Pinned      int    `json:"pinned"`
+	Ctime       int64  `json:"ctime"`
+	Mtime       int64  `json:"mtime"`
 }
 
 func toTagResponse(tag model.Tag) tagResponse {
 	return tagResponse{
-		ID: tag.ID, UserID: tag.UserID, Name: tag.Name, Pinned: tag.Pinned,
-		Ctime: tag.Ctime, Mtime: tag.Mtime,
+		ID: tag.ID, UserID: tag.UserID, Name: tag.Name,
+		Color: tag.Color, Description: tag.Description, Icon: tag.Icon,
+		Pinned: tag.Pinned, Ctime: tag.Ctime, Mtime: tag.Mtime,
 	}
 }
 
@@ -89,36 +123,124 @@ func toTagResponses(tags []model.Tag) []tagResponse {
 }
 
 type tagSummaryResponse struct {
-	ID     string `json:"id"`
-	Name   string `json:"name"`
-	Pinned int    `json:"pinned"`
-	Count  int    `json:"count"`
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Color       string `json:"color"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"`
+	Pinned      int    `json:"pinned"`
+	Count       int    `json:"count"`
 }
 
 func toTagSummaryResponses(tags []model.TagSummary) []tagSummaryResponse {
 	items := make([]tagSummaryResponse, 0, len(tags))
 	for _, tag := range tags {
 		items = append(items, tagSummaryResponse{
-			ID: tag.ID, Name: tag.Name, Pinned: tag.Pinned, Count: tag.Count,
+			ID: tag.ID, Name: tag.Name,
+			Color: tag.Color, Description: tag.Description, Icon: tag.Icon,
+			Pinned: tag.Pinned, Count: tag.Count,
 		})
 	}
 	return items
 }
 
-type todoResponse struct {
+type folderResponse struct {
+	ID       string `json:"id"`
+	UserID   string `json:"user_id"`
+	ParentID string `json:"parent_id"`
+	Name     string `json:"name"`
+	Position int    `json:"position"`
+	Ctime    int64  `json:"ctime"`
+	Mtime    int64  `json:"mtime"`
+}
+
+func toFolderResponse(folder model.Folder) folderResponse {
+	return folderResponse{
+		ID: folder.ID, UserID: folder.UserID, ParentID: folder.ParentID, Name: folder.Name,
+		Position: folder.Position, Ctime: folder.Ctime, Mtime: folder.Mtime,
+	}
+}
+
+type folderNodeResponse struct {
+	folderResponse
+	DocumentCount int                  `json:"document_count"`
+	Children      []folderNodeResponse `json:"children"`
+}
+
+func toFolderNodeResponses(nodes []service.FolderNode) []folderNodeResponse {
+	items := make([]folderNodeResponse, 0, len(nodes))
+	for _, node := range nodes {
+		items = append(items, folderNodeResponse{
+			folderResponse: toFolderResponse(node.Folder),
+			DocumentCount:  node.DocumentCount,
+			Children:       toFolderNodeResponses(node.Children),
+		})
+	}
+	return items
+}
+
+type bulkItemResponse struct {
 	ID      string `json:"id"`
-	UserID  string `json:"user_id"`
-	Content string `json:"content"`
-	DueDate string `json:"due_date"`
-	Done    int    `json:"done"`
-	Ctime   int64  `json:"ctime"`
-	Mtime   int64  `json:"mtime"`
+	OK      bool   `json:"ok"`
+	Code    uint32 `json:"code,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+type bulkResponse struct {
+	Op        string             `json:"op"`
+	Total     int                `json:"total"`
+	Succeeded int                `json:"succeeded"`
+	Failed    int                `json:"failed"`
+	Items     []bulkItemResponse `json:"items"`
+}
+
+// toBulkResponse reports each document with the business code and stable
+// message of its error, the same pair a single-document request returns.
+func toBulkResponse(op string, results []service.BulkItemResult) bulkResponse {
+	resp := bulkResponse{Op: op, Total: len(results), Items: make([]bulkItemResponse, 0, len(results))}
+	for _, result := range results {
+		item := bulkItemResponse{ID: result.ID, OK: result.Err == nil}
+		if result.Err != nil {
+			normalized := appErr.Normalize(result.Err)
+			item.Code, item.Message = normalized.Code(), normalized.Message()
+			resp.Failed++
+		} else {
+			resp.Succeeded++
+		}
+		resp.Items = append(resp.Items, item)
+	}
+	return resp
+}
+
+type todoResponse struct {
+	ID           string                `json:"id"`
+	UserID       string                `json:"user_id"`
+	Content      string                `json:"content"`
+	DueDate      string                `json:"due_date"`
+	Done         int                   `json:"done"`
+	Priority     int                   `json:"priority"`
+	DocumentID   string                `json:"document_id,omitempty"`
+	ParentID     string                `json:"parent_id,omitempty"`
+	Subtasks     *model.TodoProgress   `json:"subtasks,omitempty"`
+	Recurrence   *model.TodoRecurrence `json:"recurrence,omitempty"`
+	SeriesID     string                `json:"series_id,omitempty"`
+	Occurrence   int                   `json:"occurrence,omitempty"`
+	Virtual      bool                  `json:"virtual,omitempty"`
+	SourceAnchor string                `json:"source_anchor,omitempty"`
+	SourceLine   int                   `json:"source_line,omitempty"`
+	Ctime        int64                 `json:"ctime"`
+	Mtime        int64                 `json:"mtime"`
 }
 
 func toTodoResponse(todo model.Todo) todoResponse {
 	return todoResponse{
 		ID: todo.ID, UserID: todo.UserID, Content: todo.Content,
-		DueDate: todo.DueDate, Done: todo.Done, Ctime: todo.Ctime, Mtime: todo.Mtime,
+		DueDate: todo.DueDate, Done: todo.Done, Priority: todo.Priority,
+		DocumentID: todo.DocumentID, ParentID: todo.ParentID, Subtasks: todo.Subtasks,
+		Recurrence: todo.Recurrence, SeriesID: todo.SeriesID,
+		Occurrence: todo.Occurrence, Virtual: todo.Virtual,
+		SourceAnchor: todo.SourceAnchor, SourceLine: todo.SourceLine,
+		Ctime: todo.Ctime, Mtime: todo.Mtime,
 	}
 }
 
@@ -131,26 +253,76 @@ func toTodoResponses(todos []model.Todo) []todoResponse {
 }
 
 type templateResponse struct {
-	ID            string   `json:"id"`
-	UserID        string   `json:"user_id"`
-	Name          string   `json:"name"`
-	Description   string   `json:"description"`
-	Content       string   `json:"content"`
-	DefaultTagIDs []string `json:"default_tag_ids"`
-	BuiltIn       int      `json:"built_in"`
-	Ctime         int64    `json:"ctime"`
-	Mtime         int64    `json:"mtime"`
+	ID            string                     `json:"id"`
+	UserID        string                     `json:"user_id"`
+	Name          string                     `json:"name"`
+	Description   string                     `json:"description"`
+	Content       string                     `json:"content"`
+	Variables     []templateVariableResponse `json:"variables"`
+	DefaultTagIDs []string                   `json:"default_tag_ids"`
+	BuiltIn       int                        `json:"built_in"`
+	SourceSlug    string                     `json:"source_slug"`
+	SourceVersion int                        `json:"source_version"`
+	Ctime         int64                      `json:"ctime"`
+	Mtime         int64                      `json:"mtime"`
+}
+
+type templateVariableResponse struct {
+	Key         string   `json:"key"`
+	Type        string   `json:"type"`
+	Default     string   `json:"default"`
+	Description string   `json:"description"`
+	Options     []string `json:"options,omitempty"`
 }
 
 func toTemplateResponse(item model.Template) templateResponse {
 	return templateResponse{
 		ID: item.ID, UserID: item.UserID, Name: item.Name,
-		Description: item.Description, Content: item.Content,
+		Description: item.Description, Content: item.Content, Variables: toTemplateVariableResponses(item.Variables),
 		DefaultTagIDs: item.DefaultTagIDs, BuiltIn: item.BuiltIn,
+		SourceSlug: item.SourceSlug, SourceVersion: item.SourceVersion,
 		Ctime: item.Ctime, Mtime: item.Mtime,
 	}
 }
 
+func toTemplateVariableResponses(items []model.TemplateVariable) []templateVariableResponse {
+	variables := make([]templateVariableResponse, 0, len(items))
+	for _, variable := range items {
+		variables = append(variables, templateVariableResponse{
+			Key: variable.Key, Type: variable.Type, Default: variable.Default,
+			Description: variable.Description, Options: variable.Options,
+		})
+	}
+	return variables
+}
+
+type galleryTemplateResponse struct {
+	Slug        string                     `json:"slug"`
+	Version     int                        `json:"version"`
+	Name        string                     `json:"name"`
+	Description string                     `json:"description"`
+	Content     string                     `json:"content"`
+	Variables   []templateVariableResponse `json:"variables"`
+	Tags        []string                   `json:"tags"`
+	Mtime       int64                      `json:"mtime"`
+}
+
+func toGalleryTemplateResponses(items []model.GalleryTemplate) []galleryTemplateResponse {
+	result := make([]galleryTemplateResponse, 0, len(items))
+	for _, item := range items {
+		tags := item.Tags
+		if tags == nil {
+			tags = []string{}
+		}
+		result = append(result, galleryTemplateResponse{
+			Slug: item.Slug, Version: item.Version, Name: item.Name, Description: item.Description,
+			Content: item.Content, Variables: toTemplateVariableResponses(item.Variables), Tags: tags,
+			Mtime: item.Mtime,
+		})
+	}
+	return result
+}
+
 func toTemplateResponses(items []model.Template) []templateResponse {
 	result := make([]templateResponse, 0, len(items))
 	for _, item := range items {
@@ -192,6 +364,43 @@ func toTemplateMetaListResponse(
 	return &templateMetaListResponse{Items: items, Total: result.Total}
 }
 
+type templateScheduleResponse struct {
+	ID             string   `json:"id"`
+	TemplateID     string   `json:"template_id"`
+	CronSpec       string   `json:"cron_spec"`
+	TitlePattern   string   `json:"title_pattern"`
+	TagIDs         []string `json:"tag_ids"`
+	Enabled        bool     `json:"enabled"`
+	NextRunAt      int64    `json:"next_run_at"`
+	LastRunAt      int64    `json:"last_run_at"`
+	LastDocumentID string   `json:"last_document_id"`
+	LastError      string   `json:"last_error"`
+	Ctime          int64    `json:"ctime"`
+	Mtime          int64    `json:"mtime"`
+}
+
+func toTemplateScheduleResponse(item model.TemplateSchedule) templateScheduleResponse {
+	tagIDs := item.TagIDs
+	if tagIDs == nil {
+		tagIDs = []string{}
+	}
+	return templateScheduleResponse{
+		ID: item.ID, TemplateID: item.TemplateID, CronSpec: item.CronSpec,
+		TitlePattern: item.TitlePattern, TagIDs: tagIDs, Enabled: item.Enabled == 1,
+		NextRunAt: item.NextRunAt, LastRunAt: item.LastRunAt,
+		LastDocumentID: item.LastDocumentID, LastError: item.LastError,
+		Ctime: item.Ctime, Mtime: item.Mtime,
+	}
+}
+
+func toTemplateScheduleResponses(items []model.TemplateSchedule) []templateScheduleResponse {
+	out := make([]templateScheduleResponse, 0, len(items))
+	for _, item := range items {
+		out = append(out, toTemplateScheduleResponse(item))
+	}
+	return out
+}
+
 type shareResponse struct {
 	ID            string `json:"id"`
 	UserID        string `json:"user_id"`
@@ -284,6 +493,8 @@ type publicShareDetailResponse struct {
 	Permission    int               `json:"permission"`
 	AllowDownload int               `json:"allow_download"`
 	ExpiresAt     int64             `json:"expires_at"`
+	// Section is set when the request asked for a single section.
+	Section *outlineSectionResponse `json:"section,omitempty"`
 }
 
 func toPublicShareDetailResponse(
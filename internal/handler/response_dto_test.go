@@ -61,3 +61,16 @@ func TestDocumentResponseKeepsEditorConcurrencyContract(t *testing.T) {
 	assert.Equal(t, float64(3), item["content_revision"])
 	assert.NotContains(t, item, "summary")
 }
+
+func TestDocumentResponseCarriesStats(t *testing.T) {
+	item := responseMap(t, toDocumentResponse(model.Document{
+		ID: "d1", Stats: model.DocumentStats{WordCount: 120, ReadingMinutes: 1, TasksTotal: 3, TasksDone: 2},
+	}))
+	stats, ok := item["stats"].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, map[string]any{
+		"word_count": float64(120), "char_count": float64(0), "reading_minutes": float64(1),
+		"code_blocks": float64(0), "images": float64(0), "links": float64(0),
+		"tasks_total": float64(3), "tasks_done": float64(2),
+	}, stats)
+}
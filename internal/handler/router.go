@@ -17,22 +17,28 @@ var (
 )
 
 type RouterDeps struct {
-	Auth            *AuthHandler
-	OAuth           *OAuthHandler
-	Properties      *PropertiesHandler
-	Documents       *DocumentHandler
-	Versions        *VersionHandler
-	Shares          *ShareHandler
-	Tags            *TagHandler
-	Export          *ExportHandler
-	Files           *FileHandler
-	SemanticSearch  *SemanticSearchHandler
-	Import          *ImportHandler
-	Templates       *TemplateHandler
-	Assets          *AssetHandler
-	Todos           *TodoHandler
-	JWTSecret       []byte
-	MaxJSONBodySize int64
+	Auth              *AuthHandler
+	OAuth             *OAuthHandler
+	Properties        *PropertiesHandler
+	Documents         *DocumentHandler
+	Versions          *VersionHandler
+	Shares            *ShareHandler
+	Collab            *CollabHandler
+	Tags              *TagHandler
+	Folders           *FolderHandler
+	Bulk              *BulkHandler
+	Export            *ExportHandler
+	Files             *FileHandler
+	SemanticSearch    *SemanticSearchHandler
+	Import            *ImportHandler
+	Templates         *TemplateHandler
+	TemplateSchedules *TemplateScheduleHandler
+	Assets            *AssetHandler
+	Todos             *TodoHandler
+	Admin             *AdminHandler
+	Workspaces        *WorkspaceHandler
+	JWTSecret         []byte
+	MaxJSONBodySize   int64
 }
 
 func (deps RouterDeps) Validate() error {
@@ -46,14 +52,20 @@ func (deps RouterDeps) Validate() error {
 		{name: "documents", dependency: deps.Documents},
 		{name: "versions", dependency: deps.Versions},
 		{name: "shares", dependency: deps.Shares},
+		{name: "collab", dependency: deps.Collab},
 		{name: "tags", dependency: deps.Tags},
+		{name: "folders", dependency: deps.Folders},
+		{name: "bulk", dependency: deps.Bulk},
 		{name: "export", dependency: deps.Export},
 		{name: "files", dependency: deps.Files},
 		{name: "semantic search", dependency: deps.SemanticSearch},
 		{name: "import", dependency: deps.Import},
 		{name: "templates", dependency: deps.Templates},
+		{name: "template schedules", dependency: deps.TemplateSchedules},
 		{name: "assets", dependency: deps.Assets},
 		{name: "todos", dependency: deps.Todos},
+		{name: "admin", dependency: deps.Admin},
+		{name: "workspaces", dependency: deps.Workspaces},
 	}
 	for _, item := range required {
 		if item.dependency == nil {
@@ -79,10 +91,31 @@ func RegisterRoutes(api *gin.RouterGroup, deps RouterDeps) {
 	})
 	registerPublicRoutes(api, deps)
 	authGroup := api.Group("")
-	authGroup.Use(middleware.JWTAuth(deps.JWTSecret))
+	authGroup.Use(middleware.JWTAuth(deps.JWTSecret), deps.Auth.RequireActive)
 	registerAuthRoutes(authGroup, deps)
-	registerDocumentRoutes(authGroup, deps)
+	registerWorkspaceRoutes(authGroup, deps)
+	scopedGroup := authGroup.Group("")
+	scopedGroup.Use(deps.Workspaces.Scope)
+	registerDocumentRoutes(scopedGroup, deps)
+	registerContentRoutes(scopedGroup, deps)
+	registerTodoRoutes(scopedGroup, deps)
+	registerCollabRoutes(api, deps)
 	registerFeatureRoutes(authGroup, deps)
+	adminGroup := authGroup.Group("/admin")
+	adminGroup.Use(deps.Auth.RequireAdmin)
+	registerAdminRoutes(adminGroup, deps)
+}
+
+// registerCollabRoutes mounts the editing session WebSocket in a group of its
+// own: it is the only route that takes the token from the query string,
+// which browsers need for the handshake but which ends up in access logs.
+func registerCollabRoutes(api *gin.RouterGroup, deps RouterDeps) {
+	g := api.Group("")
+	g.Use(
+		middleware.WebSocketQueryAuth(), middleware.JWTAuth(deps.JWTSecret),
+		deps.Auth.RequireActive, deps.Workspaces.Scope,
+	)
+	g.GET("/documents/:id/collab", deps.Collab.Connect)
 }
 
 func registerPublicRoutes(api *gin.RouterGroup, deps RouterDeps) {
@@ -114,8 +147,11 @@ func registerAuthRoutes(g *gin.RouterGroup, deps RouterDeps) {
 
 func registerDocumentRoutes(g *gin.RouterGroup, deps RouterDeps) {
 	g.POST("/documents", deps.Documents.Create)
+	g.POST("/documents/bulk", deps.Bulk.Apply)
+	g.POST("/documents/tag-suggestions/apply", deps.Documents.ApplyTagSuggestions)
 	g.GET("/documents", deps.Documents.List)
 	g.GET("/documents/summary", deps.Documents.Summary)
+	g.GET("/documents/shared-with-me", deps.Shares.SharedWithMe)
 	g.GET("/documents/:id", deps.Documents.Get)
 	g.PUT("/documents/:id", deps.Documents.Update)
 	g.PUT("/documents/:id/tags", deps.Documents.UpdateTags)
@@ -124,39 +160,56 @@ func registerDocumentRoutes(g *gin.RouterGroup, deps RouterDeps) {
 	g.DELETE("/documents/:id", deps.Documents.Delete)
 	g.GET("/documents/:id/backlinks", deps.Documents.Backlinks)
 	g.GET("/documents/:id/links", deps.Documents.Links)
+	g.GET("/documents/:id/wikilinks", deps.Documents.Wikilinks)
+	g.GET("/documents/:id/properties", deps.Documents.Properties)
+	g.GET("/documents/:id/outline", deps.Documents.Outline)
+	g.GET("/documents/:id/unlinked-mentions", deps.Documents.UnlinkedMentions)
+	g.POST("/documents/:id/unlinked-mentions/convert", deps.Documents.ConvertMentions)
 	g.GET("/documents/:id/similar", deps.Documents.Similar)
+	g.PUT("/documents/:id/folder", deps.Folders.MoveDocument)
+	g.GET("/documents/:id/tag-suggestions", deps.Documents.TagSuggestions)
 	g.GET("/documents/:id/versions", deps.Versions.List)
 	g.GET("/documents/:id/versions/:version", deps.Versions.Get)
 	g.POST("/documents/:id/share", deps.Shares.Create)
 	g.PUT("/documents/:id/share", deps.Shares.UpdateConfig)
 	g.GET("/documents/:id/share", deps.Shares.GetActive)
 	g.DELETE("/documents/:id/share", deps.Shares.Revoke)
+	g.GET("/documents/:id/collaborators", deps.Shares.ListCollaborators)
+	g.POST("/documents/:id/collaborators", deps.Shares.AddCollaborator)
+	g.DELETE("/documents/:id/collaborators/:user_id", deps.Shares.RemoveCollaborator)
+	g.GET("/graph", deps.Documents.Graph)
+	g.GET("/links/broken", deps.Documents.BrokenLinks)
+	g.GET("/activity", deps.Documents.Activity)
 	g.GET("/shares", deps.Shares.List)
 }
 
-func registerFeatureRoutes(g *gin.RouterGroup, deps RouterDeps) {
+// registerContentRoutes registers the routes of workspace content besides
+// documents. Like the document routes they act in the workspace selected by
+// WorkspaceHeader.
+func registerContentRoutes(g *gin.RouterGroup, deps RouterDeps) {
 	g.POST("/tags", deps.Tags.Create)
 	g.POST("/tags/batch", deps.Tags.CreateBatch)
 	g.POST("/tags/ids", deps.Tags.ListByIDs)
 	g.GET("/tags", deps.Tags.List)
 	g.GET("/tags/summary", deps.Tags.Summary)
+	g.PUT("/tags/:id", deps.Tags.Update)
 	g.PUT("/tags/:id/pin", deps.Tags.Pin)
+	g.POST("/tags/:id/rename", deps.Tags.Rename)
+	g.POST("/tags/:id/merge", deps.Tags.Merge)
 	g.DELETE("/tags/:id", deps.Tags.Delete)
-	g.GET("/export", deps.Export.Export)
-	g.GET("/export/notes", deps.Export.ExportNotes)
-	g.POST("/export/confluence-html", deps.Export.ConvertMarkdownToConfluenceHTML)
+	g.GET("/folders", deps.Folders.List)
+	g.POST("/folders", deps.Folders.Create)
+	g.PUT("/folders/:id", deps.Folders.Rename)
+	g.PUT("/folders/:id/move", deps.Folders.Move)
+	g.DELETE("/folders/:id", deps.Folders.Delete)
 	g.POST("/files/upload", deps.Files.Upload)
 	g.GET("/ai/search", deps.SemanticSearch.Search)
-	g.POST("/import/hedgedoc/upload", deps.Import.HedgeDocUpload)
-	g.GET("/import/hedgedoc/:job_id/preview", deps.Import.HedgeDocPreview)
-	g.POST("/import/hedgedoc/:job_id/confirm", deps.Import.HedgeDocConfirm)
-	g.GET("/import/hedgedoc/:job_id/status", deps.Import.HedgeDocStatus)
-	g.POST("/import/notes/upload", deps.Import.NotesUpload)
-	g.GET("/import/notes/:job_id/preview", deps.Import.NotesPreview)
-	g.POST("/import/notes/:job_id/confirm", deps.Import.NotesConfirm)
-	g.GET("/import/notes/:job_id/status", deps.Import.NotesStatus)
 	g.GET("/templates", deps.Templates.List)
 	g.GET("/templates/meta", deps.Templates.ListMeta)
+	g.GET("/templates/export", deps.Templates.ExportBundle)
+	g.POST("/templates/import", deps.Templates.ImportBundle)
+	g.GET("/templates/gallery", deps.Templates.ListGallery)
+	g.POST("/templates/gallery/:slug/copy", deps.Templates.CopyFromGallery)
 	g.GET("/templates/:id", deps.Templates.Get)
 	g.POST("/templates", deps.Templates.Create)
 	g.PUT("/templates/:id", deps.Templates.Update)
@@ -164,9 +217,55 @@ func registerFeatureRoutes(g *gin.RouterGroup, deps RouterDeps) {
 	g.POST("/templates/:id/create", deps.Templates.CreateDocument)
 	g.GET("/assets", deps.Assets.List)
 	g.GET("/assets/:id/references", deps.Assets.References)
+}
+
+func registerFeatureRoutes(g *gin.RouterGroup, deps RouterDeps) {
+	g.GET("/export", deps.Export.Export)
+	g.GET("/export/notes", deps.Export.ExportNotes)
+	g.POST("/export/confluence-html", deps.Export.ConvertMarkdownToConfluenceHTML)
+	g.POST("/import/hedgedoc/upload", deps.Import.HedgeDocUpload)
+	g.GET("/import/hedgedoc/:job_id/preview", deps.Import.HedgeDocPreview)
+	g.POST("/import/hedgedoc/:job_id/confirm", deps.Import.HedgeDocConfirm)
+	g.GET("/import/hedgedoc/:job_id/status", deps.Import.HedgeDocStatus)
+	g.POST("/import/notes/upload", deps.Import.NotesUpload)
+	g.GET("/import/notes/:job_id/preview", deps.Import.NotesPreview)
+	g.POST("/import/notes/:job_id/confirm", deps.Import.NotesConfirm)
+	g.GET("/import/notes/:job_id/status", deps.Import.NotesStatus)
+	g.GET("/template-schedules", deps.TemplateSchedules.List)
+	g.POST("/template-schedules", deps.TemplateSchedules.Create)
+	g.PUT("/template-schedules/:id", deps.TemplateSchedules.Update)
+	g.DELETE("/template-schedules/:id", deps.TemplateSchedules.Delete)
+}
+
+// registerTodoRoutes serves the todos of the selected workspace, where the
+// task list items of its documents are synced.
+func registerTodoRoutes(g *gin.RouterGroup, deps RouterDeps) {
 	g.POST("/todos", deps.Todos.Create)
 	g.GET("/todos", deps.Todos.List)
 	g.PUT("/todos/:id", deps.Todos.Update)
 	g.PUT("/todos/:id/done", deps.Todos.ToggleDone)
+	g.PUT("/todos/:id/recurrence", deps.Todos.UpdateRecurrence)
+	g.PUT("/todos/:id/attributes", deps.Todos.UpdateAttributes)
 	g.DELETE("/todos/:id", deps.Todos.Delete)
 }
+
+func registerWorkspaceRoutes(g *gin.RouterGroup, deps RouterDeps) {
+	g.GET("/workspaces", deps.Workspaces.List)
+	g.POST("/workspaces", deps.Workspaces.Create)
+	g.PUT("/workspaces/:id", deps.Workspaces.Update)
+	g.DELETE("/workspaces/:id", deps.Workspaces.Delete)
+	g.GET("/workspaces/:id/members", deps.Workspaces.ListMembers)
+	g.POST("/workspaces/:id/members", deps.Workspaces.AddMember)
+	g.PUT("/workspaces/:id/members/:user_id", deps.Workspaces.UpdateMember)
+	g.DELETE("/workspaces/:id/members/:user_id", deps.Workspaces.RemoveMember)
+}
+
+func registerAdminRoutes(g *gin.RouterGroup, deps RouterDeps) {
+	g.GET("/users", deps.Admin.ListUsers)
+	g.POST("/users/:id/disable", deps.Admin.DisableUser)
+	g.POST("/users/:id/enable", deps.Admin.EnableUser)
+	g.GET("/usage", deps.Admin.Usage)
+	g.GET("/invites", deps.Admin.ListInvites)
+	g.POST("/invites", deps.Admin.CreateInvite)
+	g.DELETE("/invites/:id", deps.Admin.DeleteInvite)
+}
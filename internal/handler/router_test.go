@@ -1,10 +1,18 @@
 package handler
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/pkg/errcode"
+	"github.com/xxxsen/mnote/internal/pkg/jwt"
 )
 
 func TestRegisterRoutes(t *testing.T) {
@@ -12,22 +20,28 @@ func TestRegisterRoutes(t *testing.T) {
 	api := r.Group("/api/v1")
 
 	deps := RouterDeps{
-		Auth:            &AuthHandler{auth: &mockAuthService{}},
-		OAuth:           newOAuthHandler(&mockOAuthService{}),
-		Properties:      NewPropertiesHandler(Properties{}, BannerConfig{}),
-		Documents:       &DocumentHandler{documents: &mockDocumentService{}},
-		Versions:        &VersionHandler{documents: &mockDocumentService{}},
-		Shares:          &ShareHandler{documents: &mockDocumentService{}},
-		Tags:            &TagHandler{tags: &mockTagService{}},
-		Export:          &ExportHandler{export: &mockExportService{}},
-		Files:           &FileHandler{store: &mockFileStore{}},
-		SemanticSearch:  &SemanticSearchHandler{documents: &mockDocumentService{}},
-		Import:          &ImportHandler{imports: &mockImportHandlerService{}},
-		Templates:       &TemplateHandler{templates: &mockTemplateHandlerService{}},
-		Assets:          &AssetHandler{assets: &mockAssetHandlerService{}},
-		Todos:           &TodoHandler{todos: &mockTodoHandlerService{}},
-		JWTSecret:       []byte("test-secret"),
-		MaxJSONBodySize: 2 << 20,
+		Auth:              &AuthHandler{auth: &mockAuthService{}},
+		OAuth:             newOAuthHandler(&mockOAuthService{}),
+		Properties:        NewPropertiesHandler(Properties{}, BannerConfig{}),
+		Documents:         &DocumentHandler{documents: &mockDocumentService{}},
+		Versions:          &VersionHandler{documents: &mockDocumentService{}},
+		Shares:            &ShareHandler{documents: &mockDocumentService{}},
+		Collab:            NewCollabHandler(newTestCollabHub(), nil),
+		Tags:              &TagHandler{tags: &mockTagService{}},
+		Folders:           &FolderHandler{folders: &mockFolderService{}},
+		Bulk:              &BulkHandler{bulk: &mockBulkService{}},
+		Export:            &ExportHandler{export: &mockExportService{}},
+		Files:             &FileHandler{store: &mockFileStore{}},
+		SemanticSearch:    &SemanticSearchHandler{documents: &mockDocumentService{}},
+		Import:            &ImportHandler{imports: &mockImportHandlerService{}},
+		Templates:         &TemplateHandler{templates: &mockTemplateHandlerService{}},
+		TemplateSchedules: &TemplateScheduleHandler{schedules: &mockTemplateScheduleHandlerService{}},
+		Assets:            &AssetHandler{assets: &mockAssetHandlerService{}},
+		Todos:             &TodoHandler{todos: &mockTodoHandlerService{}},
+		Admin:             NewAdminHandler(&mockAdminHandlerService{}, &mockInviteService{}),
+		Workspaces:        NewWorkspaceHandler(&mockWorkspaceService{}),
+		JWTSecret:         []byte("test-secret"),
+		MaxJSONBodySize:   2 << 20,
 	}
 
 	assert.NotPanics(t, func() {
@@ -37,7 +51,7 @@ func TestRegisterRoutes(t *testing.T) {
 	routes := r.Routes()
 	assert.True(t, len(routes) > 30)
 
-	var previewGET, previewHEAD bool
+	var previewGET, previewHEAD, adminUsers, workspaces, collab, graph, wikilinks, brokenLinks, properties, outline, activity, folders, bulk bool
 	removedRoutes := map[string]bool{
 		"POST /api/v1/ai/polish":            false,
 		"POST /api/v1/ai/generate":          false,
@@ -52,6 +66,17 @@ func TestRegisterRoutes(t *testing.T) {
 		if _, tracked := removedRoutes[key]; tracked {
 			removedRoutes[key] = true
 		}
+		adminUsers = adminUsers || key == "GET /api/v1/admin/users"
+		workspaces = workspaces || key == "GET /api/v1/workspaces"
+		collab = collab || key == "GET /api/v1/documents/:id/collab"
+		graph = graph || key == "GET /api/v1/graph"
+		wikilinks = wikilinks || key == "GET /api/v1/documents/:id/wikilinks"
+		brokenLinks = brokenLinks || key == "GET /api/v1/links/broken"
+		properties = properties || key == "GET /api/v1/documents/:id/properties"
+		outline = outline || key == "GET /api/v1/documents/:id/outline"
+		activity = activity || key == "GET /api/v1/activity"
+		folders = folders || key == "PUT /api/v1/documents/:id/folder"
+		bulk = bulk || key == "POST /api/v1/documents/bulk"
 		if route.Path == "/api/v1/files/:key/preview" {
 			previewGET = previewGET || route.Method == "GET"
 			previewHEAD = previewHEAD || route.Method == "HEAD"
@@ -62,6 +87,27 @@ func TestRegisterRoutes(t *testing.T) {
 	}
 	assert.True(t, previewGET, "public preview GET route must be registered")
 	assert.True(t, previewHEAD, "public preview HEAD route must be registered")
+	assert.True(t, adminUsers, "admin user list route must be registered")
+	assert.True(t, workspaces, "workspace list route must be registered")
+	assert.True(t, collab, "collaborative editing route must be registered")
+	assert.True(t, graph, "knowledge graph route must be registered")
+	assert.True(t, wikilinks, "wikilink resolution route must be registered")
+	assert.True(t, brokenLinks, "broken link report route must be registered")
+	assert.True(t, properties, "document properties route must be registered")
+	assert.True(t, outline, "document outline route must be registered")
+	assert.True(t, activity, "writing activity route must be registered")
+	assert.True(t, folders, "document folder route must be registered")
+	assert.True(t, bulk, "bulk document route must be registered")
+
+	token, err := jwt.GenerateToken("u1", "", deps.JWTSecret, time.Hour)
+	require.NoError(t, err)
+	req := httptest.NewRequestWithContext(context.Background(), http.MethodGet,
+		"/api/v1/documents?access_token="+token, nil)
+	req.Header.Set("Upgrade", "websocket")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, float64(errcode.ErrUnauthorized), parseResponseT(t, w)["code"],
+		"only the collab route may take the token from the query string")
 }
 
 func TestRouterDepsValidateRejectsMissingDependency(t *testing.T) {
@@ -106,6 +106,20 @@ func getSharePassword(c *gin.Context) string {
 }
 
 func (h *ShareHandler) PublicGet(c *gin.Context) {
+	if section, ok := c.GetQuery("section"); ok {
+		detail, found, err := h.documents.GetShareSection(
+			c.Request.Context(), c.Param("token"), getSharePassword(c), section,
+		)
+		if err != nil {
+			handleError(c, err)
+			return
+		}
+		resp := toPublicShareDetailResponse(detail)
+		section := toOutlineSectionResponse(*found)
+		resp.Section = &section
+		response.Success(c, resp)
+		return
+	}
 	detail, err := h.documents.GetShareByToken(
 		c.Request.Context(), c.Param("token"), getSharePassword(c),
 	)
@@ -187,3 +201,53 @@ func (h *ShareHandler) List(c *gin.Context) {
 	}
 	response.Success(c, gin.H{"items": items})
 }
+
+type addCollaboratorRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+func (h *ShareHandler) ListCollaborators(c *gin.Context) {
+	items, err := h.documents.ListCollaborators(c.Request.Context(), getUserID(c), c.Param("id"))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, gin.H{"items": items})
+}
+
+func (h *ShareHandler) AddCollaborator(c *gin.Context) {
+	var req addCollaboratorRequest
+	if err := bindJSON(c, &req); err != nil {
+		response.Error(c, errcode.ErrInvalid, "invalid request")
+		return
+	}
+	collaborator, err := h.documents.AddCollaborator(
+		c.Request.Context(), getUserID(c), c.Param("id"), req.Email, req.Role,
+	)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, collaborator)
+}
+
+func (h *ShareHandler) RemoveCollaborator(c *gin.Context) {
+	if err := h.documents.RemoveCollaborator(
+		c.Request.Context(), getUserID(c), c.Param("id"), c.Param("user_id"),
+	); err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, gin.H{"ok": true})
+}
+
+// SharedWithMe lists the documents other users invited the caller to.
+func (h *ShareHandler) SharedWithMe(c *gin.Context) {
+	items, err := h.documents.ListSharedWithMe(c.Request.Context(), getUserID(c))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, gin.H{"items": items})
+}
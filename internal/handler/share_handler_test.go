@@ -9,9 +9,12 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/xxxsen/mnote/internal/middleware"
 	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/errcode"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
 	"github.com/xxxsen/mnote/internal/repo"
 	"github.com/xxxsen/mnote/internal/service"
 )
@@ -505,3 +508,49 @@ func TestShareHandler_List_Success(t *testing.T) {
 	assert.Contains(t, w.Body.String(), `"content_preview":"Preview from content"`)
 	assert.NotContains(t, w.Body.String(), `"summary"`)
 }
+
+func TestShareHandler_Collaborators(t *testing.T) {
+	mock := newShareDocMock()
+	mock.addCollaboratorFn = func(_ context.Context, userID, docID, email, role string) (*model.DocumentCollaborator, error) {
+		if role != model.CollaboratorRoleEditor && role != model.CollaboratorRoleViewer {
+			return nil, appErr.ErrInvalid
+		}
+		return &model.DocumentCollaborator{DocumentID: docID, OwnerID: userID, UserID: "u2", Email: email, Role: role}, nil
+	}
+	var removed string
+	mock.removeCollaboratorFn = func(_ context.Context, _, _, collaboratorID string) error {
+		removed = collaboratorID
+		return nil
+	}
+	mock.listSharedWithMeFn = func(context.Context, string) ([]service.SharedWithMeItem, error) {
+		return []service.SharedWithMeItem{{ID: "d1", OwnerID: "u1", Role: model.CollaboratorRoleViewer}}, nil
+	}
+	h := &ShareHandler{documents: mock}
+	r := newTestRouter()
+	r.POST("/documents/:id/collaborators", withUserID("u1"), h.AddCollaborator)
+	r.DELETE("/documents/:id/collaborators/:user_id", withUserID("u1"), h.RemoveCollaborator)
+	r.GET("/documents/shared-with-me", withUserID("u2"), h.SharedWithMe)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, "POST", "/documents/d1/collaborators",
+		map[string]any{"email": "b@example.com", "role": model.CollaboratorRoleEditor}))
+	resp := parseResponseT(t, w)
+	require.Equal(t, float64(0), resp["code"])
+	data, ok := resp["data"].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "u2", data["user_id"])
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, "POST", "/documents/d1/collaborators",
+		map[string]any{"email": "b@example.com", "role": "owner"}))
+	assert.Equal(t, float64(errcode.ErrInvalid), parseResponseT(t, w)["code"])
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("DELETE", "/documents/d1/collaborators/u2", nil))
+	assert.Equal(t, float64(0), parseResponseT(t, w)["code"])
+	assert.Equal(t, "u2", removed)
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/documents/shared-with-me", nil))
+	assert.Contains(t, w.Body.String(), `"owner_id":"u1"`)
+}
@@ -2,28 +2,32 @@ package handler
 
 import (
 	"context"
+	"time"
 
 	"github.com/xxxsen/mnote/internal/model"
 	"github.com/xxxsen/mnote/internal/oauth"
+	"github.com/xxxsen/mnote/internal/pkg/ot"
 	"github.com/xxxsen/mnote/internal/service"
 )
 
 type IAuthService interface {
-	Register(ctx context.Context, email, password, code string) (*model.User, string, error)
+	Register(ctx context.Context, email, password, code, invite string) (*model.User, string, error)
 	Login(ctx context.Context, email, password string) (*model.User, string, error)
 	SendRegisterCode(ctx context.Context, email string) error
 	UpdatePassword(ctx context.Context, userID, currentPassword, newPassword string) error
+	EnsureActive(ctx context.Context, userID string) error
+	EnsureAdmin(ctx context.Context, userID string) error
 }
 
 type oauthFlowService interface {
-	GetAuthURL(provider, state string) (string, error)
-	CreateState(ctx context.Context, provider, purpose, userID, returnTo string) (string, error)
+	GetAuthURL(ctx context.Context, provider, state string) (string, error)
+	CreateState(ctx context.Context, provider, purpose, userID, returnTo, invite string) (string, error)
 	ConsumeState(ctx context.Context, state string) (*service.OAuthState, error)
-	ExchangeCode(ctx context.Context, provider, code string) (*oauth.Profile, error)
+	ExchangeCode(ctx context.Context, provider, code, verifier string) (*oauth.Profile, error)
 }
 
 type oauthLoginService interface {
-	LoginOrCreate(ctx context.Context, profile *oauth.Profile) (*model.User, string, error)
+	LoginOrCreate(ctx context.Context, profile *oauth.Profile, inviteDigest string) (*model.User, string, error)
 	CreateExchange(ctx context.Context, user *model.User) (string, error)
 	ConsumeExchange(ctx context.Context, code string) (*service.OAuthExchange, error)
 }
@@ -41,8 +45,9 @@ type IOAuthService interface {
 }
 
 type documentLookupService interface {
-	Search(ctx context.Context, userID, query, tagID string,
-		starred *int, limit, offset uint, orderBy string) ([]model.Document, error)
+	Search(ctx context.Context, userID, query, tagID string, starred *int,
+		props []model.PropertyFilter, folder *model.FolderFilter,
+		limit, offset uint, orderBy string) ([]model.Document, error)
 	Get(ctx context.Context, userID, docID string) (*model.Document, error)
 	Overview(ctx context.Context, userID string, limit uint) (*service.DocumentOverview, error)
 	GetBacklinks(ctx context.Context, userID, docID string) ([]model.Document, error)
@@ -83,6 +88,27 @@ type IDocumentService interface {
 		userID, documentID string,
 		limit int,
 	) (*service.SimilarDocumentList, error)
+	SuggestTags(
+		ctx context.Context,
+		userID, docID string,
+		limit int,
+	) (*service.TagSuggestionList, error)
+	ApplyTagSuggestions(
+		ctx context.Context, userID string, req service.TagSuggestionApply,
+	) ([]service.TagSuggestionApplyResult, error)
+	Graph(ctx context.Context, userID string, query service.GraphQuery) (*service.DocumentGraph, error)
+	Activity(ctx context.Context, userID, from, to, timezone string) (*service.Activity, error)
+	ListWikilinks(ctx context.Context, userID, docID string) ([]service.Wikilink, error)
+	UnlinkedMentions(ctx context.Context, userID, docID string, limit int) ([]service.UnlinkedMention, error)
+	ConvertMentions(
+		ctx context.Context, userID, docID, sourceID string, baseRevision int64,
+	) (*service.MentionConversion, error)
+	ListBrokenLinks(ctx context.Context, userID string, page service.Page) ([]model.BrokenLink, error)
+	ListProperties(ctx context.Context, userID, docID string) ([]model.DocumentProperty, error)
+	Outline(ctx context.Context, userID, docID string) ([]service.OutlineSection, error)
+	GetSection(
+		ctx context.Context, userID, docID, section string,
+	) (*model.Document, *service.OutlineSection, error)
 }
 
 type IVersionHandlerService interface {
@@ -99,6 +125,9 @@ type shareConfigService interface {
 
 type publicShareService interface {
 	GetShareByToken(ctx context.Context, token, password string) (*service.PublicShareDetail, error)
+	GetShareSection(
+		ctx context.Context, token, password, section string,
+	) (*service.PublicShareDetail, *service.OutlineSection, error)
 	ListShareCommentsByToken(ctx context.Context, token, password string,
 		limit, offset int) (*service.ShareCommentListResult, error)
 	ListShareCommentRepliesByToken(ctx context.Context, token, password, rootID string,
@@ -106,9 +135,17 @@ type publicShareService interface {
 	CreateShareCommentByToken(ctx context.Context, input service.CreateShareCommentInput) (*model.ShareComment, error)
 }
 
+type collaboratorService interface {
+	ListCollaborators(ctx context.Context, userID, docID string) ([]model.DocumentCollaborator, error)
+	AddCollaborator(ctx context.Context, userID, docID, email, role string) (*model.DocumentCollaborator, error)
+	RemoveCollaborator(ctx context.Context, userID, docID, collaboratorID string) error
+	ListSharedWithMe(ctx context.Context, userID string) ([]service.SharedWithMeItem, error)
+}
+
 type IShareHandlerService interface {
 	shareConfigService
 	publicShareService
+	collaboratorService
 	ListSharedDocuments(ctx context.Context, userID, query string) ([]service.SharedDocumentListItem, error)
 }
 
@@ -130,6 +167,9 @@ type tagWriteService interface {
 	CreateBatch(ctx context.Context, userID string, names []string) ([]model.Tag, error)
 	Delete(ctx context.Context, userID, tagID string) error
 	UpdatePinned(ctx context.Context, userID, tagID string, pinned int) error
+	Update(ctx context.Context, userID, tagID string, input service.TagUpdateInput) (*model.Tag, error)
+	Rename(ctx context.Context, userID, tagID, name string) (*model.Tag, error)
+	Merge(ctx context.Context, userID, sourceID, targetID string) (*model.Tag, error)
 }
 
 type tagQueryService interface {
@@ -144,6 +184,24 @@ type ITagService interface {
 	tagQueryService
 }
 
+type folderWriteService interface {
+	Create(ctx context.Context, userID, parentID, name string) (*model.Folder, error)
+	Rename(ctx context.Context, userID, folderID, name string) (*model.Folder, error)
+	Move(ctx context.Context, userID, folderID, parentID string, position int) (*model.Folder, error)
+	Delete(ctx context.Context, userID, folderID string) error
+	MoveDocument(ctx context.Context, userID, docID, folderID string) error
+}
+
+type IFolderService interface {
+	folderWriteService
+	Tree(ctx context.Context, userID string) ([]service.FolderNode, error)
+}
+
+type IBulkService interface {
+	Apply(ctx context.Context, userID string, req service.BulkRequest) ([]service.BulkItemResult, error)
+	Export(ctx context.Context, userID string, selection service.BulkSelection) (string, error)
+}
+
 type IExportService interface {
 	Export(ctx context.Context, userID string) (*service.ExportPayload, error)
 	ExportNotesZip(ctx context.Context, userID string) (string, error)
@@ -172,9 +230,28 @@ type templateWriteService interface {
 		input service.CreateDocumentFromTemplateInput) (*model.Document, error)
 }
 
+type templateSharingService interface {
+	ExportBundle(ctx context.Context, userID string, ids []string) (*model.TemplateBundle, error)
+	ImportBundle(ctx context.Context, userID string, bundle *model.TemplateBundle) (*service.TemplateImportResult, error)
+	ListGallery(ctx context.Context) ([]model.GalleryTemplate, error)
+	CopyFromGallery(ctx context.Context, userID, slug, name string) (*model.Template, error)
+}
+
 type ITemplateHandlerService interface {
 	templateQueryService
 	templateWriteService
+	templateSharingService
+}
+
+type ITemplateScheduleHandlerService interface {
+	List(ctx context.Context, userID string) ([]model.TemplateSchedule, error)
+	Create(ctx context.Context, userID string, input service.TemplateScheduleInput) (*model.TemplateSchedule, error)
+	Update(
+		ctx context.Context,
+		userID, scheduleID string,
+		input service.TemplateScheduleInput,
+	) (*model.TemplateSchedule, error)
+	Delete(ctx context.Context, userID, scheduleID string) error
 }
 
 type IAssetHandlerService interface {
@@ -184,9 +261,51 @@ type IAssetHandlerService interface {
 }
 
 type ITodoHandlerService interface {
-	CreateTodo(ctx context.Context, userID, content, dueDate string, done bool) (*model.Todo, error)
+	CreateTodo(ctx context.Context, userID string, input service.TodoCreateInput) (*model.Todo, error)
 	ListByDateRange(ctx context.Context, userID, startDate, endDate string) ([]model.Todo, error)
+	ListTodos(ctx context.Context, userID string, query model.TodoListQuery) ([]model.Todo, error)
 	ToggleDone(ctx context.Context, userID, todoID string, done bool) error
 	UpdateContent(ctx context.Context, userID, todoID, content string) (*model.Todo, error)
+	UpdateRecurrence(
+		ctx context.Context,
+		userID, todoID string,
+		recurrence *model.TodoRecurrence,
+	) (*model.Todo, error)
+	UpdateAttributes(
+		ctx context.Context,
+		userID, todoID string,
+		input service.TodoAttributesInput,
+	) (*model.Todo, error)
 	DeleteTodo(ctx context.Context, userID, todoID string) error
 }
+
+type IAdminHandlerService interface {
+	ListUsers(ctx context.Context) ([]model.User, error)
+	SetDisabled(ctx context.Context, ref string, disabled bool) (*model.User, error)
+	Stats(ctx context.Context) ([]model.UserStats, error)
+}
+
+type IInviteService interface {
+	CreateInvite(ctx context.Context, createdBy, note string, ttl time.Duration) (*model.InviteCode, string, error)
+	ListInvites(ctx context.Context) ([]model.InviteCode, error)
+	DeleteInvite(ctx context.Context, id string) error
+}
+
+type IWorkspaceService interface {
+	Authorize(ctx context.Context, userID, workspaceID string) (service.WorkspaceAccess, error)
+	List(ctx context.Context, userID string) ([]model.Workspace, error)
+	Create(ctx context.Context, userID, name string) (*model.Workspace, error)
+	Rename(ctx context.Context, userID, workspaceID, name string) (*model.Workspace, error)
+	Delete(ctx context.Context, userID, workspaceID string) error
+	ListMembers(ctx context.Context, userID, workspaceID string) ([]model.WorkspaceMember, error)
+	AddMember(ctx context.Context, userID, workspaceID, email, role string) (*model.WorkspaceMember, error)
+	UpdateMemberRole(ctx context.Context, userID, workspaceID, memberID, role string) (*model.WorkspaceMember, error)
+	RemoveMember(ctx context.Context, userID, workspaceID, memberID string) error
+}
+
+type ICollabHub interface {
+	Join(ctx context.Context, userID, docID string) (*service.CollabClient, error)
+	Leave(client *service.CollabClient)
+	Submit(client *service.CollabClient, revision int, op ot.Operation) error
+	MoveCursor(client *service.CollabClient, cursor service.CollabCursor)
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/xxxsen/mnote/internal/model"
 	"github.com/xxxsen/mnote/internal/pkg/errcode"
 	"github.com/xxxsen/mnote/internal/pkg/response"
+	"github.com/xxxsen/mnote/internal/service"
 )
 
 type TagHandler struct {
@@ -32,6 +33,16 @@ type tagPinRequest struct {
 	Pinned bool `json:"pinned"`
 }
 
+type tagAppearanceRequest struct {
+	Color       string `json:"color"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"`
+}
+
+type tagMergeRequest struct {
+	TargetID string `json:"target_id"`
+}
+
 func (h *TagHandler) Create(c *gin.Context) {
 	var req tagRequest
 	if err := bindJSON(c, &req); err != nil {
@@ -154,3 +165,55 @@ func (h *TagHandler) Pin(c *gin.Context) {
 	}
 	response.Success(c, gin.H{"ok": true})
 }
+
+func (h *TagHandler) Update(c *gin.Context) {
+	var req tagAppearanceRequest
+	if err := bindJSON(c, &req); err != nil {
+		response.Error(c, errcode.ErrInvalid, "invalid request")
+		return
+	}
+	tag, err := h.tags.Update(c.Request.Context(), getUserID(c), c.Param("id"), service.TagUpdateInput{
+		Color: req.Color, Description: req.Description, Icon: req.Icon,
+	})
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, toTagResponse(*tag))
+}
+
+func (h *TagHandler) Rename(c *gin.Context) {
+	var req tagRequest
+	if err := bindJSON(c, &req); err != nil {
+		response.Error(c, errcode.ErrInvalid, "invalid request")
+		return
+	}
+	if req.Name == "" {
+		response.Error(c, errcode.ErrInvalid, "name required")
+		return
+	}
+	tag, err := h.tags.Rename(c.Request.Context(), getUserID(c), c.Param("id"), req.Name)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, toTagResponse(*tag))
+}
+
+func (h *TagHandler) Merge(c *gin.Context) {
+	var req tagMergeRequest
+	if err := bindJSON(c, &req); err != nil {
+		response.Error(c, errcode.ErrInvalid, "invalid request")
+		return
+	}
+	if req.TargetID == "" {
+		response.Error(c, errcode.ErrInvalid, "target_id required")
+		return
+	}
+	tag, err := h.tags.Merge(c.Request.Context(), getUserID(c), c.Param("id"), req.TargetID)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, toTagResponse(*tag))
+}
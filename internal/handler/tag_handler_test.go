@@ -10,6 +10,7 @@ import (
 	"github.com/stretchr/testify/assert"
 
 	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/service"
 )
 
 func TestTagHandler_Create_Success(t *testing.T) {
@@ -483,3 +484,149 @@ func TestTagHandler_List_PageError(t *testing.T) {
 	resp := parseResponseT(t, w)
 	assert.NotEqual(t, float64(0), resp["code"])
 }
+
+func TestTagHandler_Rename_Success(t *testing.T) {
+	mock := &mockTagService{
+		renameFn: func(_ context.Context, userID, tagID, name string) (*model.Tag, error) {
+			assert.Equal(t, "u1", userID)
+			assert.Equal(t, "t1", tagID)
+			return &model.Tag{ID: tagID, Name: name}, nil
+		},
+	}
+	h := &TagHandler{tags: mock}
+	r := newTestRouter()
+	r.POST("/tags/:id/rename", withUserID("u1"), h.Rename)
+
+	w := httptest.NewRecorder()
+	req := jsonRequestT(t, "POST", "/tags/t1/rename", map[string]string{"name": "project/mnote"})
+	r.ServeHTTP(w, req)
+
+	resp := parseResponseT(t, w)
+	assert.Equal(t, float64(0), resp["code"])
+	data := resp["data"].(map[string]any)
+	assert.Equal(t, "project/mnote", data["name"])
+}
+
+func TestTagHandler_Rename_EmptyName(t *testing.T) {
+	h := &TagHandler{tags: &mockTagService{}}
+	r := newTestRouter()
+	r.POST("/tags/:id/rename", withUserID("u1"), h.Rename)
+
+	w := httptest.NewRecorder()
+	req := jsonRequestT(t, "POST", "/tags/t1/rename", map[string]string{"name": ""})
+	r.ServeHTTP(w, req)
+
+	resp := parseResponseT(t, w)
+	assert.NotEqual(t, float64(0), resp["code"])
+}
+
+func TestTagHandler_Merge_Success(t *testing.T) {
+	mock := &mockTagService{
+		mergeFn: func(_ context.Context, _, sourceID, targetID string) (*model.Tag, error) {
+			assert.Equal(t, "t1", sourceID)
+			assert.Equal(t, "t2", targetID)
+			return &model.Tag{ID: targetID, Name: "go"}, nil
+		},
+	}
+	h := &TagHandler{tags: mock}
+	r := newTestRouter()
+	r.POST("/tags/:id/merge", withUserID("u1"), h.Merge)
+
+	w := httptest.NewRecorder()
+	req := jsonRequestT(t, "POST", "/tags/t1/merge", map[string]string{"target_id": "t2"})
+	r.ServeHTTP(w, req)
+
+	resp := parseResponseT(t, w)
+	assert.Equal(t, float64(0), resp["code"])
+}
+
+func TestTagHandler_Merge_MissingTarget(t *testing.T) {
+	h := &TagHandler{tags: &mockTagService{}}
+	r := newTestRouter()
+	r.POST("/tags/:id/merge", withUserID("u1"), h.Merge)
+
+	w := httptest.NewRecorder()
+	req := jsonRequestT(t, "POST", "/tags/t1/merge", map[string]string{})
+	r.ServeHTTP(w, req)
+
+	resp := parseResponseT(t, w)
+	assert.NotEqual(t, float64(0), resp["code"])
+}
+
+func TestTagHandler_Merge_ServiceError(t *testing.T) {
+	mock := &mockTagService{
+		mergeFn: func(context.Context, string, string, string) (*model.Tag, error) {
+			return nil, errors.New("merge error")
+		},
+	}
+	h := &TagHandler{tags: mock}
+	r := newTestRouter()
+	r.POST("/tags/:id/merge", withUserID("u1"), h.Merge)
+
+	w := httptest.NewRecorder()
+	req := jsonRequestT(t, "POST", "/tags/t1/merge", map[string]string{"target_id": "t2"})
+	r.ServeHTTP(w, req)
+
+	resp := parseResponseT(t, w)
+	assert.NotEqual(t, float64(0), resp["code"])
+}
+
+func TestTagHandler_Update_Success(t *testing.T) {
+	mock := &mockTagService{
+		updateFn: func(_ context.Context, _, tagID string, input service.TagUpdateInput) (*model.Tag, error) {
+			assert.Equal(t, "t1", tagID)
+			assert.Equal(t, "#00add8", input.Color)
+			return &model.Tag{
+				ID: tagID, Name: "go", Color: input.Color,
+				Description: input.Description, Icon: input.Icon,
+			}, nil
+		},
+	}
+	h := &TagHandler{tags: mock}
+	r := newTestRouter()
+	r.PUT("/tags/:id", withUserID("u1"), h.Update)
+
+	w := httptest.NewRecorder()
+	req := jsonRequestT(t, "PUT", "/tags/t1", map[string]string{
+		"color": "#00add8", "description": "Go notes", "icon": "🐹",
+	})
+	r.ServeHTTP(w, req)
+
+	resp := parseResponseT(t, w)
+	assert.Equal(t, float64(0), resp["code"])
+	data := resp["data"].(map[string]any)
+	assert.Equal(t, "#00add8", data["color"])
+	assert.Equal(t, "Go notes", data["description"])
+	assert.Equal(t, "🐹", data["icon"])
+}
+
+func TestTagHandler_Update_UnknownField(t *testing.T) {
+	h := &TagHandler{tags: &mockTagService{}}
+	r := newTestRouter()
+	r.PUT("/tags/:id", withUserID("u1"), h.Update)
+
+	w := httptest.NewRecorder()
+	req := jsonRequestT(t, "PUT", "/tags/t1", map[string]string{"name": "go"})
+	r.ServeHTTP(w, req)
+
+	resp := parseResponseT(t, w)
+	assert.NotEqual(t, float64(0), resp["code"])
+}
+
+func TestTagHandler_Update_ServiceError(t *testing.T) {
+	mock := &mockTagService{
+		updateFn: func(context.Context, string, string, service.TagUpdateInput) (*model.Tag, error) {
+			return nil, errors.New("update error")
+		},
+	}
+	h := &TagHandler{tags: mock}
+	r := newTestRouter()
+	r.PUT("/tags/:id", withUserID("u1"), h.Update)
+
+	w := httptest.NewRecorder()
+	req := jsonRequestT(t, "PUT", "/tags/t1", map[string]string{"color": "#fff"})
+	r.ServeHTTP(w, req)
+
+	resp := parseResponseT(t, w)
+	assert.NotEqual(t, float64(0), resp["code"])
+}
@@ -1,8 +1,11 @@
 package handler
 
 import (
+	"strings"
+
 	"github.com/gin-gonic/gin"
 
+	"github.com/xxxsen/mnote/internal/model"
 	"github.com/xxxsen/mnote/internal/pkg/errcode"
 	"github.com/xxxsen/mnote/internal/pkg/response"
 	"github.com/xxxsen/mnote/internal/service"
@@ -17,10 +20,11 @@ func NewTemplateHandler(templates ITemplateHandlerService) *TemplateHandler {
 }
 
 type templateRequest struct {
-	Name          string   `json:"name"`
-	Description   string   `json:"description"`
-	Content       string   `json:"content"`
-	DefaultTagIDs []string `json:"default_tag_ids"`
+	Name          string                   `json:"name"`
+	Description   string                   `json:"description"`
+	Content       string                   `json:"content"`
+	Variables     []model.TemplateVariable `json:"variables"`
+	DefaultTagIDs []string                 `json:"default_tag_ids"`
 }
 
 type createDocumentFromTemplateRequest struct {
@@ -29,6 +33,10 @@ type createDocumentFromTemplateRequest struct {
 	PreviewContent string            `json:"preview_content"`
 }
 
+type copyGalleryTemplateRequest struct {
+	Name string `json:"name"`
+}
+
 func (h *TemplateHandler) List(c *gin.Context) {
 	items, err := h.templates.List(c.Request.Context(), getUserID(c))
 	if err != nil {
@@ -77,6 +85,7 @@ func (h *TemplateHandler) Create(c *gin.Context) {
 		Name:          req.Name,
 		Description:   req.Description,
 		Content:       req.Content,
+		Variables:     req.Variables,
 		DefaultTagIDs: req.DefaultTagIDs,
 	})
 	if err != nil {
@@ -96,6 +105,7 @@ func (h *TemplateHandler) Update(c *gin.Context) {
 		Name:          req.Name,
 		Description:   req.Description,
 		Content:       req.Content,
+		Variables:     req.Variables,
 		DefaultTagIDs: req.DefaultTagIDs,
 	}); err != nil {
 		handleError(c, err)
@@ -130,3 +140,55 @@ func (h *TemplateHandler) CreateDocument(c *gin.Context) {
 	}
 	response.Success(c, toDocumentResponse(*doc))
 }
+
+// ExportBundle returns the templates listed in the comma separated `ids`
+// query, or all templates when it is empty, as a portable bundle.
+func (h *TemplateHandler) ExportBundle(c *gin.Context) {
+	var ids []string
+	if raw := strings.TrimSpace(c.Query("ids")); raw != "" {
+		ids = strings.Split(raw, ",")
+	}
+	bundle, err := h.templates.ExportBundle(c.Request.Context(), getUserID(c), ids)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, bundle)
+}
+
+func (h *TemplateHandler) ImportBundle(c *gin.Context) {
+	var req model.TemplateBundle
+	if err := bindJSON(c, &req); err != nil {
+		response.Error(c, errcode.ErrInvalid, "invalid request")
+		return
+	}
+	result, err := h.templates.ImportBundle(c.Request.Context(), getUserID(c), &req)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, result)
+}
+
+func (h *TemplateHandler) ListGallery(c *gin.Context) {
+	items, err := h.templates.ListGallery(c.Request.Context())
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, toGalleryTemplateResponses(items))
+}
+
+func (h *TemplateHandler) CopyFromGallery(c *gin.Context) {
+	var req copyGalleryTemplateRequest
+	if err := bindJSON(c, &req); err != nil {
+		response.Error(c, errcode.ErrInvalid, "invalid request")
+		return
+	}
+	item, err := h.templates.CopyFromGallery(c.Request.Context(), getUserID(c), c.Param("slug"), req.Name)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, toTemplateResponse(*item))
+}
@@ -351,3 +351,162 @@ func TestTemplateHandler_CreateDocument_Error(t *testing.T) {
 	resp := parseResponseT(t, w)
 	assert.NotEqual(t, float64(0), resp["code"])
 }
+
+func TestTemplateHandler_Get_Variables(t *testing.T) {
+	mock := &mockTemplateHandlerService{
+		getFn: func(_ context.Context, _, id string) (*model.Template, error) {
+			return &model.Template{ID: id, Variables: []model.TemplateVariable{
+				{Key: "MOOD", Type: model.TemplateVariableSelect, Default: "ok", Options: []string{"ok", "great"}},
+				{Key: "NAME", Type: model.TemplateVariableText},
+			}}, nil
+		},
+	}
+	h := &TemplateHandler{templates: mock}
+	r := newTestRouter()
+	r.GET("/templates/:id", withUserID("u1"), h.Get)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/templates/tpl1", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	variables := parseResponseT(t, w)["data"].(map[string]any)["variables"].([]any)
+	assert.Len(t, variables, 2)
+	assert.Equal(t, map[string]any{
+		"key": "MOOD", "type": "select", "default": "ok", "description": "",
+		"options": []any{"ok", "great"},
+	}, variables[0])
+	assert.NotContains(t, variables[1], "options")
+}
+
+func TestTemplateHandler_Create_WithVariables(t *testing.T) {
+	var captured []model.TemplateVariable
+	mock := &mockTemplateHandlerService{
+		createFn: func(_ context.Context, _ string, input service.CreateTemplateInput) (*model.Template, error) {
+			captured = input.Variables
+			return &model.Template{ID: "tpl1", Variables: input.Variables}, nil
+		},
+	}
+	h := &TemplateHandler{templates: mock}
+	r := newTestRouter()
+	r.POST("/templates", withUserID("u1"), h.Create)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, "POST", "/templates", map[string]any{
+		"name": "Daily", "content": "{{DUE}}",
+		"variables": []map[string]any{{"key": "DUE", "type": "date", "default": "sys:today+1d"}},
+	}))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []model.TemplateVariable{{Key: "DUE", Type: "date", Default: "sys:today+1d"}}, captured)
+}
+
+func TestTemplateHandler_ExportBundle(t *testing.T) {
+	var captured []string
+	mock := &mockTemplateHandlerService{
+		exportFn: func(_ context.Context, _ string, ids []string) (*model.TemplateBundle, error) {
+			captured = ids
+			return &model.TemplateBundle{
+				Format: model.TemplateBundleFormat, Version: model.TemplateBundleVersion,
+				Templates: []model.TemplateBundleItem{{Name: "Daily", Tags: []string{"journal"}}},
+			}, nil
+		},
+	}
+	h := NewTemplateHandler(mock)
+	r := newTestRouter()
+	r.GET("/templates/export", withUserID("u1"), h.ExportBundle)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/templates/export?ids=tpl1,tpl2", nil))
+
+	resp := parseResponseT(t, w)
+	assert.Equal(t, float64(0), resp["code"])
+	assert.Equal(t, []string{"tpl1", "tpl2"}, captured)
+	data, ok := resp["data"].(map[string]any)
+	assert.True(t, ok)
+	assert.Equal(t, model.TemplateBundleFormat, data["format"])
+}
+
+func TestTemplateHandler_ImportBundle(t *testing.T) {
+	mock := &mockTemplateHandlerService{
+		importFn: func(
+			_ context.Context, _ string, bundle *model.TemplateBundle,
+		) (*service.TemplateImportResult, error) {
+			assert.Equal(t, "Daily", bundle.Templates[0].Name)
+			return &service.TemplateImportResult{Created: 1}, nil
+		},
+	}
+	h := NewTemplateHandler(mock)
+	r := newTestRouter()
+	r.POST("/templates/import", withUserID("u1"), h.ImportBundle)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, http.MethodPost, "/templates/import", map[string]any{
+		"format": model.TemplateBundleFormat, "version": 1,
+		"templates": []map[string]any{{"name": "Daily", "content": "x", "tags": []string{"journal"}}},
+	}))
+
+	resp := parseResponseT(t, w)
+	assert.Equal(t, float64(0), resp["code"])
+}
+
+func TestTemplateHandler_ImportBundle_UnknownField(t *testing.T) {
+	h := NewTemplateHandler(&mockTemplateHandlerService{})
+	r := newTestRouter()
+	r.POST("/templates/import", withUserID("u1"), h.ImportBundle)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, http.MethodPost, "/templates/import", map[string]any{"tag_ids": []string{}}))
+
+	resp := parseResponseT(t, w)
+	assert.NotEqual(t, float64(0), resp["code"])
+}
+
+func TestTemplateHandler_ListGallery(t *testing.T) {
+	mock := &mockTemplateHandlerService{
+		galleryFn: func(context.Context) ([]model.GalleryTemplate, error) {
+			return []model.GalleryTemplate{{Slug: "daily-note", Version: 2, Name: "Daily note"}}, nil
+		},
+	}
+	h := NewTemplateHandler(mock)
+	r := newTestRouter()
+	r.GET("/templates/gallery", withUserID("u1"), h.ListGallery)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/templates/gallery", nil))
+
+	resp := parseResponseT(t, w)
+	assert.Equal(t, float64(0), resp["code"])
+	items, ok := resp["data"].([]any)
+	assert.True(t, ok)
+	item, ok := items[0].(map[string]any)
+	assert.True(t, ok)
+	assert.Equal(t, "daily-note", item["slug"])
+	assert.Equal(t, []any{}, item["tags"])
+	assert.Equal(t, []any{}, item["variables"])
+}
+
+func TestTemplateHandler_CopyFromGallery(t *testing.T) {
+	mock := &mockTemplateHandlerService{
+		copyFn: func(_ context.Context, userID, slug, name string) (*model.Template, error) {
+			assert.Equal(t, "u1", userID)
+			assert.Equal(t, "daily-note", slug)
+			assert.Equal(t, "My daily", name)
+			return &model.Template{ID: "tpl1", Name: name, BuiltIn: 1, SourceSlug: slug, SourceVersion: 2}, nil
+		},
+	}
+	h := NewTemplateHandler(mock)
+	r := newTestRouter()
+	r.POST("/templates/gallery/:slug/copy", withUserID("u1"), h.CopyFromGallery)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, http.MethodPost, "/templates/gallery/daily-note/copy", map[string]any{
+		"name": "My daily",
+	}))
+
+	resp := parseResponseT(t, w)
+	assert.Equal(t, float64(0), resp["code"])
+	data, ok := resp["data"].(map[string]any)
+	assert.True(t, ok)
+	assert.Equal(t, "daily-note", data["source_slug"])
+	assert.Equal(t, float64(2), data["source_version"])
+}
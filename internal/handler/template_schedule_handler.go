@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/xxxsen/mnote/internal/pkg/errcode"
+	"github.com/xxxsen/mnote/internal/pkg/response"
+	"github.com/xxxsen/mnote/internal/service"
+)
+
+type TemplateScheduleHandler struct {
+	schedules ITemplateScheduleHandlerService
+}
+
+func NewTemplateScheduleHandler(schedules ITemplateScheduleHandlerService) *TemplateScheduleHandler {
+	return &TemplateScheduleHandler{schedules: schedules}
+}
+
+type templateScheduleRequest struct {
+	TemplateID   string   `json:"template_id"`
+	CronSpec     string   `json:"cron_spec"`
+	TitlePattern string   `json:"title_pattern"`
+	TagIDs       []string `json:"tag_ids"`
+	Enabled      *bool    `json:"enabled"`
+}
+
+func (req templateScheduleRequest) input() service.TemplateScheduleInput {
+	return service.TemplateScheduleInput{
+		TemplateID:   req.TemplateID,
+		CronSpec:     req.CronSpec,
+		TitlePattern: req.TitlePattern,
+		TagIDs:       req.TagIDs,
+		Enabled:      req.Enabled == nil || *req.Enabled,
+	}
+}
+
+func (h *TemplateScheduleHandler) List(c *gin.Context) {
+	items, err := h.schedules.List(c.Request.Context(), getUserID(c))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, toTemplateScheduleResponses(items))
+}
+
+func (h *TemplateScheduleHandler) Create(c *gin.Context) {
+	var req templateScheduleRequest
+	if err := bindJSON(c, &req); err != nil {
+		response.Error(c, errcode.ErrInvalid, "invalid request")
+		return
+	}
+	item, err := h.schedules.Create(c.Request.Context(), getUserID(c), req.input())
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, toTemplateScheduleResponse(*item))
+}
+
+func (h *TemplateScheduleHandler) Update(c *gin.Context) {
+	var req templateScheduleRequest
+	if err := bindJSON(c, &req); err != nil {
+		response.Error(c, errcode.ErrInvalid, "invalid request")
+		return
+	}
+	item, err := h.schedules.Update(c.Request.Context(), getUserID(c), c.Param("id"), req.input())
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, toTemplateScheduleResponse(*item))
+}
+
+func (h *TemplateScheduleHandler) Delete(c *gin.Context) {
+	if err := h.schedules.Delete(c.Request.Context(), getUserID(c), c.Param("id")); err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, gin.H{"ok": true})
+}
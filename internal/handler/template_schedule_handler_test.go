@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+	"github.com/xxxsen/mnote/internal/service"
+)
+
+func TestTemplateScheduleHandler_List(t *testing.T) {
+	mock := &mockTemplateScheduleHandlerService{
+		listFn: func(_ context.Context, userID string) ([]model.TemplateSchedule, error) {
+			assert.Equal(t, "u1", userID)
+			return []model.TemplateSchedule{{ID: "s1", TemplateID: "tpl1", Enabled: 1, NextRunAt: 100}}, nil
+		},
+	}
+	h := NewTemplateScheduleHandler(mock)
+	r := newTestRouter()
+	r.GET("/template-schedules", withUserID("u1"), h.List)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/template-schedules", nil))
+
+	resp := parseResponseT(t, w)
+	assert.Equal(t, float64(0), resp["code"])
+	items, ok := resp["data"].([]any)
+	require.True(t, ok)
+	require.Len(t, items, 1)
+	item, ok := items[0].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, true, item["enabled"])
+	assert.Equal(t, []any{}, item["tag_ids"])
+}
+
+func TestTemplateScheduleHandler_Create(t *testing.T) {
+	var captured service.TemplateScheduleInput
+	mock := &mockTemplateScheduleHandlerService{
+		createFn: func(
+			_ context.Context, _ string, input service.TemplateScheduleInput,
+		) (*model.TemplateSchedule, error) {
+			captured = input
+			return &model.TemplateSchedule{ID: "s1", TemplateID: input.TemplateID, CronSpec: input.CronSpec}, nil
+		},
+	}
+	h := NewTemplateScheduleHandler(mock)
+	r := newTestRouter()
+	r.POST("/template-schedules", withUserID("u1"), h.Create)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, http.MethodPost, "/template-schedules", map[string]any{
+		"template_id": "tpl1", "cron_spec": "0 9 * * *", "title_pattern": "{{sys:today}}",
+		"tag_ids": []string{"t1"},
+	}))
+
+	resp := parseResponseT(t, w)
+	assert.Equal(t, float64(0), resp["code"])
+	assert.Equal(t, "tpl1", captured.TemplateID)
+	assert.Equal(t, []string{"t1"}, captured.TagIDs)
+	assert.True(t, captured.Enabled, "schedules are enabled unless stated otherwise")
+}
+
+func TestTemplateScheduleHandler_Update_Disable(t *testing.T) {
+	mock := &mockTemplateScheduleHandlerService{
+		updateFn: func(
+			_ context.Context, _, scheduleID string, input service.TemplateScheduleInput,
+		) (*model.TemplateSchedule, error) {
+			assert.Equal(t, "s1", scheduleID)
+			assert.False(t, input.Enabled)
+			return &model.TemplateSchedule{ID: scheduleID}, nil
+		},
+	}
+	h := NewTemplateScheduleHandler(mock)
+	r := newTestRouter()
+	r.PUT("/template-schedules/:id", withUserID("u1"), h.Update)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, http.MethodPut, "/template-schedules/s1", map[string]any{
+		"template_id": "tpl1", "cron_spec": "@weekly", "enabled": false,
+	}))
+
+	resp := parseResponseT(t, w)
+	assert.Equal(t, float64(0), resp["code"])
+}
+
+func TestTemplateScheduleHandler_Create_InvalidJSON(t *testing.T) {
+	h := NewTemplateScheduleHandler(&mockTemplateScheduleHandlerService{})
+	r := newTestRouter()
+	r.POST("/template-schedules", withUserID("u1"), h.Create)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, http.MethodPost, "/template-schedules", "oops"))
+
+	resp := parseResponseT(t, w)
+	assert.NotEqual(t, float64(0), resp["code"])
+}
+
+func TestTemplateScheduleHandler_Delete_NotFound(t *testing.T) {
+	mock := &mockTemplateScheduleHandlerService{
+		deleteFn: func(context.Context, string, string) error { return appErr.ErrNotFound },
+	}
+	h := NewTemplateScheduleHandler(mock)
+	r := newTestRouter()
+	r.DELETE("/template-schedules/:id", withUserID("u1"), h.Delete)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodDelete, "/template-schedules/s1", nil))
+
+	resp := parseResponseT(t, w)
+	assert.NotEqual(t, float64(0), resp["code"])
+}
@@ -1,13 +1,17 @@
 package handler
 
 import (
+	"strconv"
+	"strings"
 	"time"
 	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
 
+	"github.com/xxxsen/mnote/internal/model"
 	"github.com/xxxsen/mnote/internal/pkg/errcode"
 	"github.com/xxxsen/mnote/internal/pkg/response"
+	"github.com/xxxsen/mnote/internal/service"
 )
 
 const maxTodoContentLength = 500
@@ -21,9 +25,13 @@ func NewTodoHandler(todos ITodoHandlerService) *TodoHandler {
 }
 
 type createTodoRequest struct {
-	Content string `json:"content"`
-	DueDate string `json:"due_date"`
-	Done    *bool  `json:"done"`
+	Content    string                `json:"content"`
+	DueDate    string                `json:"due_date"`
+	Done       *bool                 `json:"done"`
+	Priority   int                   `json:"priority"`
+	DocumentID string                `json:"document_id"`
+	ParentID   string                `json:"parent_id"`
+	Recurrence *model.TodoRecurrence `json:"recurrence"`
 }
 
 func (h *TodoHandler) Create(c *gin.Context) {
@@ -41,19 +49,25 @@ func (h *TodoHandler) Create(c *gin.Context) {
 		response.Error(c, errcode.ErrInvalid, "content is too long")
 		return
 	}
-	if req.DueDate == "" {
-		response.Error(c, errcode.ErrInvalid, "due_date is required")
-		return
-	}
-	if _, err := time.Parse("2006-01-02", req.DueDate); err != nil {
-		response.Error(c, errcode.ErrInvalid, "due_date must be in YYYY-MM-DD format")
-		return
+	if req.DueDate != "" {
+		if _, err := time.Parse("2006-01-02", req.DueDate); err != nil {
+			response.Error(c, errcode.ErrInvalid, "due_date must be in YYYY-MM-DD format")
+			return
+		}
 	}
 	done := false
 	if req.Done != nil {
 		done = *req.Done
 	}
-	todo, err := h.todos.CreateTodo(c.Request.Context(), userID, req.Content, req.DueDate, done)
+	todo, err := h.todos.CreateTodo(c.Request.Context(), userID, service.TodoCreateInput{
+		Content:    req.Content,
+		DueDate:    req.DueDate,
+		Done:       done,
+		Priority:   req.Priority,
+		DocumentID: req.DocumentID,
+		ParentID:   req.ParentID,
+		Recurrence: req.Recurrence,
+	})
 	if err != nil {
 		handleError(c, err)
 		return
@@ -63,6 +77,10 @@ func (h *TodoHandler) Create(c *gin.Context) {
 
 func (h *TodoHandler) List(c *gin.Context) {
 	userID := getUserID(c)
+	if hasTodoFilters(c) {
+		h.listFiltered(c, userID)
+		return
+	}
 	startDate := c.Query("start")
 	endDate := c.Query("end")
 	if startDate == "" || endDate == "" {
@@ -85,6 +103,63 @@ func (h *TodoHandler) List(c *gin.Context) {
 	response.Success(c, toTodoResponses(todos))
 }
 
+var todoFilterParams = []string{"priority", "document_id", "parent_id", "undated", "overdue"}
+
+func hasTodoFilters(c *gin.Context) bool {
+	for _, key := range todoFilterParams {
+		if _, ok := c.GetQuery(key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *TodoHandler) listFiltered(c *gin.Context, userID string) {
+	query := model.TodoListQuery{
+		StartDate:  c.Query("start"),
+		EndDate:    c.Query("end"),
+		DocumentID: c.Query("document_id"),
+		ParentID:   c.Query("parent_id"),
+		Today:      c.Query("today"),
+	}
+	if raw := strings.TrimSpace(c.Query("priority")); raw != "" {
+		for _, part := range strings.Split(raw, ",") {
+			priority, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				response.Error(c, errcode.ErrInvalid, "priority must be a comma separated list of 0-3")
+				return
+			}
+			query.Priorities = append(query.Priorities, priority)
+		}
+	}
+	var err error
+	if query.Undated, err = parseTodoFlag(c.Query("undated")); err != nil {
+		response.Error(c, errcode.ErrInvalid, "undated must be a boolean")
+		return
+	}
+	if query.Overdue, err = parseTodoFlag(c.Query("overdue")); err != nil {
+		response.Error(c, errcode.ErrInvalid, "overdue must be a boolean")
+		return
+	}
+	todos, err := h.todos.ListTodos(c.Request.Context(), userID, query)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, toTodoResponses(todos))
+}
+
+func parseTodoFlag(raw string) (bool, error) {
+	if raw == "" {
+		return false, nil
+	}
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, err
+	}
+	return value, nil
+}
+
 type toggleDoneRequest struct {
 	Done bool `json:"done"`
 }
@@ -93,6 +168,15 @@ type updateTodoRequest struct {
 	Content string `json:"content"`
 }
 
+type updateTodoAttributesRequest struct {
+	Priority   *int    `json:"priority"`
+	DocumentID *string `json:"document_id"`
+}
+
+type updateTodoRecurrenceRequest struct {
+	Recurrence *model.TodoRecurrence `json:"recurrence"`
+}
+
 func (h *TodoHandler) ToggleDone(c *gin.Context) {
 	userID := getUserID(c)
 	todoID := c.Param("id")
@@ -132,6 +216,45 @@ func (h *TodoHandler) Update(c *gin.Context) {
 	response.Success(c, toTodoResponse(*todo))
 }
 
+func (h *TodoHandler) UpdateRecurrence(c *gin.Context) {
+	userID := getUserID(c)
+	todoID := c.Param("id")
+	var req updateTodoRecurrenceRequest
+	if err := bindJSON(c, &req); err != nil {
+		response.Error(c, errcode.ErrInvalid, "invalid request body")
+		return
+	}
+	todo, err := h.todos.UpdateRecurrence(c.Request.Context(), userID, todoID, req.Recurrence)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, toTodoResponse(*todo))
+}
+
+func (h *TodoHandler) UpdateAttributes(c *gin.Context) {
+	userID := getUserID(c)
+	todoID := c.Param("id")
+	var req updateTodoAttributesRequest
+	if err := bindJSON(c, &req); err != nil {
+		response.Error(c, errcode.ErrInvalid, "invalid request body")
+		return
+	}
+	if req.Priority == nil && req.DocumentID == nil {
+		response.Error(c, errcode.ErrInvalid, "priority or document_id is required")
+		return
+	}
+	todo, err := h.todos.UpdateAttributes(c.Request.Context(), userID, todoID, service.TodoAttributesInput{
+		Priority:   req.Priority,
+		DocumentID: req.DocumentID,
+	})
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, toTodoResponse(*todo))
+}
+
 func (h *TodoHandler) Delete(c *gin.Context) {
 	userID := getUserID(c)
 	todoID := c.Param("id")
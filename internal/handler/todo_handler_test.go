@@ -9,14 +9,16 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/service"
 )
 
 func TestTodoHandler_Create_Success(t *testing.T) {
 	mock := &mockTodoHandlerService{
-		createFn: func(_ context.Context, userID, content, dueDate string, done bool) (*model.Todo, error) {
-			return &model.Todo{ID: "t1", UserID: userID, Content: content, DueDate: dueDate, Done: 0}, nil
+		createFn: func(_ context.Context, userID string, input service.TodoCreateInput) (*model.Todo, error) {
+			return &model.Todo{ID: "t1", UserID: userID, Content: input.Content, DueDate: input.DueDate, Done: 0}, nil
 		},
 	}
 	h := &TodoHandler{todos: mock}
@@ -64,19 +66,30 @@ func TestTodoHandler_Create_ContentTooLong(t *testing.T) {
 	assert.NotEqual(t, float64(0), resp["code"])
 }
 
-func TestTodoHandler_Create_MissingDueDate(t *testing.T) {
-	h := &TodoHandler{todos: &mockTodoHandlerService{}}
+func TestTodoHandler_Create_Undated(t *testing.T) {
+	var captured service.TodoCreateInput
+	mock := &mockTodoHandlerService{
+		createFn: func(_ context.Context, _ string, input service.TodoCreateInput) (*model.Todo, error) {
+			captured = input
+			return &model.Todo{ID: "t1", Content: input.Content, Priority: input.Priority}, nil
+		},
+	}
+	h := &TodoHandler{todos: mock}
 	r := newTestRouter()
 	r.POST("/todos", withUserID("u1"), h.Create)
 
 	w := httptest.NewRecorder()
 	req := jsonRequestT(t, "POST", "/todos", map[string]any{
-		"content": "Do something",
+		"content": "Do something", "priority": 3, "document_id": "d1", "parent_id": "p1",
 	})
 	r.ServeHTTP(w, req)
 
-	resp := parseResponseT(t, w)
-	assert.NotEqual(t, float64(0), resp["code"])
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, float64(0), parseResponseT(t, w)["code"])
+	assert.Empty(t, captured.DueDate)
+	assert.Equal(t, model.TodoPriorityHigh, captured.Priority)
+	assert.Equal(t, "d1", captured.DocumentID)
+	assert.Equal(t, "p1", captured.ParentID)
 }
 
 func TestTodoHandler_Create_InvalidDueDate(t *testing.T) {
@@ -228,7 +241,7 @@ func TestTodoHandler_Delete_Error(t *testing.T) {
 
 func TestTodoHandler_Create_ServiceError(t *testing.T) {
 	mock := &mockTodoHandlerService{
-		createFn: func(_ context.Context, _, _, _ string, _ bool) (*model.Todo, error) {
+		createFn: func(context.Context, string, service.TodoCreateInput) (*model.Todo, error) {
 			return nil, errors.New("create error")
 		},
 	}
@@ -344,8 +357,8 @@ func TestTodoHandler_Update_InvalidJSON(t *testing.T) {
 func TestTodoHandler_Create_WithDone(t *testing.T) {
 	var capturedDone bool
 	mock := &mockTodoHandlerService{
-		createFn: func(_ context.Context, _, _, _ string, done bool) (*model.Todo, error) {
-			capturedDone = done
+		createFn: func(_ context.Context, _ string, input service.TodoCreateInput) (*model.Todo, error) {
+			capturedDone = input.Done
 			return &model.Todo{ID: "t1", Done: 1}, nil
 		},
 	}
@@ -363,3 +376,115 @@ func TestTodoHandler_Create_WithDone(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.True(t, capturedDone)
 }
+
+func TestTodoHandler_Create_WithRecurrence(t *testing.T) {
+	var captured *model.TodoRecurrence
+	mock := &mockTodoHandlerService{
+		createFn: func(_ context.Context, _ string, input service.TodoCreateInput) (*model.Todo, error) {
+			captured = input.Recurrence
+			return &model.Todo{
+				ID: "t1", DueDate: input.DueDate, Recurrence: input.Recurrence, SeriesID: "t1", Occurrence: 1,
+			}, nil
+		},
+	}
+	h := &TodoHandler{todos: mock}
+	r := newTestRouter()
+	r.POST("/todos", withUserID("u1"), h.Create)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, "POST", "/todos", map[string]any{
+		"content": "Weekly review", "due_date": "2026-05-01",
+		"recurrence": map[string]any{"freq": "weekly", "weekdays": []int{5}, "count": 10},
+	}))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, captured)
+	assert.Equal(t, model.TodoRecurrence{Freq: "weekly", Weekdays: []int{5}, Count: 10}, *captured)
+	data := parseResponseT(t, w)["data"].(map[string]any)
+	assert.Equal(t, "weekly", data["recurrence"].(map[string]any)["freq"])
+	assert.Equal(t, float64(1), data["occurrence"])
+}
+
+func TestTodoHandler_UpdateRecurrence(t *testing.T) {
+	mock := &mockTodoHandlerService{
+		recurrenceFn: func(
+			_ context.Context, _, todoID string, recurrence *model.TodoRecurrence,
+		) (*model.Todo, error) {
+			assert.Equal(t, "t1", todoID)
+			assert.Nil(t, recurrence)
+			return &model.Todo{ID: todoID}, nil
+		},
+	}
+	h := &TodoHandler{todos: mock}
+	r := newTestRouter()
+	r.PUT("/todos/:id/recurrence", withUserID("u1"), h.UpdateRecurrence)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, "PUT", "/todos/t1/recurrence", map[string]any{"recurrence": nil}))
+	assert.Equal(t, http.StatusOK, w.Code)
+	data := parseResponseT(t, w)["data"].(map[string]any)
+	assert.NotContains(t, data, "recurrence")
+}
+
+func TestTodoHandler_List_Filters(t *testing.T) {
+	var captured model.TodoListQuery
+	mock := &mockTodoHandlerService{
+		listTodosFn: func(_ context.Context, _ string, query model.TodoListQuery) ([]model.Todo, error) {
+			captured = query
+			return []model.Todo{{ID: "t1", Priority: 2, Subtasks: &model.TodoProgress{Total: 2, Done: 1}}}, nil
+		},
+	}
+	h := &TodoHandler{todos: mock}
+	r := newTestRouter()
+	r.GET("/todos", withUserID("u1"), h.List)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/todos?priority=2,3&document_id=d1&overdue=1&today=2026-05-01", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, model.TodoListQuery{
+		Priorities: []int{2, 3}, DocumentID: "d1", Overdue: true, Today: "2026-05-01",
+	}, captured)
+	items := parseResponseT(t, w)["data"].([]any)
+	require.Len(t, items, 1)
+	item := items[0].(map[string]any)
+	assert.Equal(t, float64(2), item["priority"])
+	assert.Equal(t, map[string]any{"total": float64(2), "done": float64(1)}, item["subtasks"])
+}
+
+func TestTodoHandler_List_InvalidFilters(t *testing.T) {
+	h := &TodoHandler{todos: &mockTodoHandlerService{}}
+	r := newTestRouter()
+	r.GET("/todos", withUserID("u1"), h.List)
+
+	for _, query := range []string{"priority=high", "undated=maybe", "overdue=x"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest("GET", "/todos?"+query, nil))
+		assert.NotEqual(t, float64(0), parseResponseT(t, w)["code"], query)
+	}
+}
+
+func TestTodoHandler_UpdateAttributes(t *testing.T) {
+	mock := &mockTodoHandlerService{
+		attributesFn: func(
+			_ context.Context, _, todoID string, input service.TodoAttributesInput,
+		) (*model.Todo, error) {
+			require.NotNil(t, input.Priority)
+			assert.Equal(t, 1, *input.Priority)
+			assert.Nil(t, input.DocumentID)
+			return &model.Todo{ID: todoID, Priority: *input.Priority}, nil
+		},
+	}
+	h := &TodoHandler{todos: mock}
+	r := newTestRouter()
+	r.PUT("/todos/:id/attributes", withUserID("u1"), h.UpdateAttributes)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, "PUT", "/todos/t1/attributes", map[string]any{"priority": 1}))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, float64(1), parseResponseT(t, w)["data"].(map[string]any)["priority"])
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, "PUT", "/todos/t1/attributes", map[string]any{}))
+	assert.NotEqual(t, float64(0), parseResponseT(t, w)["code"])
+}
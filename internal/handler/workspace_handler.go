@@ -0,0 +1,151 @@
+package handler
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/xxxsen/mnote/internal/middleware"
+	"github.com/xxxsen/mnote/internal/pkg/errcode"
+	"github.com/xxxsen/mnote/internal/pkg/response"
+	"github.com/xxxsen/mnote/internal/service"
+)
+
+// WorkspaceHeader selects the workspace a request acts in. Without it the
+// request acts in the caller's personal workspace.
+const WorkspaceHeader = "X-Workspace-Id"
+
+type WorkspaceHandler struct {
+	workspaces IWorkspaceService
+}
+
+func NewWorkspaceHandler(workspaces IWorkspaceService) *WorkspaceHandler {
+	return &WorkspaceHandler{workspaces: workspaces}
+}
+
+// Scope resolves the workspace named by WorkspaceHeader. For a team
+// workspace it replaces the user ID seen by later handlers with the
+// workspace ID, which owns the workspace's content, and passes the caller's
+// role to the services through the request context.
+func (h *WorkspaceHandler) Scope(c *gin.Context) {
+	workspaceID := c.GetHeader(WorkspaceHeader)
+	userID := getUserID(c)
+	if workspaceID == "" || workspaceID == userID {
+		c.Next()
+		return
+	}
+	access, err := h.workspaces.Authorize(c.Request.Context(), userID, workspaceID)
+	if err != nil {
+		handleError(c, err)
+		c.Abort()
+		return
+	}
+	c.Request = c.Request.WithContext(service.WithWorkspaceAccess(c.Request.Context(), access))
+	c.Set(middleware.ContextUserIDKey, access.WorkspaceID)
+	c.Next()
+}
+
+func (h *WorkspaceHandler) List(c *gin.Context) {
+	items, err := h.workspaces.List(c.Request.Context(), getUserID(c))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, items)
+}
+
+type workspaceRequest struct {
+	Name string `json:"name"`
+}
+
+func (h *WorkspaceHandler) Create(c *gin.Context) {
+	var req workspaceRequest
+	if err := bindJSON(c, &req); err != nil {
+		response.Error(c, errcode.ErrInvalid, "invalid request")
+		return
+	}
+	workspace, err := h.workspaces.Create(c.Request.Context(), getUserID(c), req.Name)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, workspace)
+}
+
+func (h *WorkspaceHandler) Update(c *gin.Context) {
+	var req workspaceRequest
+	if err := bindJSON(c, &req); err != nil {
+		response.Error(c, errcode.ErrInvalid, "invalid request")
+		return
+	}
+	workspace, err := h.workspaces.Rename(c.Request.Context(), getUserID(c), c.Param("id"), req.Name)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, workspace)
+}
+
+func (h *WorkspaceHandler) Delete(c *gin.Context) {
+	if err := h.workspaces.Delete(c.Request.Context(), getUserID(c), c.Param("id")); err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, gin.H{"ok": true})
+}
+
+func (h *WorkspaceHandler) ListMembers(c *gin.Context) {
+	members, err := h.workspaces.ListMembers(c.Request.Context(), getUserID(c), c.Param("id"))
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, members)
+}
+
+type addWorkspaceMemberRequest struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+func (h *WorkspaceHandler) AddMember(c *gin.Context) {
+	var req addWorkspaceMemberRequest
+	if err := bindJSON(c, &req); err != nil {
+		response.Error(c, errcode.ErrInvalid, "invalid request")
+		return
+	}
+	member, err := h.workspaces.AddMember(c.Request.Context(), getUserID(c), c.Param("id"), req.Email, req.Role)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, member)
+}
+
+type updateWorkspaceMemberRequest struct {
+	Role string `json:"role"`
+}
+
+func (h *WorkspaceHandler) UpdateMember(c *gin.Context) {
+	var req updateWorkspaceMemberRequest
+	if err := bindJSON(c, &req); err != nil {
+		response.Error(c, errcode.ErrInvalid, "invalid request")
+		return
+	}
+	member, err := h.workspaces.UpdateMemberRole(
+		c.Request.Context(), getUserID(c), c.Param("id"), c.Param("user_id"), req.Role,
+	)
+	if err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, member)
+}
+
+func (h *WorkspaceHandler) RemoveMember(c *gin.Context) {
+	if err := h.workspaces.RemoveMember(
+		c.Request.Context(), getUserID(c), c.Param("id"), c.Param("user_id"),
+	); err != nil {
+		handleError(c, err)
+		return
+	}
+	response.Success(c, gin.H{"ok": true})
+}
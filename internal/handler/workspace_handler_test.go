@@ -0,0 +1,115 @@
+package handler
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/errcode"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+	"github.com/xxxsen/mnote/internal/pkg/response"
+	"github.com/xxxsen/mnote/internal/service"
+)
+
+func TestWorkspaceHandler_Scope(t *testing.T) {
+	h := NewWorkspaceHandler(&mockWorkspaceService{
+		authorizeFn: func(_ context.Context, userID, workspaceID string) (service.WorkspaceAccess, error) {
+			if workspaceID != "w1" {
+				return service.WorkspaceAccess{}, appErr.ErrNotFound
+			}
+			return service.WorkspaceAccess{WorkspaceID: "w1", UserID: userID, Role: model.WorkspaceRoleEditor}, nil
+		},
+	})
+	r := newTestRouter()
+	r.GET("/documents", withUserID("u1"), h.Scope, func(c *gin.Context) {
+		response.Success(c, gin.H{"owner": getUserID(c)})
+	})
+
+	tests := []struct {
+		header string
+		code   uint32
+		owner  string
+	}{
+		{header: "", owner: "u1"},
+		{header: "u1", owner: "u1"},
+		{header: "w1", owner: "w1"},
+		{header: "w2", code: errcode.ErrNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/documents", nil)
+		if tt.header != "" {
+			req.Header.Set(WorkspaceHeader, tt.header)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		body := parseResponseT(t, w)
+		assert.Equal(t, float64(tt.code), body["code"], tt.header)
+		if tt.owner != "" {
+			data, ok := body["data"].(map[string]any)
+			require.True(t, ok)
+			assert.Equal(t, tt.owner, data["owner"], tt.header)
+		}
+	}
+}
+
+func TestWorkspaceHandler_Create(t *testing.T) {
+	h := NewWorkspaceHandler(&mockWorkspaceService{
+		createFn: func(_ context.Context, userID, name string) (*model.Workspace, error) {
+			return &model.Workspace{ID: "w1", Name: name, OwnerID: userID, Role: model.WorkspaceRoleOwner}, nil
+		},
+	})
+	r := newTestRouter()
+	r.POST("/workspaces", withUserID("u1"), h.Create)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, "POST", "/workspaces", map[string]any{"name": "Team"}))
+	body := parseResponseT(t, w)
+	require.Equal(t, float64(0), body["code"])
+	data, ok := body["data"].(map[string]any)
+	require.True(t, ok)
+	assert.Equal(t, "Team", data["name"])
+	assert.Equal(t, "u1", data["owner_id"])
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, "POST", "/workspaces", map[string]any{"title": "Team"}))
+	assert.Equal(t, float64(errcode.ErrInvalid), parseResponseT(t, w)["code"])
+}
+
+func TestWorkspaceHandler_Members(t *testing.T) {
+	var removed string
+	h := NewWorkspaceHandler(&mockWorkspaceService{
+		addMemberFn: func(_ context.Context, userID, workspaceID, email, role string) (*model.WorkspaceMember, error) {
+			if userID != "u1" {
+				return nil, appErr.ErrForbidden
+			}
+			return &model.WorkspaceMember{WorkspaceID: workspaceID, UserID: "u2", Email: email, Role: role}, nil
+		},
+		removeMemberFn: func(_ context.Context, _, _, memberID string) error {
+			removed = memberID
+			return nil
+		},
+	})
+	r := newTestRouter()
+	r.POST("/workspaces/:id/members", withUserID("u1"), h.AddMember)
+	r.POST("/other/workspaces/:id/members", withUserID("u3"), h.AddMember)
+	r.DELETE("/workspaces/:id/members/:user_id", withUserID("u1"), h.RemoveMember)
+
+	payload := map[string]any{"email": "b@example.com", "role": model.WorkspaceRoleViewer}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, "POST", "/workspaces/w1/members", payload))
+	assert.Equal(t, float64(0), parseResponseT(t, w)["code"])
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, jsonRequestT(t, "POST", "/other/workspaces/w1/members", payload))
+	assert.Equal(t, float64(errcode.ErrForbidden), parseResponseT(t, w)["code"])
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("DELETE", "/workspaces/w1/members/u2", nil))
+	assert.Equal(t, float64(0), parseResponseT(t, w)["code"])
+	assert.Equal(t, "u2", removed)
+}
@@ -144,3 +144,33 @@ func TestImportCleanupJob_Run_JobDeleteError(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "delete expired jobs")
 }
+
+// --- TemplateScheduleJob ---
+
+type mockTemplateScheduleRunner struct {
+	err       error
+	callCount int
+}
+
+func (m *mockTemplateScheduleRunner) RunDue(context.Context) error {
+	m.callCount++
+	return m.err
+}
+
+func TestTemplateScheduleJob_Name(t *testing.T) {
+	assert.Equal(t, "template_schedule", NewTemplateScheduleJob(nil).Name())
+}
+
+func TestTemplateScheduleJob_Run_NilRunner(t *testing.T) {
+	assert.NoError(t, NewTemplateScheduleJob(nil).Run(context.Background()))
+}
+
+func TestTemplateScheduleJob_Run(t *testing.T) {
+	m := &mockTemplateScheduleRunner{}
+	require.NoError(t, NewTemplateScheduleJob(m).Run(context.Background()))
+	assert.Equal(t, 1, m.callCount)
+
+	m.err = errors.New("boom")
+	err := NewTemplateScheduleJob(m).Run(context.Background())
+	assert.ErrorContains(t, err, "run due template schedules")
+}
@@ -0,0 +1,32 @@
+package job
+
+import (
+	"context"
+	"fmt"
+)
+
+type templateScheduleRunner interface {
+	RunDue(ctx context.Context) error
+}
+
+// TemplateScheduleJob creates the documents of template schedules whose
+// next slot has passed.
+type TemplateScheduleJob struct {
+	schedules templateScheduleRunner
+}
+
+func NewTemplateScheduleJob(schedules templateScheduleRunner) *TemplateScheduleJob {
+	return &TemplateScheduleJob{schedules: schedules}
+}
+
+func (j *TemplateScheduleJob) Name() string { return "template_schedule" }
+
+func (j *TemplateScheduleJob) Run(ctx context.Context) error {
+	if j.schedules == nil {
+		return nil
+	}
+	if err := j.schedules.RunDue(ctx); err != nil {
+		return fmt.Errorf("run due template schedules: %w", err)
+	}
+	return nil
+}
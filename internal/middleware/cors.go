@@ -43,7 +43,7 @@ func setCORSResponseHeaders(c *gin.Context) {
 	)
 	c.Writer.Header().Set(
 		"Access-Control-Allow-Headers",
-		"Authorization, Content-Type, X-Request-Id",
+		"Authorization, Content-Type, X-Request-Id, X-Workspace-Id",
 	)
 	c.Writer.Header().Set(
 		"Access-Control-Expose-Headers",
@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// WebSocketQueryAuth lets WebSocket handshakes authenticate through the
+// query string, since browsers cannot set headers on them. For upgrade
+// requests without an Authorization header, access_token becomes the bearer
+// token and workspace_id the X-Workspace-Id header. Run it before JWTAuth,
+// and only on WebSocket routes: query strings end up in access logs.
+func WebSocketQueryAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !strings.EqualFold(c.GetHeader("Upgrade"), "websocket") || c.GetHeader("Authorization") != "" {
+			c.Next()
+			return
+		}
+		if token := c.Query("access_token"); token != "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+		if workspaceID := c.Query("workspace_id"); workspaceID != "" && c.GetHeader("X-Workspace-Id") == "" {
+			c.Request.Header.Set("X-Workspace-Id", workspaceID)
+		}
+		c.Next()
+	}
+}
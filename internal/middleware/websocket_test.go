@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebSocketQueryAuth_CopiesQueryOnUpgrade(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequestWithContext(context.Background(), "GET", "/?access_token=tok&workspace_id=w1", nil)
+	c.Request.Header.Set("Upgrade", "websocket")
+
+	WebSocketQueryAuth()(c)
+
+	assert.Equal(t, "Bearer tok", c.Request.Header.Get("Authorization"))
+	assert.Equal(t, "w1", c.Request.Header.Get("X-Workspace-Id"))
+}
+
+func TestWebSocketQueryAuth_IgnoresPlainRequests(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequestWithContext(context.Background(), "GET", "/?access_token=tok", nil)
+
+	WebSocketQueryAuth()(c)
+
+	assert.Empty(t, c.Request.Header.Get("Authorization"))
+}
+
+func TestWebSocketQueryAuth_KeepsExistingHeader(t *testing.T) {
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequestWithContext(context.Background(), "GET", "/?access_token=tok", nil)
+	c.Request.Header.Set("Upgrade", "websocket")
+	c.Request.Header.Set("Authorization", "Bearer header")
+
+	WebSocketQueryAuth()(c)
+
+	assert.Equal(t, "Bearer header", c.Request.Header.Get("Authorization"))
+}
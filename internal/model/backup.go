@@ -0,0 +1,39 @@
+package model
+
+const (
+	BackupFormat  = "mnote.backup"
+	BackupVersion = 1
+)
+
+// SchemaMigration is a row of the schema_migrations ledger.
+type SchemaMigration struct {
+	Version  string `json:"version"`
+	Checksum string `json:"checksum"`
+}
+
+// BackupManifest describes an instance backup archive. Every table and file
+// entry carries the SHA-256 of its uncompressed bytes.
+type BackupManifest struct {
+	Format        string             `json:"format"`
+	Version       int                `json:"version"`
+	CreatedAt     int64              `json:"created_at"`
+	SchemaVersion string             `json:"schema_version"`
+	Migrations    []SchemaMigration  `json:"migrations"`
+	Tables        []BackupTableEntry `json:"tables"`
+	Files         []BackupFileEntry  `json:"files"`
+	MissingFiles  []string           `json:"missing_files"`
+}
+
+type BackupTableEntry struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	Rows   int64  `json:"rows"`
+	SHA256 string `json:"sha256"`
+}
+
+type BackupFileEntry struct {
+	Key    string `json:"key"`
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
@@ -1,18 +1,32 @@
 package model
 
 type Document struct {
-	ID              string `json:"id"`
-	UserID          string `json:"user_id"`
-	Title           string `json:"title"`
-	Content         string `json:"content"`
-	State           int    `json:"state"`
-	Pinned          int    `json:"pinned"`
-	Starred         int    `json:"starred"`
-	Ctime           int64  `json:"ctime"`
-	Mtime           int64  `json:"mtime"`
-	ContentHash     string `json:"content_hash"`
-	ContentMtime    int64  `json:"content_mtime"`
-	ContentRevision int64  `json:"content_revision"`
+	ID              string        `json:"id"`
+	UserID          string        `json:"user_id"`
+	Title           string        `json:"title"`
+	Content         string        `json:"content"`
+	State           int           `json:"state"`
+	Pinned          int           `json:"pinned"`
+	Starred         int           `json:"starred"`
+	FolderID        string        `json:"folder_id"`
+	Ctime           int64         `json:"ctime"`
+	Mtime           int64         `json:"mtime"`
+	ContentHash     string        `json:"content_hash"`
+	ContentMtime    int64         `json:"content_mtime"`
+	ContentRevision int64         `json:"content_revision"`
+	Stats           DocumentStats `json:"stats"`
+}
+
+// DocumentStats is computed from the content whenever it is saved.
+type DocumentStats struct {
+	WordCount      int `json:"word_count"`
+	CharCount      int `json:"char_count"`
+	ReadingMinutes int `json:"reading_minutes"`
+	CodeBlocks     int `json:"code_blocks"`
+	Images         int `json:"images"`
+	Links          int `json:"links"`
+	TasksTotal     int `json:"tasks_total"`
+	TasksDone      int `json:"tasks_done"`
 }
 
 // SaveDocumentResult contains only post-attempt metadata. Accepted=false with
@@ -31,3 +45,10 @@ type SaveDocumentResult struct {
 	ContentMtime    int64            `json:"content_mtime"`
 	Mtime           int64            `json:"mtime"`
 }
+
+// DocumentStatsTotals sums the statistics of the documents of a user.
+// EditedDocuments counts those whose content changed since a cutoff.
+type DocumentStatsTotals struct {
+	DocumentStats
+	EditedDocuments int
+}
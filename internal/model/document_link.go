@@ -37,3 +37,76 @@ type DocumentLinksResult struct {
 	Incoming *DocumentLinkPage
 	Outgoing *DocumentLinkPage
 }
+
+// DocumentLink is one row of document_links between two live documents.
+type DocumentLink struct {
+	SourceID string
+	TargetID string
+}
+
+// GraphDocument is the slice of a document the knowledge graph needs.
+type GraphDocument struct {
+	ID      string
+	Title   string
+	Pinned  int
+	Starred int
+	Mtime   int64
+}
+
+// LinkTarget is one outgoing link of a document. Anchor is the heading or
+// ^block anchor named by the wikilink that produced it, "" otherwise.
+type LinkTarget struct {
+	ID     string
+	Anchor string
+}
+
+const (
+	WikilinkStatusResolved  = "resolved"
+	WikilinkStatusMissing   = "missing"
+	WikilinkStatusAmbiguous = "ambiguous"
+)
+
+// DocumentWikilink is a [[Title#anchor]] reference of a document together with
+// how its title resolved. TargetID is set only for resolved links.
+type DocumentWikilink struct {
+	SourceID string
+	Title    string
+	TitleKey string
+	Anchor   string
+	Status   string
+	TargetID string
+	Ctime    int64
+}
+
+// DocumentTitle is a live document matched by a case-folded name. Key is the
+// name that matched; Alias is set when it came from the aliases property
+// rather than the title.
+type DocumentTitle struct {
+	ID    string
+	Title string
+	Mtime int64
+	Key   string
+	Alias bool
+}
+
+const (
+	BrokenLinkKindDocument = "document"
+	BrokenLinkKindWikilink = "wikilink"
+
+	BrokenLinkReasonDeleted = "deleted"
+	BrokenLinkReasonMissing = "missing"
+)
+
+// BrokenLink is a reference of a live document that leads nowhere: a
+// /docs/<id> link (Kind document, TargetID set) or a wikilink whose title
+// matches no document (Kind wikilink, Title set).
+type BrokenLink struct {
+	SourceID    string
+	SourceTitle string
+	SourceMtime int64
+	Kind        string
+	TargetID    string
+	Title       string
+	Anchor      string
+	Reason      string
+}
@@ -0,0 +1,29 @@
+package model
+
+const (
+	PropertyTypeString = "string"
+	PropertyTypeNumber = "number"
+	PropertyTypeBool   = "bool"
+	PropertyTypeDate   = "date"
+	PropertyTypeList   = "list"
+)
+
+// PropertyKeyAliases is the front matter field whose values name the
+// document alongside its title.
+const PropertyKeyAliases = "aliases"
+
+// DocumentProperty is one front matter field of a document. List fields keep
+// their items in Values; the other types hold their canonical text in Value.
+type DocumentProperty struct {
+	Key    string
+	Type   string
+	Value  string
+	Values []string
+}
+
+// PropertyFilter keeps documents whose property Key equals one of Values,
+// compared case-insensitively. A list property matches on any item.
+type PropertyFilter struct {
+	Key    string
+	Values []string
+}
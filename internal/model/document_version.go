@@ -7,6 +7,8 @@ type DocumentVersion struct {
 	Version    int    `json:"version"`
 	Title      string `json:"title"`
 	Content    string `json:"content"`
+	AuthorID   string `json:"author_id"`
+	WordCount  int    `json:"word_count"`
 	Ctime      int64  `json:"ctime"`
 }
 
@@ -15,5 +17,19 @@ type DocumentVersionSummary struct {
 	DocumentID string `json:"document_id"`
 	Version    int    `json:"version"`
 	Title      string `json:"title"`
+	AuthorID   string `json:"author_id"`
 	Ctime      int64  `json:"ctime"`
 }
+
+// VersionActivity is one saved version found by an activity query. WordDelta
+// is its change in words from the previous version, or its words for version
+// 1; DeltaKnown is false when a count predates word tracking or the previous
+// version was pruned.
+type VersionActivity struct {
+	DocumentID string
+	Title      string
+	Version    int
+	Ctime      int64
+	WordDelta  int
+	DeltaKnown bool
+}
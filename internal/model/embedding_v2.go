@@ -118,6 +118,19 @@ type SimilarDocumentResult struct {
 	Score      float32
 }
 
+// SimilarDocumentPair is a neighbour of SourceID in the centroid index.
+type SimilarDocumentPair struct {
+	SourceID string
+	TargetID string
+	Score    float32
+}
+
+type SimilarTagResult struct {
+	TagID         string
+	Score         float32
+	DocumentCount int
+}
+
 type EmbeddingCacheV2 struct {
 	ProfileID   string
 	TaskType    string
@@ -0,0 +1,20 @@
+package model
+
+// Folder is a node of the folder tree of a user. ParentID is empty for a
+// top-level folder; Position orders the folders sharing a parent.
+type Folder struct {
+	ID       string `json:"id"`
+	UserID   string `json:"user_id"`
+	ParentID string `json:"parent_id"`
+	Name     string `json:"name"`
+	Position int    `json:"position"`
+	Ctime    int64  `json:"ctime"`
+	Mtime    int64  `json:"mtime"`
+}
+
+// FolderFilter keeps documents filed directly in folder ID, or at the top
+// level when ID is empty. Recursive also keeps those in its subfolders.
+type FolderFilter struct {
+	ID        string
+	Recursive bool
+}
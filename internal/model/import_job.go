@@ -82,6 +82,7 @@ type ImportJob struct {
 	Processed      int
 	Total          int
 	Tags           []string
+	TagMeta        []Tag
 	Report         *ImportReport
 	LockedUntil    int64
 	Attempts       int
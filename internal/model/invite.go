@@ -0,0 +1,14 @@
+package model
+
+// InviteCode is a single-use registration code. Only the digest of the code
+// is stored; the plain code is shown once when it is created.
+type InviteCode struct {
+	ID         string `json:"id"`
+	CodeDigest string `json:"-"`
+	CreatedBy  string `json:"created_by"`
+	Note       string `json:"note"`
+	ExpiresAt  int64  `json:"expires_at"`
+	UsedBy     string `json:"used_by"`
+	UsedAt     int64  `json:"used_at"`
+	Ctime      int64  `json:"ctime"`
+}
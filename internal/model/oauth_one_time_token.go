@@ -8,6 +8,7 @@ type OAuthOneTimeToken struct {
 	UserID          string
 	EmailNormalized string
 	ReturnTo        string
+	InviteDigest    string
 	ExpiresAt       int64
 	ConsumedAt      int64
 	Ctime           int64
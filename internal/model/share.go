@@ -15,3 +15,21 @@ type Share struct {
 	Ctime         int64  `json:"ctime"`
 	Mtime         int64  `json:"mtime"`
 }
+
+const (
+	CollaboratorRoleEditor = "editor"
+	CollaboratorRoleViewer = "viewer"
+)
+
+// DocumentCollaborator grants a registered user access to a single document.
+// OwnerID is the owning scope of the document; Email is the collaborator's
+// address and is filled in when listing.
+type DocumentCollaborator struct {
+	DocumentID string `json:"document_id"`
+	OwnerID    string `json:"owner_id"`
+	UserID     string `json:"user_id"`
+	Email      string `json:"email"`
+	Role       string `json:"role"`
+	Ctime      int64  `json:"ctime"`
+	Mtime      int64  `json:"mtime"`
+}
@@ -1,17 +1,23 @@
 package model
 
 type Tag struct {
-	ID     string `json:"id"`
-	UserID string `json:"user_id"`
-	Name   string `json:"name"`
-	Pinned int    `json:"pinned"`
-	Ctime  int64  `json:"ctime"`
-	Mtime  int64  `json:"mtime"`
+	ID          string `json:"id"`
+	UserID      string `json:"user_id"`
+	Name        string `json:"name"`
+	Color       string `json:"color"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"`
+	Pinned      int    `json:"pinned"`
+	Ctime       int64  `json:"ctime"`
+	Mtime       int64  `json:"mtime"`
 }
 
 type TagSummary struct {
-	ID     string `json:"id"`
-	Name   string `json:"name"`
-	Pinned int    `json:"pinned"`
-	Count  int    `json:"count"`
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Color       string `json:"color"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"`
+	Pinned      int    `json:"pinned"`
+	Count       int    `json:"count"`
 }
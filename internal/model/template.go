@@ -1,15 +1,41 @@
 package model
 
+const (
+	TemplateVariableText   = "text"
+	TemplateVariableDate   = "date"
+	TemplateVariableSelect = "select"
+	TemplateVariableTag    = "tag"
+)
+
+const (
+	TemplateBundleFormat  = "mnote.templates"
+	TemplateBundleVersion = 1
+)
+
 type Template struct {
-	ID            string   `json:"id"`
-	UserID        string   `json:"user_id"`
-	Name          string   `json:"name"`
-	Description   string   `json:"description"`
-	Content       string   `json:"content"`
-	DefaultTagIDs []string `json:"default_tag_ids"`
-	BuiltIn       int      `json:"built_in"`
-	Ctime         int64    `json:"ctime"`
-	Mtime         int64    `json:"mtime"`
+	ID            string             `json:"id"`
+	UserID        string             `json:"user_id"`
+	Name          string             `json:"name"`
+	Description   string             `json:"description"`
+	Content       string             `json:"content"`
+	Variables     []TemplateVariable `json:"variables"`
+	DefaultTagIDs []string           `json:"default_tag_ids"`
+	BuiltIn       int                `json:"built_in"`
+	SourceSlug    string             `json:"source_slug"`
+	SourceVersion int                `json:"source_version"`
+	Ctime         int64              `json:"ctime"`
+	Mtime         int64              `json:"mtime"`
+}
+
+// TemplateVariable declares a user variable so clients can prompt for it.
+// Default may be a `sys:` date expression for date variables and is a tag
+// ID for tag variables; Options lists the choices of a select variable.
+type TemplateVariable struct {
+	Key         string   `json:"key"`
+	Type        string   `json:"type"`
+	Default     string   `json:"default"`
+	Description string   `json:"description"`
+	Options     []string `json:"options,omitempty"`
 }
 
 type TemplateMeta struct {
@@ -22,3 +48,34 @@ type TemplateMeta struct {
 	Ctime         int64    `json:"ctime"`
 	Mtime         int64    `json:"mtime"`
 }
+
+// GalleryTemplate is a built-in template users can copy. Tags are names,
+// resolved to the user's tags when copied.
+type GalleryTemplate struct {
+	Slug        string             `json:"slug"`
+	Version     int                `json:"version"`
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Content     string             `json:"content"`
+	Variables   []TemplateVariable `json:"variables"`
+	Tags        []string           `json:"tags"`
+	Ctime       int64              `json:"ctime"`
+	Mtime       int64              `json:"mtime"`
+}
+
+// TemplateBundle is the portable form of templates. Default tags and the
+// defaults of tag variables are tag names rather than IDs so a bundle can be
+// imported by another user or instance.
+type TemplateBundle struct {
+	Format    string               `json:"format"`
+	Version   int                  `json:"version"`
+	Templates []TemplateBundleItem `json:"templates"`
+}
+
+type TemplateBundleItem struct {
+	Name        string             `json:"name"`
+	Description string             `json:"description"`
+	Content     string             `json:"content"`
+	Variables   []TemplateVariable `json:"variables"`
+	Tags        []string           `json:"tags"`
+}
@@ -0,0 +1,20 @@
+package model
+
+// TemplateSchedule creates a document from a template whenever its cron
+// spec fires. NextRunAt is the next slot still to be produced; LastRunAt is
+// the slot of the last run, successful or not.
+type TemplateSchedule struct {
+	ID             string   `json:"id"`
+	UserID         string   `json:"user_id"`
+	TemplateID     string   `json:"template_id"`
+	CronSpec       string   `json:"cron_spec"`
+	TitlePattern   string   `json:"title_pattern"`
+	TagIDs         []string `json:"tag_ids"`
+	Enabled        int      `json:"enabled"`
+	NextRunAt      int64    `json:"next_run_at"`
+	LastRunAt      int64    `json:"last_run_at"`
+	LastDocumentID string   `json:"last_document_id"`
+	LastError      string   `json:"last_error"`
+	Ctime          int64    `json:"ctime"`
+	Mtime          int64    `json:"mtime"`
+}
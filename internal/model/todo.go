@@ -1,11 +1,71 @@
 package model
 
+// Todo is a task on the todo list. A todo synced from a task list item of a
+// document carries SourceAnchor, which identifies the item in DocumentID, and
+// SourceLine, its line as of the last save; hand-made todos leave both empty.
 type Todo struct {
-	ID      string `json:"id"`
-	UserID  string `json:"user_id"`
-	Content string `json:"content"`
-	DueDate string `json:"due_date"`
-	Done    int    `json:"done"`
-	Ctime   int64  `json:"ctime"`
-	Mtime   int64  `json:"mtime"`
+	ID           string          `json:"id"`
+	UserID       string          `json:"user_id"`
+	Content      string          `json:"content"`
+	DueDate      string          `json:"due_date"`
+	Done         int             `json:"done"`
+	Priority     int             `json:"priority"`
+	DocumentID   string          `json:"document_id"`
+	ParentID     string          `json:"parent_id"`
+	Recurrence   *TodoRecurrence `json:"recurrence,omitempty"`
+	SeriesID     string          `json:"series_id"`
+	Occurrence   int             `json:"occurrence"`
+	NextID       string          `json:"next_id"`
+	Virtual      bool            `json:"virtual"`
+	Subtasks     *TodoProgress   `json:"subtasks,omitempty"`
+	SourceAnchor string          `json:"source_anchor"`
+	SourceLine   int             `json:"source_line"`
+	Ctime        int64           `json:"ctime"`
+	Mtime        int64           `json:"mtime"`
+}
+
+const (
+	TodoPriorityNone = iota
+	TodoPriorityLow
+	TodoPriorityMedium
+	TodoPriorityHigh
+)
+
+// TodoProgress is the completion roll-up of a parent todo's subtasks.
+type TodoProgress struct {
+	Total int `json:"total"`
+	Done  int `json:"done"`
+}
+
+// TodoListQuery filters GET /todos beyond the calendar date range. Undated
+// and Overdue are mutually exclusive with a date range; Today is the
+// caller's business day used to decide what is overdue.
+type TodoListQuery struct {
+	StartDate  string
+	EndDate    string
+	Priorities []int
+	DocumentID string
+	ParentID   string
+	Undated    bool
+	Overdue    bool
+	Today      string
+	Limit      int
+}
+
+const (
+	TodoRepeatDaily   = "daily"
+	TodoRepeatWeekly  = "weekly"
+	TodoRepeatMonthly = "monthly"
+)
+
+// TodoRecurrence is a small RRULE subset. Every N days is a daily rule with
+// Interval N; Weekdays uses time.Weekday numbering (0 = Sunday). Until is an
+// inclusive YYYY-MM-DD date and Count includes the first occurrence.
+type TodoRecurrence struct {
+	Freq     string `json:"freq"`
+	Interval int    `json:"interval"`
+	Weekdays []int  `json:"weekdays,omitempty"`
+	MonthDay int    `json:"month_day,omitempty"`
+	Until    string `json:"until,omitempty"`
+	Count    int    `json:"count,omitempty"`
 }
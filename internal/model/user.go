@@ -1,10 +1,35 @@
 package model
 
+const (
+	UserRoleUser  = "user"
+	UserRoleAdmin = "admin"
+)
+
 type User struct {
 	ID              string `json:"id"`
 	Email           string `json:"email"`
 	EmailNormalized string `json:"-"`
 	PasswordHash    string `json:"-"`
+	Role            string `json:"role"`
+	Disabled        int    `json:"disabled"`
 	Ctime           int64  `json:"ctime"`
 	Mtime           int64  `json:"mtime"`
 }
+
+// IsAdmin reports whether the user may use the admin API.
+func (u *User) IsAdmin() bool {
+	return u.Role == UserRoleAdmin
+}
+
+// UserStats summarizes the data a user keeps on the server. ContentBytes
+// counts the markdown of documents and their versions, AssetBytes the
+// uploaded files.
+type UserStats struct {
+	UserID       string `json:"user_id"`
+	Email        string `json:"email"`
+	Documents    int64  `json:"documents"`
+	Versions     int64  `json:"versions"`
+	Assets       int64  `json:"assets"`
+	ContentBytes int64  `json:"content_bytes"`
+	AssetBytes   int64  `json:"asset_bytes"`
+}
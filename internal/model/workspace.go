@@ -0,0 +1,35 @@
+package model
+
+const (
+	WorkspaceRoleOwner  = "owner"
+	WorkspaceRoleEditor = "editor"
+	WorkspaceRoleViewer = "viewer"
+)
+
+// Workspace is a space that owns documents, tags, templates and assets. The
+// personal workspace of a user has the user's ID and is not stored; Personal
+// marks it in listings. Role is the caller's role and is filled in by the
+// service.
+type Workspace struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	OwnerID  string `json:"owner_id"`
+	Personal bool   `json:"personal"`
+	Role     string `json:"role"`
+	Ctime    int64  `json:"ctime"`
+	Mtime    int64  `json:"mtime"`
+}
+
+type WorkspaceMember struct {
+	WorkspaceID string `json:"workspace_id"`
+	UserID      string `json:"user_id"`
+	Email       string `json:"email"`
+	Role        string `json:"role"`
+	Ctime       int64  `json:"ctime"`
+	Mtime       int64  `json:"mtime"`
+}
+
+// CanWriteWorkspace reports whether role may change the content of a workspace.
+func CanWriteWorkspace(role string) bool {
+	return role == WorkspaceRoleOwner || role == WorkspaceRoleEditor
+}
@@ -0,0 +1,109 @@
+package oauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+var errUnsupportedJWK = errors.New("unsupported jwk")
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+// signingKeys returns the public signing keys of the set by kid. Keys meant
+// for encryption and key types that cannot verify a signature are skipped.
+func (s jsonWebKeySet) signingKeys() map[string]any {
+	keys := make(map[string]any, len(s.Keys))
+	for _, item := range s.Keys {
+		if item.Use != "" && item.Use != "sig" {
+			continue
+		}
+		key, err := item.publicKey()
+		if err != nil {
+			continue
+		}
+		keys[item.Kid] = key
+	}
+	return keys
+}
+
+func (k jsonWebKey) publicKey() (any, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeJWKInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeJWKInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("%w: rsa exponent", errUnsupportedJWK)
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("%w: curve %s", errUnsupportedJWK, k.Crv)
+		}
+		x, err := decodeJWKInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeJWKInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		key := &ecdsa.PublicKey{Curve: curve, X: x, Y: y}
+		if _, err := key.ECDH(); err != nil {
+			return nil, fmt.Errorf("%w: %w", errUnsupportedJWK, err)
+		}
+		return key, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("%w: curve %s", errUnsupportedJWK, k.Crv)
+		}
+		raw, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(raw) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: ed25519 key", errUnsupportedJWK)
+		}
+		return ed25519.PublicKey(raw), nil
+	default:
+		return nil, fmt.Errorf("%w: kty %s", errUnsupportedJWK, k.Kty)
+	}
+}
+
+func decodeJWKInt(value string) (*big.Int, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(raw) == 0 {
+		return nil, fmt.Errorf("%w: invalid integer", errUnsupportedJWK)
+	}
+	return new(big.Int).SetBytes(raw), nil
+}
@@ -0,0 +1,474 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	jwtlib "github.com/golang-jwt/jwt/v5"
+	"golang.org/x/sync/singleflight"
+
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+const (
+	oidcDiscoveryTTL   = time.Hour
+	oidcJWKSMinRefresh = time.Minute
+	oidcClockSkew      = time.Minute
+	oidcMaxResponse    = 1 << 20
+)
+
+var (
+	ErrOIDCDiscovery  = errors.New("oidc discovery failed")
+	ErrInvalidIDToken = errors.New("invalid oidc id token")
+	errUnknownJWKID   = errors.New("unknown signing key")
+)
+
+// oidcSigningMethods lists the accepted ID token algorithms. HMAC is left
+// out on purpose: it would turn the client secret into a signing key.
+var oidcSigningMethods = []string{
+	"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA",
+}
+
+type oidcDiscovery struct {
+	Issuer                   string   `json:"issuer"`
+	AuthorizationEndpoint    string   `json:"authorization_endpoint"`
+	TokenEndpoint            string   `json:"token_endpoint"`
+	UserinfoEndpoint         string   `json:"userinfo_endpoint"`
+	JWKSURI                  string   `json:"jwks_uri"`
+	TokenEndpointAuthMethods []string `json:"token_endpoint_auth_methods_supported"`
+}
+
+// oidcProvider signs users in against any OpenID Connect issuer. The
+// discovery document and the signing keys are fetched lazily and cached;
+// an unknown key id triggers a key refresh at most once a minute.
+type oidcProvider struct {
+	name   string
+	cfg    ProviderArgs
+	client *http.Client
+	now    func() time.Time
+
+	// fetches shares a discovery or key fetch between the logins waiting
+	// for it; mu guards the cached results only.
+	fetches      singleflight.Group
+	mu           sync.Mutex
+	discovery    *oidcDiscovery
+	discoveredAt time.Time
+	keys         map[string]any
+	keysLoadedAt time.Time
+}
+
+func (p *oidcProvider) Name() string {
+	return p.name
+}
+
+func (p *oidcProvider) AuthURL(state string) (string, error) {
+	return p.AuthURLWithPKCE(context.Background(), state, "")
+}
+
+func (p *oidcProvider) ExchangeCode(ctx context.Context, code string) (*Profile, error) {
+	return p.ExchangeCodeWithPKCE(ctx, code, "")
+}
+
+func (p *oidcProvider) AuthURLWithPKCE(ctx context.Context, state, verifier string) (string, error) {
+	if p.cfg.Config.ClientID == "" || p.cfg.Config.RedirectURL == "" || p.cfg.OIDC.Issuer == "" {
+		return "", appErr.ErrInvalid
+	}
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	endpoint, err := url.Parse(doc.AuthorizationEndpoint)
+	if err != nil {
+		return "", fmt.Errorf("%w: authorization endpoint: %w", ErrOIDCDiscovery, err)
+	}
+	params := endpoint.Query()
+	params.Set("response_type", "code")
+	params.Set("client_id", p.cfg.Config.ClientID)
+	params.Set("redirect_uri", p.cfg.Config.RedirectURL)
+	params.Set("scope", strings.Join(p.cfg.Config.Scopes, " "))
+	params.Set("state", state)
+	if verifier != "" {
+		params.Set("code_challenge", PKCEChallenge(verifier))
+		params.Set("code_challenge_method", "S256")
+		params.Set("nonce", oidcNonce(verifier))
+	}
+	endpoint.RawQuery = params.Encode()
+	return endpoint.String(), nil
+}
+
+func (p *oidcProvider) ExchangeCodeWithPKCE(ctx context.Context, code, verifier string) (*Profile, error) {
+	if p.cfg.Config.ClientID == "" || p.cfg.Config.RedirectURL == "" || p.cfg.OIDC.Issuer == "" {
+		return nil, appErr.ErrInvalid
+	}
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tokens, err := p.token(ctx, doc, code, verifier)
+	if err != nil {
+		return nil, fmt.Errorf("exchange token: %w", err)
+	}
+	claims, err := p.verifyIDToken(ctx, doc, tokens.IDToken, verifier)
+	if err != nil {
+		return nil, err
+	}
+	if p.needsUserinfo(claims) && doc.UserinfoEndpoint != "" && tokens.AccessToken != "" {
+		if err := p.mergeUserinfo(ctx, doc, tokens.AccessToken, claims); err != nil {
+			return nil, fmt.Errorf("fetch userinfo: %w", err)
+		}
+	}
+	subject := claimString(claims, p.cfg.OIDC.SubjectClaim)
+	email := strings.TrimSpace(claimString(claims, p.cfg.OIDC.EmailClaim))
+	if subject == "" || email == "" {
+		return nil, appErr.ErrInvalid
+	}
+	if !p.cfg.OIDC.AllowUnverifiedEmail && !claimBool(claims, p.cfg.OIDC.EmailVerifiedClaim) {
+		return nil, appErr.ErrInvalid
+	}
+	return &Profile{Provider: p.name, ProviderUserID: subject, Email: email}, nil
+}
+
+// discover returns the cached discovery document, fetching it when it is
+// missing or stale. The fetch runs without p.mu held and is shared by the
+// logins waiting for it, so a slow issuer holds up only those.
+func (p *oidcProvider) discover(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	cached := p.discovery
+	fresh := cached != nil && p.now().Sub(p.discoveredAt) < oidcDiscoveryTTL
+	p.mu.Unlock()
+	if fresh {
+		return cached, nil
+	}
+	value, err, _ := p.fetches.Do("discovery", func() (any, error) {
+		return p.fetchDiscovery(ctx)
+	})
+	if err != nil {
+		if cached != nil {
+			// Keep signing in with the last good document while the issuer
+			// is unreachable.
+			return cached, nil
+		}
+		return nil, fmt.Errorf("%w: %w", ErrOIDCDiscovery, err)
+	}
+	doc, _ := value.(*oidcDiscovery)
+	return doc, nil
+}
+
+func (p *oidcProvider) fetchDiscovery(ctx context.Context) (*oidcDiscovery, error) {
+	issuer := strings.TrimRight(p.cfg.OIDC.Issuer, "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	var doc oidcDiscovery
+	if err := p.doJSON(req, "discovery", &doc); err != nil {
+		return nil, err
+	}
+	if err := validateDiscovery(issuer, &doc); err != nil {
+		return nil, err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.discovery = &doc
+	p.discoveredAt = p.now()
+	return &doc, nil
+}
+
+func validateDiscovery(issuer string, doc *oidcDiscovery) error {
+	if strings.TrimRight(doc.Issuer, "/") != issuer {
+		return fmt.Errorf("issuer %q does not match %q", doc.Issuer, issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return errors.New("discovery document lacks required endpoints")
+	}
+	return nil
+}
+
+type oidcTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	IDToken     string `json:"id_token"`
+}
+
+func (p *oidcProvider) token(
+	ctx context.Context, doc *oidcDiscovery, code, verifier string,
+) (*oidcTokenResponse, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.cfg.Config.RedirectURL)
+	form.Set("client_id", p.cfg.Config.ClientID)
+	if verifier != "" {
+		form.Set("code_verifier", verifier)
+	}
+	useBasic := p.cfg.Config.ClientSecret != "" && supportsBasicAuth(doc.TokenEndpointAuthMethods)
+	if p.cfg.Config.ClientSecret != "" && !useBasic {
+		form.Set("client_secret", p.cfg.Config.ClientSecret)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, doc.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if useBasic {
+		req.SetBasicAuth(url.QueryEscape(p.cfg.Config.ClientID), url.QueryEscape(p.cfg.Config.ClientSecret))
+	}
+	var out oidcTokenResponse
+	if err := p.doJSON(req, "token exchange", &out); err != nil {
+		return nil, err
+	}
+	if out.IDToken == "" {
+		return nil, appErr.ErrInvalid
+	}
+	return &out, nil
+}
+
+// supportsBasicAuth follows the OpenID Connect default: client_secret_basic
+// unless the issuer only advertises client_secret_post.
+func supportsBasicAuth(methods []string) bool {
+	if len(methods) == 0 {
+		return true
+	}
+	for _, method := range methods {
+		if method == "client_secret_basic" {
+			return true
+		}
+	}
+	for _, method := range methods {
+		if method == "client_secret_post" {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *oidcProvider) verifyIDToken(
+	ctx context.Context, doc *oidcDiscovery, raw, verifier string,
+) (jwtlib.MapClaims, error) {
+	parser := jwtlib.NewParser(
+		jwtlib.WithValidMethods(oidcSigningMethods),
+		jwtlib.WithIssuer(doc.Issuer),
+		jwtlib.WithAudience(p.cfg.Config.ClientID),
+		jwtlib.WithExpirationRequired(),
+		jwtlib.WithIssuedAt(),
+		jwtlib.WithLeeway(oidcClockSkew),
+		jwtlib.WithTimeFunc(p.now),
+	)
+	claims := jwtlib.MapClaims{}
+	_, err := parser.ParseWithClaims(raw, claims, func(token *jwtlib.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.signingKey(ctx, doc, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+	audience, _ := claims.GetAudience()
+	if len(audience) > 1 {
+		if azp, _ := claims["azp"].(string); azp != p.cfg.Config.ClientID {
+			return nil, fmt.Errorf("%w: authorized party mismatch", ErrInvalidIDToken)
+		}
+	}
+	if verifier != "" {
+		if nonce, _ := claims["nonce"].(string); nonce != oidcNonce(verifier) {
+			return nil, fmt.Errorf("%w: nonce mismatch", ErrInvalidIDToken)
+		}
+	}
+	return claims, nil
+}
+
+// signingKey returns the cached key kid, refreshing the key set when kid is
+// unknown. Like discover, the refresh runs without p.mu held and is shared.
+func (p *oidcProvider) signingKey(ctx context.Context, doc *oidcDiscovery, kid string) (any, error) {
+	p.mu.Lock()
+	key := lookupSigningKey(p.keys, kid)
+	throttled := p.keys != nil && p.now().Sub(p.keysLoadedAt) < oidcJWKSMinRefresh
+	p.mu.Unlock()
+	if key != nil {
+		return key, nil
+	}
+	if throttled {
+		return nil, fmt.Errorf("%w: %q", errUnknownJWKID, kid)
+	}
+	value, err, _ := p.fetches.Do("jwks", func() (any, error) {
+		return p.fetchKeys(ctx, doc)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("refresh signing keys: %w", err)
+	}
+	keys, _ := value.(map[string]any)
+	if key := lookupSigningKey(keys, kid); key != nil {
+		return key, nil
+	}
+	return nil, fmt.Errorf("%w: %q", errUnknownJWKID, kid)
+}
+
+func (p *oidcProvider) fetchKeys(ctx context.Context, doc *oidcDiscovery) (map[string]any, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, doc.JWKSURI, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	var set jsonWebKeySet
+	if err := p.doJSON(req, "jwks", &set); err != nil {
+		return nil, err
+	}
+	keys := set.signingKeys()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.keys = keys
+	p.keysLoadedAt = p.now()
+	return keys, nil
+}
+
+// lookupSigningKey accepts a token without kid only when the set holds a
+// single key.
+func lookupSigningKey(keys map[string]any, kid string) any {
+	if key, ok := keys[kid]; ok {
+		return key
+	}
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key
+		}
+	}
+	return nil
+}
+
+func (p *oidcProvider) needsUserinfo(claims jwtlib.MapClaims) bool {
+	if claimString(claims, p.cfg.OIDC.EmailClaim) == "" {
+		return true
+	}
+	return !p.cfg.OIDC.AllowUnverifiedEmail && claimValue(claims, p.cfg.OIDC.EmailVerifiedClaim) == nil
+}
+
+// mergeUserinfo adds the userinfo claims missing from the ID token. The
+// response must describe the same subject as the ID token.
+func (p *oidcProvider) mergeUserinfo(
+	ctx context.Context, doc *oidcDiscovery, accessToken string, claims jwtlib.MapClaims,
+) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, doc.UserinfoEndpoint, nil)
+	if err != nil {
+		return fmt.Errorf("create request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	info := map[string]any{}
+	if err := p.doJSON(req, "userinfo", &info); err != nil {
+		return err
+	}
+	if subject, _ := info["sub"].(string); subject == "" || subject != claimString(claims, "sub") {
+		return appErr.ErrInvalid
+	}
+	for key, value := range info {
+		if _, ok := claims[key]; !ok {
+			claims[key] = value
+		}
+	}
+	return nil
+}
+
+func (p *oidcProvider) doJSON(req *http.Request, what string, out any) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %w", ErrRequestFailed, what, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%w: %s: %s: %s", ErrRequestFailed, what, resp.Status, strings.TrimSpace(string(body)))
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, oidcMaxResponse)).Decode(out); err != nil {
+		return fmt.Errorf("decode %s: %w", what, err)
+	}
+	return nil
+}
+
+// oidcNonce binds the ID token to the login attempt. It is derived from the
+// PKCE verifier so that nothing extra has to be kept between redirect and
+// callback, and it is hashed because the nonce travels in the browser.
+func oidcNonce(verifier string) string {
+	sum := sha256.Sum256([]byte("oidc-nonce:" + verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// claimValue resolves a dotted claim path such as "attributes.mail".
+func claimValue(claims map[string]any, path string) any {
+	if value, ok := claims[path]; ok {
+		return value
+	}
+	var current any = claims
+	for _, part := range strings.Split(path, ".") {
+		object, ok := current.(map[string]any)
+		if !ok {
+			return nil
+		}
+		current, ok = object[part]
+		if !ok {
+			return nil
+		}
+	}
+	return current
+}
+
+func claimString(claims map[string]any, path string) string {
+	switch value := claimValue(claims, path).(type) {
+	case string:
+		return value
+	case []any:
+		if len(value) > 0 {
+			if first, ok := value[0].(string); ok {
+				return first
+			}
+		}
+	}
+	return ""
+}
+
+// claimBool accepts booleans and the string "true", which some issuers emit
+// for email_verified.
+func claimBool(claims map[string]any, path string) bool {
+	switch value := claimValue(claims, path).(type) {
+	case bool:
+		return value
+	case string:
+		return strings.EqualFold(value, "true")
+	}
+	return false
+}
+
+func newOIDCProvider(args any) (Provider, error) {
+	cfg := decodeProviderArgs(args)
+	name := cfg.Name
+	if name == "" {
+		name = "oidc"
+	}
+	if len(cfg.Config.Scopes) == 0 {
+		cfg.Config.Scopes = []string{"openid", "email", "profile"}
+	}
+	if cfg.OIDC.SubjectClaim == "" {
+		cfg.OIDC.SubjectClaim = "sub"
+	}
+	if cfg.OIDC.EmailClaim == "" {
+		cfg.OIDC.EmailClaim = "email"
+	}
+	if cfg.OIDC.EmailVerifiedClaim == "" {
+		cfg.OIDC.EmailVerifiedClaim = "email_verified"
+	}
+	client := cfg.Client
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &oidcProvider{name: name, cfg: cfg, client: client, now: time.Now}, nil
+}
+
+func init() {
+	Register("oidc", newOIDCProvider)
+}
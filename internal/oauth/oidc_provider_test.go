@@ -0,0 +1,345 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	jwtlib "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+// stubIdP is a minimal OpenID Connect issuer. Tests adjust the claims of the
+// next ID token and inspect the last token request.
+type stubIdP struct {
+	t      *testing.T
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	kid    string
+
+	mu           sync.Mutex
+	claims       jwtlib.MapClaims
+	userinfo     map[string]any
+	signWith     *rsa.PrivateKey
+	tokenForm    url.Values
+	basicUser    string
+	basicPass    string
+	jwksRequests int
+	// jwksGate, when set, holds every key request until it is closed.
+	jwksGate chan struct{}
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	idp := &stubIdP{t: t, key: key, kid: "k1"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, _ *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]any{
+			"issuer":                 idp.server.URL,
+			"authorization_endpoint": idp.server.URL + "/authorize?tenant=main",
+			"token_endpoint":         idp.server.URL + "/token",
+			"userinfo_endpoint":      idp.server.URL + "/userinfo",
+			"jwks_uri":               idp.server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, _ *http.Request) {
+		idp.mu.Lock()
+		defer idp.mu.Unlock()
+		idp.jwksRequests++
+		if gate := idp.jwksGate; gate != nil {
+			idp.mu.Unlock()
+			<-gate
+			idp.mu.Lock()
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]any{{
+			"kty": "RSA", "kid": idp.kid, "use": "sig", "alg": "RS256",
+			"n": base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+			"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		idp.mu.Lock()
+		defer idp.mu.Unlock()
+		idp.tokenForm = r.PostForm
+		idp.basicUser, idp.basicPass, _ = r.BasicAuth()
+		signer := idp.signWith
+		if signer == nil {
+			signer = idp.key
+		}
+		token := jwtlib.NewWithClaims(jwtlib.SigningMethodRS256, idp.claims)
+		token.Header["kid"] = idp.kid
+		raw, err := token.SignedString(signer)
+		require.NoError(t, err)
+		_ = json.NewEncoder(w).Encode(map[string]any{"access_token": "at-1", "id_token": raw})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer at-1", r.Header.Get("Authorization"))
+		idp.mu.Lock()
+		defer idp.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(idp.userinfo)
+	})
+	idp.server = httptest.NewServer(mux)
+	t.Cleanup(idp.server.Close)
+	return idp
+}
+
+// setClaims prepares a valid ID token for client "mnote" plus overrides.
+func (idp *stubIdP) setClaims(verifier string, overrides jwtlib.MapClaims) {
+	now := time.Now()
+	claims := jwtlib.MapClaims{
+		"iss":            idp.server.URL,
+		"aud":            "mnote",
+		"sub":            "user-1",
+		"email":          "user@example.com",
+		"email_verified": true,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          oidcNonce(verifier),
+	}
+	for key, value := range overrides {
+		if value == nil {
+			delete(claims, key)
+			continue
+		}
+		claims[key] = value
+	}
+	idp.mu.Lock()
+	defer idp.mu.Unlock()
+	idp.claims = claims
+}
+
+func newTestOIDCProvider(t *testing.T, idp *stubIdP, oidc OIDCConfig) *oidcProvider {
+	t.Helper()
+	oidc.Issuer = idp.server.URL
+	p, err := NewProvider("oidc", ProviderArgs{
+		Name: "Corp",
+		Config: ProviderConfig{
+			ClientID: "mnote", ClientSecret: "s3cret", RedirectURL: "https://notes.example/cb",
+		},
+		OIDC: oidc,
+	})
+	require.NoError(t, err)
+	return p.(*oidcProvider)
+}
+
+func TestOIDC_AuthURLUsesDiscoveryAndPKCE(t *testing.T) {
+	idp := newStubIdP(t)
+	p := newTestOIDCProvider(t, idp, OIDCConfig{})
+	assert.Equal(t, "corp", p.Name())
+
+	raw, err := p.AuthURLWithPKCE(context.Background(), "state-1", "verifier-1")
+	require.NoError(t, err)
+	u, err := url.Parse(raw)
+	require.NoError(t, err)
+	assert.Equal(t, "/authorize", u.Path)
+	query := u.Query()
+	assert.Equal(t, "main", query.Get("tenant"))
+	assert.Equal(t, "code", query.Get("response_type"))
+	assert.Equal(t, "mnote", query.Get("client_id"))
+	assert.Equal(t, "openid email profile", query.Get("scope"))
+	assert.Equal(t, "state-1", query.Get("state"))
+	assert.Equal(t, PKCEChallenge("verifier-1"), query.Get("code_challenge"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	assert.Equal(t, oidcNonce("verifier-1"), query.Get("nonce"))
+}
+
+func TestOIDC_ExchangeCodeVerifiesIDToken(t *testing.T) {
+	idp := newStubIdP(t)
+	p := newTestOIDCProvider(t, idp, OIDCConfig{})
+	idp.setClaims("verifier-1", nil)
+
+	profile, err := p.ExchangeCodeWithPKCE(context.Background(), "code-1", "verifier-1")
+	require.NoError(t, err)
+	assert.Equal(t, &Profile{Provider: "corp", ProviderUserID: "user-1", Email: "user@example.com"}, profile)
+	assert.Equal(t, "verifier-1", idp.tokenForm.Get("code_verifier"))
+	assert.Equal(t, "code-1", idp.tokenForm.Get("code"))
+	assert.Equal(t, "mnote", idp.basicUser)
+	assert.Equal(t, "s3cret", idp.basicPass)
+	assert.Empty(t, idp.tokenForm.Get("client_secret"))
+
+	// The keys are cached between logins.
+	_, err = p.ExchangeCodeWithPKCE(context.Background(), "code-2", "verifier-1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, idp.jwksRequests)
+}
+
+func TestOIDC_ClaimMappingAndUserinfo(t *testing.T) {
+	idp := newStubIdP(t)
+	p := newTestOIDCProvider(t, idp, OIDCConfig{
+		SubjectClaim: "preferred_username", EmailClaim: "attributes.mail", EmailVerifiedClaim: "attributes.verified",
+	})
+	idp.setClaims("v", jwtlib.MapClaims{"preferred_username": "alice", "email": nil})
+	idp.userinfo = map[string]any{
+		"sub":        "user-1",
+		"attributes": map[string]any{"mail": "alice@example.com", "verified": "true"},
+	}
+
+	profile, err := p.ExchangeCodeWithPKCE(context.Background(), "code", "v")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", profile.ProviderUserID)
+	assert.Equal(t, "alice@example.com", profile.Email)
+
+	idp.userinfo = map[string]any{"sub": "someone-else", "attributes": map[string]any{"mail": "x@example.com"}}
+	_, err = p.ExchangeCodeWithPKCE(context.Background(), "code", "v")
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+}
+
+func TestOIDC_RejectsInvalidTokens(t *testing.T) {
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	tests := []struct {
+		name      string
+		overrides jwtlib.MapClaims
+		verifier  string
+		signWith  *rsa.PrivateKey
+		want      error
+	}{
+		{name: "audience", overrides: jwtlib.MapClaims{"aud": "other"}, want: ErrInvalidIDToken},
+		{name: "issuer", overrides: jwtlib.MapClaims{"iss": "https://evil.example"}, want: ErrInvalidIDToken},
+		{
+			name:      "expired",
+			overrides: jwtlib.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()},
+			want:      ErrInvalidIDToken,
+		},
+		{name: "nonce", verifier: "other-verifier", want: ErrInvalidIDToken},
+		{name: "signature", signWith: otherKey, want: ErrInvalidIDToken},
+		{
+			name:      "authorized party",
+			overrides: jwtlib.MapClaims{"aud": []string{"mnote", "other"}, "azp": "other"},
+			want:      ErrInvalidIDToken,
+		},
+		{name: "unverified email", overrides: jwtlib.MapClaims{"email_verified": false}, want: appErr.ErrInvalid},
+	}
+	for _, testCase := range tests {
+		t.Run(testCase.name, func(t *testing.T) {
+			idp := newStubIdP(t)
+			p := newTestOIDCProvider(t, idp, OIDCConfig{})
+			idp.setClaims("verifier-1", testCase.overrides)
+			idp.signWith = testCase.signWith
+			verifier := testCase.verifier
+			if verifier == "" {
+				verifier = "verifier-1"
+			}
+			_, err := p.ExchangeCodeWithPKCE(context.Background(), "code", verifier)
+			assert.ErrorIs(t, err, testCase.want)
+		})
+	}
+}
+
+func TestOIDC_AllowUnverifiedEmail(t *testing.T) {
+	idp := newStubIdP(t)
+	p := newTestOIDCProvider(t, idp, OIDCConfig{AllowUnverifiedEmail: true})
+	idp.setClaims("v", jwtlib.MapClaims{"email_verified": nil})
+
+	profile, err := p.ExchangeCodeWithPKCE(context.Background(), "code", "v")
+	require.NoError(t, err)
+	assert.Equal(t, "user@example.com", profile.Email)
+}
+
+func TestOIDC_RefreshesKeysOnRotation(t *testing.T) {
+	idp := newStubIdP(t)
+	p := newTestOIDCProvider(t, idp, OIDCConfig{})
+	idp.setClaims("v", nil)
+	_, err := p.ExchangeCodeWithPKCE(context.Background(), "code", "v")
+	require.NoError(t, err)
+
+	rotated, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	idp.mu.Lock()
+	idp.key, idp.kid = rotated, "k2"
+	idp.mu.Unlock()
+
+	// A new kid within a minute of the last fetch is rejected without
+	// hitting the issuer again.
+	_, err = p.ExchangeCodeWithPKCE(context.Background(), "code", "v")
+	assert.ErrorIs(t, err, ErrInvalidIDToken)
+	assert.Equal(t, 1, idp.jwksRequests)
+
+	p.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	_, err = p.ExchangeCodeWithPKCE(context.Background(), "code", "v")
+	require.NoError(t, err)
+	assert.Equal(t, 2, idp.jwksRequests)
+}
+
+func TestOIDC_SlowKeyFetchDoesNotBlockLogins(t *testing.T) {
+	idp := newStubIdP(t)
+	p := newTestOIDCProvider(t, idp, OIDCConfig{})
+	ctx := context.Background()
+	doc, err := p.discover(ctx)
+	require.NoError(t, err)
+	gate := make(chan struct{})
+	idp.mu.Lock()
+	idp.jwksGate = gate
+	idp.mu.Unlock()
+
+	var wg sync.WaitGroup
+	for range 2 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			key, err := p.signingKey(ctx, doc, "k1")
+			assert.NoError(t, err)
+			assert.NotNil(t, key)
+		}()
+	}
+	require.Eventually(t, func() bool {
+		idp.mu.Lock()
+		defer idp.mu.Unlock()
+		return idp.jwksRequests == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	discovered := make(chan struct{})
+	go func() {
+		defer close(discovered)
+		_, err := p.AuthURLWithPKCE(ctx, "state", "v")
+		assert.NoError(t, err)
+	}()
+	select {
+	case <-discovered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("login waited for the key fetch")
+	}
+	close(gate)
+	wg.Wait()
+	assert.Equal(t, 1, idp.jwksRequests)
+}
+
+func TestOIDC_DiscoveryIssuerMismatch(t *testing.T) {
+	idp := newStubIdP(t)
+	p := newTestOIDCProvider(t, idp, OIDCConfig{})
+	p.cfg.OIDC.Issuer = idp.server.URL + "/realms/other"
+	_, err := p.AuthURL("state")
+	assert.ErrorIs(t, err, ErrOIDCDiscovery)
+}
+
+func TestOIDC_MissingConfig(t *testing.T) {
+	p, err := NewProvider("oidc", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "oidc", p.Name())
+	_, err = p.AuthURL("state")
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+	_, err = p.ExchangeCode(context.Background(), "code")
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+}
+
+func TestSupportsBasicAuth(t *testing.T) {
+	assert.True(t, supportsBasicAuth(nil))
+	assert.True(t, supportsBasicAuth([]string{"client_secret_post", "client_secret_basic"}))
+	assert.False(t, supportsBasicAuth([]string{"client_secret_post"}))
+	assert.True(t, supportsBasicAuth([]string{"private_key_jwt"}))
+}
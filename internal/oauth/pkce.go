@@ -0,0 +1,21 @@
+package oauth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+)
+
+// PKCEProvider is implemented by providers that bind the authorization code
+// to a code verifier (RFC 7636). The verifier is kept by the caller between
+// the redirect and the callback; an empty verifier disables PKCE.
+type PKCEProvider interface {
+	AuthURLWithPKCE(ctx context.Context, state, verifier string) (string, error)
+	ExchangeCodeWithPKCE(ctx context.Context, code, verifier string) (*Profile, error)
+}
+
+// PKCEChallenge returns the S256 code challenge of verifier.
+func PKCEChallenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
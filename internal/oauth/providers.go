@@ -12,8 +12,22 @@ type ProviderConfig struct {
 	Scopes       []string
 }
 
+// OIDCConfig holds the settings only the generic OpenID Connect provider
+// uses. Claim names may be dotted paths into nested claims.
+type OIDCConfig struct {
+	Issuer               string
+	SubjectClaim         string
+	EmailClaim           string
+	EmailVerifiedClaim   string
+	AllowUnverifiedEmail bool
+}
+
 type ProviderArgs struct {
+	// Name is the instance name of providers that can be configured more
+	// than once; it is the provider key in URLs and account bindings.
+	Name   string
 	Config ProviderConfig
+	OIDC   OIDCConfig
 	Client *http.Client
 }
 
@@ -25,8 +39,10 @@ func decodeProviderArgs(args any) ProviderArgs {
 	if !ok {
 		return ProviderArgs{}
 	}
+	cfg.Name = strings.ToLower(strings.TrimSpace(cfg.Name))
 	cfg.Config.RedirectURL = strings.TrimSpace(cfg.Config.RedirectURL)
 	cfg.Config.ClientID = strings.TrimSpace(cfg.Config.ClientID)
 	cfg.Config.ClientSecret = strings.TrimSpace(cfg.Config.ClientSecret)
+	cfg.OIDC.Issuer = strings.TrimSpace(cfg.OIDC.Issuer)
 	return cfg
 }
@@ -0,0 +1,269 @@
+// Package ot implements operational transformation for plain text in the
+// operation format of ot.js, so browser editors can use that library as the
+// client side of the collaboration channel. An operation walks the whole
+// document: a positive number retains that many characters, a negative
+// number deletes that many, and a string inserts itself. Lengths count
+// Unicode code points, not bytes or UTF-16 units.
+package ot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+var (
+	ErrInvalidOperation = errors.New("invalid text operation")
+	ErrLengthMismatch   = errors.New("text operation does not match document length")
+)
+
+type component struct {
+	// n retains n characters when positive and deletes -n when negative.
+	n int
+	// s is inserted when not empty; n is then zero.
+	s string
+}
+
+func (c component) isRetain() bool { return c.n > 0 }
+func (c component) isDelete() bool { return c.n < 0 }
+func (c component) isInsert() bool { return c.s != "" }
+
+// Operation is a sequence of retain, insert and delete components. The
+// zero value is the empty operation on an empty document.
+type Operation struct {
+	ops       []component
+	baseLen   int
+	targetLen int
+}
+
+// BaseLen is the length of the documents the operation applies to.
+func (o Operation) BaseLen() int { return o.baseLen }
+
+// TargetLen is the length of the document after applying the operation.
+func (o Operation) TargetLen() int { return o.targetLen }
+
+// IsNoop reports whether applying the operation leaves any document
+// unchanged.
+func (o Operation) IsNoop() bool {
+	return len(o.ops) == 0 || (len(o.ops) == 1 && o.ops[0].isRetain())
+}
+
+// Retain skips over n characters.
+func (o *Operation) Retain(n int) *Operation {
+	if n <= 0 {
+		return o
+	}
+	o.baseLen += n
+	o.targetLen += n
+	if last := len(o.ops) - 1; last >= 0 && o.ops[last].isRetain() {
+		o.ops[last].n += n
+		return o
+	}
+	o.ops = append(o.ops, component{n: n})
+	return o
+}
+
+// Insert inserts s at the current position. Inserts are kept ahead of an
+// adjacent delete so equal edits always have the same components.
+func (o *Operation) Insert(s string) *Operation {
+	if s == "" {
+		return o
+	}
+	o.targetLen += utf8.RuneCountInString(s)
+	last := len(o.ops) - 1
+	switch {
+	case last >= 0 && o.ops[last].isInsert():
+		o.ops[last].s += s
+	case last >= 0 && o.ops[last].isDelete():
+		if last > 0 && o.ops[last-1].isInsert() {
+			o.ops[last-1].s += s
+		} else {
+			o.ops = append(o.ops, o.ops[last])
+			o.ops[last] = component{s: s}
+		}
+	default:
+		o.ops = append(o.ops, component{s: s})
+	}
+	return o
+}
+
+// Delete removes n characters.
+func (o *Operation) Delete(n int) *Operation {
+	if n <= 0 {
+		return o
+	}
+	o.baseLen += n
+	if last := len(o.ops) - 1; last >= 0 && o.ops[last].isDelete() {
+		o.ops[last].n -= n
+		return o
+	}
+	o.ops = append(o.ops, component{n: -n})
+	return o
+}
+
+// Apply returns text with the operation applied.
+func (o Operation) Apply(text string) (string, error) {
+	runes := []rune(text)
+	if len(runes) != o.baseLen {
+		return "", ErrLengthMismatch
+	}
+	out := make([]rune, 0, o.targetLen)
+	pos := 0
+	for _, c := range o.ops {
+		switch {
+		case c.isRetain():
+			out = append(out, runes[pos:pos+c.n]...)
+			pos += c.n
+		case c.isInsert():
+			out = append(out, []rune(c.s)...)
+		default:
+			pos -= c.n
+		}
+	}
+	return string(out), nil
+}
+
+// TransformIndex maps a position in the document before the operation to
+// the matching position after it. A position at an insert moves past it.
+func (o Operation) TransformIndex(index int) int {
+	pos, result := 0, index
+	for _, c := range o.ops {
+		if pos > index {
+			break
+		}
+		switch {
+		case c.isRetain():
+			pos += c.n
+		case c.isInsert():
+			result += utf8.RuneCountInString(c.s)
+		default:
+			result -= min(-c.n, index-pos)
+			pos -= c.n
+		}
+	}
+	return result
+}
+
+// Transform takes two operations a and b made concurrently on the same
+// document and returns a' and b' such that applying a then b' gives the same
+// document as applying b then a'. When both insert at the same position the
+// text of a ends up first.
+func Transform(a, b Operation) (Operation, Operation, error) {
+	if a.baseLen != b.baseLen {
+		return Operation{}, Operation{}, ErrLengthMismatch
+	}
+	var aPrime, bPrime Operation
+	ops1, ops2 := a.ops, b.ops
+	var op1, op2 *component
+	next := func(ops *[]component) *component {
+		if len(*ops) == 0 {
+			return nil
+		}
+		c := (*ops)[0]
+		*ops = (*ops)[1:]
+		return &c
+	}
+	op1, op2 = next(&ops1), next(&ops2)
+	for op1 != nil || op2 != nil {
+		if op1 != nil && op1.isInsert() {
+			aPrime.Insert(op1.s)
+			bPrime.Retain(utf8.RuneCountInString(op1.s))
+			op1 = next(&ops1)
+			continue
+		}
+		if op2 != nil && op2.isInsert() {
+			aPrime.Retain(utf8.RuneCountInString(op2.s))
+			bPrime.Insert(op2.s)
+			op2 = next(&ops2)
+			continue
+		}
+		if op1 == nil || op2 == nil {
+			return Operation{}, Operation{}, ErrInvalidOperation
+		}
+		n1, n2 := abs(op1.n), abs(op2.n)
+		step := min(n1, n2)
+		switch {
+		case op1.isRetain() && op2.isRetain():
+			aPrime.Retain(step)
+			bPrime.Retain(step)
+		case op1.isDelete() && op2.isRetain():
+			aPrime.Delete(step)
+		case op1.isRetain() && op2.isDelete():
+			bPrime.Delete(step)
+		}
+		// When both delete the same range neither transformed side does.
+		op1 = consume(op1, step, func() *component { return next(&ops1) })
+		op2 = consume(op2, step, func() *component { return next(&ops2) })
+	}
+	return aPrime, bPrime, nil
+}
+
+// consume shortens a retain or delete component by step characters and
+// moves on to the next component once it is used up.
+func consume(c *component, step int, next func() *component) *component {
+	if abs(c.n) == step {
+		return next()
+	}
+	if c.n > 0 {
+		c.n -= step
+	} else {
+		c.n += step
+	}
+	return c
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// MarshalJSON encodes the operation as an ot.js component array.
+func (o Operation) MarshalJSON() ([]byte, error) {
+	items := make([]any, 0, len(o.ops))
+	for _, c := range o.ops {
+		if c.isInsert() {
+			items = append(items, c.s)
+			continue
+		}
+		items = append(items, c.n)
+	}
+	data, err := json.Marshal(items)
+	if err != nil {
+		return nil, fmt.Errorf("marshal operation: %w", err)
+	}
+	return data, nil
+}
+
+// UnmarshalJSON decodes an ot.js component array. Zero retains, empty
+// inserts and values of other types are rejected.
+func (o *Operation) UnmarshalJSON(data []byte) error {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return ErrInvalidOperation
+	}
+	var op Operation
+	for _, item := range items {
+		var s string
+		if err := json.Unmarshal(item, &s); err == nil {
+			if s == "" {
+				return ErrInvalidOperation
+			}
+			op.Insert(s)
+			continue
+		}
+		var n int
+		if err := json.Unmarshal(item, &n); err != nil || n == 0 {
+			return ErrInvalidOperation
+		}
+		if n > 0 {
+			op.Retain(n)
+		} else {
+			op.Delete(-n)
+		}
+	}
+	*o = op
+	return nil
+}
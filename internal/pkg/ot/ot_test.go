@@ -0,0 +1,130 @@
+package ot
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOperation_Apply(t *testing.T) {
+	var op Operation
+	op.Retain(2).Insert("笔记").Delete(1).Retain(2)
+	assert.Equal(t, 5, op.BaseLen())
+	assert.Equal(t, 6, op.TargetLen())
+
+	got, err := op.Apply("ab-cd")
+	require.NoError(t, err)
+	assert.Equal(t, "ab笔记cd", got)
+
+	_, err = op.Apply("abcd")
+	assert.ErrorIs(t, err, ErrLengthMismatch)
+}
+
+func TestOperation_InsertStaysAheadOfDelete(t *testing.T) {
+	var a, b Operation
+	a.Retain(1).Delete(2).Insert("x")
+	b.Retain(1).Insert("x").Delete(2)
+	assert.Equal(t, a, b)
+}
+
+func TestOperation_JSON(t *testing.T) {
+	var op Operation
+	require.NoError(t, json.Unmarshal([]byte(`[3,"hi",-2,1]`), &op))
+	data, err := json.Marshal(op)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[3,"hi",-2,1]`, string(data))
+
+	for _, raw := range []string{`[0]`, `[""]`, `[true]`, `{"retain":1}`} {
+		assert.ErrorIs(t, json.Unmarshal([]byte(raw), &op), ErrInvalidOperation, raw)
+	}
+}
+
+func TestOperation_TransformIndex(t *testing.T) {
+	var op Operation
+	op.Retain(2).Insert("xy").Delete(3).Retain(4)
+	assert.Equal(t, 1, op.TransformIndex(1))
+	assert.Equal(t, 4, op.TransformIndex(2))
+	assert.Equal(t, 4, op.TransformIndex(4))
+	assert.Equal(t, 4, op.TransformIndex(5))
+	assert.Equal(t, 6, op.TransformIndex(7))
+}
+
+func TestTransform_SamePositionInsertsKeepFirstOperationAhead(t *testing.T) {
+	var a, b Operation
+	a.Retain(1).Insert("A").Retain(1)
+	b.Retain(1).Insert("B").Retain(1)
+	aPrime, bPrime, err := Transform(a, b)
+	require.NoError(t, err)
+
+	left := mustApply(t, mustApply(t, "xy", a), bPrime)
+	right := mustApply(t, mustApply(t, "xy", b), aPrime)
+	assert.Equal(t, "xABy", left)
+	assert.Equal(t, left, right)
+}
+
+func TestTransform_LengthMismatch(t *testing.T) {
+	var a, b Operation
+	a.Retain(2)
+	b.Retain(3)
+	_, _, err := Transform(a, b)
+	assert.ErrorIs(t, err, ErrLengthMismatch)
+}
+
+// TestTransform_Converges checks the transformation property on random
+// concurrent edits: both orders of application reach the same document.
+func TestTransform_Converges(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	for range 500 {
+		doc := randomText(rng, rng.Intn(20))
+		a := randomOperation(rng, doc)
+		b := randomOperation(rng, doc)
+		aPrime, bPrime, err := Transform(a, b)
+		require.NoError(t, err)
+		left := mustApply(t, mustApply(t, doc, a), bPrime)
+		right := mustApply(t, mustApply(t, doc, b), aPrime)
+		require.Equal(t, left, right, "doc=%q", doc)
+	}
+}
+
+func mustApply(t *testing.T, text string, op Operation) string {
+	t.Helper()
+	out, err := op.Apply(text)
+	require.NoError(t, err)
+	return out
+}
+
+func randomText(rng *rand.Rand, n int) string {
+	const alphabet = "abc文档 \n"
+	letters := []rune(alphabet)
+	out := make([]rune, n)
+	for i := range out {
+		out[i] = letters[rng.Intn(len(letters))]
+	}
+	return string(out)
+}
+
+func randomOperation(rng *rand.Rand, doc string) Operation {
+	var op Operation
+	left := utf8.RuneCountInString(doc)
+	for left > 0 {
+		n := 1 + rng.Intn(left)
+		switch rng.Intn(3) {
+		case 0:
+			op.Retain(n)
+			left -= n
+		case 1:
+			op.Delete(n)
+			left -= n
+		default:
+			op.Insert(randomText(rng, 1+rng.Intn(3)))
+		}
+	}
+	if rng.Intn(2) == 0 {
+		op.Insert(randomText(rng, 1+rng.Intn(3)))
+	}
+	return op
+}
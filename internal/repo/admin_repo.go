@@ -0,0 +1,244 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/xxxsen/mnote/internal/model"
+)
+
+// userOwnedTables lists the tables keyed by user_id, children before their
+// parents so the deletes never trip a foreign key.
+var userOwnedTables = []string{
+	"document_tags",
+	"document_assets",
+	"document_links",
+	"document_wikilinks",
+	"document_broken_links",
+	"document_properties",
+	"document_versions",
+	"shares",
+	"document_collaborators",
+	"document_embeddings",
+	"chunk_embeddings",
+	"embedding_jobs",
+	"document_embedding_indexes",
+	"chunk_embeddings_v2",
+	"todos",
+	"template_schedules",
+	"templates",
+	"documents",
+	"tags",
+	"folders",
+	"assets",
+	"import_job_notes",
+	"import_jobs",
+	"oauth_accounts",
+	"oauth_one_time_tokens",
+	"workspace_members",
+}
+
+// AdminRepo holds the cross-table queries of the offline admin commands.
+type AdminRepo struct {
+	db *sql.DB
+}
+
+func NewAdminRepo(db *sql.DB) *AdminRepo {
+	return &AdminRepo{db: db}
+}
+
+// UserStats returns the usage of every user, including users without data.
+func (r *AdminRepo) UserStats(ctx context.Context) ([]model.UserStats, error) {
+	const query = `
+		SELECT u.id, u.email,
+			COALESCE(d.cnt, 0), COALESCE(v.cnt, 0), COALESCE(a.cnt, 0),
+			COALESCE(d.bytes, 0) + COALESCE(v.bytes, 0), COALESCE(a.bytes, 0)
+		FROM users u
+		LEFT JOIN (
+			SELECT user_id, COUNT(*) AS cnt, SUM(OCTET_LENGTH(content)) AS bytes
+			FROM documents GROUP BY user_id
+		) d ON d.user_id = u.id
+		LEFT JOIN (
+			SELECT user_id, COUNT(*) AS cnt, SUM(OCTET_LENGTH(content)) AS bytes
+			FROM document_versions GROUP BY user_id
+		) v ON v.user_id = u.id
+		LEFT JOIN (
+			SELECT user_id, COUNT(*) AS cnt, SUM(size) AS bytes
+			FROM assets GROUP BY user_id
+		) a ON a.user_id = u.id
+		ORDER BY u.ctime ASC, u.id ASC
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	stats := make([]model.UserStats, 0)
+	for rows.Next() {
+		var item model.UserStats
+		if err := rows.Scan(
+			&item.UserID, &item.Email, &item.Documents, &item.Versions, &item.Assets,
+			&item.ContentBytes, &item.AssetBytes,
+		); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		stats = append(stats, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+	return stats, nil
+}
+
+// DeleteUserData removes the user and every row owned by it. A team
+// workspace the user solely owns goes to its longest-standing editor, or
+// viewer if it has no editor; one without other members is deleted with its
+// content. It returns the file keys of the deleted assets so the caller can
+// remove the stored files once the transaction has committed. Call it inside a
+// transaction.
+func (r *AdminRepo) DeleteUserData(ctx context.Context, userID string) ([]string, error) {
+	db := conn(ctx, r.db)
+	orphaned, err := releaseOwnedWorkspaces(ctx, db, userID)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := deleteScopeData(ctx, db, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, workspaceID := range orphaned {
+		workspaceKeys, err := deleteScopeData(ctx, db, workspaceID)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, workspaceKeys...)
+		if _, err := db.ExecContext(ctx, `DELETE FROM workspaces WHERE id = $1`, workspaceID); err != nil {
+			return nil, fmt.Errorf("delete workspace %s: %w", workspaceID, err)
+		}
+	}
+	if _, err := db.ExecContext(ctx, `DELETE FROM users WHERE id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("delete user: %w", err)
+	}
+	return keys, nil
+}
+
+// releaseOwnedWorkspaces hands every workspace the user is the only owner of
+// to another member and returns the ones left without members.
+func releaseOwnedWorkspaces(ctx context.Context, db DBTX, userID string) ([]string, error) {
+	const query = `
+		SELECT m.workspace_id, COALESCE((
+			SELECT o.user_id FROM workspace_members o
+			WHERE o.workspace_id = m.workspace_id AND o.user_id <> m.user_id
+			ORDER BY CASE o.role WHEN 'editor' THEN 0 ELSE 1 END, o.ctime, o.user_id
+			LIMIT 1
+		), '')
+		FROM workspace_members m
+		WHERE m.user_id = $1 AND m.role = 'owner'
+		  AND NOT EXISTS (
+			SELECT 1 FROM workspace_members c
+			WHERE c.workspace_id = m.workspace_id AND c.role = 'owner' AND c.user_id <> m.user_id
+		  )
+		ORDER BY m.workspace_id
+	`
+	rows, err := db.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("query owned workspaces: %w", err)
+	}
+	successors := make(map[string]string)
+	order := make([]string, 0)
+	for rows.Next() {
+		var workspaceID, successor string
+		if err := rows.Scan(&workspaceID, &successor); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("scan owned workspace: %w", err)
+		}
+		successors[workspaceID] = successor
+		order = append(order, workspaceID)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return nil, fmt.Errorf("iterate owned workspaces: %w", err)
+	}
+	_ = rows.Close()
+	orphaned := make([]string, 0)
+	for _, workspaceID := range order {
+		successor := successors[workspaceID]
+		if successor == "" {
+			orphaned = append(orphaned, workspaceID)
+			continue
+		}
+		if _, err := db.ExecContext(ctx,
+			`UPDATE workspace_members SET role = 'owner' WHERE workspace_id = $1 AND user_id = $2`,
+			workspaceID, successor,
+		); err != nil {
+			return nil, fmt.Errorf("transfer workspace %s: %w", workspaceID, err)
+		}
+		if _, err := db.ExecContext(ctx,
+			`UPDATE workspaces SET owner_id = $1 WHERE id = $2`, successor, workspaceID,
+		); err != nil {
+			return nil, fmt.Errorf("transfer workspace %s: %w", workspaceID, err)
+		}
+	}
+	return orphaned, nil
+}
+
+// deleteScopeData deletes every row keyed by the scope, a user or workspace
+// ID, and returns the file keys of its assets.
+func deleteScopeData(ctx context.Context, db DBTX, scopeID string) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT file_key FROM assets WHERE user_id = $1 ORDER BY file_key`, scopeID)
+	if err != nil {
+		return nil, fmt.Errorf("query asset keys: %w", err)
+	}
+	keys := make([]string, 0)
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			_ = rows.Close()
+			return nil, fmt.Errorf("scan asset key: %w", err)
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		_ = rows.Close()
+		return nil, fmt.Errorf("iterate asset keys: %w", err)
+	}
+	_ = rows.Close()
+	for _, table := range userOwnedTables {
+		if _, err := db.ExecContext(ctx, "DELETE FROM "+table+" WHERE user_id = $1", scopeID); err != nil {
+			return nil, fmt.Errorf("delete from %s: %w", table, err)
+		}
+	}
+	return keys, nil
+}
+
+// VacuumVersions keeps the newest keep versions of every document and
+// deletes the rest. An empty userID covers all users. It returns the number
+// of deleted versions.
+func (r *AdminRepo) VacuumVersions(ctx context.Context, keep int, userID string) (int64, error) {
+	if keep <= 0 {
+		return 0, nil
+	}
+	const query = `
+		DELETE FROM document_versions
+		WHERE id IN (
+			SELECT id FROM (
+				SELECT id, ROW_NUMBER() OVER (
+					PARTITION BY user_id, document_id ORDER BY version DESC
+				) AS rn
+				FROM document_versions
+				WHERE $1 = '' OR user_id = $1
+			) ranked
+			WHERE rn > $2
+		)
+	`
+	result, err := conn(ctx, r.db).ExecContext(ctx, query, userID, keep)
+	if err != nil {
+		return 0, fmt.Errorf("vacuum versions: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("rows affected: %w", err)
+	}
+	return affected, nil
+}
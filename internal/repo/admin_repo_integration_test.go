@@ -0,0 +1,134 @@
+//go:build integration
+
+package repo_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/timeutil"
+	"github.com/xxxsen/mnote/internal/repo"
+	"github.com/xxxsen/mnote/internal/testutil"
+)
+
+func TestAdminRepoDeleteUserDataAndVacuum(t *testing.T) {
+	db, cleanup := testutil.OpenTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	users := repo.NewUserRepo(db)
+	docs := repo.NewDocumentRepo(db)
+	versions := repo.NewVersionRepo(db)
+	tags := repo.NewTagRepo(db)
+	assets := repo.NewAssetRepo(db)
+	admin := repo.NewAdminRepo(db)
+	now := timeutil.NowUnix()
+	for _, userID := range []string{"user-1", "user-2"} {
+		email := userID + "@example.com"
+		require.NoError(t, users.Create(ctx, &model.User{
+			ID: userID, Email: email, EmailNormalized: email, Ctime: now, Mtime: now,
+		}))
+		docID := "doc-" + userID
+		require.NoError(t, docs.Create(ctx, &model.Document{
+			ID: docID, UserID: userID, Title: "t", Content: "body", State: repo.DocumentStateNormal,
+			Ctime: now, Mtime: now,
+		}))
+		for version := 1; version <= 4; version++ {
+			require.NoError(t, versions.Create(ctx, &model.DocumentVersion{
+				ID: fmt.Sprintf("%s-v%d", docID, version), UserID: userID, DocumentID: docID,
+				Version: version, Title: "t", Content: "body", Ctime: now,
+			}))
+		}
+		require.NoError(t, tags.Create(ctx, &model.Tag{
+			ID: "tag-" + userID, UserID: userID, Name: "go", Ctime: now, Mtime: now,
+		}))
+		require.NoError(t, assets.UpsertByFileKey(ctx, &model.Asset{
+			ID: "asset-" + userID, UserID: userID, FileKey: userID + "/a.png", Size: 10,
+			Status: model.AssetStatusReady, Ctime: now, Mtime: now,
+		}))
+	}
+
+	stats, err := admin.UserStats(ctx)
+	require.NoError(t, err)
+	require.Len(t, stats, 2)
+	assert.Equal(t, model.UserStats{
+		UserID: "user-1", Email: "user-1@example.com",
+		Documents: 1, Versions: 4, Assets: 1, ContentBytes: 20, AssetBytes: 10,
+	}, stats[0])
+
+	deleted, err := admin.VacuumVersions(ctx, 2, "user-2")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+	kept, err := versions.List(ctx, "user-2", "doc-user-2")
+	require.NoError(t, err)
+	require.Len(t, kept, 2)
+	assert.Equal(t, 4, kept[0].Version)
+
+	keys, err := admin.DeleteUserData(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user-1/a.png"}, keys)
+	_, err = users.GetByID(ctx, "user-1")
+	require.Error(t, err)
+	remaining, err := admin.UserStats(ctx)
+	require.NoError(t, err)
+	require.Len(t, remaining, 1)
+	assert.Equal(t, "user-2", remaining[0].UserID)
+	assert.Equal(t, int64(1), remaining[0].Documents)
+}
+
+func TestAdminRepoDeleteUserDataReleasesOwnedWorkspaces(t *testing.T) {
+	db, cleanup := testutil.OpenTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	workspaces := repo.NewWorkspaceRepo(db)
+	docs := repo.NewDocumentRepo(db)
+	assets := repo.NewAssetRepo(db)
+	admin := repo.NewAdminRepo(db)
+	now := timeutil.NowUnix()
+	members := map[string][]model.WorkspaceMember{
+		"ws-shared": {{UserID: "user-2", Role: model.WorkspaceRoleViewer}, {UserID: "user-3", Role: model.WorkspaceRoleEditor}},
+		"ws-solo":   nil,
+	}
+	for workspaceID, others := range members {
+		require.NoError(t, workspaces.Create(ctx, &model.Workspace{
+			ID: workspaceID, Name: workspaceID, OwnerID: "user-1", Ctime: now, Mtime: now,
+		}))
+		others = append(others, model.WorkspaceMember{UserID: "user-1", Role: model.WorkspaceRoleOwner})
+		for _, member := range others {
+			member.WorkspaceID, member.Ctime, member.Mtime = workspaceID, now, now
+			require.NoError(t, workspaces.AddMember(ctx, &member))
+		}
+		require.NoError(t, docs.Create(ctx, &model.Document{
+			ID: "doc-" + workspaceID, UserID: workspaceID, Title: "t", Content: "body",
+			State: repo.DocumentStateNormal, Ctime: now, Mtime: now,
+		}))
+		require.NoError(t, assets.UpsertByFileKey(ctx, &model.Asset{
+			ID: "asset-" + workspaceID, UserID: workspaceID, FileKey: workspaceID + "/a.png", Size: 10,
+			Status: model.AssetStatusReady, Ctime: now, Mtime: now,
+		}))
+	}
+
+	keys, err := admin.DeleteUserData(ctx, "user-1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"ws-solo/a.png"}, keys)
+
+	shared, err := workspaces.GetByID(ctx, "ws-shared")
+	require.NoError(t, err)
+	assert.Equal(t, "user-3", shared.OwnerID)
+	owners, err := workspaces.CountOwners(ctx, "ws-shared")
+	require.NoError(t, err)
+	assert.Equal(t, 1, owners)
+	_, err = docs.GetByID(ctx, "ws-shared", "doc-ws-shared")
+	require.NoError(t, err)
+
+	_, err = workspaces.GetByID(ctx, "ws-solo")
+	require.Error(t, err)
+	_, err = docs.GetByID(ctx, "ws-solo", "doc-ws-solo")
+	require.Error(t, err)
+}
@@ -0,0 +1,110 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdminRepo_UserStats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewAdminRepo(db)
+	rows := sqlmock.NewRows([]string{"id", "email", "documents", "versions", "assets", "content", "asset_bytes"}).
+		AddRow("u1", "a@example.com", int64(3), int64(7), int64(1), int64(420), int64(2048))
+	mock.ExpectQuery("SELECT u.id, u.email").WillReturnRows(rows)
+
+	stats, err := r.UserStats(context.Background())
+	require.NoError(t, err)
+	require.Len(t, stats, 1)
+	assert.Equal(t, int64(7), stats[0].Versions)
+	assert.Equal(t, int64(2048), stats[0].AssetBytes)
+}
+
+func TestAdminRepo_DeleteUserData(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewAdminRepo(db)
+	mock.ExpectQuery("SELECT m.workspace_id").WithArgs("u1").
+		WillReturnRows(sqlmock.NewRows([]string{"workspace_id", "successor"}))
+	expectDeleteScope(mock, "u1", "u1/a.png")
+	mock.ExpectExec("DELETE FROM users WHERE id").WithArgs("u1").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	keys, err := r.DeleteUserData(context.Background(), "u1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"u1/a.png"}, keys)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAdminRepo_DeleteUserData_OwnedWorkspaces(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewAdminRepo(db)
+	mock.ExpectQuery("SELECT m.workspace_id").WithArgs("u1").
+		WillReturnRows(sqlmock.NewRows([]string{"workspace_id", "successor"}).
+			AddRow("w1", "u2").AddRow("w2", ""))
+	mock.ExpectExec("UPDATE workspace_members SET role = 'owner'").WithArgs("w1", "u2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("UPDATE workspaces SET owner_id").WithArgs("u2", "w1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectDeleteScope(mock, "u1", "u1/a.png")
+	expectDeleteScope(mock, "w2", "w2/b.png")
+	mock.ExpectExec("DELETE FROM workspaces WHERE id").WithArgs("w2").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("DELETE FROM users WHERE id").WithArgs("u1").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	keys, err := r.DeleteUserData(context.Background(), "u1")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"u1/a.png", "w2/b.png"}, keys)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func expectDeleteScope(mock sqlmock.Sqlmock, scopeID, key string) {
+	mock.ExpectQuery("SELECT file_key FROM assets WHERE user_id = \\$1").
+		WithArgs(scopeID).
+		WillReturnRows(sqlmock.NewRows([]string{"file_key"}).AddRow(key))
+	for _, table := range userOwnedTables {
+		mock.ExpectExec("DELETE FROM " + table + " WHERE user_id").
+			WithArgs(scopeID).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+}
+
+func TestAdminRepo_DeleteUserData_Error(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewAdminRepo(db)
+	mock.ExpectQuery("SELECT m.workspace_id").WillReturnRows(sqlmock.NewRows([]string{"workspace_id", "successor"}))
+	mock.ExpectQuery("SELECT file_key").WillReturnRows(sqlmock.NewRows([]string{"file_key"}))
+	mock.ExpectExec("DELETE FROM document_tags").WillReturnError(errDB)
+	_, err = r.DeleteUserData(context.Background(), "u1")
+	assert.ErrorIs(t, err, errDB)
+}
+
+func TestAdminRepo_VacuumVersions(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewAdminRepo(db)
+	mock.ExpectExec("DELETE FROM document_versions .* ROW_NUMBER\\(\\) OVER").
+		WithArgs("", 5).
+		WillReturnResult(sqlmock.NewResult(0, 12))
+	deleted, err := r.VacuumVersions(context.Background(), 5, "")
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), deleted)
+
+	deleted, err = r.VacuumVersions(context.Background(), 0, "")
+	require.NoError(t, err)
+	assert.Zero(t, deleted)
+}
@@ -0,0 +1,184 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/didi/gendry/builder"
+
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/dbutil"
+)
+
+var (
+	errInvalidBackupIdentifier = errors.New("invalid backup identifier")
+	errUnsupportedBackupColumn = errors.New("unsupported backup column type")
+)
+
+var backupIdentifierPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// BackupRepo reads and writes whole tables for instance backups. Table and
+// column names are checked against a strict identifier pattern because they
+// are spliced into the statements.
+type BackupRepo struct {
+	db *sql.DB
+}
+
+func NewBackupRepo(db *sql.DB) *BackupRepo {
+	return &BackupRepo{db: db}
+}
+
+// Snapshot runs fn in a read-only repeatable-read transaction, so every
+// query made through the context sees the same consistent state.
+func (r *BackupRepo) Snapshot(ctx context.Context, fn func(ctx context.Context) error) error {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("begin snapshot: %w", err)
+	}
+	if err := fn(WithTx(ctx, tx)); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit snapshot: %w", err)
+	}
+	return nil
+}
+
+func (r *BackupRepo) WithinTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return RunInTx(ctx, r.db, fn)
+}
+
+// AppliedMigrations returns the migration ledger ordered by version.
+func (r *BackupRepo) AppliedMigrations(ctx context.Context) ([]model.SchemaMigration, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, `SELECT version, checksum FROM schema_migrations ORDER BY version`)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	items := make([]model.SchemaMigration, 0)
+	for rows.Next() {
+		var item model.SchemaMigration
+		if err := rows.Scan(&item.Version, &item.Checksum); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+	return items, nil
+}
+
+// DumpTable streams every row of table to fn as a column to value map.
+// Text-encoded values such as JSONB and vectors are returned as strings so
+// they can be written back unchanged.
+func (r *BackupRepo) DumpTable(ctx context.Context, table string, fn func(row map[string]any) error) error {
+	if !backupIdentifierPattern.MatchString(table) {
+		return fmt.Errorf("%w: %q", errInvalidBackupIdentifier, table)
+	}
+	rows, err := conn(ctx, r.db).QueryContext(ctx, "SELECT * FROM "+table)
+	if err != nil {
+		return fmt.Errorf("query %s: %w", table, err)
+	}
+	defer func() { _ = rows.Close() }()
+	columns, err := rows.ColumnTypes()
+	if err != nil {
+		return fmt.Errorf("column types of %s: %w", table, err)
+	}
+	for _, column := range columns {
+		if strings.EqualFold(column.DatabaseTypeName(), "BYTEA") {
+			return fmt.Errorf("%w: %s.%s", errUnsupportedBackupColumn, table, column.Name())
+		}
+	}
+	values := make([]any, len(columns))
+	targets := make([]any, len(columns))
+	for i := range values {
+		targets[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(targets...); err != nil {
+			return fmt.Errorf("scan %s: %w", table, err)
+		}
+		row := make(map[string]any, len(columns))
+		for i, column := range columns {
+			value := values[i]
+			if raw, ok := value.([]byte); ok {
+				value = string(raw)
+			}
+			row[column.Name()] = value
+		}
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate %s: %w", table, err)
+	}
+	return nil
+}
+
+// TableHasRows reports whether table holds at least one row.
+func (r *BackupRepo) TableHasRows(ctx context.Context, table string) (bool, error) {
+	if !backupIdentifierPattern.MatchString(table) {
+		return false, fmt.Errorf("%w: %q", errInvalidBackupIdentifier, table)
+	}
+	var exists bool
+	if err := conn(ctx, r.db).QueryRowContext(
+		ctx, "SELECT EXISTS (SELECT 1 FROM "+table+")",
+	).Scan(&exists); err != nil {
+		return false, fmt.Errorf("check %s: %w", table, err)
+	}
+	return exists, nil
+}
+
+// InsertRows writes rows that share the same columns into table.
+func (r *BackupRepo) InsertRows(ctx context.Context, table string, rows []map[string]any) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	if err := validateBackupIdentifiers(table, rows[0]); err != nil {
+		return err
+	}
+	sqlStr, args, err := builder.BuildInsert(table, rows)
+	if err != nil {
+		return fmt.Errorf("build insert: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	if _, err := conn(ctx, r.db).ExecContext(ctx, sqlStr, args...); err != nil {
+		return fmt.Errorf("insert %s: %w", table, err)
+	}
+	return nil
+}
+
+// UpdateRow sets values on the row of table with the given id.
+func (r *BackupRepo) UpdateRow(ctx context.Context, table, id string, values map[string]any) error {
+	if err := validateBackupIdentifiers(table, values); err != nil {
+		return err
+	}
+	sqlStr, args, err := builder.BuildUpdate(table, map[string]any{"id": id}, values)
+	if err != nil {
+		return fmt.Errorf("build update: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	if _, err := conn(ctx, r.db).ExecContext(ctx, sqlStr, args...); err != nil {
+		return fmt.Errorf("update %s: %w", table, err)
+	}
+	return nil
+}
+
+func validateBackupIdentifiers(table string, row map[string]any) error {
+	if !backupIdentifierPattern.MatchString(table) {
+		return fmt.Errorf("%w: %q", errInvalidBackupIdentifier, table)
+	}
+	for column := range row {
+		if !backupIdentifierPattern.MatchString(column) {
+			return fmt.Errorf("%w: %s.%q", errInvalidBackupIdentifier, table, column)
+		}
+	}
+	return nil
+}
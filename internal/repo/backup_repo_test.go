@@ -0,0 +1,96 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackupRepo_AppliedMigrations(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery("SELECT version, checksum FROM schema_migrations ORDER BY version").
+		WillReturnRows(sqlmock.NewRows([]string{"version", "checksum"}).
+			AddRow("001_init", "aa").AddRow("002_next", "bb"))
+
+	items, err := NewBackupRepo(db).AppliedMigrations(context.Background())
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "002_next", items[1].Version)
+	assert.Equal(t, "bb", items[1].Checksum)
+}
+
+func TestBackupRepo_DumpTable(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery("SELECT \\* FROM documents").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "content", "mtime"}).
+			AddRow("d1", []byte("# hello"), int64(10)))
+
+	var rows []map[string]any
+	err = NewBackupRepo(db).DumpTable(context.Background(), "documents", func(row map[string]any) error {
+		rows = append(rows, row)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, "# hello", rows[0]["content"])
+	assert.Equal(t, int64(10), rows[0]["mtime"])
+}
+
+func TestBackupRepo_RejectsInvalidIdentifiers(t *testing.T) {
+	db, _, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewBackupRepo(db)
+	ctx := context.Background()
+	err = r.DumpTable(ctx, "users; DROP TABLE users", func(map[string]any) error { return nil })
+	assert.ErrorIs(t, err, errInvalidBackupIdentifier)
+	_, err = r.TableHasRows(ctx, "Users")
+	assert.ErrorIs(t, err, errInvalidBackupIdentifier)
+	err = r.InsertRows(ctx, "users", []map[string]any{{"id) VALUES (1": "x"}})
+	assert.ErrorIs(t, err, errInvalidBackupIdentifier)
+}
+
+func TestBackupRepo_TableHasRows(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM tags\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	exists, err := NewBackupRepo(db).TableHasRows(context.Background(), "tags")
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestBackupRepo_InsertAndUpdate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewBackupRepo(db)
+	mock.ExpectExec("INSERT INTO tags \\(ctime,id,name\\) VALUES").
+		WithArgs(1, "t1", "go", 2, "t2", "db").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("UPDATE share_comments SET reply_to_id=\\$1 WHERE \\(id=\\$2\\)").
+		WithArgs("c1", "c2").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	ctx := context.Background()
+	require.NoError(t, r.InsertRows(ctx, "tags", []map[string]any{
+		{"id": "t1", "name": "go", "ctime": 1},
+		{"id": "t2", "name": "db", "ctime": 2},
+	}))
+	require.NoError(t, r.UpdateRow(ctx, "share_comments", "c2", map[string]any{"reply_to_id": "c1"}))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
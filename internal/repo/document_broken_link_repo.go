@@ -0,0 +1,124 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/didi/gendry/builder"
+
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/dbutil"
+)
+
+// A /docs/<id> reference is broken when its link row points at a deleted
+// document or when the save recorded it in document_broken_links. A target
+// of another scope reads as missing so the report never reveals it exists.
+const listBrokenLinksQuery = `
+	WITH broken AS (
+		SELECT link.source_id, 'document' AS kind, link.target_id, '' AS title, link.anchor,
+		       'deleted' AS reason
+		FROM document_links AS link
+		JOIN documents AS target
+		  ON target.id = link.target_id
+		 AND target.user_id = link.user_id
+		 AND target.state <> $2
+		WHERE link.user_id = $1
+		UNION ALL
+		SELECT dangling.source_id, 'document', dangling.target_id, '', '',
+		       CASE WHEN EXISTS (
+		           SELECT 1 FROM documents AS target
+		           WHERE target.user_id = dangling.user_id AND target.id = dangling.target_id
+		       ) THEN 'deleted' ELSE 'missing' END
+		FROM document_broken_links AS dangling
+		WHERE dangling.user_id = $1
+		UNION ALL
+		SELECT wiki.source_id, 'wikilink', '', wiki.title, wiki.anchor, 'missing'
+		FROM document_wikilinks AS wiki
+		WHERE wiki.user_id = $1 AND wiki.status = 'missing'
+	)
+	SELECT broken.source_id, source.title, source.mtime, broken.kind, broken.target_id,
+	       broken.title, broken.anchor, broken.reason
+	FROM broken
+	JOIN documents AS source
+	  ON source.id = broken.source_id
+	 AND source.user_id = $1
+	 AND source.state = $2
+	ORDER BY source.mtime DESC, broken.source_id, broken.kind, broken.target_id, broken.title, broken.anchor
+	LIMIT $3 OFFSET $4
+`
+
+// ReplaceBrokenLinks swaps the unresolvable /docs/<id> references recorded
+// for sourceID.
+func (r *DocumentRepo) ReplaceBrokenLinks(
+	ctx context.Context, userID, sourceID string, targetIDs []string, ctime int64,
+) error {
+	tx, owned, err := beginOrJoin(ctx, r.db)
+	if err != nil {
+		return fmt.Errorf("repo: %w", err)
+	}
+	if owned {
+		defer func() { _ = tx.Rollback() }()
+	}
+	delSQL, delArgs, err := builder.BuildDelete("document_broken_links", map[string]any{
+		"source_id": sourceID,
+		"user_id":   userID,
+	})
+	if err != nil {
+		return fmt.Errorf("build delete: %w", err)
+	}
+	delSQL, delArgs = dbutil.Finalize(delSQL, delArgs)
+	if _, err := tx.ExecContext(ctx, delSQL, delArgs...); err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	if len(targetIDs) > 0 {
+		inserts := make([]map[string]any, 0, len(targetIDs))
+		for _, targetID := range targetIDs {
+			inserts = append(inserts, map[string]any{
+				"source_id": sourceID,
+				"user_id":   userID,
+				"target_id": targetID,
+				"ctime":     ctime,
+			})
+		}
+		insertSQL, insertArgs, err := builder.BuildInsert("document_broken_links", inserts)
+		if err != nil {
+			return fmt.Errorf("build insert: %w", err)
+		}
+		insertSQL, insertArgs = dbutil.Finalize(insertSQL, insertArgs)
+		if _, err := tx.ExecContext(ctx, insertSQL, insertArgs...); err != nil {
+			return fmt.Errorf("exec: %w", err)
+		}
+	}
+	if owned {
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("commit: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListBrokenLinks returns the broken references of the user's live documents,
+// most recently modified sources first.
+func (r *DocumentRepo) ListBrokenLinks(
+	ctx context.Context, userID string, limit, offset int,
+) ([]model.BrokenLink, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, listBrokenLinksQuery,
+		userID, DocumentStateNormal, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("query broken links: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	items := make([]model.BrokenLink, 0)
+	for rows.Next() {
+		var item model.BrokenLink
+		if err := rows.Scan(&item.SourceID, &item.SourceTitle, &item.SourceMtime, &item.Kind,
+			&item.TargetID, &item.Title, &item.Anchor, &item.Reason); err != nil {
+			return nil, fmt.Errorf("scan broken link: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate broken links: %w", err)
+	}
+	return items, nil
+}
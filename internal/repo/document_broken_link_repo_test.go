@@ -0,0 +1,99 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+)
+
+func TestDocumentRepo_ReplaceBrokenLinks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM document_broken_links").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO document_broken_links").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	err = NewDocumentRepo(db).ReplaceBrokenLinks(context.Background(), "u1", "d1", []string{"x", "y"}, 10)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDocumentRepo_ReplaceBrokenLinks_Empty(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM document_broken_links").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, NewDocumentRepo(db).ReplaceBrokenLinks(context.Background(), "u1", "d1", nil, 10))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDocumentRepo_ListBrokenLinks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery("FROM document_broken_links AS dangling").
+		WithArgs("u1", DocumentStateNormal, 50, 0).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"source_id", "title", "mtime", "kind", "target_id", "title", "anchor", "reason",
+		}).
+			AddRow("d1", "Notes", 10, "document", "gone", "", "", "missing").
+			AddRow("d1", "Notes", 10, "wikilink", "", "Alpha", "Intro", "missing"))
+
+	items, err := NewDocumentRepo(db).ListBrokenLinks(context.Background(), "u1", 50, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []model.BrokenLink{
+		{SourceID: "d1", SourceTitle: "Notes", SourceMtime: 10, Kind: "document", TargetID: "gone", Reason: "missing"},
+		{SourceID: "d1", SourceTitle: "Notes", SourceMtime: 10, Kind: "wikilink", Title: "Alpha", Anchor: "Intro",
+			Reason: "missing"},
+	}, items)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDocumentRepo_ListBrokenLinks_QueryError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery("WITH broken AS").WillReturnError(errDB)
+	_, err = NewDocumentRepo(db).ListBrokenLinks(context.Background(), "u1", 50, 0)
+	require.ErrorIs(t, err, errDB)
+}
+
+func TestDocumentRepo_ListMentionCandidates(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery(`content ILIKE ANY\(\$1::text\[\]\).*id NOT IN \(SELECT source_id FROM document_links`).
+		WillReturnRows(addDocRow(sqlmock.NewRows(docCols), "d2", "Mentions"))
+
+	docs, err := NewDocumentRepo(db).ListMentionCandidates(context.Background(), "u1", "d1", []string{"50%_off"}, 20)
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, "d2", docs[0].ID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDocumentRepo_ListMentionCandidates_NoTerms(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	docs, err := NewDocumentRepo(db).ListMentionCandidates(context.Background(), "u1", "d1", nil, 20)
+	require.NoError(t, err)
+	assert.Empty(t, docs)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,75 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xxxsen/mnote/internal/model"
+)
+
+const listGraphDocumentsQuery = `
+	SELECT id, title, pinned, starred, mtime
+	FROM documents
+	WHERE user_id = $1 AND state = $2
+	ORDER BY id
+`
+
+const listAllDocumentLinksQuery = `
+	SELECT link.source_id, link.target_id
+	FROM document_links AS link
+	JOIN documents AS source
+	  ON source.id = link.source_id
+	 AND source.user_id = link.user_id
+	 AND source.state = $2
+	JOIN documents AS target
+	  ON target.id = link.target_id
+	 AND target.user_id = link.user_id
+	 AND target.state = $2
+	WHERE link.user_id = $1
+	  AND link.source_id <> link.target_id
+	ORDER BY link.source_id, link.target_id
+`
+
+// ListGraphDocuments returns every live document of the user without its
+// content, for drawing the knowledge graph.
+func (r *DocumentRepo) ListGraphDocuments(ctx context.Context, userID string) ([]model.GraphDocument, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, listGraphDocumentsQuery, userID, DocumentStateNormal)
+	if err != nil {
+		return nil, fmt.Errorf("query graph documents: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	items := make([]model.GraphDocument, 0)
+	for rows.Next() {
+		var item model.GraphDocument
+		if err := rows.Scan(&item.ID, &item.Title, &item.Pinned, &item.Starred, &item.Mtime); err != nil {
+			return nil, fmt.Errorf("scan graph document: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate graph documents: %w", err)
+	}
+	return items, nil
+}
+
+// ListAllLinks returns the user's links whose source and target are both
+// live documents.
+func (r *DocumentRepo) ListAllLinks(ctx context.Context, userID string) ([]model.DocumentLink, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, listAllDocumentLinksQuery, userID, DocumentStateNormal)
+	if err != nil {
+		return nil, fmt.Errorf("query document links: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	links := make([]model.DocumentLink, 0)
+	for rows.Next() {
+		var link model.DocumentLink
+		if err := rows.Scan(&link.SourceID, &link.TargetID); err != nil {
+			return nil, fmt.Errorf("scan document link: %w", err)
+		}
+		links = append(links, link)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate document links: %w", err)
+	}
+	return links, nil
+}
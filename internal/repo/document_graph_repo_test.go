@@ -0,0 +1,51 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+)
+
+func TestDocumentRepo_ListGraphDocuments(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery("SELECT id, title, pinned, starred, mtime").
+		WithArgs("u1", DocumentStateNormal).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "pinned", "starred", "mtime"}).
+			AddRow("d1", "One", 1, 0, 10).
+			AddRow("d2", "Two", 0, 1, 20))
+
+	items, err := NewDocumentRepo(db).ListGraphDocuments(context.Background(), "u1")
+	require.NoError(t, err)
+	assert.Equal(t, []model.GraphDocument{
+		{ID: "d1", Title: "One", Pinned: 1, Mtime: 10},
+		{ID: "d2", Title: "Two", Starred: 1, Mtime: 20},
+	}, items)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDocumentRepo_ListAllLinks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery("FROM document_links AS link").
+		WithArgs("u1", DocumentStateNormal).
+		WillReturnRows(sqlmock.NewRows([]string{"source_id", "target_id"}).AddRow("d1", "d2"))
+
+	links, err := NewDocumentRepo(db).ListAllLinks(context.Background(), "u1")
+	require.NoError(t, err)
+	assert.Equal(t, []model.DocumentLink{{SourceID: "d1", TargetID: "d2"}}, links)
+
+	mock.ExpectQuery("FROM document_links AS link").WillReturnError(errDB)
+	_, err = NewDocumentRepo(db).ListAllLinks(context.Background(), "u1")
+	assert.ErrorIs(t, err, errDB)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,64 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/didi/gendry/builder"
+	"github.com/lib/pq"
+
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/dbutil"
+)
+
+// ListMentionCandidates returns live documents other than targetID whose
+// content contains one of terms case-insensitively and that do not already
+// link to targetID. The match is a coarse prefilter; callers decide which
+// occurrences are real mentions.
+func (r *DocumentRepo) ListMentionCandidates(
+	ctx context.Context, userID, targetID string, terms []string, limit uint,
+) ([]model.Document, error) {
+	docs := make([]model.Document, 0)
+	if len(terms) == 0 {
+		return docs, nil
+	}
+	escaper := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	patterns := make([]string, 0, len(terms))
+	for _, term := range terms {
+		patterns = append(patterns, "%"+escaper.Replace(term)+"%")
+	}
+	where := map[string]any{
+		"user_id":         userID,
+		"state":           DocumentStateNormal,
+		"id !=":           targetID,
+		"_custom_mention": builder.Custom("content ILIKE ANY(?::text[])", pq.Array(patterns)),
+		"_custom_unlinked": builder.Custom(
+			"id NOT IN (SELECT source_id FROM document_links WHERE user_id = ? AND target_id = ?)",
+			userID, targetID,
+		),
+		"_orderby": "mtime desc, id asc",
+		"_limit":   []uint{0, limit},
+	}
+	sqlStr, args, err := builder.BuildSelect("documents", where, documentSelectColumns)
+	if err != nil {
+		return nil, fmt.Errorf("build select: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var doc model.Document
+		if err := scanDocument(rows, &doc); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		docs = append(docs, doc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	return docs, nil
+}
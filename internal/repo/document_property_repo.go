@@ -0,0 +1,133 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/didi/gendry/builder"
+
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/dbutil"
+)
+
+const listPropertiesQuery = `
+	SELECT key, value_type, value
+	FROM document_properties
+	WHERE user_id = $1 AND document_id = $2
+	ORDER BY position
+`
+
+// ReplaceProperties swaps the front matter properties recorded for docID.
+// Every scalar value becomes one row; list items keep their order.
+func (r *DocumentRepo) ReplaceProperties(
+	ctx context.Context, userID, docID string, props []model.DocumentProperty, ctime int64,
+) error {
+	tx, owned, err := beginOrJoin(ctx, r.db)
+	if err != nil {
+		return fmt.Errorf("repo: %w", err)
+	}
+	if owned {
+		defer func() { _ = tx.Rollback() }()
+	}
+	delSQL, delArgs, err := builder.BuildDelete("document_properties", map[string]any{
+		"document_id": docID,
+		"user_id":     userID,
+	})
+	if err != nil {
+		return fmt.Errorf("build delete: %w", err)
+	}
+	delSQL, delArgs = dbutil.Finalize(delSQL, delArgs)
+	if _, err := tx.ExecContext(ctx, delSQL, delArgs...); err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	if inserts := buildPropertyInserts(userID, docID, props, ctime); len(inserts) > 0 {
+		insertSQL, insertArgs, err := builder.BuildInsert("document_properties", inserts)
+		if err != nil {
+			return fmt.Errorf("build insert: %w", err)
+		}
+		insertSQL, insertArgs = dbutil.Finalize(insertSQL, insertArgs)
+		if _, err := tx.ExecContext(ctx, insertSQL, insertArgs...); err != nil {
+			return fmt.Errorf("exec: %w", err)
+		}
+	}
+	if owned {
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("commit: %w", err)
+		}
+	}
+	return nil
+}
+
+func buildPropertyInserts(userID, docID string, props []model.DocumentProperty, ctime int64) []map[string]any {
+	inserts := make([]map[string]any, 0, len(props))
+	add := func(prop model.DocumentProperty, value string) {
+		inserts = append(inserts, map[string]any{
+			"document_id": docID,
+			"user_id":     userID,
+			"position":    len(inserts),
+			"key":         prop.Key,
+			"value_type":  prop.Type,
+			"value":       value,
+			"ctime":       ctime,
+		})
+	}
+	for _, prop := range props {
+		if prop.Type != model.PropertyTypeList {
+			add(prop, prop.Value)
+			continue
+		}
+		for _, value := range prop.Values {
+			add(prop, value)
+		}
+	}
+	return inserts
+}
+
+// ListProperties returns the front matter properties of a document in their
+// original order. Consecutive rows of a list field are folded back together.
+func (r *DocumentRepo) ListProperties(ctx context.Context, userID, docID string) ([]model.DocumentProperty, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, listPropertiesQuery, userID, docID)
+	if err != nil {
+		return nil, fmt.Errorf("query properties: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	props := make([]model.DocumentProperty, 0)
+	for rows.Next() {
+		var key, valueType, value string
+		if err := rows.Scan(&key, &valueType, &value); err != nil {
+			return nil, fmt.Errorf("scan property: %w", err)
+		}
+		if valueType != model.PropertyTypeList {
+			props = append(props, model.DocumentProperty{Key: key, Type: valueType, Value: value})
+			continue
+		}
+		if n := len(props); n > 0 && props[n-1].Key == key && props[n-1].Type == model.PropertyTypeList {
+			props[n-1].Values = append(props[n-1].Values, value)
+			continue
+		}
+		props = append(props, model.DocumentProperty{Key: key, Type: valueType, Values: []string{value}})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate properties: %w", err)
+	}
+	return props, nil
+}
+
+// applyPropertyFilters adds one condition per filter to a document select:
+// the document must hold the property with one of the filter values.
+func applyPropertyFilters(where map[string]any, userID string, filters []model.PropertyFilter) {
+	for i, filter := range filters {
+		args := make([]any, 0, len(filter.Values)+2)
+		args = append(args, userID, filter.Key)
+		for _, value := range filter.Values {
+			args = append(args, strings.ToLower(strings.TrimSpace(value)))
+		}
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(filter.Values)), ", ")
+		where[fmt.Sprintf("_custom_property_%d", i)] = builder.Custom(
+			"id IN (SELECT document_id FROM document_properties WHERE user_id = ? AND key = ?"+
+				" AND lower(btrim(value)) IN ("+placeholders+"))",
+			args...,
+		)
+	}
+}
@@ -0,0 +1,92 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+)
+
+func TestDocumentRepo_ReplaceProperties(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM document_properties").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("INSERT INTO document_properties").WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	err = NewDocumentRepo(db).ReplaceProperties(context.Background(), "u1", "d1", []model.DocumentProperty{
+		{Key: "status", Type: model.PropertyTypeString, Value: "draft"},
+		{Key: "aliases", Type: model.PropertyTypeList, Values: []string{"A", "B"}},
+	}, 10)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDocumentRepo_ReplaceProperties_Empty(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM document_properties").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	require.NoError(t, NewDocumentRepo(db).ReplaceProperties(context.Background(), "u1", "d1", nil, 10))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBuildPropertyInserts(t *testing.T) {
+	inserts := buildPropertyInserts("u1", "d1", []model.DocumentProperty{
+		{Key: "tags", Type: model.PropertyTypeList, Values: []string{"a", "b"}},
+		{Key: "status", Type: model.PropertyTypeString, Value: "draft"},
+	}, 10)
+	require.Len(t, inserts, 3)
+	assert.Equal(t, 1, inserts[1]["position"])
+	assert.Equal(t, "b", inserts[1]["value"])
+	assert.Equal(t, "status", inserts[2]["key"])
+}
+
+func TestDocumentRepo_ListProperties(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery("FROM document_properties").
+		WithArgs("u1", "d1").
+		WillReturnRows(sqlmock.NewRows([]string{"key", "value_type", "value"}).
+			AddRow("aliases", "list", "A").
+			AddRow("aliases", "list", "B").
+			AddRow("status", "string", "draft").
+			AddRow("tags", "list", "x"))
+
+	props, err := NewDocumentRepo(db).ListProperties(context.Background(), "u1", "d1")
+	require.NoError(t, err)
+	assert.Equal(t, []model.DocumentProperty{
+		{Key: "aliases", Type: model.PropertyTypeList, Values: []string{"A", "B"}},
+		{Key: "status", Type: model.PropertyTypeString, Value: "draft"},
+		{Key: "tags", Type: model.PropertyTypeList, Values: []string{"x"}},
+	}, props)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDocumentRepo_SearchLike_PropertyFilters(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery(`FROM document_properties WHERE user_id = \$\d+ AND key = \$\d+ AND lower\(btrim\(value\)\) IN \(\$\d+, \$\d+\)`).
+		WillReturnRows(addDocRow(sqlmock.NewRows(docCols), "d1", "Draft"))
+
+	docs, err := NewDocumentRepo(db).SearchLike(context.Background(), "u1", "", "", nil,
+		[]model.PropertyFilter{{Key: "status", Values: []string{"Draft", "review"}}}, nil, 10, 0, "")
+	require.NoError(t, err)
+	assert.Len(t, docs, 1)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
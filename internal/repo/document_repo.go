@@ -34,6 +34,9 @@ var documentSelectColumns = []string{
 	"id", "user_id", "title", "content",
 	"state", "pinned", "starred", "ctime", "mtime",
 	"content_hash", "content_mtime", "content_revision",
+	"word_count", "char_count", "reading_minutes", "code_block_count",
+	"image_count", "link_count", "task_total", "task_done",
+	"folder_id",
 }
 
 // rowScanner abstracts *sql.Row and *sql.Rows so scanDocument can be reused
@@ -48,12 +51,30 @@ func scanDocument(rs rowScanner, doc *model.Document) error {
 		&doc.ID, &doc.UserID, &doc.Title, &doc.Content,
 		&doc.State, &doc.Pinned, &doc.Starred, &doc.Ctime, &doc.Mtime,
 		&doc.ContentHash, &doc.ContentMtime, &doc.ContentRevision,
+		&doc.Stats.WordCount, &doc.Stats.CharCount, &doc.Stats.ReadingMinutes, &doc.Stats.CodeBlocks,
+		&doc.Stats.Images, &doc.Stats.Links, &doc.Stats.TasksTotal, &doc.Stats.TasksDone,
+		&doc.FolderID,
 	); err != nil {
 		return fmt.Errorf("scan document: %w", err)
 	}
 	return nil
 }
 
+// documentStatsColumns maps the computed statistics of a document to the
+// columns that store them.
+func documentStatsColumns(stats model.DocumentStats) map[string]any {
+	return map[string]any{
+		"word_count":       stats.WordCount,
+		"char_count":       stats.CharCount,
+		"reading_minutes":  stats.ReadingMinutes,
+		"code_block_count": stats.CodeBlocks,
+		"image_count":      stats.Images,
+		"link_count":       stats.Links,
+		"task_total":       stats.TasksTotal,
+		"task_done":        stats.TasksDone,
+	}
+}
+
 func (r *DocumentRepo) Create(ctx context.Context, doc *model.Document) error {
 	if doc.ContentRevision == 0 {
 		doc.ContentRevision = 1
@@ -74,6 +95,10 @@ func (r *DocumentRepo) Create(ctx context.Context, doc *model.Document) error {
 		"content_hash":     doc.ContentHash,
 		"content_mtime":    doc.ContentMtime,
 		"content_revision": doc.ContentRevision,
+		"folder_id":        doc.FolderID,
+	}
+	for column, value := range documentStatsColumns(doc.Stats) {
+		data[column] = value
 	}
 	sqlStr, args, err := builder.BuildInsert("documents", []map[string]any{data})
 	if err != nil {
@@ -90,8 +115,8 @@ func (r *DocumentRepo) Create(ctx context.Context, doc *model.Document) error {
 	return nil
 }
 
-// Update writes title/content along with the content_* metadata that the
-// caller has computed (typically inside a transaction guarded by
+// Update writes title/content along with the content_* metadata and
+// statistics that the caller has computed (typically inside a transaction guarded by
 // GetByIDForUpdate). The optimistic check uses (id, user_id, state) only;
 // concurrency control is the caller's responsibility via SELECT FOR UPDATE.
 func (r *DocumentRepo) Update(ctx context.Context, doc *model.Document) error {
@@ -108,6 +133,9 @@ func (r *DocumentRepo) Update(ctx context.Context, doc *model.Document) error {
 		"content_mtime":    doc.ContentMtime,
 		"content_revision": doc.ContentRevision,
 	}
+	for column, value := range documentStatsColumns(doc.Stats) {
+		update[column] = value
+	}
 	sqlStr, args, err := builder.BuildUpdate("documents", where, update)
 	if err != nil {
 		return fmt.Errorf("build update: %w", err)
@@ -135,7 +163,9 @@ func (r *DocumentRepo) GetByIDForUpdate(
 	ctx context.Context, userID, docID string,
 ) (*model.Document, error) {
 	const q = `SELECT id, user_id, title, content, state, pinned, starred, ctime, mtime,
-        content_hash, content_mtime, content_revision
+        content_hash, content_mtime, content_revision,
+        word_count, char_count, reading_minutes, code_block_count,
+        image_count, link_count, task_total, task_done, folder_id
         FROM documents WHERE id = $1 AND user_id = $2 AND state = $3 FOR UPDATE`
 	row := conn(ctx, r.db).QueryRowContext(ctx, q, docID, userID, DocumentStateNormal)
 	var doc model.Document
@@ -173,6 +203,27 @@ func (r *DocumentRepo) updateDocField(ctx context.Context, userID, docID string,
 	return nil
 }
 
+// UpdateStats rewrites the statistics columns alone, leaving mtime and the
+// content revision untouched. Documents in the trash are updated as well.
+func (r *DocumentRepo) UpdateStats(ctx context.Context, userID, docID string, stats model.DocumentStats) error {
+	sqlStr, args, err := builder.BuildUpdate("documents", map[string]any{
+		"id":      docID,
+		"user_id": userID,
+	}, documentStatsColumns(stats))
+	if err != nil {
+		return fmt.Errorf("build update: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	affected, err := dbutil.ExecAffected(ctx, conn(ctx, r.db), sqlStr, args)
+	if err != nil {
+		return fmt.Errorf("update stats: %w", err)
+	}
+	if affected == 0 {
+		return appErr.ErrNotFound
+	}
+	return nil
+}
+
 func (r *DocumentRepo) TouchMtime(ctx context.Context, userID, docID string, mtime int64) error {
 	return r.updateDocField(ctx, userID, docID, map[string]any{"mtime": mtime})
 }
@@ -185,6 +236,12 @@ func (r *DocumentRepo) UpdateStarred(ctx context.Context, userID, docID string,
 	return r.updateDocField(ctx, userID, docID, map[string]any{"starred": starred})
 }
 
+// UpdateFolder files a document in folderID, or at the top level when it is
+// empty. Like pinning it leaves mtime alone.
+func (r *DocumentRepo) UpdateFolder(ctx context.Context, userID, docID, folderID string) error {
+	return r.updateDocField(ctx, userID, docID, map[string]any{"folder_id": folderID})
+}
+
 func (r *DocumentRepo) GetByID(ctx context.Context, userID, docID string) (*model.Document, error) {
 	where := map[string]any{
 		"id":      docID,
@@ -327,6 +384,39 @@ func (r *DocumentRepo) ListAllByUser(
 	return documents, nil
 }
 
+// ListPageAfter returns up to limit documents of every scope, including the
+// trash, ordered by ID and starting after afterID. The admin backfill pages
+// through all documents with it.
+func (r *DocumentRepo) ListPageAfter(ctx context.Context, afterID string, limit uint) ([]model.Document, error) {
+	where := map[string]any{
+		"id >":     afterID,
+		"_orderby": "id asc",
+		"_limit":   []uint{0, limit},
+	}
+	sqlStr, args, err := builder.BuildSelect("documents", where, documentSelectColumns)
+	if err != nil {
+		return nil, fmt.Errorf("build select document page: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query document page: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	documents := make([]model.Document, 0)
+	for rows.Next() {
+		var document model.Document
+		if err := scanDocument(rows, &document); err != nil {
+			return nil, err
+		}
+		documents = append(documents, document)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate document page: %w", err)
+	}
+	return documents, nil
+}
+
 func (r *DocumentRepo) ListByIDs(ctx context.Context, userID string, docIDs []string) ([]model.Document, error) {
 	if len(docIDs) == 0 {
 		return []model.Document{}, nil
@@ -387,6 +477,8 @@ func (
 	query,
 	tagID string,
 	starred *int,
+	props []model.PropertyFilter,
+	folder *model.FolderFilter,
 	limit,
 	offset uint,
 	orderBy string) ([]model.Document,
@@ -408,13 +500,15 @@ func (
 	}
 	if tagID != "" {
 		where["_custom_tag"] = builder.Custom(
-			"id IN (SELECT document_id FROM document_tags WHERE tag_id = ? AND user_id = ?)",
-			tagID, userID,
+			"id IN (SELECT document_id FROM document_tags WHERE user_id = ? AND tag_id IN ("+tagSubtreeIDsQuery+"))",
+			userID, tagID, userID,
 		)
 	}
 	if starred != nil {
 		where["starred"] = *starred
 	}
+	applyPropertyFilters(where, userID, props)
+	applyFolderFilter(where, userID, folder)
 	if limit == 0 || limit > 200 {
 		limit = 50
 	}
@@ -476,7 +570,7 @@ func (
 	r *DocumentRepo) UpdateLinks(ctx context.Context,
 	userID,
 	sourceID string,
-	targetIDs []string,
+	targets []model.LinkTarget,
 	mtime int64,
 ) error {
 	tx, owned, err := beginOrJoin(ctx, r.db)
@@ -499,7 +593,7 @@ func (
 		return fmt.Errorf("exec: %w", err)
 	}
 
-	inserts := buildLinkInserts(sourceID, userID, targetIDs, mtime)
+	inserts := buildLinkInserts(sourceID, userID, targets, mtime)
 	if len(inserts) > 0 {
 		insertSQL, insertArgs, err := builder.BuildInsert("document_links", inserts)
 		if err != nil {
@@ -556,18 +650,19 @@ func (r *DocumentRepo) GetBacklinks(ctx context.Context, userID, targetID string
 	return docs, nil
 }
 
-func buildLinkInserts(sourceID, userID string, targetIDs []string, mtime int64) []map[string]any {
+func buildLinkInserts(sourceID, userID string, targets []model.LinkTarget, mtime int64) []map[string]any {
 	seen := make(map[string]bool)
 	var inserts []map[string]any
-	for _, targetID := range targetIDs {
-		if seen[targetID] || targetID == sourceID {
+	for _, target := range targets {
+		if seen[target.ID] || target.ID == sourceID {
 			continue
 		}
-		seen[targetID] = true
+		seen[target.ID] = true
 		inserts = append(inserts, map[string]any{
 			"source_id": sourceID,
-			"target_id": targetID,
+			"target_id": target.ID,
 			"user_id":   userID,
+			"anchor":    target.Anchor,
 			"ctime":     mtime,
 		})
 	}
@@ -50,3 +50,283 @@ func TestDocumentRepoCRUDAndIsolation(t *testing.T) {
 	_, err = docs.GetByID(context.Background(), "user-1", "doc-1")
 	require.ErrorIs(t, err, appErr.ErrNotFound)
 }
+
+func TestDocumentRepoWikilinks(t *testing.T) {
+	db, cleanup := testutil.OpenTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	docs := repo.NewDocumentRepo(db)
+	now := timeutil.NowUnix()
+	for _, doc := range []model.Document{
+		{ID: "doc-a", UserID: "user-1", Title: " Alpha "},
+		{ID: "doc-b", UserID: "user-1", Title: "Beta"},
+		{ID: "doc-c", UserID: "user-2", Title: "alpha"},
+	} {
+		doc.State, doc.Ctime, doc.Mtime = repo.DocumentStateNormal, now, now
+		require.NoError(t, docs.Create(ctx, &doc))
+	}
+
+	titles, err := docs.ListByTitleKeys(ctx, "user-1", []string{"alpha", "gamma"})
+	require.NoError(t, err)
+	require.Len(t, titles, 1)
+	require.Equal(t, "doc-a", titles[0].ID)
+
+	require.NoError(t, docs.UpdateLinks(ctx, "user-1", "doc-b",
+		[]model.LinkTarget{{ID: "doc-a", Anchor: "Intro"}}, now))
+	require.NoError(t, docs.ReplaceWikilinks(ctx, "user-1", "doc-b", []model.DocumentWikilink{
+		{Title: "Alpha", TitleKey: "alpha", Anchor: "Intro", Status: model.WikilinkStatusResolved, TargetID: "doc-a", Ctime: now},
+		{Title: "Gamma", TitleKey: "gamma", Status: model.WikilinkStatusMissing, Ctime: now},
+	}))
+	links, err := docs.ListWikilinks(ctx, "user-1", "doc-b")
+	require.NoError(t, err)
+	require.Len(t, links, 2)
+	require.Equal(t, "doc-a", links[0].TargetID)
+
+	sources, err := docs.ListWikilinkSources(ctx, "user-1", []string{"gamma"}, "")
+	require.NoError(t, err)
+	require.Equal(t, []string{"doc-b"}, sources)
+	sources, err = docs.ListWikilinkSources(ctx, "user-1", nil, "doc-a")
+	require.NoError(t, err)
+	require.Equal(t, []string{"doc-b"}, sources)
+	sources, err = docs.ListWikilinkSources(ctx, "user-1", []string{"delta"}, "doc-b")
+	require.NoError(t, err)
+	require.Empty(t, sources)
+}
+
+func TestDocumentRepoPropertiesAndAliases(t *testing.T) {
+	db, cleanup := testutil.OpenTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	docs := repo.NewDocumentRepo(db)
+	now := timeutil.NowUnix()
+	for _, doc := range []model.Document{
+		{ID: "doc-a", UserID: "user-1", Title: "Alpha"},
+		{ID: "doc-b", UserID: "user-1", Title: "Beta"},
+	} {
+		doc.State, doc.Ctime, doc.Mtime = repo.DocumentStateNormal, now, now
+		require.NoError(t, docs.Create(ctx, &doc))
+	}
+	require.NoError(t, docs.ReplaceProperties(ctx, "user-1", "doc-b", []model.DocumentProperty{
+		{Key: model.PropertyKeyAliases, Type: model.PropertyTypeList, Values: []string{"alpha", " Second "}},
+		{Key: "status", Type: model.PropertyTypeString, Value: "Draft"},
+	}, now))
+
+	props, err := docs.ListProperties(ctx, "user-1", "doc-b")
+	require.NoError(t, err)
+	require.Len(t, props, 2)
+	require.Equal(t, []string{"alpha", " Second "}, props[0].Values)
+
+	titles, err := docs.ListByTitleKeys(ctx, "user-1", []string{"alpha", "second"})
+	require.NoError(t, err)
+	require.ElementsMatch(t, []model.DocumentTitle{
+		{ID: "doc-a", Title: "Alpha", Mtime: now, Key: "alpha"},
+		{ID: "doc-b", Title: "Beta", Mtime: now, Key: "alpha", Alias: true},
+		{ID: "doc-b", Title: "Beta", Mtime: now, Key: "second", Alias: true},
+	}, titles)
+
+	found, err := docs.SearchLike(ctx, "user-1", "", "", nil,
+		[]model.PropertyFilter{{Key: "status", Values: []string{"draft"}}}, nil, 10, 0, "")
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	require.Equal(t, "doc-b", found[0].ID)
+}
+
+func TestDocumentRepoStats(t *testing.T) {
+	db, cleanup := testutil.OpenTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	docs := repo.NewDocumentRepo(db)
+	now := timeutil.NowUnix()
+	for _, doc := range []model.Document{
+		{ID: "doc-a", UserID: "user-1", Title: "Short", ContentMtime: now - 100,
+			Stats: model.DocumentStats{WordCount: 10, ReadingMinutes: 1, TasksTotal: 2, TasksDone: 1}},
+		{ID: "doc-b", UserID: "user-1", Title: "Long", ContentMtime: now,
+			Stats: model.DocumentStats{WordCount: 900, ReadingMinutes: 5, Links: 3}},
+	} {
+		doc.State, doc.Ctime, doc.Mtime = repo.DocumentStateNormal, now, now
+		require.NoError(t, docs.Create(ctx, &doc))
+	}
+
+	got, err := docs.GetByID(ctx, "user-1", "doc-a")
+	require.NoError(t, err)
+	require.Equal(t, 2, got.Stats.TasksTotal)
+
+	sorted, err := docs.List(ctx, "user-1", nil, 10, 0, "word_count desc")
+	require.NoError(t, err)
+	require.Len(t, sorted, 2)
+	require.Equal(t, "doc-b", sorted[0].ID)
+
+	totals, err := docs.SumStats(ctx, "user-1", now-10)
+	require.NoError(t, err)
+	require.Equal(t, 910, totals.WordCount)
+	require.Equal(t, 6, totals.ReadingMinutes)
+	require.Equal(t, 1, totals.EditedDocuments)
+}
+
+func TestVersionRepoActivity(t *testing.T) {
+	db, cleanup := testutil.OpenTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	docs := repo.NewDocumentRepo(db)
+	versions := repo.NewVersionRepo(db)
+	require.NoError(t, docs.Create(ctx, &model.Document{
+		ID: "doc-a", UserID: "user-1", Title: "Alpha", State: repo.DocumentStateNormal, Ctime: 100, Mtime: 100,
+	}))
+	for _, v := range []model.DocumentVersion{
+		{ID: "v1", Version: 1, WordCount: 10, Ctime: 100},
+		{ID: "v2", Version: 2, WordCount: 25, Ctime: 200},
+		{ID: "v3", Version: 3, WordCount: 20, Ctime: 300},
+	} {
+		v.UserID, v.DocumentID, v.Title = "user-1", "doc-a", "Alpha"
+		require.NoError(t, versions.Create(ctx, &v))
+	}
+
+	items, err := versions.ListActivity(ctx, "user-1", 150, 301)
+	require.NoError(t, err)
+	require.Equal(t, []model.VersionActivity{
+		{DocumentID: "doc-a", Title: "Alpha", Version: 2, Ctime: 200, WordDelta: 15, DeltaKnown: true},
+		{DocumentID: "doc-a", Title: "Alpha", Version: 3, Ctime: 300, WordDelta: -5, DeltaKnown: true},
+	}, items)
+}
+
+func TestDocumentRepoTasks(t *testing.T) {
+	db, cleanup := testutil.OpenTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	docs := repo.NewDocumentRepo(db)
+	todos := repo.NewTodoRepo(db)
+	require.NoError(t, docs.Create(ctx, &model.Document{
+		ID: "doc-a", UserID: "user-1", Title: "Alpha", State: repo.DocumentStateNormal, Ctime: 100, Mtime: 100,
+	}))
+	require.NoError(t, docs.ReplaceTasks(ctx, "user-1", "doc-a", []model.Todo{
+		{ID: "t1", Content: "write", SourceAnchor: "a1", SourceLine: 1, Ctime: 100, Mtime: 100},
+		{ID: "t2", Content: "ship", SourceAnchor: "a2", SourceLine: 2, Ctime: 100, Mtime: 100},
+	}))
+	require.NoError(t, todos.Update(ctx, &model.Todo{
+		ID: "t1", UserID: "user-1", Content: "write", Priority: model.TodoPriorityHigh, DocumentID: "doc-a", Mtime: 150,
+	}))
+	require.NoError(t, todos.Create(ctx, &model.Todo{
+		ID: "t2-sub", UserID: "user-1", Content: "check", ParentID: "t2", Ctime: 150, Mtime: 150,
+	}))
+
+	require.NoError(t, docs.ReplaceTasks(ctx, "user-1", "doc-a", []model.Todo{
+		{ID: "t1", Content: "write more", Done: 1, SourceAnchor: "a3", SourceLine: 4, Ctime: 100, Mtime: 200},
+	}))
+	tasks, err := docs.ListTasks(ctx, "user-1", "doc-a")
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	require.Equal(t, "write more", tasks[0].Content)
+	require.Equal(t, model.TodoPriorityHigh, tasks[0].Priority, "priority set on the todo list survives")
+	require.Equal(t, 4, tasks[0].SourceLine)
+	_, err = todos.GetByID(ctx, "user-1", "t2-sub")
+	require.ErrorIs(t, err, appErr.ErrNotFound, "subtasks of a removed task go with it")
+}
+
+func TestDocumentRepoBrokenLinksAndMentions(t *testing.T) {
+	db, cleanup := testutil.OpenTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	docs := repo.NewDocumentRepo(db)
+	now := timeutil.NowUnix()
+	for _, doc := range []model.Document{
+		{ID: "doc-a", UserID: "user-1", Title: "Alpha", Content: "see /docs/doc-b and /docs/doc-x, 50% of alpha"},
+		{ID: "doc-b", UserID: "user-1", Title: "Beta", Content: "Alpha again"},
+		{ID: "doc-c", UserID: "user-1", Title: "Gamma", Content: "alpha is linked", Mtime: now - 10},
+	} {
+		doc.State, doc.Ctime = repo.DocumentStateNormal, now
+		if doc.Mtime == 0 {
+			doc.Mtime = now
+		}
+		require.NoError(t, docs.Create(ctx, &doc))
+	}
+	require.NoError(t, docs.UpdateLinks(ctx, "user-1", "doc-a", []model.LinkTarget{{ID: "doc-b"}}, now))
+	require.NoError(t, docs.ReplaceBrokenLinks(ctx, "user-1", "doc-a", []string{"doc-x"}, now))
+	require.NoError(t, docs.UpdateLinks(ctx, "user-1", "doc-c", []model.LinkTarget{{ID: "doc-a"}}, now))
+	require.NoError(t, docs.ReplaceWikilinks(ctx, "user-1", "doc-c", []model.DocumentWikilink{
+		{Title: "Delta", TitleKey: "delta", Status: model.WikilinkStatusMissing, Ctime: now},
+	}))
+	require.NoError(t, docs.Delete(ctx, "user-1", "doc-b", now))
+
+	broken, err := docs.ListBrokenLinks(ctx, "user-1", 50, 0)
+	require.NoError(t, err)
+	require.Len(t, broken, 3)
+	require.Equal(t, model.BrokenLink{
+		SourceID: "doc-a", SourceTitle: "Alpha", SourceMtime: now, Kind: model.BrokenLinkKindDocument,
+		TargetID: "doc-b", Reason: model.BrokenLinkReasonDeleted,
+	}, broken[0])
+	require.Equal(t, "doc-x", broken[1].TargetID)
+	require.Equal(t, model.BrokenLinkReasonMissing, broken[1].Reason)
+	require.Equal(t, "Delta", broken[2].Title)
+
+	mentions, err := docs.ListMentionCandidates(ctx, "user-1", "doc-a", []string{"ALPHA"}, 10)
+	require.NoError(t, err)
+	require.Empty(t, mentions, "doc-b is deleted and doc-c already links doc-a")
+	mentions, err = docs.ListMentionCandidates(ctx, "user-1", "doc-c", []string{"50%"}, 10)
+	require.NoError(t, err)
+	require.Len(t, mentions, 1)
+	require.Equal(t, "doc-a", mentions[0].ID)
+}
+
+func TestDocumentRepoFolders(t *testing.T) {
+	db, cleanup := testutil.OpenTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	docs := repo.NewDocumentRepo(db)
+	folders := repo.NewFolderRepo(db)
+	now := timeutil.NowUnix()
+	for _, folder := range []model.Folder{
+		{ID: "f-work", UserID: "user-1", Name: "Work"},
+		{ID: "f-2024", UserID: "user-1", ParentID: "f-work", Name: "2024"},
+		{ID: "f-home", UserID: "user-1", Name: "Home", Position: 1},
+	} {
+		folder.Ctime, folder.Mtime = now, now
+		require.NoError(t, folders.Create(ctx, &folder))
+	}
+	require.ErrorIs(t, folders.Create(ctx, &model.Folder{
+		ID: "f-dup", UserID: "user-1", Name: "work", Ctime: now, Mtime: now,
+	}), appErr.ErrConflict)
+	for _, doc := range []model.Document{
+		{ID: "doc-top", UserID: "user-1", Title: "Top"},
+		{ID: "doc-work", UserID: "user-1", Title: "Plan", FolderID: "f-work"},
+		{ID: "doc-2024", UserID: "user-1", Title: "Review"},
+	} {
+		doc.State, doc.Ctime, doc.Mtime = repo.DocumentStateNormal, now, now
+		require.NoError(t, docs.Create(ctx, &doc))
+	}
+	require.NoError(t, docs.UpdateFolder(ctx, "user-1", "doc-2024", "f-2024"))
+
+	search := func(filter *model.FolderFilter) []string {
+		found, err := docs.SearchLike(ctx, "user-1", "", "", nil, nil, filter, 10, 0, "title")
+		require.NoError(t, err)
+		ids := make([]string, 0, len(found))
+		for _, doc := range found {
+			ids = append(ids, doc.ID)
+		}
+		return ids
+	}
+	require.ElementsMatch(t, []string{"doc-top"}, search(&model.FolderFilter{}))
+	require.ElementsMatch(t, []string{"doc-work"}, search(&model.FolderFilter{ID: "f-work"}))
+	require.ElementsMatch(t, []string{"doc-work", "doc-2024"}, search(&model.FolderFilter{ID: "f-work", Recursive: true}))
+
+	require.NoError(t, folders.Reorder(ctx, "user-1", "", []string{"f-home", "f-work"}, now))
+	counts, err := folders.CountDocuments(ctx, "user-1")
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"f-work": 1, "f-2024": 1}, counts)
+
+	require.NoError(t, folders.Delete(ctx, "user-1", []string{"f-work", "f-2024"}, ""))
+	list, err := folders.List(ctx, "user-1")
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, "f-home", list[0].ID)
+	require.Equal(t, 0, list[0].Position)
+	moved, err := docs.GetByID(ctx, "user-1", "doc-2024")
+	require.NoError(t, err)
+	require.Equal(t, "", moved.FolderID)
+}
@@ -16,12 +16,15 @@ import (
 var docCols = []string{
 	"id", "user_id", "title", "content", "state", "pinned", "starred",
 	"ctime", "mtime", "content_hash", "content_mtime", "content_revision",
+	"word_count", "char_count", "reading_minutes", "code_block_count",
+	"image_count", "link_count", "task_total", "task_done", "folder_id",
 }
 
 func addDocRow(rows *sqlmock.Rows, id, title string) *sqlmock.Rows {
 	return rows.AddRow(
 		id, "u1", title, "content", 1, 0, 0, int64(1000), int64(2000),
 		"hash-"+id, int64(2000), int64(1),
+		1, 7, 1, 0, 0, 0, 0, 0, "",
 	)
 }
 
@@ -232,6 +235,37 @@ func TestDocumentRepo_TouchMtime(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestDocumentRepo_UpdateStats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewDocumentRepo(db)
+	mock.ExpectExec("UPDATE documents SET .*word_count").WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, r.UpdateStats(context.Background(), "u1", "d1", model.DocumentStats{WordCount: 3}))
+
+	mock.ExpectExec("UPDATE documents").WillReturnResult(sqlmock.NewResult(0, 0))
+	err = r.UpdateStats(context.Background(), "u1", "missing", model.DocumentStats{})
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
+
+func TestDocumentRepo_ListPageAfter(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewDocumentRepo(db)
+	rows := addDocRow(addDocRow(sqlmock.NewRows(docCols), "d2", "A"), "d3", "B")
+	mock.ExpectQuery("SELECT .* FROM documents WHERE \\(id>\\$1\\) ORDER BY id asc LIMIT").
+		WithArgs("d1", 2, 0).
+		WillReturnRows(rows)
+
+	docs, err := r.ListPageAfter(context.Background(), "d1", 2)
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	assert.Equal(t, "d3", docs[1].ID)
+}
+
 func TestDocumentRepo_UpdatePinned(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
@@ -265,7 +299,7 @@ func TestDocumentRepo_SearchLike(t *testing.T) {
 	rows := addDocRow(sqlmock.NewRows(docCols), "d1", "Hello World")
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 
-	docs, err := r.SearchLike(context.Background(), "u1", "Hello", "", nil, 10, 0, "")
+	docs, err := r.SearchLike(context.Background(), "u1", "Hello", "", nil, nil, nil, 10, 0, "")
 	require.NoError(t, err)
 	assert.Len(t, docs, 1)
 }
@@ -306,7 +340,7 @@ func TestDocumentRepo_UpdateLinks(t *testing.T) {
 	mock.ExpectExec("INSERT INTO").WillReturnResult(sqlmock.NewResult(1, 2))
 	mock.ExpectCommit()
 
-	err = r.UpdateLinks(context.Background(), "u1", "d1", []string{"d2", "d3"}, 1000)
+	err = r.UpdateLinks(context.Background(), "u1", "d1", []model.LinkTarget{{ID: "d2"}, {ID: "d3", Anchor: "Intro"}}, 1000)
 	require.NoError(t, err)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
@@ -341,8 +375,11 @@ func TestDocumentRepo_GetBacklinks(t *testing.T) {
 }
 
 func TestBuildLinkInserts(t *testing.T) {
-	inserts := buildLinkInserts("s1", "u1", []string{"t1", "t2", "t1", "s1"}, 1000)
+	inserts := buildLinkInserts("s1", "u1", []model.LinkTarget{
+		{ID: "t1", Anchor: "Intro"}, {ID: "t2"}, {ID: "t1", Anchor: "Other"}, {ID: "s1"},
+	}, 1000)
 	assert.Len(t, inserts, 2)
+	assert.Equal(t, "Intro", inserts[0]["anchor"])
 }
 
 func TestBuildLinkInserts_Empty(t *testing.T) {
@@ -521,7 +558,7 @@ func TestDocumentRepo_SearchLike_QueryError(t *testing.T) {
 
 	r := NewDocumentRepo(db)
 	mock.ExpectQuery("SELECT").WillReturnError(errDB)
-	_, err = r.SearchLike(context.Background(), "u1", "q", "", nil, 10, 0, "")
+	_, err = r.SearchLike(context.Background(), "u1", "q", "", nil, nil, nil, 10, 0, "")
 	assert.Error(t, err)
 }
 
@@ -533,7 +570,7 @@ func TestDocumentRepo_SearchLike_ScanError(t *testing.T) {
 	r := NewDocumentRepo(db)
 	rows := sqlmock.NewRows([]string{"id"}).AddRow("d1")
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
-	_, err = r.SearchLike(context.Background(), "u1", "q", "", nil, 10, 0, "")
+	_, err = r.SearchLike(context.Background(), "u1", "q", "", nil, nil, nil, 10, 0, "")
 	assert.Error(t, err)
 }
 
@@ -546,7 +583,7 @@ func TestDocumentRepo_SearchLike_WithTagAndStarred(t *testing.T) {
 	starred := 1
 	rows := addDocRow(sqlmock.NewRows(docCols), "d1", "Result")
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
-	docs, err := r.SearchLike(context.Background(), "u1", "q", "tag1", &starred, 10, 0, "mtime desc")
+	docs, err := r.SearchLike(context.Background(), "u1", "q", "tag1", &starred, nil, nil, 10, 0, "mtime desc")
 	require.NoError(t, err)
 	assert.Len(t, docs, 1)
 }
@@ -569,7 +606,7 @@ func TestDocumentRepo_UpdateLinks_BeginError(t *testing.T) {
 
 	r := NewDocumentRepo(db)
 	mock.ExpectBegin().WillReturnError(errDB)
-	err = r.UpdateLinks(context.Background(), "u1", "d1", []string{"d2"}, 1000)
+	err = r.UpdateLinks(context.Background(), "u1", "d1", []model.LinkTarget{{ID: "d2"}}, 1000)
 	assert.Error(t, err)
 }
 
@@ -582,7 +619,7 @@ func TestDocumentRepo_UpdateLinks_DeleteError(t *testing.T) {
 	mock.ExpectBegin()
 	mock.ExpectExec("DELETE FROM").WillReturnError(errDB)
 	mock.ExpectRollback()
-	err = r.UpdateLinks(context.Background(), "u1", "d1", []string{"d2"}, 1000)
+	err = r.UpdateLinks(context.Background(), "u1", "d1", []model.LinkTarget{{ID: "d2"}}, 1000)
 	assert.Error(t, err)
 }
 
@@ -596,7 +633,7 @@ func TestDocumentRepo_UpdateLinks_InsertError(t *testing.T) {
 	mock.ExpectExec("DELETE FROM").WillReturnResult(sqlmock.NewResult(0, 0))
 	mock.ExpectExec("INSERT INTO").WillReturnError(errDB)
 	mock.ExpectRollback()
-	err = r.UpdateLinks(context.Background(), "u1", "d1", []string{"d2"}, 1000)
+	err = r.UpdateLinks(context.Background(), "u1", "d1", []model.LinkTarget{{ID: "d2"}}, 1000)
 	assert.Error(t, err)
 }
 
@@ -610,7 +647,7 @@ func TestDocumentRepo_UpdateLinks_CommitError(t *testing.T) {
 	mock.ExpectExec("DELETE FROM").WillReturnResult(sqlmock.NewResult(0, 0))
 	mock.ExpectExec("INSERT INTO").WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit().WillReturnError(errDB)
-	err = r.UpdateLinks(context.Background(), "u1", "d1", []string{"d2"}, 1000)
+	err = r.UpdateLinks(context.Background(), "u1", "d1", []model.LinkTarget{{ID: "d2"}}, 1000)
 	assert.Error(t, err)
 }
 
@@ -730,7 +767,7 @@ func TestDocumentRepo_SearchLike_RowsErr(t *testing.T) {
 	r := NewDocumentRepo(db)
 	rows := addDocRow(sqlmock.NewRows(docCols), "d1", "Doc1").RowError(0, errDB)
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
-	_, err = r.SearchLike(context.Background(), "u1", "test", "", nil, 10, 0, "")
+	_, err = r.SearchLike(context.Background(), "u1", "test", "", nil, nil, nil, 10, 0, "")
 	assert.Error(t, err)
 }
 
@@ -781,7 +818,7 @@ func TestDocumentRepo_SearchLike_WithAllFilters(t *testing.T) {
 	starred := 1
 	rows := addDocRow(sqlmock.NewRows(docCols), "d1", "Doc1")
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
-	docs, err := r.SearchLike(context.Background(), "u1", "test", "t1", &starred, 10, 0, "mtime desc")
+	docs, err := r.SearchLike(context.Background(), "u1", "test", "t1", &starred, nil, nil, 10, 0, "mtime desc")
 	require.NoError(t, err)
 	assert.Len(t, docs, 1)
 }
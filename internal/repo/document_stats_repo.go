@@ -0,0 +1,30 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xxxsen/mnote/internal/model"
+)
+
+const sumDocumentStatsQuery = `SELECT
+        COALESCE(SUM(word_count), 0), COALESCE(SUM(char_count), 0),
+        COALESCE(SUM(reading_minutes), 0), COALESCE(SUM(code_block_count), 0),
+        COALESCE(SUM(image_count), 0), COALESCE(SUM(link_count), 0),
+        COALESCE(SUM(task_total), 0), COALESCE(SUM(task_done), 0),
+        COUNT(1) FILTER (WHERE content_mtime >= $3)
+        FROM documents WHERE user_id = $1 AND state = $2`
+
+// SumStats adds up the statistics of the live documents of a user and counts
+// the documents whose content changed at or after since.
+func (r *DocumentRepo) SumStats(ctx context.Context, userID string, since int64) (*model.DocumentStatsTotals, error) {
+	row := conn(ctx, r.db).QueryRowContext(ctx, sumDocumentStatsQuery, userID, DocumentStateNormal, since)
+	var totals model.DocumentStatsTotals
+	if err := row.Scan(
+		&totals.WordCount, &totals.CharCount, &totals.ReadingMinutes, &totals.CodeBlocks,
+		&totals.Images, &totals.Links, &totals.TasksTotal, &totals.TasksDone, &totals.EditedDocuments,
+	); err != nil {
+		return nil, fmt.Errorf("scan: %w", err)
+	}
+	return &totals, nil
+}
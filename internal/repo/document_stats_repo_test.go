@@ -0,0 +1,75 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+)
+
+func TestDocumentRepo_SumStats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery(`SUM\(word_count\).*FILTER \(WHERE content_mtime >= \$3\)`).
+		WithArgs("u1", DocumentStateNormal, int64(100)).
+		WillReturnRows(sqlmock.NewRows([]string{"w", "c", "r", "cb", "i", "l", "tt", "td", "e"}).
+			AddRow(1200, 5000, 7, 3, 2, 9, 10, 4, 2))
+
+	totals, err := NewDocumentRepo(db).SumStats(context.Background(), "u1", 100)
+	require.NoError(t, err)
+	assert.Equal(t, &model.DocumentStatsTotals{
+		DocumentStats: model.DocumentStats{
+			WordCount: 1200, CharCount: 5000, ReadingMinutes: 7, CodeBlocks: 3,
+			Images: 2, Links: 9, TasksTotal: 10, TasksDone: 4,
+		},
+		EditedDocuments: 2,
+	}, totals)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDocumentRepo_SumStats_Error(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery("FROM documents").WillReturnError(errors.New("db down"))
+	_, err = NewDocumentRepo(db).SumStats(context.Background(), "u1", 0)
+	assert.Error(t, err)
+}
+
+func TestDocumentRepo_Update_WritesStats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectExec(`UPDATE documents SET .*task_done=\$\d+.*word_count=\$\d+`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = NewDocumentRepo(db).Update(context.Background(), &model.Document{
+		ID: "d1", UserID: "u1", Content: "x", Stats: model.DocumentStats{WordCount: 1},
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDocumentRepo_List_OrderByStats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery(`ORDER BY word_count desc, id asc`).
+		WillReturnRows(addDocRow(sqlmock.NewRows(docCols), "d1", "Long"))
+
+	docs, err := NewDocumentRepo(db).List(context.Background(), "u1", nil, 10, 0, "word_count desc")
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	assert.Equal(t, 1, docs[0].Stats.WordCount)
+	assert.Equal(t, 7, docs[0].Stats.CharCount)
+}
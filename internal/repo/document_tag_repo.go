@@ -94,7 +94,25 @@ func (r *DocumentTagRepo) ListTagIDs(ctx context.Context, userID, docID string)
 }
 
 func (r *DocumentTagRepo) ListDocIDsByTag(ctx context.Context, userID, tagID string) ([]string, error) {
-	return r.queryStringColumn(ctx, map[string]any{"user_id": userID, "tag_id": tagID}, "document_id")
+	where := map[string]any{
+		"user_id":      userID,
+		"_custom_tree": builder.Custom("tag_id IN ("+tagSubtreeIDsQuery+")", tagID, userID),
+		"_groupby":     "document_id",
+	}
+	return r.queryStringColumn(ctx, where, "document_id")
+}
+
+// ReassignTag moves every document from one tag to another, skipping
+// documents that already carry the destination tag.
+func (r *DocumentTagRepo) ReassignTag(ctx context.Context, userID, fromTagID, toTagID string) error {
+	sqlStr := "INSERT INTO document_tags (user_id, document_id, tag_id) " +
+		"SELECT user_id, document_id, ? FROM document_tags WHERE user_id = ? AND tag_id = ? " +
+		"ON CONFLICT (user_id, document_id, tag_id) DO NOTHING"
+	sqlStr, args := dbutil.Finalize(sqlStr, []any{toTagID, userID, fromTagID})
+	if _, err := conn(ctx, r.db).ExecContext(ctx, sqlStr, args...); err != nil {
+		return fmt.Errorf("copy document tags: %w", err)
+	}
+	return r.DeleteByTag(ctx, userID, fromTagID)
 }
 
 func (r *DocumentTagRepo) ListByUser(ctx context.Context, userID string) ([]model.DocumentTag, error) {
@@ -83,7 +83,9 @@ func TestDocumentTagRepo_ListDocIDsByTag(t *testing.T) {
 
 	r := NewDocumentTagRepo(db)
 	rows := sqlmock.NewRows([]string{"document_id"}).AddRow("d1").AddRow("d2")
-	mock.ExpectQuery("SELECT").WillReturnRows(rows)
+	mock.ExpectQuery(`tag_id IN \(SELECT c.id FROM tags c JOIN tags p`).
+		WithArgs("t1", "u1", "u1").
+		WillReturnRows(rows)
 
 	ids, err := r.ListDocIDsByTag(context.Background(), "u1", "t1")
 	require.NoError(t, err)
@@ -267,3 +269,31 @@ func TestDocumentTagRepo_ListTagIDsByDocIDs_RowsErr(t *testing.T) {
 	_, err = r.ListTagIDsByDocIDs(context.Background(), "u1", []string{"d1"})
 	assert.Error(t, err)
 }
+
+func TestDocumentTagRepo_ReassignTag(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewDocumentTagRepo(db)
+	mock.ExpectExec("INSERT INTO document_tags .* ON CONFLICT").
+		WithArgs("t2", "u1", "t1").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("DELETE FROM document_tags").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	require.NoError(t, r.ReassignTag(context.Background(), "u1", "t1", "t2"))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDocumentTagRepo_ReassignTag_Error(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewDocumentTagRepo(db)
+	mock.ExpectExec("INSERT INTO document_tags").WillReturnError(errDB)
+
+	err = r.ReassignTag(context.Background(), "u1", "t1", "t2")
+	assert.Error(t, err)
+}
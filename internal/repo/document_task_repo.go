@@ -0,0 +1,122 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/didi/gendry/builder"
+	"github.com/lib/pq"
+
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/dbutil"
+)
+
+// deleteStaleTasksQuery removes the synced todos of a document that are not
+// kept, together with the subtasks added to them by hand.
+const deleteStaleTasksQuery = `
+	WITH removed AS (
+		DELETE FROM todos
+		WHERE user_id = $1 AND document_id = $2 AND source_anchor <> '' AND NOT (id = ANY($3))
+		RETURNING id
+	)
+	DELETE FROM todos WHERE user_id = $1 AND parent_id IN (SELECT id FROM removed)
+`
+
+const upsertTasksSuffix = ` ON CONFLICT (id) DO UPDATE SET
+	content = EXCLUDED.content, due_date = EXCLUDED.due_date, done = EXCLUDED.done,
+	source_anchor = EXCLUDED.source_anchor, source_line = EXCLUDED.source_line, mtime = EXCLUDED.mtime`
+
+// ListTasks returns the todos synced from the task list items of docID in
+// document order.
+func (r *DocumentRepo) ListTasks(ctx context.Context, userID, docID string) ([]model.Todo, error) {
+	where := map[string]any{
+		"user_id":          userID,
+		"document_id":      docID,
+		"source_anchor !=": "",
+		"_orderby":         "source_line asc",
+	}
+	sqlStr, args, err := builder.BuildSelect("todos", where, todoColumns)
+	if err != nil {
+		return nil, fmt.Errorf("build select: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	items := make([]model.Todo, 0)
+	for rows.Next() {
+		todo, err := scanTodo(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		items = append(items, *todo)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows: %w", err)
+	}
+	return items, nil
+}
+
+// ReplaceTasks makes tasks the synced todos of docID. Todos missing from
+// tasks are deleted; the others are inserted or, when their ID exists,
+// updated in the fields the document owns, so priority and subtasks set on
+// the todo list survive.
+func (r *DocumentRepo) ReplaceTasks(ctx context.Context, userID, docID string, tasks []model.Todo) error {
+	tx, owned, err := beginOrJoin(ctx, r.db)
+	if err != nil {
+		return fmt.Errorf("repo: %w", err)
+	}
+	if owned {
+		defer func() { _ = tx.Rollback() }()
+	}
+	keep := make([]string, 0, len(tasks))
+	for _, task := range tasks {
+		keep = append(keep, task.ID)
+	}
+	if _, err := tx.ExecContext(ctx, deleteStaleTasksQuery, userID, docID, pq.Array(keep)); err != nil {
+		return fmt.Errorf("delete stale tasks: %w", err)
+	}
+	if inserts := buildTaskInserts(userID, docID, tasks); len(inserts) > 0 {
+		insertSQL, insertArgs, err := builder.BuildInsert("todos", inserts)
+		if err != nil {
+			return fmt.Errorf("build insert: %w", err)
+		}
+		insertSQL, insertArgs = dbutil.Finalize(insertSQL+upsertTasksSuffix, insertArgs)
+		if _, err := tx.ExecContext(ctx, insertSQL, insertArgs...); err != nil {
+			return fmt.Errorf("upsert tasks: %w", err)
+		}
+	}
+	if owned {
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("commit: %w", err)
+		}
+	}
+	return nil
+}
+
+func buildTaskInserts(userID, docID string, tasks []model.Todo) []map[string]any {
+	inserts := make([]map[string]any, 0, len(tasks))
+	for _, task := range tasks {
+		inserts = append(inserts, map[string]any{
+			"id":              task.ID,
+			"user_id":         userID,
+			"content":         task.Content,
+			"due_date":        task.DueDate,
+			"done":            task.Done,
+			"priority":        task.Priority,
+			"document_id":     docID,
+			"parent_id":       "",
+			"recurrence_json": "",
+			"series_id":       "",
+			"occurrence":      0,
+			"next_id":         "",
+			"source_anchor":   task.SourceAnchor,
+			"source_line":     task.SourceLine,
+			"ctime":           task.Ctime,
+			"mtime":           task.Mtime,
+		})
+	}
+	return inserts
+}
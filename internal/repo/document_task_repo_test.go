@@ -0,0 +1,73 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+)
+
+func TestDocumentRepo_ReplaceTasks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("WITH removed AS").WithArgs("u1", "d1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO todos .* ON CONFLICT \(id\) DO UPDATE SET`).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	err = NewDocumentRepo(db).ReplaceTasks(context.Background(), "u1", "d1", []model.Todo{
+		{ID: "t1", Content: "write", SourceAnchor: "a1", SourceLine: 3},
+		{ID: "t2", Content: "ship", Done: 1, SourceAnchor: "a2", SourceLine: 4},
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDocumentRepo_ReplaceTasks_Empty(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("WITH removed AS").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	require.NoError(t, NewDocumentRepo(db).ReplaceTasks(context.Background(), "u1", "d1", nil))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestBuildTaskInserts(t *testing.T) {
+	inserts := buildTaskInserts("u1", "d1", []model.Todo{
+		{ID: "t1", Content: "write", DueDate: "2025-11-01", Priority: 2, SourceAnchor: "a1", SourceLine: 3},
+	})
+	require.Len(t, inserts, 1)
+	assert.Equal(t, "d1", inserts[0]["document_id"])
+	assert.Equal(t, "a1", inserts[0]["source_anchor"])
+	assert.Equal(t, 3, inserts[0]["source_line"])
+	assert.Equal(t, "", inserts[0]["parent_id"])
+}
+
+func TestDocumentRepo_ListTasks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery(`FROM todos WHERE .*source_anchor!=\$\d+.* ORDER BY source_line asc`).
+		WillReturnRows(sqlmock.NewRows(todoColumns).
+			AddRow("t1", "u1", "write", "", 0, 0, "d1", "", "", "", 0, "", "a1", 3, int64(1000), int64(2000)))
+
+	tasks, err := NewDocumentRepo(db).ListTasks(context.Background(), "u1", "d1")
+	require.NoError(t, err)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, "a1", tasks[0].SourceAnchor)
+	assert.Equal(t, 3, tasks[0].SourceLine)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,183 @@
+package repo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/didi/gendry/builder"
+	"github.com/lib/pq"
+
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/dbutil"
+)
+
+// A document matches a key through its title or one of its aliases; a
+// document matching the same key both ways is returned once, as a title.
+const listDocumentsByTitleKeysQuery = `
+	SELECT doc.id, doc.title, doc.mtime, name.key, bool_and(name.alias)
+	FROM (
+		SELECT id AS document_id, lower(btrim(title)) AS key, false AS alias
+		FROM documents
+		WHERE user_id = $1 AND lower(btrim(title)) = ANY($3::text[])
+		UNION ALL
+		SELECT document_id, lower(btrim(value)), true
+		FROM document_properties
+		WHERE user_id = $1 AND key = $4 AND lower(btrim(value)) = ANY($3::text[])
+	) AS name
+	JOIN documents AS doc
+	  ON doc.id = name.document_id
+	 AND doc.user_id = $1
+	 AND doc.state = $2
+	GROUP BY doc.id, doc.title, doc.mtime, name.key
+	ORDER BY doc.mtime DESC, doc.id, name.key
+`
+
+const listWikilinksQuery = `
+	SELECT source_id, title, title_key, anchor, status, target_id, ctime
+	FROM document_wikilinks
+	WHERE user_id = $1 AND source_id = $2
+	ORDER BY title_key, anchor
+`
+
+// Sources match either one of the title keys or, for a non-empty $3, a
+// wikilink currently resolved to that document.
+const listWikilinkSourcesQuery = `
+	SELECT DISTINCT link.source_id
+	FROM document_wikilinks AS link
+	JOIN documents AS source
+	  ON source.id = link.source_id
+	 AND source.user_id = link.user_id
+	 AND source.state = $4
+	WHERE link.user_id = $1
+	  AND (link.title_key = ANY($2::text[]) OR ($3 <> '' AND link.target_id = $3))
+	ORDER BY link.source_id
+`
+
+// ListByTitleKeys returns the live documents whose trimmed, lower-cased title
+// or alias is one of keys, newest first, once per matching key.
+func (r *DocumentRepo) ListByTitleKeys(
+	ctx context.Context, userID string, keys []string,
+) ([]model.DocumentTitle, error) {
+	items := make([]model.DocumentTitle, 0)
+	if len(keys) == 0 {
+		return items, nil
+	}
+	rows, err := conn(ctx, r.db).QueryContext(ctx, listDocumentsByTitleKeysQuery,
+		userID, DocumentStateNormal, pq.Array(keys), model.PropertyKeyAliases)
+	if err != nil {
+		return nil, fmt.Errorf("query documents by title: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var item model.DocumentTitle
+		if err := rows.Scan(&item.ID, &item.Title, &item.Mtime, &item.Key, &item.Alias); err != nil {
+			return nil, fmt.Errorf("scan document title: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate document titles: %w", err)
+	}
+	return items, nil
+}
+
+// ReplaceWikilinks swaps the recorded wikilinks of sourceID for links.
+func (r *DocumentRepo) ReplaceWikilinks(
+	ctx context.Context, userID, sourceID string, links []model.DocumentWikilink,
+) error {
+	tx, owned, err := beginOrJoin(ctx, r.db)
+	if err != nil {
+		return fmt.Errorf("repo: %w", err)
+	}
+	if owned {
+		defer func() { _ = tx.Rollback() }()
+	}
+	delSQL, delArgs, err := builder.BuildDelete("document_wikilinks", map[string]any{
+		"source_id": sourceID,
+		"user_id":   userID,
+	})
+	if err != nil {
+		return fmt.Errorf("build delete: %w", err)
+	}
+	delSQL, delArgs = dbutil.Finalize(delSQL, delArgs)
+	if _, err := tx.ExecContext(ctx, delSQL, delArgs...); err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	if len(links) > 0 {
+		inserts := make([]map[string]any, 0, len(links))
+		for _, link := range links {
+			inserts = append(inserts, map[string]any{
+				"source_id": sourceID,
+				"user_id":   userID,
+				"title":     link.Title,
+				"title_key": link.TitleKey,
+				"anchor":    link.Anchor,
+				"status":    link.Status,
+				"target_id": link.TargetID,
+				"ctime":     link.Ctime,
+			})
+		}
+		insertSQL, insertArgs, err := builder.BuildInsert("document_wikilinks", inserts)
+		if err != nil {
+			return fmt.Errorf("build insert: %w", err)
+		}
+		insertSQL, insertArgs = dbutil.Finalize(insertSQL, insertArgs)
+		if _, err := tx.ExecContext(ctx, insertSQL, insertArgs...); err != nil {
+			return fmt.Errorf("exec: %w", err)
+		}
+	}
+	if owned {
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("commit: %w", err)
+		}
+	}
+	return nil
+}
+
+// ListWikilinks returns the recorded wikilinks of a document.
+func (r *DocumentRepo) ListWikilinks(ctx context.Context, userID, sourceID string) ([]model.DocumentWikilink, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, listWikilinksQuery, userID, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("query wikilinks: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	items := make([]model.DocumentWikilink, 0)
+	for rows.Next() {
+		var item model.DocumentWikilink
+		if err := rows.Scan(&item.SourceID, &item.Title, &item.TitleKey, &item.Anchor,
+			&item.Status, &item.TargetID, &item.Ctime); err != nil {
+			return nil, fmt.Errorf("scan wikilink: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate wikilinks: %w", err)
+	}
+	return items, nil
+}
+
+// ListWikilinkSources returns the live documents holding a wikilink to one of
+// titleKeys or resolved to targetID, the documents whose wikilinks may
+// resolve differently once a title appears, changes or disappears.
+func (r *DocumentRepo) ListWikilinkSources(
+	ctx context.Context, userID string, titleKeys []string, targetID string,
+) ([]string, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, listWikilinkSourcesQuery,
+		userID, pq.Array(titleKeys), targetID, DocumentStateNormal)
+	if err != nil {
+		return nil, fmt.Errorf("query wikilink sources: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	ids := make([]string, 0)
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan wikilink source: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate wikilink sources: %w", err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,123 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+)
+
+func TestDocumentRepo_ListByTitleKeys(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery(`lower\(btrim\(title\)\) = ANY(?s).*FROM document_properties`).
+		WithArgs("u1", DocumentStateNormal, pq.Array([]string{"alpha", "al"}), model.PropertyKeyAliases).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "title", "mtime", "key", "alias"}).
+			AddRow("d1", "Alpha", 10, "alpha", false).
+			AddRow("d1", "Alpha", 10, "al", true))
+
+	items, err := NewDocumentRepo(db).ListByTitleKeys(context.Background(), "u1", []string{"alpha", "al"})
+	require.NoError(t, err)
+	assert.Equal(t, []model.DocumentTitle{
+		{ID: "d1", Title: "Alpha", Mtime: 10, Key: "alpha"},
+		{ID: "d1", Title: "Alpha", Mtime: 10, Key: "al", Alias: true},
+	}, items)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDocumentRepo_ListByTitleKeys_Empty(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	items, err := NewDocumentRepo(db).ListByTitleKeys(context.Background(), "u1", nil)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDocumentRepo_ReplaceWikilinks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM document_wikilinks").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO document_wikilinks").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err = NewDocumentRepo(db).ReplaceWikilinks(context.Background(), "u1", "d1", []model.DocumentWikilink{
+		{Title: "Alpha", TitleKey: "alpha", Status: model.WikilinkStatusMissing, Ctime: 10},
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDocumentRepo_ReplaceWikilinks_InsertError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM document_wikilinks").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO document_wikilinks").WillReturnError(errDB)
+	mock.ExpectRollback()
+
+	err = NewDocumentRepo(db).ReplaceWikilinks(context.Background(), "u1", "d1", []model.DocumentWikilink{
+		{Title: "Alpha", TitleKey: "alpha", Status: model.WikilinkStatusMissing},
+	})
+	require.ErrorIs(t, err, errDB)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDocumentRepo_ListWikilinks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery("FROM document_wikilinks").
+		WithArgs("u1", "d1").
+		WillReturnRows(sqlmock.NewRows([]string{"source_id", "title", "title_key", "anchor", "status", "target_id", "ctime"}).
+			AddRow("d1", "Alpha", "alpha", "Intro", "resolved", "d2", 10))
+
+	items, err := NewDocumentRepo(db).ListWikilinks(context.Background(), "u1", "d1")
+	require.NoError(t, err)
+	assert.Equal(t, []model.DocumentWikilink{{
+		SourceID: "d1", Title: "Alpha", TitleKey: "alpha", Anchor: "Intro",
+		Status: model.WikilinkStatusResolved, TargetID: "d2", Ctime: 10,
+	}}, items)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDocumentRepo_ListWikilinkSources(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery("SELECT DISTINCT link.source_id").
+		WithArgs("u1", pq.Array([]string{"alpha"}), "d9", DocumentStateNormal).
+		WillReturnRows(sqlmock.NewRows([]string{"source_id"}).AddRow("d1").AddRow("d2"))
+
+	ids, err := NewDocumentRepo(db).ListWikilinkSources(context.Background(), "u1", []string{"alpha"}, "d9")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d1", "d2"}, ids)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestDocumentRepo_ListWikilinkSources_QueryError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery("SELECT DISTINCT link.source_id").WillReturnError(errDB)
+
+	_, err = NewDocumentRepo(db).ListWikilinkSources(context.Background(), "u1", nil, "d9")
+	require.ErrorIs(t, err, errDB)
+}
@@ -222,6 +222,37 @@ func TestEmbeddingV2RepoIntegration_FencedCompletionAndActiveSearch(t *testing.T
 	require.Len(t, similar, 1)
 	assert.Equal(t, documents[1].ID, similar[0].DocumentID)
 
+	_, pairs, err := embeddings.SimilarDocumentPairs(
+		ctx,
+		documents[0].UserID,
+		[]string{documents[0].ID},
+		5,
+	)
+	require.NoError(t, err)
+	require.Len(t, pairs, 1)
+	assert.Equal(t, model.SimilarDocumentPair{
+		SourceID: documents[0].ID, TargetID: documents[1].ID, Score: similar[0].Score,
+	}, pairs[0])
+
+	tagID := fmt.Sprintf("embedding-v2-tag-%d", time.Now().UnixNano())
+	require.NoError(t, NewTagRepo(db).Create(ctx, &model.Tag{
+		ID: tagID, UserID: documents[1].UserID, Name: tagID, Ctime: now, Mtime: now,
+	}))
+	require.NoError(t, NewDocumentTagRepo(db).Add(ctx, &model.DocumentTag{
+		UserID: documents[1].UserID, DocumentID: documents[1].ID, TagID: tagID,
+	}))
+	_, similarTags, indexed, err := embeddings.SimilarTagCentroids(
+		ctx,
+		documents[0].UserID,
+		documents[0].ID,
+		5,
+	)
+	require.NoError(t, err)
+	assert.True(t, indexed)
+	require.Len(t, similarTags, 1)
+	assert.Equal(t, tagID, similarTags[0].TagID)
+	assert.Equal(t, 1, similarTags[0].DocumentCount)
+
 	firstDocument := documents[0]
 	firstDocument.Title = "Alpha changed"
 	firstDocument.ContentHash = dochash.Compute(firstDocument.Title, firstDocument.Content)
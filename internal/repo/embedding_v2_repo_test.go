@@ -303,4 +303,6 @@ func TestEmbeddingV2Helpers(t *testing.T) {
 	assert.Contains(t, preciseEmbeddingSearchQuery("vector(384)", 384), "WITH scored")
 	assert.Contains(t, hnswEmbeddingSearchQuery("vector(384)", 384), "AS closer")
 	assert.Contains(t, similarCentroidSearchQuery("vector(384)", 384), "centroid")
+	assert.Contains(t, similarTagCentroidQuery("vector(384)", 384), "GROUP BY tag.tag_id")
+	assert.Contains(t, similarDocumentPairsQuery("vector(384)", 384), "CROSS JOIN LATERAL")
 }
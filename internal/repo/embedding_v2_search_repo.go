@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 
+	"github.com/lib/pq"
 	"github.com/pgvector/pgvector-go"
 
 	"github.com/xxxsen/mnote/internal/model"
@@ -386,6 +387,116 @@ func (r *EmbeddingV2Repo) SimilarDocuments(
 	return generation, results, true, nil
 }
 
+// SimilarTagCentroids ranks the user's tags by the cosine similarity between
+// the source document centroid and each tag centroid, where a tag centroid is
+// the mean of the current centroids of its other documents.
+func (r *EmbeddingV2Repo) SimilarTagCentroids(
+	ctx context.Context,
+	userID, documentID string,
+	limit int,
+) (*model.EmbeddingGeneration, []model.SimilarTagResult, bool, error) {
+	generation, profile, err := r.GetActiveGeneration(ctx)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	vectorCast, err := vectorDimensionCast(profile.Dimensions)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	centroid, current, err := r.sourceEmbeddingCentroid(
+		ctx,
+		generation.ID,
+		userID,
+		documentID,
+		profile.Dimensions,
+	)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	if !current || limit <= 0 {
+		return generation, []model.SimilarTagResult{}, current, nil
+	}
+	rows, err := conn(ctx, r.db).QueryContext(
+		ctx,
+		similarTagCentroidQuery(vectorCast, profile.Dimensions),
+		generation.ID,
+		userID,
+		documentID,
+		centroid,
+		DocumentStateNormal,
+		limit,
+	)
+	if err != nil {
+		return nil, nil, false, fmt.Errorf("search similar tags: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	results := make([]model.SimilarTagResult, 0, limit)
+	for rows.Next() {
+		var result model.SimilarTagResult
+		if err := rows.Scan(&result.TagID, &result.Score, &result.DocumentCount); err != nil {
+			return nil, nil, false, fmt.Errorf("scan similar tag: %w", err)
+		}
+		results = append(results, result)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, false, fmt.Errorf("iterate similar tags: %w", err)
+	}
+	if err := r.ensureActiveGeneration(ctx, generation.ID); err != nil {
+		return nil, nil, false, err
+	}
+	return generation, results, true, nil
+}
+
+// SimilarDocumentPairs returns, for each of documentIDs with a current
+// centroid, its perDocument nearest neighbours among the user's current
+// centroids, ordered by source and then by descending score.
+func (r *EmbeddingV2Repo) SimilarDocumentPairs(
+	ctx context.Context,
+	userID string,
+	documentIDs []string,
+	perDocument int,
+) (*model.EmbeddingGeneration, []model.SimilarDocumentPair, error) {
+	generation, profile, err := r.GetActiveGeneration(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(documentIDs) == 0 || perDocument <= 0 {
+		return generation, []model.SimilarDocumentPair{}, nil
+	}
+	vectorCast, err := vectorDimensionCast(profile.Dimensions)
+	if err != nil {
+		return nil, nil, err
+	}
+	rows, err := conn(ctx, r.db).QueryContext(
+		ctx,
+		similarDocumentPairsQuery(vectorCast, profile.Dimensions),
+		generation.ID,
+		userID,
+		DocumentStateNormal,
+		pq.Array(documentIDs),
+		perDocument,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("search similar document pairs: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	pairs := make([]model.SimilarDocumentPair, 0, len(documentIDs)*perDocument)
+	for rows.Next() {
+		var pair model.SimilarDocumentPair
+		if err := rows.Scan(&pair.SourceID, &pair.TargetID, &pair.Score); err != nil {
+			return nil, nil, fmt.Errorf("scan similar document pair: %w", err)
+		}
+		pairs = append(pairs, pair)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf("iterate similar document pairs: %w", err)
+	}
+	if err := r.ensureActiveGeneration(ctx, generation.ID); err != nil {
+		return nil, nil, err
+	}
+	return generation, pairs, nil
+}
+
 func (r *EmbeddingV2Repo) sourceEmbeddingCentroid(
 	ctx context.Context,
 	generationID, userID, documentID string,
@@ -493,3 +604,81 @@ func similarCentroidSearchQuery(vectorCast string, dimensions int) string {
 		LIMIT $6
 	`, vectorCast, dimensions)
 }
+
+func similarTagCentroidQuery(vectorCast string, dimensions int) string {
+	return fmt.Sprintf(`
+		SELECT
+			tag.tag_id,
+			GREATEST(
+				-1::double precision,
+				LEAST(
+					1::double precision,
+					1 - (AVG(index.centroid::%[1]s) <=> $4::%[1]s)
+				)
+			)::real AS score,
+			COUNT(*) AS document_count
+		FROM document_embedding_indexes AS index
+		JOIN documents AS document
+		  ON document.id = index.document_id
+		 AND document.user_id = index.user_id
+		JOIN embedding_jobs AS job
+		  ON job.generation_id = index.generation_id
+		 AND job.document_id = index.document_id
+		 AND job.user_id = index.user_id
+		JOIN document_tags AS tag
+		  ON tag.document_id = index.document_id
+		 AND tag.user_id = index.user_id
+		WHERE index.generation_id = $1::uuid
+		  AND index.user_id = $2
+		  AND index.document_id <> $3
+		  AND index.dimensions = %[2]d
+		  AND index.centroid IS NOT NULL
+		  AND job.status = 'succeeded'
+		  AND job.desired_content_hash = index.indexed_content_hash
+		  AND index.indexed_content_hash = document.content_hash
+		  AND document.state = $5
+		GROUP BY tag.tag_id
+		ORDER BY score DESC, tag.tag_id
+		LIMIT $6
+	`, vectorCast, dimensions)
+}
+
+func similarDocumentPairsQuery(vectorCast string, dimensions int) string {
+	return fmt.Sprintf(`
+		WITH current_index AS (
+			SELECT index.document_id, index.centroid::%[1]s AS centroid
+			FROM document_embedding_indexes AS index
+			JOIN documents AS document
+			  ON document.id = index.document_id
+			 AND document.user_id = index.user_id
+			JOIN embedding_jobs AS job
+			  ON job.generation_id = index.generation_id
+			 AND job.document_id = index.document_id
+			 AND job.user_id = index.user_id
+			WHERE index.generation_id = $1::uuid
+			  AND index.user_id = $2
+			  AND index.dimensions = %[2]d
+			  AND index.centroid IS NOT NULL
+			  AND job.status = 'succeeded'
+			  AND job.desired_content_hash = index.indexed_content_hash
+			  AND index.indexed_content_hash = document.content_hash
+			  AND document.state = $3
+		)
+		SELECT source.document_id, neighbour.document_id, neighbour.score
+		FROM current_index AS source
+		CROSS JOIN LATERAL (
+			SELECT
+				other.document_id,
+				GREATEST(
+					-1::double precision,
+					LEAST(1::double precision, 1 - (other.centroid <=> source.centroid))
+				)::real AS score
+			FROM current_index AS other
+			WHERE other.document_id <> source.document_id
+			ORDER BY other.centroid <=> source.centroid, other.document_id
+			LIMIT $5
+		) AS neighbour
+		WHERE source.document_id = ANY($4::text[])
+		ORDER BY source.document_id, neighbour.score DESC, neighbour.document_id
+	`, vectorCast, dimensions)
+}
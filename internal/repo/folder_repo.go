@@ -0,0 +1,289 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/didi/gendry/builder"
+	"github.com/lib/pq"
+
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/dbutil"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+var folderColumns = []string{"id", "user_id", "parent_id", "name", "position", "ctime", "mtime"}
+
+// folderSubtreeIDsQuery selects the IDs of a folder and of every folder below
+// it; it takes the user ID, the folder ID and the user ID again as arguments.
+const folderSubtreeIDsQuery = "WITH RECURSIVE subtree AS (" +
+	"SELECT id FROM folders WHERE user_id = ? AND id = ? " +
+	"UNION ALL SELECT f.id FROM folders f JOIN subtree s ON f.parent_id = s.id WHERE f.user_id = ?" +
+	") SELECT id FROM subtree"
+
+// reorderFoldersQuery numbers the listed children of a parent from 0 in the
+// order given.
+const reorderFoldersQuery = `
+	UPDATE folders f SET position = o.ord - 1, mtime = $4
+	FROM unnest($3::text[]) WITH ORDINALITY AS o(id, ord)
+	WHERE f.user_id = $1 AND f.parent_id = $2 AND f.id = o.id
+`
+
+const countFolderDocumentsQuery = `
+	SELECT folder_id, COUNT(1) FROM documents
+	WHERE user_id = $1 AND state = $2 AND folder_id <> ''
+	GROUP BY folder_id
+`
+
+func scanFolder(scanner interface{ Scan(...any) error }) (model.Folder, error) {
+	var folder model.Folder
+	if err := scanner.Scan(
+		&folder.ID, &folder.UserID, &folder.ParentID, &folder.Name, &folder.Position,
+		&folder.Ctime, &folder.Mtime,
+	); err != nil {
+		return model.Folder{}, fmt.Errorf("scan: %w", err)
+	}
+	return folder, nil
+}
+
+// applyFolderFilter narrows a documents query to the folder of filter. The
+// top level taken recursively is every document, so it adds no condition.
+func applyFolderFilter(where map[string]any, userID string, filter *model.FolderFilter) {
+	switch {
+	case filter == nil:
+	case !filter.Recursive:
+		where["folder_id"] = filter.ID
+	case filter.ID != "":
+		where["_custom_folder"] = builder.Custom(
+			"folder_id IN ("+folderSubtreeIDsQuery+")", userID, filter.ID, userID,
+		)
+	}
+}
+
+type FolderRepo struct {
+	db *sql.DB
+}
+
+func NewFolderRepo(db *sql.DB) *FolderRepo {
+	return &FolderRepo{db: db}
+}
+
+func (r *FolderRepo) Create(ctx context.Context, folder *model.Folder) error {
+	return insertRecord(ctx, r.db, "folders", map[string]any{
+		"id":        folder.ID,
+		"user_id":   folder.UserID,
+		"parent_id": folder.ParentID,
+		"name":      folder.Name,
+		"position":  folder.Position,
+		"ctime":     folder.Ctime,
+		"mtime":     folder.Mtime,
+	})
+}
+
+// CreateIfAbsent inserts folder unless a sibling already has its name, and
+// reports whether it was inserted. Unlike Create, a taken name does not abort
+// the surrounding transaction; when the sibling is still being inserted by
+// another transaction it waits for that one to finish.
+func (r *FolderRepo) CreateIfAbsent(ctx context.Context, folder *model.Folder) (bool, error) {
+	const query = `
+		INSERT INTO folders (id, user_id, parent_id, name, position, ctime, mtime)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT DO NOTHING
+	`
+	affected, err := dbutil.ExecAffected(ctx, conn(ctx, r.db), query, []any{
+		folder.ID, folder.UserID, folder.ParentID, folder.Name, folder.Position, folder.Ctime, folder.Mtime,
+	})
+	if err != nil {
+		return false, fmt.Errorf("insert folder: %w", err)
+	}
+	return affected == 1, nil
+}
+
+// GetByName returns the child of parentID called name, compared
+// case-insensitively like the unique index on folder names.
+func (r *FolderRepo) GetByName(ctx context.Context, userID, parentID, name string) (*model.Folder, error) {
+	sqlStr, args, err := builder.BuildSelect("folders", map[string]any{
+		"user_id":      userID,
+		"parent_id":    parentID,
+		"_custom_name": builder.Custom("lower(name) = lower(?)", name),
+	}, folderColumns)
+	if err != nil {
+		return nil, fmt.Errorf("build select: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	folder, err := scanFolder(conn(ctx, r.db).QueryRowContext(ctx, sqlStr, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, appErr.ErrNotFound
+		}
+		return nil, err
+	}
+	return &folder, nil
+}
+
+func (r *FolderRepo) GetByID(ctx context.Context, userID, folderID string) (*model.Folder, error) {
+	sqlStr, args, err := builder.BuildSelect("folders", map[string]any{
+		"id":      folderID,
+		"user_id": userID,
+	}, folderColumns)
+	if err != nil {
+		return nil, fmt.Errorf("build select: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	folder, err := scanFolder(conn(ctx, r.db).QueryRowContext(ctx, sqlStr, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, appErr.ErrNotFound
+		}
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// List returns every folder of a user, siblings in position order.
+func (r *FolderRepo) List(ctx context.Context, userID string) ([]model.Folder, error) {
+	sqlStr, args, err := builder.BuildSelect("folders", map[string]any{
+		"user_id":  userID,
+		"_orderby": "parent_id asc, position asc, lower(name) asc, id asc",
+	}, folderColumns)
+	if err != nil {
+		return nil, fmt.Errorf("build select: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	folders := make([]model.Folder, 0)
+	for rows.Next() {
+		folder, err := scanFolder(rows)
+		if err != nil {
+			return nil, err
+		}
+		folders = append(folders, folder)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+	return folders, nil
+}
+
+// Update writes the name, parent and position of a folder. A name taken by a
+// sibling is a conflict.
+func (r *FolderRepo) Update(ctx context.Context, folder *model.Folder) error {
+	sqlStr, args, err := builder.BuildUpdate("folders", map[string]any{
+		"id":      folder.ID,
+		"user_id": folder.UserID,
+	}, map[string]any{
+		"parent_id": folder.ParentID,
+		"name":      folder.Name,
+		"position":  folder.Position,
+		"mtime":     folder.Mtime,
+	})
+	if err != nil {
+		return fmt.Errorf("build update: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	result, err := conn(ctx, r.db).ExecContext(ctx, sqlStr, args...)
+	if err != nil {
+		if dbutil.IsConflict(err) {
+			return appErr.ErrConflict
+		}
+		return fmt.Errorf("exec: %w", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	if affected == 0 {
+		return appErr.ErrNotFound
+	}
+	return nil
+}
+
+// Reorder sets the positions of the children of parentID to their order in
+// folderIDs.
+func (r *FolderRepo) Reorder(ctx context.Context, userID, parentID string, folderIDs []string, mtime int64) error {
+	if len(folderIDs) == 0 {
+		return nil
+	}
+	if _, err := conn(ctx, r.db).ExecContext(
+		ctx, reorderFoldersQuery, userID, parentID, pq.Array(folderIDs), mtime,
+	); err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	return nil
+}
+
+// Delete removes the given folders and files the documents they held under
+// parentID, all in one transaction.
+func (r *FolderRepo) Delete(ctx context.Context, userID string, folderIDs []string, parentID string) error {
+	if len(folderIDs) == 0 {
+		return nil
+	}
+	ids := make([]any, 0, len(folderIDs))
+	for _, id := range folderIDs {
+		ids = append(ids, id)
+	}
+	tx, owned, err := beginOrJoin(ctx, r.db)
+	if err != nil {
+		return fmt.Errorf("repo: %w", err)
+	}
+	if owned {
+		defer func() { _ = tx.Rollback() }()
+	}
+	moveSQL, moveArgs, err := builder.BuildUpdate("documents", map[string]any{
+		"user_id":         userID,
+		"_custom_folders": builder.In{"folder_id": ids},
+	}, map[string]any{"folder_id": parentID})
+	if err != nil {
+		return fmt.Errorf("build update: %w", err)
+	}
+	moveSQL, moveArgs = dbutil.Finalize(moveSQL, moveArgs)
+	if _, err := tx.ExecContext(ctx, moveSQL, moveArgs...); err != nil {
+		return fmt.Errorf("move documents: %w", err)
+	}
+	delSQL, delArgs, err := builder.BuildDelete("folders", map[string]any{
+		"user_id":     userID,
+		"_custom_ids": builder.In{"id": ids},
+	})
+	if err != nil {
+		return fmt.Errorf("build delete: %w", err)
+	}
+	delSQL, delArgs = dbutil.Finalize(delSQL, delArgs)
+	if _, err := tx.ExecContext(ctx, delSQL, delArgs...); err != nil {
+		return fmt.Errorf("delete folders: %w", err)
+	}
+	if owned {
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("commit: %w", err)
+		}
+	}
+	return nil
+}
+
+// CountDocuments returns the number of documents filed directly in each
+// folder of a user. Folders without documents are absent.
+func (r *FolderRepo) CountDocuments(ctx context.Context, userID string) (map[string]int, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, countFolderDocumentsQuery, userID, DocumentStateNormal)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	counts := make(map[string]int)
+	for rows.Next() {
+		var folderID string
+		var count int
+		if err := rows.Scan(&folderID, &count); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		counts[folderID] = count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+	return counts, nil
+}
@@ -0,0 +1,169 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+var folderCols = []string{"id", "user_id", "parent_id", "name", "position", "ctime", "mtime"}
+
+func TestFolderRepo_Create_Conflict(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewFolderRepo(db)
+	mock.ExpectExec("INSERT INTO").WillReturnError(errConflictStub)
+
+	err = r.Create(context.Background(), &model.Folder{ID: "f1", UserID: "u1", Name: "Work"})
+	assert.ErrorIs(t, err, appErr.ErrConflict)
+}
+
+func TestFolderRepo_CreateIfAbsent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewFolderRepo(db)
+	mock.ExpectExec("INSERT INTO folders .+ ON CONFLICT DO NOTHING").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO folders .+ ON CONFLICT DO NOTHING").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	created, err := r.CreateIfAbsent(context.Background(), &model.Folder{ID: "f1", UserID: "u1", Name: "Work"})
+	require.NoError(t, err)
+	assert.True(t, created)
+	created, err = r.CreateIfAbsent(context.Background(), &model.Folder{ID: "f2", UserID: "u1", Name: "work"})
+	require.NoError(t, err)
+	assert.False(t, created)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFolderRepo_GetByName(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewFolderRepo(db)
+	rows := sqlmock.NewRows(folderCols).AddRow("f1", "u1", "", "Work", 0, int64(1000), int64(1000))
+	mock.ExpectQuery("SELECT .+ FROM folders .+lower\\(name\\) = lower\\(").WillReturnRows(rows)
+	mock.ExpectQuery("SELECT .+ FROM folders").WillReturnRows(sqlmock.NewRows(folderCols))
+
+	folder, err := r.GetByName(context.Background(), "u1", "", "work")
+	require.NoError(t, err)
+	assert.Equal(t, "f1", folder.ID)
+	_, err = r.GetByName(context.Background(), "u1", "", "missing")
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
+
+func TestFolderRepo_GetByID_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewFolderRepo(db)
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows(folderCols))
+
+	_, err = r.GetByID(context.Background(), "u1", "f1")
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
+
+func TestFolderRepo_List(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewFolderRepo(db)
+	rows := sqlmock.NewRows(folderCols).
+		AddRow("f1", "u1", "", "Work", 0, int64(1000), int64(1000)).
+		AddRow("f2", "u1", "f1", "2024", 0, int64(1000), int64(1000))
+	mock.ExpectQuery("SELECT .+ FROM folders .+ ORDER BY parent_id asc, position asc").WillReturnRows(rows)
+
+	folders, err := r.List(context.Background(), "u1")
+	require.NoError(t, err)
+	require.Len(t, folders, 2)
+	assert.Equal(t, "f1", folders[1].ParentID)
+}
+
+func TestFolderRepo_Update(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewFolderRepo(db)
+	mock.ExpectExec("UPDATE folders").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("UPDATE folders").WillReturnError(errConflictStub)
+
+	folder := &model.Folder{ID: "f1", UserID: "u1", Name: "Work"}
+	assert.ErrorIs(t, r.Update(context.Background(), folder), appErr.ErrNotFound)
+	assert.ErrorIs(t, r.Update(context.Background(), folder), appErr.ErrConflict)
+}
+
+func TestFolderRepo_Reorder(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewFolderRepo(db)
+	mock.ExpectExec("UPDATE folders f SET position").
+		WithArgs("u1", "f1", sqlmock.AnyArg(), int64(2000)).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	require.NoError(t, r.Reorder(context.Background(), "u1", "f1", []string{"f3", "f2"}, 2000))
+	require.NoError(t, r.Reorder(context.Background(), "u1", "f1", nil, 2000))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFolderRepo_Delete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewFolderRepo(db)
+	mock.ExpectBegin()
+	mock.ExpectExec("UPDATE documents SET folder_id").
+		WithArgs("f1", "f2", "f3", "u1").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectExec("DELETE FROM folders").
+		WithArgs("f2", "f3", "u1").
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	require.NoError(t, r.Delete(context.Background(), "u1", []string{"f2", "f3"}, "f1"))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFolderRepo_CountDocuments(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewFolderRepo(db)
+	rows := sqlmock.NewRows([]string{"folder_id", "count"}).AddRow("f1", 3).AddRow("f2", 1)
+	mock.ExpectQuery("SELECT folder_id, COUNT").WithArgs("u1", DocumentStateNormal).WillReturnRows(rows)
+
+	counts, err := r.CountDocuments(context.Background(), "u1")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"f1": 3, "f2": 1}, counts)
+}
+
+func TestApplyFolderFilter(t *testing.T) {
+	where := map[string]any{}
+	applyFolderFilter(where, "u1", nil)
+	assert.Empty(t, where)
+
+	applyFolderFilter(where, "u1", &model.FolderFilter{Recursive: true})
+	assert.Empty(t, where)
+
+	applyFolderFilter(where, "u1", &model.FolderFilter{})
+	assert.Equal(t, "", where["folder_id"])
+
+	where = map[string]any{}
+	applyFolderFilter(where, "u1", &model.FolderFilter{ID: "f1", Recursive: true})
+	assert.Contains(t, where, "_custom_folder")
+}
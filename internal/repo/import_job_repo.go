@@ -26,6 +26,10 @@ func (r *ImportJobRepo) Create(ctx context.Context, job *model.ImportJob) error
 	if err != nil {
 		return fmt.Errorf("marshal: %w", err)
 	}
+	tagMetaJSON, err := marshalTagMeta(job.TagMeta)
+	if err != nil {
+		return err
+	}
 	reportJSON := []byte("{}")
 	if job.Report != nil {
 		reportJSON, err = json.Marshal(job.Report)
@@ -34,9 +38,9 @@ func (r *ImportJobRepo) Create(ctx context.Context, job *model.ImportJob) error
 		}
 	}
 	const query = `
-		INSERT INTO import_jobs (id, user_id, source, status, require_content, processed, total, tags_json, report_json,
-			ctime, mtime)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		INSERT INTO import_jobs (id, user_id, source, status, require_content, processed, total, tags_json,
+			tag_meta_json, report_json, ctime, mtime)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 	`
 	_, err = conn(ctx, r.db).ExecContext(ctx, query,
 		job.ID,
@@ -47,6 +51,7 @@ func (r *ImportJobRepo) Create(ctx context.Context, job *model.ImportJob) error
 		job.Processed,
 		job.Total,
 		string(tagsJSON),
+		tagMetaJSON,
 		string(reportJSON),
 		job.Ctime,
 		job.Mtime,
@@ -59,7 +64,8 @@ func (r *ImportJobRepo) Create(ctx context.Context, job *model.ImportJob) error
 
 func (r *ImportJobRepo) Get(ctx context.Context, userID, jobID string) (*model.ImportJob, error) {
 	const query = `
-		SELECT id, user_id, source, status, require_content, processed, total, tags_json, report_json, ctime, mtime
+		SELECT id, user_id, source, status, require_content, processed, total, tags_json, tag_meta_json,
+			report_json, ctime, mtime
 		FROM import_jobs
 		WHERE id = $1 AND user_id = $2
 	`
@@ -67,6 +73,7 @@ func (r *ImportJobRepo) Get(ctx context.Context, userID, jobID string) (*model.I
 	var job model.ImportJob
 	var requireContent int
 	var tagsJSON string
+	var tagMetaJSON string
 	var reportJSON string
 	if err := row.Scan(
 		&job.ID,
@@ -77,6 +84,7 @@ func (r *ImportJobRepo) Get(ctx context.Context, userID, jobID string) (*model.I
 		&job.Processed,
 		&job.Total,
 		&tagsJSON,
+		&tagMetaJSON,
 		&reportJSON,
 		&job.Ctime,
 		&job.Mtime,
@@ -97,6 +105,9 @@ func (r *ImportJobRepo) Get(ctx context.Context, userID, jobID string) (*model.I
 			return nil, fmt.Errorf("decode import_jobs.tags_json for %s: %w", job.ID, err)
 		}
 	}
+	if err := unmarshalTagMeta(&job, tagMetaJSON); err != nil {
+		return nil, err
+	}
 	if reportJSON != "" {
 		var report model.ImportReport
 		if err := json.Unmarshal([]byte(reportJSON), &report); err != nil {
@@ -136,6 +147,20 @@ func (r *ImportJobRepo) Confirm(
 
 func (r *ImportJobRepo) Claim(
 	ctx context.Context, now, lockedUntil int64,
+) (*model.ImportJob, error) {
+	return r.claim(ctx, "", now, lockedUntil)
+}
+
+// ClaimJob leases the given job the same way Claim does, leaving every other
+// queued job to the server workers.
+func (r *ImportJobRepo) ClaimJob(
+	ctx context.Context, jobID string, now, lockedUntil int64,
+) (*model.ImportJob, error) {
+	return r.claim(ctx, jobID, now, lockedUntil)
+}
+
+func (r *ImportJobRepo) claim(
+	ctx context.Context, jobID string, now, lockedUntil int64,
 ) (*model.ImportJob, error) {
 	const query = `
 		WITH candidate AS (
@@ -145,6 +170,7 @@ func (r *ImportJobRepo) Claim(
 			  AND locked_until <= $1
 			  AND next_retry_at <= $1
 			  AND attempts < 5
+			  AND ($3 = '' OR id = $3)
 			ORDER BY ctime, id
 			FOR UPDATE SKIP LOCKED
 			LIMIT 1
@@ -157,10 +183,10 @@ func (r *ImportJobRepo) Claim(
 		WHERE job.id = candidate.id
 		RETURNING job.id, job.user_id, job.source, job.status, job.mode,
 			job.require_content, job.processed, job.total, job.tags_json,
-			job.report_json, job.locked_until, job.attempts,
+			job.tag_meta_json, job.report_json, job.locked_until, job.attempts,
 			job.next_retry_at, job.last_error, job.ctime, job.mtime
 	`
-	row := conn(ctx, r.db).QueryRowContext(ctx, query, now, lockedUntil)
+	row := conn(ctx, r.db).QueryRowContext(ctx, query, now, lockedUntil, jobID)
 	job, err := scanImportJob(row)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, appErr.ErrNoWork
@@ -262,6 +288,10 @@ func (r *ImportJobRepo) UpdateSummary(ctx context.Context, job *model.ImportJob)
 	if err != nil {
 		return fmt.Errorf("marshal: %w", err)
 	}
+	tagMetaJSON, err := marshalTagMeta(job.TagMeta)
+	if err != nil {
+		return err
+	}
 	reportJSON := []byte("{}")
 	if job.Report != nil {
 		reportJSON, err = json.Marshal(job.Report)
@@ -276,9 +306,10 @@ func (r *ImportJobRepo) UpdateSummary(ctx context.Context, job *model.ImportJob)
 			processed = $3,
 			total = $4,
 			tags_json = $5,
-			report_json = $6,
-			mtime = $7
-		WHERE id = $8 AND user_id = $9
+			tag_meta_json = $6,
+			report_json = $7,
+			mtime = $8
+		WHERE id = $9 AND user_id = $10
 	`
 	res, err := conn(ctx, r.db).ExecContext(ctx, query,
 		job.Status,
@@ -286,6 +317,7 @@ func (r *ImportJobRepo) UpdateSummary(ctx context.Context, job *model.ImportJob)
 		job.Processed,
 		job.Total,
 		string(tagsJSON),
+		tagMetaJSON,
 		string(reportJSON),
 		job.Mtime,
 		job.ID,
@@ -372,10 +404,10 @@ func (r *ImportJobRepo) DeleteBefore(ctx context.Context, cutoff int64) (int64,
 func scanImportJob(scanner interface{ Scan(...any) error }) (*model.ImportJob, error) {
 	var job model.ImportJob
 	var requireContent int
-	var tagsJSON, reportJSON string
+	var tagsJSON, tagMetaJSON, reportJSON string
 	if err := scanner.Scan(
 		&job.ID, &job.UserID, &job.Source, &job.Status, &job.Mode,
-		&requireContent, &job.Processed, &job.Total, &tagsJSON, &reportJSON,
+		&requireContent, &job.Processed, &job.Total, &tagsJSON, &tagMetaJSON, &reportJSON,
 		&job.LockedUntil, &job.Attempts, &job.NextRetryAt, &job.LastError,
 		&job.Ctime, &job.Mtime,
 	); err != nil {
@@ -395,6 +427,9 @@ func scanImportJob(scanner interface{ Scan(...any) error }) (*model.ImportJob, e
 	if err := json.Unmarshal([]byte(tagsJSON), &job.Tags); err != nil {
 		return nil, fmt.Errorf("decode import_jobs.tags_json for %s: %w", job.ID, err)
 	}
+	if err := unmarshalTagMeta(&job, tagMetaJSON); err != nil {
+		return nil, err
+	}
 	var report model.ImportReport
 	if err := json.Unmarshal([]byte(reportJSON), &report); err != nil {
 		return nil, fmt.Errorf("decode import_jobs.report_json for %s: %w", job.ID, err)
@@ -412,6 +447,27 @@ func (r *ImportJobRepo) Delete(ctx context.Context, userID, jobID string) error
 	return nil
 }
 
+func marshalTagMeta(meta []model.Tag) (string, error) {
+	if meta == nil {
+		meta = []model.Tag{}
+	}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return "", fmt.Errorf("marshal tag meta: %w", err)
+	}
+	return string(data), nil
+}
+
+func unmarshalTagMeta(job *model.ImportJob, raw string) error {
+	if raw == "" {
+		return nil
+	}
+	if err := json.Unmarshal([]byte(raw), &job.TagMeta); err != nil {
+		return fmt.Errorf("decode import_jobs.tag_meta_json for %s: %w", job.ID, err)
+	}
+	return nil
+}
+
 func boolToInt(value bool) int {
 	if value {
 		return 1
@@ -34,8 +34,8 @@ func TestImportJobRepo_Get(t *testing.T) {
 	r := NewImportJobRepo(db)
 	rows := sqlmock.NewRows([]string{
 		"id", "user_id", "source", "status", "require_content",
-		"processed", "total", "tags_json", "report_json", "ctime", "mtime",
-	}).AddRow("j1", "u1", "hedgedoc", "done", 0, 10, 10, "[]", "{}", int64(1000), int64(2000))
+		"processed", "total", "tags_json", "tag_meta_json", "report_json", "ctime", "mtime",
+	}).AddRow("j1", "u1", "hedgedoc", "done", 0, 10, 10, "[]", "[]", "{}", int64(1000), int64(2000))
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 
 	job, err := r.Get(context.Background(), "u1", "j1")
@@ -55,6 +55,18 @@ func TestImportJobRepo_Get_NotFound(t *testing.T) {
 	assert.ErrorIs(t, err, appErr.ErrNotFound)
 }
 
+func TestImportJobRepo_ClaimJob_NoWork(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewImportJobRepo(db)
+	mock.ExpectQuery("WITH candidate").WithArgs(int64(200), int64(500), "j2").WillReturnError(sql.ErrNoRows)
+	_, err = r.ClaimJob(context.Background(), "j2", 200, 500)
+	assert.ErrorIs(t, err, appErr.ErrNoWork)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
 func TestImportJobRepo_DeleteBefore(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
@@ -558,13 +570,18 @@ func TestImportJobRepo_Get_WithRequireContentAndTags(t *testing.T) {
 	r := NewImportJobRepo(db)
 	rows := sqlmock.NewRows([]string{
 		"id", "user_id", "source", "status", "require_content",
-		"processed", "total", "tags_json", "report_json", "ctime", "mtime",
-	}).AddRow("j1", "u1", "zip", "done", 1, 5, 5, `["go","rust"]`, `{"imported":5}`, int64(1000), int64(2000))
+		"processed", "total", "tags_json", "tag_meta_json", "report_json", "ctime", "mtime",
+	}).AddRow(
+		"j1", "u1", "zip", "done", 1, 5, 5, `["go","rust"]`,
+		`[{"name":"go","color":"#00add8","icon":"🐹"}]`, `{"imported":5}`, int64(1000), int64(2000),
+	)
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 
 	job, err := r.Get(context.Background(), "u1", "j1")
 	require.NoError(t, err)
 	assert.True(t, job.RequireContent)
 	assert.Equal(t, []string{"go", "rust"}, job.Tags)
+	require.Len(t, job.TagMeta, 1)
+	assert.Equal(t, "#00add8", job.TagMeta[0].Color)
 	assert.NotNil(t, job.Report)
 }
@@ -0,0 +1,119 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/didi/gendry/builder"
+
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/dbutil"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+type InviteRepo struct {
+	db *sql.DB
+}
+
+func NewInviteRepo(db *sql.DB) *InviteRepo {
+	return &InviteRepo{db: db}
+}
+
+const inviteColumns = `id, code_digest, created_by, note, expires_at, used_by, used_at, ctime`
+
+func (r *InviteRepo) Create(ctx context.Context, invite *model.InviteCode) error {
+	return insertRecord(ctx, r.db, "invite_codes", map[string]any{
+		"id":          invite.ID,
+		"code_digest": invite.CodeDigest,
+		"created_by":  invite.CreatedBy,
+		"note":        invite.Note,
+		"expires_at":  invite.ExpiresAt,
+		"used_by":     invite.UsedBy,
+		"used_at":     invite.UsedAt,
+		"ctime":       invite.Ctime,
+	})
+}
+
+// GetUsable returns the invite with digest when it is unused and has not
+// expired at now.
+func (r *InviteRepo) GetUsable(ctx context.Context, digest string, now int64) (*model.InviteCode, error) {
+	query := `SELECT ` + inviteColumns + ` FROM invite_codes
+		WHERE code_digest = $1 AND used_at = 0 AND (expires_at = 0 OR expires_at > $2)`
+	invite, err := scanInvite(conn(ctx, r.db).QueryRowContext(ctx, query, digest, now))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, appErr.ErrNotFound
+		}
+		return nil, fmt.Errorf("get invite: %w", err)
+	}
+	return invite, nil
+}
+
+// Redeem marks the invite used by userID. The conditional update makes
+// concurrent registrations with the same code succeed at most once.
+func (r *InviteRepo) Redeem(ctx context.Context, digest, userID string, now int64) error {
+	const query = `
+		UPDATE invite_codes
+		SET used_by = $1, used_at = $2
+		WHERE code_digest = $3 AND used_at = 0 AND (expires_at = 0 OR expires_at > $2)
+	`
+	affected, err := dbutil.ExecAffected(ctx, conn(ctx, r.db), query, []any{userID, now, digest})
+	if err != nil {
+		return fmt.Errorf("redeem invite: %w", err)
+	}
+	if affected == 0 {
+		return appErr.ErrNotFound
+	}
+	return nil
+}
+
+// List returns all invites, newest first.
+func (r *InviteRepo) List(ctx context.Context) ([]model.InviteCode, error) {
+	query := `SELECT ` + inviteColumns + ` FROM invite_codes ORDER BY ctime DESC, id ASC`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	items := make([]model.InviteCode, 0)
+	for rows.Next() {
+		invite, err := scanInvite(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		items = append(items, *invite)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+	return items, nil
+}
+
+func (r *InviteRepo) Delete(ctx context.Context, id string) error {
+	sqlStr, args, err := builder.BuildDelete("invite_codes", map[string]any{"id": id})
+	if err != nil {
+		return fmt.Errorf("build delete: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	affected, err := dbutil.ExecAffected(ctx, conn(ctx, r.db), sqlStr, args)
+	if err != nil {
+		return fmt.Errorf("delete invite: %w", err)
+	}
+	if affected == 0 {
+		return appErr.ErrNotFound
+	}
+	return nil
+}
+
+func scanInvite(scanner interface{ Scan(dest ...any) error }) (*model.InviteCode, error) {
+	var invite model.InviteCode
+	if err := scanner.Scan(
+		&invite.ID, &invite.CodeDigest, &invite.CreatedBy, &invite.Note,
+		&invite.ExpiresAt, &invite.UsedBy, &invite.UsedAt, &invite.Ctime,
+	); err != nil {
+		return nil, err
+	}
+	return &invite, nil
+}
@@ -0,0 +1,114 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+var inviteTestColumns = []string{
+	"id", "code_digest", "created_by", "note", "expires_at", "used_by", "used_at", "ctime",
+}
+
+func TestInviteRepo_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewInviteRepo(db)
+	mock.ExpectExec("INSERT INTO").WillReturnResult(sqlmock.NewResult(1, 1))
+	err = r.Create(context.Background(), &model.InviteCode{
+		ID: "i1", CodeDigest: "digest", CreatedBy: "u1", Ctime: 1000,
+	})
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInviteRepo_GetUsable(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewInviteRepo(db)
+	rows := sqlmock.NewRows(inviteTestColumns).
+		AddRow("i1", "digest", "u1", "for bob", int64(5000), "", int64(0), int64(1000))
+	mock.ExpectQuery("SELECT").WithArgs("digest", int64(2000)).WillReturnRows(rows)
+
+	invite, err := r.GetUsable(context.Background(), "digest", 2000)
+	require.NoError(t, err)
+	assert.Equal(t, "i1", invite.ID)
+	assert.Equal(t, "for bob", invite.Note)
+	assert.Equal(t, int64(5000), invite.ExpiresAt)
+}
+
+func TestInviteRepo_GetUsable_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewInviteRepo(db)
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows(inviteTestColumns))
+
+	_, err = r.GetUsable(context.Background(), "digest", 2000)
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
+
+func TestInviteRepo_Redeem(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewInviteRepo(db)
+	mock.ExpectExec("UPDATE invite_codes").WithArgs("u2", int64(2000), "digest").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, r.Redeem(context.Background(), "digest", "u2", 2000))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInviteRepo_Redeem_AlreadyUsed(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewInviteRepo(db)
+	mock.ExpectExec("UPDATE invite_codes").WillReturnResult(sqlmock.NewResult(0, 0))
+	err = r.Redeem(context.Background(), "digest", "u2", 2000)
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
+
+func TestInviteRepo_List(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewInviteRepo(db)
+	rows := sqlmock.NewRows(inviteTestColumns).
+		AddRow("i2", "d2", "u1", "", int64(0), "", int64(0), int64(2000)).
+		AddRow("i1", "d1", "u1", "", int64(0), "u2", int64(1500), int64(1000))
+	mock.ExpectQuery("SELECT").WillReturnRows(rows)
+
+	items, err := r.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "i2", items[0].ID)
+	assert.Equal(t, "u2", items[1].UsedBy)
+}
+
+func TestInviteRepo_Delete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewInviteRepo(db)
+	mock.ExpectExec("DELETE FROM").WithArgs("i1").WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, r.Delete(context.Background(), "i1"))
+
+	mock.ExpectExec("DELETE FROM").WithArgs("missing").WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, r.Delete(context.Background(), "missing"), appErr.ErrNotFound)
+}
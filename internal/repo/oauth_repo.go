@@ -163,6 +163,7 @@ func (r *OAuthRepo) CreateOneTimeToken(ctx context.Context, token *model.OAuthOn
 		"user_id":          nullableString(token.UserID),
 		"email_normalized": nullableString(token.EmailNormalized),
 		"return_to":        token.ReturnTo,
+		"invite_digest":    token.InviteDigest,
 		"expires_at":       token.ExpiresAt,
 		"consumed_at":      nullableUnix(token.ConsumedAt),
 		"ctime":            token.Ctime,
@@ -181,12 +182,12 @@ func (r *OAuthRepo) ConsumeOneTimeToken(
 		  AND expires_at > $1
 		RETURNING kind, digest, purpose, provider,
 		          COALESCE(user_id, ''), COALESCE(email_normalized, ''),
-		          return_to, expires_at, consumed_at, ctime
+		          return_to, invite_digest, expires_at, consumed_at, ctime
 	`
 	var token model.OAuthOneTimeToken
 	err := conn(ctx, r.db).QueryRowContext(ctx, query, now, kind, digest).Scan(
 		&token.Kind, &token.Digest, &token.Purpose, &token.Provider,
-		&token.UserID, &token.EmailNormalized, &token.ReturnTo,
+		&token.UserID, &token.EmailNormalized, &token.ReturnTo, &token.InviteDigest,
 		&token.ExpiresAt, &token.ConsumedAt, &token.Ctime,
 	)
 	if err != nil {
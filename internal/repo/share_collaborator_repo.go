@@ -0,0 +1,162 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/didi/gendry/builder"
+
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/dbutil"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+// UpsertCollaborator adds a collaborator to a document or changes the role
+// of an existing one.
+func (r *ShareRepo) UpsertCollaborator(ctx context.Context, collaborator *model.DocumentCollaborator) error {
+	sqlStr := `
+		INSERT INTO document_collaborators (document_id, owner_id, user_id, role, ctime, mtime)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (document_id, user_id)
+		DO UPDATE SET
+			role = EXCLUDED.role,
+			mtime = EXCLUDED.mtime
+	`
+	args := []any{
+		collaborator.DocumentID,
+		collaborator.OwnerID,
+		collaborator.UserID,
+		collaborator.Role,
+		collaborator.Ctime,
+		collaborator.Mtime,
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	if _, err := conn(ctx, r.db).ExecContext(ctx, sqlStr, args...); err != nil {
+		return fmt.Errorf("upsert collaborator: %w", err)
+	}
+	return nil
+}
+
+func (r *ShareRepo) GetCollaborator(ctx context.Context, docID, userID string) (*model.DocumentCollaborator, error) {
+	const query = `
+		SELECT c.document_id, c.owner_id, c.user_id, COALESCE(u.email, ''), c.role, c.ctime, c.mtime
+		FROM document_collaborators c
+		LEFT JOIN users u ON u.id = c.user_id
+		WHERE c.document_id = $1 AND c.user_id = $2
+	`
+	collaborator, err := scanCollaborator(conn(ctx, r.db).QueryRowContext(ctx, query, docID, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, appErr.ErrNotFound
+		}
+		return nil, fmt.Errorf("get collaborator: %w", err)
+	}
+	return collaborator, nil
+}
+
+func (r *ShareRepo) ListCollaborators(
+	ctx context.Context, ownerID, docID string,
+) ([]model.DocumentCollaborator, error) {
+	const query = `
+		SELECT c.document_id, c.owner_id, c.user_id, COALESCE(u.email, ''), c.role, c.ctime, c.mtime
+		FROM document_collaborators c
+		LEFT JOIN users u ON u.id = c.user_id
+		WHERE c.owner_id = $1 AND c.document_id = $2
+		ORDER BY c.ctime ASC, c.user_id ASC
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, ownerID, docID)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	items := make([]model.DocumentCollaborator, 0)
+	for rows.Next() {
+		collaborator, err := scanCollaborator(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		items = append(items, *collaborator)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+	return items, nil
+}
+
+func (r *ShareRepo) DeleteCollaborator(ctx context.Context, docID, userID string) error {
+	sqlStr, args, err := builder.BuildDelete("document_collaborators", map[string]any{
+		"document_id": docID,
+		"user_id":     userID,
+	})
+	if err != nil {
+		return fmt.Errorf("build delete: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	affected, err := dbutil.ExecAffected(ctx, conn(ctx, r.db), sqlStr, args)
+	if err != nil {
+		return fmt.Errorf("delete collaborator: %w", err)
+	}
+	if affected == 0 {
+		return appErr.ErrNotFound
+	}
+	return nil
+}
+
+// CollaboratedDocument is a document another user or workspace shared with
+// the caller. OwnerName is the owner's email, or the workspace name when a
+// team workspace owns the document.
+type CollaboratedDocument struct {
+	ID             string
+	Title          string
+	ContentPreview string
+	Mtime          int64
+	OwnerID        string
+	OwnerName      string
+	Role           string
+}
+
+// ListCollaboratedDocuments returns the live documents the user has been
+// invited to, most recently modified first.
+func (r *ShareRepo) ListCollaboratedDocuments(ctx context.Context, userID string) ([]CollaboratedDocument, error) {
+	const query = `
+		SELECT d.id, d.title, LEFT(d.content, 1000) AS content_preview, d.mtime,
+			c.owner_id, COALESCE(u.email, w.name, '') AS owner_name, c.role
+		FROM document_collaborators c
+		JOIN documents d ON d.id = c.document_id AND d.user_id = c.owner_id
+		LEFT JOIN users u ON u.id = c.owner_id
+		LEFT JOIN workspaces w ON w.id = c.owner_id
+		WHERE c.user_id = $1 AND d.state = $2
+		ORDER BY d.mtime DESC, d.id ASC
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, userID, DocumentStateNormal)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	items := make([]CollaboratedDocument, 0)
+	for rows.Next() {
+		var item CollaboratedDocument
+		if err := rows.Scan(&item.ID, &item.Title, &item.ContentPreview, &item.Mtime,
+			&item.OwnerID, &item.OwnerName, &item.Role); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+	return items, nil
+}
+
+func scanCollaborator(scanner interface{ Scan(dest ...any) error }) (*model.DocumentCollaborator, error) {
+	var collaborator model.DocumentCollaborator
+	if err := scanner.Scan(
+		&collaborator.DocumentID, &collaborator.OwnerID, &collaborator.UserID, &collaborator.Email,
+		&collaborator.Role, &collaborator.Ctime, &collaborator.Mtime,
+	); err != nil {
+		return nil, err
+	}
+	return &collaborator, nil
+}
@@ -0,0 +1,81 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+var collaboratorTestColumns = []string{"document_id", "owner_id", "user_id", "email", "role", "ctime", "mtime"}
+
+func TestShareRepo_UpsertCollaborator(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewShareRepo(db)
+	mock.ExpectExec("INSERT INTO document_collaborators .* ON CONFLICT").
+		WithArgs("d1", "u1", "u2", model.CollaboratorRoleEditor, int64(1000), int64(1000)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = r.UpsertCollaborator(context.Background(), &model.DocumentCollaborator{
+		DocumentID: "d1", OwnerID: "u1", UserID: "u2", Role: model.CollaboratorRoleEditor, Ctime: 1000, Mtime: 1000,
+	})
+	require.NoError(t, err)
+}
+
+func TestShareRepo_GetCollaborator(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewShareRepo(db)
+	rows := sqlmock.NewRows(collaboratorTestColumns).
+		AddRow("d1", "u1", "u2", "b@example.com", "viewer", int64(1000), int64(1000))
+	mock.ExpectQuery("SELECT").WithArgs("d1", "u2").WillReturnRows(rows)
+
+	collaborator, err := r.GetCollaborator(context.Background(), "d1", "u2")
+	require.NoError(t, err)
+	assert.Equal(t, "u1", collaborator.OwnerID)
+	assert.Equal(t, model.CollaboratorRoleViewer, collaborator.Role)
+
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows(collaboratorTestColumns))
+	_, err = r.GetCollaborator(context.Background(), "d1", "u3")
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
+
+func TestShareRepo_DeleteCollaborator_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewShareRepo(db)
+	mock.ExpectExec("DELETE FROM document_collaborators").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = r.DeleteCollaborator(context.Background(), "d1", "u3")
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
+
+func TestShareRepo_ListCollaboratedDocuments(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewShareRepo(db)
+	rows := sqlmock.NewRows([]string{"id", "title", "content_preview", "mtime", "owner_id", "owner_name", "role"}).
+		AddRow("d1", "Plan", "body", int64(2000), "u1", "a@example.com", "editor")
+	mock.ExpectQuery("SELECT .* FROM document_collaborators").
+		WithArgs("u2", DocumentStateNormal).WillReturnRows(rows)
+
+	items, err := r.ListCollaboratedDocuments(context.Background(), "u2")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "a@example.com", items[0].OwnerName)
+	assert.Equal(t, model.CollaboratorRoleEditor, items[0].Role)
+}
@@ -12,7 +12,18 @@ import (
 	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
 )
 
-var tagColumns = []string{"id", "user_id", "name", "pinned", "ctime", "mtime"}
+var tagColumns = []string{"id", "user_id", "name", "color", "description", "icon", "pinned", "ctime", "mtime"}
+
+func scanTag(scanner interface{ Scan(...any) error }) (model.Tag, error) {
+	var tag model.Tag
+	if err := scanner.Scan(
+		&tag.ID, &tag.UserID, &tag.Name, &tag.Color, &tag.Description, &tag.Icon,
+		&tag.Pinned, &tag.Ctime, &tag.Mtime,
+	); err != nil {
+		return model.Tag{}, fmt.Errorf("scan: %w", err)
+	}
+	return tag, nil
+}
 
 type TagRepo struct {
 	db *sql.DB
@@ -24,12 +35,15 @@ func NewTagRepo(db *sql.DB) *TagRepo {
 
 func (r *TagRepo) Create(ctx context.Context, tag *model.Tag) error {
 	return insertRecord(ctx, r.db, "tags", map[string]any{
-		"id":      tag.ID,
-		"user_id": tag.UserID,
-		"name":    tag.Name,
-		"pinned":  tag.Pinned,
-		"ctime":   tag.Ctime,
-		"mtime":   tag.Mtime,
+		"id":          tag.ID,
+		"user_id":     tag.UserID,
+		"name":        tag.Name,
+		"color":       tag.Color,
+		"description": tag.Description,
+		"icon":        tag.Icon,
+		"pinned":      tag.Pinned,
+		"ctime":       tag.Ctime,
+		"mtime":       tag.Mtime,
 	})
 }
 
@@ -40,12 +54,15 @@ func (r *TagRepo) CreateBatch(ctx context.Context, tags []model.Tag) error {
 	rows := make([]map[string]any, 0, len(tags))
 	for _, tag := range tags {
 		rows = append(rows, map[string]any{
-			"id":      tag.ID,
-			"user_id": tag.UserID,
-			"name":    tag.Name,
-			"pinned":  tag.Pinned,
-			"ctime":   tag.Ctime,
-			"mtime":   tag.Mtime,
+			"id":          tag.ID,
+			"user_id":     tag.UserID,
+			"name":        tag.Name,
+			"color":       tag.Color,
+			"description": tag.Description,
+			"icon":        tag.Icon,
+			"pinned":      tag.Pinned,
+			"ctime":       tag.Ctime,
+			"mtime":       tag.Mtime,
 		})
 	}
 	sqlStr, args, err := builder.BuildInsert("tags", rows)
@@ -65,7 +82,7 @@ func (r *TagRepo) CreateBatch(ctx context.Context, tags []model.Tag) error {
 
 func (r *TagRepo) List(ctx context.Context, userID string) ([]model.Tag, error) {
 	where := map[string]any{"user_id": userID, "_orderby": "pinned desc, mtime desc"}
-	sqlStr, args, err := builder.BuildSelect("tags", where, []string{"id", "user_id", "name", "pinned", "ctime", "mtime"})
+	sqlStr, args, err := builder.BuildSelect("tags", where, tagColumns)
 	if err != nil {
 		return nil, fmt.Errorf("build select: %w", err)
 	}
@@ -77,9 +94,9 @@ func (r *TagRepo) List(ctx context.Context, userID string) ([]model.Tag, error)
 	defer func() { _ = rows.Close() }()
 	tags := make([]model.Tag, 0)
 	for rows.Next() {
-		var tag model.Tag
-		if err := rows.Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.Pinned, &tag.Ctime, &tag.Mtime); err != nil {
-			return nil, fmt.Errorf("scan: %w", err)
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, err
 		}
 		tags = append(tags, tag)
 	}
@@ -102,6 +119,20 @@ func (r *TagRepo) ListPage(ctx context.Context, userID, query string, limit, off
 	return r.listTagsByQuery(ctx, userID, query, limit, offset)
 }
 
+// tagSubtreeIDsQuery selects the IDs of a tag and its descendants; it takes
+// the parent tag ID and user ID as arguments.
+var tagSubtreeIDsQuery = "SELECT c.id FROM tags c JOIN tags p ON p.user_id = c.user_id AND " +
+	tagSubtreeCond("c", "p") + " WHERE p.id = ? AND p.user_id = ?"
+
+// tagSubtreeCond matches the tag aliased child against the tag aliased parent
+// itself or any of its hierarchical descendants ("parent/child").
+func tagSubtreeCond(child, parent string) string {
+	return fmt.Sprintf(
+		"(%[1]s.id = %[2]s.id OR substr(%[1]s.name, 1, length(%[2]s.name) + 1) = %[2]s.name || '/')",
+		child, parent,
+	)
+}
+
 func clampUint(v int) uint {
 	if v < 0 {
 		return 0
@@ -159,9 +190,9 @@ func (r *TagRepo) queryTags(ctx context.Context, sqlStr string, args []any) ([]m
 	defer func() { _ = rows.Close() }()
 	tags := make([]model.Tag, 0)
 	for rows.Next() {
-		var tag model.Tag
-		if err := rows.Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.Pinned, &tag.Ctime, &tag.Mtime); err != nil {
-			return nil, fmt.Errorf("scan: %w", err)
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, err
 		}
 		tags = append(tags, tag)
 	}
@@ -194,16 +225,18 @@ func (
 	if offset < 0 {
 		offset = 0
 	}
-	sqlStr := "SELECT t.id, t.name, t.pinned, COUNT(dt.tag_id) AS cnt, MAX(t.mtime) as mtime FROM tags t " +
-		"JOIN document_tags dt ON dt.tag_id = t.id AND dt.user_id = t.user_id " +
+	sqlStr := "SELECT t.id, t.name, t.color, t.description, t.icon, t.pinned, " +
+		"COUNT(DISTINCT dt.document_id) AS cnt, MAX(t.mtime) as mtime FROM tags t " +
+		"JOIN tags c ON c.user_id = t.user_id AND " + tagSubtreeCond("c", "t") + " " +
+		"JOIN document_tags dt ON dt.tag_id = c.id AND dt.user_id = c.user_id " +
 		"WHERE t.user_id = ?"
 	args := []any{userID}
 	if query != "" {
 		sqlStr += " AND t.name LIKE ?"
 		args = append(args, "%"+query+"%")
 	}
-	sqlStr += " GROUP BY t.id, t.name, t.pinned" +
-		" HAVING COUNT(dt.tag_id) > 0" +
+	sqlStr += " GROUP BY t.id, t.name, t.color, t.description, t.icon, t.pinned" +
+		" HAVING COUNT(DISTINCT dt.document_id) > 0" +
 		" ORDER BY t.pinned DESC, cnt DESC, mtime DESC" +
 		" LIMIT ? OFFSET ?"
 	args = append(args, limit, offset)
@@ -217,7 +250,10 @@ func (
 	for rows.Next() {
 		var item model.TagSummary
 		var mtime int64
-		if err := rows.Scan(&item.ID, &item.Name, &item.Pinned, &item.Count, &mtime); err != nil {
+		if err := rows.Scan(
+			&item.ID, &item.Name, &item.Color, &item.Description, &item.Icon,
+			&item.Pinned, &item.Count, &mtime,
+		); err != nil {
 			return nil, fmt.Errorf("scan: %w", err)
 		}
 		items = append(items, item)
@@ -233,7 +269,7 @@ func (r *TagRepo) ListByIDs(ctx context.Context, userID string, ids []string) ([
 		return []model.Tag{}, nil
 	}
 	where := map[string]any{"user_id": userID, "id": ids, "_orderby": "pinned desc, mtime desc"}
-	sqlStr, args, err := builder.BuildSelect("tags", where, []string{"id", "user_id", "name", "pinned", "ctime", "mtime"})
+	sqlStr, args, err := builder.BuildSelect("tags", where, tagColumns)
 	if err != nil {
 		return nil, fmt.Errorf("build select: %w", err)
 	}
@@ -245,9 +281,9 @@ func (r *TagRepo) ListByIDs(ctx context.Context, userID string, ids []string) ([
 	defer func() { _ = rows.Close() }()
 	tags := make([]model.Tag, 0)
 	for rows.Next() {
-		var tag model.Tag
-		if err := rows.Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.Pinned, &tag.Ctime, &tag.Mtime); err != nil {
-			return nil, fmt.Errorf("scan: %w", err)
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, err
 		}
 		tags = append(tags, tag)
 	}
@@ -269,10 +305,7 @@ func (r *TagRepo) ListByNames(ctx context.Context, userID string, names []string
 		"user_id":     userID,
 		"_custom_ids": builder.In{"name": args},
 	}
-	sqlStr, argsList, err := builder.BuildSelect("tags", where, []string{
-		"id", "user_id", "name", "pinned", "ctime",
-		"mtime",
-	})
+	sqlStr, argsList, err := builder.BuildSelect("tags", where, tagColumns)
 	if err != nil {
 		return nil, fmt.Errorf("build select: %w", err)
 	}
@@ -284,9 +317,9 @@ func (r *TagRepo) ListByNames(ctx context.Context, userID string, names []string
 	defer func() { _ = rows.Close() }()
 	tags := make([]model.Tag, 0)
 	for rows.Next() {
-		var tag model.Tag
-		if err := rows.Scan(&tag.ID, &tag.UserID, &tag.Name, &tag.Pinned, &tag.Ctime, &tag.Mtime); err != nil {
-			return nil, fmt.Errorf("scan: %w", err)
+		tag, err := scanTag(rows)
+		if err != nil {
+			return nil, err
 		}
 		tags = append(tags, tag)
 	}
@@ -324,6 +357,50 @@ func (r *TagRepo) UpdatePinned(ctx context.Context, userID, tagID string, pinned
 	return nil
 }
 
+func (r *TagRepo) UpdateName(ctx context.Context, userID, tagID, name string, mtime int64) error {
+	where := map[string]any{"id": tagID, "user_id": userID}
+	update := map[string]any{"name": name, "mtime": mtime}
+	sqlStr, args, err := builder.BuildUpdate("tags", where, update)
+	if err != nil {
+		return fmt.Errorf("build update: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	affected, err := dbutil.ExecAffected(ctx, conn(ctx, r.db), sqlStr, args)
+	if err != nil {
+		if dbutil.IsConflict(err) {
+			return appErr.ErrConflict
+		}
+		return fmt.Errorf("update name: %w", err)
+	}
+	if affected == 0 {
+		return appErr.ErrNotFound
+	}
+	return nil
+}
+
+func (r *TagRepo) UpdateAppearance(ctx context.Context, tag *model.Tag) error {
+	where := map[string]any{"id": tag.ID, "user_id": tag.UserID}
+	update := map[string]any{
+		"color":       tag.Color,
+		"description": tag.Description,
+		"icon":        tag.Icon,
+		"mtime":       tag.Mtime,
+	}
+	sqlStr, args, err := builder.BuildUpdate("tags", where, update)
+	if err != nil {
+		return fmt.Errorf("build update: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	affected, err := dbutil.ExecAffected(ctx, conn(ctx, r.db), sqlStr, args)
+	if err != nil {
+		return fmt.Errorf("update appearance: %w", err)
+	}
+	if affected == 0 {
+		return appErr.ErrNotFound
+	}
+	return nil
+}
+
 func (r *TagRepo) Delete(ctx context.Context, userID, tagID string) error {
 	where := map[string]any{"id": tagID, "user_id": userID}
 	sqlStr, args, err := builder.BuildDelete("tags", where)
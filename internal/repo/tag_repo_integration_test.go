@@ -32,3 +32,51 @@ func TestTagRepoCRUD(t *testing.T) {
 	require.NoError(t, err)
 	require.Len(t, list, 0)
 }
+
+func TestTagRepoHierarchyRollup(t *testing.T) {
+	db, cleanup := testutil.OpenTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	tags := repo.NewTagRepo(db)
+	docTags := repo.NewDocumentTagRepo(db)
+	docs := repo.NewDocumentRepo(db)
+	now := timeutil.NowUnix()
+	for _, tag := range []model.Tag{
+		{ID: "tag-p", UserID: "user-1", Name: "project", Ctime: now, Mtime: now},
+		{ID: "tag-c", UserID: "user-1", Name: "project/mnote", Ctime: now, Mtime: now},
+		{ID: "tag-x", UserID: "user-1", Name: "projects", Ctime: now, Mtime: now},
+	} {
+		require.NoError(t, tags.Create(ctx, &tag))
+	}
+	for _, docID := range []string{"doc-1", "doc-2"} {
+		require.NoError(t, docs.Create(ctx, &model.Document{
+			ID: docID, UserID: "user-1", Title: docID, State: repo.DocumentStateNormal, Ctime: now, Mtime: now,
+		}))
+	}
+	for _, link := range []model.DocumentTag{
+		{UserID: "user-1", DocumentID: "doc-1", TagID: "tag-p"},
+		{UserID: "user-1", DocumentID: "doc-1", TagID: "tag-c"},
+		{UserID: "user-1", DocumentID: "doc-2", TagID: "tag-c"},
+		{UserID: "user-1", DocumentID: "doc-2", TagID: "tag-x"},
+	} {
+		require.NoError(t, docTags.Add(ctx, &link))
+	}
+
+	ids, err := docTags.ListDocIDsByTag(ctx, "user-1", "tag-p")
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"doc-1", "doc-2"}, ids)
+
+	summary, err := tags.ListSummary(ctx, "user-1", "project", 20, 0)
+	require.NoError(t, err)
+	counts := make(map[string]int, len(summary))
+	for _, item := range summary {
+		counts[item.Name] = item.Count
+	}
+	require.Equal(t, map[string]int{"project": 2, "project/mnote": 2, "projects": 1}, counts)
+
+	require.NoError(t, docTags.ReassignTag(ctx, "user-1", "tag-c", "tag-p"))
+	ids, err = docTags.ListDocIDsByTag(ctx, "user-1", "tag-c")
+	require.NoError(t, err)
+	require.Empty(t, ids)
+}
@@ -12,10 +12,12 @@ import (
 	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
 )
 
-var tagCols = []string{"id", "user_id", "name", "pinned", "ctime", "mtime"}
+var tagCols = []string{"id", "user_id", "name", "color", "description", "icon", "pinned", "ctime", "mtime"}
+
+var tagSummaryCols = []string{"id", "name", "color", "description", "icon", "pinned", "cnt", "mtime"}
 
 func addTagRow(rows *sqlmock.Rows, id, name string) *sqlmock.Rows {
-	return rows.AddRow(id, "u1", name, 0, int64(1000), int64(2000))
+	return rows.AddRow(id, "u1", name, "", "", "", 0, int64(1000), int64(2000))
 }
 
 func TestTagRepo_Create(t *testing.T) {
@@ -170,9 +172,9 @@ func TestTagRepo_ListSummary(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	r := NewTagRepo(db)
-	rows := sqlmock.NewRows([]string{"id", "name", "pinned", "cnt", "mtime"}).
-		AddRow("t1", "golang", 0, 5, int64(2000))
-	mock.ExpectQuery("SELECT").WillReturnRows(rows)
+	rows := sqlmock.NewRows(tagSummaryCols).
+		AddRow("t1", "golang", "", "", "", 0, 5, int64(2000))
+	mock.ExpectQuery(`COUNT\(DISTINCT dt.document_id\).*JOIN tags c`).WillReturnRows(rows)
 
 	items, err := r.ListSummary(context.Background(), "u1", "", 20, 0)
 	require.NoError(t, err)
@@ -192,6 +194,72 @@ func TestTagRepo_UpdatePinned(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestTagRepo_UpdateName(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewTagRepo(db)
+	mock.ExpectExec("UPDATE tags SET").
+		WithArgs(int64(3000), "work/2024", "t1", "u1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = r.UpdateName(context.Background(), "u1", "t1", "work/2024", 3000)
+	require.NoError(t, err)
+}
+
+func TestTagRepo_UpdateName_Conflict(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewTagRepo(db)
+	mock.ExpectExec("UPDATE").WillReturnError(errConflictStub)
+
+	err = r.UpdateName(context.Background(), "u1", "t1", "go", 3000)
+	assert.ErrorIs(t, err, appErr.ErrConflict)
+}
+
+func TestTagRepo_UpdateName_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewTagRepo(db)
+	mock.ExpectExec("UPDATE").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = r.UpdateName(context.Background(), "u1", "t1", "go", 3000)
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
+
+func TestTagRepo_UpdateAppearance(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewTagRepo(db)
+	mock.ExpectExec("UPDATE tags SET").
+		WithArgs("#00add8", "Go notes", "🐹", int64(3000), "t1", "u1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err = r.UpdateAppearance(context.Background(), &model.Tag{
+		ID: "t1", UserID: "u1", Color: "#00add8", Description: "Go notes", Icon: "🐹", Mtime: 3000,
+	})
+	require.NoError(t, err)
+}
+
+func TestTagRepo_UpdateAppearance_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewTagRepo(db)
+	mock.ExpectExec("UPDATE").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = r.UpdateAppearance(context.Background(), &model.Tag{ID: "t1", UserID: "u1"})
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
+
 func TestTagRepo_UpdatePinned_NotFound(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
@@ -428,8 +496,8 @@ func TestTagRepo_ListSummary_WithQuery(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	r := NewTagRepo(db)
-	rows := sqlmock.NewRows([]string{"id", "name", "pinned", "cnt", "mtime"}).
-		AddRow("t1", "golang", 0, 3, int64(2000))
+	rows := sqlmock.NewRows(tagSummaryCols).
+		AddRow("t1", "golang", "", "", "", 0, 3, int64(2000))
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 	items, err := r.ListSummary(context.Background(), "u1", "go", 20, 0)
 	require.NoError(t, err)
@@ -442,7 +510,7 @@ func TestTagRepo_ListSummary_NegativeDefaults(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	r := NewTagRepo(db)
-	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows([]string{"id", "name", "pinned", "cnt", "mtime"}))
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows(tagSummaryCols))
 	_, err = r.ListSummary(context.Background(), "u1", "", -1, -5)
 	require.NoError(t, err)
 }
@@ -475,8 +543,8 @@ func TestTagRepo_List_RowsErr(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	r := NewTagRepo(db)
-	rows := sqlmock.NewRows([]string{"id", "user_id", "name", "pinned", "ctime", "mtime"}).
-		AddRow("t1", "u1", "Tag1", 0, int64(1000), int64(2000)).
+	rows := sqlmock.NewRows(tagCols).
+		AddRow("t1", "u1", "Tag1", "", "", "", 0, int64(1000), int64(2000)).
 		RowError(0, errDB)
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 	_, err = r.List(context.Background(), "u1")
@@ -489,8 +557,8 @@ func TestTagRepo_ListByIDs_RowsErr(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	r := NewTagRepo(db)
-	rows := sqlmock.NewRows([]string{"id", "user_id", "name", "pinned", "ctime", "mtime"}).
-		AddRow("t1", "u1", "Tag1", 0, int64(1000), int64(2000)).
+	rows := sqlmock.NewRows(tagCols).
+		AddRow("t1", "u1", "Tag1", "", "", "", 0, int64(1000), int64(2000)).
 		RowError(0, errDB)
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 	_, err = r.ListByIDs(context.Background(), "u1", []string{"t1"})
@@ -503,8 +571,8 @@ func TestTagRepo_ListByNames_RowsErr(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	r := NewTagRepo(db)
-	rows := sqlmock.NewRows([]string{"id", "user_id", "name", "pinned", "ctime", "mtime"}).
-		AddRow("t1", "u1", "Tag1", 0, int64(1000), int64(2000)).
+	rows := sqlmock.NewRows(tagCols).
+		AddRow("t1", "u1", "Tag1", "", "", "", 0, int64(1000), int64(2000)).
 		RowError(0, errDB)
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 	_, err = r.ListByNames(context.Background(), "u1", []string{"Tag1"})
@@ -517,8 +585,8 @@ func TestTagRepo_ListSummary_RowsErr(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	r := NewTagRepo(db)
-	rows := sqlmock.NewRows([]string{"id", "name", "pinned", "cnt", "mtime"}).
-		AddRow("t1", "Tag1", 0, 5, int64(2000)).
+	rows := sqlmock.NewRows(tagSummaryCols).
+		AddRow("t1", "Tag1", "", "", "", 0, 5, int64(2000)).
 		RowError(0, errDB)
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 	_, err = r.ListSummary(context.Background(), "u1", "Tag", 10, 0)
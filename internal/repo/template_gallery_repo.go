@@ -0,0 +1,149 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/didi/gendry/builder"
+
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/dbutil"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+var templateGalleryColumns = []string{
+	"slug", "version", "name", "description", "content", "variables_json", "tag_names_json", "ctime", "mtime",
+}
+
+type TemplateGalleryRepo struct {
+	db *sql.DB
+}
+
+func NewTemplateGalleryRepo(db *sql.DB) *TemplateGalleryRepo {
+	return &TemplateGalleryRepo{db: db}
+}
+
+// Upsert inserts a built-in template or replaces the stored one when item
+// has a higher version. It reports whether a row was written.
+func (r *TemplateGalleryRepo) Upsert(ctx context.Context, item *model.GalleryTemplate) (bool, error) {
+	variables := item.Variables
+	if variables == nil {
+		variables = []model.TemplateVariable{}
+	}
+	variablesJSON, err := json.Marshal(variables)
+	if err != nil {
+		return false, fmt.Errorf("marshal gallery template %s variables: %w", item.Slug, err)
+	}
+	tags := item.Tags
+	if tags == nil {
+		tags = []string{}
+	}
+	tagsJSON, err := json.Marshal(tags)
+	if err != nil {
+		return false, fmt.Errorf("marshal gallery template %s tags: %w", item.Slug, err)
+	}
+	sqlStr := `
+		INSERT INTO template_gallery
+			(slug, version, name, description, content, variables_json, tag_names_json, ctime, mtime)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (slug) DO UPDATE SET
+			version = EXCLUDED.version,
+			name = EXCLUDED.name,
+			description = EXCLUDED.description,
+			content = EXCLUDED.content,
+			variables_json = EXCLUDED.variables_json,
+			tag_names_json = EXCLUDED.tag_names_json,
+			mtime = EXCLUDED.mtime
+		WHERE template_gallery.version < EXCLUDED.version
+	`
+	sqlStr, args := dbutil.Finalize(sqlStr, []any{
+		item.Slug, item.Version, item.Name, item.Description, item.Content,
+		string(variablesJSON), string(tagsJSON), item.Ctime, item.Mtime,
+	})
+	affected, err := dbutil.ExecAffected(ctx, conn(ctx, r.db), sqlStr, args)
+	if err != nil {
+		return false, fmt.Errorf("upsert gallery template: %w", err)
+	}
+	return affected > 0, nil
+}
+
+// DeleteExcept removes built-in templates no longer shipped with the binary.
+func (r *TemplateGalleryRepo) DeleteExcept(ctx context.Context, slugs []string) (int64, error) {
+	where := map[string]any{}
+	if len(slugs) > 0 {
+		where["slug not in"] = slugs
+	}
+	sqlStr, args, err := builder.BuildDelete("template_gallery", where)
+	if err != nil {
+		return 0, fmt.Errorf("build delete: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	affected, err := dbutil.ExecAffected(ctx, conn(ctx, r.db), sqlStr, args)
+	if err != nil {
+		return 0, fmt.Errorf("delete gallery templates: %w", err)
+	}
+	return affected, nil
+}
+
+func (r *TemplateGalleryRepo) List(ctx context.Context) ([]model.GalleryTemplate, error) {
+	sqlStr, args, err := builder.BuildSelect("template_gallery", map[string]any{
+		"_orderby": "name asc",
+	}, templateGalleryColumns)
+	if err != nil {
+		return nil, fmt.Errorf("build select: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	items := make([]model.GalleryTemplate, 0)
+	for rows.Next() {
+		item, err := scanGalleryTemplate(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		items = append(items, *item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+	return items, nil
+}
+
+func (r *TemplateGalleryRepo) GetBySlug(ctx context.Context, slug string) (*model.GalleryTemplate, error) {
+	sqlStr, args, err := builder.BuildSelect("template_gallery", map[string]any{"slug": slug}, templateGalleryColumns)
+	if err != nil {
+		return nil, fmt.Errorf("build select: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	item, err := scanGalleryTemplate(conn(ctx, r.db).QueryRowContext(ctx, sqlStr, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, appErr.ErrNotFound
+		}
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	return item, nil
+}
+
+func scanGalleryTemplate(scanner interface{ Scan(dest ...any) error }) (*model.GalleryTemplate, error) {
+	var item model.GalleryTemplate
+	var variablesJSON, tagsJSON string
+	if err := scanner.Scan(&item.Slug, &item.Version, &item.Name, &item.Description, &item.Content,
+		&variablesJSON, &tagsJSON, &item.Ctime, &item.Mtime); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(variablesJSON), &item.Variables); err != nil {
+		return nil, fmt.Errorf("decode template_gallery.variables_json for %s: %w", item.Slug, err)
+	}
+	if err := json.Unmarshal([]byte(tagsJSON), &item.Tags); err != nil {
+		return nil, fmt.Errorf("decode template_gallery.tag_names_json for %s: %w", item.Slug, err)
+	}
+	return &item, nil
+}
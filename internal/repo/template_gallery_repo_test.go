@@ -0,0 +1,78 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+func TestTemplateGalleryRepo_Upsert(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewTemplateGalleryRepo(db)
+	item := &model.GalleryTemplate{Slug: "daily-note", Version: 2, Name: "Daily note", Content: "# {{sys:today}}"}
+	mock.ExpectExec(`INSERT INTO template_gallery .* ON CONFLICT \(slug\) DO UPDATE SET .* `+
+		`WHERE template_gallery.version < EXCLUDED.version`).
+		WithArgs("daily-note", 2, "Daily note", "", "# {{sys:today}}", "[]", "[]", int64(0), int64(0)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	written, err := r.Upsert(context.Background(), item)
+	require.NoError(t, err)
+	assert.True(t, written)
+
+	mock.ExpectExec("INSERT INTO template_gallery").WillReturnResult(sqlmock.NewResult(0, 0))
+	written, err = r.Upsert(context.Background(), item)
+	require.NoError(t, err)
+	assert.False(t, written)
+}
+
+func TestTemplateGalleryRepo_DeleteExcept(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewTemplateGalleryRepo(db)
+	mock.ExpectExec(`DELETE FROM template_gallery WHERE \(slug NOT IN \(\$1,\$2\)\)`).
+		WithArgs("a", "b").
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	deleted, err := r.DeleteExcept(context.Background(), []string{"a", "b"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+}
+
+func TestTemplateGalleryRepo_List(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewTemplateGalleryRepo(db)
+	rows := sqlmock.NewRows(templateGalleryColumns).
+		AddRow("daily-note", 2, "Daily note", "d", "c", `[{"key":"MOOD","type":"text"}]`, `["journal"]`,
+			int64(1), int64(2))
+	mock.ExpectQuery("SELECT .* FROM template_gallery ORDER BY name asc").WillReturnRows(rows)
+
+	items, err := r.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, []string{"journal"}, items[0].Tags)
+	assert.Equal(t, "MOOD", items[0].Variables[0].Key)
+}
+
+func TestTemplateGalleryRepo_GetBySlug_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewTemplateGalleryRepo(db)
+	mock.ExpectQuery("SELECT").WillReturnError(sql.ErrNoRows)
+	_, err = r.GetBySlug(context.Background(), "missing")
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
@@ -23,9 +23,9 @@ func NewTemplateRepo(db *sql.DB) *TemplateRepo {
 }
 
 func (r *TemplateRepo) Create(ctx context.Context, tpl *model.Template) error {
-	tagIDsJSON, err := json.Marshal(tpl.DefaultTagIDs)
+	tagIDsJSON, variablesJSON, err := marshalTemplateJSON(tpl)
 	if err != nil {
-		return fmt.Errorf("marshal template %s default tag ids: %w", tpl.ID, err)
+		return err
 	}
 	data := map[string]any{
 		"id":                   tpl.ID,
@@ -33,7 +33,11 @@ func (r *TemplateRepo) Create(ctx context.Context, tpl *model.Template) error {
 		"name":                 tpl.Name,
 		"description":          tpl.Description,
 		"content":              tpl.Content,
-		"default_tag_ids_json": string(tagIDsJSON),
+		"variables_json":       variablesJSON,
+		"default_tag_ids_json": tagIDsJSON,
+		"built_in":             tpl.BuiltIn,
+		"source_slug":          tpl.SourceSlug,
+		"source_version":       tpl.SourceVersion,
 		"ctime":                tpl.Ctime,
 		"mtime":                tpl.Mtime,
 	}
@@ -53,16 +57,17 @@ func (r *TemplateRepo) Create(ctx context.Context, tpl *model.Template) error {
 }
 
 func (r *TemplateRepo) Update(ctx context.Context, tpl *model.Template) error {
-	tagIDsJSON, err := json.Marshal(tpl.DefaultTagIDs)
+	tagIDsJSON, variablesJSON, err := marshalTemplateJSON(tpl)
 	if err != nil {
-		return fmt.Errorf("marshal template %s default tag ids: %w", tpl.ID, err)
+		return err
 	}
 	where := map[string]any{"id": tpl.ID, "user_id": tpl.UserID}
 	update := map[string]any{
 		"name":                 tpl.Name,
 		"description":          tpl.Description,
 		"content":              tpl.Content,
-		"default_tag_ids_json": string(tagIDsJSON),
+		"variables_json":       variablesJSON,
+		"default_tag_ids_json": tagIDsJSON,
 		"mtime":                tpl.Mtime,
 	}
 	sqlStr, args, err := builder.BuildUpdate("templates", where, update)
@@ -106,7 +111,8 @@ func (r *TemplateRepo) Delete(ctx context.Context, userID, templateID string) er
 
 func (r *TemplateRepo) GetByID(ctx context.Context, userID, templateID string) (*model.Template, error) {
 	sqlStr, args, err := builder.BuildSelect("templates", map[string]any{"id": templateID, "user_id": userID}, []string{
-		"id", "user_id", "name", "description", "content", "default_tag_ids_json", "ctime", "mtime",
+		"id", "user_id", "name", "description", "content", "variables_json", "default_tag_ids_json",
+		"built_in", "source_slug", "source_version", "ctime", "mtime",
 	})
 	if err != nil {
 		return nil, fmt.Errorf("build select: %w", err)
@@ -128,7 +134,8 @@ func (r *TemplateRepo) GetByID(ctx context.Context, userID, templateID string) (
 
 func (r *TemplateRepo) ListByUser(ctx context.Context, userID string) ([]model.Template, error) {
 	sqlStr := `
-		SELECT id, user_id, name, description, content, default_tag_ids_json, ctime, mtime
+		SELECT id, user_id, name, description, content, variables_json, default_tag_ids_json,
+			built_in, source_slug, source_version, ctime, mtime
 		FROM templates
 		WHERE user_id = ?
 		ORDER BY mtime DESC
@@ -164,7 +171,7 @@ func (
 	error,
 ) {
 	sqlStr := `
-		SELECT id, user_id, name, description, default_tag_ids_json, ctime, mtime
+		SELECT id, user_id, name, description, default_tag_ids_json, built_in, ctime, mtime
 		FROM templates
 		WHERE user_id = ?
 	`
@@ -229,16 +236,36 @@ type templateScanner interface {
 	Scan(dest ...any) error
 }
 
+func marshalTemplateJSON(tpl *model.Template) (string, string, error) {
+	tagIDsJSON, err := json.Marshal(tpl.DefaultTagIDs)
+	if err != nil {
+		return "", "", fmt.Errorf("marshal template %s default tag ids: %w", tpl.ID, err)
+	}
+	variables := tpl.Variables
+	if variables == nil {
+		variables = []model.TemplateVariable{}
+	}
+	variablesJSON, err := json.Marshal(variables)
+	if err != nil {
+		return "", "", fmt.Errorf("marshal template %s variables: %w", tpl.ID, err)
+	}
+	return string(tagIDsJSON), string(variablesJSON), nil
+}
+
 func scanTemplate(s templateScanner) (*model.Template, error) {
 	var tpl model.Template
-	var tagIDsJSON string
+	var tagIDsJSON, variablesJSON string
 	if err := s.Scan(
 		&tpl.ID,
 		&tpl.UserID,
 		&tpl.Name,
 		&tpl.Description,
 		&tpl.Content,
+		&variablesJSON,
 		&tagIDsJSON,
+		&tpl.BuiltIn,
+		&tpl.SourceSlug,
+		&tpl.SourceVersion,
 		&tpl.Ctime,
 		&tpl.Mtime,
 	); err != nil {
@@ -247,6 +274,9 @@ func scanTemplate(s templateScanner) (*model.Template, error) {
 	if err := json.Unmarshal([]byte(tagIDsJSON), &tpl.DefaultTagIDs); err != nil {
 		return nil, fmt.Errorf("decode templates.default_tag_ids_json for %s: %w", tpl.ID, err)
 	}
+	if err := json.Unmarshal([]byte(variablesJSON), &tpl.Variables); err != nil {
+		return nil, fmt.Errorf("decode templates.variables_json for %s: %w", tpl.ID, err)
+	}
 	return &tpl, nil
 }
 
@@ -259,6 +289,7 @@ func scanTemplateMeta(s templateScanner) (*model.TemplateMeta, error) {
 		&tpl.Name,
 		&tpl.Description,
 		&tagIDsJSON,
+		&tpl.BuiltIn,
 		&tpl.Ctime,
 		&tpl.Mtime,
 	); err != nil {
@@ -12,7 +12,10 @@ import (
 	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
 )
 
-var tplCols = []string{"id", "user_id", "name", "description", "content", "default_tag_ids_json", "ctime", "mtime"}
+var tplCols = []string{
+	"id", "user_id", "name", "description", "content", "variables_json", "default_tag_ids_json",
+	"built_in", "source_slug", "source_version", "ctime", "mtime",
+}
 
 func TestTemplateRepo_Create(t *testing.T) {
 	db, mock, err := sqlmock.New()
@@ -99,7 +102,8 @@ func TestTemplateRepo_GetByID(t *testing.T) {
 
 	r := NewTemplateRepo(db)
 	rows := sqlmock.NewRows(tplCols).
-		AddRow("tpl1", "u1", "Note", "desc", "# Hello", `["t1"]`, int64(1000), int64(2000))
+		AddRow("tpl1", "u1", "Note", "desc", "# Hello", `[{"key":"NAME","type":"text"}]`, `["t1"]`,
+			1, "daily-note", 2, int64(1000), int64(2000))
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 
 	tpl, err := r.GetByID(context.Background(), "u1", "tpl1")
@@ -107,6 +111,10 @@ func TestTemplateRepo_GetByID(t *testing.T) {
 	assert.Equal(t, "tpl1", tpl.ID)
 	assert.Equal(t, "Note", tpl.Name)
 	assert.Equal(t, []string{"t1"}, tpl.DefaultTagIDs)
+	assert.Equal(t, []model.TemplateVariable{{Key: "NAME", Type: "text"}}, tpl.Variables)
+	assert.Equal(t, 1, tpl.BuiltIn)
+	assert.Equal(t, "daily-note", tpl.SourceSlug)
+	assert.Equal(t, 2, tpl.SourceVersion)
 }
 
 func TestTemplateRepo_GetByID_NotFound(t *testing.T) {
@@ -129,8 +137,8 @@ func TestTemplateRepo_ListByUser(t *testing.T) {
 
 	r := NewTemplateRepo(db)
 	rows := sqlmock.NewRows(tplCols).
-		AddRow("tpl1", "u1", "Note1", "d1", "c1", `[]`, int64(1000), int64(2000)).
-		AddRow("tpl2", "u1", "Note2", "d2", "c2", `["t1"]`, int64(1000), int64(2000))
+		AddRow("tpl1", "u1", "Note1", "d1", "c1", `[]`, `[]`, 0, "", 0, int64(1000), int64(2000)).
+		AddRow("tpl2", "u1", "Note2", "d2", "c2", `[]`, `["t1"]`, 0, "", 0, int64(1000), int64(2000))
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 
 	items, err := r.ListByUser(context.Background(), "u1")
@@ -144,9 +152,9 @@ func TestTemplateRepo_ListMetaByUser(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	r := NewTemplateRepo(db)
-	metaCols := []string{"id", "user_id", "name", "description", "default_tag_ids_json", "ctime", "mtime"}
+	metaCols := []string{"id", "user_id", "name", "description", "default_tag_ids_json", "built_in", "ctime", "mtime"}
 	rows := sqlmock.NewRows(metaCols).
-		AddRow("tpl1", "u1", "Note1", "d1", `[]`, int64(1000), int64(2000))
+		AddRow("tpl1", "u1", "Note1", "d1", `[]`, 0, int64(1000), int64(2000))
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 
 	items, err := r.ListMetaByUser(context.Background(), "u1", "", 10, 0)
@@ -298,9 +306,9 @@ func TestTemplateRepo_ListMetaByUser_NoLimit(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	r := NewTemplateRepo(db)
-	metaCols := []string{"id", "user_id", "name", "description", "default_tag_ids_json", "ctime", "mtime"}
+	metaCols := []string{"id", "user_id", "name", "description", "default_tag_ids_json", "built_in", "ctime", "mtime"}
 	rows := sqlmock.NewRows(metaCols).
-		AddRow("t1", "u1", "Note", "desc", `[]`, int64(1000), int64(2000))
+		AddRow("t1", "u1", "Note", "desc", `[]`, 0, int64(1000), int64(2000))
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 	items, err := r.ListMetaByUser(context.Background(), "u1", "", 0, 0)
 	require.NoError(t, err)
@@ -325,7 +333,7 @@ func TestTemplateRepo_GetByID_RowsErr(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	r := NewTemplateRepo(db)
-	rows := sqlmock.NewRows([]string{"id", "user_id", "name", "description", "content", "default_tag_ids_json", "ctime", "mtime"}).
+	rows := sqlmock.NewRows(tplCols).
 		CloseError(errDB)
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 	_, err = r.GetByID(context.Background(), "u1", "t1")
@@ -339,8 +347,8 @@ func TestTemplateRepo_ListByUser_RowsErr(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	r := NewTemplateRepo(db)
-	rows := sqlmock.NewRows([]string{"id", "user_id", "name", "description", "content", "default_tag_ids_json", "ctime", "mtime"}).
-		AddRow("t1", "u1", "Note", "desc", "body", `[]`, int64(1000), int64(2000)).
+	rows := sqlmock.NewRows(tplCols).
+		AddRow("t1", "u1", "Note", "desc", "body", `[]`, `[]`, 0, "", 0, int64(1000), int64(2000)).
 		RowError(0, errDB)
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 	_, err = r.ListByUser(context.Background(), "u1")
@@ -353,9 +361,9 @@ func TestTemplateRepo_ListMetaByUser_RowsErr(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	r := NewTemplateRepo(db)
-	metaCols := []string{"id", "user_id", "name", "description", "default_tag_ids_json", "ctime", "mtime"}
+	metaCols := []string{"id", "user_id", "name", "description", "default_tag_ids_json", "built_in", "ctime", "mtime"}
 	rows := sqlmock.NewRows(metaCols).
-		AddRow("t1", "u1", "Note", "desc", `[]`, int64(1000), int64(2000)).
+		AddRow("t1", "u1", "Note", "desc", `[]`, 0, int64(1000), int64(2000)).
 		RowError(0, errDB)
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 	_, err = r.ListMetaByUser(context.Background(), "u1", "", 10, 0)
@@ -0,0 +1,240 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/didi/gendry/builder"
+
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/dbutil"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+var templateScheduleColumns = []string{
+	"id", "user_id", "template_id", "cron_spec", "title_pattern", "tag_ids_json", "enabled",
+	"next_run_at", "last_run_at", "last_document_id", "last_error", "ctime", "mtime",
+}
+
+type TemplateScheduleRepo struct {
+	db *sql.DB
+}
+
+func NewTemplateScheduleRepo(db *sql.DB) *TemplateScheduleRepo {
+	return &TemplateScheduleRepo{db: db}
+}
+
+func (r *TemplateScheduleRepo) Create(ctx context.Context, item *model.TemplateSchedule) error {
+	tagIDs, err := marshalTemplateScheduleTags(item)
+	if err != nil {
+		return err
+	}
+	data := map[string]any{
+		"id":               item.ID,
+		"user_id":          item.UserID,
+		"template_id":      item.TemplateID,
+		"cron_spec":        item.CronSpec,
+		"title_pattern":    item.TitlePattern,
+		"tag_ids_json":     tagIDs,
+		"enabled":          item.Enabled,
+		"next_run_at":      item.NextRunAt,
+		"last_run_at":      item.LastRunAt,
+		"last_document_id": item.LastDocumentID,
+		"last_error":       item.LastError,
+		"ctime":            item.Ctime,
+		"mtime":            item.Mtime,
+	}
+	sqlStr, args, err := builder.BuildInsert("template_schedules", []map[string]any{data})
+	if err != nil {
+		return fmt.Errorf("build insert: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	if _, err := conn(ctx, r.db).ExecContext(ctx, sqlStr, args...); err != nil {
+		if dbutil.IsConflict(err) {
+			return appErr.ErrConflict
+		}
+		return fmt.Errorf("exec: %w", err)
+	}
+	return nil
+}
+
+// Update replaces the user editable fields and the next slot; the result of
+// the last run is kept.
+func (r *TemplateScheduleRepo) Update(ctx context.Context, item *model.TemplateSchedule) error {
+	tagIDs, err := marshalTemplateScheduleTags(item)
+	if err != nil {
+		return err
+	}
+	where := map[string]any{"id": item.ID, "user_id": item.UserID}
+	update := map[string]any{
+		"template_id":   item.TemplateID,
+		"cron_spec":     item.CronSpec,
+		"title_pattern": item.TitlePattern,
+		"tag_ids_json":  tagIDs,
+		"enabled":       item.Enabled,
+		"next_run_at":   item.NextRunAt,
+		"mtime":         item.Mtime,
+	}
+	sqlStr, args, err := builder.BuildUpdate("template_schedules", where, update)
+	if err != nil {
+		return fmt.Errorf("build update: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	affected, err := dbutil.ExecAffected(ctx, conn(ctx, r.db), sqlStr, args)
+	if err != nil {
+		return fmt.Errorf("update: %w", err)
+	}
+	if affected == 0 {
+		return appErr.ErrNotFound
+	}
+	return nil
+}
+
+// AdvanceRun records a run and moves next_run_at forward. It only succeeds
+// while next_run_at still equals expectedNextRunAt, so a slot is claimed by
+// exactly one run; false means another run already took it.
+func (r *TemplateScheduleRepo) AdvanceRun(
+	ctx context.Context,
+	item *model.TemplateSchedule,
+	expectedNextRunAt int64,
+) (bool, error) {
+	where := map[string]any{
+		"id":          item.ID,
+		"user_id":     item.UserID,
+		"next_run_at": expectedNextRunAt,
+	}
+	update := map[string]any{
+		"enabled":          item.Enabled,
+		"next_run_at":      item.NextRunAt,
+		"last_run_at":      item.LastRunAt,
+		"last_document_id": item.LastDocumentID,
+		"last_error":       item.LastError,
+		"mtime":            item.Mtime,
+	}
+	sqlStr, args, err := builder.BuildUpdate("template_schedules", where, update)
+	if err != nil {
+		return false, fmt.Errorf("build update: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	affected, err := dbutil.ExecAffected(ctx, conn(ctx, r.db), sqlStr, args)
+	if err != nil {
+		return false, fmt.Errorf("update: %w", err)
+	}
+	return affected > 0, nil
+}
+
+func (r *TemplateScheduleRepo) Delete(ctx context.Context, userID, id string) error {
+	sqlStr, args, err := builder.BuildDelete("template_schedules", map[string]any{
+		"id":      id,
+		"user_id": userID,
+	})
+	if err != nil {
+		return fmt.Errorf("build delete: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	affected, err := dbutil.ExecAffected(ctx, conn(ctx, r.db), sqlStr, args)
+	if err != nil {
+		return fmt.Errorf("delete: %w", err)
+	}
+	if affected == 0 {
+		return appErr.ErrNotFound
+	}
+	return nil
+}
+
+func (r *TemplateScheduleRepo) GetByID(ctx context.Context, userID, id string) (*model.TemplateSchedule, error) {
+	where := map[string]any{"id": id, "user_id": userID}
+	sqlStr, args, err := builder.BuildSelect("template_schedules", where, templateScheduleColumns)
+	if err != nil {
+		return nil, fmt.Errorf("build select: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	item, err := scanTemplateSchedule(conn(ctx, r.db).QueryRowContext(ctx, sqlStr, args...))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, appErr.ErrNotFound
+		}
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	return item, nil
+}
+
+func (r *TemplateScheduleRepo) ListByUser(ctx context.Context, userID string) ([]model.TemplateSchedule, error) {
+	return r.listSchedules(ctx, map[string]any{
+		"user_id":  userID,
+		"_orderby": "ctime asc",
+	})
+}
+
+// ListDue returns enabled schedules of every user whose next slot is at or
+// before now, oldest slot first.
+func (r *TemplateScheduleRepo) ListDue(ctx context.Context, now int64, limit int) ([]model.TemplateSchedule, error) {
+	where := map[string]any{
+		"enabled":        1,
+		"next_run_at >":  0,
+		"next_run_at <=": now,
+		"_orderby":       "next_run_at asc",
+	}
+	if limit > 0 {
+		where["_limit"] = []uint{0, uint(limit)}
+	}
+	return r.listSchedules(ctx, where)
+}
+
+func (r *TemplateScheduleRepo) listSchedules(
+	ctx context.Context,
+	where map[string]any,
+) ([]model.TemplateSchedule, error) {
+	sqlStr, args, err := builder.BuildSelect("template_schedules", where, templateScheduleColumns)
+	if err != nil {
+		return nil, fmt.Errorf("build select: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	items := make([]model.TemplateSchedule, 0)
+	for rows.Next() {
+		item, err := scanTemplateSchedule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		items = append(items, *item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+	return items, nil
+}
+
+func scanTemplateSchedule(scanner interface{ Scan(dest ...any) error }) (*model.TemplateSchedule, error) {
+	var item model.TemplateSchedule
+	var tagIDs string
+	if err := scanner.Scan(&item.ID, &item.UserID, &item.TemplateID, &item.CronSpec, &item.TitlePattern,
+		&tagIDs, &item.Enabled, &item.NextRunAt, &item.LastRunAt, &item.LastDocumentID, &item.LastError,
+		&item.Ctime, &item.Mtime); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(tagIDs), &item.TagIDs); err != nil {
+		return nil, fmt.Errorf("decode template_schedules.tag_ids_json for %s: %w", item.ID, err)
+	}
+	return &item, nil
+}
+
+func marshalTemplateScheduleTags(item *model.TemplateSchedule) (string, error) {
+	tagIDs := item.TagIDs
+	if tagIDs == nil {
+		tagIDs = []string{}
+	}
+	data, err := json.Marshal(tagIDs)
+	if err != nil {
+		return "", fmt.Errorf("marshal template schedule %s tag ids: %w", item.ID, err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,136 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+func TestTemplateScheduleRepo_Create(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewTemplateScheduleRepo(db)
+	mock.ExpectExec("INSERT INTO template_schedules").
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(),
+			`["t1"]`, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	err = r.Create(context.Background(), &model.TemplateSchedule{
+		ID: "s1", UserID: "u1", TemplateID: "tpl1", CronSpec: "0 9 * * *", TagIDs: []string{"t1"}, Enabled: 1,
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTemplateScheduleRepo_Update_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewTemplateScheduleRepo(db)
+	mock.ExpectExec("UPDATE template_schedules").WillReturnResult(sqlmock.NewResult(0, 0))
+	err = r.Update(context.Background(), &model.TemplateSchedule{ID: "s1", UserID: "u1"})
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
+
+func TestTemplateScheduleRepo_AdvanceRun(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewTemplateScheduleRepo(db)
+	item := &model.TemplateSchedule{ID: "s1", UserID: "u1", Enabled: 1, NextRunAt: 200, LastRunAt: 100}
+	mock.ExpectExec(`UPDATE template_schedules SET .* WHERE .*next_run_at=\$`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	claimed, err := r.AdvanceRun(context.Background(), item, 100)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	mock.ExpectExec("UPDATE template_schedules").WillReturnResult(sqlmock.NewResult(0, 0))
+	claimed, err = r.AdvanceRun(context.Background(), item, 100)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+}
+
+func TestTemplateScheduleRepo_GetByID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewTemplateScheduleRepo(db)
+	rows := sqlmock.NewRows(templateScheduleColumns).
+		AddRow("s1", "u1", "tpl1", "0 9 * * *", "{{sys:today}}", `["t1"]`, 1,
+			int64(200), int64(100), "d1", "", int64(10), int64(20))
+	mock.ExpectQuery("SELECT").WillReturnRows(rows)
+
+	item, err := r.GetByID(context.Background(), "u1", "s1")
+	require.NoError(t, err)
+	assert.Equal(t, "tpl1", item.TemplateID)
+	assert.Equal(t, []string{"t1"}, item.TagIDs)
+	assert.Equal(t, int64(200), item.NextRunAt)
+	assert.Equal(t, "d1", item.LastDocumentID)
+}
+
+func TestTemplateScheduleRepo_GetByID_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewTemplateScheduleRepo(db)
+	mock.ExpectQuery("SELECT").WillReturnError(sql.ErrNoRows)
+	_, err = r.GetByID(context.Background(), "u1", "missing")
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
+
+func TestTemplateScheduleRepo_GetByID_BadTags(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewTemplateScheduleRepo(db)
+	rows := sqlmock.NewRows(templateScheduleColumns).
+		AddRow("s1", "u1", "tpl1", "0 9 * * *", "", `{`, 1, int64(0), int64(0), "", "", int64(0), int64(0))
+	mock.ExpectQuery("SELECT").WillReturnRows(rows)
+	_, err = r.GetByID(context.Background(), "u1", "s1")
+	assert.ErrorContains(t, err, "template_schedules.tag_ids_json")
+}
+
+func TestTemplateScheduleRepo_ListDue(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewTemplateScheduleRepo(db)
+	rows := sqlmock.NewRows(templateScheduleColumns).
+		AddRow("s1", "u1", "tpl1", "0 9 * * *", "", `[]`, 1, int64(100), int64(0), "", "", int64(0), int64(0)).
+		AddRow("s2", "u2", "tpl2", "@daily", "", `[]`, 1, int64(150), int64(0), "", "", int64(0), int64(0))
+	mock.ExpectQuery(`SELECT .* FROM template_schedules WHERE .*ORDER BY next_run_at asc LIMIT`).
+		WillReturnRows(rows)
+
+	items, err := r.ListDue(context.Background(), 200, 100)
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, "u2", items[1].UserID)
+}
+
+func TestTemplateScheduleRepo_Delete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewTemplateScheduleRepo(db)
+	mock.ExpectExec("DELETE FROM template_schedules").WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, r.Delete(context.Background(), "u1", "s1"))
+
+	mock.ExpectExec("DELETE FROM template_schedules").WillReturnResult(sqlmock.NewResult(0, 0))
+	assert.ErrorIs(t, r.Delete(context.Background(), "u1", "s1"), appErr.ErrNotFound)
+}
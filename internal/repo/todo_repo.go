@@ -3,6 +3,7 @@ package repo
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
 
@@ -13,6 +14,11 @@ import (
 	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
 )
 
+var todoColumns = []string{
+	"id", "user_id", "content", "due_date", "done", "priority", "document_id", "parent_id",
+	"recurrence_json", "series_id", "occurrence", "next_id", "source_anchor", "source_line", "ctime", "mtime",
+}
+
 type TodoRepo struct {
 	db *sql.DB
 }
@@ -22,14 +28,27 @@ func NewTodoRepo(db *sql.DB) *TodoRepo {
 }
 
 func (r *TodoRepo) Create(ctx context.Context, todo *model.Todo) error {
+	recurrence, err := marshalTodoRecurrence(todo.Recurrence)
+	if err != nil {
+		return err
+	}
 	data := map[string]any{
-		"id":       todo.ID,
-		"user_id":  todo.UserID,
-		"content":  todo.Content,
-		"due_date": todo.DueDate,
-		"done":     todo.Done,
-		"ctime":    todo.Ctime,
-		"mtime":    todo.Mtime,
+		"id":              todo.ID,
+		"user_id":         todo.UserID,
+		"content":         todo.Content,
+		"due_date":        todo.DueDate,
+		"done":            todo.Done,
+		"priority":        todo.Priority,
+		"document_id":     todo.DocumentID,
+		"parent_id":       todo.ParentID,
+		"recurrence_json": recurrence,
+		"series_id":       todo.SeriesID,
+		"occurrence":      todo.Occurrence,
+		"next_id":         todo.NextID,
+		"source_anchor":   todo.SourceAnchor,
+		"source_line":     todo.SourceLine,
+		"ctime":           todo.Ctime,
+		"mtime":           todo.Mtime,
 	}
 	sqlStr, args, err := builder.BuildInsert("todos", []map[string]any{data})
 	if err != nil {
@@ -50,11 +69,20 @@ func (r *TodoRepo) Update(ctx context.Context, todo *model.Todo) error {
 		"id":      todo.ID,
 		"user_id": todo.UserID,
 	}
+	recurrence, err := marshalTodoRecurrence(todo.Recurrence)
+	if err != nil {
+		return err
+	}
 	update := map[string]any{
-		"content":  todo.Content,
-		"due_date": todo.DueDate,
-		"done":     todo.Done,
-		"mtime":    todo.Mtime,
+		"content":         todo.Content,
+		"due_date":        todo.DueDate,
+		"done":            todo.Done,
+		"priority":        todo.Priority,
+		"document_id":     todo.DocumentID,
+		"recurrence_json": recurrence,
+		"series_id":       todo.SeriesID,
+		"occurrence":      todo.Occurrence,
+		"mtime":           todo.Mtime,
 	}
 	sqlStr, args, err := builder.BuildUpdate("todos", where, update)
 	if err != nil {
@@ -98,23 +126,19 @@ func (r *TodoRepo) GetByID(ctx context.Context, userID, id string) (*model.Todo,
 		"id":      id,
 		"user_id": userID,
 	}
-	sqlStr, args, err := builder.BuildSelect("todos", where, []string{
-		"id", "user_id", "content", "due_date", "done", "ctime", "mtime",
-	})
+	sqlStr, args, err := builder.BuildSelect("todos", where, todoColumns)
 	if err != nil {
 		return nil, fmt.Errorf("build select: %w", err)
 	}
 	sqlStr, args = dbutil.Finalize(sqlStr, args)
-	row := conn(ctx, r.db).QueryRowContext(ctx, sqlStr, args...)
-	var todo model.Todo
-	if err := row.Scan(&todo.ID, &todo.UserID, &todo.Content, &todo.DueDate, &todo.Done, &todo.Ctime,
-		&todo.Mtime); err != nil {
+	todo, err := scanTodo(conn(ctx, r.db).QueryRowContext(ctx, sqlStr, args...))
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, appErr.ErrNotFound
 		}
 		return nil, fmt.Errorf("query: %w", err)
 	}
-	return &todo, nil
+	return todo, nil
 }
 
 func (r *TodoRepo) ListByDateRange(ctx context.Context, userID, startDate, endDate string) ([]model.Todo, error) {
@@ -124,8 +148,62 @@ func (r *TodoRepo) ListByDateRange(ctx context.Context, userID, startDate, endDa
 		"due_date <=": endDate,
 		"_orderby":    "due_date asc, ctime asc",
 	}
+	return r.listTodos(ctx, where)
+}
+
+// List returns todos matching the query ordered by due date, then by
+// priority (highest first). Undated todos sort before dated ones.
+func (r *TodoRepo) List(ctx context.Context, userID string, query model.TodoListQuery) ([]model.Todo, error) {
+	where := map[string]any{
+		"user_id":  userID,
+		"_orderby": "due_date asc, priority desc, ctime asc",
+	}
+	if query.StartDate != "" {
+		where["due_date >="] = query.StartDate
+	}
+	if query.EndDate != "" {
+		where["due_date <="] = query.EndDate
+	}
+	if len(query.Priorities) > 0 {
+		where["priority in"] = query.Priorities
+	}
+	if query.DocumentID != "" {
+		where["document_id"] = query.DocumentID
+	}
+	if query.ParentID != "" {
+		where["parent_id"] = query.ParentID
+	}
+	if query.Undated {
+		where["due_date"] = ""
+	}
+	if query.Overdue {
+		where["due_date <>"] = ""
+		where["due_date <"] = query.Today
+		where["done"] = 0
+	}
+	if query.Limit > 0 {
+		where["_limit"] = []uint{0, uint(query.Limit)}
+	}
+	return r.listTodos(ctx, where)
+}
+
+// CountSubtasks returns total and completed subtask counts per parent.
+func (r *TodoRepo) CountSubtasks(
+	ctx context.Context,
+	userID string,
+	parentIDs []string,
+) (map[string]model.TodoProgress, error) {
+	result := make(map[string]model.TodoProgress, len(parentIDs))
+	if len(parentIDs) == 0 {
+		return result, nil
+	}
+	where := map[string]any{
+		"user_id":      userID,
+		"parent_id in": parentIDs,
+		"_groupby":     "parent_id",
+	}
 	sqlStr, args, err := builder.BuildSelect("todos", where, []string{
-		"id", "user_id", "content", "due_date", "done", "ctime", "mtime",
+		"parent_id", "COUNT(*)", "COALESCE(SUM(done), 0)",
 	})
 	if err != nil {
 		return nil, fmt.Errorf("build select: %w", err)
@@ -136,15 +214,88 @@ func (r *TodoRepo) ListByDateRange(ctx context.Context, userID, startDate, endDa
 		return nil, fmt.Errorf("query: %w", err)
 	}
 	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var parentID string
+		var progress model.TodoProgress
+		if err := rows.Scan(&parentID, &progress.Total, &progress.Done); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		result[parentID] = progress
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+	return result, nil
+}
+
+// DeleteByParent removes the subtasks of a parent todo.
+func (r *TodoRepo) DeleteByParent(ctx context.Context, userID, parentID string) error {
+	sqlStr, args, err := builder.BuildDelete("todos", map[string]any{
+		"user_id":   userID,
+		"parent_id": parentID,
+	})
+	if err != nil {
+		return fmt.Errorf("build delete: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	if _, err := conn(ctx, r.db).ExecContext(ctx, sqlStr, args...); err != nil {
+		return fmt.Errorf("exec: %w", err)
+	}
+	return nil
+}
+
+// ListRecurringHeads returns recurring todos that have not produced their
+// next occurrence yet and are due on or before endDate; these are the rows
+// a calendar range may expand into virtual occurrences.
+func (r *TodoRepo) ListRecurringHeads(ctx context.Context, userID, endDate string) ([]model.Todo, error) {
+	where := map[string]any{
+		"user_id":            userID,
+		"due_date <=":        endDate,
+		"due_date <>":        "",
+		"recurrence_json <>": "",
+		"next_id":            "",
+		"_orderby":           "due_date asc, ctime asc",
+	}
+	return r.listTodos(ctx, where)
+}
+
+// SetNextID records the occurrence generated from a completed todo. It only
+// succeeds while next_id is still empty, so concurrent or repeated
+// completions generate at most one successor; false means it was taken.
+func (r *TodoRepo) SetNextID(ctx context.Context, userID, id, nextID string, mtime int64) (bool, error) {
+	where := map[string]any{"id": id, "user_id": userID, "next_id": ""}
+	update := map[string]any{"next_id": nextID, "mtime": mtime}
+	sqlStr, args, err := builder.BuildUpdate("todos", where, update)
+	if err != nil {
+		return false, fmt.Errorf("build update: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	affected, err := dbutil.ExecAffected(ctx, conn(ctx, r.db), sqlStr, args)
+	if err != nil {
+		return false, fmt.Errorf("update: %w", err)
+	}
+	return affected > 0, nil
+}
+
+func (r *TodoRepo) listTodos(ctx context.Context, where map[string]any) ([]model.Todo, error) {
+	sqlStr, args, err := builder.BuildSelect("todos", where, todoColumns)
+	if err != nil {
+		return nil, fmt.Errorf("build select: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	rows, err := conn(ctx, r.db).QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
 
 	items := make([]model.Todo, 0)
 	for rows.Next() {
-		var item model.Todo
-		if err := rows.Scan(&item.ID, &item.UserID, &item.Content, &item.DueDate, &item.Done, &item.Ctime,
-			&item.Mtime); err != nil {
+		item, err := scanTodo(rows)
+		if err != nil {
 			return nil, fmt.Errorf("scan: %w", err)
 		}
-		items = append(items, item)
+		items = append(items, *item)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("iterate rows: %w", err)
@@ -174,3 +325,31 @@ func (r *TodoRepo) Delete(ctx context.Context, userID, id string) error {
 	}
 	return nil
 }
+
+func scanTodo(scanner interface{ Scan(dest ...any) error }) (*model.Todo, error) {
+	var todo model.Todo
+	var recurrence string
+	if err := scanner.Scan(&todo.ID, &todo.UserID, &todo.Content, &todo.DueDate, &todo.Done,
+		&todo.Priority, &todo.DocumentID, &todo.ParentID, &recurrence, &todo.SeriesID,
+		&todo.Occurrence, &todo.NextID, &todo.SourceAnchor, &todo.SourceLine, &todo.Ctime, &todo.Mtime); err != nil {
+		return nil, err
+	}
+	if recurrence != "" {
+		todo.Recurrence = &model.TodoRecurrence{}
+		if err := json.Unmarshal([]byte(recurrence), todo.Recurrence); err != nil {
+			return nil, fmt.Errorf("decode todos.recurrence_json for %s: %w", todo.ID, err)
+		}
+	}
+	return &todo, nil
+}
+
+func marshalTodoRecurrence(rule *model.TodoRecurrence) (string, error) {
+	if rule == nil {
+		return "", nil
+	}
+	data, err := json.Marshal(rule)
+	if err != nil {
+		return "", fmt.Errorf("marshal todo recurrence: %w", err)
+	}
+	return string(data), nil
+}
@@ -89,8 +89,8 @@ func TestTodoRepo_GetByID(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	r := NewTodoRepo(db)
-	rows := sqlmock.NewRows([]string{"id", "user_id", "content", "due_date", "done", "ctime", "mtime"}).
-		AddRow("t1", "u1", "task", "2026-01-01", 0, int64(1000), int64(2000))
+	rows := sqlmock.NewRows(todoColumns).
+		AddRow("t1", "u1", "task", "2026-01-01", 0, 0, "", "", "", "", 0, "", "", 0, int64(1000), int64(2000))
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 
 	todo, err := r.GetByID(context.Background(), "u1", "t1")
@@ -116,9 +116,9 @@ func TestTodoRepo_ListByDateRange(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	r := NewTodoRepo(db)
-	rows := sqlmock.NewRows([]string{"id", "user_id", "content", "due_date", "done", "ctime", "mtime"}).
-		AddRow("t1", "u1", "task1", "2026-01-01", 0, int64(1000), int64(2000)).
-		AddRow("t2", "u1", "task2", "2026-01-02", 1, int64(1000), int64(2000))
+	rows := sqlmock.NewRows(todoColumns).
+		AddRow("t1", "u1", "task1", "2026-01-01", 0, 0, "", "", "", "", 0, "", "", 0, int64(1000), int64(2000)).
+		AddRow("t2", "u1", "task2", "2026-01-02", 1, 0, "", "", "", "", 0, "", "", 0, int64(1000), int64(2000))
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 
 	items, err := r.ListByDateRange(context.Background(), "u1", "2026-01-01", "2026-01-31")
@@ -258,10 +258,120 @@ func TestTodoRepo_ListByDateRange_RowsErr(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	r := NewTodoRepo(db)
-	rows := sqlmock.NewRows([]string{"id", "user_id", "content", "due_date", "done", "ctime", "mtime"}).
-		AddRow("t1", "u1", "task", "2026-01-15", 0, int64(1000), int64(2000)).
+	rows := sqlmock.NewRows(todoColumns).
+		AddRow("t1", "u1", "task", "2026-01-15", 0, 0, "", "", "", "", 0, "", "", 0, int64(1000), int64(2000)).
 		RowError(0, errDB)
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 	_, err = r.ListByDateRange(context.Background(), "u1", "2026-01-01", "2026-01-31")
 	assert.Error(t, err)
 }
+
+func TestTodoRepo_GetByID_Recurrence(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewTodoRepo(db)
+	rows := sqlmock.NewRows(todoColumns).
+		AddRow("t1", "u1", "review", "2026-01-05", 0, 0, "", "",
+			`{"freq":"weekly","interval":1,"weekdays":[1]}`, "t0", 3, "", "", 0, int64(1000), int64(2000))
+	mock.ExpectQuery("SELECT").WillReturnRows(rows)
+
+	todo, err := r.GetByID(context.Background(), "u1", "t1")
+	require.NoError(t, err)
+	require.NotNil(t, todo.Recurrence)
+	assert.Equal(t, model.TodoRepeatWeekly, todo.Recurrence.Freq)
+	assert.Equal(t, []int{1}, todo.Recurrence.Weekdays)
+	assert.Equal(t, "t0", todo.SeriesID)
+	assert.Equal(t, 3, todo.Occurrence)
+}
+
+func TestTodoRepo_ListRecurringHeads(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewTodoRepo(db)
+	rows := sqlmock.NewRows(todoColumns).
+		AddRow("t1", "u1", "review", "2026-01-05", 0, 0, "", "", `{"freq":"daily","interval":2}`,
+			"t1", 1, "", "", 0, int64(1000), int64(2000))
+	mock.ExpectQuery(`SELECT .* FROM todos WHERE .*next_id=.*recurrence_json!=`).
+		WillReturnRows(rows)
+
+	items, err := r.ListRecurringHeads(context.Background(), "u1", "2026-01-31")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, 2, items[0].Recurrence.Interval)
+}
+
+func TestTodoRepo_SetNextID(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewTodoRepo(db)
+	mock.ExpectExec("UPDATE todos SET").WillReturnResult(sqlmock.NewResult(0, 1))
+	claimed, err := r.SetNextID(context.Background(), "u1", "t1", "t2", 1000)
+	require.NoError(t, err)
+	assert.True(t, claimed)
+
+	mock.ExpectExec("UPDATE todos SET").WillReturnResult(sqlmock.NewResult(0, 0))
+	claimed, err = r.SetNextID(context.Background(), "u1", "t1", "t3", 1000)
+	require.NoError(t, err)
+	assert.False(t, claimed)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTodoRepo_List(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewTodoRepo(db)
+	rows := sqlmock.NewRows(todoColumns).
+		AddRow("t1", "u1", "task", "2026-01-01", 0, 3, "d1", "", "", "", 0, "", "", 0, int64(1000), int64(2000))
+	mock.ExpectQuery(`SELECT .* FROM todos WHERE .*document_id=.*done=.*priority IN .*due_date!=.*due_date<.*` +
+		`ORDER BY due_date asc, priority desc, ctime asc LIMIT`).
+		WillReturnRows(rows)
+
+	items, err := r.List(context.Background(), "u1", model.TodoListQuery{
+		Priorities: []int{2, 3}, DocumentID: "d1", Overdue: true, Today: "2026-01-02", Limit: 500,
+	})
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, 3, items[0].Priority)
+	assert.Equal(t, "d1", items[0].DocumentID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTodoRepo_CountSubtasks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewTodoRepo(db)
+	counts, err := r.CountSubtasks(context.Background(), "u1", nil)
+	require.NoError(t, err)
+	assert.Empty(t, counts)
+
+	rows := sqlmock.NewRows([]string{"parent_id", "total", "done"}).AddRow("p1", 3, 2)
+	mock.ExpectQuery(`SELECT parent_id,COUNT\(\*\),COALESCE\(SUM\(done\), 0\) FROM todos .*GROUP BY parent_id`).
+		WillReturnRows(rows)
+	counts, err = r.CountSubtasks(context.Background(), "u1", []string{"p1", "p2"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]model.TodoProgress{"p1": {Total: 3, Done: 2}}, counts)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTodoRepo_DeleteByParent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewTodoRepo(db)
+	mock.ExpectExec(`DELETE FROM todos WHERE .*parent_id=`).WillReturnResult(sqlmock.NewResult(0, 2))
+	require.NoError(t, r.DeleteByParent(context.Background(), "u1", "p1"))
+
+	mock.ExpectExec("DELETE FROM todos").WillReturnError(errDB)
+	assert.Error(t, r.DeleteByParent(context.Background(), "u1", "p1"))
+}
@@ -27,13 +27,17 @@ func (r *UserRepo) Create(ctx context.Context, user *model.User) error {
 		"email":            user.Email,
 		"email_normalized": user.EmailNormalized,
 		"password_hash":    user.PasswordHash,
+		"role":             userRole(user.Role),
+		"disabled":         user.Disabled,
 		"ctime":            user.Ctime,
 		"mtime":            user.Mtime,
 	})
 }
 
 func (r *UserRepo) getUser(ctx context.Context, where map[string]any) (*model.User, error) {
-	cols := []string{"id", "email", "COALESCE(email_normalized, '')", "password_hash", "ctime", "mtime"}
+	cols := []string{
+		"id", "email", "COALESCE(email_normalized, '')", "password_hash", "role", "disabled", "ctime", "mtime",
+	}
 	sqlStr, args, err := builder.BuildSelect("users", where, cols)
 	if err != nil {
 		return nil, fmt.Errorf("build select: %w", err)
@@ -50,14 +54,11 @@ func (r *UserRepo) getUser(ctx context.Context, where map[string]any) (*model.Us
 		}
 		return nil, appErr.ErrNotFound
 	}
-	var user model.User
-	if err := rows.Scan(
-		&user.ID, &user.Email, &user.EmailNormalized,
-		&user.PasswordHash, &user.Ctime, &user.Mtime,
-	); err != nil {
+	user, err := scanUser(rows)
+	if err != nil {
 		return nil, fmt.Errorf("scan: %w", err)
 	}
-	return &user, nil
+	return user, nil
 }
 
 func (r *UserRepo) GetByEmail(ctx context.Context, email string) (*model.User, error) {
@@ -70,7 +71,7 @@ func (r *UserRepo) GetByNormalizedEmail(ctx context.Context, normalized string)
 
 func (r *UserRepo) GetLegacyByExactEmail(ctx context.Context, trimmed string) (*model.User, error) {
 	const query = `
-		SELECT id, email, COALESCE(email_normalized, ''), password_hash, ctime, mtime
+		SELECT id, email, COALESCE(email_normalized, ''), password_hash, role, disabled, ctime, mtime
 		FROM users
 		WHERE email_normalized IS NULL AND BTRIM(email) = $1
 	`
@@ -92,7 +93,7 @@ func (r *UserRepo) GetByID(ctx context.Context, userID string) (*model.User, err
 
 func (r *UserRepo) GetByIDForUpdate(ctx context.Context, userID string) (*model.User, error) {
 	const query = `
-		SELECT id, email, COALESCE(email_normalized, ''), password_hash, ctime, mtime
+		SELECT id, email, COALESCE(email_normalized, ''), password_hash, role, disabled, ctime, mtime
 		FROM users
 		WHERE id = $1
 		FOR UPDATE
@@ -101,19 +102,98 @@ func (r *UserRepo) GetByIDForUpdate(ctx context.Context, userID string) (*model.
 }
 
 func (r *UserRepo) scanUserRow(row *sql.Row) (*model.User, error) {
-	var user model.User
-	if err := row.Scan(
-		&user.ID, &user.Email, &user.EmailNormalized,
-		&user.PasswordHash, &user.Ctime, &user.Mtime,
-	); err != nil {
+	user, err := scanUser(row)
+	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, appErr.ErrNotFound
 		}
 		return nil, fmt.Errorf("scan user: %w", err)
 	}
+	return user, nil
+}
+
+func scanUser(scanner interface{ Scan(dest ...any) error }) (*model.User, error) {
+	var user model.User
+	if err := scanner.Scan(
+		&user.ID, &user.Email, &user.EmailNormalized,
+		&user.PasswordHash, &user.Role, &user.Disabled, &user.Ctime, &user.Mtime,
+	); err != nil {
+		return nil, err
+	}
 	return &user, nil
 }
 
+// List returns all users ordered by creation time.
+func (r *UserRepo) List(ctx context.Context) ([]model.User, error) {
+	const query = `
+		SELECT id, email, COALESCE(email_normalized, ''), password_hash, role, disabled, ctime, mtime
+		FROM users
+		ORDER BY ctime ASC, id ASC
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	users := make([]model.User, 0)
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		users = append(users, *user)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+	return users, nil
+}
+
+func (r *UserRepo) UpdateDisabled(ctx context.Context, userID string, disabled int, mtime int64) error {
+	sqlStr, args, err := builder.BuildUpdate("users", map[string]any{"id": userID}, map[string]any{
+		"disabled": disabled,
+		"mtime":    mtime,
+	})
+	if err != nil {
+		return fmt.Errorf("build update: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	affected, err := dbutil.ExecAffected(ctx, conn(ctx, r.db), sqlStr, args)
+	if err != nil {
+		return fmt.Errorf("update disabled: %w", err)
+	}
+	if affected == 0 {
+		return appErr.ErrNotFound
+	}
+	return nil
+}
+
+func (r *UserRepo) UpdateRole(ctx context.Context, userID, role string, mtime int64) error {
+	sqlStr, args, err := builder.BuildUpdate("users", map[string]any{"id": userID}, map[string]any{
+		"role":  role,
+		"mtime": mtime,
+	})
+	if err != nil {
+		return fmt.Errorf("build update: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	affected, err := dbutil.ExecAffected(ctx, conn(ctx, r.db), sqlStr, args)
+	if err != nil {
+		return fmt.Errorf("update role: %w", err)
+	}
+	if affected == 0 {
+		return appErr.ErrNotFound
+	}
+	return nil
+}
+
+func userRole(role string) string {
+	if role == "" {
+		return model.UserRoleUser
+	}
+	return role
+}
+
 func (r *UserRepo) UpdatePassword(ctx context.Context, userID, passwordHash string, mtime int64) error {
 	where := map[string]any{"id": userID}
 	update := map[string]any{
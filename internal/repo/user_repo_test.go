@@ -12,6 +12,10 @@ import (
 	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
 )
 
+var userTestColumns = []string{
+	"id", "email", "email_normalized", "password_hash", "role", "disabled", "ctime", "mtime",
+}
+
 func TestUserRepo_Create(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	require.NoError(t, err)
@@ -50,8 +54,8 @@ func TestUserRepo_GetByEmail(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	r := NewUserRepo(db)
-	rows := sqlmock.NewRows([]string{"id", "email", "email_normalized", "password_hash", "ctime", "mtime"}).
-		AddRow("u1", "test@example.com", "test@example.com", "hash", int64(1000), int64(2000))
+	rows := sqlmock.NewRows(userTestColumns).
+		AddRow("u1", "test@example.com", "test@example.com", "hash", "user", 0, int64(1000), int64(2000))
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 
 	user, err := r.GetByEmail(context.Background(), "test@example.com")
@@ -67,7 +71,7 @@ func TestUserRepo_GetByEmail_NotFound(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	r := NewUserRepo(db)
-	rows := sqlmock.NewRows([]string{"id", "email", "email_normalized", "password_hash", "ctime", "mtime"})
+	rows := sqlmock.NewRows(userTestColumns)
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 
 	_, err = r.GetByEmail(context.Background(), "missing@example.com")
@@ -80,8 +84,8 @@ func TestUserRepo_GetByID(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	r := NewUserRepo(db)
-	rows := sqlmock.NewRows([]string{"id", "email", "email_normalized", "password_hash", "ctime", "mtime"}).
-		AddRow("u1", "test@example.com", "test@example.com", "hash", int64(1000), int64(2000))
+	rows := sqlmock.NewRows(userTestColumns).
+		AddRow("u1", "test@example.com", "test@example.com", "hash", "user", 0, int64(1000), int64(2000))
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 
 	user, err := r.GetByID(context.Background(), "u1")
@@ -185,3 +189,53 @@ func TestUserRepo_GetByEmail_RowsErr(t *testing.T) {
 	assert.Error(t, err)
 	assert.NotErrorIs(t, err, appErr.ErrNotFound)
 }
+
+func TestUserRepo_List(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewUserRepo(db)
+	rows := sqlmock.NewRows(userTestColumns).
+		AddRow("u1", "a@example.com", "a@example.com", "hash", "admin", 0, int64(1), int64(1)).
+		AddRow("u2", "b@example.com", "b@example.com", "", "user", 1, int64(2), int64(2))
+	mock.ExpectQuery("SELECT .* FROM users ORDER BY ctime ASC, id ASC").WillReturnRows(rows)
+
+	users, err := r.List(context.Background())
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, 1, users[1].Disabled)
+	assert.True(t, users[0].IsAdmin())
+}
+
+func TestUserRepo_UpdateDisabled(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewUserRepo(db)
+	mock.ExpectExec("UPDATE users SET").
+		WithArgs(1, int64(3000), "u1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, r.UpdateDisabled(context.Background(), "u1", 1, 3000))
+
+	mock.ExpectExec("UPDATE users SET").WillReturnResult(sqlmock.NewResult(0, 0))
+	err = r.UpdateDisabled(context.Background(), "missing", 1, 3000)
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
+
+func TestUserRepo_UpdateRole(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewUserRepo(db)
+	mock.ExpectExec("UPDATE users SET").
+		WithArgs(int64(3000), "admin", "u1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	require.NoError(t, r.UpdateRole(context.Background(), "u1", "admin", 3000))
+
+	mock.ExpectExec("UPDATE users SET").WillReturnResult(sqlmock.NewResult(0, 0))
+	err = r.UpdateRole(context.Background(), "missing", "admin", 3000)
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
@@ -0,0 +1,51 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/xxxsen/mnote/internal/model"
+)
+
+// listVersionActivityQuery pairs every version saved in [$2, $3) with the
+// version before it, which may be older than the range. Version 1 counts
+// its words as added; a missing or NULL count leaves the delta NULL.
+const listVersionActivityQuery = `WITH touched AS (
+            SELECT document_id, title, version, ctime, word_count,
+                LAG(word_count) OVER w AS prev_count, LAG(version) OVER w AS prev_version
+            FROM document_versions
+            WHERE user_id = $1 AND document_id IN (
+                SELECT document_id FROM document_versions WHERE user_id = $1 AND ctime >= $2 AND ctime < $3)
+            WINDOW w AS (PARTITION BY document_id ORDER BY version))
+        SELECT document_id, title, version, ctime,
+            CASE WHEN version = 1 THEN word_count
+                 WHEN prev_version IS NOT NULL THEN word_count - prev_count END
+        FROM touched WHERE ctime >= $2 AND ctime < $3
+        ORDER BY ctime ASC, document_id ASC, version ASC`
+
+// ListActivity returns the versions a user saved in [from, to), oldest
+// first, each with its change in words.
+func (r *VersionRepo) ListActivity(
+	ctx context.Context, userID string, from, to int64,
+) ([]model.VersionActivity, error) {
+	rows, err := conn(ctx, r.db).QueryContext(ctx, listVersionActivityQuery, userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	items := make([]model.VersionActivity, 0)
+	for rows.Next() {
+		var item model.VersionActivity
+		var delta sql.NullInt64
+		if err := rows.Scan(&item.DocumentID, &item.Title, &item.Version, &item.Ctime, &delta); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		item.WordDelta, item.DeltaKnown = int(delta.Int64), delta.Valid
+		items = append(items, item)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+	return items, nil
+}
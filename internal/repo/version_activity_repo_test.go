@@ -0,0 +1,45 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+)
+
+func TestVersionRepo_ListActivity(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery(`LAG\(word_count\) OVER w.*FROM touched WHERE ctime >= \$2 AND ctime < \$3`).
+		WithArgs("u1", int64(100), int64(200)).
+		WillReturnRows(sqlmock.NewRows([]string{"document_id", "title", "version", "ctime", "delta"}).
+			AddRow("d1", "New", 1, int64(110), 40).
+			AddRow("d2", "Old", 7, int64(150), nil).
+			AddRow("d1", "New", 2, int64(190), -5))
+
+	items, err := NewVersionRepo(db).ListActivity(context.Background(), "u1", 100, 200)
+	require.NoError(t, err)
+	assert.Equal(t, []model.VersionActivity{
+		{DocumentID: "d1", Title: "New", Version: 1, Ctime: 110, WordDelta: 40, DeltaKnown: true},
+		{DocumentID: "d2", Title: "Old", Version: 7, Ctime: 150},
+		{DocumentID: "d1", Title: "New", Version: 2, Ctime: 190, WordDelta: -5, DeltaKnown: true},
+	}, items)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestVersionRepo_ListActivity_QueryError(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	mock.ExpectQuery("FROM touched").WillReturnError(errors.New("db down"))
+	_, err = NewVersionRepo(db).ListActivity(context.Background(), "u1", 0, 1)
+	assert.Error(t, err)
+}
@@ -28,6 +28,8 @@ func (r *VersionRepo) Create(ctx context.Context, version *model.DocumentVersion
 		"version":     version.Version,
 		"title":       version.Title,
 		"content":     version.Content,
+		"author_id":   version.AuthorID,
+		"word_count":  version.WordCount,
 		"ctime":       version.Ctime,
 	}
 	sqlStr, args, err := builder.BuildInsert("document_versions", []map[string]any{data})
@@ -50,7 +52,7 @@ func (r *VersionRepo) List(ctx context.Context, userID, docID string) ([]model.D
 	}
 	sqlStr, args, err := builder.BuildSelect("document_versions", where, []string{
 		"id", "user_id", "document_id",
-		"version", "title", "content", "ctime",
+		"version", "title", "content", "author_id", "ctime",
 	})
 	if err != nil {
 		return nil, fmt.Errorf("build select: %w", err)
@@ -64,7 +66,9 @@ func (r *VersionRepo) List(ctx context.Context, userID, docID string) ([]model.D
 	versions := make([]model.DocumentVersion, 0)
 	for rows.Next() {
 		var v model.DocumentVersion
-		if err := rows.Scan(&v.ID, &v.UserID, &v.DocumentID, &v.Version, &v.Title, &v.Content, &v.Ctime); err != nil {
+		if err := rows.Scan(
+			&v.ID, &v.UserID, &v.DocumentID, &v.Version, &v.Title, &v.Content, &v.AuthorID, &v.Ctime,
+		); err != nil {
 			return nil, fmt.Errorf("scan: %w", err)
 		}
 		versions = append(versions, v)
@@ -83,7 +87,7 @@ func (r *VersionRepo) ListSummaries(ctx context.Context, userID, docID string) (
 	}
 	sqlStr, args, err := builder.BuildSelect("document_versions", where, []string{
 		"id", "document_id", "version",
-		"title", "ctime",
+		"title", "author_id", "ctime",
 	})
 	if err != nil {
 		return nil, fmt.Errorf("build select: %w", err)
@@ -97,7 +101,7 @@ func (r *VersionRepo) ListSummaries(ctx context.Context, userID, docID string) (
 	versions := make([]model.DocumentVersionSummary, 0)
 	for rows.Next() {
 		var v model.DocumentVersionSummary
-		if err := rows.Scan(&v.ID, &v.DocumentID, &v.Version, &v.Title, &v.Ctime); err != nil {
+		if err := rows.Scan(&v.ID, &v.DocumentID, &v.Version, &v.Title, &v.AuthorID, &v.Ctime); err != nil {
 			return nil, fmt.Errorf("scan: %w", err)
 		}
 		versions = append(versions, v)
@@ -115,7 +119,7 @@ func (r *VersionRepo) ListByUser(ctx context.Context, userID string) ([]model.Do
 	}
 	sqlStr, args, err := builder.BuildSelect("document_versions", where, []string{
 		"id", "user_id", "document_id",
-		"version", "title", "content", "ctime",
+		"version", "title", "content", "author_id", "ctime",
 	})
 	if err != nil {
 		return nil, fmt.Errorf("build select: %w", err)
@@ -129,7 +133,9 @@ func (r *VersionRepo) ListByUser(ctx context.Context, userID string) ([]model.Do
 	versions := make([]model.DocumentVersion, 0)
 	for rows.Next() {
 		var v model.DocumentVersion
-		if err := rows.Scan(&v.ID, &v.UserID, &v.DocumentID, &v.Version, &v.Title, &v.Content, &v.Ctime); err != nil {
+		if err := rows.Scan(
+			&v.ID, &v.UserID, &v.DocumentID, &v.Version, &v.Title, &v.Content, &v.AuthorID, &v.Ctime,
+		); err != nil {
 			return nil, fmt.Errorf("scan: %w", err)
 		}
 		versions = append(versions, v)
@@ -154,7 +160,7 @@ func (
 	}
 	sqlStr, args, err := builder.BuildSelect("document_versions", where, []string{
 		"id", "user_id", "document_id",
-		"version", "title", "content", "ctime",
+		"version", "title", "content", "author_id", "ctime",
 	})
 	if err != nil {
 		return nil, fmt.Errorf("build select: %w", err)
@@ -172,7 +178,9 @@ func (
 		return nil, appErr.ErrNotFound
 	}
 	var v model.DocumentVersion
-	if err := rows.Scan(&v.ID, &v.UserID, &v.DocumentID, &v.Version, &v.Title, &v.Content, &v.Ctime); err != nil {
+	if err := rows.Scan(
+		&v.ID, &v.UserID, &v.DocumentID, &v.Version, &v.Title, &v.Content, &v.AuthorID, &v.Ctime,
+	); err != nil {
 		return nil, fmt.Errorf("scan: %w", err)
 	}
 	return &v, nil
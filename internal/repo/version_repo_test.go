@@ -12,7 +12,7 @@ import (
 	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
 )
 
-var verCols = []string{"id", "user_id", "document_id", "version", "title", "content", "ctime"}
+var verCols = []string{"id", "user_id", "document_id", "version", "title", "content", "author_id", "ctime"}
 
 func TestVersionRepo_Create(t *testing.T) {
 	db, mock, err := sqlmock.New()
@@ -36,8 +36,8 @@ func TestVersionRepo_List(t *testing.T) {
 
 	r := NewVersionRepo(db)
 	rows := sqlmock.NewRows(verCols).
-		AddRow("v2", "u1", "d1", 2, "title2", "c2", int64(2000)).
-		AddRow("v1", "u1", "d1", 1, "title1", "c1", int64(1000))
+		AddRow("v2", "u1", "d1", 2, "title2", "c2", "u1", int64(2000)).
+		AddRow("v1", "u1", "d1", 1, "title1", "c1", "u1", int64(1000))
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 
 	versions, err := r.List(context.Background(), "u1", "d1")
@@ -52,9 +52,9 @@ func TestVersionRepo_ListSummaries(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	r := NewVersionRepo(db)
-	sumCols := []string{"id", "document_id", "version", "title", "ctime"}
+	sumCols := []string{"id", "document_id", "version", "title", "author_id", "ctime"}
 	rows := sqlmock.NewRows(sumCols).
-		AddRow("v1", "d1", 1, "title1", int64(1000))
+		AddRow("v1", "d1", 1, "title1", "u1", int64(1000))
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 
 	summaries, err := r.ListSummaries(context.Background(), "u1", "d1")
@@ -69,7 +69,7 @@ func TestVersionRepo_ListByUser(t *testing.T) {
 
 	r := NewVersionRepo(db)
 	rows := sqlmock.NewRows(verCols).
-		AddRow("v1", "u1", "d1", 1, "t1", "c1", int64(1000))
+		AddRow("v1", "u1", "d1", 1, "t1", "c1", "u1", int64(1000))
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 
 	versions, err := r.ListByUser(context.Background(), "u1")
@@ -84,7 +84,7 @@ func TestVersionRepo_GetByVersion(t *testing.T) {
 
 	r := NewVersionRepo(db)
 	rows := sqlmock.NewRows(verCols).
-		AddRow("v1", "u1", "d1", 1, "title1", "content1", int64(1000))
+		AddRow("v1", "u1", "d1", 1, "title1", "content1", "u1", int64(1000))
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 
 	v, err := r.GetByVersion(context.Background(), "u1", "d1", 1)
@@ -248,8 +248,8 @@ func TestVersionRepo_List_RowsErr(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	r := NewVersionRepo(db)
-	rows := sqlmock.NewRows([]string{"id", "user_id", "document_id", "version", "title", "content", "ctime"}).
-		AddRow("v1", "u1", "d1", 1, "Title", "Content", int64(1000)).
+	rows := sqlmock.NewRows([]string{"id", "user_id", "document_id", "version", "title", "content", "author_id", "ctime"}).
+		AddRow("v1", "u1", "d1", 1, "Title", "Content", "u1", int64(1000)).
 		RowError(0, errDB)
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 	_, err = r.List(context.Background(), "u1", "d1")
@@ -262,8 +262,8 @@ func TestVersionRepo_ListSummaries_RowsErr(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	r := NewVersionRepo(db)
-	rows := sqlmock.NewRows([]string{"id", "document_id", "version", "title", "ctime"}).
-		AddRow("v1", "d1", 1, "Title", int64(1000)).
+	rows := sqlmock.NewRows([]string{"id", "document_id", "version", "title", "author_id", "ctime"}).
+		AddRow("v1", "d1", 1, "Title", "u1", int64(1000)).
 		RowError(0, errDB)
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 	_, err = r.ListSummaries(context.Background(), "u1", "d1")
@@ -276,8 +276,8 @@ func TestVersionRepo_ListByUser_RowsErr(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	r := NewVersionRepo(db)
-	rows := sqlmock.NewRows([]string{"id", "user_id", "document_id", "version", "title", "content", "ctime"}).
-		AddRow("v1", "u1", "d1", 1, "Title", "Content", int64(1000)).
+	rows := sqlmock.NewRows([]string{"id", "user_id", "document_id", "version", "title", "content", "author_id", "ctime"}).
+		AddRow("v1", "u1", "d1", 1, "Title", "Content", "u1", int64(1000)).
 		RowError(0, errDB)
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 	_, err = r.ListByUser(context.Background(), "u1")
@@ -290,7 +290,7 @@ func TestVersionRepo_GetByVersion_RowsErr(t *testing.T) {
 	defer func() { _ = db.Close() }()
 
 	r := NewVersionRepo(db)
-	rows := sqlmock.NewRows([]string{"id", "user_id", "document_id", "version", "title", "content", "ctime"}).
+	rows := sqlmock.NewRows([]string{"id", "user_id", "document_id", "version", "title", "content", "author_id", "ctime"}).
 		CloseError(errDB)
 	mock.ExpectQuery("SELECT").WillReturnRows(rows)
 	_, err = r.GetByVersion(context.Background(), "u1", "d1", 1)
@@ -64,6 +64,32 @@ func TestImportJobClaimHasSingleConcurrentWinner(t *testing.T) {
 	require.Equal(t, 1, winners)
 }
 
+func TestImportJobClaimJobSkipsOtherJobs(t *testing.T) {
+	database, cleanup := testutil.OpenTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	jobs := repo.NewImportJobRepo(database)
+	for i, id := range []string{"job-1", "job-2"} {
+		require.NoError(t, jobs.Create(ctx, &model.ImportJob{
+			ID: id, UserID: "user-1", Source: "notes",
+			Status: model.ImportStatusReady, Ctime: int64(100 + i), Mtime: 100,
+		}))
+		confirmed, err := jobs.Confirm(ctx, "user-1", id, model.ImportModeSkip, 101)
+		require.NoError(t, err)
+		require.True(t, confirmed)
+	}
+
+	job, err := jobs.ClaimJob(ctx, "job-2", 200, 500)
+	require.NoError(t, err)
+	require.Equal(t, "job-2", job.ID)
+	_, err = jobs.ClaimJob(ctx, "job-2", 200, 500)
+	require.ErrorIs(t, err, appErr.ErrNoWork)
+	job, err = jobs.Claim(ctx, 200, 500)
+	require.NoError(t, err)
+	require.Equal(t, "job-1", job.ID)
+}
+
 func TestAssetCleanupClaimExcludesReadyAndHasSingleWinner(t *testing.T) {
 	database, cleanup := testutil.OpenTestDB(t)
 	defer cleanup()
@@ -0,0 +1,256 @@
+package repo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/didi/gendry/builder"
+
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/pkg/dbutil"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+type WorkspaceRepo struct {
+	db *sql.DB
+}
+
+func NewWorkspaceRepo(db *sql.DB) *WorkspaceRepo {
+	return &WorkspaceRepo{db: db}
+}
+
+const workspaceColumns = `id, name, owner_id, ctime, mtime`
+
+func (r *WorkspaceRepo) Create(ctx context.Context, workspace *model.Workspace) error {
+	return insertRecord(ctx, r.db, "workspaces", map[string]any{
+		"id":       workspace.ID,
+		"name":     workspace.Name,
+		"owner_id": workspace.OwnerID,
+		"ctime":    workspace.Ctime,
+		"mtime":    workspace.Mtime,
+	})
+}
+
+func (r *WorkspaceRepo) GetByID(ctx context.Context, id string) (*model.Workspace, error) {
+	query := `SELECT ` + workspaceColumns + ` FROM workspaces WHERE id = $1`
+	return r.scanWorkspaceRow(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+}
+
+// GetByIDForUpdate locks the workspace row so membership changes of one
+// workspace serialize. Call it inside a transaction.
+func (r *WorkspaceRepo) GetByIDForUpdate(ctx context.Context, id string) (*model.Workspace, error) {
+	query := `SELECT ` + workspaceColumns + ` FROM workspaces WHERE id = $1 FOR UPDATE`
+	return r.scanWorkspaceRow(conn(ctx, r.db).QueryRowContext(ctx, query, id))
+}
+
+func (r *WorkspaceRepo) scanWorkspaceRow(row *sql.Row) (*model.Workspace, error) {
+	var workspace model.Workspace
+	if err := row.Scan(
+		&workspace.ID, &workspace.Name, &workspace.OwnerID, &workspace.Ctime, &workspace.Mtime,
+	); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, appErr.ErrNotFound
+		}
+		return nil, fmt.Errorf("scan workspace: %w", err)
+	}
+	return &workspace, nil
+}
+
+// ListByUser returns the team workspaces userID is a member of, with Role
+// set to the user's role.
+func (r *WorkspaceRepo) ListByUser(ctx context.Context, userID string) ([]model.Workspace, error) {
+	const query = `
+		SELECT w.id, w.name, w.owner_id, w.ctime, w.mtime, m.role
+		FROM workspaces w
+		JOIN workspace_members m ON m.workspace_id = w.id
+		WHERE m.user_id = $1
+		ORDER BY w.ctime ASC, w.id ASC
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	items := make([]model.Workspace, 0)
+	for rows.Next() {
+		var workspace model.Workspace
+		if err := rows.Scan(
+			&workspace.ID, &workspace.Name, &workspace.OwnerID,
+			&workspace.Ctime, &workspace.Mtime, &workspace.Role,
+		); err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		items = append(items, workspace)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+	return items, nil
+}
+
+func (r *WorkspaceRepo) UpdateName(ctx context.Context, id, name string, mtime int64) error {
+	sqlStr, args, err := builder.BuildUpdate("workspaces", map[string]any{"id": id}, map[string]any{
+		"name":  name,
+		"mtime": mtime,
+	})
+	if err != nil {
+		return fmt.Errorf("build update: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	affected, err := dbutil.ExecAffected(ctx, conn(ctx, r.db), sqlStr, args)
+	if err != nil {
+		return fmt.Errorf("update workspace: %w", err)
+	}
+	if affected == 0 {
+		return appErr.ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes the workspace; its members go with it through the foreign
+// key.
+func (r *WorkspaceRepo) Delete(ctx context.Context, id string) error {
+	sqlStr, args, err := builder.BuildDelete("workspaces", map[string]any{"id": id})
+	if err != nil {
+		return fmt.Errorf("build delete: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	affected, err := dbutil.ExecAffected(ctx, conn(ctx, r.db), sqlStr, args)
+	if err != nil {
+		return fmt.Errorf("delete workspace: %w", err)
+	}
+	if affected == 0 {
+		return appErr.ErrNotFound
+	}
+	return nil
+}
+
+// HasContent reports whether any document, tag, template or asset is still
+// owned by the workspace.
+func (r *WorkspaceRepo) HasContent(ctx context.Context, id string) (bool, error) {
+	const query = `SELECT EXISTS (SELECT 1 FROM documents WHERE user_id = $1 AND state = $2)
+		OR EXISTS (SELECT 1 FROM tags WHERE user_id = $1)
+		OR EXISTS (SELECT 1 FROM templates WHERE user_id = $1)
+		OR EXISTS (SELECT 1 FROM assets WHERE user_id = $1)`
+	var exists bool
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, id, DocumentStateNormal).Scan(&exists); err != nil {
+		return false, fmt.Errorf("query workspace content: %w", err)
+	}
+	return exists, nil
+}
+
+func (r *WorkspaceRepo) AddMember(ctx context.Context, member *model.WorkspaceMember) error {
+	return insertRecord(ctx, r.db, "workspace_members", map[string]any{
+		"workspace_id": member.WorkspaceID,
+		"user_id":      member.UserID,
+		"role":         member.Role,
+		"ctime":        member.Ctime,
+		"mtime":        member.Mtime,
+	})
+}
+
+func (r *WorkspaceRepo) GetMember(ctx context.Context, workspaceID, userID string) (*model.WorkspaceMember, error) {
+	const query = `
+		SELECT m.workspace_id, m.user_id, COALESCE(u.email, ''), m.role, m.ctime, m.mtime
+		FROM workspace_members m
+		LEFT JOIN users u ON u.id = m.user_id
+		WHERE m.workspace_id = $1 AND m.user_id = $2
+	`
+	member, err := scanWorkspaceMember(conn(ctx, r.db).QueryRowContext(ctx, query, workspaceID, userID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, appErr.ErrNotFound
+		}
+		return nil, fmt.Errorf("get workspace member: %w", err)
+	}
+	return member, nil
+}
+
+// ListMembers returns the members of the workspace, oldest first.
+func (r *WorkspaceRepo) ListMembers(ctx context.Context, workspaceID string) ([]model.WorkspaceMember, error) {
+	const query = `
+		SELECT m.workspace_id, m.user_id, COALESCE(u.email, ''), m.role, m.ctime, m.mtime
+		FROM workspace_members m
+		LEFT JOIN users u ON u.id = m.user_id
+		WHERE m.workspace_id = $1
+		ORDER BY m.ctime ASC, m.user_id ASC
+	`
+	rows, err := conn(ctx, r.db).QueryContext(ctx, query, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("query: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+	items := make([]model.WorkspaceMember, 0)
+	for rows.Next() {
+		member, err := scanWorkspaceMember(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan: %w", err)
+		}
+		items = append(items, *member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate rows: %w", err)
+	}
+	return items, nil
+}
+
+func (r *WorkspaceRepo) UpdateMemberRole(ctx context.Context, workspaceID, userID, role string, mtime int64) error {
+	where := map[string]any{"workspace_id": workspaceID, "user_id": userID}
+	sqlStr, args, err := builder.BuildUpdate("workspace_members", where, map[string]any{
+		"role":  role,
+		"mtime": mtime,
+	})
+	if err != nil {
+		return fmt.Errorf("build update: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	affected, err := dbutil.ExecAffected(ctx, conn(ctx, r.db), sqlStr, args)
+	if err != nil {
+		return fmt.Errorf("update workspace member: %w", err)
+	}
+	if affected == 0 {
+		return appErr.ErrNotFound
+	}
+	return nil
+}
+
+func (r *WorkspaceRepo) RemoveMember(ctx context.Context, workspaceID, userID string) error {
+	sqlStr, args, err := builder.BuildDelete("workspace_members", map[string]any{
+		"workspace_id": workspaceID,
+		"user_id":      userID,
+	})
+	if err != nil {
+		return fmt.Errorf("build delete: %w", err)
+	}
+	sqlStr, args = dbutil.Finalize(sqlStr, args)
+	affected, err := dbutil.ExecAffected(ctx, conn(ctx, r.db), sqlStr, args)
+	if err != nil {
+		return fmt.Errorf("delete workspace member: %w", err)
+	}
+	if affected == 0 {
+		return appErr.ErrNotFound
+	}
+	return nil
+}
+
+func (r *WorkspaceRepo) CountOwners(ctx context.Context, workspaceID string) (int, error) {
+	const query = `SELECT COUNT(1) FROM workspace_members WHERE workspace_id = $1 AND role = $2`
+	var count int
+	if err := conn(ctx, r.db).QueryRowContext(ctx, query, workspaceID, model.WorkspaceRoleOwner).
+		Scan(&count); err != nil {
+		return 0, fmt.Errorf("count workspace owners: %w", err)
+	}
+	return count, nil
+}
+
+func scanWorkspaceMember(scanner interface{ Scan(dest ...any) error }) (*model.WorkspaceMember, error) {
+	var member model.WorkspaceMember
+	if err := scanner.Scan(
+		&member.WorkspaceID, &member.UserID, &member.Email, &member.Role, &member.Ctime, &member.Mtime,
+	); err != nil {
+		return nil, err
+	}
+	return &member, nil
+}
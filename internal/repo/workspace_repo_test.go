@@ -0,0 +1,113 @@
+package repo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+var workspaceMemberTestColumns = []string{"workspace_id", "user_id", "email", "role", "ctime", "mtime"}
+
+func TestWorkspaceRepo_AddMember_Conflict(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewWorkspaceRepo(db)
+	mock.ExpectExec("INSERT INTO").WillReturnError(errConflictStub)
+	err = r.AddMember(context.Background(), &model.WorkspaceMember{
+		WorkspaceID: "w1", UserID: "u2", Role: model.WorkspaceRoleEditor, Ctime: 1000, Mtime: 1000,
+	})
+	assert.ErrorIs(t, err, appErr.ErrConflict)
+}
+
+func TestWorkspaceRepo_GetMember(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewWorkspaceRepo(db)
+	rows := sqlmock.NewRows(workspaceMemberTestColumns).
+		AddRow("w1", "u2", "b@example.com", "viewer", int64(1000), int64(1000))
+	mock.ExpectQuery("SELECT").WithArgs("w1", "u2").WillReturnRows(rows)
+
+	member, err := r.GetMember(context.Background(), "w1", "u2")
+	require.NoError(t, err)
+	assert.Equal(t, "b@example.com", member.Email)
+	assert.Equal(t, model.WorkspaceRoleViewer, member.Role)
+}
+
+func TestWorkspaceRepo_GetMember_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewWorkspaceRepo(db)
+	mock.ExpectQuery("SELECT").WillReturnRows(sqlmock.NewRows(workspaceMemberTestColumns))
+
+	_, err = r.GetMember(context.Background(), "w1", "u3")
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
+
+func TestWorkspaceRepo_ListByUser(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewWorkspaceRepo(db)
+	rows := sqlmock.NewRows([]string{"id", "name", "owner_id", "ctime", "mtime", "role"}).
+		AddRow("w1", "Team", "u1", int64(1000), int64(1000), "editor")
+	mock.ExpectQuery("SELECT").WithArgs("u2").WillReturnRows(rows)
+
+	items, err := r.ListByUser(context.Background(), "u2")
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "Team", items[0].Name)
+	assert.Equal(t, model.WorkspaceRoleEditor, items[0].Role)
+}
+
+func TestWorkspaceRepo_UpdateMemberRole_NotFound(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewWorkspaceRepo(db)
+	mock.ExpectExec("UPDATE workspace_members").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err = r.UpdateMemberRole(context.Background(), "w1", "u3", model.WorkspaceRoleViewer, 2000)
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
+
+func TestWorkspaceRepo_HasContent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewWorkspaceRepo(db)
+	mock.ExpectQuery("SELECT EXISTS").WithArgs("w1", DocumentStateNormal).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	exists, err := r.HasContent(context.Background(), "w1")
+	require.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func TestWorkspaceRepo_CountOwners(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer func() { _ = db.Close() }()
+
+	r := NewWorkspaceRepo(db)
+	mock.ExpectQuery("SELECT COUNT").WithArgs("w1", model.WorkspaceRoleOwner).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+
+	count, err := r.CountOwners(context.Background(), "w1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
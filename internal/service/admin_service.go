@@ -0,0 +1,271 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/xxxsen/common/logutil"
+	"go.uber.org/zap"
+
+	"github.com/xxxsen/mnote/internal/filestore"
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+	"github.com/xxxsen/mnote/internal/pkg/password"
+)
+
+// generatedPasswordBytes gives a 24 character hex password.
+const generatedPasswordBytes = 12
+
+// AdminService backs the offline admin commands and the admin API. Accounts
+// it creates bypass the registration policy.
+type AdminService struct {
+	users       adminUserRepo
+	data        adminDataRepo
+	store       filestore.Store
+	versionKeep int
+	runtime     Runtime
+}
+
+// NewAdminService builds the service. store may be nil, in which case
+// deleting a user leaves the uploaded files in place.
+func NewAdminService(
+	users adminUserRepo, data adminDataRepo, store filestore.Store, versionKeep int, runtime Runtime,
+) *AdminService {
+	runtime = prepareRuntime(runtime)
+	return &AdminService{users: users, data: data, store: store, versionKeep: versionKeep, runtime: runtime}
+}
+
+// DeleteUserResult reports what DeleteUser removed. FailedFiles lists the
+// stored files that could not be removed and need manual cleanup.
+type DeleteUserResult struct {
+	UserID       string   `json:"user_id"`
+	Email        string   `json:"email"`
+	DeletedFiles int      `json:"deleted_files"`
+	FailedFiles  []string `json:"failed_files"`
+}
+
+// ResolveUser finds a user by ID, or by email when ref contains an "@".
+func (s *AdminService) ResolveUser(ctx context.Context, ref string) (*model.User, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return nil, appErr.ErrInvalid
+	}
+	if !strings.Contains(ref, "@") {
+		user, err := s.users.GetByID(ctx, ref)
+		if err != nil {
+			return nil, fmt.Errorf("get user: %w", err)
+		}
+		return user, nil
+	}
+	normalized, err := NormalizeEmail(ref)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.users.GetByNormalizedEmail(ctx, normalized)
+	if errors.Is(err, appErr.ErrNotFound) {
+		user, err = s.users.GetLegacyByExactEmail(ctx, ref)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get user by email: %w", err)
+	}
+	return user, nil
+}
+
+func (s *AdminService) ListUsers(ctx context.Context) ([]model.User, error) {
+	users, err := s.users.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list users: %w", err)
+	}
+	return users, nil
+}
+
+// CreateUser adds a password account and returns it with its password. An
+// empty plainPassword generates a random one. Registration settings do not
+// apply.
+func (s *AdminService) CreateUser(
+	ctx context.Context, email, plainPassword string,
+) (*model.User, string, error) {
+	normalized, err := NormalizeEmail(email)
+	if err != nil {
+		return nil, "", err
+	}
+	plainPassword, hash, err := s.preparePassword(plainPassword)
+	if err != nil {
+		return nil, "", err
+	}
+	id, err := s.runtime.IDs.ID()
+	if err != nil {
+		return nil, "", fmt.Errorf("generate user id: %w", err)
+	}
+	now := s.runtime.Clock.Now().Unix()
+	user := &model.User{
+		ID:              id,
+		Email:           normalized,
+		EmailNormalized: normalized,
+		PasswordHash:    hash,
+		Ctime:           now,
+		Mtime:           now,
+	}
+	if err := s.runtime.Transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+		exists, err := s.users.HasCanonicalEmail(txCtx, normalized)
+		if err != nil {
+			return fmt.Errorf("check canonical email: %w", err)
+		}
+		if exists {
+			return appErr.ErrConflict
+		}
+		if err := s.users.Create(txCtx, user); err != nil {
+			return fmt.Errorf("create user: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, "", fmt.Errorf("create user transaction: %w", err)
+	}
+	return user, plainPassword, nil
+}
+
+// SetDisabled disables or re-enables an account. A disabled user cannot sign
+// in and tokens issued earlier are rejected.
+func (s *AdminService) SetDisabled(ctx context.Context, ref string, disabled bool) (*model.User, error) {
+	user, err := s.ResolveUser(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	value := 0
+	if disabled {
+		value = 1
+	}
+	now := s.runtime.Clock.Now().Unix()
+	if err := s.users.UpdateDisabled(ctx, user.ID, value, now); err != nil {
+		return nil, fmt.Errorf("update disabled: %w", err)
+	}
+	user.Disabled = value
+	user.Mtime = now
+	return user, nil
+}
+
+// SetRole gives a user the admin role or takes it away. Admins manage users
+// and invites through the HTTP API.
+func (s *AdminService) SetRole(ctx context.Context, ref, role string) (*model.User, error) {
+	if role != model.UserRoleUser && role != model.UserRoleAdmin {
+		return nil, appErr.ErrInvalid
+	}
+	user, err := s.ResolveUser(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	now := s.runtime.Clock.Now().Unix()
+	if err := s.users.UpdateRole(ctx, user.ID, role, now); err != nil {
+		return nil, fmt.Errorf("update role: %w", err)
+	}
+	user.Role = role
+	user.Mtime = now
+	return user, nil
+}
+
+// ResetPassword sets a new password and returns it. An empty plainPassword
+// generates a random one.
+func (s *AdminService) ResetPassword(ctx context.Context, ref, plainPassword string) (*model.User, string, error) {
+	user, err := s.ResolveUser(ctx, ref)
+	if err != nil {
+		return nil, "", err
+	}
+	plainPassword, hash, err := s.preparePassword(plainPassword)
+	if err != nil {
+		return nil, "", err
+	}
+	if err := s.users.UpdatePassword(ctx, user.ID, hash, s.runtime.Clock.Now().Unix()); err != nil {
+		return nil, "", fmt.Errorf("update password: %w", err)
+	}
+	return user, plainPassword, nil
+}
+
+// preparePassword validates and hashes plainPassword, generating a random
+// password when it is empty.
+func (s *AdminService) preparePassword(plainPassword string) (string, string, error) {
+	if plainPassword == "" {
+		generated, err := s.runtime.IDs.Token(generatedPasswordBytes)
+		if err != nil {
+			return "", "", fmt.Errorf("generate password: %w", err)
+		}
+		plainPassword = generated
+	}
+	if err := validateNewPassword(plainPassword); err != nil {
+		return "", "", err
+	}
+	hash, err := password.Hash(plainPassword)
+	if err != nil {
+		return "", "", fmt.Errorf("hash password: %w", err)
+	}
+	return plainPassword, hash, nil
+}
+
+// DeleteUser removes the user with all of its data. Stored files are removed
+// after the rows are gone; a file that cannot be removed is reported instead
+// of failing the whole operation.
+func (s *AdminService) DeleteUser(ctx context.Context, ref string) (*DeleteUserResult, error) {
+	user, err := s.ResolveUser(ctx, ref)
+	if err != nil {
+		return nil, err
+	}
+	var keys []string
+	if err := s.runtime.Transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+		keys, err = s.data.DeleteUserData(txCtx, user.ID)
+		return err
+	}); err != nil {
+		return nil, fmt.Errorf("delete user data: %w", err)
+	}
+	result := &DeleteUserResult{UserID: user.ID, Email: user.Email, FailedFiles: []string{}}
+	if s.store == nil {
+		result.FailedFiles = append(result.FailedFiles, keys...)
+		return result, nil
+	}
+	for _, key := range keys {
+		if err := s.store.Delete(ctx, key); err != nil && !errors.Is(err, filestore.ErrObjectNotFound) {
+			logutil.GetLogger(ctx).Warn("delete user file failed",
+				zap.String("user_id", user.ID), zap.String("file_key", key), zap.Error(err))
+			result.FailedFiles = append(result.FailedFiles, key)
+			continue
+		}
+		result.DeletedFiles++
+	}
+	return result, nil
+}
+
+func (s *AdminService) Stats(ctx context.Context) ([]model.UserStats, error) {
+	stats, err := s.data.UserStats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("user stats: %w", err)
+	}
+	return stats, nil
+}
+
+// VacuumVersions prunes the version history of every document down to keep
+// versions, or to the configured limit when keep is zero. A non-empty ref
+// limits it to one user. It returns the number of deleted versions.
+func (s *AdminService) VacuumVersions(ctx context.Context, keep int, ref string) (int64, error) {
+	if keep < 0 {
+		return 0, appErr.ErrInvalid
+	}
+	if keep == 0 {
+		keep = s.versionKeep
+	}
+	if keep <= 0 {
+		return 0, appErr.ErrInvalid
+	}
+	userID := ""
+	if strings.TrimSpace(ref) != "" {
+		user, err := s.ResolveUser(ctx, ref)
+		if err != nil {
+			return 0, err
+		}
+		userID = user.ID
+	}
+	deleted, err := s.data.VacuumVersions(ctx, keep, userID)
+	if err != nil {
+		return 0, fmt.Errorf("vacuum versions: %w", err)
+	}
+	return deleted, nil
+}
@@ -0,0 +1,209 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/filestore"
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+	"github.com/xxxsen/mnote/internal/pkg/password"
+)
+
+type mockAdminUserRepo struct {
+	mockUserRepo
+	listFn           func(ctx context.Context) ([]model.User, error)
+	updateDisabledFn func(ctx context.Context, userID string, disabled int, mtime int64) error
+	updateRoleFn     func(ctx context.Context, userID, role string, mtime int64) error
+}
+
+func (m *mockAdminUserRepo) List(ctx context.Context) ([]model.User, error) {
+	return m.listFn(ctx)
+}
+
+func (m *mockAdminUserRepo) UpdateDisabled(ctx context.Context, userID string, disabled int, mtime int64) error {
+	return m.updateDisabledFn(ctx, userID, disabled, mtime)
+}
+
+func (m *mockAdminUserRepo) UpdateRole(ctx context.Context, userID, role string, mtime int64) error {
+	return m.updateRoleFn(ctx, userID, role, mtime)
+}
+
+type mockAdminDataRepo struct {
+	deleteUserDataFn func(ctx context.Context, userID string) ([]string, error)
+	vacuumFn         func(ctx context.Context, keep int, userID string) (int64, error)
+}
+
+func (m *mockAdminDataRepo) UserStats(context.Context) ([]model.UserStats, error) {
+	return []model.UserStats{}, nil
+}
+
+func (m *mockAdminDataRepo) DeleteUserData(ctx context.Context, userID string) ([]string, error) {
+	return m.deleteUserDataFn(ctx, userID)
+}
+
+func (m *mockAdminDataRepo) VacuumVersions(ctx context.Context, keep int, userID string) (int64, error) {
+	return m.vacuumFn(ctx, keep, userID)
+}
+
+// deleteOnlyStore implements the Delete method of filestore.Store; the other
+// methods are not used by the admin service.
+type deleteOnlyStore struct {
+	filestore.Store
+	deleted []string
+	failKey string
+}
+
+func (s *deleteOnlyStore) Delete(_ context.Context, key string) error {
+	if key == s.failKey {
+		return errors.New("store unavailable")
+	}
+	s.deleted = append(s.deleted, key)
+	return nil
+}
+
+func adminUsers() *mockAdminUserRepo {
+	return &mockAdminUserRepo{mockUserRepo: mockUserRepo{
+		getByEmailFn: func(_ context.Context, email string) (*model.User, error) {
+			if email == "a@example.com" {
+				return &model.User{ID: "u1", Email: email}, nil
+			}
+			return nil, appErr.ErrNotFound
+		},
+	}}
+}
+
+func TestAdminService_ResolveUser(t *testing.T) {
+	svc := NewAdminService(adminUsers(), nil, nil, 10, testRuntime())
+
+	user, err := svc.ResolveUser(context.Background(), " A@Example.com ")
+	require.NoError(t, err)
+	assert.Equal(t, "u1", user.ID)
+
+	user, err = svc.ResolveUser(context.Background(), "u9")
+	require.NoError(t, err)
+	assert.Equal(t, "u9", user.ID)
+
+	_, err = svc.ResolveUser(context.Background(), "missing@example.com")
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+	_, err = svc.ResolveUser(context.Background(), " ")
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+}
+
+func TestAdminService_CreateUser(t *testing.T) {
+	var created *model.User
+	users := adminUsers()
+	users.createFn = func(_ context.Context, user *model.User) error {
+		created = user
+		return nil
+	}
+	svc := NewAdminService(users, nil, nil, 10, testRuntimeAt(100))
+
+	user, plain, err := svc.CreateUser(context.Background(), "New@Example.com", "secret123")
+	require.NoError(t, err)
+	assert.Same(t, created, user)
+	assert.Equal(t, "secret123", plain)
+	assert.Equal(t, "new@example.com", user.EmailNormalized)
+	assert.Equal(t, int64(100), user.Ctime)
+	assert.NoError(t, password.Compare(user.PasswordHash, "secret123"))
+
+	_, plain, err = svc.CreateUser(context.Background(), "b@example.com", "")
+	require.NoError(t, err)
+	assert.Len(t, plain, 2*generatedPasswordBytes)
+
+	_, _, err = svc.CreateUser(context.Background(), "a@example.com", "secret123")
+	assert.ErrorIs(t, err, appErr.ErrConflict)
+	_, _, err = svc.CreateUser(context.Background(), "b@example.com", "x")
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+}
+
+func TestAdminService_SetDisabled(t *testing.T) {
+	users := adminUsers()
+	var got int
+	users.updateDisabledFn = func(_ context.Context, userID string, disabled int, _ int64) error {
+		assert.Equal(t, "u1", userID)
+		got = disabled
+		return nil
+	}
+	svc := NewAdminService(users, nil, nil, 10, testRuntime())
+
+	user, err := svc.SetDisabled(context.Background(), "a@example.com", true)
+	require.NoError(t, err)
+	assert.Equal(t, 1, got)
+	assert.Equal(t, 1, user.Disabled)
+}
+
+func TestAdminService_SetRole(t *testing.T) {
+	users := adminUsers()
+	var got string
+	users.updateRoleFn = func(_ context.Context, userID, role string, _ int64) error {
+		assert.Equal(t, "u1", userID)
+		got = role
+		return nil
+	}
+	svc := NewAdminService(users, nil, nil, 10, testRuntime())
+
+	user, err := svc.SetRole(context.Background(), "a@example.com", model.UserRoleAdmin)
+	require.NoError(t, err)
+	assert.Equal(t, model.UserRoleAdmin, got)
+	assert.True(t, user.IsAdmin())
+	_, err = svc.SetRole(context.Background(), "a@example.com", "owner")
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+}
+
+func TestAdminService_ResetPassword(t *testing.T) {
+	users := adminUsers()
+	var hash string
+	users.updatePasswordFn = func(_ context.Context, _, passwordHash string, _ int64) error {
+		hash = passwordHash
+		return nil
+	}
+	svc := NewAdminService(users, nil, nil, 10, testRuntime())
+
+	_, plain, err := svc.ResetPassword(context.Background(), "u1", "")
+	require.NoError(t, err)
+	assert.Len(t, plain, 2*generatedPasswordBytes)
+	assert.NoError(t, password.Compare(hash, plain))
+
+	_, plain, err = svc.ResetPassword(context.Background(), "u1", "chosen-password")
+	require.NoError(t, err)
+	assert.Equal(t, "chosen-password", plain)
+}
+
+func TestAdminService_DeleteUser(t *testing.T) {
+	data := &mockAdminDataRepo{deleteUserDataFn: func(_ context.Context, userID string) ([]string, error) {
+		assert.Equal(t, "u1", userID)
+		return []string{"u1/a.png", "u1/b.png"}, nil
+	}}
+	store := &deleteOnlyStore{failKey: "u1/b.png"}
+	svc := NewAdminService(adminUsers(), data, store, 10, testRuntime())
+
+	result, err := svc.DeleteUser(context.Background(), "a@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.DeletedFiles)
+	assert.Equal(t, []string{"u1/b.png"}, result.FailedFiles)
+	assert.Equal(t, []string{"u1/a.png"}, store.deleted)
+}
+
+func TestAdminService_VacuumVersions(t *testing.T) {
+	var keeps []int
+	data := &mockAdminDataRepo{vacuumFn: func(_ context.Context, keep int, userID string) (int64, error) {
+		keeps = append(keeps, keep)
+		return int64(len(userID)), nil
+	}}
+	svc := NewAdminService(adminUsers(), data, nil, 10, testRuntime())
+
+	_, err := svc.VacuumVersions(context.Background(), 0, "")
+	require.NoError(t, err)
+	deleted, err := svc.VacuumVersions(context.Background(), 3, "u42")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), deleted)
+	assert.Equal(t, []int{10, 3}, keeps)
+
+	_, err = svc.VacuumVersions(context.Background(), -1, "")
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+}
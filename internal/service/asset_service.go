@@ -61,6 +61,9 @@ func (
 	contentType string,
 	size int64,
 ) error {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return err
+	}
 	if userID == "" || fileKey == "" {
 		return errInvalidAssetInput
 	}
@@ -91,6 +94,9 @@ func (
 	s *AssetService) BeginUpload(ctx context.Context,
 	userID, fileKey, url, name, contentType string, size int64,
 ) error {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return err
+	}
 	if userID == "" || fileKey == "" || size < 0 {
 		return errInvalidAssetInput
 	}
@@ -19,6 +19,7 @@ type AuthService struct {
 	jwtTTL        time.Duration
 	verify        *EmailVerificationService
 	allowRegister bool
+	registration  *RegistrationService
 	runtime       Runtime
 }
 
@@ -28,6 +29,7 @@ func NewAuthService(
 	secret []byte,
 	ttl time.Duration,
 	allowRegister bool,
+	registration *RegistrationService,
 	runtime Runtime,
 ) *AuthService {
 	runtime = prepareRuntime(runtime)
@@ -35,12 +37,15 @@ func NewAuthService(
 		users: users, verify: verify,
 		jwtSecret: secret, jwtTTL: ttl,
 		allowRegister: allowRegister,
+		registration:  registration,
 		runtime:       runtime,
 	}
 }
 
+// Register creates a password account. invite is the invite code required
+// by the invite-only registration policy and is ignored otherwise.
 func (s *AuthService) Register(
-	ctx context.Context, email, plainPassword, code string,
+	ctx context.Context, email, plainPassword, code, invite string,
 ) (*model.User, string, error) {
 	if !s.allowRegister {
 		return nil, "", appErr.ErrForbidden
@@ -55,6 +60,10 @@ func (s *AuthService) Register(
 	if err := validateNewPassword(plainPassword); err != nil {
 		return nil, "", err
 	}
+	inviteDigest := InviteDigest(invite)
+	if err := s.registration.Admit(ctx, normalized, inviteDigest); err != nil {
+		return nil, "", err
+	}
 	verification, err := s.verify.ValidateRegisterCode(ctx, normalized, code)
 	if err != nil {
 		return nil, "", err
@@ -90,7 +99,7 @@ func (s *AuthService) Register(
 		if err := s.users.Create(txCtx, user); err != nil {
 			return fmt.Errorf("create user: %w", err)
 		}
-		return nil
+		return s.registration.Redeem(txCtx, inviteDigest, user.ID)
 	}); err != nil {
 		return nil, "", fmt.Errorf("register transaction: %w", err)
 	}
@@ -112,6 +121,9 @@ func (s *AuthService) SendRegisterCode(ctx context.Context, email string) error
 	if err != nil {
 		return err
 	}
+	if err := s.registration.CheckEmail(normalized); err != nil {
+		return err
+	}
 	exists, err := s.users.HasCanonicalEmail(ctx, normalized)
 	if err != nil {
 		return fmt.Errorf("check email: %w", err)
@@ -140,6 +152,9 @@ func (s *AuthService) Login(
 	if err := password.Compare(user.PasswordHash, plainPassword); err != nil {
 		return nil, "", appErr.ErrUnauthorized
 	}
+	if user.Disabled != 0 {
+		return nil, "", appErr.ErrForbidden
+	}
 	token, err := jwt.GenerateToken(user.ID, user.Email, s.jwtSecret, s.jwtTTL)
 	if err != nil {
 		return nil, "", fmt.Errorf("generate token: %w", err)
@@ -147,6 +162,37 @@ func (s *AuthService) Login(
 	return user, token, nil
 }
 
+// EnsureActive rejects tokens of users that were deleted or disabled after
+// the token was issued.
+func (s *AuthService) EnsureActive(ctx context.Context, userID string) error {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, appErr.ErrNotFound) {
+			return appErr.ErrUnauthorized
+		}
+		return fmt.Errorf("get user: %w", err)
+	}
+	if user.Disabled != 0 {
+		return appErr.ErrForbidden
+	}
+	return nil
+}
+
+// EnsureAdmin rejects users without the admin role.
+func (s *AuthService) EnsureAdmin(ctx context.Context, userID string) error {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		if errors.Is(err, appErr.ErrNotFound) {
+			return appErr.ErrUnauthorized
+		}
+		return fmt.Errorf("get user: %w", err)
+	}
+	if !user.IsAdmin() {
+		return appErr.ErrForbidden
+	}
+	return nil
+}
+
 func (s *AuthService) UpdatePassword(
 	ctx context.Context, userID, currentPassword, newPassword string,
 ) error {
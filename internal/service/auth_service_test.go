@@ -85,7 +85,7 @@ func TestAuthService_Login(t *testing.T) {
 				return &model.User{ID: "u1", Email: email, PasswordHash: hash}, nil
 			},
 		}
-		svc := NewAuthService(users, nil, []byte("test-jwt-secret"), time.Hour, false, testRuntime())
+		svc := NewAuthService(users, nil, []byte("test-jwt-secret"), time.Hour, false, nil, testRuntime())
 		user, token, err := svc.Login(context.Background(), "a@b.com", "secret123")
 		require.NoError(t, err)
 		assert.Equal(t, "u1", user.ID)
@@ -98,7 +98,7 @@ func TestAuthService_Login(t *testing.T) {
 				return nil, appErr.ErrNotFound
 			},
 		}
-		svc := NewAuthService(users, nil, []byte("secret"), time.Hour, false, testRuntime())
+		svc := NewAuthService(users, nil, []byte("secret"), time.Hour, false, nil, testRuntime())
 		_, _, err := svc.Login(context.Background(), "a@b.com", "wrong")
 		assert.ErrorIs(t, err, appErr.ErrUnauthorized)
 	})
@@ -109,10 +109,83 @@ func TestAuthService_Login(t *testing.T) {
 				return &model.User{ID: "u1", PasswordHash: hash}, nil
 			},
 		}
-		svc := NewAuthService(users, nil, []byte("secret"), time.Hour, false, testRuntime())
+		svc := NewAuthService(users, nil, []byte("secret"), time.Hour, false, nil, testRuntime())
 		_, _, err := svc.Login(context.Background(), "a@b.com", "wrong-password")
 		assert.ErrorIs(t, err, appErr.ErrUnauthorized)
 	})
+
+	t.Run("disabled", func(t *testing.T) {
+		users := &mockUserRepo{
+			getByEmailFn: func(context.Context, string) (*model.User, error) {
+				return &model.User{ID: "u1", PasswordHash: hash, Disabled: 1}, nil
+			},
+		}
+		svc := NewAuthService(users, nil, []byte("secret"), time.Hour, false, nil, testRuntime())
+		_, _, err := svc.Login(context.Background(), "a@b.com", "secret123")
+		assert.ErrorIs(t, err, appErr.ErrForbidden)
+	})
+}
+
+func TestAuthService_EnsureActive(t *testing.T) {
+	users := &mockUserRepo{
+		getByIDFn: func(_ context.Context, id string) (*model.User, error) {
+			switch id {
+			case "active":
+				return &model.User{ID: id}, nil
+			case "disabled":
+				return &model.User{ID: id, Disabled: 1}, nil
+			}
+			return nil, appErr.ErrNotFound
+		},
+	}
+	svc := NewAuthService(users, nil, []byte("secret"), time.Hour, false, nil, testRuntime())
+	assert.NoError(t, svc.EnsureActive(context.Background(), "active"))
+	assert.ErrorIs(t, svc.EnsureActive(context.Background(), "disabled"), appErr.ErrForbidden)
+	assert.ErrorIs(t, svc.EnsureActive(context.Background(), "deleted"), appErr.ErrUnauthorized)
+}
+
+func TestAuthService_EnsureAdmin(t *testing.T) {
+	users := &mockUserRepo{
+		getByIDFn: func(_ context.Context, id string) (*model.User, error) {
+			switch id {
+			case "admin":
+				return &model.User{ID: id, Role: model.UserRoleAdmin}, nil
+			case "user":
+				return &model.User{ID: id, Role: model.UserRoleUser}, nil
+			}
+			return nil, appErr.ErrNotFound
+		},
+	}
+	svc := NewAuthService(users, nil, []byte("secret"), time.Hour, false, nil, testRuntime())
+	assert.NoError(t, svc.EnsureAdmin(context.Background(), "admin"))
+	assert.ErrorIs(t, svc.EnsureAdmin(context.Background(), "user"), appErr.ErrForbidden)
+	assert.ErrorIs(t, svc.EnsureAdmin(context.Background(), "deleted"), appErr.ErrUnauthorized)
+}
+
+func TestAuthService_RegisterPolicy(t *testing.T) {
+	ctx := context.Background()
+	invites := &memoryInviteRepo{}
+	registration := NewRegistrationService(RegistrationPolicy{
+		Mode: RegistrationModeInvite, AllowedDomains: []string{"b.com"},
+	}, invites, testRuntime())
+	_, code, err := registration.CreateInvite(ctx, "admin", "", 0)
+	require.NoError(t, err)
+	users := &mockUserRepo{createFn: func(context.Context, *model.User) error { return nil }}
+	svc := NewAuthService(
+		users, newMockVerificationService(nil), []byte("secret"), time.Hour, true, registration, testRuntime(),
+	)
+
+	_, _, err = svc.Register(ctx, "a@b.com", "password123", "123456", "")
+	assert.ErrorIs(t, err, appErr.ErrForbidden)
+	_, _, err = svc.Register(ctx, "a@c.com", "password123", "123456", code)
+	assert.ErrorIs(t, err, appErr.ErrForbidden)
+	assert.ErrorIs(t, svc.SendRegisterCode(ctx, "a@c.com"), appErr.ErrForbidden)
+
+	user, _, err := svc.Register(ctx, "a@b.com", "password123", "123456", code)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, invites.invites[0].UsedBy)
+	_, _, err = svc.Register(ctx, "d@b.com", "password123", "123456", code)
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
 }
 
 func TestAuthService_Register(t *testing.T) {
@@ -125,29 +198,29 @@ func TestAuthService_Register(t *testing.T) {
 			},
 		}
 		verify := newMockVerificationService(nil)
-		svc := NewAuthService(users, verify, []byte("jwt-secret"), time.Hour, true, testRuntime())
-		user, token, err := svc.Register(context.Background(), "a@b.com", "password123", "123456")
+		svc := NewAuthService(users, verify, []byte("jwt-secret"), time.Hour, true, nil, testRuntime())
+		user, token, err := svc.Register(context.Background(), "a@b.com", "password123", "123456", "")
 		require.NoError(t, err)
 		assert.NotEmpty(t, user.ID)
 		assert.NotEmpty(t, token)
 	})
 
 	t.Run("register_disabled", func(t *testing.T) {
-		svc := NewAuthService(&mockUserRepo{}, nil, []byte("secret"), time.Hour, false, testRuntime())
-		_, _, err := svc.Register(context.Background(), "a@b.com", "pw", "code")
+		svc := NewAuthService(&mockUserRepo{}, nil, []byte("secret"), time.Hour, false, nil, testRuntime())
+		_, _, err := svc.Register(context.Background(), "a@b.com", "pw", "code", "")
 		assert.ErrorIs(t, err, appErr.ErrForbidden)
 	})
 
 	t.Run("nil_verify", func(t *testing.T) {
-		svc := NewAuthService(&mockUserRepo{}, nil, []byte("secret"), time.Hour, true, testRuntime())
-		_, _, err := svc.Register(context.Background(), "a@b.com", "pw", "code")
+		svc := NewAuthService(&mockUserRepo{}, nil, []byte("secret"), time.Hour, true, nil, testRuntime())
+		_, _, err := svc.Register(context.Background(), "a@b.com", "pw", "code", "")
 		assert.ErrorIs(t, err, appErr.ErrInvalid)
 	})
 
 	t.Run("verify_fails", func(t *testing.T) {
 		verify := newMockVerificationService(appErr.ErrInvalid)
-		svc := NewAuthService(&mockUserRepo{}, verify, []byte("secret"), time.Hour, true, testRuntime())
-		_, _, err := svc.Register(context.Background(), "a@b.com", "pw", "bad-code")
+		svc := NewAuthService(&mockUserRepo{}, verify, []byte("secret"), time.Hour, true, nil, testRuntime())
+		_, _, err := svc.Register(context.Background(), "a@b.com", "pw", "bad-code", "")
 		assert.ErrorIs(t, err, appErr.ErrInvalid)
 	})
 
@@ -158,8 +231,8 @@ func TestAuthService_Register(t *testing.T) {
 			},
 		}
 		verify := newMockVerificationService(nil)
-		svc := NewAuthService(users, verify, []byte("secret"), time.Hour, true, testRuntime())
-		_, _, err := svc.Register(context.Background(), "a@b.com", "password123", "123456")
+		svc := NewAuthService(users, verify, []byte("secret"), time.Hour, true, nil, testRuntime())
+		_, _, err := svc.Register(context.Background(), "a@b.com", "password123", "123456", "")
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "create user")
 	})
@@ -173,19 +246,19 @@ func TestAuthService_SendRegisterCode(t *testing.T) {
 			},
 		}
 		verify := newMockVerificationService(nil)
-		svc := NewAuthService(users, verify, []byte("secret"), time.Hour, true, testRuntime())
+		svc := NewAuthService(users, verify, []byte("secret"), time.Hour, true, nil, testRuntime())
 		err := svc.SendRegisterCode(context.Background(), "new@b.com")
 		require.NoError(t, err)
 	})
 
 	t.Run("register_disabled", func(t *testing.T) {
-		svc := NewAuthService(&mockUserRepo{}, nil, []byte("secret"), time.Hour, false, testRuntime())
+		svc := NewAuthService(&mockUserRepo{}, nil, []byte("secret"), time.Hour, false, nil, testRuntime())
 		err := svc.SendRegisterCode(context.Background(), "a@b.com")
 		assert.ErrorIs(t, err, appErr.ErrForbidden)
 	})
 
 	t.Run("nil_verify", func(t *testing.T) {
-		svc := NewAuthService(&mockUserRepo{}, nil, []byte("secret"), time.Hour, true, testRuntime())
+		svc := NewAuthService(&mockUserRepo{}, nil, []byte("secret"), time.Hour, true, nil, testRuntime())
 		err := svc.SendRegisterCode(context.Background(), "a@b.com")
 		assert.ErrorIs(t, err, appErr.ErrInvalid)
 	})
@@ -197,7 +270,7 @@ func TestAuthService_SendRegisterCode(t *testing.T) {
 			},
 		}
 		verify := newMockVerificationService(nil)
-		svc := NewAuthService(users, verify, []byte("secret"), time.Hour, true, testRuntime())
+		svc := NewAuthService(users, verify, []byte("secret"), time.Hour, true, nil, testRuntime())
 		err := svc.SendRegisterCode(context.Background(), "exists@b.com")
 		assert.ErrorIs(t, err, appErr.ErrConflict)
 	})
@@ -209,7 +282,7 @@ func TestAuthService_SendRegisterCode(t *testing.T) {
 			},
 		}
 		verify := newMockVerificationService(nil)
-		svc := NewAuthService(users, verify, []byte("secret"), time.Hour, true, testRuntime())
+		svc := NewAuthService(users, verify, []byte("secret"), time.Hour, true, nil, testRuntime())
 		err := svc.SendRegisterCode(context.Background(), "a@b.com")
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "check email")
@@ -230,7 +303,7 @@ func TestAuthService_UpdatePassword(t *testing.T) {
 				return nil
 			},
 		}
-		svc := NewAuthService(users, nil, []byte("secret"), time.Hour, false, testRuntime())
+		svc := NewAuthService(users, nil, []byte("secret"), time.Hour, false, nil, testRuntime())
 		err := svc.UpdatePassword(context.Background(), "u1", "oldpw", "newpass123")
 		require.NoError(t, err)
 	})
@@ -244,13 +317,13 @@ func TestAuthService_UpdatePassword(t *testing.T) {
 				return nil
 			},
 		}
-		svc := NewAuthService(users, nil, []byte("secret"), time.Hour, false, testRuntime())
+		svc := NewAuthService(users, nil, []byte("secret"), time.Hour, false, nil, testRuntime())
 		err := svc.UpdatePassword(context.Background(), "u1", "", "newpass123")
 		require.NoError(t, err)
 	})
 
 	t.Run("empty_new_password", func(t *testing.T) {
-		svc := NewAuthService(&mockUserRepo{}, nil, []byte("secret"), time.Hour, false, testRuntime())
+		svc := NewAuthService(&mockUserRepo{}, nil, []byte("secret"), time.Hour, false, nil, testRuntime())
 		err := svc.UpdatePassword(context.Background(), "u1", "old", "  ")
 		assert.ErrorIs(t, err, appErr.ErrInvalid)
 	})
@@ -261,7 +334,7 @@ func TestAuthService_UpdatePassword(t *testing.T) {
 				return &model.User{ID: "u1", PasswordHash: hash}, nil
 			},
 		}
-		svc := NewAuthService(users, nil, []byte("secret"), time.Hour, false, testRuntime())
+		svc := NewAuthService(users, nil, []byte("secret"), time.Hour, false, nil, testRuntime())
 		err := svc.UpdatePassword(context.Background(), "u1", "wrongpw", "newpass123")
 		assert.ErrorIs(t, err, appErr.ErrInvalid)
 	})
@@ -272,7 +345,7 @@ func TestAuthService_UpdatePassword(t *testing.T) {
 				return &model.User{ID: "u1", PasswordHash: hash}, nil
 			},
 		}
-		svc := NewAuthService(users, nil, []byte("secret"), time.Hour, false, testRuntime())
+		svc := NewAuthService(users, nil, []byte("secret"), time.Hour, false, nil, testRuntime())
 		err := svc.UpdatePassword(context.Background(), "u1", "", "newpass123")
 		assert.ErrorIs(t, err, appErr.ErrInvalid)
 	})
@@ -283,7 +356,7 @@ func TestAuthService_UpdatePassword(t *testing.T) {
 				return nil, errors.New("db error")
 			},
 		}
-		svc := NewAuthService(users, nil, []byte("secret"), time.Hour, false, testRuntime())
+		svc := NewAuthService(users, nil, []byte("secret"), time.Hour, false, nil, testRuntime())
 		err := svc.UpdatePassword(context.Background(), "u1", "old", "newpass123")
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "get user")
@@ -298,7 +371,7 @@ func TestAuthService_UpdatePassword(t *testing.T) {
 				return errors.New("db error")
 			},
 		}
-		svc := NewAuthService(users, nil, []byte("secret"), time.Hour, false, testRuntime())
+		svc := NewAuthService(users, nil, []byte("secret"), time.Hour, false, nil, testRuntime())
 		err := svc.UpdatePassword(context.Background(), "u1", "oldpw", "newpass123")
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "update password")
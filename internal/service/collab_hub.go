@@ -0,0 +1,631 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/xxxsen/common/logutil"
+	"go.uber.org/zap"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+	"github.com/xxxsen/mnote/internal/pkg/ot"
+)
+
+const (
+	CollabMessageSnapshot  = "snapshot"
+	CollabMessageOperation = "operation"
+	CollabMessageAck       = "ack"
+	CollabMessagePresence  = "presence"
+	CollabMessageCursor    = "cursor"
+	CollabMessageError     = "error"
+	// CollabMessageConflict tells the clients that their unsaved edits could
+	// not be merged with a save made elsewhere and were dropped; a snapshot
+	// of the saved document follows.
+	CollabMessageConflict = "conflict"
+
+	// collabClientBuffer is the number of messages a client may fall behind
+	// before the hub drops it; the client reconnects for a fresh snapshot.
+	collabClientBuffer = 256
+	// collabHistoryLimit is the number of operations kept for transforming
+	// late submissions. Older bases must resync.
+	collabHistoryLimit = 1000
+	// collabSaveAttempts bounds how often a flush merges a save made elsewhere
+	// and retries before it gives up on the unsaved edits.
+	collabSaveAttempts = 3
+)
+
+var errCollabDependencies = errors.New("collaboration hub dependencies are required")
+
+type collabDocumentStore interface {
+	OpenForEditing(ctx context.Context, userID, docID string) (*model.Document, bool, error)
+	Save(ctx context.Context, userID, docID string, input DocumentUpdateInput) (*model.SaveDocumentResult, error)
+}
+
+type collabWorkspaceAuthorizer interface {
+	Authorize(ctx context.Context, userID, workspaceID string) (WorkspaceAccess, error)
+}
+
+// CollabCursor is a selection in code points of the current content. Anchor
+// equals Head for a plain caret.
+type CollabCursor struct {
+	Anchor int `json:"anchor"`
+	Head   int `json:"head"`
+}
+
+type CollabPresence struct {
+	ClientID string        `json:"client_id"`
+	UserID   string        `json:"user_id"`
+	Writable bool          `json:"writable"`
+	Cursor   *CollabCursor `json:"cursor,omitempty"`
+}
+
+// CollabMessage is sent from the hub to a client. Snapshot carries Title,
+// Content and Writable; operation carries the transformed Operation of
+// another client; ack confirms the client's own operation; presence lists
+// the connected clients; cursor moves one client's selection; error carries
+// the code and message of a rejected request, or of revoked access, before
+// the server hangs up. An operation without ClientID is a save made outside
+// the session, merged into it.
+type CollabMessage struct {
+	Type      string           `json:"type"`
+	Revision  int              `json:"revision"`
+	ClientID  string           `json:"client_id,omitempty"`
+	UserID    string           `json:"user_id,omitempty"`
+	Title     string           `json:"title,omitempty"`
+	Content   *string          `json:"content,omitempty"`
+	Writable  bool             `json:"writable,omitempty"`
+	Operation *ot.Operation    `json:"operation,omitempty"`
+	Cursor    *CollabCursor    `json:"cursor,omitempty"`
+	Clients   []CollabPresence `json:"clients,omitempty"`
+	Code      uint32           `json:"code,omitempty"`
+	Error     string           `json:"error,omitempty"`
+}
+
+// CollabClient is one connection to a document's editing session.
+type CollabClient struct {
+	ID       string
+	UserID   string
+	Writable bool
+
+	scopeID string
+	ctx     context.Context
+	// access is the team workspace access the client joined with, nil in a
+	// personal workspace.
+	access  *WorkspaceAccess
+	session *collabSession
+	send    chan CollabMessage
+	cursor  *CollabCursor
+	closed  bool
+}
+
+// Messages delivers the messages for the client. It is closed when the
+// client leaves or falls too far behind.
+func (c *CollabClient) Messages() <-chan CollabMessage {
+	return c.send
+}
+
+type collabSession struct {
+	mu sync.Mutex
+	// saveMu serializes saves of the session.
+	saveMu sync.Mutex
+
+	docID       string
+	title       string
+	text        string
+	revision    int
+	historyBase int
+	history     []ot.Operation
+	// savedText is the content at contentRevision; pending holds the
+	// operations applied on top of it since, which turn it into text.
+	savedText       string
+	pending         []ot.Operation
+	contentRevision int64
+	editor          *CollabClient
+	clients         map[string]*CollabClient
+}
+
+// CollabHub relays operations between the editors of a document using
+// operational transformation, tracks their presence and cursors, and saves
+// the merged content through DocumentService.Save at a fixed interval and
+// when the last editor leaves. At the same interval it re-checks the access
+// of every client and closes those who lost it, and relays saves made
+// outside an idle session to its clients.
+type CollabHub struct {
+	documents  collabDocumentStore
+	workspaces collabWorkspaceAuthorizer
+	interval   time.Duration
+	runtime    Runtime
+
+	mu       sync.Mutex
+	sessions map[string]*collabSession
+}
+
+func NewCollabHub(
+	documents collabDocumentStore, workspaces collabWorkspaceAuthorizer, interval time.Duration, runtime Runtime,
+) *CollabHub {
+	return &CollabHub{
+		documents:  documents,
+		workspaces: workspaces,
+		interval:   interval,
+		runtime:    prepareRuntime(runtime),
+		sessions:   map[string]*collabSession{},
+	}
+}
+
+// Join connects the user to the editing session of a document, opening the
+// session when it is the first editor. The client first receives a snapshot
+// and then the presence of everyone connected.
+func (h *CollabHub) Join(ctx context.Context, userID, docID string) (*CollabClient, error) {
+	doc, writable, err := h.documents.OpenForEditing(ctx, userID, docID)
+	if err != nil {
+		return nil, fmt.Errorf("open document: %w", err)
+	}
+	clientID, err := h.runtime.IDs.ID()
+	if err != nil {
+		return nil, fmt.Errorf("generate client id: %w", err)
+	}
+	client := &CollabClient{
+		ID: clientID, UserID: actorID(ctx, userID), Writable: writable,
+		scopeID: userID, ctx: context.WithoutCancel(ctx),
+		send: make(chan CollabMessage, collabClientBuffer),
+	}
+	if access, ok := workspaceAccessFrom(ctx); ok {
+		client.access = &access
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	session, ok := h.sessions[doc.ID]
+	if !ok {
+		session = &collabSession{
+			docID: doc.ID, title: doc.Title, text: doc.Content,
+			savedText: doc.Content, contentRevision: doc.ContentRevision,
+			clients: map[string]*CollabClient{},
+		}
+		h.sessions[doc.ID] = session
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	client.session = session
+	session.clients[client.ID] = client
+	session.push(client, session.snapshotLocked(client))
+	session.broadcastPresenceLocked()
+	return client, nil
+}
+
+// Leave disconnects the client. The last client to leave saves pending
+// changes and closes the session.
+func (h *CollabHub) Leave(client *CollabClient) {
+	session := client.session
+	session.mu.Lock()
+	if _, ok := session.clients[client.ID]; ok {
+		session.dropLocked(client)
+		session.broadcastPresenceLocked()
+	}
+	empty := len(session.clients) == 0
+	session.mu.Unlock()
+	if !empty {
+		return
+	}
+	h.flush(session)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if len(session.clients) == 0 && h.sessions[session.docID] == session {
+		delete(h.sessions, session.docID)
+	}
+}
+
+// Submit applies an operation the client made on top of revision. The
+// operation is transformed past everything applied since, acknowledged to
+// the client and relayed to the others. A revision the hub no longer
+// remembers, or a client the hub already dropped, returns ErrConflict; the
+// client must reconnect.
+func (h *CollabHub) Submit(client *CollabClient, revision int, op ot.Operation) error {
+	if !client.Writable {
+		return appErr.ErrForbidden
+	}
+	session := client.session
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if client.closed || revision < session.historyBase || revision > session.revision {
+		return appErr.ErrConflict
+	}
+	for _, applied := range session.history[revision-session.historyBase:] {
+		transformed, _, err := ot.Transform(op, applied)
+		if err != nil {
+			return appErr.ErrInvalid
+		}
+		op = transformed
+	}
+	text, err := op.Apply(session.text)
+	if err != nil || len(text) > h.runtime.Limits.MaxDocumentBytes {
+		return appErr.ErrInvalid
+	}
+	session.text = text
+	session.pending = append(session.pending, op)
+	session.applyLocked(op)
+	session.editor = client
+	session.push(client, CollabMessage{Type: CollabMessageAck, Revision: session.revision})
+	session.broadcastLocked(client, CollabMessage{
+		Type: CollabMessageOperation, Revision: session.revision,
+		ClientID: client.ID, UserID: client.UserID, Operation: &op,
+	})
+	return nil
+}
+
+// MoveCursor records the client's selection, clamped to the content, and
+// shows it to the other clients.
+func (h *CollabHub) MoveCursor(client *CollabClient, cursor CollabCursor) {
+	session := client.session
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if client.closed {
+		return
+	}
+	length := len([]rune(session.text))
+	cursor.Anchor = min(max(cursor.Anchor, 0), length)
+	cursor.Head = min(max(cursor.Head, 0), length)
+	client.cursor = &cursor
+	session.broadcastLocked(client, CollabMessage{
+		Type: CollabMessageCursor, Revision: session.revision,
+		ClientID: client.ID, UserID: client.UserID, Cursor: &cursor,
+	})
+}
+
+// Run saves changed sessions every interval until ctx is done, then saves
+// them once more.
+func (h *CollabHub) Run(ctx context.Context) error {
+	if h.documents == nil || h.interval <= 0 {
+		return errCollabDependencies
+	}
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			h.flushAll()
+			return nil
+		case <-ticker.C:
+			h.revalidateAll()
+			h.flushAll()
+		}
+	}
+}
+
+func (h *CollabHub) activeSessions() []*collabSession {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	sessions := make([]*collabSession, 0, len(h.sessions))
+	for _, session := range h.sessions {
+		sessions = append(sessions, session)
+	}
+	return sessions
+}
+
+func (h *CollabHub) flushAll() {
+	for _, session := range h.activeSessions() {
+		h.flush(session)
+	}
+}
+
+func (h *CollabHub) revalidateAll() {
+	for _, session := range h.activeSessions() {
+		h.revalidate(session)
+	}
+}
+
+// revalidate closes the clients that lost access to the session's document
+// since they joined: removed collaborators and workspace members, or a
+// document deleted under them. A client that may no longer write is closed
+// too and reconnects read-only. When the last editor is closed, another
+// writer of the session saves its edits.
+func (h *CollabHub) revalidate(session *collabSession) {
+	session.mu.Lock()
+	clients := make([]*CollabClient, 0, len(session.clients))
+	for _, client := range session.clients {
+		clients = append(clients, client)
+	}
+	session.mu.Unlock()
+	revoked := make([]*CollabClient, 0)
+	for _, client := range clients {
+		if h.revoked(client, session.docID) {
+			revoked = append(revoked, client)
+		}
+	}
+	if len(revoked) == 0 {
+		return
+	}
+	forbidden := appErr.Normalize(appErr.ErrForbidden)
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	for _, client := range revoked {
+		session.push(client, CollabMessage{
+			Type: CollabMessageError, Revision: session.revision, Code: forbidden.Code(), Error: forbidden.Message(),
+		})
+		session.dropLocked(client)
+		if session.editor == client {
+			session.editor = session.writerLocked()
+		}
+	}
+	session.broadcastPresenceLocked()
+}
+
+// revoked reports whether the client lost access to docID, or the right to
+// write it. Lookup failures other than a missing or forbidden document keep
+// the client connected.
+func (h *CollabHub) revoked(client *CollabClient, docID string) bool {
+	ctx := client.ctx
+	logger := logutil.GetLogger(ctx).With(zap.String("document_id", docID), zap.String("user_id", client.UserID))
+	if client.access != nil && h.workspaces != nil {
+		access, err := h.workspaces.Authorize(ctx, client.access.UserID, client.access.WorkspaceID)
+		if err != nil {
+			if isAccessLost(err) {
+				return true
+			}
+			logger.Error("check collaborator workspace access failed", zap.Error(err))
+			return false
+		}
+		ctx = WithWorkspaceAccess(ctx, access)
+	}
+	_, writable, err := h.documents.OpenForEditing(ctx, client.scopeID, docID)
+	if err != nil {
+		if isAccessLost(err) {
+			return true
+		}
+		logger.Error("check collaborator access failed", zap.Error(err))
+		return false
+	}
+	return client.Writable && !writable
+}
+
+func isAccessLost(err error) bool {
+	return errors.Is(err, appErr.ErrNotFound) || errors.Is(err, appErr.ErrForbidden)
+}
+
+// flush saves the session content as its last editor, based on the content
+// revision the session last loaded or saved. A rejected save means the
+// document was saved elsewhere; the session then merges that save into its
+// unsaved edits and tries again.
+func (h *CollabHub) flush(session *collabSession) {
+	session.saveMu.Lock()
+	defer session.saveMu.Unlock()
+	for attempt := 1; attempt <= collabSaveAttempts; attempt++ {
+		if !h.save(session, attempt == collabSaveAttempts) {
+			return
+		}
+	}
+}
+
+// save makes one save attempt and reports whether the session merged a save
+// made elsewhere and must try again. On the last attempt, or when the merge
+// fails, the clients are told of the conflict and reset to the saved
+// document instead.
+func (h *CollabHub) save(session *collabSession, last bool) bool {
+	session.mu.Lock()
+	editor := session.editor
+	if len(session.pending) == 0 {
+		session.mu.Unlock()
+		h.refresh(session)
+		return false
+	}
+	if editor == nil {
+		session.mu.Unlock()
+		return false
+	}
+	saved := len(session.pending)
+	input := DocumentUpdateInput{
+		Title: session.title, Content: session.text, BaseRevision: session.contentRevision,
+	}
+	session.mu.Unlock()
+
+	logger := logutil.GetLogger(editor.ctx).With(zap.String("document_id", session.docID))
+	result, err := h.documents.Save(editor.ctx, editor.scopeID, session.docID, input)
+	if err != nil {
+		logger.Error("save collaborative document failed", zap.Error(err))
+		return false
+	}
+	if result.Accepted {
+		session.mu.Lock()
+		defer session.mu.Unlock()
+		session.savedText = input.Content
+		session.pending = session.pending[saved:]
+		session.contentRevision = result.ContentRevision
+		return false
+	}
+	doc, _, err := h.documents.OpenForEditing(editor.ctx, editor.scopeID, session.docID)
+	if err != nil {
+		logger.Error("reload collaborative document failed", zap.Error(err))
+		return false
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if !last && session.rebaseLocked(doc) {
+		return true
+	}
+	logger.Warn("collaborative edits conflict with a save made elsewhere, resetting session",
+		zap.Int64("content_revision", doc.ContentRevision))
+	conflict := appErr.Normalize(appErr.ErrConflict)
+	session.broadcastLocked(nil, CollabMessage{
+		Type: CollabMessageConflict, Revision: session.revision, Code: conflict.Code(), Error: conflict.Message(),
+	})
+	session.reset(doc)
+	return false
+}
+
+// refresh brings a session without unsaved edits up to date with a save made
+// outside it, such as a save through the API, a restore or a bulk edit, so
+// idle clients do not keep showing stale content until their next edit. A
+// content change is relayed as an operation; a new title resets the clients
+// to a snapshot, which is the only message carrying it.
+func (h *CollabHub) refresh(session *collabSession) {
+	session.mu.Lock()
+	var reader *CollabClient
+	for _, client := range session.clients {
+		reader = client
+		break
+	}
+	session.mu.Unlock()
+	if reader == nil {
+		return
+	}
+	doc, _, err := h.documents.OpenForEditing(reader.ctx, reader.scopeID, session.docID)
+	if err != nil {
+		logutil.GetLogger(reader.ctx).Error("reload collaborative document failed",
+			zap.String("document_id", session.docID), zap.Error(err))
+		return
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if len(session.pending) > 0 || doc.ContentRevision == session.contentRevision {
+		return
+	}
+	if doc.Title != session.title || !session.rebaseLocked(doc) {
+		session.reset(doc)
+	}
+}
+
+// rebaseLocked merges doc, saved outside the session, into it: the change
+// from the last saved content to doc is transformed past the unsaved
+// operations and relayed to the clients as an operation of its own, and the
+// unsaved operations are rebased onto doc. It reports false when the change
+// cannot be merged.
+func (s *collabSession) rebaseLocked(doc *model.Document) bool {
+	external := diffText(s.savedText, doc.Content)
+	rebased := make([]ot.Operation, 0, len(s.pending))
+	for _, op := range s.pending {
+		opPrime, externalPrime, err := ot.Transform(op, external)
+		if err != nil {
+			return false
+		}
+		rebased = append(rebased, opPrime)
+		external = externalPrime
+	}
+	text, err := external.Apply(s.text)
+	if err != nil {
+		return false
+	}
+	s.title = doc.Title
+	s.text = text
+	s.savedText = doc.Content
+	s.pending = rebased
+	s.contentRevision = doc.ContentRevision
+	s.applyLocked(external)
+	s.broadcastLocked(nil, CollabMessage{Type: CollabMessageOperation, Revision: s.revision, Operation: &external})
+	return true
+}
+
+// applyLocked records op, already applied to the text, as the next revision
+// and moves the cursors past it.
+func (s *collabSession) applyLocked(op ot.Operation) {
+	s.revision++
+	s.history = append(s.history, op)
+	if len(s.history) > collabHistoryLimit {
+		trim := len(s.history) - collabHistoryLimit
+		s.history = s.history[trim:]
+		s.historyBase += trim
+	}
+	for _, client := range s.clients {
+		if client.cursor != nil {
+			client.cursor = &CollabCursor{
+				Anchor: op.TransformIndex(client.cursor.Anchor),
+				Head:   op.TransformIndex(client.cursor.Head),
+			}
+		}
+	}
+}
+
+// diffText returns an operation turning from into to by replacing what lies
+// between their common prefix and suffix.
+func diffText(from, to string) ot.Operation {
+	a, b := []rune(from), []rune(to)
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	var op ot.Operation
+	op.Retain(prefix).Delete(len(a) - prefix - suffix).Insert(string(b[prefix : len(b)-suffix])).Retain(suffix)
+	return op
+}
+
+func (s *collabSession) reset(doc *model.Document) {
+	s.title = doc.Title
+	s.text = doc.Content
+	s.savedText = doc.Content
+	s.pending = nil
+	s.contentRevision = doc.ContentRevision
+	s.revision++
+	s.historyBase = s.revision
+	s.history = nil
+	for _, client := range s.clients {
+		client.cursor = nil
+		s.push(client, s.snapshotLocked(client))
+	}
+	s.broadcastPresenceLocked()
+}
+
+func (s *collabSession) snapshotLocked(client *CollabClient) CollabMessage {
+	content := s.text
+	return CollabMessage{
+		Type: CollabMessageSnapshot, Revision: s.revision, ClientID: client.ID,
+		Title: s.title, Content: &content, Writable: client.Writable,
+	}
+}
+
+func (s *collabSession) broadcastPresenceLocked() {
+	clients := make([]CollabPresence, 0, len(s.clients))
+	for _, client := range s.clients {
+		clients = append(clients, CollabPresence{
+			ClientID: client.ID, UserID: client.UserID, Writable: client.Writable, Cursor: client.cursor,
+		})
+	}
+	s.broadcastLocked(nil, CollabMessage{Type: CollabMessagePresence, Revision: s.revision, Clients: clients})
+}
+
+// broadcastLocked sends msg to every client except skip.
+func (s *collabSession) broadcastLocked(skip *CollabClient, msg CollabMessage) {
+	for _, client := range s.clients {
+		if client != skip {
+			s.push(client, msg)
+		}
+	}
+}
+
+// push queues msg for the client without blocking. A client whose queue is
+// full is dropped.
+func (s *collabSession) push(client *CollabClient, msg CollabMessage) {
+	if client.closed {
+		return
+	}
+	select {
+	case client.send <- msg:
+	default:
+		s.dropLocked(client)
+	}
+}
+
+// writerLocked returns a connected client that may write, or nil.
+func (s *collabSession) writerLocked() *CollabClient {
+	for _, client := range s.clients {
+		if client.Writable {
+			return client
+		}
+	}
+	return nil
+}
+
+func (s *collabSession) dropLocked(client *CollabClient) {
+	delete(s.clients, client.ID)
+	if !client.closed {
+		client.closed = true
+		close(client.send)
+	}
+}
@@ -0,0 +1,354 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+	"github.com/xxxsen/mnote/internal/pkg/ot"
+)
+
+// fakeCollabStore serves one document and accepts saves based on its
+// current content revision, like DocumentService.Save.
+type fakeCollabStore struct {
+	doc      model.Document
+	writable map[string]bool
+	saves    []DocumentUpdateInput
+	// outside, when set, runs before each save as a save made elsewhere.
+	outside func(doc *model.Document)
+}
+
+func (f *fakeCollabStore) OpenForEditing(_ context.Context, userID, docID string) (*model.Document, bool, error) {
+	writable, ok := f.writable[userID]
+	if !ok || docID != f.doc.ID {
+		return nil, false, appErr.ErrNotFound
+	}
+	doc := f.doc
+	return &doc, writable, nil
+}
+
+func (f *fakeCollabStore) Save(
+	_ context.Context, _, _ string, input DocumentUpdateInput,
+) (*model.SaveDocumentResult, error) {
+	f.saves = append(f.saves, input)
+	if f.outside != nil {
+		f.outside(&f.doc)
+	}
+	if input.BaseRevision != f.doc.ContentRevision {
+		return &model.SaveDocumentResult{Accepted: false, ContentRevision: f.doc.ContentRevision}, nil
+	}
+	f.doc.Content = input.Content
+	f.doc.ContentRevision++
+	return &model.SaveDocumentResult{Accepted: true, ContentRevision: f.doc.ContentRevision}, nil
+}
+
+func newCollabHub(t *testing.T) (*CollabHub, *fakeCollabStore) {
+	t.Helper()
+	store := &fakeCollabStore{
+		doc:      model.Document{ID: "d1", UserID: "u1", Title: "Meeting", Content: "ab", ContentRevision: 3},
+		writable: map[string]bool{"u1": true, "u2": true, "u3": false},
+	}
+	return NewCollabHub(store, nil, time.Minute, testRuntime()), store
+}
+
+// fakeWorkspaceAuthorizer grants the listed members their role.
+type fakeWorkspaceAuthorizer map[string]string
+
+func (f fakeWorkspaceAuthorizer) Authorize(_ context.Context, userID, workspaceID string) (WorkspaceAccess, error) {
+	role, ok := f[userID]
+	if !ok {
+		return WorkspaceAccess{}, appErr.ErrNotFound
+	}
+	return WorkspaceAccess{WorkspaceID: workspaceID, UserID: userID, Role: role}, nil
+}
+
+func parseOperation(t *testing.T, raw string) ot.Operation {
+	t.Helper()
+	var op ot.Operation
+	require.NoError(t, json.Unmarshal([]byte(raw), &op))
+	return op
+}
+
+// drain returns the messages queued for the client.
+func drain(client *CollabClient) []CollabMessage {
+	var items []CollabMessage
+	for {
+		select {
+		case msg, ok := <-client.Messages():
+			if !ok {
+				return items
+			}
+			items = append(items, msg)
+		default:
+			return items
+		}
+	}
+}
+
+func TestCollabHub_JoinSendsSnapshotAndPresence(t *testing.T) {
+	hub, _ := newCollabHub(t)
+	ctx := context.Background()
+
+	a, err := hub.Join(ctx, "u1", "d1")
+	require.NoError(t, err)
+	msgs := drain(a)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, CollabMessageSnapshot, msgs[0].Type)
+	assert.Equal(t, "ab", *msgs[0].Content)
+	assert.True(t, msgs[0].Writable)
+
+	viewer, err := hub.Join(ctx, "u3", "d1")
+	require.NoError(t, err)
+	assert.False(t, viewer.Writable)
+	msgs = drain(a)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, CollabMessagePresence, msgs[0].Type)
+	assert.Len(t, msgs[0].Clients, 2)
+
+	_, err = hub.Join(ctx, "u4", "d1")
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
+
+func TestCollabHub_SubmitTransformsConcurrentOperations(t *testing.T) {
+	hub, _ := newCollabHub(t)
+	ctx := context.Background()
+	a, err := hub.Join(ctx, "u1", "d1")
+	require.NoError(t, err)
+	b, err := hub.Join(ctx, "u2", "d1")
+	require.NoError(t, err)
+	drain(a)
+	drain(b)
+
+	hub.MoveCursor(b, CollabCursor{Anchor: 2, Head: 2})
+	require.NoError(t, hub.Submit(a, 0, parseOperation(t, `["X",2]`)))
+	require.NoError(t, hub.Submit(b, 0, parseOperation(t, `[2,"Y"]`)))
+	assert.Equal(t, "XabY", a.session.text)
+	assert.Equal(t, 2, a.session.revision)
+	assert.Equal(t, 4, b.cursor.Head)
+
+	msgs := drain(a)
+	require.Len(t, msgs, 3)
+	assert.Equal(t, CollabMessageCursor, msgs[0].Type)
+	assert.Equal(t, CollabMessageAck, msgs[1].Type)
+	assert.Equal(t, CollabMessageOperation, msgs[2].Type)
+	data, err := json.Marshal(msgs[2].Operation)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[3,"Y"]`, string(data))
+
+	assert.ErrorIs(t, hub.Submit(a, 5, parseOperation(t, `[4]`)), appErr.ErrConflict)
+	assert.ErrorIs(t, hub.Submit(a, 2, parseOperation(t, `[3]`)), appErr.ErrInvalid)
+}
+
+func TestCollabHub_ViewerCannotSubmit(t *testing.T) {
+	hub, _ := newCollabHub(t)
+	viewer, err := hub.Join(context.Background(), "u3", "d1")
+	require.NoError(t, err)
+	assert.ErrorIs(t, hub.Submit(viewer, 0, parseOperation(t, `[2,"x"]`)), appErr.ErrForbidden)
+}
+
+func TestCollabHub_FlushSavesChangedSessions(t *testing.T) {
+	hub, store := newCollabHub(t)
+	a, err := hub.Join(context.Background(), "u1", "d1")
+	require.NoError(t, err)
+
+	hub.flushAll()
+	assert.Empty(t, store.saves)
+
+	require.NoError(t, hub.Submit(a, 0, parseOperation(t, `[2,"c"]`)))
+	hub.flushAll()
+	hub.flushAll()
+	require.Len(t, store.saves, 1)
+	assert.Equal(t, DocumentUpdateInput{Title: "Meeting", Content: "abc", BaseRevision: 3}, store.saves[0])
+	assert.Equal(t, int64(4), a.session.contentRevision)
+}
+
+func TestCollabHub_FlushMergesOutsideSave(t *testing.T) {
+	hub, store := newCollabHub(t)
+	a, err := hub.Join(context.Background(), "u1", "d1")
+	require.NoError(t, err)
+	hub.MoveCursor(a, CollabCursor{Anchor: 1, Head: 1})
+	drain(a)
+
+	store.doc.Title = "Renamed"
+	store.doc.Content = "xab"
+	store.doc.ContentRevision = 9
+	require.NoError(t, hub.Submit(a, 0, parseOperation(t, `[2,"c"]`)))
+	hub.flushAll()
+
+	require.Len(t, store.saves, 2)
+	assert.Equal(t, DocumentUpdateInput{Title: "Renamed", Content: "xabc", BaseRevision: 9}, store.saves[1])
+	assert.Equal(t, "xabc", store.doc.Content)
+	assert.Equal(t, int64(10), a.session.contentRevision)
+	assert.Empty(t, a.session.pending)
+	assert.Equal(t, 2, a.cursor.Head)
+
+	msgs := drain(a)
+	require.Len(t, msgs, 2)
+	assert.Equal(t, CollabMessageAck, msgs[0].Type)
+	assert.Equal(t, CollabMessageOperation, msgs[1].Type)
+	assert.Empty(t, msgs[1].ClientID)
+	assert.Equal(t, 2, msgs[1].Revision)
+	data, err := json.Marshal(msgs[1].Operation)
+	require.NoError(t, err)
+	assert.JSONEq(t, `["x",3]`, string(data))
+	require.NoError(t, hub.Submit(a, 2, parseOperation(t, `[4,"!"]`)))
+	assert.Equal(t, "xabc!", a.session.text)
+}
+
+func TestCollabHub_FlushRefreshesIdleSession(t *testing.T) {
+	hub, store := newCollabHub(t)
+	ctx := context.Background()
+	a, err := hub.Join(ctx, "u1", "d1")
+	require.NoError(t, err)
+	viewer, err := hub.Join(ctx, "u3", "d1")
+	require.NoError(t, err)
+	drain(a)
+	drain(viewer)
+
+	store.doc.Content = "abx"
+	store.doc.ContentRevision = 7
+	hub.flushAll()
+	assert.Empty(t, store.saves)
+	assert.Equal(t, "abx", a.session.text)
+	assert.Equal(t, int64(7), a.session.contentRevision)
+	msgs := drain(viewer)
+	require.Len(t, msgs, 1)
+	assert.Equal(t, CollabMessageOperation, msgs[0].Type)
+	assert.Equal(t, 1, msgs[0].Revision)
+	data, err := json.Marshal(msgs[0].Operation)
+	require.NoError(t, err)
+	assert.JSONEq(t, `[2,"x"]`, string(data))
+	drain(a)
+
+	require.NoError(t, hub.Submit(a, 1, parseOperation(t, `[3,"!"]`)))
+	hub.flushAll()
+	require.Len(t, store.saves, 1)
+	assert.Equal(t, DocumentUpdateInput{Title: "Meeting", Content: "abx!", BaseRevision: 7}, store.saves[0])
+	drain(a)
+
+	store.doc.Title = "Renamed"
+	store.doc.ContentRevision++
+	hub.flushAll()
+	msgs = drain(a)
+	require.NotEmpty(t, msgs)
+	assert.Equal(t, CollabMessageSnapshot, msgs[0].Type)
+	assert.Equal(t, "Renamed", msgs[0].Title)
+	assert.Equal(t, "abx!", *msgs[0].Content)
+}
+
+func TestCollabHub_FlushReportsUnmergeableConflict(t *testing.T) {
+	hub, store := newCollabHub(t)
+	a, err := hub.Join(context.Background(), "u1", "d1")
+	require.NoError(t, err)
+	drain(a)
+	store.outside = func(doc *model.Document) {
+		doc.Content += "z"
+		doc.ContentRevision++
+	}
+
+	require.NoError(t, hub.Submit(a, 0, parseOperation(t, `[2,"c"]`)))
+	hub.flushAll()
+
+	assert.Len(t, store.saves, collabSaveAttempts)
+	msgs := drain(a)
+	var types []string
+	for _, msg := range msgs {
+		types = append(types, msg.Type)
+	}
+	assert.Equal(t, []string{
+		CollabMessageAck, CollabMessageOperation, CollabMessageOperation,
+		CollabMessageConflict, CollabMessageSnapshot, CollabMessagePresence,
+	}, types)
+	assert.Equal(t, "abzzz", *msgs[4].Content)
+	assert.Empty(t, a.session.pending)
+	assert.ErrorIs(t, hub.Submit(a, 1, parseOperation(t, `[3]`)), appErr.ErrConflict)
+}
+
+func TestCollabHub_RevalidateClosesRevokedClients(t *testing.T) {
+	hub, store := newCollabHub(t)
+	ctx := context.Background()
+	a, err := hub.Join(ctx, "u1", "d1")
+	require.NoError(t, err)
+	b, err := hub.Join(ctx, "u2", "d1")
+	require.NoError(t, err)
+	viewer, err := hub.Join(ctx, "u3", "d1")
+	require.NoError(t, err)
+	require.NoError(t, hub.Submit(b, 0, parseOperation(t, `[2,"c"]`)))
+	drain(a)
+	drain(viewer)
+
+	delete(store.writable, "u2")
+	hub.revalidateAll()
+	msgs := drain(b)
+	require.NotEmpty(t, msgs)
+	last := msgs[len(msgs)-1]
+	assert.Equal(t, CollabMessageError, last.Type)
+	assert.Equal(t, appErr.Normalize(appErr.ErrForbidden).Code(), last.Code)
+	_, open := <-b.Messages()
+	assert.False(t, open)
+	assert.ErrorIs(t, hub.Submit(b, 1, parseOperation(t, `[3,"d"]`)), appErr.ErrConflict)
+
+	msgs = drain(a)
+	require.Len(t, msgs, 1)
+	assert.Len(t, msgs[0].Clients, 2)
+	assert.Same(t, a, a.session.editor)
+	hub.flushAll()
+	assert.Equal(t, "abc", store.doc.Content)
+
+	store.writable["u1"] = false
+	hub.revalidateAll()
+	assert.Len(t, a.session.clients, 1)
+	assert.Nil(t, a.session.editor)
+}
+
+func TestCollabHub_RevalidateChecksWorkspaceMembership(t *testing.T) {
+	store := &fakeCollabStore{
+		doc:      model.Document{ID: "d1", UserID: "w1", Content: "ab"},
+		writable: map[string]bool{"w1": true},
+	}
+	members := fakeWorkspaceAuthorizer{"u1": model.WorkspaceRoleEditor}
+	hub := NewCollabHub(store, members, time.Minute, testRuntime())
+	ctx := WithWorkspaceAccess(context.Background(), WorkspaceAccess{
+		WorkspaceID: "w1", UserID: "u1", Role: model.WorkspaceRoleEditor,
+	})
+	a, err := hub.Join(ctx, "w1", "d1")
+	require.NoError(t, err)
+
+	hub.revalidateAll()
+	assert.Len(t, a.session.clients, 1)
+
+	delete(members, "u1")
+	hub.revalidateAll()
+	assert.Empty(t, a.session.clients)
+}
+
+func TestCollabHub_LastLeaveSavesAndClosesSession(t *testing.T) {
+	hub, store := newCollabHub(t)
+	a, err := hub.Join(context.Background(), "u1", "d1")
+	require.NoError(t, err)
+	require.NoError(t, hub.Submit(a, 0, parseOperation(t, `["!",2]`)))
+
+	hub.Leave(a)
+	require.Len(t, store.saves, 1)
+	assert.Equal(t, "!ab", store.doc.Content)
+	assert.Empty(t, hub.sessions)
+	drain(a)
+	_, open := <-a.Messages()
+	assert.False(t, open)
+}
+
+func TestCollabHub_DroppedClientCannotSubmit(t *testing.T) {
+	hub, _ := newCollabHub(t)
+	a, err := hub.Join(context.Background(), "u1", "d1")
+	require.NoError(t, err)
+
+	hub.Leave(a)
+	assert.ErrorIs(t, hub.Submit(a, 0, parseOperation(t, `[2,"c"]`)), appErr.ErrConflict)
+	assert.Equal(t, "ab", a.session.text)
+}
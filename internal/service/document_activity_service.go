@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+const (
+	defaultActivityDays = 30
+	maxActivityDays     = 366
+)
+
+// ActivityDocument is one document changed on a day. Created marks the day
+// it was first saved; WordsAdded and WordsRemoved sum its versions that day.
+type ActivityDocument struct {
+	DocumentID   string
+	Title        string
+	Created      bool
+	Versions     int
+	WordsAdded   int
+	WordsRemoved int
+	LastCtime    int64
+}
+
+// ActivityDay counts the documents created and the other documents edited on
+// one day, the words they gained and lost, and lists them most recent first.
+type ActivityDay struct {
+	Date         string
+	Created      int
+	Edited       int
+	WordsAdded   int
+	WordsRemoved int
+	Documents    []ActivityDocument
+}
+
+// Activity is the saved versions of a user grouped by day, one entry per day
+// of the range including quiet ones.
+type Activity struct {
+	From     string
+	To       string
+	Timezone string
+	Days     []ActivityDay
+}
+
+// activityRange resolves the inclusive from/to dates of an activity query in
+// the named time zone. Either date may be empty: to defaults to today and
+// from to the 30 days ending at to.
+func activityRange(from, to, timezone string, now time.Time) (time.Time, time.Time, error) {
+	loc := time.UTC
+	if timezone != "" {
+		var err error
+		if loc, err = time.LoadLocation(timezone); err != nil {
+			return time.Time{}, time.Time{}, appErr.ErrInvalid
+		}
+	}
+	today := now.In(loc)
+	end := time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, loc)
+	if to != "" {
+		parsed, err := time.ParseInLocation(time.DateOnly, to, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, appErr.ErrInvalid
+		}
+		end = parsed
+	}
+	start := end.AddDate(0, 0, 1-defaultActivityDays)
+	if from != "" {
+		parsed, err := time.ParseInLocation(time.DateOnly, from, loc)
+		if err != nil {
+			return time.Time{}, time.Time{}, appErr.ErrInvalid
+		}
+		start = parsed
+	}
+	end = end.AddDate(0, 0, 1)
+	if !start.Before(end) || start.AddDate(0, 0, maxActivityDays).Before(end) {
+		return time.Time{}, time.Time{}, appErr.ErrInvalid
+	}
+	return start, end, nil
+}
+
+// Activity reports what a user wrote between two dates from the timestamps
+// of their document versions. Words added and removed compare each version
+// with the one before it; versions saved before word counts were recorded,
+// or whose predecessor was pruned, count as edits without words.
+func (s *DocumentService) Activity(ctx context.Context, userID, from, to, timezone string) (*Activity, error) {
+	start, end, err := activityRange(from, to, timezone, s.runtime.Clock.Now())
+	if err != nil {
+		return nil, err
+	}
+	versions, err := s.versions.ListActivity(ctx, userID, start.Unix(), end.Unix())
+	if err != nil {
+		return nil, fmt.Errorf("list activity: %w", err)
+	}
+	result := &Activity{
+		From: start.Format(time.DateOnly), To: end.AddDate(0, 0, -1).Format(time.DateOnly),
+		Timezone: start.Location().String(), Days: make([]ActivityDay, 0),
+	}
+	index := make(map[string]int)
+	for day := start; day.Before(end); day = day.AddDate(0, 0, 1) {
+		index[day.Format(time.DateOnly)] = len(result.Days)
+		result.Days = append(result.Days, ActivityDay{
+			Date: day.Format(time.DateOnly), Documents: make([]ActivityDocument, 0),
+		})
+	}
+	positions := make(map[string]int)
+	for _, version := range versions {
+		date := time.Unix(version.Ctime, 0).In(start.Location()).Format(time.DateOnly)
+		i, ok := index[date]
+		if !ok {
+			continue
+		}
+		day := &result.Days[i]
+		key := date + "/" + version.DocumentID
+		pos, seen := positions[key]
+		if !seen {
+			pos = len(day.Documents)
+			positions[key] = pos
+			day.Documents = append(day.Documents, ActivityDocument{DocumentID: version.DocumentID})
+		}
+		doc := &day.Documents[pos]
+		doc.Title, doc.LastCtime = version.Title, version.Ctime
+		doc.Versions++
+		doc.Created = doc.Created || version.Version == 1
+		if version.DeltaKnown && version.WordDelta > 0 {
+			doc.WordsAdded += version.WordDelta
+		} else if version.DeltaKnown {
+			doc.WordsRemoved -= version.WordDelta
+		}
+	}
+	for i := range result.Days {
+		summarizeActivityDay(&result.Days[i])
+	}
+	return result, nil
+}
+
+func summarizeActivityDay(day *ActivityDay) {
+	for _, doc := range day.Documents {
+		if doc.Created {
+			day.Created++
+		} else {
+			day.Edited++
+		}
+		day.WordsAdded += doc.WordsAdded
+		day.WordsRemoved += doc.WordsRemoved
+	}
+	sort.SliceStable(day.Documents, func(a, b int) bool {
+		return day.Documents[a].LastCtime > day.Documents[b].LastCtime
+	})
+}
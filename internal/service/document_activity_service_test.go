@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+func TestActivityRange(t *testing.T) {
+	now := time.Date(2024, 5, 10, 20, 0, 0, 0, time.UTC)
+
+	start, end, err := activityRange("", "", "", now)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-04-11", start.Format(time.DateOnly))
+	assert.Equal(t, "2024-05-11", end.Format(time.DateOnly))
+
+	start, end, err = activityRange("2024-05-01", "2024-05-01", "Asia/Shanghai", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, 4, 30, 16, 0, 0, 0, time.UTC).Unix(), start.Unix())
+	assert.Equal(t, int64(24*3600), end.Unix()-start.Unix())
+
+	start, _, err = activityRange("", "", "Asia/Shanghai", now)
+	require.NoError(t, err)
+	assert.Equal(t, "2024-04-12", start.Format(time.DateOnly), "today is already May 11 in Shanghai")
+
+	for _, tc := range [][3]string{
+		{"2024-05-02", "2024-05-01", ""},
+		{"2023-01-01", "2024-05-01", ""},
+		{"May 1", "", ""},
+		{"", "2024-13-01", ""},
+		{"", "", "Mars/Olympus"},
+	} {
+		_, _, err := activityRange(tc[0], tc[1], tc[2], now)
+		assert.ErrorIs(t, err, appErr.ErrInvalid, "%v", tc)
+	}
+}
+
+func TestDocumentService_Activity(t *testing.T) {
+	day := func(d, hour int) int64 { return time.Date(2024, 5, d, hour, 0, 0, 0, time.UTC).Unix() }
+	versions := &mockVersionRepo{
+		listActivityFn: func(_ context.Context, userID string, from, to int64) ([]model.VersionActivity, error) {
+			assert.Equal(t, "u1", userID)
+			assert.Equal(t, day(1, 0), from)
+			assert.Equal(t, day(4, 0), to)
+			return []model.VersionActivity{
+				{DocumentID: "d1", Title: "Draft", Version: 1, Ctime: day(1, 9), WordDelta: 100, DeltaKnown: true},
+				{DocumentID: "d1", Title: "Final", Version: 2, Ctime: day(1, 10), WordDelta: -30, DeltaKnown: true},
+				{DocumentID: "d2", Title: "Old", Version: 8, Ctime: day(1, 11)},
+				{DocumentID: "d2", Title: "Old", Version: 9, Ctime: day(3, 8), WordDelta: 12, DeltaKnown: true},
+			}, nil
+		},
+	}
+	activity, err := newDocSvc(nil, versions, nil, nil).Activity(context.Background(), "u1", "2024-05-01", "2024-05-03", "")
+	require.NoError(t, err)
+	assert.Equal(t, "2024-05-01", activity.From)
+	assert.Equal(t, "2024-05-03", activity.To)
+	assert.Equal(t, "UTC", activity.Timezone)
+	require.Len(t, activity.Days, 3)
+
+	first := activity.Days[0]
+	assert.Equal(t, 1, first.Created)
+	assert.Equal(t, 1, first.Edited)
+	assert.Equal(t, 100, first.WordsAdded)
+	assert.Equal(t, 30, first.WordsRemoved)
+	require.Len(t, first.Documents, 2)
+	assert.Equal(t, "d2", first.Documents[0].DocumentID, "most recent first")
+	assert.Equal(t, ActivityDocument{
+		DocumentID: "d1", Title: "Final", Created: true, Versions: 2,
+		WordsAdded: 100, WordsRemoved: 30, LastCtime: day(1, 10),
+	}, first.Documents[1])
+
+	assert.Equal(t, ActivityDay{Date: "2024-05-02", Documents: []ActivityDocument{}}, activity.Days[1])
+	assert.Equal(t, 1, activity.Days[2].Edited)
+	assert.Equal(t, 12, activity.Days[2].WordsAdded)
+}
+
+func TestDocumentService_Create_RecordsVersionWordCount(t *testing.T) {
+	docs := &mockDocumentRepo{
+		createFn:      func(context.Context, *model.Document) error { return nil },
+		updateLinksFn: func(context.Context, string, string, []model.LinkTarget, int64) error { return nil },
+	}
+	var recorded *model.DocumentVersion
+	versions := &mockVersionRepo{
+		createFn: func(_ context.Context, version *model.DocumentVersion) error {
+			recorded = version
+			return nil
+		},
+		deleteOldVersionsFn: func(context.Context, string, string, int) error { return nil },
+	}
+	_, err := newDocSvc(docs, versions, nil, nil).Create(context.Background(), "u1", DocumentCreateInput{
+		Title: "T", Content: "three small words",
+	})
+	require.NoError(t, err)
+	require.NotNil(t, recorded)
+	assert.Equal(t, 3, recorded.WordCount)
+}
+
+func TestDocumentService_Activity_DefaultRangeUsesClock(t *testing.T) {
+	now := time.Date(2024, 5, 10, 20, 0, 0, 0, time.UTC)
+	versions := &mockVersionRepo{
+		listActivityFn: func(_ context.Context, _ string, from, to int64) ([]model.VersionActivity, error) {
+			assert.Equal(t, time.Date(2024, 4, 11, 0, 0, 0, 0, time.UTC).Unix(), from)
+			assert.Equal(t, time.Date(2024, 5, 11, 0, 0, 0, 0, time.UTC).Unix(), to)
+			return nil, nil
+		},
+	}
+	svc := NewDocumentService(testRuntimeAt(now.Unix()), nil, versions, nil, nil, nil, nil, nil, 10, nil)
+	activity, err := svc.Activity(context.Background(), "u1", "", "", "")
+	require.NoError(t, err)
+	assert.Equal(t, "2024-05-10", activity.To)
+	assert.Len(t, activity.Days, 30)
+}
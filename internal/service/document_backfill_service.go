@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/xxxsen/mnote/internal/model"
+)
+
+// backfillPageSize is the number of documents read per page by a backfill.
+const backfillPageSize = 200
+
+var errBackfillDocuments = errors.New("property backfill requires the document service")
+
+type documentBackfillRepo interface {
+	ListPageAfter(ctx context.Context, afterID string, limit uint) ([]model.Document, error)
+	UpdateStats(ctx context.Context, userID, docID string, stats model.DocumentStats) error
+}
+
+// DocumentBackfillService recomputes what a save derives from the content
+// for documents written before the columns holding it existed. It backs the
+// admin backfill command and is safe to run repeatedly or next to a server.
+type DocumentBackfillService struct {
+	docs      documentBackfillRepo
+	documents *DocumentService
+}
+
+// NewDocumentBackfillService builds the service. documents writes the
+// properties and may be nil when only statistics are backfilled.
+func NewDocumentBackfillService(docs documentBackfillRepo, documents *DocumentService) *DocumentBackfillService {
+	return &DocumentBackfillService{docs: docs, documents: documents}
+}
+
+// BackfillStats recomputes the reading statistics of every document and
+// returns how many of them changed.
+func (s *DocumentBackfillService) BackfillStats(ctx context.Context) (int, error) {
+	updated := 0
+	err := s.eachDocument(ctx, func(doc *model.Document) error {
+		stats := documentStats(doc.Content)
+		if stats == doc.Stats {
+			return nil
+		}
+		if err := s.docs.UpdateStats(ctx, doc.UserID, doc.ID, stats); err != nil {
+			return fmt.Errorf("update stats of %s: %w", doc.ID, err)
+		}
+		updated++
+		return nil
+	})
+	return updated, err
+}
+
+// BackfillProperties stores the front matter properties of every document
+// that has none recorded, re-resolving the wikilinks their aliases match, and
+// returns how many documents were written.
+func (s *DocumentBackfillService) BackfillProperties(ctx context.Context) (int, error) {
+	if s.documents == nil {
+		return 0, errBackfillDocuments
+	}
+	updated := 0
+	err := s.eachDocument(ctx, func(doc *model.Document) error {
+		written, err := s.documents.backfillProperties(ctx, doc)
+		if err != nil {
+			return fmt.Errorf("backfill properties of %s: %w", doc.ID, err)
+		}
+		if written {
+			updated++
+		}
+		return nil
+	})
+	return updated, err
+}
+
+// eachDocument calls fn for every document, page by page in ID order.
+func (s *DocumentBackfillService) eachDocument(ctx context.Context, fn func(doc *model.Document) error) error {
+	afterID := ""
+	for {
+		docs, err := s.docs.ListPageAfter(ctx, afterID, backfillPageSize)
+		if err != nil {
+			return fmt.Errorf("list documents: %w", err)
+		}
+		for i := range docs {
+			if err := fn(&docs[i]); err != nil {
+				return err
+			}
+		}
+		if len(docs) < backfillPageSize {
+			return nil
+		}
+		afterID = docs[len(docs)-1].ID
+	}
+}
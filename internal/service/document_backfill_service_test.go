@@ -0,0 +1,106 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	"github.com/xxxsen/mnote/internal/repo"
+)
+
+// fakeBackfillDocuments pages over documents kept in ID order.
+type fakeBackfillDocuments struct {
+	docs  []model.Document
+	pages int
+	stats map[string]model.DocumentStats
+}
+
+func newFakeBackfillDocuments(count int) *fakeBackfillDocuments {
+	docs := &fakeBackfillDocuments{stats: make(map[string]model.DocumentStats)}
+	for i := 0; i < count; i++ {
+		docs.docs = append(docs.docs, model.Document{
+			ID: fmt.Sprintf("d%04d", i), UserID: "u1", Content: "two words",
+		})
+	}
+	return docs
+}
+
+func (f *fakeBackfillDocuments) ListPageAfter(_ context.Context, afterID string, limit uint) ([]model.Document, error) {
+	f.pages++
+	page := make([]model.Document, 0)
+	for _, doc := range f.docs {
+		if doc.ID > afterID && len(page) < int(limit) {
+			page = append(page, doc)
+		}
+	}
+	return page, nil
+}
+
+func (f *fakeBackfillDocuments) UpdateStats(_ context.Context, _, docID string, stats model.DocumentStats) error {
+	f.stats[docID] = stats
+	return nil
+}
+
+func TestDocumentBackfillService_BackfillStats(t *testing.T) {
+	docs := newFakeBackfillDocuments(backfillPageSize + 1)
+	docs.docs[0].Stats = documentStats(docs.docs[0].Content)
+	svc := NewDocumentBackfillService(docs, nil)
+
+	updated, err := svc.BackfillStats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, backfillPageSize, updated)
+	assert.Equal(t, 2, docs.pages)
+	assert.NotContains(t, docs.stats, "d0000")
+	assert.Equal(t, 2, docs.stats["d0200"].WordCount)
+}
+
+func TestDocumentBackfillService_BackfillProperties(t *testing.T) {
+	content := "---\naliases: [Alpha]\n---\nbody"
+	stored := map[string]model.Document{
+		"d1": {Title: "One", Content: content, State: repo.DocumentStateNormal},
+		"d2": {Title: "Two", Content: content, State: repo.DocumentStateNormal},
+	}
+	docs := newWikilinkDocs(stored, nil)
+	docs.listPropsFn = func(_ context.Context, _, docID string) ([]model.DocumentProperty, error) {
+		if docID == "d2" {
+			return []model.DocumentProperty{{Key: "aliases", Type: model.PropertyTypeList, Values: []string{"Alpha"}}}, nil
+		}
+		return nil, nil
+	}
+	written := make(map[string][]model.DocumentProperty)
+	docs.replacePropsFn = func(_ context.Context, _, docID string, props []model.DocumentProperty, _ int64) error {
+		written[docID] = props
+		return nil
+	}
+	var keys [][]string
+	docs.listWikiSourcesFn = func(_ context.Context, _ string, titleKeys []string, _ string) ([]string, error) {
+		keys = append(keys, titleKeys)
+		return nil, nil
+	}
+	pages := &fakeBackfillDocuments{docs: []model.Document{
+		{ID: "d1", UserID: "u1", Content: content, State: repo.DocumentStateNormal},
+		{ID: "d2", UserID: "u1", Content: content, State: repo.DocumentStateNormal},
+		{ID: "d3", UserID: "u1", Content: "no front matter", State: repo.DocumentStateNormal},
+		{ID: "d4", UserID: "u1", Content: content, State: repo.DocumentStateDeleted},
+	}}
+	svc := NewDocumentBackfillService(pages, newDocSvc(docs, nil, nil, nil))
+
+	updated, err := svc.BackfillProperties(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, updated)
+	assert.Equal(t, []string{"d1", "d4"}, slices.Sorted(maps.Keys(written)))
+	assert.Equal(t, []string{"Alpha"}, written["d1"][0].Values)
+	assert.NotEmpty(t, keys)
+	for _, titleKeys := range keys {
+		assert.Equal(t, []string{"alpha"}, titleKeys)
+	}
+
+	_, err = NewDocumentBackfillService(pages, nil).BackfillProperties(context.Background())
+	assert.Error(t, err)
+}
@@ -0,0 +1,290 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+	"github.com/xxxsen/mnote/internal/pkg/safeconv"
+)
+
+// Bulk operations accepted by BulkService.
+const (
+	BulkOpAddTags    = "add_tags"
+	BulkOpRemoveTags = "remove_tags"
+	BulkOpPin        = "pin"
+	BulkOpUnpin      = "unpin"
+	BulkOpStar       = "star"
+	BulkOpUnstar     = "unstar"
+	BulkOpDelete     = "delete"
+	BulkOpMove       = "move"
+	BulkOpExport     = "export"
+)
+
+const (
+	maxBulkDocuments = 1000
+	bulkBatchSize    = 100
+	bulkSearchPage   = 200
+)
+
+type bulkDocumentQuery interface {
+	Search(ctx context.Context, userID, query, tagID string, starred *int,
+		props []model.PropertyFilter, folder *model.FolderFilter,
+		limit, offset uint, orderBy string) ([]model.Document, error)
+	ValidateOwnedTagIDs(ctx context.Context, userID string, ids []string) ([]string, error)
+}
+
+type bulkDocumentWriter interface {
+	AddTags(ctx context.Context, userID, docID string, tagIDs []string) ([]string, error)
+	RemoveTags(ctx context.Context, userID, docID string, tagIDs []string) ([]string, error)
+	UpdatePinned(ctx context.Context, userID, docID string, pinned int) error
+	UpdateStarred(ctx context.Context, userID, docID string, starred int) error
+	Delete(ctx context.Context, userID, docID string) error
+}
+
+type bulkDocumentService interface {
+	bulkDocumentQuery
+	bulkDocumentWriter
+}
+
+type bulkFolderService interface {
+	Get(ctx context.Context, userID, folderID string) (*model.Folder, error)
+	MoveDocument(ctx context.Context, userID, docID, folderID string) error
+}
+
+type bulkExportService interface {
+	ExportNotesZipByIDs(ctx context.Context, userID string, docIDs []string) (string, error)
+}
+
+// BulkQuery selects documents the way the document list filters them.
+type BulkQuery struct {
+	Query   string
+	TagID   string
+	Starred *int
+	Props   []model.PropertyFilter
+	Folder  *model.FolderFilter
+}
+
+// BulkSelection names the documents of a bulk operation: either the listed
+// IDs or every document matching Query, never both.
+type BulkSelection struct {
+	IDs   []string
+	Query *BulkQuery
+}
+
+// BulkRequest is a bulk operation with its arguments: TagIDs for the tag
+// operations and FolderID, empty for the top level, for a move.
+type BulkRequest struct {
+	Op string
+	BulkSelection
+	TagIDs   []string
+	FolderID string
+}
+
+// BulkItemResult is the outcome for one document; Err is nil on success.
+type BulkItemResult struct {
+	ID  string
+	Err error
+}
+
+type bulkApplyFunc func(ctx context.Context, docID string) error
+
+// BulkService applies one operation to many documents at once.
+type BulkService struct {
+	transactor Transactor
+	documents  bulkDocumentService
+	folders    bulkFolderService
+	export     bulkExportService
+}
+
+func NewBulkService(
+	runtime Runtime,
+	documents bulkDocumentService,
+	folders bulkFolderService,
+	export bulkExportService,
+) *BulkService {
+	runtime = prepareRuntime(runtime)
+	return &BulkService{
+		transactor: runtime.Transactor, documents: documents, folders: folders, export: export,
+	}
+}
+
+// Apply runs a mutating operation over the selected documents in batches of
+// bulkBatchSize, each batch in one transaction. A document that is missing
+// or rejected fails on its own and the rest of the batch goes on; the
+// operations check before they write, so it leaves nothing behind. Any other
+// error rolls the whole batch back and fails each of its documents, while
+// earlier batches stay committed. Results follow the selection order.
+func (s *BulkService) Apply(ctx context.Context, userID string, req BulkRequest) ([]BulkItemResult, error) {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return nil, err
+	}
+	apply, err := s.operation(ctx, userID, req)
+	if err != nil {
+		return nil, err
+	}
+	ids, err := s.resolve(ctx, userID, req.BulkSelection)
+	if err != nil {
+		return nil, err
+	}
+	results := make([]BulkItemResult, 0, len(ids))
+	for start := 0; start < len(ids); start += bulkBatchSize {
+		batch := ids[start:min(start+bulkBatchSize, len(ids))]
+		results = append(results, s.runBatch(ctx, batch, apply)...)
+	}
+	return results, nil
+}
+
+// Export writes the selected documents to a notes zip and returns its path.
+func (s *BulkService) Export(ctx context.Context, userID string, selection BulkSelection) (string, error) {
+	ids, err := s.resolve(ctx, userID, selection)
+	if err != nil {
+		return "", err
+	}
+	path, err := s.export.ExportNotesZipByIDs(ctx, userID, ids)
+	if err != nil {
+		return "", fmt.Errorf("export notes: %w", err)
+	}
+	return path, nil
+}
+
+func (s *BulkService) operation(ctx context.Context, userID string, req BulkRequest) (bulkApplyFunc, error) {
+	switch req.Op {
+	case BulkOpAddTags, BulkOpRemoveTags:
+		return s.tagOperation(ctx, userID, req.Op, req.TagIDs)
+	case BulkOpPin, BulkOpUnpin:
+		pinned := boolToInt(req.Op == BulkOpPin)
+		return func(ctx context.Context, docID string) error {
+			if err := s.documents.UpdatePinned(ctx, userID, docID, pinned); err != nil {
+				return fmt.Errorf("update pinned: %w", err)
+			}
+			return nil
+		}, nil
+	case BulkOpStar, BulkOpUnstar:
+		starred := boolToInt(req.Op == BulkOpStar)
+		return func(ctx context.Context, docID string) error {
+			if err := s.documents.UpdateStarred(ctx, userID, docID, starred); err != nil {
+				return fmt.Errorf("update starred: %w", err)
+			}
+			return nil
+		}, nil
+	case BulkOpDelete:
+		return func(ctx context.Context, docID string) error {
+			if err := s.documents.Delete(ctx, userID, docID); err != nil {
+				return fmt.Errorf("delete document: %w", err)
+			}
+			return nil
+		}, nil
+	case BulkOpMove:
+		if req.FolderID != "" {
+			if _, err := s.folders.Get(ctx, userID, req.FolderID); err != nil {
+				return nil, fmt.Errorf("get folder: %w", err)
+			}
+		}
+		return func(ctx context.Context, docID string) error {
+			if err := s.folders.MoveDocument(ctx, userID, docID, req.FolderID); err != nil {
+				return fmt.Errorf("move document: %w", err)
+			}
+			return nil
+		}, nil
+	default:
+		return nil, appErr.ErrInvalid
+	}
+}
+
+// tagOperation checks the tags once up front so that a tag the user does not
+// own rejects the request instead of failing every document.
+func (s *BulkService) tagOperation(
+	ctx context.Context, userID, op string, tagIDs []string,
+) (bulkApplyFunc, error) {
+	owned, err := s.documents.ValidateOwnedTagIDs(ctx, userID, tagIDs)
+	if err != nil {
+		return nil, fmt.Errorf("validate tags: %w", err)
+	}
+	if len(owned) == 0 {
+		return nil, appErr.ErrInvalid
+	}
+	change := s.documents.AddTags
+	if op == BulkOpRemoveTags {
+		change = s.documents.RemoveTags
+	}
+	return func(ctx context.Context, docID string) error {
+		if _, err := change(ctx, userID, docID, owned); err != nil {
+			return fmt.Errorf("change tags: %w", err)
+		}
+		return nil
+	}, nil
+}
+
+// resolve returns the IDs of the selected documents, at most
+// maxBulkDocuments of them. A query is resolved before anything is applied,
+// so the operation cannot shift the pages it reads.
+func (s *BulkService) resolve(ctx context.Context, userID string, selection BulkSelection) ([]string, error) {
+	if (len(selection.IDs) == 0) == (selection.Query == nil) {
+		return nil, appErr.ErrInvalid
+	}
+	if selection.Query == nil {
+		ids := uniqueStringSlice(selection.IDs)
+		if len(ids) == 0 || len(ids) > maxBulkDocuments {
+			return nil, appErr.ErrInvalid
+		}
+		return ids, nil
+	}
+	query := selection.Query
+	ids := make([]string, 0)
+	for offset := 0; ; offset += bulkSearchPage {
+		docs, err := s.documents.Search(
+			ctx, userID, query.Query, query.TagID, query.Starred, query.Props, query.Folder,
+			bulkSearchPage, safeconv.IntToUint(offset), "",
+		)
+		if err != nil {
+			return nil, fmt.Errorf("search documents: %w", err)
+		}
+		for _, doc := range docs {
+			ids = append(ids, doc.ID)
+		}
+		if len(ids) > maxBulkDocuments {
+			return nil, appErr.ErrInvalid
+		}
+		if len(docs) < bulkSearchPage {
+			return ids, nil
+		}
+	}
+}
+
+func (s *BulkService) runBatch(ctx context.Context, ids []string, apply bulkApplyFunc) []BulkItemResult {
+	results := make([]BulkItemResult, 0, len(ids))
+	err := s.transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+		for _, id := range ids {
+			err := apply(txCtx, id)
+			if err != nil && !isBulkItemError(err) {
+				return err
+			}
+			results = append(results, BulkItemResult{ID: id, Err: err})
+		}
+		return nil
+	})
+	if err != nil {
+		results = results[:0]
+		for _, id := range ids {
+			results = append(results, BulkItemResult{ID: id, Err: err})
+		}
+	}
+	return results
+}
+
+// isBulkItemError tells the errors that fail a single document, raised
+// before it is written to, from those that break the batch.
+func isBulkItemError(err error) bool {
+	return errors.Is(err, appErr.ErrNotFound) || errors.Is(err, appErr.ErrInvalid) ||
+		errors.Is(err, appErr.ErrForbidden)
+}
+
+func boolToInt(value bool) int {
+	if value {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,219 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+// fakeBulkDocuments keeps documents with their pin, star and tags in memory.
+type fakeBulkDocuments struct {
+	docs     []string
+	pinned   map[string]int
+	tags     map[string][]string
+	searches int
+	failOn   string
+}
+
+func newFakeBulkDocuments(count int) *fakeBulkDocuments {
+	docs := &fakeBulkDocuments{pinned: make(map[string]int), tags: make(map[string][]string)}
+	for i := 0; i < count; i++ {
+		docs.docs = append(docs.docs, fmt.Sprintf("d%d", i))
+	}
+	return docs
+}
+
+func (f *fakeBulkDocuments) find(docID string) error {
+	if docID == f.failOn {
+		return errors.New("db error")
+	}
+	for _, id := range f.docs {
+		if id == docID {
+			return nil
+		}
+	}
+	return appErr.ErrNotFound
+}
+
+func (f *fakeBulkDocuments) Search(
+	_ context.Context, _, _, _ string, _ *int, _ []model.PropertyFilter, _ *model.FolderFilter,
+	limit, offset uint, _ string,
+) ([]model.Document, error) {
+	f.searches++
+	docs := make([]model.Document, 0)
+	for i := int(offset); i < len(f.docs) && len(docs) < int(limit); i++ {
+		docs = append(docs, model.Document{ID: f.docs[i]})
+	}
+	return docs, nil
+}
+
+func (f *fakeBulkDocuments) ValidateOwnedTagIDs(_ context.Context, _ string, ids []string) ([]string, error) {
+	ids = uniqueStringSlice(ids)
+	for _, id := range ids {
+		if id != "t1" {
+			return nil, appErr.ErrInvalid
+		}
+	}
+	return ids, nil
+}
+
+func (f *fakeBulkDocuments) AddTags(_ context.Context, _, docID string, tagIDs []string) ([]string, error) {
+	if err := f.find(docID); err != nil {
+		return nil, err
+	}
+	f.tags[docID] = uniqueStringSlice(append(f.tags[docID], tagIDs...))
+	return f.tags[docID], nil
+}
+
+func (f *fakeBulkDocuments) RemoveTags(_ context.Context, _, docID string, _ []string) ([]string, error) {
+	if err := f.find(docID); err != nil {
+		return nil, err
+	}
+	f.tags[docID] = []string{}
+	return f.tags[docID], nil
+}
+
+func (f *fakeBulkDocuments) UpdatePinned(_ context.Context, _, docID string, pinned int) error {
+	if err := f.find(docID); err != nil {
+		return err
+	}
+	f.pinned[docID] = pinned
+	return nil
+}
+
+func (f *fakeBulkDocuments) UpdateStarred(_ context.Context, _, docID string, _ int) error {
+	return f.find(docID)
+}
+
+func (f *fakeBulkDocuments) Delete(_ context.Context, _, docID string) error {
+	return f.find(docID)
+}
+
+type fakeBulkExport struct {
+	ids []string
+}
+
+func (f *fakeBulkExport) ExportNotesZipByIDs(_ context.Context, _ string, docIDs []string) (string, error) {
+	f.ids = docIDs
+	return "/tmp/notes.zip", nil
+}
+
+func newTestBulkService(docs *fakeBulkDocuments) (*BulkService, *fakeFolderRepo, *fakeBulkExport) {
+	folders, repo := newTestFolderService()
+	export := &fakeBulkExport{}
+	return NewBulkService(testRuntime(), docs, folders, export), repo, export
+}
+
+func TestBulkService_Apply_IDs(t *testing.T) {
+	docs := newFakeBulkDocuments(2)
+	svc, _, _ := newTestBulkService(docs)
+
+	results, err := svc.Apply(context.Background(), "u1", BulkRequest{
+		Op: BulkOpPin, BulkSelection: BulkSelection{IDs: []string{"d1", "missing", "d1", " ", "d0"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+	assert.Equal(t, BulkItemResult{ID: "d1"}, results[0])
+	assert.Equal(t, "missing", results[1].ID)
+	assert.ErrorIs(t, results[1].Err, appErr.ErrNotFound)
+	assert.NoError(t, results[2].Err)
+	assert.Equal(t, map[string]int{"d0": 1, "d1": 1}, docs.pinned)
+}
+
+func TestBulkService_Apply_Query(t *testing.T) {
+	docs := newFakeBulkDocuments(450)
+	svc, _, _ := newTestBulkService(docs)
+
+	results, err := svc.Apply(context.Background(), "u1", BulkRequest{
+		Op: BulkOpAddTags, BulkSelection: BulkSelection{Query: &BulkQuery{Query: "imported"}},
+		TagIDs: []string{"t1"},
+	})
+	require.NoError(t, err)
+	assert.Len(t, results, 450)
+	assert.Equal(t, 3, docs.searches)
+	assert.Equal(t, []string{"t1"}, docs.tags["d449"])
+}
+
+func TestBulkService_Apply_BatchFailure(t *testing.T) {
+	docs := newFakeBulkDocuments(250)
+	docs.failOn = "d150"
+	svc, _, _ := newTestBulkService(docs)
+
+	results, err := svc.Apply(context.Background(), "u1", BulkRequest{
+		Op: BulkOpUnpin, BulkSelection: BulkSelection{Query: &BulkQuery{}},
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 250)
+	for i, result := range results {
+		if i >= bulkBatchSize && i < 2*bulkBatchSize {
+			assert.Error(t, result.Err, result.ID)
+			continue
+		}
+		assert.NoError(t, result.Err, result.ID)
+	}
+}
+
+func TestBulkService_Apply_Move(t *testing.T) {
+	docs := newFakeBulkDocuments(1)
+	svc, repo, _ := newTestBulkService(docs)
+	repo.docs["d0"] = ""
+	repo.folders["f1"] = model.Folder{ID: "f1", UserID: "u1", Name: "Inbox"}
+
+	results, err := svc.Apply(context.Background(), "u1", BulkRequest{
+		Op: BulkOpMove, BulkSelection: BulkSelection{IDs: []string{"d0"}}, FolderID: "f1",
+	})
+	require.NoError(t, err)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, "f1", repo.docs["d0"])
+
+	_, err = svc.Apply(context.Background(), "u1", BulkRequest{
+		Op: BulkOpMove, BulkSelection: BulkSelection{IDs: []string{"d0"}}, FolderID: "missing",
+	})
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
+
+func TestBulkService_Apply_Invalid(t *testing.T) {
+	docs := newFakeBulkDocuments(maxBulkDocuments + 1)
+	svc, _, _ := newTestBulkService(docs)
+	ids := BulkSelection{IDs: []string{"d0"}}
+
+	tests := []BulkRequest{
+		{Op: "rename", BulkSelection: ids},
+		{Op: BulkOpExport, BulkSelection: ids},
+		{Op: BulkOpAddTags, BulkSelection: ids},
+		{Op: BulkOpRemoveTags, BulkSelection: ids, TagIDs: []string{"t2"}},
+		{Op: BulkOpStar},
+		{Op: BulkOpStar, BulkSelection: BulkSelection{IDs: []string{"d0"}, Query: &BulkQuery{}}},
+		{Op: BulkOpDelete, BulkSelection: BulkSelection{Query: &BulkQuery{}}},
+	}
+	for _, req := range tests {
+		_, err := svc.Apply(context.Background(), "u1", req)
+		assert.ErrorIs(t, err, appErr.ErrInvalid, req.Op)
+	}
+}
+
+func TestBulkService_Apply_ReadOnlyWorkspace(t *testing.T) {
+	svc, _, _ := newTestBulkService(newFakeBulkDocuments(1))
+	ctx := WithWorkspaceAccess(context.Background(), WorkspaceAccess{
+		WorkspaceID: "w1", UserID: "u2", Role: model.WorkspaceRoleViewer,
+	})
+	_, err := svc.Apply(ctx, "w1", BulkRequest{Op: BulkOpPin, BulkSelection: BulkSelection{IDs: []string{"d0"}}})
+	assert.ErrorIs(t, err, appErr.ErrForbidden)
+}
+
+func TestBulkService_Export(t *testing.T) {
+	docs := newFakeBulkDocuments(3)
+	svc, _, export := newTestBulkService(docs)
+
+	path, err := svc.Export(context.Background(), "u1", BulkSelection{Query: &BulkQuery{}})
+	require.NoError(t, err)
+	assert.Equal(t, "/tmp/notes.zip", path)
+	assert.Equal(t, []string{"d0", "d1", "d2"}, export.ids)
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+// SharedWithMeItem is a document another user or workspace invited the
+// caller to, with the role the invitation grants.
+type SharedWithMeItem struct {
+	ID             string `json:"id"`
+	Title          string `json:"title"`
+	ContentPreview string `json:"content_preview"`
+	Mtime          int64  `json:"mtime"`
+	OwnerID        string `json:"owner_id"`
+	OwnerName      string `json:"owner_name"`
+	Role           string `json:"role"`
+}
+
+func (s *DocumentService) ListCollaborators(
+	ctx context.Context, userID, docID string,
+) ([]model.DocumentCollaborator, error) {
+	if _, err := s.docs.GetByID(ctx, userID, docID); err != nil {
+		return nil, fmt.Errorf("get by id: %w", err)
+	}
+	items, err := s.shares.ListCollaborators(ctx, userID, docID)
+	if err != nil {
+		return nil, fmt.Errorf("list collaborators: %w", err)
+	}
+	return items, nil
+}
+
+// AddCollaborator invites the registered user with the given email to the
+// document, or changes the role of a user already invited.
+func (s *DocumentService) AddCollaborator(
+	ctx context.Context, userID, docID, email, role string,
+) (*model.DocumentCollaborator, error) {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return nil, err
+	}
+	if role != model.CollaboratorRoleEditor && role != model.CollaboratorRoleViewer {
+		return nil, appErr.ErrInvalid
+	}
+	if _, err := s.docs.GetByID(ctx, userID, docID); err != nil {
+		return nil, fmt.Errorf("get by id: %w", err)
+	}
+	user, err := lookupUserByEmail(ctx, s.userRepo, email)
+	if err != nil {
+		return nil, err
+	}
+	if user.ID == actorID(ctx, userID) {
+		return nil, appErr.ErrInvalid
+	}
+	now := s.now()
+	if err := s.shares.UpsertCollaborator(ctx, &model.DocumentCollaborator{
+		DocumentID: docID, OwnerID: userID, UserID: user.ID, Role: role, Ctime: now, Mtime: now,
+	}); err != nil {
+		return nil, fmt.Errorf("upsert collaborator: %w", err)
+	}
+	collaborator, err := s.shares.GetCollaborator(ctx, docID, user.ID)
+	if err != nil {
+		return nil, fmt.Errorf("get collaborator: %w", err)
+	}
+	return collaborator, nil
+}
+
+// RemoveCollaborator revokes a user's access to the document. Collaborators
+// may also remove themselves to leave a document.
+func (s *DocumentService) RemoveCollaborator(ctx context.Context, userID, docID, collaboratorID string) error {
+	if collaboratorID != userID {
+		if err := requireWorkspaceWrite(ctx); err != nil {
+			return err
+		}
+		if _, err := s.docs.GetByID(ctx, userID, docID); err != nil {
+			return fmt.Errorf("get by id: %w", err)
+		}
+	}
+	if err := s.shares.DeleteCollaborator(ctx, docID, collaboratorID); err != nil {
+		return fmt.Errorf("delete collaborator: %w", err)
+	}
+	return nil
+}
+
+func (s *DocumentService) ListSharedWithMe(ctx context.Context, userID string) ([]SharedWithMeItem, error) {
+	items, err := s.shares.ListCollaboratedDocuments(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list collaborated documents: %w", err)
+	}
+	results := make([]SharedWithMeItem, 0, len(items))
+	for _, item := range items {
+		results = append(results, SharedWithMeItem{
+			ID:             item.ID,
+			Title:          item.Title,
+			ContentPreview: item.ContentPreview,
+			Mtime:          item.Mtime,
+			OwnerID:        item.OwnerID,
+			OwnerName:      item.OwnerName,
+			Role:           item.Role,
+		})
+	}
+	return results, nil
+}
+
+// readableDocument loads a document the user owns or was invited to and
+// returns it with the owning scope that later reads must use.
+func (s *DocumentService) readableDocument(
+	ctx context.Context, userID, docID string,
+) (*model.Document, string, error) {
+	doc, err := s.docs.GetByID(ctx, userID, docID)
+	if err == nil {
+		return doc, userID, nil
+	}
+	ownerID, cerr := s.collaboratorOwner(ctx, userID, docID, false, err)
+	if cerr != nil {
+		return nil, "", cerr
+	}
+	doc, err = s.docs.GetByID(ctx, ownerID, docID)
+	if err != nil {
+		return nil, "", fmt.Errorf("get shared document: %w", err)
+	}
+	return doc, ownerID, nil
+}
+
+// collaboratorOwner resolves the owner of a document the user could not load
+// in their own scope. lookupErr is that failed lookup; it is returned as is
+// unless it is ErrNotFound and the user was invited to the document. Viewers
+// asking for write access get ErrForbidden.
+func (s *DocumentService) collaboratorOwner(
+	ctx context.Context, userID, docID string, write bool, lookupErr error,
+) (string, error) {
+	if !errors.Is(lookupErr, appErr.ErrNotFound) {
+		return "", fmt.Errorf("get by id: %w", lookupErr)
+	}
+	collaborator, err := s.shares.GetCollaborator(ctx, docID, userID)
+	if err != nil {
+		if errors.Is(err, appErr.ErrNotFound) {
+			return "", fmt.Errorf("get by id: %w", lookupErr)
+		}
+		return "", fmt.Errorf("get collaborator: %w", err)
+	}
+	if write && collaborator.Role != model.CollaboratorRoleEditor {
+		return "", appErr.ErrForbidden
+	}
+	return collaborator.OwnerID, nil
+}
+
+// OpenForEditing loads a document for the real-time editing channel and
+// reports whether the user may change it. Workspace viewers and documents
+// shared with the viewer role open read-only.
+func (s *DocumentService) OpenForEditing(ctx context.Context, userID, docID string) (*model.Document, bool, error) {
+	doc, ownerID, err := s.readableDocument(ctx, userID, docID)
+	if err != nil {
+		return nil, false, err
+	}
+	if requireWorkspaceWrite(ctx) != nil {
+		return doc, false, nil
+	}
+	if ownerID == userID {
+		return doc, true, nil
+	}
+	if _, err := s.collaboratorOwner(ctx, userID, docID, true, appErr.ErrNotFound); err != nil {
+		if errors.Is(err, appErr.ErrForbidden) {
+			return doc, false, nil
+		}
+		return nil, false, err
+	}
+	return doc, true, nil
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+// collaboratorDocs serves document d1 to its owner u1 only, like the SQL repo.
+func collaboratorDocs() *mockDocumentRepo {
+	get := func(_ context.Context, userID, docID string) (*model.Document, error) {
+		if userID != "u1" || docID != "d1" {
+			return nil, appErr.ErrNotFound
+		}
+		return &model.Document{ID: "d1", UserID: "u1", Title: "Plan", ContentRevision: 2}, nil
+	}
+	return &mockDocumentRepo{
+		getByIDFn:          get,
+		getByIDForUpdateFn: get,
+		updateFn:           func(context.Context, *model.Document) error { return nil },
+		updateLinksFn:      func(context.Context, string, string, []model.LinkTarget, int64) error { return nil },
+	}
+}
+
+func collaboratorShares(roles map[string]string) *mockShareRepo {
+	return &mockShareRepo{
+		getCollaboratorFn: func(_ context.Context, docID, userID string) (*model.DocumentCollaborator, error) {
+			role, ok := roles[userID]
+			if !ok {
+				return nil, appErr.ErrNotFound
+			}
+			return &model.DocumentCollaborator{DocumentID: docID, OwnerID: "u1", UserID: userID, Role: role}, nil
+		},
+	}
+}
+
+func TestDocumentService_Get_Collaborator(t *testing.T) {
+	shares := collaboratorShares(map[string]string{"u2": model.CollaboratorRoleViewer})
+	svc := newDocSvc(collaboratorDocs(), nil, nil, shares)
+
+	doc, err := svc.Get(context.Background(), "u2", "d1")
+	require.NoError(t, err)
+	assert.Equal(t, "u1", doc.UserID)
+
+	_, err = svc.Get(context.Background(), "u3", "d1")
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
+
+func TestDocumentService_Save_Collaborator(t *testing.T) {
+	var created *model.DocumentVersion
+	versions := &mockVersionRepo{
+		createFn: func(_ context.Context, v *model.DocumentVersion) error {
+			created = v
+			return nil
+		},
+		deleteOldVersionsFn: func(context.Context, string, string, int) error { return nil },
+	}
+	tags := &mockDocumentTagRepo{
+		deleteByDocFn: func(context.Context, string, string) error { return nil },
+	}
+	shares := collaboratorShares(map[string]string{
+		"u2": model.CollaboratorRoleEditor,
+		"u3": model.CollaboratorRoleViewer,
+	})
+	svc := newDocSvc(collaboratorDocs(), versions, tags, shares)
+	input := DocumentUpdateInput{Title: "Plan", Content: "v3", BaseRevision: 2}
+
+	result, err := svc.Save(context.Background(), "u2", "d1", input)
+	require.NoError(t, err)
+	assert.True(t, result.Accepted)
+	require.NotNil(t, created)
+	assert.Equal(t, "u1", created.UserID)
+	assert.Equal(t, "u2", created.AuthorID)
+
+	_, err = svc.Save(context.Background(), "u3", "d1", input)
+	assert.ErrorIs(t, err, appErr.ErrForbidden)
+	_, err = svc.Save(context.Background(), "u4", "d1", input)
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
+
+func TestDocumentService_AddCollaborator(t *testing.T) {
+	var upserted *model.DocumentCollaborator
+	shares := collaboratorShares(map[string]string{})
+	shares.upsertCollaboratorFn = func(_ context.Context, c *model.DocumentCollaborator) error {
+		upserted = c
+		return nil
+	}
+	shares.getCollaboratorFn = func(context.Context, string, string) (*model.DocumentCollaborator, error) {
+		return upserted, nil
+	}
+	users := &mockUserRepo{getByEmailFn: func(_ context.Context, email string) (*model.User, error) {
+		switch email {
+		case "a@example.com":
+			return &model.User{ID: "u1", Email: email}, nil
+		case "b@example.com":
+			return &model.User{ID: "u2", Email: email}, nil
+		}
+		return nil, appErr.ErrNotFound
+	}}
+	svc := NewDocumentService(testRuntime(), collaboratorDocs(), nil, nil, shares, &mockTagRepo{}, users, nil, 10, nil)
+	ctx := context.Background()
+
+	collaborator, err := svc.AddCollaborator(ctx, "u1", "d1", "b@example.com", model.CollaboratorRoleEditor)
+	require.NoError(t, err)
+	assert.Equal(t, "u1", collaborator.OwnerID)
+	assert.Equal(t, "u2", collaborator.UserID)
+
+	_, err = svc.AddCollaborator(ctx, "u1", "d1", "a@example.com", model.CollaboratorRoleViewer)
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+	_, err = svc.AddCollaborator(ctx, "u1", "d1", "b@example.com", model.WorkspaceRoleOwner)
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+	_, err = svc.AddCollaborator(ctx, "u2", "d1", "b@example.com", model.CollaboratorRoleViewer)
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+const (
+	DefaultGraphDepth = 1
+	MaxGraphDepth     = 3
+
+	// graphSemanticNeighbours caps the semantic edges fetched per document.
+	graphSemanticNeighbours = 5
+)
+
+const (
+	GraphEdgeLink     = "link"
+	GraphEdgeSemantic = "semantic"
+)
+
+// GraphQuery selects the part of the knowledge graph to return. Without
+// RootID the whole library is returned; with it only documents within Depth
+// edges of the root. TagID keeps only documents with that tag, though the
+// root is always kept. Semantic adds similarity edges scoring at least
+// MinScore and the profile's own minimum.
+type GraphQuery struct {
+	TagID    string
+	RootID   string
+	Depth    int
+	Semantic bool
+	MinScore float32
+}
+
+type GraphNode struct {
+	model.GraphDocument
+	TagIDs []string
+	// Degree counts the returned edges touching the node.
+	Degree int
+}
+
+// GraphEdge is a link from Source to Target, or an undirected semantic edge
+// with its similarity Score.
+type GraphEdge struct {
+	Source string
+	Target string
+	Kind   string
+	Score  float32
+}
+
+type DocumentGraph struct {
+	Nodes []GraphNode
+	Edges []GraphEdge
+	// SemanticStatus is the index status when semantic edges were requested.
+	SemanticStatus string
+}
+
+// Graph builds the knowledge graph of the user's documents from their links
+// and, when asked, from the semantic index. A rooted query walks outward one
+// ring at a time, fetching semantic neighbours only for the current ring.
+func (s *DocumentService) Graph(ctx context.Context, userID string, query GraphQuery) (*DocumentGraph, error) {
+	if query.Depth < 0 || query.Depth > MaxGraphDepth || query.MinScore < 0 || query.MinScore > 1 {
+		return nil, appErr.ErrInvalid
+	}
+	if query.RootID == "" && query.Depth != 0 {
+		return nil, appErr.ErrInvalid
+	}
+	docs, err := s.docs.ListGraphDocuments(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list graph documents: %w", err)
+	}
+	builder, err := s.newGraphBuilder(ctx, userID, docs, query)
+	if err != nil {
+		return nil, err
+	}
+	links, err := s.docs.ListAllLinks(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list links: %w", err)
+	}
+	for _, link := range links {
+		builder.addEdge(GraphEdge{Source: link.SourceID, Target: link.TargetID, Kind: GraphEdgeLink})
+	}
+	if query.RootID == "" {
+		if query.Semantic {
+			if err := builder.addSemanticEdges(ctx, s, userID, builder.candidateIDs(docs), query.MinScore); err != nil {
+				return nil, err
+			}
+		}
+		builder.selected = builder.candidates
+	} else if err := builder.walk(ctx, s, userID, query); err != nil {
+		return nil, err
+	}
+	return s.finishGraph(ctx, userID, docs, builder)
+}
+
+// graphBuilder collects the edges between candidate documents. Every pair
+// of documents gets at most one semantic edge, and none when linked.
+type graphBuilder struct {
+	candidates map[string]bool
+	selected   map[string]bool
+	edges      []GraphEdge
+	pairs      map[[2]string]bool
+	adjacent   map[string][]string
+	status     string
+}
+
+func (s *DocumentService) newGraphBuilder(
+	ctx context.Context, userID string, docs []model.GraphDocument, query GraphQuery,
+) (*graphBuilder, error) {
+	builder := &graphBuilder{
+		candidates: make(map[string]bool, len(docs)),
+		pairs:      map[[2]string]bool{},
+		adjacent:   map[string][]string{},
+	}
+	var tagged map[string]bool
+	if query.TagID != "" {
+		ids, err := s.tags.ListDocIDsByTag(ctx, userID, query.TagID)
+		if err != nil {
+			return nil, fmt.Errorf("list tag documents: %w", err)
+		}
+		tagged = make(map[string]bool, len(ids))
+		for _, id := range ids {
+			tagged[id] = true
+		}
+	}
+	rootFound := false
+	for _, doc := range docs {
+		if doc.ID == query.RootID {
+			rootFound = true
+			builder.candidates[doc.ID] = true
+			continue
+		}
+		if tagged == nil || tagged[doc.ID] {
+			builder.candidates[doc.ID] = true
+		}
+	}
+	if query.RootID != "" && !rootFound {
+		return nil, appErr.ErrNotFound
+	}
+	return builder, nil
+}
+
+func (b *graphBuilder) candidateIDs(docs []model.GraphDocument) []string {
+	ids := make([]string, 0, len(b.candidates))
+	for _, doc := range docs {
+		if b.candidates[doc.ID] {
+			ids = append(ids, doc.ID)
+		}
+	}
+	return ids
+}
+
+func (b *graphBuilder) addEdge(edge GraphEdge) {
+	if edge.Source == edge.Target || !b.candidates[edge.Source] || !b.candidates[edge.Target] {
+		return
+	}
+	key := [2]string{min(edge.Source, edge.Target), max(edge.Source, edge.Target)}
+	if edge.Kind == GraphEdgeSemantic && b.pairs[key] {
+		return
+	}
+	b.pairs[key] = true
+	b.edges = append(b.edges, edge)
+	b.adjacent[edge.Source] = append(b.adjacent[edge.Source], edge.Target)
+	b.adjacent[edge.Target] = append(b.adjacent[edge.Target], edge.Source)
+}
+
+func (b *graphBuilder) addSemanticEdges(
+	ctx context.Context, s *DocumentService, userID string, ids []string, minScore float32,
+) error {
+	if s.embedding == nil {
+		b.status = "disabled"
+		return nil
+	}
+	pairs, status, err := s.embedding.SimilarDocumentPairs(ctx, userID, ids, graphSemanticNeighbours, minScore)
+	if err != nil {
+		return fmt.Errorf("similar document pairs: %w", err)
+	}
+	b.status = status
+	for _, pair := range pairs {
+		b.addEdge(GraphEdge{Source: pair.SourceID, Target: pair.TargetID, Kind: GraphEdgeSemantic, Score: pair.Score})
+	}
+	return nil
+}
+
+// walk selects the documents within query.Depth edges of the root.
+func (b *graphBuilder) walk(ctx context.Context, s *DocumentService, userID string, query GraphQuery) error {
+	depth := query.Depth
+	if depth == 0 {
+		depth = DefaultGraphDepth
+	}
+	b.selected = map[string]bool{query.RootID: true}
+	frontier := []string{query.RootID}
+	for range depth {
+		if len(frontier) == 0 {
+			break
+		}
+		if query.Semantic {
+			if err := b.addSemanticEdges(ctx, s, userID, frontier, query.MinScore); err != nil {
+				return err
+			}
+		}
+		var next []string
+		for _, id := range frontier {
+			for _, neighbour := range b.adjacent[id] {
+				if !b.selected[neighbour] {
+					b.selected[neighbour] = true
+					next = append(next, neighbour)
+				}
+			}
+		}
+		slices.Sort(next)
+		frontier = next
+	}
+	return nil
+}
+
+func (s *DocumentService) finishGraph(
+	ctx context.Context, userID string, docs []model.GraphDocument, builder *graphBuilder,
+) (*DocumentGraph, error) {
+	graph := &DocumentGraph{Nodes: []GraphNode{}, Edges: []GraphEdge{}, SemanticStatus: builder.status}
+	degree := map[string]int{}
+	for _, edge := range builder.edges {
+		if builder.selected[edge.Source] && builder.selected[edge.Target] {
+			graph.Edges = append(graph.Edges, edge)
+			degree[edge.Source]++
+			degree[edge.Target]++
+		}
+	}
+	ids := make([]string, 0, len(builder.selected))
+	for _, doc := range docs {
+		if builder.selected[doc.ID] {
+			ids = append(ids, doc.ID)
+		}
+	}
+	tagIDs, err := s.tags.ListTagIDsByDocIDs(ctx, userID, ids)
+	if err != nil {
+		return nil, fmt.Errorf("list tag ids: %w", err)
+	}
+	for _, doc := range docs {
+		if !builder.selected[doc.ID] {
+			continue
+		}
+		tags := tagIDs[doc.ID]
+		if tags == nil {
+			tags = []string{}
+		}
+		graph.Nodes = append(graph.Nodes, GraphNode{GraphDocument: doc, TagIDs: tags, Degree: degree[doc.ID]})
+	}
+	return graph, nil
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+type graphEmbeddingClient struct {
+	stubEmbeddingClient
+	pairs   []model.SimilarDocumentPair
+	sources [][]string
+}
+
+func (c *graphEmbeddingClient) SimilarDocumentPairs(
+	_ context.Context, _ string, ids []string, _ int, _ float32,
+) ([]model.SimilarDocumentPair, string, error) {
+	c.sources = append(c.sources, ids)
+	var pairs []model.SimilarDocumentPair
+	for _, pair := range c.pairs {
+		for _, id := range ids {
+			if pair.SourceID == id {
+				pairs = append(pairs, pair)
+			}
+		}
+	}
+	return pairs, "ready", nil
+}
+
+// newGraphSvc serves the chain a -> b -> c -> d plus the unlinked e; a, b and
+// d carry tag t.
+func newGraphSvc(embedding documentEmbeddingClient) *DocumentService {
+	docs := &mockDocumentRepo{
+		listGraphDocsFn: func(context.Context, string) ([]model.GraphDocument, error) {
+			return []model.GraphDocument{
+				{ID: "a", Title: "A", Pinned: 1}, {ID: "b", Title: "B"}, {ID: "c", Title: "C"},
+				{ID: "d", Title: "D", Starred: 1}, {ID: "e", Title: "E"},
+			}, nil
+		},
+		listAllLinksFn: func(context.Context, string) ([]model.DocumentLink, error) {
+			return []model.DocumentLink{
+				{SourceID: "a", TargetID: "b"}, {SourceID: "b", TargetID: "c"}, {SourceID: "c", TargetID: "d"},
+			}, nil
+		},
+	}
+	tags := &mockDocumentTagRepo{
+		listDocIDsByTagFn: func(_ context.Context, _, tagID string) ([]string, error) {
+			if tagID != "t" {
+				return nil, nil
+			}
+			return []string{"a", "b", "d"}, nil
+		},
+		listTagIDsByDocIDsFn: func(context.Context, string, []string) (map[string][]string, error) {
+			return map[string][]string{"a": {"t"}, "b": {"t"}, "d": {"t"}}, nil
+		},
+	}
+	return NewDocumentService(testRuntime(), docs, nil, tags, &mockShareRepo{},
+		&mockTagRepo{}, &mockUserRepo{}, embedding, 10, nil)
+}
+
+func graphNodeIDs(graph *DocumentGraph) []string {
+	ids := make([]string, 0, len(graph.Nodes))
+	for _, node := range graph.Nodes {
+		ids = append(ids, node.ID)
+	}
+	return ids
+}
+
+func TestDocumentService_Graph_WholeLibrary(t *testing.T) {
+	graph, err := newGraphSvc(nil).Graph(context.Background(), "u1", GraphQuery{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c", "d", "e"}, graphNodeIDs(graph))
+	assert.Len(t, graph.Edges, 3)
+	assert.Equal(t, 2, graph.Nodes[1].Degree)
+	assert.Equal(t, []string{"t"}, graph.Nodes[0].TagIDs)
+	assert.Equal(t, []string{}, graph.Nodes[2].TagIDs)
+	assert.Equal(t, 1, graph.Nodes[0].Pinned)
+	assert.Empty(t, graph.SemanticStatus)
+}
+
+func TestDocumentService_Graph_RootDepthAndTag(t *testing.T) {
+	svc := newGraphSvc(nil)
+	ctx := context.Background()
+
+	graph, err := svc.Graph(ctx, "u1", GraphQuery{RootID: "b"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, graphNodeIDs(graph))
+
+	graph, err = svc.Graph(ctx, "u1", GraphQuery{RootID: "a", Depth: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, graphNodeIDs(graph))
+	assert.Len(t, graph.Edges, 2)
+
+	graph, err = svc.Graph(ctx, "u1", GraphQuery{TagID: "t"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "d"}, graphNodeIDs(graph))
+	assert.Equal(t, []GraphEdge{{Source: "a", Target: "b", Kind: GraphEdgeLink}}, graph.Edges)
+	assert.Zero(t, graph.Nodes[2].Degree)
+
+	graph, err = svc.Graph(ctx, "u1", GraphQuery{TagID: "t", RootID: "c", Depth: 1})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b", "c", "d"}, graphNodeIDs(graph))
+}
+
+func TestDocumentService_Graph_SemanticEdges(t *testing.T) {
+	embedding := &graphEmbeddingClient{pairs: []model.SimilarDocumentPair{
+		{SourceID: "a", TargetID: "e", Score: 0.9},
+		{SourceID: "a", TargetID: "b", Score: 0.8},
+		{SourceID: "e", TargetID: "a", Score: 0.9},
+		{SourceID: "e", TargetID: "d", Score: 0.7},
+	}}
+	graph, err := newGraphSvc(embedding).Graph(context.Background(), "u1", GraphQuery{RootID: "a", Semantic: true})
+	require.NoError(t, err)
+	assert.Equal(t, "ready", graph.SemanticStatus)
+	assert.Equal(t, [][]string{{"a"}}, embedding.sources)
+	assert.Equal(t, []string{"a", "b", "e"}, graphNodeIDs(graph))
+	assert.Equal(t, []GraphEdge{
+		{Source: "a", Target: "b", Kind: GraphEdgeLink},
+		{Source: "a", Target: "e", Kind: GraphEdgeSemantic, Score: 0.9},
+	}, graph.Edges)
+
+	graph, err = newGraphSvc(embedding).Graph(context.Background(), "u1", GraphQuery{Semantic: true})
+	require.NoError(t, err)
+	assert.Len(t, graph.Edges, 5)
+
+	graph, err = newGraphSvc(nil).Graph(context.Background(), "u1", GraphQuery{Semantic: true})
+	require.NoError(t, err)
+	assert.Equal(t, "disabled", graph.SemanticStatus)
+}
+
+func TestDocumentService_Graph_InvalidQuery(t *testing.T) {
+	svc := newGraphSvc(nil)
+	ctx := context.Background()
+	for _, query := range []GraphQuery{
+		{Depth: 1},
+		{RootID: "a", Depth: MaxGraphDepth + 1},
+		{RootID: "a", Depth: -1},
+		{MinScore: 1.5},
+	} {
+		_, err := svc.Graph(ctx, "u1", query)
+		assert.ErrorIs(t, err, appErr.ErrInvalid, "%+v", query)
+	}
+	_, err := svc.Graph(ctx, "u1", GraphQuery{RootID: "missing"})
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
@@ -0,0 +1,310 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+const (
+	DefaultUnlinkedMentionsLimit = 20
+	MaxUnlinkedMentionsLimit     = 50
+	DefaultBrokenLinksLimit      = 50
+	MaxBrokenLinksLimit          = 200
+
+	// Candidates come from a substring prefilter, so more rows are read than
+	// returned to make up for hits that sit in code or inside other words.
+	mentionCandidateFactor = 4
+	maxMentionSnippets     = 3
+	mentionSnippetRunes    = 40
+	minMentionTermRunes    = 2
+)
+
+// Markdown that already links somewhere or is not prose: wikilinks, inline
+// links and images, autolinks and bare URLs. Mentions inside are ignored.
+var mentionMaskRegex = regexp.MustCompile(
+	"`+[^`\n]*`+" + `|\[\[[^\]\n]*\]\]|!?\[[^\]\n]*\]\([^)\n]*\)|<[^>\s]+>|https?://\S+`,
+)
+
+// UnlinkedMention is a document that names another document in prose
+// without linking to it.
+type UnlinkedMention struct {
+	ID       string
+	Title    string
+	Mtime    int64
+	Count    int
+	Snippets []string
+}
+
+// MentionConversion reports a convert-to-link save. Result is nil when the
+// source no longer holds an unlinked mention and nothing was saved.
+type MentionConversion struct {
+	Converted int
+	Result    *model.SaveDocumentResult
+}
+
+type mentionSpan struct {
+	Start int
+	End   int
+}
+
+// mentionTerms returns the names a document is mentioned by: its title and
+// aliases, each once regardless of case.
+func mentionTerms(doc *model.Document, aliases []string) []string {
+	terms := make([]string, 0, 1+len(aliases))
+	seen := make(map[string]struct{}, 1+len(aliases))
+	for _, name := range append([]string{doc.Title}, aliases...) {
+		name = strings.TrimSpace(name)
+		key := wikilinkTitleKey(name)
+		if _, dup := seen[key]; dup || utf8.RuneCountInString(name) < minMentionTermRunes {
+			continue
+		}
+		seen[key] = struct{}{}
+		terms = append(terms, name)
+	}
+	return terms
+}
+
+// targetMentionTerms loads docID and the names it is mentioned by.
+func (s *DocumentService) targetMentionTerms(
+	ctx context.Context, userID, docID string,
+) (*model.Document, []string, error) {
+	target, err := s.docs.GetByID(ctx, userID, docID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get document: %w", err)
+	}
+	props, err := s.docs.ListProperties(ctx, userID, docID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("list properties: %w", err)
+	}
+	return target, mentionTerms(target, propertyAliases(props)), nil
+}
+
+func mentionRegex(terms []string) *regexp.Regexp {
+	sorted := append([]string(nil), terms...)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	quoted := make([]string, 0, len(sorted))
+	for _, term := range sorted {
+		quoted = append(quoted, regexp.QuoteMeta(term))
+	}
+	return regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+}
+
+// findMentions returns the byte spans of content where one of terms appears
+// in prose: outside code, outside existing links and not inside a longer
+// word. CJK text has no word separators, so a CJK neighbour never needs one.
+func findMentions(content string, terms []string) []mentionSpan {
+	spans := make([]mentionSpan, 0)
+	if len(terms) == 0 {
+		return spans
+	}
+	re := mentionRegex(terms)
+	for _, line := range markdownProseLines(content) {
+		masks := mentionMaskRegex.FindAllStringIndex(line.Text, -1)
+		for _, m := range re.FindAllStringIndex(line.Text, -1) {
+			if overlapsAny(m, masks) || !mentionBoundary(line.Text, m[0], m[1]) {
+				continue
+			}
+			spans = append(spans, mentionSpan{Start: line.Offset + m[0], End: line.Offset + m[1]})
+		}
+	}
+	return spans
+}
+
+func overlapsAny(span []int, masks [][]int) bool {
+	for _, mask := range masks {
+		if span[0] < mask[1] && mask[0] < span[1] {
+			return true
+		}
+	}
+	return false
+}
+
+func mentionBoundary(text string, start, end int) bool {
+	if start > 0 {
+		prev, _ := utf8.DecodeLastRuneInString(text[:start])
+		first, _ := utf8.DecodeRuneInString(text[start:])
+		if separatedWordRune(prev) && separatedWordRune(first) {
+			return false
+		}
+	}
+	if end < len(text) {
+		next, _ := utf8.DecodeRuneInString(text[end:])
+		last, _ := utf8.DecodeLastRuneInString(text[:end])
+		if separatedWordRune(next) && separatedWordRune(last) {
+			return false
+		}
+	}
+	return true
+}
+
+// separatedWordRune reports a rune that belongs to a space-separated word.
+func separatedWordRune(r rune) bool {
+	return isWordRune(r) && !isCJKRune(r)
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func isCJKRune(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// mentionSnippet returns the line around span, cut to a few words each side.
+func mentionSnippet(content string, span mentionSpan) string {
+	lineStart := strings.LastIndexByte(content[:span.Start], '\n') + 1
+	lineEnd := len(content)
+	if i := strings.IndexByte(content[span.End:], '\n'); i >= 0 {
+		lineEnd = span.End + i
+	}
+	before := []rune(content[lineStart:span.Start])
+	after := []rune(content[span.End:lineEnd])
+	prefix, suffix := "", ""
+	if len(before) > mentionSnippetRunes {
+		before, prefix = before[len(before)-mentionSnippetRunes:], "…"
+	}
+	if len(after) > mentionSnippetRunes {
+		after, suffix = after[:mentionSnippetRunes], "…"
+	}
+	return strings.TrimSpace(prefix + string(before) + content[span.Start:span.End] + string(after) + suffix)
+}
+
+// UnlinkedMentions lists documents that mention docID by title or alias in
+// prose but do not link to it.
+func (s *DocumentService) UnlinkedMentions(
+	ctx context.Context, userID, docID string, limit int,
+) ([]UnlinkedMention, error) {
+	if limit <= 0 || limit > MaxUnlinkedMentionsLimit {
+		limit = DefaultUnlinkedMentionsLimit
+	}
+	_, terms, err := s.targetMentionTerms(ctx, userID, docID)
+	if err != nil {
+		return nil, err
+	}
+	items := make([]UnlinkedMention, 0)
+	if len(terms) == 0 {
+		return items, nil
+	}
+	candidates, err := s.docs.ListMentionCandidates(ctx, userID, docID, terms, uint(limit*mentionCandidateFactor))
+	if err != nil {
+		return nil, fmt.Errorf("list mention candidates: %w", err)
+	}
+	for _, candidate := range candidates {
+		spans := findMentions(candidate.Content, terms)
+		if len(spans) == 0 {
+			continue
+		}
+		item := UnlinkedMention{
+			ID: candidate.ID, Title: candidate.Title, Mtime: candidate.Mtime,
+			Count: len(spans), Snippets: make([]string, 0, maxMentionSnippets),
+		}
+		for _, span := range spans {
+			if len(item.Snippets) == maxMentionSnippets {
+				break
+			}
+			item.Snippets = append(item.Snippets, mentionSnippet(candidate.Content, span))
+		}
+		items = append(items, item)
+		if len(items) == limit {
+			break
+		}
+	}
+	return items, nil
+}
+
+// ConvertMentions turns every unlinked mention of docID in sourceID into a
+// link and saves sourceID through Save. A zero baseRevision uses the revision
+// that was read; either way a concurrent edit makes the save a conflict.
+func (s *DocumentService) ConvertMentions(
+	ctx context.Context, userID, docID, sourceID string, baseRevision int64,
+) (*MentionConversion, error) {
+	if sourceID == "" || sourceID == docID || baseRevision < 0 {
+		return nil, appErr.ErrInvalid
+	}
+	target, terms, err := s.targetMentionTerms(ctx, userID, docID)
+	if err != nil {
+		return nil, err
+	}
+	source, err := s.docs.GetByID(ctx, userID, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("get source document: %w", err)
+	}
+	spans := findMentions(source.Content, terms)
+	if len(spans) == 0 {
+		return &MentionConversion{}, nil
+	}
+	byWikilink, err := s.linksByWikilink(ctx, userID, target)
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		b.WriteString(source.Content[last:span.Start])
+		b.WriteString(mentionLink(target, source.Content[span.Start:span.End], byWikilink))
+		last = span.End
+	}
+	b.WriteString(source.Content[last:])
+	if baseRevision == 0 {
+		baseRevision = source.ContentRevision
+	}
+	result, err := s.Save(ctx, userID, sourceID, DocumentUpdateInput{
+		Title: source.Title, Content: b.String(), BaseRevision: baseRevision,
+	})
+	if err != nil {
+		return nil, err
+	}
+	conversion := &MentionConversion{Result: result}
+	if result.Accepted {
+		conversion.Converted = len(spans)
+	}
+	return conversion, nil
+}
+
+// linksByWikilink reports whether [[Title]] would resolve to target: the
+// title must be expressible as a wikilink and no other document may share it.
+func (s *DocumentService) linksByWikilink(ctx context.Context, userID string, target *model.Document) (bool, error) {
+	title := strings.TrimSpace(target.Title)
+	if strings.ContainsAny(title, "[]|#\n") {
+		return false, nil
+	}
+	key := wikilinkTitleKey(title)
+	matches, err := s.docs.ListByTitleKeys(ctx, userID, []string{key})
+	if err != nil {
+		return false, fmt.Errorf("check title: %w", err)
+	}
+	candidates := wikilinkCandidates(matches)[key]
+	return len(candidates) == 1 && candidates[0].ID == target.ID, nil
+}
+
+func mentionLink(target *model.Document, text string, byWikilink bool) string {
+	title := strings.TrimSpace(target.Title)
+	switch {
+	case byWikilink && text == title:
+		return "[[" + title + "]]"
+	case byWikilink:
+		return "[[" + title + "|" + text + "]]"
+	default:
+		return "[" + strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`).Replace(text) + "](/docs/" + target.ID + ")"
+	}
+}
+
+// ListBrokenLinks lists the references of the user's documents that lead
+// nowhere: /docs/<id> links to deleted, missing or foreign documents and
+// wikilinks whose title matches no document.
+func (s *DocumentService) ListBrokenLinks(ctx context.Context, userID string, page Page) ([]model.BrokenLink, error) {
+	page = page.Clamp(DefaultBrokenLinksLimit, MaxBrokenLinksLimit)
+	items, err := s.docs.ListBrokenLinks(ctx, userID, page.Limit, page.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("list broken links: %w", err)
+	}
+	return items, nil
+}
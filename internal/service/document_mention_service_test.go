@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+func mentionTexts(content string, spans []mentionSpan) []string {
+	texts := make([]string, 0, len(spans))
+	for _, span := range spans {
+		texts = append(texts, content[span.Start:span.End])
+	}
+	return texts
+}
+
+func TestFindMentions(t *testing.T) {
+	content := "Read the go guide and the GO GUIDE.\n" +
+		"Not a golang guide or go guides, nor `go guide` in code.\n" +
+		"Already [go guide](/docs/d1), [[Go Guide]] or https://x.io/go-guide.\n" +
+		"```\ngo guide\n```\n" +
+		"中文里提到go guide也算。"
+	spans := findMentions(content, []string{"Go Guide"})
+	assert.Equal(t, []string{"go guide", "GO GUIDE", "go guide"}, mentionTexts(content, spans))
+}
+
+func TestFindMentions_CJKTitle(t *testing.T) {
+	content := "今天整理了知识图谱的笔记，知识图谱很有用。"
+	assert.Len(t, findMentions(content, []string{"知识图谱"}), 2)
+}
+
+func TestMentionSnippet(t *testing.T) {
+	content := "first line\n" + "a long lead in sentence that goes on and on before the Topic appears here\nlast"
+	spans := findMentions(content, []string{"topic"})
+	require.Len(t, spans, 1)
+	assert.Equal(t, "…sentence that goes on and on before the Topic appears here", mentionSnippet(content, spans[0]))
+}
+
+func TestDocumentService_UnlinkedMentions(t *testing.T) {
+	docs := &mockDocumentRepo{
+		getByIDFn: func(_ context.Context, _, docID string) (*model.Document, error) {
+			return &model.Document{ID: docID, Title: "Go Guide"}, nil
+		},
+		listMentionsFn: func(_ context.Context, _, targetID string, terms []string, limit uint) ([]model.Document, error) {
+			assert.Equal(t, "d1", targetID)
+			assert.Equal(t, []string{"Go Guide"}, terms)
+			assert.Equal(t, uint(4), limit)
+			return []model.Document{
+				{ID: "d2", Title: "Code only", Content: "`go guide`"},
+				{ID: "d3", Title: "Notes", Content: "see the go guide, then the Go Guide", Mtime: 7},
+			}, nil
+		},
+	}
+	items, err := newDocSvc(docs, nil, nil, nil).UnlinkedMentions(context.Background(), "u1", "d1", 1)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "d3", items[0].ID)
+	assert.Equal(t, 2, items[0].Count)
+	assert.Len(t, items[0].Snippets, 2)
+}
+
+func TestDocumentService_UnlinkedMentions_ShortTitle(t *testing.T) {
+	docs := &mockDocumentRepo{
+		getByIDFn: func(context.Context, string, string) (*model.Document, error) {
+			return &model.Document{ID: "d1", Title: "A"}, nil
+		},
+	}
+	items, err := newDocSvc(docs, nil, nil, nil).UnlinkedMentions(context.Background(), "u1", "d1", 10)
+	require.NoError(t, err)
+	assert.Empty(t, items)
+}
+
+func newMentionDocs(target, source model.Document, titles []model.DocumentTitle) (*mockDocumentRepo, *string) {
+	saved := new(string)
+	docs := &mockDocumentRepo{
+		getByIDFn: func(_ context.Context, _, docID string) (*model.Document, error) {
+			switch docID {
+			case target.ID:
+				return &target, nil
+			case source.ID:
+				return &source, nil
+			}
+			return nil, appErr.ErrNotFound
+		},
+		getByIDForUpdateFn: func(context.Context, string, string) (*model.Document, error) {
+			return &source, nil
+		},
+		updateFn: func(_ context.Context, doc *model.Document) error {
+			*saved = doc.Content
+			return nil
+		},
+		updateLinksFn: func(context.Context, string, string, []model.LinkTarget, int64) error { return nil },
+		listByTitleKeysFn: func(context.Context, string, []string) ([]model.DocumentTitle, error) {
+			return titles, nil
+		},
+	}
+	return docs, saved
+}
+
+func TestDocumentService_ConvertMentions(t *testing.T) {
+	target := model.Document{ID: "d1", Title: "Go Guide"}
+	source := model.Document{ID: "d2", Title: "Notes", Content: "the Go Guide and the go guide", ContentRevision: 3}
+	docs, saved := newMentionDocs(target, source, []model.DocumentTitle{{ID: "d1", Title: "Go Guide", Key: "go guide"}})
+	svc := newDocSvc(docs, newWikilinkVersions(), nil, nil)
+
+	conversion, err := svc.ConvertMentions(context.Background(), "u1", "d1", "d2", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 2, conversion.Converted)
+	assert.True(t, conversion.Result.Accepted)
+	assert.Equal(t, int64(4), conversion.Result.ContentRevision)
+	assert.Equal(t, "the [[Go Guide]] and the [[Go Guide|go guide]]", *saved)
+}
+
+func TestDocumentService_ConvertMentions_AmbiguousTitleUsesPathLink(t *testing.T) {
+	target := model.Document{ID: "d1", Title: "Go Guide"}
+	source := model.Document{ID: "d2", Title: "Notes", Content: "the go guide", ContentRevision: 3}
+	docs, saved := newMentionDocs(target, source, []model.DocumentTitle{{ID: "d1", Key: "go guide"}, {ID: "d9", Key: "go guide"}})
+	svc := newDocSvc(docs, newWikilinkVersions(), nil, nil)
+
+	_, err := svc.ConvertMentions(context.Background(), "u1", "d1", "d2", 3)
+	require.NoError(t, err)
+	assert.Equal(t, "the [go guide](/docs/d1)", *saved)
+}
+
+func TestDocumentService_ConvertMentions_StaleRevision(t *testing.T) {
+	target := model.Document{ID: "d1", Title: "Go Guide"}
+	source := model.Document{ID: "d2", Title: "Notes", Content: "the go guide", ContentRevision: 5}
+	docs, saved := newMentionDocs(target, source, nil)
+	svc := newDocSvc(docs, newWikilinkVersions(), nil, nil)
+
+	conversion, err := svc.ConvertMentions(context.Background(), "u1", "d1", "d2", 4)
+	require.NoError(t, err)
+	assert.Zero(t, conversion.Converted)
+	assert.Equal(t, model.SaveRejectReasonRevisionConflict, conversion.Result.Reason)
+	assert.Empty(t, *saved)
+}
+
+func TestDocumentService_ConvertMentions_NothingToConvert(t *testing.T) {
+	target := model.Document{ID: "d1", Title: "Go Guide"}
+	source := model.Document{ID: "d2", Title: "Notes", Content: "nothing here"}
+	docs, _ := newMentionDocs(target, source, nil)
+
+	conversion, err := newDocSvc(docs, nil, nil, nil).ConvertMentions(context.Background(), "u1", "d1", "d2", 0)
+	require.NoError(t, err)
+	assert.Equal(t, &MentionConversion{}, conversion)
+}
+
+func TestDocumentService_ConvertMentions_Invalid(t *testing.T) {
+	svc := newDocSvc(&mockDocumentRepo{}, nil, nil, nil)
+	_, err := svc.ConvertMentions(context.Background(), "u1", "d1", "d1", 0)
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+}
+
+func TestDocumentService_Save_RecordsBrokenLinks(t *testing.T) {
+	docs := newWikilinkDocs(map[string]model.Document{"d1": {Title: "Notes"}}, nil)
+	docs.updateLinksFn = func(context.Context, string, string, []model.LinkTarget, int64) error { return nil }
+	docs.listByIDsFn = func(_ context.Context, _ string, ids []string) ([]model.Document, error) {
+		assert.Equal(t, []string{"d2", "gone"}, ids)
+		return []model.Document{{ID: "d2"}}, nil
+	}
+	var broken []string
+	docs.replaceBrokenFn = func(_ context.Context, _, sourceID string, ids []string, _ int64) error {
+		assert.Equal(t, "d1", sourceID)
+		broken = ids
+		return nil
+	}
+	svc := newDocSvc(docs, newWikilinkVersions(), nil, nil)
+
+	_, err := svc.Save(context.Background(), "u1", "d1", DocumentUpdateInput{
+		Title: "Notes", Content: "/docs/d2 /docs/gone /docs/d1 /docs/gone",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gone"}, broken)
+}
+
+func TestDocumentService_ListBrokenLinks(t *testing.T) {
+	docs := &mockDocumentRepo{
+		listBrokenFn: func(_ context.Context, _ string, limit, offset int) ([]model.BrokenLink, error) {
+			assert.Equal(t, MaxBrokenLinksLimit, limit)
+			assert.Equal(t, 10, offset)
+			return []model.BrokenLink{{SourceID: "d1", Kind: model.BrokenLinkKindDocument, TargetID: "x"}}, nil
+		},
+	}
+	items, err := newDocSvc(docs, nil, nil, nil).ListBrokenLinks(context.Background(), "u1", Page{Limit: 999, Offset: 10})
+	require.NoError(t, err)
+	assert.Len(t, items, 1)
+}
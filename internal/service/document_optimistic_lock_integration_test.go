@@ -142,6 +142,15 @@ func (failingVersionRepo) ListByUser(
 	return nil, errors.New("not implemented")
 }
 
+func (failingVersionRepo) ListActivity(
+	context.Context,
+	string,
+	int64,
+	int64,
+) ([]model.VersionActivity, error) {
+	return nil, errors.New("not implemented")
+}
+
 func (failingVersionRepo) DeleteOldVersions(context.Context, string, string, int) error {
 	return nil
 }
@@ -33,7 +33,7 @@ func TestDocumentServiceSaveRejectsRevisionConflictWithoutWrites(t *testing.T) {
 			documentUpdated = true
 			return nil
 		},
-		updateLinksFn: func(context.Context, string, string, []string, int64) error {
+		updateLinksFn: func(context.Context, string, string, []model.LinkTarget, int64) error {
 			linksUpdated = true
 			return nil
 		},
@@ -100,7 +100,7 @@ func TestDocumentServiceSaveMatchingRevisionUsesServerNextRevision(t *testing.T)
 			saved = doc
 			return nil
 		},
-		updateLinksFn: func(context.Context, string, string, []string, int64) error {
+		updateLinksFn: func(context.Context, string, string, []model.LinkTarget, int64) error {
 			return nil
 		},
 	}
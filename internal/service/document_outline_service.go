@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+const maxSectionQueryRunes = 200
+
+var (
+	// An ATX heading: up to three spaces, one to six #, then the text and an
+	// optional closing run of # separated by a space.
+	atxHeadingRegex      = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	markdownLinkText     = regexp.MustCompile(`!?\[([^\]\n]*)\]\([^)\n]*\)`)
+	markdownWikilinkText = regexp.MustCompile(`\[\[(?:[^\]|\n]*\|)?([^\]\n]*)\]\]`)
+)
+
+// OutlineSection is a heading of a document and the text under it, which
+// runs to the next heading of the same or a higher level. Line and EndLine
+// are 1-based and inclusive; WordCount covers the body of the section with
+// its subsections and without fenced code.
+type OutlineSection struct {
+	Level     int
+	Title     string
+	Slug      string
+	Line      int
+	EndLine   int
+	WordCount int
+	Children  []OutlineSection
+
+	base  string
+	start int
+	end   int
+}
+
+// headingSlug turns heading text into an anchor the way GitHub does: lower
+// case, spaces to hyphens, and punctuation other than - and _ dropped. Link
+// markup keeps only its text.
+func headingSlug(text string) string {
+	text = markdownWikilinkText.ReplaceAllString(text, "$1")
+	text = markdownLinkText.ReplaceAllString(text, "$1")
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(text)) {
+		switch {
+		case r == ' ' || r == '-':
+			b.WriteByte('-')
+		case isWordRune(r):
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// documentSections returns the headings of content in document order, each
+// with its extent. Headings inside fenced code and front matter are ignored;
+// repeated slugs get -1, -2... suffixes so every slug is unique.
+func documentSections(content string) []OutlineSection {
+	lines := markdownProseLines(content)
+	sections := make([]OutlineSection, 0)
+	positions := make([]int, 0)
+	used := make(map[string]int)
+	for i, line := range lines {
+		m := atxHeadingRegex.FindStringSubmatch(line.Text)
+		if m == nil || strings.TrimSpace(m[2]) == "" {
+			continue
+		}
+		title := strings.TrimSpace(m[2])
+		base := headingSlug(title)
+		if base == "" {
+			base = "section"
+		}
+		slug := base
+		if n := used[base]; n > 0 {
+			slug = base + "-" + strconv.Itoa(n)
+		}
+		used[base]++
+		sections = append(sections, OutlineSection{
+			Level: len(m[1]), Title: title, Slug: slug, Line: line.Line, base: base, start: line.Offset,
+		})
+		positions = append(positions, i)
+	}
+	lastLine := strings.Count(strings.TrimSuffix(content, "\n"), "\n") + 1
+	for i := range sections {
+		sections[i].end, sections[i].EndLine = len(content), lastLine
+		for j := i + 1; j < len(sections); j++ {
+			if sections[j].Level <= sections[i].Level {
+				sections[i].end, sections[i].EndLine = sections[j].start, sections[j].Line-1
+				break
+			}
+		}
+		for k := positions[i] + 1; k < len(lines) && lines[k].Offset < sections[i].end; k++ {
+			sections[i].WordCount += countWords(proseText(lines[k].Text))
+		}
+	}
+	return sections
+}
+
+// nestSections arranges flat sections into a tree. A heading that skips
+// levels nests under the closest shallower heading before it.
+func nestSections(flat []OutlineSection) []OutlineSection {
+	var build func(i, level int) ([]OutlineSection, int)
+	build = func(i, level int) ([]OutlineSection, int) {
+		nodes := make([]OutlineSection, 0)
+		for i < len(flat) && flat[i].Level > level {
+			node := flat[i]
+			node.Children, i = build(i+1, node.Level)
+			nodes = append(nodes, node)
+		}
+		return nodes, i
+	}
+	tree, _ := build(0, 0)
+	return tree
+}
+
+// findSection returns the section named by slug. A value that is not one of
+// the slugs is read as heading text, such as the anchor of [[Title#Heading]],
+// and picks the first heading it slugs to.
+func findSection(sections []OutlineSection, slug string) (OutlineSection, bool) {
+	for _, section := range sections {
+		if section.Slug == slug {
+			return section, true
+		}
+	}
+	base := headingSlug(slug)
+	for _, section := range sections {
+		if section.base == base {
+			return section, true
+		}
+	}
+	return OutlineSection{}, false
+}
+
+// Outline returns the heading tree of a document the user can read.
+func (s *DocumentService) Outline(ctx context.Context, userID, docID string) ([]OutlineSection, error) {
+	doc, _, err := s.readableDocument(ctx, userID, docID)
+	if err != nil {
+		return nil, err
+	}
+	return nestSections(documentSections(doc.Content)), nil
+}
+
+// GetSection returns a readable document with its content cut down to one
+// section, heading line included. An unknown section is not found.
+func (s *DocumentService) GetSection(
+	ctx context.Context, userID, docID, section string,
+) (*model.Document, *OutlineSection, error) {
+	doc, _, err := s.readableDocument(ctx, userID, docID)
+	if err != nil {
+		return nil, nil, err
+	}
+	found, err := cutSection(doc, section)
+	if err != nil {
+		return nil, nil, err
+	}
+	return doc, found, nil
+}
+
+// GetShareSection is GetShareByToken limited to one section of the shared
+// document, so a share link can point at a heading.
+func (s *DocumentService) GetShareSection(
+	ctx context.Context, token, sharePassword, section string,
+) (*PublicShareDetail, *OutlineSection, error) {
+	detail, err := s.GetShareByToken(ctx, token, sharePassword)
+	if err != nil {
+		return nil, nil, err
+	}
+	found, err := cutSection(detail.Document, section)
+	if err != nil {
+		return nil, nil, err
+	}
+	return detail, found, nil
+}
+
+func cutSection(doc *model.Document, section string) (*OutlineSection, error) {
+	section = strings.TrimSpace(section)
+	if section == "" || utf8.RuneCountInString(section) > maxSectionQueryRunes {
+		return nil, appErr.ErrInvalid
+	}
+	found, ok := findSection(documentSections(doc.Content), section)
+	if !ok {
+		return nil, appErr.ErrNotFound
+	}
+	doc.Content = strings.TrimRight(doc.Content[found.start:found.end], "\n")
+	return &found, nil
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+const outlineContent = "---\ntitle: x\n---\n" +
+	"# Intro\n" +
+	"Hello world.\n" +
+	"## Setup `go` [docs](https://go.dev) ##\n" +
+	"Install 工具 first.\n" +
+	"```\n# not a heading\n```\n" +
+	"#### Deep\n" +
+	"deep text\n" +
+	"# Intro\n" +
+	"again\n" +
+	"#hashtag line\n"
+
+func TestDocumentSections(t *testing.T) {
+	sections := documentSections(outlineContent)
+	require.Len(t, sections, 4)
+	assert.Equal(t, "Intro", sections[0].Title)
+	assert.Equal(t, "intro", sections[0].Slug)
+	assert.Equal(t, 4, sections[0].Line)
+	assert.Equal(t, 12, sections[0].EndLine)
+	assert.Equal(t, "setup-go-docs", sections[1].Slug)
+	assert.Equal(t, 2, sections[1].Level)
+	assert.Equal(t, 7, sections[1].WordCount, "subsections counted, code excluded")
+	assert.Equal(t, "deep", sections[2].Slug)
+	assert.Equal(t, "intro-1", sections[3].Slug)
+	assert.Equal(t, 15, sections[3].EndLine)
+	assert.Equal(t, 3, sections[3].WordCount)
+}
+
+func TestNestSections(t *testing.T) {
+	tree := nestSections(documentSections(outlineContent))
+	require.Len(t, tree, 2)
+	require.Len(t, tree[0].Children, 1)
+	require.Len(t, tree[0].Children[0].Children, 1)
+	assert.Equal(t, "Deep", tree[0].Children[0].Children[0].Title)
+	assert.Empty(t, tree[1].Children)
+}
+
+func TestCountWords(t *testing.T) {
+	assert.Equal(t, 0, countWords(""))
+	assert.Equal(t, 3, countWords("don't stop-now"))
+	assert.Equal(t, 6, countWords("用 Go 写笔记 v2"))
+}
+
+func newOutlineDocs(content string) *mockDocumentRepo {
+	return &mockDocumentRepo{
+		getByIDFn: func(_ context.Context, _, docID string) (*model.Document, error) {
+			return &model.Document{ID: docID, Content: content}, nil
+		},
+	}
+}
+
+func TestDocumentService_GetSection(t *testing.T) {
+	svc := newDocSvc(newOutlineDocs(outlineContent), nil, nil, nil)
+
+	doc, section, err := svc.GetSection(context.Background(), "u1", "d1", "setup-go-docs")
+	require.NoError(t, err)
+	assert.Equal(t, "Setup `go` [docs](https://go.dev)", section.Title)
+	assert.Equal(t, "## Setup `go` [docs](https://go.dev) ##\nInstall 工具 first.\n```\n# not a heading\n```\n"+
+		"#### Deep\ndeep text", doc.Content)
+
+	doc, _, err = svc.GetSection(context.Background(), "u1", "d1", "Deep")
+	require.NoError(t, err, "heading text as written in a wikilink anchor")
+	assert.Equal(t, "#### Deep\ndeep text", doc.Content)
+
+	_, _, err = svc.GetSection(context.Background(), "u1", "d1", "missing")
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+	_, _, err = svc.GetSection(context.Background(), "u1", "d1", " ")
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+}
+
+func TestDocumentService_Outline(t *testing.T) {
+	tree, err := newDocSvc(newOutlineDocs("plain text"), nil, nil, nil).Outline(context.Background(), "u1", "d1")
+	require.NoError(t, err)
+	assert.Empty(t, tree)
+}
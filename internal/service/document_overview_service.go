@@ -3,16 +3,22 @@ package service
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/xxxsen/mnote/internal/model"
 	"github.com/xxxsen/mnote/internal/pkg/safeconv"
+	"github.com/xxxsen/mnote/internal/pkg/timeutil"
 )
 
+// overviewActivityWindow is how far back Overview counts edited documents.
+const overviewActivityWindow = 7 * 24 * time.Hour
+
 type DocumentOverview struct {
 	Recent       []model.Document
 	TagCounts    map[string]int
 	Total        int
 	StarredTotal int
+	Stats        model.DocumentStatsTotals
 }
 
 func (s *DocumentService) Overview(
@@ -41,7 +47,12 @@ func (s *DocumentService) Overview(
 	if err != nil {
 		return nil, fmt.Errorf("count starred: %w", err)
 	}
+	since := timeutil.NowUnix() - int64(overviewActivityWindow/time.Second)
+	stats, err := s.docs.SumStats(ctx, userID, since)
+	if err != nil {
+		return nil, fmt.Errorf("sum stats: %w", err)
+	}
 	return &DocumentOverview{
-		Recent: recent, TagCounts: counts, Total: count, StarredTotal: starredCount,
+		Recent: recent, TagCounts: counts, Total: count, StarredTotal: starredCount, Stats: *stats,
 	}, nil
 }
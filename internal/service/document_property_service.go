@@ -0,0 +1,305 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+	"github.com/xxxsen/mnote/internal/repo"
+)
+
+const (
+	maxDocumentProperties = 64
+	maxPropertyListItems  = 64
+	maxPropertyKeyRunes   = 64
+	maxPropertyValueRunes = 500
+	MaxPropertyFilters    = 10
+)
+
+// frontMatter returns the YAML between a leading "---" line and the next
+// "---" or "..." line, and the byte length of the block including both
+// delimiters. ok is false when content does not open with front matter.
+func frontMatter(content string) (body string, size int, ok bool) {
+	rest := strings.TrimPrefix(content, "\ufeff")
+	bom := len(content) - len(rest)
+	first, rest, found := strings.Cut(rest, "\n")
+	if !found || strings.TrimRight(first, " \t\r") != "---" {
+		return "", 0, false
+	}
+	offset := bom + len(first) + 1
+	for len(rest) > 0 {
+		line, next, more := strings.Cut(rest, "\n")
+		if trimmed := strings.TrimRight(line, " \t\r"); trimmed == "---" || trimmed == "..." {
+			end := offset + len(line)
+			if more {
+				end++
+			}
+			return content[bom+len(first)+1 : offset], end, true
+		}
+		offset += len(line) + 1
+		if !more {
+			break
+		}
+		rest = next
+	}
+	return "", 0, false
+}
+
+// parseProperties reads the front matter of content into properties. Keys
+// are case-folded and alias is folded into aliases; nested mappings, nulls
+// and values too long to be useful are skipped. Front matter that is not a
+// YAML mapping yields no properties rather than failing the save.
+func parseProperties(content string) []model.DocumentProperty {
+	props := make([]model.DocumentProperty, 0)
+	body, _, ok := frontMatter(content)
+	if !ok {
+		return props
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(body), &doc); err != nil || len(doc.Content) == 0 {
+		return props
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return props
+	}
+	seen := make(map[string]struct{})
+	for i := 0; i+1 < len(root.Content) && len(props) < maxDocumentProperties; i += 2 {
+		key := propertyKey(root.Content[i].Value)
+		if key == "" {
+			continue
+		}
+		if _, dup := seen[key]; dup {
+			continue
+		}
+		prop, ok := parseProperty(key, root.Content[i+1])
+		if !ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		props = append(props, prop)
+	}
+	return props
+}
+
+func propertyKey(raw string) string {
+	key := strings.ToLower(strings.TrimSpace(raw))
+	if key == "alias" {
+		key = model.PropertyKeyAliases
+	}
+	if utf8.RuneCountInString(key) > maxPropertyKeyRunes {
+		return ""
+	}
+	return key
+}
+
+// parseProperty converts one front matter value. Aliases are always a list
+// so that a single alias written as a plain string is found the same way.
+func parseProperty(key string, node *yaml.Node) (model.DocumentProperty, bool) {
+	prop := model.DocumentProperty{Key: key}
+	switch node.Kind {
+	case yaml.SequenceNode:
+		prop.Type = model.PropertyTypeList
+		for _, item := range node.Content {
+			if len(prop.Values) == maxPropertyListItems {
+				break
+			}
+			if _, value, ok := propertyScalar(item); ok {
+				prop.Values = append(prop.Values, value)
+			}
+		}
+		return prop, len(prop.Values) > 0
+	case yaml.ScalarNode:
+		valueType, value, ok := propertyScalar(node)
+		if !ok {
+			return prop, false
+		}
+		if key == model.PropertyKeyAliases {
+			prop.Type, prop.Values = model.PropertyTypeList, []string{value}
+			return prop, true
+		}
+		prop.Type, prop.Value = valueType, value
+		return prop, true
+	default:
+		return prop, false
+	}
+}
+
+// propertyScalar returns the type and canonical text of a scalar: numbers
+// without redundant digits, booleans as true/false and timestamps as a date,
+// or as RFC 3339 when they carry a time of day.
+func propertyScalar(node *yaml.Node) (string, string, bool) {
+	if node.Kind != yaml.ScalarNode {
+		return "", "", false
+	}
+	var value any
+	if err := node.Decode(&value); err != nil {
+		return "", "", false
+	}
+	valueType, text := model.PropertyTypeString, ""
+	switch v := value.(type) {
+	case nil:
+		return "", "", false
+	case string:
+		text = strings.TrimSpace(v)
+	case bool:
+		valueType, text = model.PropertyTypeBool, strconv.FormatBool(v)
+	case int:
+		valueType, text = model.PropertyTypeNumber, strconv.Itoa(v)
+	case int64:
+		valueType, text = model.PropertyTypeNumber, strconv.FormatInt(v, 10)
+	case uint64:
+		valueType, text = model.PropertyTypeNumber, strconv.FormatUint(v, 10)
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return "", "", false
+		}
+		valueType, text = model.PropertyTypeNumber, strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		valueType, text = model.PropertyTypeDate, v.UTC().Format(time.RFC3339)
+		if v.UTC().Equal(v.UTC().Truncate(24 * time.Hour)) {
+			text = v.UTC().Format(time.DateOnly)
+		}
+	default:
+		text = strings.TrimSpace(node.Value)
+	}
+	if text == "" || utf8.RuneCountInString(text) > maxPropertyValueRunes {
+		return "", "", false
+	}
+	return valueType, text, true
+}
+
+// propertyAliases returns the aliases among props.
+func propertyAliases(props []model.DocumentProperty) []string {
+	for _, prop := range props {
+		if prop.Key == model.PropertyKeyAliases {
+			return prop.Values
+		}
+	}
+	return nil
+}
+
+// syncProperties rewrites the properties of docID from the front matter of
+// content. When the aliases changed it returns the old and new ones, the
+// names whose wikilinks must be re-resolved; otherwise it returns nil.
+func (s *DocumentService) syncProperties(
+	ctx context.Context, userID, docID, content string, now int64,
+) ([]string, error) {
+	previous, err := s.docs.ListProperties(ctx, userID, docID)
+	if err != nil {
+		return nil, fmt.Errorf("list properties: %w", err)
+	}
+	props := parseProperties(content)
+	if err := s.docs.ReplaceProperties(ctx, userID, docID, props, now); err != nil {
+		return nil, fmt.Errorf("replace properties: %w", err)
+	}
+	oldAliases, newAliases := propertyAliases(previous), propertyAliases(props)
+	if sameTitleKeys(oldAliases, newAliases) {
+		return nil, nil
+	}
+	return append(append([]string{}, oldAliases...), newAliases...), nil
+}
+
+// backfillProperties stores the front matter properties of a document saved
+// before properties were recorded and re-resolves the wikilinks its aliases
+// now match. Documents that already have properties, or have none to store,
+// are left alone. It reports whether properties were written.
+func (s *DocumentService) backfillProperties(ctx context.Context, doc *model.Document) (bool, error) {
+	if len(parseProperties(doc.Content)) == 0 {
+		return false, nil
+	}
+	written := false
+	err := s.runInTx(ctx, func(txCtx context.Context) error {
+		current := doc
+		if doc.State == repo.DocumentStateNormal {
+			if aliases := propertyAliases(parseProperties(doc.Content)); len(aliases) > 0 {
+				if err := s.lockWikilinkSet(txCtx, doc.UserID, doc.ID, aliases); err != nil {
+					return err
+				}
+			}
+			locked, err := s.docs.GetByIDForUpdate(txCtx, doc.UserID, doc.ID)
+			if errors.Is(err, appErr.ErrNotFound) {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("lock document: %w", err)
+			}
+			current = locked
+		}
+		previous, err := s.docs.ListProperties(txCtx, doc.UserID, doc.ID)
+		if err != nil {
+			return fmt.Errorf("list properties: %w", err)
+		}
+		props := parseProperties(current.Content)
+		if len(previous) > 0 || len(props) == 0 {
+			return nil
+		}
+		now := s.runtime.Clock.Now().Unix()
+		if err := s.docs.ReplaceProperties(txCtx, doc.UserID, doc.ID, props, now); err != nil {
+			return fmt.Errorf("replace properties: %w", err)
+		}
+		written = true
+		if aliases := propertyAliases(props); len(aliases) > 0 && current.State == repo.DocumentStateNormal {
+			return s.reresolveWikilinks(txCtx, doc.UserID, doc.ID, aliases, now)
+		}
+		return nil
+	})
+	return written, err
+}
+
+func sameTitleKeys(a, b []string) bool {
+	fold := func(names []string) []string {
+		keys := make([]string, 0, len(names))
+		for _, name := range names {
+			keys = append(keys, wikilinkTitleKey(name))
+		}
+		keys = uniqueStringSlice(keys)
+		sort.Strings(keys)
+		return keys
+	}
+	return strings.Join(fold(a), "\n") == strings.Join(fold(b), "\n")
+}
+
+// ListProperties returns the front matter properties of a document.
+func (s *DocumentService) ListProperties(ctx context.Context, userID, docID string) ([]model.DocumentProperty, error) {
+	if _, err := s.docs.GetByID(ctx, userID, docID); err != nil {
+		return nil, fmt.Errorf("get document: %w", err)
+	}
+	props, err := s.docs.ListProperties(ctx, userID, docID)
+	if err != nil {
+		return nil, fmt.Errorf("list properties: %w", err)
+	}
+	return props, nil
+}
+
+// normalizePropertyFilters validates list filters and folds their keys the
+// way front matter keys are stored.
+func normalizePropertyFilters(filters []model.PropertyFilter) ([]model.PropertyFilter, error) {
+	if len(filters) > MaxPropertyFilters {
+		return nil, appErr.ErrInvalid
+	}
+	out := make([]model.PropertyFilter, 0, len(filters))
+	for _, filter := range filters {
+		key := propertyKey(filter.Key)
+		if key == "" || len(filter.Values) == 0 || len(filter.Values) > maxPropertyListItems {
+			return nil, appErr.ErrInvalid
+		}
+		for _, value := range filter.Values {
+			if utf8.RuneCountInString(value) > maxPropertyValueRunes {
+				return nil, appErr.ErrInvalid
+			}
+		}
+		out = append(out, model.PropertyFilter{Key: key, Values: filter.Values})
+	}
+	return out, nil
+}
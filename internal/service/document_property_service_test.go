@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+func TestParseProperties(t *testing.T) {
+	content := "---\n" +
+		"Status: draft\n" +
+		"alias: Go Notes\n" +
+		"priority: 1.50\n" +
+		"published: false\n" +
+		"due: 2024-05-01\n" +
+		"at: 2024-05-01T08:30:00Z\n" +
+		"tags: [go, 2]\n" +
+		"nested: {a: 1}\n" +
+		"empty:\n" +
+		"status: duplicate\n" +
+		"---\n" +
+		"# Body\n"
+	assert.Equal(t, []model.DocumentProperty{
+		{Key: "status", Type: model.PropertyTypeString, Value: "draft"},
+		{Key: "aliases", Type: model.PropertyTypeList, Values: []string{"Go Notes"}},
+		{Key: "priority", Type: model.PropertyTypeNumber, Value: "1.5"},
+		{Key: "published", Type: model.PropertyTypeBool, Value: "false"},
+		{Key: "due", Type: model.PropertyTypeDate, Value: "2024-05-01"},
+		{Key: "at", Type: model.PropertyTypeDate, Value: "2024-05-01T08:30:00Z"},
+		{Key: "tags", Type: model.PropertyTypeList, Values: []string{"go", "2"}},
+	}, parseProperties(content))
+}
+
+func TestParseProperties_NoFrontMatter(t *testing.T) {
+	assert.Empty(t, parseProperties("# Title\n---\nstatus: draft\n---\n"))
+	assert.Empty(t, parseProperties("---\nstatus: draft\nno closing line"))
+	assert.Empty(t, parseProperties("---\n- a list\n---\n"))
+	assert.Empty(t, parseProperties("---\nstatus: [unclosed\n---\n"))
+}
+
+func TestMarkdownProseLines_SkipsFrontMatter(t *testing.T) {
+	content := "---\naliases: [[Alpha]]\n...\nSee [[Beta]]"
+	assert.Equal(t, []wikilinkRef{{Title: "Beta", Key: "beta"}}, extractWikilinks(content))
+	lines := markdownProseLines(content)
+	require.Len(t, lines, 1)
+	assert.Equal(t, "See [[Beta]]", content[lines[0].Offset:])
+}
+
+func TestWikilinkCandidates_TitleOutranksAlias(t *testing.T) {
+	candidates := wikilinkCandidates([]model.DocumentTitle{
+		{ID: "d1", Key: "go", Alias: true},
+		{ID: "d2", Key: "go"},
+		{ID: "d3", Key: "golang", Alias: true},
+		{ID: "d4", Key: "golang", Alias: true},
+	})
+	assert.Equal(t, []model.DocumentTitle{{ID: "d2", Key: "go"}}, candidates["go"])
+	assert.Len(t, candidates["golang"], 2)
+}
+
+func TestMentionTerms_IncludesAliases(t *testing.T) {
+	doc := &model.Document{Title: "Go Guide"}
+	assert.Equal(t, []string{"Go Guide", "golang"}, mentionTerms(doc, []string{"go guide", "golang", "x"}))
+}
+
+func TestDocumentService_Save_SyncsPropertiesAndAliases(t *testing.T) {
+	docs := newWikilinkDocs(map[string]model.Document{"d1": {Title: "Notes"}}, nil)
+	docs.updateLinksFn = func(context.Context, string, string, []model.LinkTarget, int64) error { return nil }
+	docs.listPropsFn = func(context.Context, string, string) ([]model.DocumentProperty, error) {
+		return []model.DocumentProperty{{Key: "aliases", Type: model.PropertyTypeList, Values: []string{"Old"}}}, nil
+	}
+	var saved []model.DocumentProperty
+	docs.replacePropsFn = func(_ context.Context, _, docID string, props []model.DocumentProperty, _ int64) error {
+		assert.Equal(t, "d1", docID)
+		saved = props
+		return nil
+	}
+	docs.listWikiSourcesFn = func(_ context.Context, _ string, keys []string, _ string) ([]string, error) {
+		assert.ElementsMatch(t, []string{"old", "new"}, keys)
+		return nil, nil
+	}
+	svc := newDocSvc(docs, newWikilinkVersions(), nil, nil)
+
+	_, err := svc.Save(context.Background(), "u1", "d1", DocumentUpdateInput{
+		Title: "Notes", Content: "---\naliases: [New]\nstatus: draft\n---\nbody",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []model.DocumentProperty{
+		{Key: "aliases", Type: model.PropertyTypeList, Values: []string{"New"}},
+		{Key: "status", Type: model.PropertyTypeString, Value: "draft"},
+	}, saved)
+}
+
+func TestDocumentService_Save_SameAliasesSkipReresolve(t *testing.T) {
+	docs := newWikilinkDocs(map[string]model.Document{"d1": {Title: "Notes"}}, nil)
+	docs.updateLinksFn = func(context.Context, string, string, []model.LinkTarget, int64) error { return nil }
+	docs.listPropsFn = func(context.Context, string, string) ([]model.DocumentProperty, error) {
+		return []model.DocumentProperty{{Key: "aliases", Type: model.PropertyTypeList, Values: []string{"Same"}}}, nil
+	}
+	docs.listWikiSourcesFn = func(context.Context, string, []string, string) ([]string, error) {
+		t.Fatal("unchanged aliases must not re-resolve wikilinks")
+		return nil, nil
+	}
+	svc := newDocSvc(docs, newWikilinkVersions(), nil, nil)
+
+	_, err := svc.Save(context.Background(), "u1", "d1", DocumentUpdateInput{
+		Title: "Notes", Content: "---\naliases: same\n---\nbody",
+	})
+	require.NoError(t, err)
+}
+
+func TestDocumentService_Search_PropertyFilters(t *testing.T) {
+	docs := &mockDocumentRepo{
+		searchLikeFn: func(
+			_ context.Context, _, _, _ string, _ *int, props []model.PropertyFilter, _ *model.FolderFilter, _, _ uint, _ string,
+		) ([]model.Document, error) {
+			assert.Equal(t, []model.PropertyFilter{{Key: "status", Values: []string{"draft", "review"}}}, props)
+			return []model.Document{{ID: "d1"}}, nil
+		},
+	}
+	svc := newDocSvc(docs, nil, nil, nil)
+
+	result, err := svc.Search(context.Background(), "u1", "", "", nil,
+		[]model.PropertyFilter{{Key: " Status ", Values: []string{"draft", "review"}}}, nil, 10, 0, "")
+	require.NoError(t, err)
+	assert.Len(t, result, 1)
+
+	_, err = svc.Search(context.Background(), "u1", "", "", nil,
+		[]model.PropertyFilter{{Key: "status"}}, nil, 10, 0, "")
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+}
+
+func TestDocumentService_ListProperties(t *testing.T) {
+	docs := &mockDocumentRepo{
+		getByIDFn: func(context.Context, string, string) (*model.Document, error) {
+			return nil, appErr.ErrNotFound
+		},
+	}
+	_, err := newDocSvc(docs, nil, nil, nil).ListProperties(context.Background(), "u1", "d1")
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
@@ -38,6 +38,18 @@ type documentEmbeddingClient interface {
 		userID, documentID string,
 		limit int,
 	) ([]string, []float32, string, error)
+	SimilarTags(
+		ctx context.Context,
+		userID, documentID string,
+		limit int,
+	) ([]model.SimilarTagResult, string, error)
+	SimilarDocumentPairs(
+		ctx context.Context,
+		userID string,
+		documentIDs []string,
+		perDocument int,
+		minScore float32,
+	) ([]model.SimilarDocumentPair, string, error)
 }
 
 type DocumentService struct {
@@ -96,6 +108,8 @@ func (
 	query,
 	tagID string,
 	starred *int,
+	props []model.PropertyFilter,
+	folder *model.FolderFilter,
 	limit,
 	offset uint,
 	orderBy string) ([]model.Document,
@@ -105,18 +119,22 @@ func (
 	if utf8.RuneCountInString(query) > 200 {
 		return nil, appErr.ErrInvalid
 	}
+	props, err := normalizePropertyFilters(props)
+	if err != nil {
+		return nil, err
+	}
 	page := Page{Limit: safeconv.UintToInt(limit), Offset: safeconv.UintToInt(offset)}.
 		Clamp(50, 200)
 	limit = safeconv.IntToUint(page.Limit)
 	offset = safeconv.IntToUint(page.Offset)
-	if query == "" && tagID == "" {
+	if query == "" && tagID == "" && len(props) == 0 && folder == nil {
 		docs, err := s.docs.List(ctx, userID, starred, limit, offset, orderBy)
 		if err != nil {
 			return nil, fmt.Errorf("list documents: %w", err)
 		}
 		return docs, nil
 	}
-	docs, err := s.docs.SearchLike(ctx, userID, query, tagID, starred, limit, offset, orderBy)
+	docs, err := s.docs.SearchLike(ctx, userID, query, tagID, starred, props, folder, limit, offset, orderBy)
 	if err != nil {
 		return nil, fmt.Errorf("search documents: %w", err)
 	}
@@ -177,10 +195,11 @@ func (s *DocumentService) SemanticSearch(
 	return []model.Document{}, []float32{}, nil
 }
 
+// Get returns a document the user owns or was invited to as a collaborator.
 func (s *DocumentService) Get(ctx context.Context, userID, docID string) (*model.Document, error) {
-	doc, err := s.docs.GetByID(ctx, userID, docID)
+	doc, _, err := s.readableDocument(ctx, userID, docID)
 	if err != nil {
-		return nil, fmt.Errorf("get by id: %w", err)
+		return nil, err
 	}
 	return doc, nil
 }
@@ -381,10 +400,11 @@ func (s *DocumentService) ListByTag(ctx context.Context, userID, tagID string) (
 }
 
 func (s *DocumentService) ListTagIDs(ctx context.Context, userID, docID string) ([]string, error) {
-	if _, err := s.docs.GetByID(ctx, userID, docID); err != nil {
-		return nil, fmt.Errorf("get by id: %w", err)
+	_, ownerID, err := s.readableDocument(ctx, userID, docID)
+	if err != nil {
+		return nil, err
 	}
-	v0, err := s.tags.ListTagIDs(ctx, userID, docID)
+	v0, err := s.tags.ListTagIDs(ctx, ownerID, docID)
 	if err != nil {
 		return nil, fmt.Errorf("list tag ids: %w", err)
 	}
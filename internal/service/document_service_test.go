@@ -20,6 +20,9 @@ func newDocSvc(
 	tags documentTagRepo,
 	shares shareRepo,
 ) *DocumentService {
+	if shares == nil {
+		shares = &mockShareRepo{}
+	}
 	return NewDocumentService(testRuntime(), docs, versions, tags, shares, &mockTagRepo{}, &mockUserRepo{}, nil, 10, nil)
 }
 
@@ -31,20 +34,20 @@ func TestDocumentService_Search(t *testing.T) {
 			},
 		}
 		svc := newDocSvc(docs, nil, nil, nil)
-		result, err := svc.Search(context.Background(), "u1", "", "", nil, 10, 0, "")
+		result, err := svc.Search(context.Background(), "u1", "", "", nil, nil, nil, 10, 0, "")
 		require.NoError(t, err)
 		assert.Len(t, result, 1)
 	})
 
 	t.Run("search_with_query", func(t *testing.T) {
 		docs := &mockDocumentRepo{
-			searchLikeFn: func(_ context.Context, _, query, _ string, _ *int, _, _ uint, _ string) ([]model.Document, error) {
+			searchLikeFn: func(_ context.Context, _, query, _ string, _ *int, _ []model.PropertyFilter, _ *model.FolderFilter, _, _ uint, _ string) ([]model.Document, error) {
 				assert.Equal(t, "golang", query)
 				return []model.Document{{ID: "d1"}}, nil
 			},
 		}
 		svc := newDocSvc(docs, nil, nil, nil)
-		result, err := svc.Search(context.Background(), "u1", "golang", "", nil, 10, 0, "")
+		result, err := svc.Search(context.Background(), "u1", "golang", "", nil, nil, nil, 10, 0, "")
 		require.NoError(t, err)
 		assert.Len(t, result, 1)
 	})
@@ -56,7 +59,7 @@ func TestDocumentService_Search(t *testing.T) {
 			},
 		}
 		svc := newDocSvc(docs, nil, nil, nil)
-		_, err := svc.Search(context.Background(), "u1", "", "", nil, 10, 0, "")
+		_, err := svc.Search(context.Background(), "u1", "", "", nil, nil, nil, 10, 0, "")
 		assert.Error(t, err)
 	})
 }
@@ -131,6 +134,33 @@ func TestDocumentService_UpdateTags(t *testing.T) {
 	})
 }
 
+func TestDocumentService_RemoveTags(t *testing.T) {
+	t.Run("empty", func(t *testing.T) {
+		svc := newDocSvc(&mockDocumentRepo{}, nil, &mockDocumentTagRepo{}, nil)
+		_, err := svc.RemoveTags(context.Background(), "u1", "d1", nil)
+		assert.ErrorIs(t, err, appErr.ErrInvalid)
+	})
+
+	t.Run("keeps_other_tags", func(t *testing.T) {
+		var added []string
+		docTags := &mockDocumentTagRepo{
+			listTagIDsFn: func(context.Context, string, string) ([]string, error) {
+				return []string{"t1", "t2", "t3"}, nil
+			},
+			deleteByDocFn: func(context.Context, string, string) error { return nil },
+			addFn: func(_ context.Context, docTag *model.DocumentTag) error {
+				added = append(added, docTag.TagID)
+				return nil
+			},
+		}
+		svc := newDocSvc(&mockDocumentRepo{}, nil, docTags, nil)
+		tagIDs, err := svc.RemoveTags(context.Background(), "u1", "d1", []string{"t2", "t9"})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"t1", "t3"}, tagIDs)
+		assert.Equal(t, []string{"t1", "t3"}, added)
+	})
+}
+
 func TestDocumentService_UpdatePinned(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		docs := &mockDocumentRepo{
@@ -508,7 +538,7 @@ func TestDocumentService_Create(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		docs := &mockDocumentRepo{
 			createFn:      func(context.Context, *model.Document) error { return nil },
-			updateLinksFn: func(context.Context, string, string, []string, int64) error { return nil },
+			updateLinksFn: func(context.Context, string, string, []model.LinkTarget, int64) error { return nil },
 		}
 		versions := &mockVersionRepo{
 			createFn:            func(context.Context, *model.DocumentVersion) error { return nil },
@@ -550,7 +580,7 @@ func TestDocumentService_Update(t *testing.T) {
 				updatedDoc = d
 				return nil
 			},
-			updateLinksFn: func(context.Context, string, string, []string, int64) error { return nil },
+			updateLinksFn: func(context.Context, string, string, []model.LinkTarget, int64) error { return nil },
 		}
 		versions := &mockVersionRepo{
 			createFn: func(_ context.Context, v *model.DocumentVersion) error {
@@ -1247,7 +1277,7 @@ func TestDocumentService_UpdateShareConfig_UpdateError(t *testing.T) {
 func TestDocumentService_Update_VersionCreateError(t *testing.T) {
 	docs := &mockDocumentRepo{
 		updateFn:      func(context.Context, *model.Document) error { return nil },
-		updateLinksFn: func(context.Context, string, string, []string, int64) error { return nil },
+		updateLinksFn: func(context.Context, string, string, []model.LinkTarget, int64) error { return nil },
 	}
 	versions := &mockVersionRepo{
 		createFn: func(context.Context, *model.DocumentVersion) error { return errors.New("create version fail") },
@@ -1260,7 +1290,7 @@ func TestDocumentService_Update_VersionCreateError(t *testing.T) {
 func TestDocumentService_Update_PruneVersionsError(t *testing.T) {
 	docs := &mockDocumentRepo{
 		updateFn:      func(context.Context, *model.Document) error { return nil },
-		updateLinksFn: func(context.Context, string, string, []string, int64) error { return nil },
+		updateLinksFn: func(context.Context, string, string, []model.LinkTarget, int64) error { return nil },
 	}
 	versions := &mockVersionRepo{
 		createFn: func(context.Context, *model.DocumentVersion) error { return nil },
@@ -1277,7 +1307,7 @@ func TestDocumentService_Update_TagDeleteError(t *testing.T) {
 	tagIDs := []string{"t1"}
 	docs := &mockDocumentRepo{
 		updateFn:      func(context.Context, *model.Document) error { return nil },
-		updateLinksFn: func(context.Context, string, string, []string, int64) error { return nil },
+		updateLinksFn: func(context.Context, string, string, []model.LinkTarget, int64) error { return nil },
 	}
 	versions := &mockVersionRepo{
 		createFn:            func(context.Context, *model.DocumentVersion) error { return nil },
@@ -1294,7 +1324,7 @@ func TestDocumentService_Update_TagDeleteError(t *testing.T) {
 func TestDocumentService_Update_UpdateLinkError(t *testing.T) {
 	docs := &mockDocumentRepo{
 		updateFn:      func(context.Context, *model.Document) error { return nil },
-		updateLinksFn: func(context.Context, string, string, []string, int64) error { return errors.New("link fail") },
+		updateLinksFn: func(context.Context, string, string, []model.LinkTarget, int64) error { return errors.New("link fail") },
 	}
 	versions := &mockVersionRepo{
 		createFn:            func(context.Context, *model.DocumentVersion) error { return nil },
@@ -1316,12 +1346,12 @@ func TestDocumentService_Update_DocUpdateError(t *testing.T) {
 
 func TestDocumentService_Search_SearchError(t *testing.T) {
 	docs := &mockDocumentRepo{
-		searchLikeFn: func(context.Context, string, string, string, *int, uint, uint, string) ([]model.Document, error) {
+		searchLikeFn: func(context.Context, string, string, string, *int, []model.PropertyFilter, *model.FolderFilter, uint, uint, string) ([]model.Document, error) {
 			return nil, errors.New("fail")
 		},
 	}
 	svc := newDocSvc(docs, nil, nil, nil)
-	_, err := svc.Search(context.Background(), "u1", "query", "", nil, 10, 0, "")
+	_, err := svc.Search(context.Background(), "u1", "query", "", nil, nil, nil, 10, 0, "")
 	assert.Error(t, err)
 }
 
@@ -1416,7 +1446,7 @@ func TestDocumentService_Create_Errors(t *testing.T) {
 	t.Run("tag_delete_error", func(t *testing.T) {
 		docs := &mockDocumentRepo{
 			createFn:      func(context.Context, *model.Document) error { return nil },
-			updateLinksFn: func(context.Context, string, string, []string, int64) error { return nil },
+			updateLinksFn: func(context.Context, string, string, []model.LinkTarget, int64) error { return nil },
 		}
 		versions := &mockVersionRepo{
 			createFn:            func(context.Context, *model.DocumentVersion) error { return nil },
@@ -1433,7 +1463,7 @@ func TestDocumentService_Create_Errors(t *testing.T) {
 	t.Run("tag_add_error", func(t *testing.T) {
 		docs := &mockDocumentRepo{
 			createFn:      func(context.Context, *model.Document) error { return nil },
-			updateLinksFn: func(context.Context, string, string, []string, int64) error { return nil },
+			updateLinksFn: func(context.Context, string, string, []model.LinkTarget, int64) error { return nil },
 		}
 		versions := &mockVersionRepo{
 			createFn:            func(context.Context, *model.DocumentVersion) error { return nil },
@@ -1451,7 +1481,7 @@ func TestDocumentService_Create_Errors(t *testing.T) {
 	t.Run("link_update_error", func(t *testing.T) {
 		docs := &mockDocumentRepo{
 			createFn:      func(context.Context, *model.Document) error { return nil },
-			updateLinksFn: func(context.Context, string, string, []string, int64) error { return errors.New("fail") },
+			updateLinksFn: func(context.Context, string, string, []model.LinkTarget, int64) error { return errors.New("fail") },
 		}
 		versions := &mockVersionRepo{
 			createFn:            func(context.Context, *model.DocumentVersion) error { return nil },
@@ -1465,7 +1495,7 @@ func TestDocumentService_Create_Errors(t *testing.T) {
 	t.Run("asset_sync_error", func(t *testing.T) {
 		docs := &mockDocumentRepo{
 			createFn:      func(context.Context, *model.Document) error { return nil },
-			updateLinksFn: func(context.Context, string, string, []string, int64) error { return nil },
+			updateLinksFn: func(context.Context, string, string, []model.LinkTarget, int64) error { return nil },
 		}
 		versions := &mockVersionRepo{
 			createFn:            func(context.Context, *model.DocumentVersion) error { return nil },
@@ -1484,7 +1514,7 @@ func TestDocumentService_Create_Errors(t *testing.T) {
 	t.Run("success_full_flow", func(t *testing.T) {
 		docs := &mockDocumentRepo{
 			createFn:      func(context.Context, *model.Document) error { return nil },
-			updateLinksFn: func(context.Context, string, string, []string, int64) error { return nil },
+			updateLinksFn: func(context.Context, string, string, []model.LinkTarget, int64) error { return nil },
 		}
 		versions := &mockVersionRepo{
 			createFn:            func(context.Context, *model.DocumentVersion) error { return nil },
@@ -1671,7 +1701,7 @@ func TestDocumentService_Search_ListError(t *testing.T) {
 		},
 	}
 	svc := newDocSvc(docs, nil, nil, nil)
-	_, err := svc.Search(context.Background(), "u1", "", "", nil, 10, 0, "")
+	_, err := svc.Search(context.Background(), "u1", "", "", nil, nil, nil, 10, 0, "")
 	assert.Error(t, err)
 }
 
@@ -2022,7 +2052,7 @@ func TestDocumentService_Save_AcceptsFreshSaveSeq(t *testing.T) {
 			saved = d
 			return nil
 		},
-		updateLinksFn: func(context.Context, string, string, []string, int64) error { return nil },
+		updateLinksFn: func(context.Context, string, string, []model.LinkTarget, int64) error { return nil },
 	}
 	versions := &mockVersionRepo{
 		createFn: func(_ context.Context, v *model.DocumentVersion) error {
@@ -2055,6 +2085,9 @@ func TestDocumentService_Save_AcceptsFreshSaveSeq(t *testing.T) {
 func TestDocumentService_Save_LockFailure(t *testing.T) {
 	updateCalled := false
 	docs := &mockDocumentRepo{
+		getByIDFn: func(context.Context, string, string) (*model.Document, error) {
+			return &model.Document{ID: "d1", Title: "T"}, nil
+		},
 		getByIDForUpdateFn: func(context.Context, string, string) (*model.Document, error) {
 			return nil, errors.New("lock conflict")
 		},
@@ -2134,6 +2167,25 @@ func (*stubEmbeddingClient) SimilarDocuments(
 	return nil, nil, "disabled", nil
 }
 
+func (*stubEmbeddingClient) SimilarTags(
+	context.Context,
+	string,
+	string,
+	int,
+) ([]model.SimilarTagResult, string, error) {
+	return nil, "disabled", nil
+}
+
+func (*stubEmbeddingClient) SimilarDocumentPairs(
+	context.Context,
+	string,
+	[]string,
+	int,
+	float32,
+) ([]model.SimilarDocumentPair, string, error) {
+	return nil, "disabled", nil
+}
+
 // TestDocumentService_Save_UpdateLinksError covers the UpdateLinks failure
 // branch in refreshReferences. The companion test below covers the
 // SyncDocumentReferences and MarkEmbeddingPending branches via the
@@ -2144,7 +2196,7 @@ func TestDocumentService_Save_UpdateLinksError(t *testing.T) {
 			return &model.Document{ID: "d1", UserID: "u1", ContentRevision: 1}, nil
 		},
 		updateFn: func(context.Context, *model.Document) error { return nil },
-		updateLinksFn: func(context.Context, string, string, []string, int64) error {
+		updateLinksFn: func(context.Context, string, string, []model.LinkTarget, int64) error {
 			return errors.New("link boom")
 		},
 	}
@@ -2165,7 +2217,7 @@ func TestDocumentService_Save_RefreshReferences_AssetAndEmbeddingErrors(t *testi
 				return &model.Document{ID: "d1", UserID: "u1", ContentRevision: 1}, nil
 			},
 			updateFn:      func(context.Context, *model.Document) error { return nil },
-			updateLinksFn: func(context.Context, string, string, []string, int64) error { return nil },
+			updateLinksFn: func(context.Context, string, string, []model.LinkTarget, int64) error { return nil },
 		}
 		versions := &mockVersionRepo{
 			createFn:            func(context.Context, *model.DocumentVersion) error { return nil },
@@ -59,6 +59,9 @@ func (
 }
 
 func (s *DocumentService) CreateShare(ctx context.Context, userID, docID string) (*model.Share, error) {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return nil, err
+	}
 	shareID, err := s.runtime.IDs.ID()
 	if err != nil {
 		return nil, fmt.Errorf("generate share id: %w", err)
@@ -114,6 +117,9 @@ func (
 	input ShareConfigInput) (*model.Share,
 	error,
 ) {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return nil, err
+	}
 	share, err := s.GetActiveShare(ctx, userID, docID)
 	if err != nil {
 		return nil, fmt.Errorf("get active share: %w", err)
@@ -148,6 +154,9 @@ func (
 }
 
 func (s *DocumentService) RevokeShare(ctx context.Context, userID, docID string) error {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return err
+	}
 	if _, err := s.docs.GetByID(ctx, userID, docID); err != nil {
 		return fmt.Errorf("get by id: %w", err)
 	}
@@ -222,8 +231,14 @@ func (
 	if err != nil {
 		return nil, fmt.Errorf("get document by id: %w", err)
 	}
+	// Documents of a team workspace are owned by the workspace, which has
+	// no account to name as the author.
+	author := ""
 	user, err := s.userRepo.GetByID(ctx, share.UserID)
-	if err != nil {
+	switch {
+	case err == nil:
+		author = user.Email
+	case !errors.Is(err, appErr.ErrNotFound):
 		return nil, fmt.Errorf("get user by id: %w", err)
 	}
 	tagIDs, err := s.tags.ListTagIDs(ctx, share.UserID, share.DocumentID)
@@ -236,7 +251,7 @@ func (
 	}
 	return &PublicShareDetail{
 		Document:      doc,
-		Author:        user.Email,
+		Author:        author,
 		Tags:          tags,
 		Permission:    share.Permission,
 		AllowDownload: share.AllowDownload,
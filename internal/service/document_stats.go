@@ -0,0 +1,68 @@
+package service
+
+import (
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/xxxsen/mnote/internal/model"
+)
+
+const (
+	wordsPerMinute    = 200
+	cjkCharsPerMinute = 400
+)
+
+// taskItemRegex matches a GFM task list item; the group is the checkbox mark.
+var taskItemRegex = regexp.MustCompile(`^\s*(?:[-*+]|\d{1,9}[.)])\s+\[([ xX])\](?:\s|$)`)
+
+// proseText returns what a reader reads on a markdown line: link and
+// wikilink markup reduced to their text and the checkbox of a task dropped.
+func proseText(line string) string {
+	if m := taskItemRegex.FindString(line); m != "" {
+		line = line[len(m):]
+	}
+	line = markdownWikilinkText.ReplaceAllString(line, "$1")
+	return markdownLinkText.ReplaceAllString(line, "$1")
+}
+
+// documentStats computes the reading statistics of content. Words, links,
+// images and tasks are taken from the prose only, so front matter and fenced
+// code do not count; characters are the non-space characters of everything
+// but the front matter.
+func documentStats(content string) model.DocumentStats {
+	lines, blocks := scanMarkdownLines(content)
+	stats := model.DocumentStats{CodeBlocks: blocks}
+	words, cjk := 0, 0
+	for _, line := range lines {
+		if m := taskItemRegex.FindStringSubmatch(line.Text); m != nil {
+			stats.TasksTotal++
+			if m[1] != " " {
+				stats.TasksDone++
+			}
+		}
+		text := inlineCodeRegex.ReplaceAllString(line.Text, " ")
+		for _, m := range markdownLinkText.FindAllString(text, -1) {
+			if strings.HasPrefix(m, "!") {
+				stats.Images++
+			} else {
+				stats.Links++
+			}
+		}
+		stats.Links += len(markdownWikilinkText.FindAllStringIndex(text, -1))
+		w, c := countWordsByScript(proseText(line.Text))
+		words += w
+		cjk += c
+	}
+	stats.WordCount = words + cjk
+	_, offset, _ := frontMatter(content)
+	for _, r := range content[offset:] {
+		if !unicode.IsSpace(r) {
+			stats.CharCount++
+		}
+	}
+	minutes := float64(words)/wordsPerMinute + float64(cjk)/cjkCharsPerMinute
+	stats.ReadingMinutes = int(math.Ceil(minutes))
+	return stats
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+)
+
+func TestDocumentStats(t *testing.T) {
+	content := "---\ntitle: ignored words here\n---\n" +
+		"# 笔记 Notes\n" +
+		"See [the docs](https://go.dev/doc) and [[Other|other note]].\n" +
+		"![diagram](a.png) `[not](a link)`\n" +
+		"- [x] done task\n" +
+		"- [ ] open task\n" +
+		"1. [X] numbered\n" +
+		"```go\nfunc main() {}\n```\n" +
+		"~~~\n[[inside code]]\n~~~\n"
+	assert.Equal(t, model.DocumentStats{
+		WordCount:      16,
+		CharCount:      utf8.RuneCountInString(strings.Join(strings.Fields(content[strings.Index(content, "# "):]), "")),
+		ReadingMinutes: 1,
+		CodeBlocks:     2,
+		Images:         1,
+		Links:          2,
+		TasksTotal:     3,
+		TasksDone:      2,
+	}, documentStats(content))
+}
+
+func TestDocumentStats_ReadingMinutes(t *testing.T) {
+	assert.Equal(t, model.DocumentStats{}, documentStats(""))
+	assert.Equal(t, 2, documentStats(strings.Repeat("word ", 201)).ReadingMinutes)
+	assert.Equal(t, 1, documentStats(strings.Repeat("字", 400)).ReadingMinutes)
+	assert.Equal(t, 400, documentStats(strings.Repeat("字", 400)).WordCount)
+}
+
+func TestDocumentService_Save_WritesStats(t *testing.T) {
+	docs := newWikilinkDocs(map[string]model.Document{"d1": {Title: "Notes"}}, nil)
+	docs.updateLinksFn = func(context.Context, string, string, []model.LinkTarget, int64) error { return nil }
+	var stats model.DocumentStats
+	docs.updateFn = func(_ context.Context, doc *model.Document) error {
+		stats = doc.Stats
+		return nil
+	}
+	svc := newDocSvc(docs, newWikilinkVersions(), nil, nil)
+
+	_, err := svc.Save(context.Background(), "u1", "d1", DocumentUpdateInput{
+		Title: "Notes", Content: "two words\n- [x] task",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, model.DocumentStats{
+		WordCount: 3, CharCount: 16, ReadingMinutes: 1, TasksTotal: 1, TasksDone: 1,
+	}, stats)
+}
+
+func TestDocumentService_Overview_Stats(t *testing.T) {
+	docs := &mockDocumentRepo{
+		listFn: func(context.Context, string, *int, uint, uint, string) ([]model.Document, error) {
+			return nil, nil
+		},
+		countFn: func(context.Context, string, *int) (int, error) { return 1, nil },
+		sumStatsFn: func(_ context.Context, _ string, since int64) (*model.DocumentStatsTotals, error) {
+			assert.InDelta(t, time.Now().Add(-overviewActivityWindow).Unix(), since, 5)
+			return &model.DocumentStatsTotals{DocumentStats: model.DocumentStats{WordCount: 42}, EditedDocuments: 1}, nil
+		},
+	}
+	tags := &mockDocumentTagRepo{
+		listByUserFn: func(context.Context, string) ([]model.DocumentTag, error) { return nil, nil },
+	}
+	result, err := newDocSvc(docs, nil, tags, nil).Overview(context.Background(), "u1", 5)
+	require.NoError(t, err)
+	assert.Equal(t, 42, result.Stats.WordCount)
+	assert.Equal(t, 1, result.Stats.EditedDocuments)
+}
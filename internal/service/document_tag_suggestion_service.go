@@ -0,0 +1,257 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+const (
+	maxTagSuggestions       = 20
+	tagSuggestionNeighbours = 20
+	tagSuggestionCentroids  = 50
+	// tagSuggestionNeighbourWeight balances the two signals: neighbour votes
+	// react to a few very close documents, tag centroids to the overall theme
+	// of a tag. Neither needs a model call; both read the V2 centroid index.
+	tagSuggestionNeighbourWeight = 0.5
+)
+
+type TagSuggestion struct {
+	Tag            model.Tag
+	Score          float32
+	NeighbourScore float32
+	CentroidScore  float32
+	NeighbourCount int
+}
+
+type TagSuggestionList struct {
+	Suggestions []TagSuggestion
+	IndexStatus string
+}
+
+// SuggestTags proposes tags the document does not carry yet. Each candidate
+// score blends the similarity-weighted share of nearest neighbours carrying
+// the tag with the similarity between the document and the tag centroid.
+func (s *DocumentService) SuggestTags(
+	ctx context.Context,
+	userID, docID string,
+	limit int,
+) (*TagSuggestionList, error) {
+	if limit < 1 || limit > maxTagSuggestions {
+		return nil, appErr.ErrInvalid
+	}
+	if _, err := s.docs.GetByID(ctx, userID, docID); err != nil {
+		return nil, fmt.Errorf("get source document: %w", err)
+	}
+	if s.embedding == nil {
+		return &TagSuggestionList{Suggestions: []TagSuggestion{}, IndexStatus: "disabled"}, nil
+	}
+	neighbourIDs, neighbourScores, status, err := s.embedding.SimilarDocuments(
+		ctx, userID, docID, tagSuggestionNeighbours,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("similar documents: %w", err)
+	}
+	if status != "ready" {
+		return &TagSuggestionList{Suggestions: []TagSuggestion{}, IndexStatus: status}, nil
+	}
+	centroids, status, err := s.embedding.SimilarTags(ctx, userID, docID, tagSuggestionCentroids)
+	if err != nil {
+		return nil, fmt.Errorf("similar tags: %w", err)
+	}
+	if status != "ready" {
+		return &TagSuggestionList{Suggestions: []TagSuggestion{}, IndexStatus: status}, nil
+	}
+	current, err := s.tags.ListTagIDs(ctx, userID, docID)
+	if err != nil {
+		return nil, fmt.Errorf("list tag ids: %w", err)
+	}
+	neighbourTags := map[string][]string{}
+	if len(neighbourIDs) > 0 {
+		neighbourTags, err = s.tags.ListTagIDsByDocIDs(ctx, userID, neighbourIDs)
+		if err != nil {
+			return nil, fmt.Errorf("list neighbour tag ids: %w", err)
+		}
+	}
+	candidates := scoreTagSuggestions(current, neighbourIDs, neighbourScores, neighbourTags, centroids)
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	ids := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		ids = append(ids, candidate.Tag.ID)
+	}
+	tags := []model.Tag{}
+	if len(ids) > 0 {
+		tags, err = s.tagRepo.ListByIDs(ctx, userID, ids)
+		if err != nil {
+			return nil, fmt.Errorf("list suggested tags: %w", err)
+		}
+	}
+	byID := make(map[string]model.Tag, len(tags))
+	for _, tag := range tags {
+		byID[tag.ID] = tag
+	}
+	result := &TagSuggestionList{
+		Suggestions: make([]TagSuggestion, 0, len(candidates)),
+		IndexStatus: status,
+	}
+	for _, candidate := range candidates {
+		tag, ok := byID[candidate.Tag.ID]
+		if !ok {
+			continue
+		}
+		candidate.Tag = tag
+		result.Suggestions = append(result.Suggestions, candidate)
+	}
+	return result, nil
+}
+
+// TagSuggestionApply selects the suggestions ApplyTagSuggestions adds to
+// each document: those scoring at least MinScore, at most Limit of them. At
+// least one of the two must be set.
+type TagSuggestionApply struct {
+	DocIDs   []string
+	MinScore float32
+	Limit    int
+}
+
+// TagSuggestionApplyResult is the outcome for one document along with the
+// tags added to it.
+type TagSuggestionApplyResult struct {
+	BulkItemResult
+	TagIDs []string
+}
+
+// ApplyTagSuggestions computes the suggestions of every listed document and
+// adds the selected ones to it, each document in its own transaction. A
+// document that fails, including one whose index is not ready, fails on its
+// own; results follow the request order.
+func (s *DocumentService) ApplyTagSuggestions(
+	ctx context.Context,
+	userID string,
+	req TagSuggestionApply,
+) ([]TagSuggestionApplyResult, error) {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return nil, err
+	}
+	limit := req.Limit
+	if limit == 0 {
+		limit = maxTagSuggestions
+	}
+	if (req.Limit == 0 && req.MinScore <= 0) || req.MinScore < 0 || req.MinScore > 1 ||
+		limit < 1 || limit > maxTagSuggestions {
+		return nil, appErr.ErrInvalid
+	}
+	ids := uniqueStringSlice(req.DocIDs)
+	if len(ids) == 0 || len(ids) > maxBulkDocuments {
+		return nil, appErr.ErrInvalid
+	}
+	results := make([]TagSuggestionApplyResult, 0, len(ids))
+	for _, id := range ids {
+		tagIDs, err := s.applyTagSuggestions(ctx, userID, id, req.MinScore, limit)
+		results = append(results, TagSuggestionApplyResult{
+			BulkItemResult: BulkItemResult{ID: id, Err: err}, TagIDs: tagIDs,
+		})
+	}
+	return results, nil
+}
+
+// applyTagSuggestions adds the top limit suggestions of one document scoring
+// at least minScore and returns the IDs of the added tags.
+func (s *DocumentService) applyTagSuggestions(
+	ctx context.Context, userID, docID string, minScore float32, limit int,
+) ([]string, error) {
+	added := []string{}
+	err := s.runInTx(ctx, func(txCtx context.Context) error {
+		list, err := s.SuggestTags(txCtx, userID, docID, limit)
+		if err != nil {
+			return err
+		}
+		if list.IndexStatus != "ready" {
+			return appErr.ErrConflict
+		}
+		for _, suggestion := range list.Suggestions {
+			if suggestion.Score >= minScore {
+				added = append(added, suggestion.Tag.ID)
+			}
+		}
+		if len(added) == 0 {
+			return nil
+		}
+		_, err = s.AddTags(txCtx, userID, docID, added)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return added, nil
+}
+
+func scoreTagSuggestions(
+	current []string,
+	neighbourIDs []string,
+	neighbourScores []float32,
+	neighbourTags map[string][]string,
+	centroids []model.SimilarTagResult,
+) []TagSuggestion {
+	skip := make(map[string]struct{}, len(current))
+	for _, id := range current {
+		skip[id] = struct{}{}
+	}
+	candidates := make(map[string]*TagSuggestion)
+	candidate := func(tagID string) *TagSuggestion {
+		item, ok := candidates[tagID]
+		if !ok {
+			item = &TagSuggestion{Tag: model.Tag{ID: tagID}}
+			candidates[tagID] = item
+		}
+		return item
+	}
+	var total float32
+	for index, docID := range neighbourIDs {
+		if index >= len(neighbourScores) || neighbourScores[index] <= 0 {
+			continue
+		}
+		score := neighbourScores[index]
+		total += score
+		for _, tagID := range uniqueStringSlice(neighbourTags[docID]) {
+			if _, ok := skip[tagID]; ok {
+				continue
+			}
+			item := candidate(tagID)
+			item.NeighbourScore += score
+			item.NeighbourCount++
+		}
+	}
+	if total > 0 {
+		for _, item := range candidates {
+			item.NeighbourScore /= total
+		}
+	}
+	for _, centroid := range centroids {
+		if _, ok := skip[centroid.TagID]; ok || centroid.Score <= 0 {
+			continue
+		}
+		candidate(centroid.TagID).CentroidScore = centroid.Score
+	}
+	out := make([]TagSuggestion, 0, len(candidates))
+	for _, item := range candidates {
+		item.Score = tagSuggestionNeighbourWeight*item.NeighbourScore +
+			(1-tagSuggestionNeighbourWeight)*item.CentroidScore
+		if item.Score <= 0 {
+			continue
+		}
+		out = append(out, *item)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Score != out[j].Score {
+			return out[i].Score > out[j].Score
+		}
+		return out[i].Tag.ID < out[j].Tag.ID
+	})
+	return out
+}
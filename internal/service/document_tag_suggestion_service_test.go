@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+type suggestionEmbeddingClient struct {
+	stubEmbeddingClient
+	neighbourIDs    []string
+	neighbourScores []float32
+	tags            []model.SimilarTagResult
+	status          string
+}
+
+func (s *suggestionEmbeddingClient) SimilarDocuments(
+	context.Context, string, string, int,
+) ([]string, []float32, string, error) {
+	return s.neighbourIDs, s.neighbourScores, s.status, nil
+}
+
+func (s *suggestionEmbeddingClient) SimilarTags(
+	context.Context, string, string, int,
+) ([]model.SimilarTagResult, string, error) {
+	return s.tags, s.status, nil
+}
+
+func TestScoreTagSuggestions(t *testing.T) {
+	items := scoreTagSuggestions(
+		[]string{"owned"},
+		[]string{"n1", "n2"},
+		[]float32{0.9, 0.6},
+		map[string][]string{"n1": {"go", "owned"}, "n2": {"go", "db"}},
+		[]model.SimilarTagResult{
+			{TagID: "go", Score: 0.8},
+			{TagID: "rust", Score: 0.4},
+			{TagID: "owned", Score: 0.99},
+			{TagID: "far", Score: -0.2},
+		},
+	)
+	require.Len(t, items, 3)
+	assert.Equal(t, "go", items[0].Tag.ID)
+	assert.InDelta(t, 0.9, items[0].Score, 0.0001)
+	assert.Equal(t, 2, items[0].NeighbourCount)
+	assert.Equal(t, "db", items[1].Tag.ID)
+	assert.InDelta(t, 0.2, items[1].Score, 0.0001)
+	assert.InDelta(t, 0.4, items[1].NeighbourScore, 0.0001)
+	assert.Equal(t, "rust", items[2].Tag.ID)
+	assert.InDelta(t, 0.2, items[2].Score, 0.0001)
+	assert.Zero(t, items[2].NeighbourCount)
+}
+
+func TestDocumentService_SuggestTags(t *testing.T) {
+	docs := &mockDocumentRepo{
+		getByIDFn: func(context.Context, string, string) (*model.Document, error) {
+			return &model.Document{ID: "d1"}, nil
+		},
+	}
+	docTags := &mockDocumentTagRepo{
+		listTagIDsFn: func(context.Context, string, string) ([]string, error) {
+			return []string{"owned"}, nil
+		},
+		listTagIDsByDocIDsFn: func(_ context.Context, _ string, ids []string) (map[string][]string, error) {
+			assert.Equal(t, []string{"n1"}, ids)
+			return map[string][]string{"n1": {"go", "owned"}}, nil
+		},
+	}
+
+	t.Run("invalid_limit", func(t *testing.T) {
+		svc := newDocSvc(docs, nil, docTags, nil)
+		_, err := svc.SuggestTags(context.Background(), "u1", "d1", 0)
+		assert.ErrorIs(t, err, appErr.ErrInvalid)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		svc := newDocSvc(docs, nil, docTags, nil)
+		result, err := svc.SuggestTags(context.Background(), "u1", "d1", 5)
+		require.NoError(t, err)
+		assert.Equal(t, "disabled", result.IndexStatus)
+		assert.Empty(t, result.Suggestions)
+	})
+
+	t.Run("pending", func(t *testing.T) {
+		svc := newDocSvc(docs, nil, docTags, nil)
+		svc.embedding = &suggestionEmbeddingClient{status: "pending"}
+		result, err := svc.SuggestTags(context.Background(), "u1", "d1", 5)
+		require.NoError(t, err)
+		assert.Equal(t, "pending", result.IndexStatus)
+		assert.Empty(t, result.Suggestions)
+	})
+
+	t.Run("ready", func(t *testing.T) {
+		svc := newDocSvc(docs, nil, docTags, nil)
+		svc.tagRepo = &mockTagRepo{
+			listByIDsFn: func(_ context.Context, _ string, ids []string) ([]model.Tag, error) {
+				assert.Equal(t, []string{"go"}, ids)
+				return []model.Tag{{ID: "go", Name: "go"}}, nil
+			},
+		}
+		svc.embedding = &suggestionEmbeddingClient{
+			neighbourIDs:    []string{"n1"},
+			neighbourScores: []float32{0.8},
+			tags:            []model.SimilarTagResult{{TagID: "go", Score: 0.6}, {TagID: "rust", Score: 0.1}},
+			status:          "ready",
+		}
+		result, err := svc.SuggestTags(context.Background(), "u1", "d1", 1)
+		require.NoError(t, err)
+		assert.Equal(t, "ready", result.IndexStatus)
+		require.Len(t, result.Suggestions, 1)
+		assert.Equal(t, "go", result.Suggestions[0].Tag.Name)
+		assert.InDelta(t, 0.8, result.Suggestions[0].Score, 0.0001)
+	})
+}
+
+func newTagSuggestionApplySvc(written map[string][]string, status string) *DocumentService {
+	docs := &mockDocumentRepo{
+		getByIDFn: func(_ context.Context, _, docID string) (*model.Document, error) {
+			if docID == "missing" {
+				return nil, appErr.ErrNotFound
+			}
+			return &model.Document{ID: docID}, nil
+		},
+	}
+	docTags := &mockDocumentTagRepo{
+		listTagIDsFn: func(_ context.Context, _, docID string) ([]string, error) {
+			return written[docID], nil
+		},
+		listTagIDsByDocIDsFn: func(context.Context, string, []string) (map[string][]string, error) {
+			return map[string][]string{"n1": {"go", "db"}}, nil
+		},
+		deleteByDocFn: func(_ context.Context, _, docID string) error {
+			written[docID] = nil
+			return nil
+		},
+		addFn: func(_ context.Context, docTag *model.DocumentTag) error {
+			written[docTag.DocumentID] = append(written[docTag.DocumentID], docTag.TagID)
+			return nil
+		},
+	}
+	svc := newDocSvc(docs, nil, docTags, nil)
+	svc.tagRepo = &mockTagRepo{
+		listByIDsFn: func(_ context.Context, _ string, ids []string) ([]model.Tag, error) {
+			tags := make([]model.Tag, 0, len(ids))
+			for _, id := range ids {
+				tags = append(tags, model.Tag{ID: id, Name: id})
+			}
+			return tags, nil
+		},
+	}
+	// go scores 0.8, db 0.5 and rust 0.05.
+	svc.embedding = &suggestionEmbeddingClient{
+		neighbourIDs:    []string{"n1"},
+		neighbourScores: []float32{0.8},
+		tags:            []model.SimilarTagResult{{TagID: "go", Score: 0.6}, {TagID: "rust", Score: 0.1}},
+		status:          status,
+	}
+	return svc
+}
+
+func TestDocumentService_ApplyTagSuggestions(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("min_score", func(t *testing.T) {
+		written := map[string][]string{"d1": {"owned"}}
+		svc := newTagSuggestionApplySvc(written, "ready")
+		results, err := svc.ApplyTagSuggestions(ctx, "u1", TagSuggestionApply{
+			DocIDs: []string{"d1", "missing", "d2", "d1"}, MinScore: 0.6,
+		})
+		require.NoError(t, err)
+		require.Len(t, results, 3)
+		assert.Equal(t, "d1", results[0].ID)
+		assert.NoError(t, results[0].Err)
+		assert.Equal(t, []string{"go"}, results[0].TagIDs)
+		assert.ErrorIs(t, results[1].Err, appErr.ErrNotFound)
+		assert.Nil(t, results[1].TagIDs)
+		assert.NoError(t, results[2].Err)
+		assert.Equal(t, map[string][]string{"d1": {"owned", "go"}, "d2": {"go"}}, written)
+	})
+
+	t.Run("top_n", func(t *testing.T) {
+		written := map[string][]string{}
+		svc := newTagSuggestionApplySvc(written, "ready")
+		results, err := svc.ApplyTagSuggestions(ctx, "u1", TagSuggestionApply{DocIDs: []string{"d1"}, Limit: 2})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"go", "db"}, results[0].TagIDs)
+		assert.Equal(t, []string{"go", "db"}, written["d1"])
+	})
+
+	t.Run("nothing_clears_min_score", func(t *testing.T) {
+		written := map[string][]string{}
+		svc := newTagSuggestionApplySvc(written, "ready")
+		results, err := svc.ApplyTagSuggestions(ctx, "u1", TagSuggestionApply{DocIDs: []string{"d1"}, MinScore: 0.9})
+		require.NoError(t, err)
+		assert.NoError(t, results[0].Err)
+		assert.Empty(t, results[0].TagIDs)
+		assert.Empty(t, written)
+	})
+
+	t.Run("index_not_ready", func(t *testing.T) {
+		written := map[string][]string{}
+		svc := newTagSuggestionApplySvc(written, "pending")
+		results, err := svc.ApplyTagSuggestions(ctx, "u1", TagSuggestionApply{DocIDs: []string{"d1"}, Limit: 5})
+		require.NoError(t, err)
+		assert.ErrorIs(t, results[0].Err, appErr.ErrConflict)
+		assert.Empty(t, written)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		svc := newTagSuggestionApplySvc(map[string][]string{}, "ready")
+		tests := []TagSuggestionApply{
+			{DocIDs: []string{"d1"}},
+			{DocIDs: []string{"d1"}, MinScore: 1.5},
+			{DocIDs: []string{"d1"}, Limit: maxTagSuggestions + 1},
+			{DocIDs: []string{" "}, Limit: 5},
+		}
+		for _, req := range tests {
+			_, err := svc.ApplyTagSuggestions(ctx, "u1", req)
+			assert.ErrorIs(t, err, appErr.ErrInvalid)
+		}
+	})
+
+	t.Run("read_only_workspace", func(t *testing.T) {
+		svc := newTagSuggestionApplySvc(map[string][]string{}, "ready")
+		viewer := WithWorkspaceAccess(ctx, WorkspaceAccess{
+			WorkspaceID: "w1", UserID: "u2", Role: model.WorkspaceRoleViewer,
+		})
+		_, err := svc.ApplyTagSuggestions(viewer, "w1", TagSuggestionApply{DocIDs: []string{"d1"}, Limit: 5})
+		assert.ErrorIs(t, err, appErr.ErrForbidden)
+	})
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xxxsen/mnote/internal/model"
+)
+
+// taskDueDateRegex matches an @YYYY-MM-DD due date written in a task item.
+var taskDueDateRegex = regexp.MustCompile(`(?:^|\s)@(\d{4}-\d{2}-\d{2})\b`)
+
+// documentTask is a task list item of a document. Mark is the byte offset of
+// its checkbox mark in the document, the one byte a write-back replaces.
+type documentTask struct {
+	Anchor  string
+	Line    int
+	Content string
+	DueDate string
+	Done    bool
+	Mark    int
+}
+
+// documentTasks returns the task list items of content in document order,
+// skipping front matter, fenced code and items without text. The anchor of
+// an item is derived from its text alone, so it survives lines moving, the
+// box being ticked and the due date changing; repeated texts are numbered.
+func documentTasks(content string) []documentTask {
+	tasks := make([]documentTask, 0)
+	seen := make(map[string]int)
+	for _, line := range markdownProseLines(content) {
+		m := taskItemRegex.FindStringSubmatchIndex(line.Text)
+		if m == nil {
+			continue
+		}
+		text, due := taskDueDate(line.Text[m[1]:])
+		text = strings.Join(strings.Fields(text), " ")
+		if text == "" {
+			continue
+		}
+		if runes := []rune(text); len(runes) > maxTodoContentRunes {
+			text = string(runes[:maxTodoContentRunes])
+		}
+		key := strings.ToLower(text)
+		sum := sha256.Sum256([]byte(key))
+		anchor := hex.EncodeToString(sum[:6])
+		if n := seen[key]; n > 0 {
+			anchor += "-" + strconv.Itoa(n)
+		}
+		seen[key]++
+		tasks = append(tasks, documentTask{
+			Anchor: anchor, Line: line.Line, Content: text, DueDate: due,
+			Done: line.Text[m[2]] != ' ', Mark: line.Offset + m[2],
+		})
+	}
+	return tasks
+}
+
+// taskDueDate cuts the last valid @YYYY-MM-DD out of the text of a task item
+// and returns the remaining text with the date.
+func taskDueDate(text string) (string, string) {
+	matches := taskDueDateRegex.FindAllStringSubmatchIndex(text, -1)
+	for i := len(matches) - 1; i >= 0; i-- {
+		m := matches[i]
+		date := text[m[2]:m[3]]
+		if _, err := time.Parse(todoDateLayout, date); err == nil {
+			return text[:m[0]] + " " + text[m[1]:], date
+		}
+	}
+	return text, ""
+}
+
+// syncTasks makes the task list items of content the synced todos of docID.
+// Nothing is written when the items still match their todos.
+func (s *DocumentService) syncTasks(ctx context.Context, userID, docID, content string, now int64) error {
+	existing, err := s.docs.ListTasks(ctx, userID, docID)
+	if err != nil {
+		return fmt.Errorf("list tasks: %w", err)
+	}
+	tasks := documentTasks(content)
+	todos, changed, err := s.taskTodos(existing, tasks, pairTasks(existing, tasks), now)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		return nil
+	}
+	if err := s.docs.ReplaceTasks(ctx, userID, docID, todos); err != nil {
+		return fmt.Errorf("replace tasks: %w", err)
+	}
+	return nil
+}
+
+// pairTasks returns, for each task, the index of the existing todo it
+// updates or -1 for a new one. Tasks pair by anchor first and the rest by
+// line, so editing the text of an item in place keeps its todo and with it
+// the priority and subtasks set on the todo list.
+func pairTasks(existing []model.Todo, tasks []documentTask) []int {
+	pairs := make([]int, len(tasks))
+	used := make([]bool, len(existing))
+	byAnchor := make(map[string]int, len(existing))
+	for i, todo := range existing {
+		byAnchor[todo.SourceAnchor] = i
+	}
+	for i, task := range tasks {
+		pairs[i] = -1
+		if j, ok := byAnchor[task.Anchor]; ok {
+			pairs[i], used[j] = j, true
+		}
+	}
+	byLine := make(map[int]int)
+	for j, todo := range existing {
+		if !used[j] {
+			byLine[todo.SourceLine] = j
+		}
+	}
+	for i, task := range tasks {
+		if j, ok := byLine[task.Line]; ok && pairs[i] < 0 && !used[j] {
+			pairs[i], used[j] = j, true
+		}
+	}
+	return pairs
+}
+
+func (s *DocumentService) taskTodos(
+	existing []model.Todo, tasks []documentTask, pairs []int, now int64,
+) ([]model.Todo, bool, error) {
+	changed := len(tasks) != len(existing)
+	todos := make([]model.Todo, 0, len(tasks))
+	for i, task := range tasks {
+		todo := model.Todo{Ctime: now}
+		if pairs[i] >= 0 {
+			todo = existing[pairs[i]]
+		} else {
+			id, err := s.runtime.IDs.ID()
+			if err != nil {
+				return nil, false, fmt.Errorf("generate todo id: %w", err)
+			}
+			todo.ID = id
+		}
+		done := 0
+		if task.Done {
+			done = 1
+		}
+		if todo.Content != task.Content || todo.DueDate != task.DueDate || todo.Done != done ||
+			todo.SourceAnchor != task.Anchor || todo.SourceLine != task.Line {
+			todo.Content, todo.DueDate, todo.Done = task.Content, task.DueDate, done
+			todo.SourceAnchor, todo.SourceLine, todo.Mtime = task.Anchor, task.Line, now
+			changed = true
+		}
+		todos = append(todos, todo)
+	}
+	return todos, changed, nil
+}
+
+// setTaskMark returns content with the checkbox of task ticked or cleared.
+func setTaskMark(content string, task documentTask, done bool) string {
+	mark := " "
+	if done {
+		mark = "x"
+	}
+	return content[:task.Mark] + mark + content[task.Mark+1:]
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+)
+
+const taskContent = "---\ntodo: - [ ] not a task\n---\n" +
+	"- [ ] Write the draft @2025-11-01\n" +
+	"1. [x] Book   the room\n" +
+	"```\n- [ ] in code\n```\n" +
+	"- [ ] due @2025-02-30 stays text\n" +
+	"- [ ]\n" +
+	"* [X] book the room\n"
+
+func TestDocumentTasks(t *testing.T) {
+	tasks := documentTasks(taskContent)
+	require.Len(t, tasks, 4)
+
+	assert.Equal(t, "Write the draft", tasks[0].Content)
+	assert.Equal(t, "2025-11-01", tasks[0].DueDate)
+	assert.Equal(t, 4, tasks[0].Line)
+	assert.False(t, tasks[0].Done)
+	assert.Equal(t, byte(' '), taskContent[tasks[0].Mark])
+
+	assert.Equal(t, "Book the room", tasks[1].Content)
+	assert.True(t, tasks[1].Done)
+	assert.Equal(t, "due @2025-02-30 stays text", tasks[2].Content, "invalid dates stay in the text")
+	assert.Empty(t, tasks[2].DueDate)
+
+	assert.True(t, tasks[3].Done)
+	assert.Equal(t, tasks[1].Anchor+"-1", tasks[3].Anchor, "repeated texts are numbered")
+
+	moved := documentTasks("intro\n\n- [x] write the draft @2025-12-01\n")
+	require.Len(t, moved, 1)
+	assert.Equal(t, tasks[0].Anchor, moved[0].Anchor, "anchor ignores line, box and due date")
+}
+
+func TestSetTaskMark(t *testing.T) {
+	content := "a\n- [ ] one\n- [x] two\n"
+	tasks := documentTasks(content)
+	require.Len(t, tasks, 2)
+	assert.Equal(t, "a\n- [x] one\n- [x] two\n", setTaskMark(content, tasks[0], true))
+	assert.Equal(t, "a\n- [ ] one\n- [ ] two\n", setTaskMark(content, tasks[1], false))
+}
+
+func TestPairTasks(t *testing.T) {
+	tasks := documentTasks("- [ ] keep\n- [ ] renamed\n- [ ] new\n")
+	existing := []model.Todo{
+		{ID: "t-gone", SourceAnchor: "gone", SourceLine: 9},
+		{ID: "t-renamed", SourceAnchor: "old", SourceLine: 2},
+		{ID: "t-keep", SourceAnchor: tasks[0].Anchor, SourceLine: 5},
+	}
+	assert.Equal(t, []int{2, 1, -1}, pairTasks(existing, tasks))
+}
+
+func TestDocumentService_Save_SyncsTasks(t *testing.T) {
+	docs := newWikilinkDocs(map[string]model.Document{"d1": {Title: "Plan"}}, nil)
+	docs.updateLinksFn = func(context.Context, string, string, []model.LinkTarget, int64) error { return nil }
+	content := "- [ ] write @2025-11-01\n- [x] review\n"
+	tasks := documentTasks(content)
+	docs.listTasksFn = func(context.Context, string, string) ([]model.Todo, error) {
+		return []model.Todo{
+			{ID: "t1", Content: "write", Priority: model.TodoPriorityHigh, SourceAnchor: tasks[0].Anchor,
+				SourceLine: 3, Ctime: 5, Mtime: 5},
+			{ID: "t-old", Content: "dropped", SourceAnchor: "gone", SourceLine: 9},
+		}, nil
+	}
+	var replaced []model.Todo
+	replaceCalls := 0
+	docs.replaceTasksFn = func(_ context.Context, userID, docID string, got []model.Todo) error {
+		assert.Equal(t, "u1", userID)
+		assert.Equal(t, "d1", docID)
+		replaced = got
+		replaceCalls++
+		return nil
+	}
+	svc := newDocSvc(docs, newWikilinkVersions(), nil, nil)
+
+	_, err := svc.Save(context.Background(), "u1", "d1", DocumentUpdateInput{Title: "Plan", Content: content})
+	require.NoError(t, err)
+	require.Equal(t, 1, replaceCalls)
+	require.Len(t, replaced, 2)
+	assert.Equal(t, "t1", replaced[0].ID)
+	assert.Equal(t, model.TodoPriorityHigh, replaced[0].Priority)
+	assert.Equal(t, "2025-11-01", replaced[0].DueDate)
+	assert.Equal(t, 1, replaced[0].SourceLine)
+	assert.Equal(t, int64(5), replaced[0].Ctime)
+	assert.NotEmpty(t, replaced[1].ID)
+	assert.Equal(t, "review", replaced[1].Content)
+	assert.Equal(t, 1, replaced[1].Done)
+
+	docs.listTasksFn = func(context.Context, string, string) ([]model.Todo, error) {
+		return replaced, nil
+	}
+	_, err = svc.Save(context.Background(), "u1", "d1", DocumentUpdateInput{Title: "Plan", Content: content})
+	require.NoError(t, err)
+	assert.Equal(t, 1, replaceCalls, "unchanged tasks are not rewritten")
+}
+
+func TestDocumentService_Delete_RemovesTasks(t *testing.T) {
+	docs := newWikilinkDocs(map[string]model.Document{"d1": {Title: "Plan"}}, nil)
+	removed := false
+	docs.replaceTasksFn = func(_ context.Context, _, docID string, got []model.Todo) error {
+		removed = docID == "d1" && len(got) == 0
+		return nil
+	}
+	svc := newDocSvc(docs, newWikilinkVersions(), &mockDocumentTagRepo{
+		deleteByDocFn: func(context.Context, string, string) error { return nil },
+	}, &mockShareRepo{
+		revokeByDocumentFn: func(context.Context, string, string, int64) error { return nil },
+	})
+
+	require.NoError(t, svc.Delete(context.Background(), "u1", "d1"))
+	assert.True(t, removed)
+}
@@ -13,10 +13,11 @@ func (
 	docID string) ([]model.DocumentVersionSummary,
 	error,
 ) {
-	if _, err := s.docs.GetByID(ctx, userID, docID); err != nil {
-		return nil, fmt.Errorf("get by id: %w", err)
+	_, ownerID, err := s.readableDocument(ctx, userID, docID)
+	if err != nil {
+		return nil, err
 	}
-	v0, err := s.versions.ListSummaries(ctx, userID, docID)
+	v0, err := s.versions.ListSummaries(ctx, ownerID, docID)
 	if err != nil {
 		return nil, fmt.Errorf("list summaries: %w", err)
 	}
@@ -30,10 +31,11 @@ func (
 	version int) (*model.DocumentVersion,
 	error,
 ) {
-	if _, err := s.docs.GetByID(ctx, userID, docID); err != nil {
-		return nil, fmt.Errorf("get by id: %w", err)
+	_, ownerID, err := s.readableDocument(ctx, userID, docID)
+	if err != nil {
+		return nil, err
 	}
-	v0, err := s.versions.GetByVersion(ctx, userID, docID, version)
+	v0, err := s.versions.GetByVersion(ctx, ownerID, docID, version)
 	if err != nil {
 		return nil, fmt.Errorf("get by version: %w", err)
 	}
@@ -0,0 +1,348 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+var wikilinkRegex = regexp.MustCompile(`\[\[([^\[\]\n]+)\]\]`)
+
+// wikilinkRef is one parsed [[Title#anchor|alias]]. The alias only changes
+// how the link renders, so it is dropped.
+type wikilinkRef struct {
+	Title  string
+	Key    string
+	Anchor string
+}
+
+// Wikilink is a recorded wikilink of a document. Candidates lists the live
+// documents sharing an ambiguous title so the author can disambiguate.
+type Wikilink struct {
+	model.DocumentWikilink
+	Candidates []model.DocumentTitle
+}
+
+// wikilinkTitleKey folds a title the way titles are matched: trimmed and
+// case-insensitive, mirroring lower(btrim(title)) in the repository.
+func wikilinkTitleKey(title string) string {
+	return strings.ToLower(strings.TrimSpace(title))
+}
+
+// wikilinkRowKey is what identifies a recorded wikilink of a document.
+type wikilinkRowKey struct {
+	Key    string
+	Anchor string
+}
+
+// extractWikilinks returns the distinct wikilinks of content outside code
+// fences and inline code, where [[ ... ]] is usually shell or array syntax.
+// Links differing only in the case of their title are one record, the first
+// spelling kept, as titles are matched case-insensitively.
+func extractWikilinks(content string) []wikilinkRef {
+	refs := make([]wikilinkRef, 0)
+	seen := make(map[wikilinkRowKey]struct{})
+	for _, line := range markdownProseLines(content) {
+		if !strings.Contains(line.Text, "[[") {
+			continue
+		}
+		text := inlineCodeRegex.ReplaceAllString(line.Text, "")
+		for _, m := range wikilinkRegex.FindAllStringSubmatch(text, -1) {
+			ref, ok := parseWikilink(m[1])
+			if !ok {
+				continue
+			}
+			key := wikilinkRowKey{Key: ref.Key, Anchor: ref.Anchor}
+			if _, dup := seen[key]; dup {
+				continue
+			}
+			seen[key] = struct{}{}
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// parseWikilink splits the inside of [[...]]. A link without a title, such as
+// [[#Heading]], points into the same document and is not recorded.
+func parseWikilink(inner string) (wikilinkRef, bool) {
+	target, _, _ := strings.Cut(inner, "|")
+	title, anchor, _ := strings.Cut(target, "#")
+	title = strings.TrimSpace(title)
+	if title == "" || utf8.RuneCountInString(title) > 200 {
+		return wikilinkRef{}, false
+	}
+	return wikilinkRef{Title: title, Key: wikilinkTitleKey(title), Anchor: strings.TrimSpace(anchor)}, true
+}
+
+// resolvedLinks is what the references of one document resolve to: the
+// link rows, the wikilink records explaining each title, and the /docs/<id>
+// targets that are deleted, missing or outside the scope.
+type resolvedLinks struct {
+	Targets   []model.LinkTarget
+	Wikilinks []model.DocumentWikilink
+	Broken    []string
+}
+
+// resolveLinks turns the /docs/<id> links and wikilinks of content into the
+// link rows of docID and the wikilink records explaining each title. A title
+// matching several documents is recorded as ambiguous and links nowhere. The
+// first anchored wikilink to a target sets the anchor of its link row.
+func (s *DocumentService) resolveLinks(
+	ctx context.Context, userID, docID, content string, now int64,
+) (*resolvedLinks, error) {
+	linked := extractLinkIDs(content)
+	ids, err := s.validateOwnedLinkIDs(ctx, userID, docID, linked)
+	if err != nil {
+		return nil, err
+	}
+	resolved := &resolvedLinks{
+		Targets:   make([]model.LinkTarget, 0, len(ids)),
+		Wikilinks: []model.DocumentWikilink{},
+		Broken:    []string{},
+	}
+	index := make(map[string]int, len(ids))
+	for _, id := range ids {
+		index[id] = len(resolved.Targets)
+		resolved.Targets = append(resolved.Targets, model.LinkTarget{ID: id})
+	}
+	for _, id := range uniqueStringSlice(linked) {
+		if _, ok := index[id]; !ok && id != docID {
+			resolved.Broken = append(resolved.Broken, id)
+		}
+	}
+	refs := extractWikilinks(content)
+	if len(refs) == 0 {
+		return resolved, nil
+	}
+	keys := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		keys = append(keys, ref.Key)
+	}
+	matches, err := s.docs.ListByTitleKeys(ctx, userID, uniqueStringSlice(keys))
+	if err != nil {
+		return nil, fmt.Errorf("resolve wikilinks: %w", err)
+	}
+	byKey := wikilinkCandidates(matches)
+	for _, ref := range refs {
+		link := model.DocumentWikilink{
+			SourceID: docID, Title: ref.Title, TitleKey: ref.Key,
+			Anchor: ref.Anchor, Status: model.WikilinkStatusMissing, Ctime: now,
+		}
+		if candidates := byKey[ref.Key]; len(candidates) > 1 {
+			link.Status = model.WikilinkStatusAmbiguous
+		} else if len(candidates) == 1 {
+			link.Status = model.WikilinkStatusResolved
+			link.TargetID = candidates[0].ID
+			if i, ok := index[link.TargetID]; ok {
+				if resolved.Targets[i].Anchor == "" {
+					resolved.Targets[i].Anchor = ref.Anchor
+				}
+			} else {
+				index[link.TargetID] = len(resolved.Targets)
+				resolved.Targets = append(resolved.Targets, model.LinkTarget{ID: link.TargetID, Anchor: ref.Anchor})
+			}
+		}
+		resolved.Wikilinks = append(resolved.Wikilinks, link)
+	}
+	return resolved, nil
+}
+
+// wikilinkCandidates groups the documents a wikilink title can name by key.
+// A document carrying the title outranks one that only has it as an alias,
+// so adding an alias never makes a resolved title ambiguous.
+func wikilinkCandidates(matches []model.DocumentTitle) map[string][]model.DocumentTitle {
+	byTitle := make(map[string][]model.DocumentTitle)
+	byAlias := make(map[string][]model.DocumentTitle)
+	for _, match := range matches {
+		if match.Alias {
+			byAlias[match.Key] = append(byAlias[match.Key], match)
+		} else {
+			byTitle[match.Key] = append(byTitle[match.Key], match)
+		}
+	}
+	for key, items := range byAlias {
+		if _, ok := byTitle[key]; !ok {
+			byTitle[key] = items
+		}
+	}
+	return byTitle
+}
+
+// syncLinks rewrites the link rows, wikilink records and broken references of
+// docID from content.
+func (s *DocumentService) syncLinks(ctx context.Context, userID, docID, content string, now int64) error {
+	resolved, err := s.resolveLinks(ctx, userID, docID, content, now)
+	if err != nil {
+		return err
+	}
+	if err := s.docs.UpdateLinks(ctx, userID, docID, resolved.Targets, now); err != nil {
+		return fmt.Errorf("update links: %w", err)
+	}
+	if err := s.docs.ReplaceWikilinks(ctx, userID, docID, resolved.Wikilinks); err != nil {
+		return fmt.Errorf("replace wikilinks: %w", err)
+	}
+	if err := s.docs.ReplaceBrokenLinks(ctx, userID, docID, resolved.Broken, now); err != nil {
+		return fmt.Errorf("replace broken links: %w", err)
+	}
+	return nil
+}
+
+// wikilinkSources returns, in id order, the other documents whose wikilinks
+// name one of titles or currently resolve to docID.
+func (s *DocumentService) wikilinkSources(
+	ctx context.Context, userID, docID string, titles []string,
+) ([]string, error) {
+	keys := make([]string, 0, len(titles))
+	for _, title := range titles {
+		if key := wikilinkTitleKey(title); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	sources, err := s.docs.ListWikilinkSources(ctx, userID, uniqueStringSlice(keys), docID)
+	if err != nil {
+		return nil, fmt.Errorf("list wikilink sources: %w", err)
+	}
+	sources = slices.DeleteFunc(uniqueStringSlice(sources), func(id string) bool { return id == docID })
+	sort.Strings(sources)
+	return sources, nil
+}
+
+// lockWikilinkSet locks docID together with the documents its titles are
+// linked from, all in id order, before the caller takes its own row lock.
+// Renames and deletes re-resolve each other's sources, so taking these locks
+// in one order keeps two of them from deadlocking. Sources gained after this
+// read are locked by reresolveWikilinks.
+func (s *DocumentService) lockWikilinkSet(ctx context.Context, userID, docID string, titles []string) error {
+	sources, err := s.wikilinkSources(ctx, userID, docID, titles)
+	if err != nil {
+		return err
+	}
+	ids := append(sources, docID)
+	sort.Strings(ids)
+	for _, id := range ids {
+		if _, err := s.docs.GetByIDForUpdate(ctx, userID, id); err != nil && !errors.Is(err, appErr.ErrNotFound) {
+			return fmt.Errorf("lock wikilink source: %w", err)
+		}
+	}
+	return nil
+}
+
+// documentNames returns the title and aliases docID is linked by, read
+// before the document is locked.
+func (s *DocumentService) documentNames(ctx context.Context, userID, docID string) ([]string, error) {
+	doc, err := s.docs.GetByID(ctx, userID, docID)
+	if err != nil {
+		return nil, fmt.Errorf("get document: %w", err)
+	}
+	props, err := s.docs.ListProperties(ctx, userID, docID)
+	if err != nil {
+		return nil, fmt.Errorf("list properties: %w", err)
+	}
+	return append([]string{doc.Title}, propertyAliases(props)...), nil
+}
+
+// lockRenameSet runs ahead of lockForSave. When input renames docID or
+// changes its aliases, it locks the sources of the old and new names with
+// the document, in id order; other saves lock only their own row.
+func (s *DocumentService) lockRenameSet(
+	ctx context.Context, userID, docID string, input DocumentUpdateInput,
+) error {
+	ownerID := userID
+	current, err := s.docs.GetByID(ctx, userID, docID)
+	if err != nil {
+		ownerID, err = s.collaboratorOwner(ctx, userID, docID, true, err)
+		if err != nil {
+			return fmt.Errorf("get document: %w", err)
+		}
+		if current, err = s.docs.GetByID(ctx, ownerID, docID); err != nil {
+			return fmt.Errorf("get shared document: %w", err)
+		}
+	}
+	props, err := s.docs.ListProperties(ctx, ownerID, docID)
+	if err != nil {
+		return fmt.Errorf("list properties: %w", err)
+	}
+	var names []string
+	oldAliases, newAliases := propertyAliases(props), propertyAliases(parseProperties(input.Content))
+	if !sameTitleKeys(oldAliases, newAliases) {
+		names = append(append(names, oldAliases...), newAliases...)
+	}
+	if wikilinkTitleKey(current.Title) != wikilinkTitleKey(input.Title) {
+		names = append(names, current.Title, input.Title)
+	}
+	if len(names) == 0 {
+		return nil
+	}
+	return s.lockWikilinkSet(ctx, ownerID, docID, names)
+}
+
+// reresolveWikilinks re-runs link resolution for every other document whose
+// wikilinks name one of titles or currently resolve to docID. It is called
+// after docID was created, renamed, re-aliased or deleted, inside the same
+// transaction; sources are locked in id order, normally already held from
+// lockWikilinkSet.
+func (s *DocumentService) reresolveWikilinks(
+	ctx context.Context, userID, docID string, titles []string, now int64,
+) error {
+	sources, err := s.wikilinkSources(ctx, userID, docID, titles)
+	if err != nil {
+		return err
+	}
+	for _, sourceID := range sources {
+		source, err := s.docs.GetByIDForUpdate(ctx, userID, sourceID)
+		if errors.Is(err, appErr.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("lock wikilink source: %w", err)
+		}
+		if err := s.syncLinks(ctx, userID, sourceID, source.Content, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListWikilinks returns the wikilinks of a document with their resolution.
+// Ambiguous links carry the documents their title matches.
+func (s *DocumentService) ListWikilinks(ctx context.Context, userID, docID string) ([]Wikilink, error) {
+	if _, err := s.docs.GetByID(ctx, userID, docID); err != nil {
+		return nil, fmt.Errorf("get document: %w", err)
+	}
+	links, err := s.docs.ListWikilinks(ctx, userID, docID)
+	if err != nil {
+		return nil, fmt.Errorf("list wikilinks: %w", err)
+	}
+	var ambiguous []string
+	for _, link := range links {
+		if link.Status == model.WikilinkStatusAmbiguous {
+			ambiguous = append(ambiguous, link.TitleKey)
+		}
+	}
+	candidates := make(map[string][]model.DocumentTitle)
+	if len(ambiguous) > 0 {
+		matches, err := s.docs.ListByTitleKeys(ctx, userID, uniqueStringSlice(ambiguous))
+		if err != nil {
+			return nil, fmt.Errorf("list wikilink candidates: %w", err)
+		}
+		candidates = wikilinkCandidates(matches)
+	}
+	items := make([]Wikilink, 0, len(links))
+	for _, link := range links {
+		item := Wikilink{DocumentWikilink: link, Candidates: []model.DocumentTitle{}}
+		if link.Status == model.WikilinkStatusAmbiguous && candidates[link.TitleKey] != nil {
+			item.Candidates = candidates[link.TitleKey]
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
@@ -0,0 +1,280 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+func TestExtractWikilinks(t *testing.T) {
+	content := "See [[Alpha]], [[ alpha | the alpha ]] and [[Beta#Setup|setup]].\n" +
+		"Block ref [[Beta#^b1]] and self heading [[#Local]].\n" +
+		"Inline `[[Code]]` stays code.\n" +
+		"```bash\nif [[ -f x ]]; then echo; fi\n```\n" +
+		"~~~~\n[[Fenced]]\n~~~\n~~~~\n" +
+		"[[Gamma]]"
+	assert.Equal(t, []wikilinkRef{
+		{Title: "Alpha", Key: "alpha"},
+		{Title: "Beta", Key: "beta", Anchor: "Setup"},
+		{Title: "Beta", Key: "beta", Anchor: "^b1"},
+		{Title: "Gamma", Key: "gamma"},
+	}, extractWikilinks(content))
+}
+
+func newWikilinkDocs(contents map[string]model.Document, titles []model.DocumentTitle) *mockDocumentRepo {
+	return &mockDocumentRepo{
+		createFn: func(context.Context, *model.Document) error { return nil },
+		updateFn: func(context.Context, *model.Document) error { return nil },
+		deleteFn: func(context.Context, string, string, int64) error { return nil },
+		getByIDForUpdateFn: func(_ context.Context, userID, docID string) (*model.Document, error) {
+			doc, ok := contents[docID]
+			if !ok {
+				return nil, appErr.ErrNotFound
+			}
+			doc.ID, doc.UserID = docID, userID
+			return &doc, nil
+		},
+		listByTitleKeysFn: func(_ context.Context, _ string, keys []string) ([]model.DocumentTitle, error) {
+			var out []model.DocumentTitle
+			for _, title := range titles {
+				for _, key := range keys {
+					if wikilinkTitleKey(title.Title) == key {
+						title.Key = key
+						out = append(out, title)
+					}
+				}
+			}
+			return out, nil
+		},
+	}
+}
+
+func newWikilinkVersions() *mockVersionRepo {
+	return &mockVersionRepo{
+		createFn:            func(context.Context, *model.DocumentVersion) error { return nil },
+		deleteOldVersionsFn: func(context.Context, string, string, int) error { return nil },
+	}
+}
+
+func TestDocumentService_Save_ResolvesWikilinks(t *testing.T) {
+	docs := newWikilinkDocs(map[string]model.Document{"d1": {Title: "Notes"}}, []model.DocumentTitle{
+		{ID: "d2", Title: "Alpha"}, {ID: "d4", Title: "Beta"}, {ID: "d5", Title: "beta"}, {ID: "d3", Title: "Linked"},
+	})
+	var targets []model.LinkTarget
+	var wikilinks []model.DocumentWikilink
+	docs.updateLinksFn = func(_ context.Context, _, sourceID string, got []model.LinkTarget, _ int64) error {
+		assert.Equal(t, "d1", sourceID)
+		targets = got
+		return nil
+	}
+	docs.replaceWikilinksFn = func(_ context.Context, _, _ string, got []model.DocumentWikilink) error {
+		wikilinks = got
+		return nil
+	}
+	svc := newDocSvc(docs, newWikilinkVersions(), nil, nil)
+
+	_, err := svc.Save(context.Background(), "u1", "d1", DocumentUpdateInput{
+		Title:   "Notes",
+		Content: "/docs/d3 then [[ALPHA#Intro|see]] [[Linked#Usage]] [[Beta]] [[Gamma]]",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []model.LinkTarget{{ID: "d3", Anchor: "Usage"}, {ID: "d2", Anchor: "Intro"}}, targets)
+	require.Len(t, wikilinks, 4)
+	assert.Equal(t, model.WikilinkStatusResolved, wikilinks[0].Status)
+	assert.Equal(t, "d2", wikilinks[0].TargetID)
+	assert.Equal(t, "Intro", wikilinks[0].Anchor)
+	assert.Equal(t, model.WikilinkStatusAmbiguous, wikilinks[2].Status)
+	assert.Empty(t, wikilinks[2].TargetID)
+	assert.Equal(t, model.WikilinkStatusMissing, wikilinks[3].Status)
+}
+
+func TestDocumentService_Save_RenameReresolvesWikilinks(t *testing.T) {
+	docs := newWikilinkDocs(map[string]model.Document{
+		"d1":  {Title: "Old"},
+		"src": {Content: "[[New#Top]]"},
+	}, []model.DocumentTitle{{ID: "d1", Title: "New"}})
+	docs.listWikiSourcesFn = func(_ context.Context, _ string, keys []string, targetID string) ([]string, error) {
+		assert.ElementsMatch(t, []string{"old", "new"}, keys)
+		assert.Equal(t, "d1", targetID)
+		return []string{"d1", "gone", "src"}, nil
+	}
+	synced := make(map[string][]model.LinkTarget)
+	docs.updateLinksFn = func(_ context.Context, _, sourceID string, got []model.LinkTarget, _ int64) error {
+		synced[sourceID] = got
+		return nil
+	}
+	svc := newDocSvc(docs, newWikilinkVersions(), nil, nil)
+
+	_, err := svc.Save(context.Background(), "u1", "d1", DocumentUpdateInput{Title: "New", Content: "body"})
+	require.NoError(t, err)
+	assert.Equal(t, []model.LinkTarget{{ID: "d1", Anchor: "Top"}}, synced["src"])
+	assert.Len(t, synced, 2)
+}
+
+func TestDocumentService_Save_RenameLocksInIDOrder(t *testing.T) {
+	docs := newWikilinkDocs(map[string]model.Document{
+		"d5": {Title: "Old"},
+		"a1": {Content: "[[Old]]"},
+		"z9": {Content: "[[New]]"},
+	}, nil)
+	docs.listWikiSourcesFn = func(context.Context, string, []string, string) ([]string, error) {
+		return []string{"z9", "d5", "a1"}, nil
+	}
+	var locked []string
+	lock := docs.getByIDForUpdateFn
+	docs.getByIDForUpdateFn = func(ctx context.Context, userID, docID string) (*model.Document, error) {
+		locked = append(locked, docID)
+		return lock(ctx, userID, docID)
+	}
+	docs.getByIDFn = lock
+	docs.updateLinksFn = func(context.Context, string, string, []model.LinkTarget, int64) error { return nil }
+	svc := newDocSvc(docs, newWikilinkVersions(), nil, nil)
+
+	_, err := svc.Save(context.Background(), "u1", "d5", DocumentUpdateInput{Title: "New", Content: "body"})
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, len(locked), 3)
+	assert.Equal(t, []string{"a1", "d5", "z9"}, locked[:3])
+}
+
+func TestDocumentService_Save_CaseOnlyDuplicateWikilinks(t *testing.T) {
+	docs := newWikilinkDocs(map[string]model.Document{"d1": {Title: "Notes"}}, []model.DocumentTitle{
+		{ID: "d2", Title: "Design"},
+	})
+	docs.updateLinksFn = func(context.Context, string, string, []model.LinkTarget, int64) error { return nil }
+	var wikilinks []model.DocumentWikilink
+	docs.replaceWikilinksFn = func(_ context.Context, _, _ string, got []model.DocumentWikilink) error {
+		wikilinks = got
+		return nil
+	}
+	svc := newDocSvc(docs, newWikilinkVersions(), nil, nil)
+
+	_, err := svc.Save(context.Background(), "u1", "d1", DocumentUpdateInput{
+		Title: "Notes", Content: "[[Design]] and [[design]], then [[DESIGN#Goals]]",
+	})
+	require.NoError(t, err)
+	require.Len(t, wikilinks, 2)
+	assert.Equal(t, "Design", wikilinks[0].Title)
+	assert.Equal(t, "Goals", wikilinks[1].Anchor)
+}
+
+func TestDocumentService_Save_SameTitleSkipsReresolve(t *testing.T) {
+	docs := newWikilinkDocs(map[string]model.Document{"d1": {Title: "Same"}}, nil)
+	docs.updateLinksFn = func(context.Context, string, string, []model.LinkTarget, int64) error { return nil }
+	docs.listWikiSourcesFn = func(context.Context, string, []string, string) ([]string, error) {
+		t.Fatal("unchanged title must not re-resolve wikilinks")
+		return nil, nil
+	}
+	svc := newDocSvc(docs, newWikilinkVersions(), nil, nil)
+
+	_, err := svc.Save(context.Background(), "u1", "d1", DocumentUpdateInput{Title: " same ", Content: "body"})
+	require.NoError(t, err)
+}
+
+func TestDocumentService_Create_ResolvesDanglingWikilinks(t *testing.T) {
+	docs := newWikilinkDocs(map[string]model.Document{"src": {Content: "[[alpha]]"}}, nil)
+	var created string
+	docs.createFn = func(_ context.Context, doc *model.Document) error {
+		created = doc.ID
+		return nil
+	}
+	docs.listByTitleKeysFn = func(context.Context, string, []string) ([]model.DocumentTitle, error) {
+		return []model.DocumentTitle{{ID: created, Title: "Alpha", Key: "alpha"}}, nil
+	}
+	docs.listWikiSourcesFn = func(_ context.Context, _ string, keys []string, _ string) ([]string, error) {
+		assert.Equal(t, []string{"alpha"}, keys)
+		return []string{"src"}, nil
+	}
+	var srcTargets []model.LinkTarget
+	docs.updateLinksFn = func(_ context.Context, _, sourceID string, got []model.LinkTarget, _ int64) error {
+		if sourceID == "src" {
+			srcTargets = got
+		}
+		return nil
+	}
+	svc := newDocSvc(docs, newWikilinkVersions(), nil, nil)
+
+	doc, err := svc.Create(context.Background(), "u1", DocumentCreateInput{Title: "Alpha", Content: "body"})
+	require.NoError(t, err)
+	assert.Equal(t, []model.LinkTarget{{ID: doc.ID}}, srcTargets)
+}
+
+func TestDocumentService_Delete_ReresolvesWikilinks(t *testing.T) {
+	docs := newWikilinkDocs(map[string]model.Document{
+		"d1":  {Title: "Alpha"},
+		"src": {Content: "[[Alpha]]"},
+	}, []model.DocumentTitle{{ID: "d2", Title: "alpha"}})
+	docs.listWikiSourcesFn = func(_ context.Context, _ string, keys []string, targetID string) ([]string, error) {
+		assert.Equal(t, []string{"alpha"}, keys)
+		assert.Equal(t, "d1", targetID)
+		return []string{"src"}, nil
+	}
+	var wikilinks []model.DocumentWikilink
+	docs.updateLinksFn = func(context.Context, string, string, []model.LinkTarget, int64) error { return nil }
+	docs.replaceWikilinksFn = func(_ context.Context, _, _ string, got []model.DocumentWikilink) error {
+		wikilinks = got
+		return nil
+	}
+	shares := &mockShareRepo{
+		revokeByDocumentFn: func(context.Context, string, string, int64) error { return nil },
+	}
+	tags := &mockDocumentTagRepo{
+		deleteByDocFn: func(context.Context, string, string) error { return nil },
+	}
+	svc := newDocSvc(docs, nil, tags, shares)
+
+	require.NoError(t, svc.Delete(context.Background(), "u1", "d1"))
+	require.Len(t, wikilinks, 1)
+	assert.Equal(t, "d2", wikilinks[0].TargetID)
+}
+
+func TestDocumentService_Save_WikilinkResolveError(t *testing.T) {
+	docs := newWikilinkDocs(map[string]model.Document{"d1": {Title: "Notes"}}, nil)
+	docs.listByTitleKeysFn = func(context.Context, string, []string) ([]model.DocumentTitle, error) {
+		return nil, errors.New("lookup fail")
+	}
+	svc := newDocSvc(docs, newWikilinkVersions(), nil, nil)
+
+	_, err := svc.Save(context.Background(), "u1", "d1", DocumentUpdateInput{Title: "Notes", Content: "[[Alpha]]"})
+	assert.ErrorContains(t, err, "resolve wikilinks")
+}
+
+func TestDocumentService_ListWikilinks(t *testing.T) {
+	docs := &mockDocumentRepo{
+		getByIDFn: func(context.Context, string, string) (*model.Document, error) {
+			return &model.Document{ID: "d1"}, nil
+		},
+		listWikilinksFn: func(context.Context, string, string) ([]model.DocumentWikilink, error) {
+			return []model.DocumentWikilink{
+				{Title: "Alpha", TitleKey: "alpha", Status: model.WikilinkStatusResolved, TargetID: "d2"},
+				{Title: "Beta", TitleKey: "beta", Status: model.WikilinkStatusAmbiguous},
+			}, nil
+		},
+		listByTitleKeysFn: func(_ context.Context, _ string, keys []string) ([]model.DocumentTitle, error) {
+			assert.Equal(t, []string{"beta"}, keys)
+			return []model.DocumentTitle{{ID: "d4", Title: "Beta", Key: "beta"}, {ID: "d5", Title: "BETA", Key: "beta"}}, nil
+		},
+	}
+	svc := newDocSvc(docs, nil, nil, nil)
+
+	items, err := svc.ListWikilinks(context.Background(), "u1", "d1")
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Empty(t, items[0].Candidates)
+	assert.Len(t, items[1].Candidates, 2)
+}
+
+func TestDocumentService_ListWikilinks_NotFound(t *testing.T) {
+	docs := &mockDocumentRepo{
+		getByIDFn: func(context.Context, string, string) (*model.Document, error) {
+			return nil, appErr.ErrNotFound
+		},
+	}
+	_, err := newDocSvc(docs, nil, nil, nil).ListWikilinks(context.Background(), "u1", "d1")
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
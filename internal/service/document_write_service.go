@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"slices"
 	"strings"
 	"unicode/utf8"
 
@@ -23,6 +24,9 @@ func computeDocumentHash(title, content string) string {
 }
 
 func (s *DocumentService) UpdateTags(ctx context.Context, userID, docID string, tagIDs []string) error {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return err
+	}
 	return s.runInTx(ctx, func(txCtx context.Context) error {
 		if _, err := s.docs.GetByIDForUpdate(txCtx, userID, docID); err != nil {
 			return fmt.Errorf("lock document: %w", err)
@@ -31,7 +35,69 @@ func (s *DocumentService) UpdateTags(ctx context.Context, userID, docID string,
 	})
 }
 
+// AddTags adds tagIDs to the tags a document already has and returns the
+// resulting tag IDs.
+func (s *DocumentService) AddTags(ctx context.Context, userID, docID string, tagIDs []string) ([]string, error) {
+	added := uniqueStringSlice(tagIDs)
+	if len(added) == 0 {
+		return nil, appErr.ErrInvalid
+	}
+	return s.changeTags(ctx, userID, docID, func(current []string) []string {
+		return uniqueStringSlice(append(current, added...))
+	})
+}
+
+// RemoveTags takes tagIDs off a document and returns the tag IDs it keeps.
+// Tags the document does not have are ignored.
+func (s *DocumentService) RemoveTags(ctx context.Context, userID, docID string, tagIDs []string) ([]string, error) {
+	removed := uniqueStringSlice(tagIDs)
+	if len(removed) == 0 {
+		return nil, appErr.ErrInvalid
+	}
+	return s.changeTags(ctx, userID, docID, func(current []string) []string {
+		kept := make([]string, 0, len(current))
+		for _, id := range current {
+			if !slices.Contains(removed, id) {
+				kept = append(kept, id)
+			}
+		}
+		return kept
+	})
+}
+
+// changeTags replaces the tags of a locked document with what change makes
+// of its current tag IDs.
+func (s *DocumentService) changeTags(
+	ctx context.Context, userID, docID string, change func(current []string) []string,
+) ([]string, error) {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return nil, err
+	}
+	var tagIDs []string
+	err := s.runInTx(ctx, func(txCtx context.Context) error {
+		if _, err := s.docs.GetByIDForUpdate(txCtx, userID, docID); err != nil {
+			return fmt.Errorf("lock document: %w", err)
+		}
+		current, err := s.tags.ListTagIDs(txCtx, userID, docID)
+		if err != nil {
+			return fmt.Errorf("list tag ids: %w", err)
+		}
+		tagIDs = change(current)
+		if len(tagIDs) > 100 {
+			return appErr.ErrInvalid
+		}
+		return s.applyTagChanges(txCtx, userID, docID, tagIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tagIDs, nil
+}
+
 func (s *DocumentService) UpdatePinned(ctx context.Context, userID, docID string, pinned int) error {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return err
+	}
 	if pinned != 0 && pinned != 1 {
 		return appErr.ErrInvalid
 	}
@@ -42,6 +108,9 @@ func (s *DocumentService) UpdatePinned(ctx context.Context, userID, docID string
 }
 
 func (s *DocumentService) UpdateStarred(ctx context.Context, userID, docID string, starred int) error {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return err
+	}
 	if starred != 0 && starred != 1 {
 		return appErr.ErrInvalid
 	}
@@ -52,17 +121,42 @@ func (s *DocumentService) UpdateStarred(ctx context.Context, userID, docID strin
 }
 
 func (s *DocumentService) Delete(ctx context.Context, userID, docID string) error {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return err
+	}
 	return s.runInTx(ctx, func(txCtx context.Context) error {
 		now := timeutil.NowUnix()
+		names, err := s.documentNames(txCtx, userID, docID)
+		if err != nil {
+			return err
+		}
+		if err := s.lockWikilinkSet(txCtx, userID, docID, names); err != nil {
+			return err
+		}
+		current, err := s.docs.GetByIDForUpdate(txCtx, userID, docID)
+		if err != nil {
+			return fmt.Errorf("lock document: %w", err)
+		}
+		props, err := s.docs.ListProperties(txCtx, userID, docID)
+		if err != nil {
+			return fmt.Errorf("list properties: %w", err)
+		}
 		if err := s.docs.Delete(txCtx, userID, docID, now); err != nil {
 			return fmt.Errorf("delete: %w", err)
 		}
+		names = append([]string{current.Title}, propertyAliases(props)...)
+		if err := s.reresolveWikilinks(txCtx, userID, docID, names, now); err != nil {
+			return err
+		}
 		if err := s.shares.RevokeByDocument(txCtx, userID, docID, now); err != nil {
 			return fmt.Errorf("revoke by document: %w", err)
 		}
 		if err := s.tags.DeleteByDoc(txCtx, userID, docID); err != nil {
 			return fmt.Errorf("delete by doc: %w", err)
 		}
+		if err := s.docs.ReplaceTasks(txCtx, userID, docID, nil); err != nil {
+			return fmt.Errorf("remove tasks: %w", err)
+		}
 		if s.assets != nil {
 			if err := s.assets.RemoveDocumentReferences(txCtx, userID, docID); err != nil {
 				return fmt.Errorf("remove document references: %w", err)
@@ -140,6 +234,9 @@ func (
 	input DocumentUpdateInput) (*model.SaveDocumentResult,
 	error,
 ) {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return nil, err
+	}
 	if err := s.validateDocumentInput(input.Title, input.Content, input.TagIDs); err != nil {
 		return nil, err
 	}
@@ -159,7 +256,7 @@ func (
 
 // saveImpl runs the body of Save inside a transaction. The work is split
 // into small helpers so each step (lock+revision check, document update, version
-// snapshot, tag refresh, references) is independently readable and the
+// snapshot, tag refresh, properties, references) is independently readable and the
 // orchestrator stays well below the gocyclo threshold.
 func (
 	s *DocumentService) saveImpl(ctx context.Context,
@@ -168,10 +265,15 @@ func (
 	input DocumentUpdateInput) (*model.SaveDocumentResult,
 	error,
 ) {
-	current, err := s.lockForSave(ctx, userID, docID)
+	author := actorID(ctx, userID)
+	if err := s.lockRenameSet(ctx, userID, docID, input); err != nil {
+		return nil, err
+	}
+	current, ownerID, err := s.lockForSave(ctx, userID, docID)
 	if err != nil {
 		return nil, err
 	}
+	userID = ownerID
 	if input.BaseRevision > 0 && input.BaseRevision != current.ContentRevision {
 		return &model.SaveDocumentResult{
 			ID: current.ID, Accepted: false,
@@ -196,15 +298,20 @@ func (
 		newRevision = input.SaveSeq
 	}
 	newHash := computeDocumentHash(input.Title, input.Content)
-	if err := s.persistDocument(ctx, userID, docID, input, now, newRevision, newHash); err != nil {
+	stats := documentStats(input.Content)
+	if err := s.persistDocument(ctx, userID, docID, input, stats, now, newRevision, newHash); err != nil {
 		return nil, err
 	}
-	if err := s.recordVersion(ctx, userID, docID, input, now, newRevision); err != nil {
+	if err := s.recordVersion(ctx, userID, author, docID, input, stats.WordCount, now, newRevision); err != nil {
 		return nil, err
 	}
 	if err := s.applyTagChanges(ctx, userID, docID, input.TagIDs); err != nil {
 		return nil, err
 	}
+	names, err := s.syncProperties(ctx, userID, docID, input.Content, now)
+	if err != nil {
+		return nil, err
+	}
 	if err := s.refreshReferences(
 		ctx,
 		userID,
@@ -216,6 +323,14 @@ func (
 	); err != nil {
 		return nil, err
 	}
+	if wikilinkTitleKey(current.Title) != wikilinkTitleKey(input.Title) {
+		names = append(names, current.Title, input.Title)
+	}
+	if len(names) > 0 {
+		if err := s.reresolveWikilinks(ctx, userID, docID, names, now); err != nil {
+			return nil, err
+		}
+	}
 	return &model.SaveDocumentResult{
 		ID:              docID,
 		Accepted:        true,
@@ -228,21 +343,31 @@ func (
 
 // lockForSave returns the row under a write lock so base-revision comparison
 // and the eventual write observe one serial state. A rejected branch exits
-// before any version or derived relation is modified.
+// before any version or derived relation is modified. Editors invited to the
+// document save into its owner's scope, which is returned with the row.
 func (s *DocumentService) lockForSave(
 	ctx context.Context, userID, docID string,
-) (*model.Document, error) {
+) (*model.Document, string, error) {
 	current, err := s.docs.GetByIDForUpdate(ctx, userID, docID)
+	if err == nil {
+		return current, userID, nil
+	}
+	ownerID, cerr := s.collaboratorOwner(ctx, userID, docID, true, err)
+	if cerr != nil {
+		return nil, "", fmt.Errorf("lock document: %w", cerr)
+	}
+	current, err = s.docs.GetByIDForUpdate(ctx, ownerID, docID)
 	if err != nil {
-		return nil, fmt.Errorf("lock document: %w", err)
+		return nil, "", fmt.Errorf("lock shared document: %w", err)
 	}
-	return current, nil
+	return current, ownerID, nil
 }
 
 func (s *DocumentService) persistDocument(
 	ctx context.Context,
 	userID, docID string,
 	input DocumentUpdateInput,
+	stats model.DocumentStats,
 	now, newRevision int64,
 	newHash string,
 ) error {
@@ -250,6 +375,7 @@ func (s *DocumentService) persistDocument(
 		ID: docID, UserID: userID,
 		Title: input.Title, Content: input.Content, Mtime: now,
 		ContentHash: newHash, ContentMtime: now, ContentRevision: newRevision,
+		Stats: stats,
 	}
 	if err := s.docs.Update(ctx, doc); err != nil {
 		return fmt.Errorf("update: %w", err)
@@ -259,8 +385,9 @@ func (s *DocumentService) persistDocument(
 
 func (s *DocumentService) recordVersion(
 	ctx context.Context,
-	userID, docID string,
+	userID, authorID, docID string,
 	input DocumentUpdateInput,
+	wordCount int,
 	now, newRevision int64,
 ) error {
 	versionID, err := s.runtime.IDs.ID()
@@ -270,7 +397,8 @@ func (s *DocumentService) recordVersion(
 	version := &model.DocumentVersion{
 		ID: versionID, UserID: userID, DocumentID: docID,
 		Version: int(newRevision), Title: input.Title,
-		Content: input.Content, Ctime: now,
+		Content: input.Content, AuthorID: authorID, Ctime: now,
+		WordCount: wordCount,
 	}
 	if err := s.versions.Create(ctx, version); err != nil {
 		return fmt.Errorf("create version: %w", err)
@@ -335,12 +463,11 @@ func (s *DocumentService) refreshReferences(
 	now, revision int64,
 	newHash string,
 ) error {
-	linkIDs, err := s.validateOwnedLinkIDs(ctx, userID, docID, extractLinkIDs(content))
-	if err != nil {
+	if err := s.syncLinks(ctx, userID, docID, content, now); err != nil {
 		return err
 	}
-	if err := s.docs.UpdateLinks(ctx, userID, docID, linkIDs, now); err != nil {
-		return fmt.Errorf("update links: %w", err)
+	if err := s.syncTasks(ctx, userID, docID, content, now); err != nil {
+		return err
 	}
 	if s.assets != nil {
 		if err := s.assets.SyncDocumentReferences(ctx, userID, docID, content); err != nil {
@@ -398,6 +525,9 @@ func (
 	input DocumentCreateInput) (*model.Document,
 	error,
 ) {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return nil, err
+	}
 	if err := s.validateDocumentInput(input.Title, input.Content, input.TagIDs); err != nil {
 		return nil, err
 	}
@@ -413,6 +543,7 @@ func (
 		ContentHash:     computeDocumentHash(input.Title, input.Content),
 		ContentMtime:    now,
 		ContentRevision: 1,
+		Stats:           documentStats(input.Content),
 	}
 	if err := s.runInTx(ctx, func(txCtx context.Context) error {
 		return s.createImpl(txCtx, userID, doc, input)
@@ -434,7 +565,8 @@ func (s *DocumentService) createImpl(
 	}
 	version := &model.DocumentVersion{
 		ID: versionID, UserID: userID, DocumentID: doc.ID,
-		Version: 1, Title: doc.Title, Content: doc.Content, Ctime: doc.Mtime,
+		Version: 1, Title: doc.Title, Content: doc.Content,
+		AuthorID: actorID(ctx, userID), WordCount: doc.Stats.WordCount, Ctime: doc.Mtime,
 	}
 	if err := s.versions.Create(ctx, version); err != nil {
 		return fmt.Errorf("create version: %w", err)
@@ -445,12 +577,19 @@ func (s *DocumentService) createImpl(
 	if err := s.applyTagChanges(ctx, userID, doc.ID, input.TagIDs); err != nil {
 		return err
 	}
-	linkIDs, err := s.validateOwnedLinkIDs(ctx, userID, doc.ID, extractLinkIDs(input.Content))
-	if err != nil {
+	props := parseProperties(input.Content)
+	if err := s.docs.ReplaceProperties(ctx, userID, doc.ID, props, doc.Mtime); err != nil {
+		return fmt.Errorf("replace properties: %w", err)
+	}
+	if err := s.syncLinks(ctx, userID, doc.ID, input.Content, doc.Mtime); err != nil {
+		return err
+	}
+	if err := s.syncTasks(ctx, userID, doc.ID, input.Content, doc.Mtime); err != nil {
 		return err
 	}
-	if err := s.docs.UpdateLinks(ctx, userID, doc.ID, linkIDs, doc.Mtime); err != nil {
-		return fmt.Errorf("update links: %w", err)
+	names := append([]string{doc.Title}, propertyAliases(props)...)
+	if err := s.reresolveWikilinks(ctx, userID, doc.ID, names, doc.Mtime); err != nil {
+		return err
 	}
 	if s.assets != nil {
 		if err := s.assets.SyncDocumentReferences(ctx, userID, doc.ID, input.Content); err != nil {
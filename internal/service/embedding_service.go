@@ -82,6 +82,22 @@ type embeddingV2RuntimeRepo interface {
 		bool,
 		error,
 	)
+	SimilarTagCentroids(
+		ctx context.Context,
+		userID, documentID string,
+		limit int,
+	) (
+		*model.EmbeddingGeneration,
+		[]model.SimilarTagResult,
+		bool,
+		error,
+	)
+	SimilarDocumentPairs(
+		ctx context.Context,
+		userID string,
+		documentIDs []string,
+		perDocument int,
+	) (*model.EmbeddingGeneration, []model.SimilarDocumentPair, error)
 }
 
 func NewEmbeddingService(embedder ai.IEmbedder, embeddings embeddingRepo) *EmbeddingService {
@@ -778,6 +794,101 @@ func (s *EmbeddingService) similarDocumentsV2(
 	)
 }
 
+// SimilarTags ranks tag centroids against the document centroid. Unlike
+// SimilarDocuments no minimum score is applied: the caller blends these
+// scores with neighbour votes and decides what to surface.
+func (s *EmbeddingService) SimilarTags(
+	ctx context.Context,
+	userID, documentID string,
+	limit int,
+) ([]model.SimilarTagResult, string, error) {
+	if s == nil || s.v2 == nil || !s.v2Enabled {
+		return []model.SimilarTagResult{}, "disabled", nil
+	}
+	active, err := s.hasActiveV2(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if !active {
+		return []model.SimilarTagResult{}, "building", nil
+	}
+	var lastErr error
+	for range 2 {
+		_, results, indexed, err := s.v2.SimilarTagCentroids(
+			ctx,
+			userID,
+			documentID,
+			limit,
+		)
+		if err == nil {
+			if !indexed {
+				return []model.SimilarTagResult{}, "pending", nil
+			}
+			for index := range results {
+				results[index].Score = clampSemanticScore(results[index].Score)
+			}
+			return results, "ready", nil
+		}
+		if !errors.Is(err, repo.ErrEmbeddingActiveChanged) {
+			return nil, "", fmt.Errorf("query similar tags: %w", err)
+		}
+		lastErr = err
+	}
+	return nil, "", fmt.Errorf(
+		"active embedding generation changed repeatedly: %w",
+		errors.Join(ai.ErrUnavailable, lastErr),
+	)
+}
+
+// SimilarDocumentPairs returns up to perDocument centroid neighbours of
+// each document whose score reaches both the profile's minimum score and
+// minScore. Documents without a current centroid have no pairs.
+func (s *EmbeddingService) SimilarDocumentPairs(
+	ctx context.Context,
+	userID string,
+	documentIDs []string,
+	perDocument int,
+	minScore float32,
+) ([]model.SimilarDocumentPair, string, error) {
+	if s == nil || s.v2 == nil || !s.v2Enabled {
+		return []model.SimilarDocumentPair{}, "disabled", nil
+	}
+	active, err := s.hasActiveV2(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if !active {
+		return []model.SimilarDocumentPair{}, "building", nil
+	}
+	var lastErr error
+	for range 2 {
+		generation, results, err := s.v2.SimilarDocumentPairs(ctx, userID, documentIDs, perDocument)
+		if err == nil {
+			threshold, configured := s.v2MinScores[generation.ProfileID]
+			if !configured {
+				threshold = 0.55
+			}
+			threshold = max(threshold, minScore)
+			pairs := make([]model.SimilarDocumentPair, 0, len(results))
+			for _, pair := range results {
+				pair.Score = clampSemanticScore(pair.Score)
+				if pair.Score >= threshold {
+					pairs = append(pairs, pair)
+				}
+			}
+			return pairs, "ready", nil
+		}
+		if !errors.Is(err, repo.ErrEmbeddingActiveChanged) {
+			return nil, "", fmt.Errorf("query similar document pairs: %w", err)
+		}
+		lastErr = err
+	}
+	return nil, "", fmt.Errorf(
+		"active embedding generation changed repeatedly: %w",
+		errors.Join(ai.ErrUnavailable, lastErr),
+	)
+}
+
 // SyncEmbedding chunks the snapshot (title,content) the worker captured at
 // scan time, embeds the chunks, and hands the result to
 // CompleteEmbeddingIfCurrent. The completion call runs SELECT FOR UPDATE on
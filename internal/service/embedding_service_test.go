@@ -167,6 +167,8 @@ type fakeEmbeddingV2RuntimeRepo struct {
 	searchExclude  string
 	searchRecall   int
 	similarResults []model.SimilarDocumentResult
+	similarTags    []model.SimilarTagResult
+	similarPairs   []model.SimilarDocumentPair
 	similarIndexed bool
 	similarErrors  []error
 	similarCalls   int
@@ -249,6 +251,29 @@ func (repository *fakeEmbeddingV2RuntimeRepo) SimilarDocuments(
 	return repository.generation, repository.similarResults, repository.similarIndexed, similarErr
 }
 
+func (repository *fakeEmbeddingV2RuntimeRepo) SimilarTagCentroids(
+	context.Context,
+	string,
+	string,
+	int,
+) (
+	*model.EmbeddingGeneration,
+	[]model.SimilarTagResult,
+	bool,
+	error,
+) {
+	return repository.generation, repository.similarTags, repository.similarIndexed, nil
+}
+
+func (repository *fakeEmbeddingV2RuntimeRepo) SimilarDocumentPairs(
+	context.Context,
+	string,
+	[]string,
+	int,
+) (*model.EmbeddingGeneration, []model.SimilarDocumentPair, error) {
+	return repository.generation, repository.similarPairs, nil
+}
+
 func TestEmbeddingService_Embed(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		mgr := &mockEmbedder{
@@ -392,6 +417,58 @@ func TestEmbeddingService_SimilarDocumentStatuses(t *testing.T) {
 	assert.Equal(t, []float32{1}, scores)
 }
 
+func TestEmbeddingService_SimilarTagStatuses(t *testing.T) {
+	service := NewEmbeddingService(nil, nil)
+	_, status, err := service.SimilarTags(context.Background(), "user", "document", 5)
+	require.NoError(t, err)
+	assert.Equal(t, "disabled", status)
+
+	repository := &fakeEmbeddingV2RuntimeRepo{
+		generation: &model.EmbeddingGeneration{ID: "generation", ProfileID: "profile"},
+		profile:    &model.EmbeddingProfile{ID: "profile"},
+	}
+	service.ConfigureV2(repository, 0, nil, nil)
+	_, status, err = service.SimilarTags(context.Background(), "user", "document", 5)
+	require.NoError(t, err)
+	assert.Equal(t, "pending", status)
+
+	repository.similarIndexed = true
+	repository.similarTags = []model.SimilarTagResult{{TagID: "go", Score: 1.5, DocumentCount: 2}}
+	tags, status, err := service.SimilarTags(context.Background(), "user", "document", 5)
+	require.NoError(t, err)
+	assert.Equal(t, "ready", status)
+	assert.Equal(t, []model.SimilarTagResult{{TagID: "go", Score: 1, DocumentCount: 2}}, tags)
+}
+
+func TestEmbeddingService_SimilarDocumentPairsThreshold(t *testing.T) {
+	service := NewEmbeddingService(nil, nil)
+	_, status, err := service.SimilarDocumentPairs(context.Background(), "user", []string{"a"}, 5, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "disabled", status)
+
+	repository := &fakeEmbeddingV2RuntimeRepo{
+		generation: &model.EmbeddingGeneration{ID: "generation", ProfileID: "profile"},
+		profile:    &model.EmbeddingProfile{ID: "profile"},
+		similarPairs: []model.SimilarDocumentPair{
+			{SourceID: "a", TargetID: "b", Score: 1.2},
+			{SourceID: "a", TargetID: "c", Score: 0.7},
+			{SourceID: "a", TargetID: "d", Score: 0.5},
+		},
+	}
+	service.ConfigureV2(repository, 0, nil, nil)
+	pairs, status, err := service.SimilarDocumentPairs(context.Background(), "user", []string{"a"}, 5, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "ready", status)
+	assert.Equal(t, []model.SimilarDocumentPair{
+		{SourceID: "a", TargetID: "b", Score: 1},
+		{SourceID: "a", TargetID: "c", Score: 0.7},
+	}, pairs)
+
+	pairs, _, err = service.SimilarDocumentPairs(context.Background(), "user", []string{"a"}, 5, 0.8)
+	require.NoError(t, err)
+	assert.Equal(t, []model.SimilarDocumentPair{{SourceID: "a", TargetID: "b", Score: 1}}, pairs)
+}
+
 func TestEmbeddingService_RetriesActiveGenerationSwitchOnce(t *testing.T) {
 	profile := &model.EmbeddingProfile{
 		ID:            "profile",
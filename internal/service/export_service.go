@@ -8,6 +8,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"strings"
 
@@ -28,12 +29,23 @@ type ExportService struct {
 	versions versionRepo
 	tags     tagRepo
 	docTags  documentTagRepo
+	folders  folderRepo
 }
 
 type NotesExportItem struct {
-	Title   string   `json:"title"`
-	Content string   `json:"content"`
-	TagList []string `json:"tag_list,omitempty"`
+	Title   string           `json:"title"`
+	Content string           `json:"content"`
+	TagList []string         `json:"tag_list,omitempty"`
+	Tags    []NotesExportTag `json:"tags,omitempty"`
+}
+
+// NotesExportTag carries tag appearance next to the plain tag_list so that an
+// import can restore it; older readers only look at tag_list.
+type NotesExportTag struct {
+	Name        string `json:"name"`
+	Color       string `json:"color,omitempty"`
+	Description string `json:"description,omitempty"`
+	Icon        string `json:"icon,omitempty"`
 }
 
 func NewExportService(
@@ -41,8 +53,9 @@ func NewExportService(
 	versions versionRepo,
 	tags tagRepo,
 	docTags documentTagRepo,
+	folders folderRepo,
 ) *ExportService {
-	return &ExportService{docs: docs, versions: versions, tags: tags, docTags: docTags}
+	return &ExportService{docs: docs, versions: versions, tags: tags, docTags: docTags, folders: folders}
 }
 
 func (s *ExportService) Export(ctx context.Context, userID string) (*ExportPayload, error) {
@@ -65,11 +78,28 @@ func (s *ExportService) Export(ctx context.Context, userID string) (*ExportPaylo
 	return &ExportPayload{Documents: docs, Versions: versions, Tags: tags, DocTags: docTags}, nil
 }
 
+// ExportNotesZip writes every document as a JSON entry of a zip file and
+// returns its path. Documents filed in a folder sit under the directory of
+// its path, which a notes import turns back into folders.
 func (s *ExportService) ExportNotesZip(ctx context.Context, userID string) (string, error) {
 	docs, err := s.docs.ListAllByUser(ctx, userID)
 	if err != nil {
 		return "", fmt.Errorf("list documents: %w", err)
 	}
+	return s.writeNotesZip(ctx, userID, docs)
+}
+
+// ExportNotesZipByIDs is ExportNotesZip limited to the given documents. IDs
+// that name no document of the user are left out.
+func (s *ExportService) ExportNotesZipByIDs(ctx context.Context, userID string, docIDs []string) (string, error) {
+	docs, err := s.docs.ListByIDs(ctx, userID, docIDs)
+	if err != nil {
+		return "", fmt.Errorf("list documents: %w", err)
+	}
+	return s.writeNotesZip(ctx, userID, docs)
+}
+
+func (s *ExportService) writeNotesZip(ctx context.Context, userID string, docs []model.Document) (string, error) {
 	tags, err := s.tags.List(ctx, userID)
 	if err != nil {
 		return "", fmt.Errorf("list tags: %w", err)
@@ -82,10 +112,15 @@ func (s *ExportService) ExportNotesZip(ctx context.Context, userID string) (stri
 	if err != nil {
 		return "", fmt.Errorf("list tag ids by doc ids: %w", err)
 	}
-	tagNames := make(map[string]string)
+	tagsByID := make(map[string]model.Tag, len(tags))
 	for _, tag := range tags {
-		tagNames[tag.ID] = tag.Name
+		tagsByID[tag.ID] = tag
 	}
+	folders, err := s.folders.List(ctx, userID)
+	if err != nil {
+		return "", fmt.Errorf("list folders: %w", err)
+	}
+	tree := newFolderTree(folders)
 
 	tmp, err := os.CreateTemp("", "mnote-notes-*.zip")
 	if err != nil {
@@ -95,7 +130,8 @@ func (s *ExportService) ExportNotesZip(ctx context.Context, userID string) (stri
 	writer := zip.NewWriter(tmp)
 	nameCounts := make(map[string]int)
 	for _, doc := range docs {
-		if err := writeExportEntry(writer, doc, docTags[doc.ID], tagNames, nameCounts); err != nil {
+		dir := path.Join(tree.path(doc.FolderID)...)
+		if err := writeExportEntry(writer, dir, doc, docTags[doc.ID], tagsByID, nameCounts); err != nil {
 			_ = writer.Close()
 			return "", err
 		}
@@ -119,15 +155,15 @@ func (s *ExportService) ConvertMarkdownToConfluenceHTML(ctx context.Context, use
 }
 
 func writeExportEntry(
-	w *zip.Writer, doc model.Document, tagIDs []string,
-	tagNames map[string]string, nameCounts map[string]int,
+	w *zip.Writer, dir string, doc model.Document, tagIDs []string,
+	tagsByID map[string]model.Tag, nameCounts map[string]int,
 ) error {
 	baseTitle := strings.TrimSpace(doc.Title)
 	if baseTitle == "" {
 		baseTitle = "Untitled"
 	}
 	hash := sha256.Sum256([]byte(baseTitle))
-	name := hex.EncodeToString(hash[:])
+	name := path.Join(dir, hex.EncodeToString(hash[:]))
 	nameCounts[name]++
 	filename := name
 	if nameCounts[name] > 1 {
@@ -135,14 +171,22 @@ func writeExportEntry(
 	}
 	filename += ".json"
 	tagList := make([]string, 0, len(tagIDs))
+	tagMeta := make([]NotesExportTag, 0)
 	for _, tagID := range tagIDs {
-		if tagName, ok := tagNames[tagID]; ok {
-			tagList = append(tagList, tagName)
+		tag, ok := tagsByID[tagID]
+		if !ok {
+			continue
+		}
+		tagList = append(tagList, tag.Name)
+		if tag.Color != "" || tag.Description != "" || tag.Icon != "" {
+			tagMeta = append(tagMeta, NotesExportTag{
+				Name: tag.Name, Color: tag.Color, Description: tag.Description, Icon: tag.Icon,
+			})
 		}
 	}
 	payload := NotesExportItem{
 		Title: doc.Title, Content: doc.Content,
-		TagList: tagList,
+		TagList: tagList, Tags: tagMeta,
 	}
 	content, err := json.Marshal(payload)
 	if err != nil {
@@ -7,6 +7,7 @@ import (
 	"errors"
 	"io"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -21,7 +22,7 @@ func newExportSvc(
 	tags tagRepo,
 	docTags documentTagRepo,
 ) *ExportService {
-	return NewExportService(docs, versions, tags, docTags)
+	return NewExportService(docs, versions, tags, docTags, newFakeFolderRepo())
 }
 
 func TestExportService_Export(t *testing.T) {
@@ -148,6 +149,79 @@ func TestExportService_ExportNotesZip(t *testing.T) {
 		assert.NotContains(t, payload, "summary")
 	})
 
+	t.Run("carries_tag_appearance", func(t *testing.T) {
+		docs := &mockDocumentRepo{
+			listFn: func(context.Context, string, *int, uint, uint, string) ([]model.Document, error) {
+				return []model.Document{{ID: "d1", Title: "Note 1", Content: "# Hello"}}, nil
+			},
+		}
+		tags := &mockTagRepo{
+			listFn: func(context.Context, string) ([]model.Tag, error) {
+				return []model.Tag{
+					{ID: "t1", Name: "go", Color: "#00add8", Icon: "🐹"},
+					{ID: "t2", Name: "plain"},
+				}, nil
+			},
+		}
+		docTags := &mockDocumentTagRepo{
+			listTagIDsByDocIDsFn: func(context.Context, string, []string) (map[string][]string, error) {
+				return map[string][]string{"d1": {"t1", "t2"}}, nil
+			},
+		}
+		svc := newExportSvc(docs, nil, tags, docTags)
+		path, err := svc.ExportNotesZip(context.Background(), "u1")
+		require.NoError(t, err)
+		defer func() { _ = os.Remove(path) }()
+
+		archive, err := zip.OpenReader(path)
+		require.NoError(t, err)
+		defer func() { _ = archive.Close() }()
+		require.Len(t, archive.File, 1)
+		exported, err := archive.File[0].Open()
+		require.NoError(t, err)
+		payloadBytes, err := io.ReadAll(exported)
+		require.NoError(t, err)
+		require.NoError(t, exported.Close())
+		var item NotesExportItem
+		require.NoError(t, json.Unmarshal(payloadBytes, &item))
+		assert.Equal(t, []string{"go", "plain"}, item.TagList)
+		assert.Equal(t, []NotesExportTag{{Name: "go", Color: "#00add8", Icon: "🐹"}}, item.Tags)
+	})
+
+	t.Run("folder_dirs", func(t *testing.T) {
+		docs := &mockDocumentRepo{
+			listFn: func(context.Context, string, *int, uint, uint, string) ([]model.Document, error) {
+				return []model.Document{
+					{ID: "d1", Title: "Top"},
+					{ID: "d2", Title: "Nested", FolderID: "f2"},
+				}, nil
+			},
+		}
+		tags := &mockTagRepo{
+			listFn: func(context.Context, string) ([]model.Tag, error) { return nil, nil },
+		}
+		docTags := &mockDocumentTagRepo{
+			listTagIDsByDocIDsFn: func(context.Context, string, []string) (map[string][]string, error) {
+				return map[string][]string{}, nil
+			},
+		}
+		folders := newFakeFolderRepo()
+		folders.folders["f1"] = model.Folder{ID: "f1", UserID: "u1", Name: "Work"}
+		folders.folders["f2"] = model.Folder{ID: "f2", UserID: "u1", ParentID: "f1", Name: "2024"}
+		svc := NewExportService(docs, nil, tags, docTags, folders)
+		path, err := svc.ExportNotesZip(context.Background(), "u1")
+		require.NoError(t, err)
+		defer func() { _ = os.Remove(path) }()
+
+		archive, err := zip.OpenReader(path)
+		require.NoError(t, err)
+		defer func() { _ = archive.Close() }()
+		require.Len(t, archive.File, 2)
+		assert.NotContains(t, archive.File[0].Name, "/")
+		assert.True(t, strings.HasPrefix(archive.File[1].Name, "Work/2024/"), archive.File[1].Name)
+		assert.Equal(t, []string{"Work", "2024"}, importFolderPath(archive.File[1].Name))
+	})
+
 	t.Run("list_error", func(t *testing.T) {
 		docs := &mockDocumentRepo{
 			listFn: func(context.Context, string, *int, uint, uint, string) ([]model.Document, error) {
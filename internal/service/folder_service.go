@@ -0,0 +1,450 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+const (
+	maxFolderNameRunes = 64
+	maxFolderDepth     = 10
+)
+
+type folderDocumentRepo interface {
+	UpdateFolder(ctx context.Context, userID, docID, folderID string) error
+}
+
+type FolderService struct {
+	transactor Transactor
+	folders    folderRepo
+	docs       folderDocumentRepo
+	runtime    Runtime
+}
+
+func NewFolderService(runtime Runtime, folders folderRepo, docs folderDocumentRepo) *FolderService {
+	runtime = prepareRuntime(runtime)
+	return &FolderService{
+		transactor: runtime.Transactor, folders: folders, docs: docs, runtime: runtime,
+	}
+}
+
+// FolderNode is a folder of the tree with the number of documents filed
+// directly in it and its subfolders in position order.
+type FolderNode struct {
+	model.Folder
+	DocumentCount int
+	Children      []FolderNode
+}
+
+// normalizeFolderName trims a folder name and rejects names that cannot be a
+// path segment: empty, too long, "." or "..", or holding a slash, backslash
+// or control character.
+func normalizeFolderName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == ".." || utf8.RuneCountInString(name) > maxFolderNameRunes {
+		return "", appErr.ErrInvalid
+	}
+	if strings.ContainsAny(name, `/\`) || strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		return "", appErr.ErrInvalid
+	}
+	return name, nil
+}
+
+// folderTree indexes the folders of a user by ID and by parent, children in
+// position order.
+type folderTree struct {
+	byID     map[string]model.Folder
+	children map[string][]model.Folder
+}
+
+func newFolderTree(folders []model.Folder) *folderTree {
+	tree := &folderTree{
+		byID:     make(map[string]model.Folder, len(folders)),
+		children: make(map[string][]model.Folder),
+	}
+	for _, folder := range folders {
+		tree.add(folder)
+	}
+	return tree
+}
+
+func (t *folderTree) add(folder model.Folder) {
+	t.byID[folder.ID] = folder
+	t.children[folder.ParentID] = append(t.children[folder.ParentID], folder)
+}
+
+// depth is the number of folders from the top level down to id, so 0 for
+// the top level itself.
+func (t *folderTree) depth(id string) int {
+	depth := 0
+	for id != "" && depth <= maxFolderDepth {
+		depth++
+		id = t.byID[id].ParentID
+	}
+	return depth
+}
+
+// subtree returns id and the IDs of every folder below it, parents first.
+func (t *folderTree) subtree(id string) []string {
+	ids := []string{id}
+	for i := 0; i < len(ids); i++ {
+		for _, child := range t.children[ids[i]] {
+			ids = append(ids, child.ID)
+		}
+	}
+	return ids
+}
+
+// path returns the names of the folders from the top level down to id.
+func (t *folderTree) path(id string) []string {
+	names := make([]string, t.depth(id))
+	for i := len(names) - 1; i >= 0; i-- {
+		folder := t.byID[id]
+		names[i], id = folder.Name, folder.ParentID
+	}
+	return names
+}
+
+func (t *folderTree) child(parentID, name string) (model.Folder, bool) {
+	for _, folder := range t.children[parentID] {
+		if strings.EqualFold(folder.Name, name) {
+			return folder, true
+		}
+	}
+	return model.Folder{}, false
+}
+
+// childIDs returns the IDs of the children of parentID in order, leaving
+// out exclude.
+func (t *folderTree) childIDs(parentID, exclude string) []string {
+	ids := make([]string, 0, len(t.children[parentID]))
+	for _, folder := range t.children[parentID] {
+		if folder.ID != exclude {
+			ids = append(ids, folder.ID)
+		}
+	}
+	return ids
+}
+
+func (t *folderTree) nextPosition(parentID string) int {
+	siblings := t.children[parentID]
+	if len(siblings) == 0 {
+		return 0
+	}
+	return siblings[len(siblings)-1].Position + 1
+}
+
+func (s *FolderService) runInTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := s.transactor.WithinTransaction(ctx, fn); err != nil {
+		return fmt.Errorf("run in tx: %w", err)
+	}
+	return nil
+}
+
+func (s *FolderService) loadTree(ctx context.Context, userID string) (*folderTree, error) {
+	folders, err := s.folders.List(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list folders: %w", err)
+	}
+	return newFolderTree(folders), nil
+}
+
+// Tree returns the folders of a user nested under their parents.
+func (s *FolderService) Tree(ctx context.Context, userID string) ([]FolderNode, error) {
+	tree, err := s.loadTree(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	counts, err := s.folders.CountDocuments(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("count documents: %w", err)
+	}
+	var build func(parentID string) []FolderNode
+	build = func(parentID string) []FolderNode {
+		nodes := make([]FolderNode, 0, len(tree.children[parentID]))
+		for _, folder := range tree.children[parentID] {
+			nodes = append(nodes, FolderNode{
+				Folder: folder, DocumentCount: counts[folder.ID], Children: build(folder.ID),
+			})
+		}
+		return nodes
+	}
+	return build(""), nil
+}
+
+func (s *FolderService) Get(ctx context.Context, userID, folderID string) (*model.Folder, error) {
+	folder, err := s.folders.GetByID(ctx, userID, folderID)
+	if err != nil {
+		return nil, fmt.Errorf("get folder: %w", err)
+	}
+	return folder, nil
+}
+
+// Create adds a folder after the existing children of parentID, or at the
+// top level when parentID is empty.
+func (s *FolderService) Create(ctx context.Context, userID, parentID, name string) (*model.Folder, error) {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return nil, err
+	}
+	name, err := normalizeFolderName(name)
+	if err != nil {
+		return nil, err
+	}
+	var created *model.Folder
+	err = s.runInTx(ctx, func(txCtx context.Context) error {
+		tree, err := s.loadTree(txCtx, userID)
+		if err != nil {
+			return err
+		}
+		created, err = s.createIn(txCtx, tree, userID, parentID, name)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (s *FolderService) createIn(
+	ctx context.Context, tree *folderTree, userID, parentID, name string,
+) (*model.Folder, error) {
+	folder, err := s.newFolderIn(tree, userID, parentID, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.folders.Create(ctx, folder); err != nil {
+		if appErr.IsConflict(err) {
+			return nil, appErr.ErrConflict
+		}
+		return nil, fmt.Errorf("create folder: %w", err)
+	}
+	tree.add(*folder)
+	return folder, nil
+}
+
+// newFolderIn builds a folder called name placed last under parentID.
+func (s *FolderService) newFolderIn(tree *folderTree, userID, parentID, name string) (*model.Folder, error) {
+	if _, ok := tree.byID[parentID]; parentID != "" && !ok {
+		return nil, appErr.ErrNotFound
+	}
+	if tree.depth(parentID) >= maxFolderDepth {
+		return nil, appErr.ErrInvalid
+	}
+	id, err := s.runtime.IDs.ID()
+	if err != nil {
+		return nil, fmt.Errorf("generate folder id: %w", err)
+	}
+	now := s.runtime.Clock.Now().Unix()
+	return &model.Folder{
+		ID: id, UserID: userID, ParentID: parentID, Name: name,
+		Position: tree.nextPosition(parentID), Ctime: now, Mtime: now,
+	}, nil
+}
+
+// ensureChild returns the ID of the child of parentID called name, creating
+// it when missing. A folder of that name created meanwhile by a concurrent
+// import is looked up again instead of failing on the unique name index.
+func (s *FolderService) ensureChild(
+	ctx context.Context, tree *folderTree, userID, parentID, name string,
+) (string, error) {
+	if existing, ok := tree.child(parentID, name); ok {
+		return existing.ID, nil
+	}
+	folder, err := s.newFolderIn(tree, userID, parentID, name)
+	if err != nil {
+		return "", err
+	}
+	created, err := s.folders.CreateIfAbsent(ctx, folder)
+	if err != nil {
+		return "", fmt.Errorf("create folder: %w", err)
+	}
+	if !created {
+		if folder, err = s.folders.GetByName(ctx, userID, parentID, name); err != nil {
+			return "", fmt.Errorf("get folder by name: %w", err)
+		}
+	}
+	tree.add(*folder)
+	return folder.ID, nil
+}
+
+// Rename changes the name of a folder; a sibling of the same name is a
+// conflict.
+func (s *FolderService) Rename(ctx context.Context, userID, folderID, name string) (*model.Folder, error) {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return nil, err
+	}
+	name, err := normalizeFolderName(name)
+	if err != nil {
+		return nil, err
+	}
+	folder, err := s.folders.GetByID(ctx, userID, folderID)
+	if err != nil {
+		return nil, fmt.Errorf("get folder: %w", err)
+	}
+	folder.Name, folder.Mtime = name, s.runtime.Clock.Now().Unix()
+	if err := s.folders.Update(ctx, folder); err != nil {
+		return nil, fmt.Errorf("update folder: %w", err)
+	}
+	return folder, nil
+}
+
+// Move puts a folder under parentID, or at the top level when it is empty, at
+// index position among its new siblings; a negative or too large position
+// appends it. The siblings are renumbered around it. A folder cannot move
+// into its own subtree or past maxFolderDepth.
+func (s *FolderService) Move(
+	ctx context.Context, userID, folderID, parentID string, position int,
+) (*model.Folder, error) {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return nil, err
+	}
+	var moved *model.Folder
+	err := s.runInTx(ctx, func(txCtx context.Context) error {
+		tree, err := s.loadTree(txCtx, userID)
+		if err != nil {
+			return err
+		}
+		folder, ok := tree.byID[folderID]
+		if !ok {
+			return appErr.ErrNotFound
+		}
+		if err := checkFolderMove(tree, folderID, parentID); err != nil {
+			return err
+		}
+		oldParent := folder.ParentID
+		order := tree.childIDs(parentID, folderID)
+		index := position
+		if index < 0 || index > len(order) {
+			index = len(order)
+		}
+		order = append(order[:index], append([]string{folderID}, order[index:]...)...)
+		now := s.runtime.Clock.Now().Unix()
+		folder.ParentID, folder.Position, folder.Mtime = parentID, index, now
+		if err := s.folders.Update(txCtx, &folder); err != nil {
+			return fmt.Errorf("update folder: %w", err)
+		}
+		if err := s.folders.Reorder(txCtx, userID, parentID, order, now); err != nil {
+			return fmt.Errorf("reorder folders: %w", err)
+		}
+		if oldParent != parentID {
+			rest := tree.childIDs(oldParent, folderID)
+			if err := s.folders.Reorder(txCtx, userID, oldParent, rest, now); err != nil {
+				return fmt.Errorf("reorder folders: %w", err)
+			}
+		}
+		moved = &folder
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return moved, nil
+}
+
+func checkFolderMove(tree *folderTree, folderID, parentID string) error {
+	if parentID == "" {
+		return nil
+	}
+	if _, ok := tree.byID[parentID]; !ok {
+		return appErr.ErrNotFound
+	}
+	subtree := tree.subtree(folderID)
+	height := 0
+	for _, id := range subtree {
+		if id == parentID {
+			return appErr.ErrInvalid
+		}
+		height = max(height, tree.depth(id))
+	}
+	height -= tree.depth(folderID) - 1
+	if tree.depth(parentID)+height > maxFolderDepth {
+		return appErr.ErrInvalid
+	}
+	return nil
+}
+
+// Delete removes a folder with its subfolders. The documents filed in any of
+// them move up to the parent of the deleted folder rather than being lost.
+func (s *FolderService) Delete(ctx context.Context, userID, folderID string) error {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return err
+	}
+	return s.runInTx(ctx, func(txCtx context.Context) error {
+		tree, err := s.loadTree(txCtx, userID)
+		if err != nil {
+			return err
+		}
+		folder, ok := tree.byID[folderID]
+		if !ok {
+			return appErr.ErrNotFound
+		}
+		if err := s.folders.Delete(txCtx, userID, tree.subtree(folderID), folder.ParentID); err != nil {
+			return fmt.Errorf("delete folders: %w", err)
+		}
+		return nil
+	})
+}
+
+// MoveDocument files a document in folderID, or at the top level when it is
+// empty.
+func (s *FolderService) MoveDocument(ctx context.Context, userID, docID, folderID string) error {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return err
+	}
+	if folderID != "" {
+		if _, err := s.folders.GetByID(ctx, userID, folderID); err != nil {
+			return fmt.Errorf("get folder: %w", err)
+		}
+	}
+	if err := s.docs.UpdateFolder(ctx, userID, docID, folderID); err != nil {
+		return fmt.Errorf("update folder: %w", err)
+	}
+	return nil
+}
+
+// EnsurePath returns the ID of the folder reached by following names from
+// the top level, creating the folders missing along the way. Names match
+// existing folders case-insensitively; an empty path is the top level.
+func (s *FolderService) EnsurePath(ctx context.Context, userID string, names []string) (string, error) {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return "", err
+	}
+	if len(names) > maxFolderDepth {
+		return "", appErr.ErrInvalid
+	}
+	cleaned := make([]string, 0, len(names))
+	for _, name := range names {
+		name, err := normalizeFolderName(name)
+		if err != nil {
+			return "", err
+		}
+		cleaned = append(cleaned, name)
+	}
+	if len(cleaned) == 0 {
+		return "", nil
+	}
+	var folderID string
+	err := s.runInTx(ctx, func(txCtx context.Context) error {
+		folderID = ""
+		tree, err := s.loadTree(txCtx, userID)
+		if err != nil {
+			return err
+		}
+		for _, name := range cleaned {
+			if folderID, err = s.ensureChild(txCtx, tree, userID, folderID, name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return folderID, nil
+}
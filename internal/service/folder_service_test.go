@@ -0,0 +1,350 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+// fakeFolderRepo keeps folders and the folder of each document in memory,
+// enforcing the unique sibling name index of the folders table.
+type fakeFolderRepo struct {
+	folders map[string]model.Folder
+	docs    map[string]string
+	// hidden folders are left out of List, as if created by another
+	// transaction after the tree was read.
+	hidden map[string]bool
+	lists  int
+}
+
+func newFakeFolderRepo() *fakeFolderRepo {
+	return &fakeFolderRepo{folders: make(map[string]model.Folder), docs: make(map[string]string)}
+}
+
+func (r *fakeFolderRepo) nameTaken(folder *model.Folder) bool {
+	for _, other := range r.folders {
+		if other.ID != folder.ID && other.UserID == folder.UserID && other.ParentID == folder.ParentID &&
+			strings.EqualFold(other.Name, folder.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *fakeFolderRepo) Create(_ context.Context, folder *model.Folder) error {
+	if r.nameTaken(folder) {
+		return appErr.ErrConflict
+	}
+	r.folders[folder.ID] = *folder
+	return nil
+}
+
+func (r *fakeFolderRepo) CreateIfAbsent(_ context.Context, folder *model.Folder) (bool, error) {
+	if r.nameTaken(folder) {
+		return false, nil
+	}
+	r.folders[folder.ID] = *folder
+	return true, nil
+}
+
+func (r *fakeFolderRepo) GetByName(_ context.Context, userID, parentID, name string) (*model.Folder, error) {
+	for _, folder := range r.folders {
+		if folder.UserID == userID && folder.ParentID == parentID && strings.EqualFold(folder.Name, name) {
+			return &folder, nil
+		}
+	}
+	return nil, appErr.ErrNotFound
+}
+
+func (r *fakeFolderRepo) GetByID(_ context.Context, userID, folderID string) (*model.Folder, error) {
+	folder, ok := r.folders[folderID]
+	if !ok || folder.UserID != userID {
+		return nil, appErr.ErrNotFound
+	}
+	return &folder, nil
+}
+
+func (r *fakeFolderRepo) List(_ context.Context, userID string) ([]model.Folder, error) {
+	r.lists++
+	folders := make([]model.Folder, 0, len(r.folders))
+	for _, folder := range r.folders {
+		if folder.UserID == userID && !r.hidden[folder.ID] {
+			folders = append(folders, folder)
+		}
+	}
+	sort.Slice(folders, func(i, j int) bool {
+		if folders[i].ParentID != folders[j].ParentID {
+			return folders[i].ParentID < folders[j].ParentID
+		}
+		return folders[i].Position < folders[j].Position
+	})
+	return folders, nil
+}
+
+func (r *fakeFolderRepo) Update(_ context.Context, folder *model.Folder) error {
+	if _, ok := r.folders[folder.ID]; !ok {
+		return appErr.ErrNotFound
+	}
+	if r.nameTaken(folder) {
+		return appErr.ErrConflict
+	}
+	r.folders[folder.ID] = *folder
+	return nil
+}
+
+func (r *fakeFolderRepo) Reorder(_ context.Context, _, parentID string, folderIDs []string, _ int64) error {
+	for i, id := range folderIDs {
+		if folder, ok := r.folders[id]; ok && folder.ParentID == parentID {
+			folder.Position = i
+			r.folders[id] = folder
+		}
+	}
+	return nil
+}
+
+func (r *fakeFolderRepo) Delete(_ context.Context, _ string, folderIDs []string, parentID string) error {
+	for _, id := range folderIDs {
+		delete(r.folders, id)
+		for docID, folderID := range r.docs {
+			if folderID == id {
+				r.docs[docID] = parentID
+			}
+		}
+	}
+	return nil
+}
+
+func (r *fakeFolderRepo) CountDocuments(context.Context, string) (map[string]int, error) {
+	counts := make(map[string]int)
+	for _, folderID := range r.docs {
+		if folderID != "" {
+			counts[folderID]++
+		}
+	}
+	return counts, nil
+}
+
+func (r *fakeFolderRepo) UpdateFolder(_ context.Context, _, docID, folderID string) error {
+	if _, ok := r.docs[docID]; !ok {
+		return appErr.ErrNotFound
+	}
+	r.docs[docID] = folderID
+	return nil
+}
+
+func newTestFolderService() (*FolderService, *fakeFolderRepo) {
+	folders := newFakeFolderRepo()
+	return NewFolderService(testRuntimeAt(1000), folders, folders), folders
+}
+
+func childNames(t *testing.T, svc *FolderService, parentID string) []string {
+	t.Helper()
+	tree, err := svc.loadTree(context.Background(), "u1")
+	require.NoError(t, err)
+	names := make([]string, 0)
+	for _, folder := range tree.children[parentID] {
+		names = append(names, folder.Name)
+	}
+	return names
+}
+
+func TestNormalizeFolderName(t *testing.T) {
+	name, err := normalizeFolderName("  Work  ")
+	require.NoError(t, err)
+	assert.Equal(t, "Work", name)
+
+	for _, bad := range []string{"", "   ", ".", "..", "a/b", `a\b`, "a\tb", strings.Repeat("x", 65)} {
+		_, err := normalizeFolderName(bad)
+		assert.ErrorIs(t, err, appErr.ErrInvalid, bad)
+	}
+}
+
+func TestFolderService_Create(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestFolderService()
+
+	work, err := svc.Create(ctx, "u1", "", "Work")
+	require.NoError(t, err)
+	assert.Equal(t, 0, work.Position)
+	assert.Equal(t, int64(1000), work.Ctime)
+	home, err := svc.Create(ctx, "u1", "", "Home")
+	require.NoError(t, err)
+	assert.Equal(t, 1, home.Position)
+	sub, err := svc.Create(ctx, "u1", work.ID, "2024")
+	require.NoError(t, err)
+	assert.Equal(t, work.ID, sub.ParentID)
+	assert.Equal(t, 0, sub.Position)
+
+	_, err = svc.Create(ctx, "u1", "", "work")
+	assert.ErrorIs(t, err, appErr.ErrConflict)
+	_, err = svc.Create(ctx, "u1", "missing", "Notes")
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+	_, err = svc.Create(ctx, "u1", "", "a/b")
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+}
+
+func TestFolderService_Create_MaxDepth(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestFolderService()
+	parentID := ""
+	for i := 0; i < maxFolderDepth; i++ {
+		folder, err := svc.Create(ctx, "u1", parentID, "level")
+		require.NoError(t, err)
+		parentID = folder.ID
+	}
+	_, err := svc.Create(ctx, "u1", parentID, "level")
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+}
+
+func TestFolderService_Move(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestFolderService()
+	a, err := svc.Create(ctx, "u1", "", "A")
+	require.NoError(t, err)
+	b, err := svc.Create(ctx, "u1", "", "B")
+	require.NoError(t, err)
+	c, err := svc.Create(ctx, "u1", "", "C")
+	require.NoError(t, err)
+
+	moved, err := svc.Move(ctx, "u1", c.ID, "", 0)
+	require.NoError(t, err)
+	assert.Equal(t, 0, moved.Position)
+	assert.Equal(t, []string{"C", "A", "B"}, childNames(t, svc, ""))
+
+	_, err = svc.Move(ctx, "u1", a.ID, b.ID, -1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"C", "B"}, childNames(t, svc, ""))
+	assert.Equal(t, []string{"A"}, childNames(t, svc, b.ID))
+
+	_, err = svc.Move(ctx, "u1", b.ID, a.ID, -1)
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+	_, err = svc.Move(ctx, "u1", b.ID, b.ID, -1)
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+	_, err = svc.Move(ctx, "u1", "missing", "", -1)
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+	_, err = svc.Move(ctx, "u1", c.ID, "missing", -1)
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
+
+func TestFolderService_Move_MaxDepth(t *testing.T) {
+	ctx := context.Background()
+	svc, _ := newTestFolderService()
+	parentID := ""
+	for i := 0; i < maxFolderDepth-1; i++ {
+		folder, err := svc.Create(ctx, "u1", parentID, "deep")
+		require.NoError(t, err)
+		parentID = folder.ID
+	}
+	top, err := svc.Create(ctx, "u1", "", "Top")
+	require.NoError(t, err)
+	_, err = svc.Create(ctx, "u1", top.ID, "Child")
+	require.NoError(t, err)
+
+	_, err = svc.Move(ctx, "u1", top.ID, parentID, -1)
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+}
+
+func TestFolderService_Delete(t *testing.T) {
+	ctx := context.Background()
+	svc, repo := newTestFolderService()
+	work, err := svc.Create(ctx, "u1", "", "Work")
+	require.NoError(t, err)
+	sub, err := svc.Create(ctx, "u1", work.ID, "2024")
+	require.NoError(t, err)
+	leaf, err := svc.Create(ctx, "u1", sub.ID, "Q1")
+	require.NoError(t, err)
+	repo.docs["d1"] = sub.ID
+	repo.docs["d2"] = leaf.ID
+
+	require.NoError(t, svc.Delete(ctx, "u1", sub.ID))
+	assert.Len(t, repo.folders, 1)
+	assert.Equal(t, work.ID, repo.docs["d1"])
+	assert.Equal(t, work.ID, repo.docs["d2"])
+
+	assert.ErrorIs(t, svc.Delete(ctx, "u1", sub.ID), appErr.ErrNotFound)
+}
+
+func TestFolderService_Tree(t *testing.T) {
+	ctx := context.Background()
+	svc, repo := newTestFolderService()
+	work, err := svc.Create(ctx, "u1", "", "Work")
+	require.NoError(t, err)
+	_, err = svc.Create(ctx, "u1", work.ID, "2024")
+	require.NoError(t, err)
+	_, err = svc.Create(ctx, "u1", "", "Home")
+	require.NoError(t, err)
+	repo.docs["d1"] = work.ID
+	repo.docs["d2"] = ""
+
+	nodes, err := svc.Tree(ctx, "u1")
+	require.NoError(t, err)
+	require.Len(t, nodes, 2)
+	assert.Equal(t, "Work", nodes[0].Name)
+	assert.Equal(t, 1, nodes[0].DocumentCount)
+	require.Len(t, nodes[0].Children, 1)
+	assert.Equal(t, "2024", nodes[0].Children[0].Name)
+	assert.Empty(t, nodes[0].Children[0].Children)
+	assert.Equal(t, "Home", nodes[1].Name)
+}
+
+func TestFolderService_MoveDocument(t *testing.T) {
+	ctx := context.Background()
+	svc, repo := newTestFolderService()
+	work, err := svc.Create(ctx, "u1", "", "Work")
+	require.NoError(t, err)
+	repo.docs["d1"] = ""
+
+	require.NoError(t, svc.MoveDocument(ctx, "u1", "d1", work.ID))
+	assert.Equal(t, work.ID, repo.docs["d1"])
+	require.NoError(t, svc.MoveDocument(ctx, "u1", "d1", ""))
+	assert.Equal(t, "", repo.docs["d1"])
+
+	assert.ErrorIs(t, svc.MoveDocument(ctx, "u1", "d1", "missing"), appErr.ErrNotFound)
+	assert.ErrorIs(t, svc.MoveDocument(ctx, "u2", "d1", work.ID), appErr.ErrNotFound)
+	assert.ErrorIs(t, svc.MoveDocument(ctx, "u1", "d2", work.ID), appErr.ErrNotFound)
+}
+
+func TestFolderService_EnsurePath(t *testing.T) {
+	ctx := context.Background()
+	svc, repo := newTestFolderService()
+	work, err := svc.Create(ctx, "u1", "", "Work")
+	require.NoError(t, err)
+
+	id, err := svc.EnsurePath(ctx, "u1", []string{"work", "2024", "Q1"})
+	require.NoError(t, err)
+	assert.Len(t, repo.folders, 3)
+	assert.Equal(t, []string{"2024"}, childNames(t, svc, work.ID))
+	assert.Equal(t, "Q1", repo.folders[id].Name)
+
+	again, err := svc.EnsurePath(ctx, "u1", []string{"Work", "2024", "q1"})
+	require.NoError(t, err)
+	assert.Equal(t, id, again)
+	assert.Len(t, repo.folders, 3)
+
+	top, err := svc.EnsurePath(ctx, "u1", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "", top)
+
+	_, err = svc.EnsurePath(ctx, "u1", []string{"ok", ".."})
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+}
+
+func TestFolderService_EnsurePath_CreatedConcurrently(t *testing.T) {
+	ctx := context.Background()
+	svc, repo := newTestFolderService()
+	work, err := svc.Create(ctx, "u1", "", "Work")
+	require.NoError(t, err)
+	repo.hidden = map[string]bool{work.ID: true}
+
+	id, err := svc.EnsurePath(ctx, "u1", []string{"work", "Notes"})
+	require.NoError(t, err)
+	assert.Len(t, repo.folders, 2)
+	assert.Equal(t, work.ID, repo.folders[id].ParentID)
+}
@@ -7,7 +7,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
@@ -28,6 +30,7 @@ type ImportPreview struct {
 type ImportService struct {
 	documents importDocumentService
 	tags      importTagService
+	folders   importFolderService
 	jobRepo   importJobRepo
 	noteRepo  importJobNoteRepo
 	runtime   Runtime
@@ -42,6 +45,14 @@ type importDocumentService interface {
 type importTagService interface {
 	ListByNames(ctx context.Context, userID string, names []string) ([]model.Tag, error)
 	CreateBatch(ctx context.Context, userID string, names []string) ([]model.Tag, error)
+	Update(ctx context.Context, userID, tagID string, input TagUpdateInput) (*model.Tag, error)
+}
+
+// importFolderService files imported documents in the folders matching the
+// directories of the archive.
+type importFolderService interface {
+	EnsurePath(ctx context.Context, userID string, names []string) (string, error)
+	MoveDocument(ctx context.Context, userID, docID, folderID string) error
 }
 
 const (
@@ -57,9 +68,12 @@ type importJobConfirmer interface {
 	) (bool, error)
 }
 
+// NewImportService builds the import service. folders may be nil, in which
+// case imported documents stay at the top level whatever their directory.
 func NewImportService(
 	documents importDocumentService,
 	tags importTagService,
+	folders importFolderService,
 	jobRepo importJobRepo,
 	noteRepo importJobNoteRepo,
 	runtime Runtime,
@@ -67,6 +81,7 @@ func NewImportService(
 	return &ImportService{
 		documents: documents,
 		tags:      tags,
+		folders:   folders,
 		jobRepo:   jobRepo,
 		noteRepo:  noteRepo,
 		runtime:   prepareRuntime(runtime),
@@ -74,14 +89,16 @@ func NewImportService(
 }
 
 type notesImportPayload struct {
-	Title   string   `json:"title"`
-	Content string   `json:"content"`
-	TagList []string `json:"tag_list,omitempty"`
+	Title   string           `json:"title"`
+	Content string           `json:"content"`
+	TagList []string         `json:"tag_list,omitempty"`
+	Tags    []NotesExportTag `json:"tags,omitempty"`
 }
 
 type parsedNote struct {
-	note model.ImportNote
-	row  model.ImportJobNote
+	note    model.ImportNote
+	row     model.ImportJobNote
+	tagMeta []model.Tag
 }
 
 type (
@@ -143,7 +160,16 @@ func (s *ImportService) CreateNotesJob(ctx context.Context, userID, filePath str
 		if content != "" {
 			content = strings.TrimRight(content, "\n")
 		}
-		cleanTags := normalizeTags(payload.TagList)
+		names := append([]string{}, payload.TagList...)
+		tagMeta := make([]model.Tag, 0, len(payload.Tags))
+		for _, tag := range payload.Tags {
+			names = append(names, tag.Name)
+			tagMeta = append(tagMeta, model.Tag{
+				Name: normalizeTagName(tag.Name), Color: tag.Color,
+				Description: tag.Description, Icon: tag.Icon,
+			})
+		}
+		cleanTags := normalizeTags(names)
 		noteID, err := s.runtime.IDs.ID()
 		if err != nil {
 			return nil, fmt.Errorf("generate import note id: %w", err)
@@ -154,6 +180,7 @@ func (s *ImportService) CreateNotesJob(ctx context.Context, userID, filePath str
 				ID: noteID, JobID: jobID, UserID: userID, Position: position,
 				Title: title, Content: content, Tags: cleanTags, Source: file.Name, Ctime: now,
 			},
+			tagMeta: tagMeta,
 		}, nil
 	}
 	return s.createImportJob(ctx, userID, "notes", true, filePath, filter, parser)
@@ -231,6 +258,7 @@ func (s *ImportService) parseZipFiles(
 ) ([]model.ImportJobNote, map[string]bool, error) {
 	var noteRows []model.ImportJobNote
 	uniqueTags := make(map[string]bool)
+	seenMeta := make(map[string]bool)
 	position := 0
 	for _, file := range reader.File {
 		if file.FileInfo().IsDir() {
@@ -249,6 +277,14 @@ func (s *ImportService) parseZipFiles(
 		for _, tag := range parsed.note.Tags {
 			uniqueTags[tag] = true
 		}
+		for _, meta := range parsed.tagMeta {
+			key := strings.ToLower(meta.Name)
+			if meta.Name == "" || seenMeta[key] {
+				continue
+			}
+			seenMeta[key] = true
+			job.TagMeta = append(job.TagMeta, meta)
+		}
 		noteRows = append(noteRows, parsed.row)
 		position++
 	}
@@ -418,6 +454,108 @@ func (s *ImportService) lookupByTitle(
 	return "", false, fmt.Errorf("get document by title: %w", err)
 }
 
+// importFolderPath turns the directory of a zip entry into folder names from
+// the top level down. Segments that cannot name a folder, such as "..", are
+// dropped, and so is the part of a deep path past maxFolderDepth.
+func importFolderPath(source string) []string {
+	names := make([]string, 0)
+	for _, segment := range strings.Split(path.Dir(strings.ReplaceAll(source, `\`, "/")), "/") {
+		name, err := normalizeFolderName(segment)
+		if err != nil {
+			continue
+		}
+		if len(names) == maxFolderDepth {
+			break
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+// importFolderCache remembers the folders resolved for the notes of one job
+// by directory, so the folder tree is read once per directory rather than
+// once per note. A folder resolved in a note's transaction is kept only after
+// that transaction commits, since a rollback undoes its creation.
+type importFolderCache struct {
+	resolved map[string]string
+	pending  map[string]string
+}
+
+func newImportFolderCache() *importFolderCache {
+	return &importFolderCache{resolved: make(map[string]string), pending: make(map[string]string)}
+}
+
+func (c *importFolderCache) lookup(key string) (string, bool) {
+	if folderID, ok := c.pending[key]; ok {
+		return folderID, true
+	}
+	folderID, ok := c.resolved[key]
+	return folderID, ok
+}
+
+// commit keeps the folders resolved since the last commit or discard.
+func (c *importFolderCache) commit() {
+	maps.Copy(c.resolved, c.pending)
+	clear(c.pending)
+}
+
+// discard forgets the folders resolved since the last commit or discard.
+func (c *importFolderCache) discard() {
+	clear(c.pending)
+}
+
+// importFolder returns the folder an imported note goes to, creating it when
+// missing, or "" for a note at the root of the archive. cache may be nil.
+func (s *ImportService) importFolder(
+	ctx context.Context, userID, source string, cache *importFolderCache,
+) (string, error) {
+	names := importFolderPath(source)
+	if s.folders == nil || len(names) == 0 {
+		return "", nil
+	}
+	key := strings.ToLower(strings.Join(names, "/"))
+	if cache != nil {
+		if folderID, ok := cache.lookup(key); ok {
+			return folderID, nil
+		}
+	}
+	folderID, err := s.folders.EnsurePath(ctx, userID, names)
+	if err != nil {
+		return "", fmt.Errorf("ensure folder path: %w", err)
+	}
+	if cache != nil {
+		cache.pending[key] = folderID
+	}
+	return folderID, nil
+}
+
+// writeInFolder runs write, which creates or updates a document and returns
+// its ID, and files that document in folderID in the same transaction. An
+// empty folderID leaves the document where it is.
+func (s *ImportService) writeInFolder(
+	ctx context.Context, userID, folderID string, write func(ctx context.Context) (string, error),
+) (string, error) {
+	if folderID == "" {
+		return write(ctx)
+	}
+	var docID string
+	err := s.runtime.Transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+		id, err := write(txCtx)
+		if err != nil {
+			return err
+		}
+		docID = id
+		if err := s.folders.MoveDocument(txCtx, userID, id, folderID); err != nil {
+			return fmt.Errorf("move document: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("write in folder: %w", err)
+	}
+	return docID, nil
+}
+
 func (s *ImportService) runImport(ctx context.Context, job *model.ImportJob, mode string) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -462,7 +600,7 @@ func (s *ImportService) importNote(
 		prog.tick()
 		return
 	}
-	tagIDs, err := s.ensureTags(ctx, job.UserID, note.Tags)
+	tagIDs, err := s.ensureTagsWithMeta(ctx, job.UserID, note.Tags, job.TagMeta)
 	if err != nil {
 		prog.recordFail(fmt.Sprintf("create tags failed: %s", note.Title), note.Title)
 		return
@@ -513,6 +651,16 @@ func (s *ImportService) overwriteNote(
 }
 
 func (s *ImportService) ensureTags(ctx context.Context, userID string, tags []string) ([]string, error) {
+	return s.ensureTagsWithMeta(ctx, userID, tags, nil)
+}
+
+// ensureTagsWithMeta resolves tag names to IDs, creating missing tags. Newly
+// created tags take their color, description and icon from meta; existing
+// tags keep their current appearance, and invalid appearance values from the
+// archive are dropped rather than failing the note.
+func (s *ImportService) ensureTagsWithMeta(
+	ctx context.Context, userID string, tags []string, meta []model.Tag,
+) ([]string, error) {
 	if len(tags) == 0 {
 		return []string{}, nil
 	}
@@ -542,6 +690,9 @@ func (s *ImportService) ensureTags(ctx context.Context, userID string, tags []st
 		for _, tag := range created {
 			ids[strings.ToLower(tag.Name)] = tag.ID
 		}
+		if err := s.applyTagMeta(ctx, userID, created, meta); err != nil {
+			return nil, err
+		}
 	}
 	result := make([]string, 0, len(cleaned))
 	for _, name := range cleaned {
@@ -552,6 +703,29 @@ func (s *ImportService) ensureTags(ctx context.Context, userID string, tags []st
 	return result, nil
 }
 
+func (s *ImportService) applyTagMeta(ctx context.Context, userID string, created, meta []model.Tag) error {
+	if len(meta) == 0 {
+		return nil
+	}
+	byName := make(map[string]model.Tag, len(meta))
+	for _, item := range meta {
+		byName[strings.ToLower(item.Name)] = item
+	}
+	for _, tag := range created {
+		item, ok := byName[strings.ToLower(tag.Name)]
+		if !ok || (item.Color == "" && item.Description == "" && item.Icon == "") {
+			continue
+		}
+		_, err := s.tags.Update(ctx, userID, tag.ID, TagUpdateInput{
+			Color: item.Color, Description: item.Description, Icon: item.Icon,
+		})
+		if err != nil && !errors.Is(err, appErr.ErrInvalid) {
+			return fmt.Errorf("update tag appearance: %w", err)
+		}
+	}
+	return nil
+}
+
 func (s *ImportService) appendSuffix(ctx context.Context, userID, title string) string {
 	base := strings.TrimSpace(title)
 	if base == "" {
@@ -602,7 +776,7 @@ func normalizeTags(tags []string) []string {
 	seen := make(map[string]bool)
 	result := make([]string, 0, len(tags))
 	for _, tag := range tags {
-		normalized := strings.TrimSpace(tag)
+		normalized := normalizeTagName(tag)
 		if normalized == "" {
 			continue
 		}
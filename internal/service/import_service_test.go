@@ -112,7 +112,7 @@ func TestImportService_Status(t *testing.T) {
 			return &model.ImportJob{ID: "j1", Status: "ready", Total: 5}, nil
 		},
 	}
-	svc := NewImportService(nil, nil, jobRepo, nil, testRuntime())
+	svc := NewImportService(nil, nil, nil, jobRepo, nil, testRuntime())
 	job, err := svc.Status(context.Background(), "u1", "j1")
 	require.NoError(t, err)
 	assert.Equal(t, model.ImportStatusReady, job.Status)
@@ -125,7 +125,7 @@ func TestImportService_Status_Error(t *testing.T) {
 			return nil, appErr.ErrNotFound
 		},
 	}
-	svc := NewImportService(nil, nil, jobRepo, nil, testRuntime())
+	svc := NewImportService(nil, nil, nil, jobRepo, nil, testRuntime())
 	_, err := svc.Status(context.Background(), "u1", "bad")
 	assert.Error(t, err)
 }
@@ -155,7 +155,7 @@ func TestImportService_Preview(t *testing.T) {
 				}, nil
 			},
 		}
-		svc := NewImportService(docSvc, nil, jobRepo, noteRepo, testRuntime())
+		svc := NewImportService(docSvc, nil, nil, jobRepo, noteRepo, testRuntime())
 		preview, err := svc.Preview(context.Background(), "u1", "j1")
 		require.NoError(t, err)
 		assert.Equal(t, 2, preview.NotesCount)
@@ -187,7 +187,7 @@ func TestImportService_Preview(t *testing.T) {
 				return nil, nil
 			},
 		}
-		svc := NewImportService(docSvc, nil, jobRepo, noteRepo, testRuntime())
+		svc := NewImportService(docSvc, nil, nil, jobRepo, noteRepo, testRuntime())
 		preview, err := svc.Preview(context.Background(), "u1", "j1")
 		require.NoError(t, err)
 		assert.Equal(t, 1, preview.Conflicts)
@@ -199,7 +199,7 @@ func TestImportService_Preview(t *testing.T) {
 				return &model.ImportJob{ID: "j1"}, nil
 			},
 		}
-		svc := NewImportService(nil, nil, jobRepo, nil, testRuntime())
+		svc := NewImportService(nil, nil, nil, jobRepo, nil, testRuntime())
 		_, err := svc.Preview(context.Background(), "u1", "j1")
 		assert.ErrorIs(t, err, appErr.ErrInvalid)
 	})
@@ -212,7 +212,7 @@ func TestImportService_Confirm(t *testing.T) {
 				return &model.ImportJob{ID: "j1", Status: "ready"}, nil
 			},
 		}
-		svc := NewImportService(nil, nil, jobRepo, nil, testRuntime())
+		svc := NewImportService(nil, nil, nil, jobRepo, nil, testRuntime())
 		err := svc.Confirm(context.Background(), "u1", "j1", "invalid")
 		assert.ErrorIs(t, err, appErr.ErrInvalid)
 	})
@@ -223,7 +223,7 @@ func TestImportService_Confirm(t *testing.T) {
 				return &model.ImportJob{ID: "j1", Status: "running"}, nil
 			},
 		}
-		svc := NewImportService(nil, nil, jobRepo, nil, testRuntime())
+		svc := NewImportService(nil, nil, nil, jobRepo, nil, testRuntime())
 		err := svc.Confirm(context.Background(), "u1", "j1", "append")
 		require.NoError(t, err)
 	})
@@ -248,7 +248,7 @@ func TestImportService_Confirm(t *testing.T) {
 		}
 		docRepo := &mockDocumentRepo{}
 		docSvc := newDocSvc(docRepo, nil, nil, nil)
-		svc := NewImportService(docSvc, nil, jobRepo, noteRepo, testRuntime())
+		svc := NewImportService(docSvc, nil, nil, jobRepo, noteRepo, testRuntime())
 		err := svc.Confirm(context.Background(), "u1", "j1", "")
 		require.NoError(t, err)
 	})
@@ -262,7 +262,7 @@ func TestImportService_Confirm(t *testing.T) {
 				return false, nil
 			},
 		}
-		svc := NewImportService(nil, nil, jobRepo, nil, testRuntime())
+		svc := NewImportService(nil, nil, nil, jobRepo, nil, testRuntime())
 		err := svc.Confirm(context.Background(), "u1", "j1", "append")
 		assert.ErrorIs(t, err, appErr.ErrInvalid)
 	})
@@ -287,7 +287,7 @@ func TestImportService_EnsureTags(t *testing.T) {
 			},
 		}
 		docTagRepo := &mockDocumentTagRepo{}
-		tagSvc := NewTagService(testRuntime(), tagRepo, docTagRepo)
+		tagSvc := NewTagService(testRuntime(), tagRepo, docTagRepo, nil)
 		svc := &ImportService{tags: tagSvc}
 		ids, err := svc.ensureTags(context.Background(), "u1", []string{"go", "rust"})
 		require.NoError(t, err)
@@ -307,20 +307,54 @@ func TestImportService_EnsureTags(t *testing.T) {
 			},
 		}
 		docTagRepo := &mockDocumentTagRepo{}
-		tagSvc := NewTagService(testRuntime(), tagRepo, docTagRepo)
+		tagSvc := NewTagService(testRuntime(), tagRepo, docTagRepo, nil)
 		svc := &ImportService{tags: tagSvc}
 		ids, err := svc.ensureTags(context.Background(), "u1", []string{"go", "rust"})
 		require.NoError(t, err)
 		assert.Len(t, ids, 2)
 	})
 
+	t.Run("applies_meta_to_new_tags_only", func(t *testing.T) {
+		updated := map[string]*model.Tag{}
+		tagRepo := &mockTagRepo{
+			listByNamesFn: func(context.Context, string, []string) ([]model.Tag, error) {
+				return []model.Tag{{ID: "t1", Name: "go"}}, nil
+			},
+			createBatchFn: func(_ context.Context, tags []model.Tag) error {
+				for i := range tags {
+					tags[i].ID = "new-" + tags[i].Name
+				}
+				return nil
+			},
+			updateAppearFn: func(_ context.Context, tag *model.Tag) error {
+				updated[tag.ID] = tag
+				return nil
+			},
+			listByIDsFn: func(_ context.Context, _ string, ids []string) ([]model.Tag, error) {
+				return []model.Tag{{ID: ids[0]}}, nil
+			},
+		}
+		tagSvc := NewTagService(testRuntime(), tagRepo, &mockDocumentTagRepo{}, nil)
+		svc := &ImportService{tags: tagSvc}
+		ids, err := svc.ensureTagsWithMeta(context.Background(), "u1", []string{"go", "Rust", "misc"}, []model.Tag{
+			{Name: "go", Color: "#00add8"},
+			{Name: "rust", Color: "#dea584", Icon: "🦀"},
+			{Name: "misc", Color: "not-a-color"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, []string{"t1", "new-Rust", "new-misc"}, ids)
+		require.Len(t, updated, 1)
+		assert.Equal(t, "#dea584", updated["new-Rust"].Color)
+		assert.Equal(t, "🦀", updated["new-Rust"].Icon)
+	})
+
 	t.Run("list_error", func(t *testing.T) {
 		tagRepo := &mockTagRepo{
 			listByNamesFn: func(context.Context, string, []string) ([]model.Tag, error) {
 				return nil, errors.New("db error")
 			},
 		}
-		tagSvc := NewTagService(testRuntime(), tagRepo, nil)
+		tagSvc := NewTagService(testRuntime(), tagRepo, nil, nil)
 		svc := &ImportService{tags: tagSvc}
 		_, err := svc.ensureTags(context.Background(), "u1", []string{"go"})
 		assert.Error(t, err)
@@ -468,7 +502,7 @@ func TestImportService_CreateHedgeDocJob(t *testing.T) {
 				return nil
 			},
 		}
-		svc := NewImportService(nil, nil, jobRepo, noteRepo, testRuntime())
+		svc := NewImportService(nil, nil, nil, jobRepo, noteRepo, testRuntime())
 		job, err := svc.CreateHedgeDocJob(context.Background(), "u1", zipPath)
 		require.NoError(t, err)
 		assert.Equal(t, model.ImportStatusReady, job.Status)
@@ -480,13 +514,13 @@ func TestImportService_CreateHedgeDocJob(t *testing.T) {
 	t.Run("nil_repos", func(t *testing.T) {
 		zipPath := createTestZipWithMD(t, map[string]string{"note.md": "hello"})
 		defer func() { _ = os.Remove(zipPath) }()
-		svc := NewImportService(nil, nil, nil, nil, testRuntime())
+		svc := NewImportService(nil, nil, nil, nil, nil, testRuntime())
 		_, err := svc.CreateHedgeDocJob(context.Background(), "u1", zipPath)
 		assert.ErrorIs(t, err, appErr.ErrInvalid)
 	})
 
 	t.Run("invalid_zip", func(t *testing.T) {
-		svc := NewImportService(nil, nil, &mockImportJobRepo{}, &mockImportJobNoteRepo{}, testRuntime())
+		svc := NewImportService(nil, nil, nil, &mockImportJobRepo{}, &mockImportJobNoteRepo{}, testRuntime())
 		_, err := svc.CreateHedgeDocJob(context.Background(), "u1", "/nonexistent.zip")
 		assert.Error(t, err)
 	})
@@ -501,7 +535,7 @@ func TestImportService_CreateHedgeDocJob(t *testing.T) {
 			createFn: func(context.Context, *model.ImportJob) error { return nil },
 			deleteFn: func(context.Context, string, string) error { return nil },
 		}
-		svc := NewImportService(nil, nil, jobRepo, &mockImportJobNoteRepo{}, testRuntime())
+		svc := NewImportService(nil, nil, nil, jobRepo, &mockImportJobNoteRepo{}, testRuntime())
 		_, err := svc.CreateHedgeDocJob(context.Background(), "u1", zipPath)
 		assert.ErrorIs(t, err, appErr.ErrInvalid)
 	})
@@ -526,13 +560,58 @@ func TestImportService_CreateNotesJob(t *testing.T) {
 				return nil
 			},
 		}
-		svc := NewImportService(nil, nil, jobRepo, noteRepo, testRuntime())
+		svc := NewImportService(nil, nil, nil, jobRepo, noteRepo, testRuntime())
 		job, err := svc.CreateNotesJob(context.Background(), "u1", zipPath)
 		require.NoError(t, err)
 		assert.Equal(t, model.ImportStatusReady, job.Status)
 		assert.Equal(t, 2, job.Total)
 	})
 
+	t.Run("stages_tag_meta", func(t *testing.T) {
+		zipPath := createTestZipWithJSON(t, map[string]notesImportPayload{
+			"note1.json": {
+				Title: "Note 1", Content: "Hello", TagList: []string{"go"},
+				Tags: []NotesExportTag{{Name: "go", Color: "#00add8"}, {Name: "lang/rust", Icon: "🦀"}},
+			},
+			"note2.json": {
+				Title: "Note 2", Content: "World",
+				Tags: []NotesExportTag{{Name: "go", Color: "#ffffff"}},
+			},
+		})
+		defer func() { _ = os.Remove(zipPath) }()
+
+		var staged *model.ImportJob
+		jobRepo := &mockImportJobRepo{
+			createFn: func(_ context.Context, job *model.ImportJob) error {
+				staged = job
+				return nil
+			},
+			updateSummaryFn: func(context.Context, *model.ImportJob) error { return nil },
+			deleteFn:        func(context.Context, string, string) error { return nil },
+		}
+		var notes []model.ImportJobNote
+		noteRepo := &mockImportJobNoteRepo{
+			insertBatchFn: func(_ context.Context, rows []model.ImportJobNote) error {
+				notes = rows
+				return nil
+			},
+		}
+		svc := NewImportService(nil, nil, nil, jobRepo, noteRepo, testRuntime())
+		_, err := svc.CreateNotesJob(context.Background(), "u1", zipPath)
+		require.NoError(t, err)
+		require.Len(t, staged.TagMeta, 2)
+		byName := map[string]model.Tag{}
+		for _, tag := range staged.TagMeta {
+			byName[tag.Name] = tag
+		}
+		assert.Equal(t, "🦀", byName["lang/rust"].Icon)
+		for _, note := range notes {
+			if note.Title == "Note 1" {
+				assert.Equal(t, []string{"go", "lang/rust"}, note.Tags)
+			}
+		}
+	})
+
 	t.Run("legacy_summary_field_is_ignored", func(t *testing.T) {
 		zipPath := createTestZipWithMD(t, map[string]string{
 			"legacy.json": `{
@@ -559,7 +638,7 @@ func TestImportService_CreateNotesJob(t *testing.T) {
 			},
 		}
 
-		svc := NewImportService(nil, nil, jobRepo, noteRepo, testRuntime())
+		svc := NewImportService(nil, nil, nil, jobRepo, noteRepo, testRuntime())
 		job, err := svc.CreateNotesJob(context.Background(), "u1", zipPath)
 		require.NoError(t, err)
 		assert.Equal(t, model.ImportStatusReady, job.Status)
@@ -579,7 +658,7 @@ func TestImportService_CreateNotesJob(t *testing.T) {
 			createFn: func(context.Context, *model.ImportJob) error { return nil },
 			deleteFn: func(context.Context, string, string) error { return nil },
 		}
-		svc := NewImportService(nil, nil, jobRepo, &mockImportJobNoteRepo{}, testRuntime())
+		svc := NewImportService(nil, nil, nil, jobRepo, &mockImportJobNoteRepo{}, testRuntime())
 		_, err = svc.CreateNotesJob(context.Background(), "u1", tmp.Name())
 		assert.ErrorIs(t, err, appErr.ErrImportInvalidJSON)
 	})
@@ -593,7 +672,7 @@ func TestImportService_RunImport(t *testing.T) {
 				createdTitles = append(createdTitles, doc.Title)
 				return nil
 			},
-			updateLinksFn: func(context.Context, string, string, []string, int64) error { return nil },
+			updateLinksFn: func(context.Context, string, string, []model.LinkTarget, int64) error { return nil },
 			getByTitleFn: func(context.Context, string, string) (*model.Document, error) {
 				return nil, appErr.ErrNotFound
 			},
@@ -624,7 +703,7 @@ func TestImportService_RunImport(t *testing.T) {
 			},
 		}
 
-		svc := NewImportService(docSvc, nil, jobRepo, noteRepo, testRuntime())
+		svc := NewImportService(docSvc, nil, nil, jobRepo, noteRepo, testRuntime())
 		job := &model.ImportJob{ID: "j1", UserID: "u1", Total: 2}
 		svc.runImport(context.Background(), job, "append")
 
@@ -655,7 +734,7 @@ func TestImportService_RunImport(t *testing.T) {
 			},
 		}
 
-		svc := NewImportService(docSvc, nil, jobRepo, noteRepo, testRuntime())
+		svc := NewImportService(docSvc, nil, nil, jobRepo, noteRepo, testRuntime())
 		job := &model.ImportJob{ID: "j1", UserID: "u1", Total: 1}
 		svc.runImport(context.Background(), job, "skip")
 
@@ -699,7 +778,7 @@ func TestImportService_OverwriteNote(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		docRepo := &mockDocumentRepo{
 			updateFn:      func(context.Context, *model.Document) error { return nil },
-			updateLinksFn: func(context.Context, string, string, []string, int64) error { return nil },
+			updateLinksFn: func(context.Context, string, string, []model.LinkTarget, int64) error { return nil },
 		}
 		versions := &mockVersionRepo{
 			createFn:            func(context.Context, *model.DocumentVersion) error { return nil },
@@ -738,7 +817,7 @@ func TestImportService_ImportNote_Overwrite(t *testing.T) {
 			return &model.Document{ID: "d1"}, nil
 		},
 		updateFn:      func(context.Context, *model.Document) error { return nil },
-		updateLinksFn: func(context.Context, string, string, []string, int64) error { return nil },
+		updateLinksFn: func(context.Context, string, string, []model.LinkTarget, int64) error { return nil },
 	}
 	versions := &mockVersionRepo{
 		createFn:            func(context.Context, *model.DocumentVersion) error { return nil },
@@ -761,9 +840,9 @@ func TestImportService_ImportNote_Overwrite(t *testing.T) {
 			return nil
 		},
 	}
-	tagSvc := NewTagService(testRuntime(), tagRepo, &mockDocumentTagRepo{})
+	tagSvc := NewTagService(testRuntime(), tagRepo, &mockDocumentTagRepo{}, nil)
 
-	svc := NewImportService(docSvc, tagSvc, nil, nil, testRuntime())
+	svc := NewImportService(docSvc, tagSvc, nil, nil, nil, testRuntime())
 	prog := &importProgress{
 		report:  &model.ImportReport{},
 		job:     &model.ImportJob{UserID: "u1", RequireContent: false},
@@ -804,7 +883,7 @@ func TestImportService_Preview_Errors(t *testing.T) {
 				return nil, errors.New("db error")
 			},
 		}
-		svc := NewImportService(nil, nil, jobRepo, nil, testRuntime())
+		svc := NewImportService(nil, nil, nil, jobRepo, nil, testRuntime())
 		_, err := svc.Preview(context.Background(), "u1", "j1")
 		assert.Error(t, err)
 	})
@@ -820,7 +899,7 @@ func TestImportService_Preview_Errors(t *testing.T) {
 				return nil, errors.New("db error")
 			},
 		}
-		svc := NewImportService(nil, nil, jobRepo, noteRepo, testRuntime())
+		svc := NewImportService(nil, nil, nil, jobRepo, noteRepo, testRuntime())
 		_, err := svc.Preview(context.Background(), "u1", "j1")
 		assert.Error(t, err)
 	})
@@ -845,7 +924,7 @@ func TestImportService_Preview_Errors(t *testing.T) {
 				return nil, errors.New("db error")
 			},
 		}
-		svc := NewImportService(docSvc, nil, jobRepo, noteRepo, testRuntime())
+		svc := NewImportService(docSvc, nil, nil, jobRepo, noteRepo, testRuntime())
 		_, err := svc.Preview(context.Background(), "u1", "j1")
 		assert.Error(t, err)
 	})
@@ -870,7 +949,7 @@ func TestImportService_Preview_Errors(t *testing.T) {
 				return nil, nil
 			},
 		}
-		svc := NewImportService(docSvc, nil, jobRepo, noteRepo, testRuntime())
+		svc := NewImportService(docSvc, nil, nil, jobRepo, noteRepo, testRuntime())
 		preview, err := svc.Preview(context.Background(), "u1", "j1")
 		require.NoError(t, err)
 		assert.Equal(t, 0, preview.Conflicts)
@@ -884,7 +963,7 @@ func TestImportService_Confirm_Errors(t *testing.T) {
 				return nil, errors.New("db error")
 			},
 		}
-		svc := NewImportService(nil, nil, jobRepo, nil, testRuntime())
+		svc := NewImportService(nil, nil, nil, jobRepo, nil, testRuntime())
 		err := svc.Confirm(context.Background(), "u1", "j1", "append")
 		assert.Error(t, err)
 	})
@@ -898,7 +977,7 @@ func TestImportService_Confirm_Errors(t *testing.T) {
 				return false, errors.New("db error")
 			},
 		}
-		svc := NewImportService(nil, nil, jobRepo, nil, testRuntime())
+		svc := NewImportService(nil, nil, nil, jobRepo, nil, testRuntime())
 		err := svc.Confirm(context.Background(), "u1", "j1", "append")
 		assert.Error(t, err)
 	})
@@ -915,7 +994,7 @@ func TestImportService_RunImport_ListError(t *testing.T) {
 			return nil, errors.New("db error")
 		},
 	}
-	svc := NewImportService(nil, nil, jobRepo, noteRepo, testRuntime())
+	svc := NewImportService(nil, nil, nil, jobRepo, noteRepo, testRuntime())
 	job := &model.ImportJob{ID: "j1", UserID: "u1", Total: 1}
 	svc.runImport(context.Background(), job, "append")
 }
@@ -950,7 +1029,7 @@ func TestImportService_ImportNote_AppendExisting(t *testing.T) {
 			return nil, appErr.ErrNotFound
 		},
 		createFn:      func(context.Context, *model.Document) error { return nil },
-		updateLinksFn: func(context.Context, string, string, []string, int64) error { return nil },
+		updateLinksFn: func(context.Context, string, string, []model.LinkTarget, int64) error { return nil },
 	}
 	versions := &mockVersionRepo{
 		createFn:            func(context.Context, *model.DocumentVersion) error { return nil },
@@ -968,9 +1047,9 @@ func TestImportService_ImportNote_AppendExisting(t *testing.T) {
 			}
 			return nil
 		},
-	}, &mockDocumentTagRepo{})
+	}, &mockDocumentTagRepo{}, nil)
 
-	svc := NewImportService(docSvc, tagSvc, nil, nil, testRuntime())
+	svc := NewImportService(docSvc, tagSvc, nil, nil, nil, testRuntime())
 	prog := &importProgress{
 		report:  &model.ImportReport{},
 		job:     &model.ImportJob{UserID: "u1", RequireContent: false},
@@ -988,7 +1067,7 @@ func TestImportService_ImportNote_LookupError(t *testing.T) {
 		},
 	}
 	docSvc := newDocSvc(docRepo, nil, nil, nil)
-	svc := NewImportService(docSvc, nil, nil, nil, testRuntime())
+	svc := NewImportService(docSvc, nil, nil, nil, nil, testRuntime())
 	prog := &importProgress{
 		report:  &model.ImportReport{},
 		job:     &model.ImportJob{UserID: "u1", RequireContent: false},
@@ -1010,8 +1089,8 @@ func TestImportService_ImportNote_CreateError(t *testing.T) {
 	tagSvc := NewTagService(testRuntime(), &mockTagRepo{
 		listByNamesFn: func(context.Context, string, []string) ([]model.Tag, error) { return nil, nil },
 		createBatchFn: func(context.Context, []model.Tag) error { return nil },
-	}, &mockDocumentTagRepo{})
-	svc := NewImportService(docSvc, tagSvc, nil, nil, testRuntime())
+	}, &mockDocumentTagRepo{}, nil)
+	svc := NewImportService(docSvc, tagSvc, nil, nil, nil, testRuntime())
 	prog := &importProgress{
 		report:  &model.ImportReport{},
 		job:     &model.ImportJob{UserID: "u1"},
@@ -1033,8 +1112,8 @@ func TestImportService_ImportNote_EnsureTagsError(t *testing.T) {
 		listByNamesFn: func(context.Context, string, []string) ([]model.Tag, error) {
 			return nil, errors.New("db error")
 		},
-	}, &mockDocumentTagRepo{})
-	svc := NewImportService(docSvc, tagSvc, nil, nil, testRuntime())
+	}, &mockDocumentTagRepo{}, nil)
+	svc := NewImportService(docSvc, tagSvc, nil, nil, nil, testRuntime())
 	prog := &importProgress{
 		report:  &model.ImportReport{},
 		job:     &model.ImportJob{UserID: "u1"},
@@ -1056,7 +1135,7 @@ func TestImportService_CreateNotesJob_NoteTooLarge(t *testing.T) {
 		createFn: func(context.Context, *model.ImportJob) error { return nil },
 		deleteFn: func(context.Context, string, string) error { return nil },
 	}
-	svc := NewImportService(nil, nil, jobRepo, &mockImportJobNoteRepo{}, testRuntime())
+	svc := NewImportService(nil, nil, nil, jobRepo, &mockImportJobNoteRepo{}, testRuntime())
 	_, err := svc.CreateNotesJob(context.Background(), "u1", zipPath)
 	assert.ErrorIs(t, err, appErr.ErrImportNoteTooLarge)
 }
@@ -1072,7 +1151,7 @@ func TestImportService_CreateImportJob_InsertBatchError(t *testing.T) {
 	noteRepo := &mockImportJobNoteRepo{
 		insertBatchFn: func(context.Context, []model.ImportJobNote) error { return errors.New("db error") },
 	}
-	svc := NewImportService(nil, nil, jobRepo, noteRepo, testRuntime())
+	svc := NewImportService(nil, nil, nil, jobRepo, noteRepo, testRuntime())
 	_, err := svc.CreateHedgeDocJob(context.Background(), "u1", zipPath)
 	assert.Error(t, err)
 }
@@ -1089,7 +1168,81 @@ func TestImportService_CreateImportJob_UpdateSummaryError(t *testing.T) {
 	noteRepo := &mockImportJobNoteRepo{
 		insertBatchFn: func(context.Context, []model.ImportJobNote) error { return nil },
 	}
-	svc := NewImportService(nil, nil, jobRepo, noteRepo, testRuntime())
+	svc := NewImportService(nil, nil, nil, jobRepo, noteRepo, testRuntime())
 	_, err := svc.CreateHedgeDocJob(context.Background(), "u1", zipPath)
 	assert.Error(t, err)
 }
+
+func TestImportFolderPath(t *testing.T) {
+	tests := []struct {
+		source string
+		want   []string
+	}{
+		{source: "note.md", want: []string{}},
+		{source: "Work/2024/note.md", want: []string{"Work", "2024"}},
+		{source: `Work\Drafts\note.md`, want: []string{"Work", "Drafts"}},
+		{source: "./Work/../ Notes /note.json", want: []string{"Notes"}},
+		{source: strings.Repeat("d/", maxFolderDepth+2) + "note.md", want: strings.Fields(strings.Repeat("d ", maxFolderDepth))},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, importFolderPath(tt.source), tt.source)
+	}
+}
+
+func TestImportService_ImportFolder(t *testing.T) {
+	ctx := context.Background()
+	folders, repo := newTestFolderService()
+	svc := NewImportService(nil, nil, folders, nil, nil, testRuntime())
+
+	folderID, err := svc.importFolder(ctx, "u1", "Work/2024/note.md", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "2024", repo.folders[folderID].Name)
+	top, err := svc.importFolder(ctx, "u1", "note.md", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "", top)
+
+	repo.docs["d1"] = ""
+	docID, err := svc.writeInFolder(ctx, "u1", folderID, func(context.Context) (string, error) {
+		return "d1", nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "d1", docID)
+	assert.Equal(t, folderID, repo.docs["d1"])
+
+	_, err = svc.writeInFolder(ctx, "u1", folderID, func(context.Context) (string, error) {
+		return "", errors.New("db error")
+	})
+	assert.Error(t, err)
+
+	none := NewImportService(nil, nil, nil, nil, nil, testRuntime())
+	folderID, err = none.importFolder(ctx, "u1", "Work/note.md", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "", folderID)
+}
+
+func TestImportService_ImportFolder_Cache(t *testing.T) {
+	ctx := context.Background()
+	folders, repo := newTestFolderService()
+	svc := NewImportService(nil, nil, folders, nil, nil, testRuntime())
+	cache := newImportFolderCache()
+
+	first, err := svc.importFolder(ctx, "u1", "Work/a.md", cache)
+	require.NoError(t, err)
+	second, err := svc.importFolder(ctx, "u1", "work/b.md", cache)
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+	assert.Equal(t, 1, repo.lists)
+
+	cache.discard()
+	delete(repo.folders, first)
+	again, err := svc.importFolder(ctx, "u1", "Work/c.md", cache)
+	require.NoError(t, err)
+	assert.NotEqual(t, first, again)
+	assert.Equal(t, 2, repo.lists)
+
+	cache.commit()
+	_, err = svc.importFolder(ctx, "u1", "Work/d.md", cache)
+	require.NoError(t, err)
+	assert.Equal(t, 2, repo.lists)
+	assert.Equal(t, map[string]string{"work": again}, cache.resolved)
+}
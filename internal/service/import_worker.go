@@ -18,6 +18,7 @@ const importLeaseDuration = 2 * time.Minute
 
 type importWorkerJobRepo interface {
 	Claim(ctx context.Context, now, lockedUntil int64) (*model.ImportJob, error)
+	ClaimJob(ctx context.Context, jobID string, now, lockedUntil int64) (*model.ImportJob, error)
 	Finish(
 		ctx context.Context, jobID string, report *model.ImportReport,
 		processed, total int, now int64,
@@ -64,9 +65,27 @@ func NewImportWorker(
 }
 
 func (worker *ImportWorker) Run(ctx context.Context) error {
-	if worker.imports == nil || worker.jobs == nil || worker.notes == nil {
-		return errImportWorkerDependencies
+	if err := worker.ready(); err != nil {
+		return err
 	}
+	return worker.run(ctx, worker.jobs.Claim)
+}
+
+// RunJob processes only the given job until ctx is cancelled. Other queued
+// jobs are left to the server workers; the caller watches the job status to
+// know when to stop.
+func (worker *ImportWorker) RunJob(ctx context.Context, jobID string) error {
+	if err := worker.ready(); err != nil {
+		return err
+	}
+	return worker.run(ctx, func(ctx context.Context, now, lockedUntil int64) (*model.ImportJob, error) {
+		return worker.jobs.ClaimJob(ctx, jobID, now, lockedUntil)
+	})
+}
+
+func (worker *ImportWorker) run(
+	ctx context.Context, claim func(ctx context.Context, now, lockedUntil int64) (*model.ImportJob, error),
+) error {
 	for {
 		select {
 		case <-ctx.Done():
@@ -74,7 +93,7 @@ func (worker *ImportWorker) Run(ctx context.Context) error {
 		default:
 		}
 		now := worker.runtime.Clock.Now()
-		job, err := worker.jobs.Claim(
+		job, err := claim(
 			ctx, now.Unix(), now.Add(importLeaseDuration).Unix(),
 		)
 		if errors.Is(err, appErr.ErrNoWork) {
@@ -100,6 +119,13 @@ func (worker *ImportWorker) Run(ctx context.Context) error {
 	}
 }
 
+func (worker *ImportWorker) ready() error {
+	if worker.imports == nil || worker.jobs == nil || worker.notes == nil {
+		return errImportWorkerDependencies
+	}
+	return nil
+}
+
 func waitImportPoll(ctx context.Context, duration time.Duration) bool {
 	timer := time.NewTimer(duration)
 	defer timer.Stop()
@@ -119,11 +145,12 @@ func (worker *ImportWorker) processClaimed(ctx context.Context, job *model.Impor
 			worker.releaseFailure(ctx, job, "internal worker panic")
 		}
 	}()
+	folders := newImportFolderCache()
 	for {
 		if err := ctx.Err(); err != nil {
 			return
 		}
-		done, err := worker.processNextNote(ctx, job)
+		done, err := worker.processNextNote(ctx, job, folders)
 		if err != nil {
 			logger.Error("process import note failed", zap.Error(err))
 			worker.releaseFailure(ctx, job, "dependency failure")
@@ -137,7 +164,7 @@ func (worker *ImportWorker) processClaimed(ctx context.Context, job *model.Impor
 }
 
 func (worker *ImportWorker) processNextNote(
-	ctx context.Context, job *model.ImportJob,
+	ctx context.Context, job *model.ImportJob, folders *importFolderCache,
 ) (bool, error) {
 	done := false
 	err := worker.runtime.Transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
@@ -162,7 +189,7 @@ func (worker *ImportWorker) processNextNote(
 			done = true
 			return nil
 		}
-		outcome := worker.importNote(txCtx, job, note)
+		outcome := worker.importNote(txCtx, job, note, folders)
 		if outcome.retryErr != nil {
 			return outcome.retryErr
 		}
@@ -172,8 +199,10 @@ func (worker *ImportWorker) processNextNote(
 		)
 	})
 	if err != nil {
+		folders.discard()
 		return false, fmt.Errorf("process next import note transaction: %w", err)
 	}
+	folders.commit()
 	return done, nil
 }
 
@@ -186,7 +215,7 @@ type importNoteOutcome struct {
 }
 
 func (worker *ImportWorker) importNote(
-	ctx context.Context, job *model.ImportJob, note *model.ImportJobNote,
+	ctx context.Context, job *model.ImportJob, note *model.ImportJobNote, folders *importFolderCache,
 ) importNoteOutcome {
 	if strings.TrimSpace(note.Title) == "" ||
 		(job.RequireContent && strings.TrimSpace(note.Content) == "") {
@@ -202,15 +231,17 @@ func (worker *ImportWorker) importNote(
 			action: "skipped",
 		}
 	}
-	tagIDs, err := worker.imports.ensureTags(ctx, job.UserID, note.Tags)
+	tagIDs, err := worker.imports.ensureTagsWithMeta(ctx, job.UserID, note.Tags, job.TagMeta)
+	if err != nil {
+		return classifyImportNoteError(err)
+	}
+	folderID, err := worker.imports.importFolder(ctx, job.UserID, note.Source, folders)
 	if err != nil {
 		return classifyImportNoteError(err)
 	}
 	if exists && job.Mode == model.ImportModeOverwrite {
-		err := worker.imports.documents.Update(ctx, job.UserID, existingID, DocumentUpdateInput{
-			Title: note.Title, Content: note.Content, TagIDs: tagIDs,
-		})
-		if err != nil {
+		input := DocumentUpdateInput{Title: note.Title, Content: note.Content, TagIDs: tagIDs}
+		if err := worker.overwriteImportNote(ctx, job.UserID, existingID, folderID, input); err != nil {
 			return classifyImportNoteError(err)
 		}
 		return importNoteOutcome{
@@ -222,18 +253,44 @@ func (worker *ImportWorker) importNote(
 	if exists && job.Mode == model.ImportModeAppend {
 		title = worker.imports.appendSuffix(ctx, job.UserID, note.Title)
 	}
-	document, err := worker.imports.documents.Create(ctx, job.UserID, DocumentCreateInput{
-		Title: title, Content: note.Content, TagIDs: tagIDs,
-	})
+	input := DocumentCreateInput{Title: title, Content: note.Content, TagIDs: tagIDs}
+	documentID, err := worker.createImportNote(ctx, job.UserID, folderID, input)
 	if err != nil {
 		return classifyImportNoteError(err)
 	}
 	return importNoteOutcome{
-		status: model.ImportNoteStatusDone, documentID: document.ID,
+		status: model.ImportNoteStatusDone, documentID: documentID,
 		action: "created",
 	}
 }
 
+// overwriteImportNote updates an existing document with an imported note and
+// files it in folderID.
+func (worker *ImportWorker) overwriteImportNote(
+	ctx context.Context, userID, docID, folderID string, input DocumentUpdateInput,
+) error {
+	_, err := worker.imports.writeInFolder(ctx, userID, folderID, func(txCtx context.Context) (string, error) {
+		if err := worker.imports.documents.Update(txCtx, userID, docID, input); err != nil {
+			return "", fmt.Errorf("update document: %w", err)
+		}
+		return docID, nil
+	})
+	return err
+}
+
+// createImportNote creates a document from an imported note in folderID.
+func (worker *ImportWorker) createImportNote(
+	ctx context.Context, userID, folderID string, input DocumentCreateInput,
+) (string, error) {
+	return worker.imports.writeInFolder(ctx, userID, folderID, func(txCtx context.Context) (string, error) {
+		document, err := worker.imports.documents.Create(txCtx, userID, input)
+		if err != nil {
+			return "", fmt.Errorf("create document: %w", err)
+		}
+		return document.ID, nil
+	})
+}
+
 func failedImportNote(message string) importNoteOutcome {
 	return importNoteOutcome{
 		status: model.ImportNoteStatusFailed, action: "failed", stableError: message,
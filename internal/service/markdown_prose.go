@@ -0,0 +1,95 @@
+package service
+
+import (
+	"regexp"
+	"strings"
+)
+
+var inlineCodeRegex = regexp.MustCompile("`+[^`\n]*`+")
+
+// proseLine is a line of markdown outside fenced code blocks. Offset is the
+// byte offset of Text within the whole document and Line its 1-based number.
+type proseLine struct {
+	Offset int
+	Line   int
+	Text   string
+}
+
+// markdownProseLines splits content into lines and drops the front matter and
+// fenced code blocks, fence lines included. A fence closes on a line of the
+// same character that is at least as long as the opening one, as in CommonMark.
+func markdownProseLines(content string) []proseLine {
+	lines, _ := scanMarkdownLines(content)
+	return lines
+}
+
+// scanMarkdownLines is markdownProseLines that also counts the fenced code
+// blocks it dropped.
+func scanMarkdownLines(content string) ([]proseLine, int) {
+	lines := make([]proseLine, 0)
+	fence := ""
+	blocks := 0
+	_, offset, _ := frontMatter(content)
+	number := strings.Count(content[:offset], "\n")
+	for _, line := range strings.Split(content[offset:], "\n") {
+		start := offset
+		offset += len(line) + 1
+		number++
+		trimmed := strings.TrimSpace(line)
+		if marker := codeFenceMarker(trimmed); marker != "" {
+			switch {
+			case fence == "":
+				fence = marker
+				blocks++
+			case strings.HasPrefix(marker, fence) && strings.TrimLeft(trimmed, marker[:1]) == "":
+				fence = ""
+			}
+			continue
+		}
+		if fence == "" {
+			lines = append(lines, proseLine{Offset: start, Line: number, Text: line})
+		}
+	}
+	return lines, blocks
+}
+
+func codeFenceMarker(line string) string {
+	for _, ch := range []string{"`", "~"} {
+		n := len(line) - len(strings.TrimLeft(line, ch))
+		if n >= 3 {
+			return line[:n]
+		}
+	}
+	return ""
+}
+
+// countWords counts the words of text the way a reader would: every CJK
+// character is a word, and so is every run of other letters and digits.
+// An apostrophe inside a run, as in "don't", does not split it.
+func countWords(text string) int {
+	words, cjk := countWordsByScript(text)
+	return words + cjk
+}
+
+// countWordsByScript is countWords split into runs of letters and digits and
+// CJK characters, which are read at different speeds.
+func countWordsByScript(text string) (int, int) {
+	words, cjk := 0, 0
+	inWord := false
+	for _, r := range text {
+		switch {
+		case isCJKRune(r):
+			cjk++
+			inWord = false
+		case isWordRune(r):
+			if !inWord {
+				words++
+			}
+			inWord = true
+		case inWord && (r == '\'' || r == '’'):
+		default:
+			inWord = false
+		}
+	}
+	return words, cjk
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
 	"github.com/xxxsen/mnote/internal/repo"
 )
 
@@ -17,14 +18,28 @@ type mockDocumentRepo struct {
 	listAllFn          func(ctx context.Context, userID string) ([]model.Document, error)
 	listByIDsFn        func(ctx context.Context, userID string, docIDs []string) ([]model.Document, error)
 	countFn            func(ctx context.Context, userID string, starred *int) (int, error)
-	searchLikeFn       func(ctx context.Context, userID, query, tagID string, starred *int, limit, offset uint, orderBy string) ([]model.Document, error)
+	sumStatsFn         func(ctx context.Context, userID string, since int64) (*model.DocumentStatsTotals, error)
+	searchLikeFn       func(ctx context.Context, userID, query, tagID string, starred *int, props []model.PropertyFilter, folder *model.FolderFilter, limit, offset uint, orderBy string) ([]model.Document, error)
 	deleteFn           func(ctx context.Context, userID, docID string, mtime int64) error
 	touchMtimeFn       func(ctx context.Context, userID, docID string, mtime int64) error
 	updatePinnedFn     func(ctx context.Context, userID, docID string, pinned int) error
 	updateStarredFn    func(ctx context.Context, userID, docID string, starred int) error
-	updateLinksFn      func(ctx context.Context, userID, sourceID string, targetIDs []string, mtime int64) error
+	updateLinksFn      func(ctx context.Context, userID, sourceID string, targets []model.LinkTarget, mtime int64) error
 	getBacklinksFn     func(ctx context.Context, userID, targetID string) ([]model.Document, error)
 	listLinksFn        func(ctx context.Context, userID, documentID string, query model.DocumentLinksQuery) (*model.DocumentLinksResult, error)
+	listAllLinksFn     func(ctx context.Context, userID string) ([]model.DocumentLink, error)
+	listGraphDocsFn    func(ctx context.Context, userID string) ([]model.GraphDocument, error)
+	listByTitleKeysFn  func(ctx context.Context, userID string, keys []string) ([]model.DocumentTitle, error)
+	replaceWikilinksFn func(ctx context.Context, userID, sourceID string, links []model.DocumentWikilink) error
+	listWikilinksFn    func(ctx context.Context, userID, sourceID string) ([]model.DocumentWikilink, error)
+	listWikiSourcesFn  func(ctx context.Context, userID string, titleKeys []string, targetID string) ([]string, error)
+	replaceBrokenFn    func(ctx context.Context, userID, sourceID string, targetIDs []string, ctime int64) error
+	listBrokenFn       func(ctx context.Context, userID string, limit, offset int) ([]model.BrokenLink, error)
+	listMentionsFn     func(ctx context.Context, userID, targetID string, terms []string, limit uint) ([]model.Document, error)
+	replacePropsFn     func(ctx context.Context, userID, docID string, props []model.DocumentProperty, ctime int64) error
+	listPropsFn        func(ctx context.Context, userID, docID string) ([]model.DocumentProperty, error)
+	listTasksFn        func(ctx context.Context, userID, docID string) ([]model.Todo, error)
+	replaceTasksFn     func(ctx context.Context, userID, docID string, tasks []model.Todo) error
 }
 
 func (m *mockDocumentRepo) Create(ctx context.Context, doc *model.Document) error {
@@ -36,7 +51,13 @@ func (m *mockDocumentRepo) Update(ctx context.Context, doc *model.Document) erro
 }
 
 func (m *mockDocumentRepo) GetByID(ctx context.Context, userID, docID string) (*model.Document, error) {
-	return m.getByIDFn(ctx, userID, docID)
+	if m.getByIDFn != nil {
+		return m.getByIDFn(ctx, userID, docID)
+	}
+	if m.getByIDForUpdateFn != nil {
+		return m.getByIDForUpdateFn(ctx, userID, docID)
+	}
+	return &model.Document{ID: docID, UserID: userID}, nil
 }
 
 func (m *mockDocumentRepo) GetByIDForUpdate(ctx context.Context, userID, docID string) (*model.Document, error) {
@@ -81,8 +102,15 @@ func (m *mockDocumentRepo) Count(ctx context.Context, userID string, starred *in
 	return m.countFn(ctx, userID, starred)
 }
 
-func (m *mockDocumentRepo) SearchLike(ctx context.Context, userID, query, tagID string, starred *int, limit, offset uint, orderBy string) ([]model.Document, error) {
-	return m.searchLikeFn(ctx, userID, query, tagID, starred, limit, offset, orderBy)
+func (m *mockDocumentRepo) SumStats(ctx context.Context, userID string, since int64) (*model.DocumentStatsTotals, error) {
+	if m.sumStatsFn == nil {
+		return &model.DocumentStatsTotals{}, nil
+	}
+	return m.sumStatsFn(ctx, userID, since)
+}
+
+func (m *mockDocumentRepo) SearchLike(ctx context.Context, userID, query, tagID string, starred *int, props []model.PropertyFilter, folder *model.FolderFilter, limit, offset uint, orderBy string) ([]model.Document, error) {
+	return m.searchLikeFn(ctx, userID, query, tagID, starred, props, folder, limit, offset, orderBy)
 }
 
 func (m *mockDocumentRepo) Delete(ctx context.Context, userID, docID string, mtime int64) error {
@@ -101,8 +129,8 @@ func (m *mockDocumentRepo) UpdateStarred(ctx context.Context, userID, docID stri
 	return m.updateStarredFn(ctx, userID, docID, starred)
 }
 
-func (m *mockDocumentRepo) UpdateLinks(ctx context.Context, userID, sourceID string, targetIDs []string, mtime int64) error {
-	return m.updateLinksFn(ctx, userID, sourceID, targetIDs, mtime)
+func (m *mockDocumentRepo) UpdateLinks(ctx context.Context, userID, sourceID string, targets []model.LinkTarget, mtime int64) error {
+	return m.updateLinksFn(ctx, userID, sourceID, targets, mtime)
 }
 
 func (m *mockDocumentRepo) GetBacklinks(ctx context.Context, userID, targetID string) ([]model.Document, error) {
@@ -121,11 +149,92 @@ func (m *mockDocumentRepo) ListLinks(
 	return m.listLinksFn(ctx, userID, documentID, query)
 }
 
+func (m *mockDocumentRepo) ListAllLinks(ctx context.Context, userID string) ([]model.DocumentLink, error) {
+	return m.listAllLinksFn(ctx, userID)
+}
+
+func (m *mockDocumentRepo) ListGraphDocuments(ctx context.Context, userID string) ([]model.GraphDocument, error) {
+	return m.listGraphDocsFn(ctx, userID)
+}
+
+func (m *mockDocumentRepo) ListByTitleKeys(ctx context.Context, userID string, keys []string) ([]model.DocumentTitle, error) {
+	if m.listByTitleKeysFn == nil {
+		return []model.DocumentTitle{}, nil
+	}
+	return m.listByTitleKeysFn(ctx, userID, keys)
+}
+
+func (m *mockDocumentRepo) ReplaceWikilinks(ctx context.Context, userID, sourceID string, links []model.DocumentWikilink) error {
+	if m.replaceWikilinksFn == nil {
+		return nil
+	}
+	return m.replaceWikilinksFn(ctx, userID, sourceID, links)
+}
+
+func (m *mockDocumentRepo) ListWikilinks(ctx context.Context, userID, sourceID string) ([]model.DocumentWikilink, error) {
+	return m.listWikilinksFn(ctx, userID, sourceID)
+}
+
+func (m *mockDocumentRepo) ListWikilinkSources(ctx context.Context, userID string, titleKeys []string, targetID string) ([]string, error) {
+	if m.listWikiSourcesFn == nil {
+		return []string{}, nil
+	}
+	return m.listWikiSourcesFn(ctx, userID, titleKeys, targetID)
+}
+
+func (m *mockDocumentRepo) ReplaceBrokenLinks(ctx context.Context, userID, sourceID string, targetIDs []string, ctime int64) error {
+	if m.replaceBrokenFn == nil {
+		return nil
+	}
+	return m.replaceBrokenFn(ctx, userID, sourceID, targetIDs, ctime)
+}
+
+func (m *mockDocumentRepo) ListBrokenLinks(ctx context.Context, userID string, limit, offset int) ([]model.BrokenLink, error) {
+	return m.listBrokenFn(ctx, userID, limit, offset)
+}
+
+func (m *mockDocumentRepo) ListMentionCandidates(
+	ctx context.Context, userID, targetID string, terms []string, limit uint,
+) ([]model.Document, error) {
+	return m.listMentionsFn(ctx, userID, targetID, terms, limit)
+}
+
+func (m *mockDocumentRepo) ReplaceProperties(
+	ctx context.Context, userID, docID string, props []model.DocumentProperty, ctime int64,
+) error {
+	if m.replacePropsFn == nil {
+		return nil
+	}
+	return m.replacePropsFn(ctx, userID, docID, props, ctime)
+}
+
+func (m *mockDocumentRepo) ListProperties(ctx context.Context, userID, docID string) ([]model.DocumentProperty, error) {
+	if m.listPropsFn == nil {
+		return nil, nil
+	}
+	return m.listPropsFn(ctx, userID, docID)
+}
+
+func (m *mockDocumentRepo) ListTasks(ctx context.Context, userID, docID string) ([]model.Todo, error) {
+	if m.listTasksFn == nil {
+		return nil, nil
+	}
+	return m.listTasksFn(ctx, userID, docID)
+}
+
+func (m *mockDocumentRepo) ReplaceTasks(ctx context.Context, userID, docID string, tasks []model.Todo) error {
+	if m.replaceTasksFn == nil {
+		return nil
+	}
+	return m.replaceTasksFn(ctx, userID, docID, tasks)
+}
+
 type mockVersionRepo struct {
 	createFn            func(ctx context.Context, version *model.DocumentVersion) error
 	getByVersionFn      func(ctx context.Context, userID, docID string, version int) (*model.DocumentVersion, error)
 	listSummariesFn     func(ctx context.Context, userID, docID string) ([]model.DocumentVersionSummary, error)
 	listByUserFn        func(ctx context.Context, userID string) ([]model.DocumentVersion, error)
+	listActivityFn      func(ctx context.Context, userID string, from, to int64) ([]model.VersionActivity, error)
 	deleteOldVersionsFn func(ctx context.Context, userID, docID string, keep int) error
 }
 
@@ -145,6 +254,10 @@ func (m *mockVersionRepo) ListByUser(ctx context.Context, userID string) ([]mode
 	return m.listByUserFn(ctx, userID)
 }
 
+func (m *mockVersionRepo) ListActivity(ctx context.Context, userID string, from, to int64) ([]model.VersionActivity, error) {
+	return m.listActivityFn(ctx, userID, from, to)
+}
+
 func (m *mockVersionRepo) DeleteOldVersions(ctx context.Context, userID, docID string, keep int) error {
 	return m.deleteOldVersionsFn(ctx, userID, docID, keep)
 }
@@ -163,6 +276,11 @@ type mockShareRepo struct {
 	countRepliesByRootIDsFn    func(ctx context.Context, shareID string, rootIDs []string) (map[string]int, error)
 	countRootCommentsByShareFn func(ctx context.Context, shareID string) (int, error)
 	listRepliesByRootIDFn      func(ctx context.Context, shareID, rootID string, limit, offset int) ([]model.ShareComment, error)
+	upsertCollaboratorFn       func(ctx context.Context, collaborator *model.DocumentCollaborator) error
+	getCollaboratorFn          func(ctx context.Context, docID, userID string) (*model.DocumentCollaborator, error)
+	listCollaboratorsFn        func(ctx context.Context, ownerID, docID string) ([]model.DocumentCollaborator, error)
+	deleteCollaboratorFn       func(ctx context.Context, docID, userID string) error
+	listCollaboratedDocsFn     func(ctx context.Context, userID string) ([]repo.CollaboratedDocument, error)
 }
 
 func (m *mockShareRepo) Create(ctx context.Context, share *model.Share) error {
@@ -189,6 +307,31 @@ func (m *mockShareRepo) ListActiveDocuments(ctx context.Context, userID, query s
 	return m.listActiveDocumentsFn(ctx, userID, query, now)
 }
 
+func (m *mockShareRepo) UpsertCollaborator(ctx context.Context, collaborator *model.DocumentCollaborator) error {
+	return m.upsertCollaboratorFn(ctx, collaborator)
+}
+
+// GetCollaborator reports ErrNotFound when unset so tests of missing
+// documents need not configure the collaborator fallback.
+func (m *mockShareRepo) GetCollaborator(ctx context.Context, docID, userID string) (*model.DocumentCollaborator, error) {
+	if m.getCollaboratorFn == nil {
+		return nil, appErr.ErrNotFound
+	}
+	return m.getCollaboratorFn(ctx, docID, userID)
+}
+
+func (m *mockShareRepo) ListCollaborators(ctx context.Context, ownerID, docID string) ([]model.DocumentCollaborator, error) {
+	return m.listCollaboratorsFn(ctx, ownerID, docID)
+}
+
+func (m *mockShareRepo) DeleteCollaborator(ctx context.Context, docID, userID string) error {
+	return m.deleteCollaboratorFn(ctx, docID, userID)
+}
+
+func (m *mockShareRepo) ListCollaboratedDocuments(ctx context.Context, userID string) ([]repo.CollaboratedDocument, error) {
+	return m.listCollaboratedDocsFn(ctx, userID)
+}
+
 func (m *mockShareRepo) CreateComment(ctx context.Context, comment *model.ShareComment) error {
 	return m.createCommentFn(ctx, comment)
 }
@@ -2,7 +2,9 @@ package service
 
 import (
 	"context"
+	"crypto/hmac"
 	"crypto/sha256"
+	"encoding/base64"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -16,12 +18,13 @@ import (
 )
 
 type OAuthService struct {
-	users     userRepo
-	oauths    oauthRepo
-	jwtSecret []byte
-	jwtTTL    time.Duration
-	providers map[string]oauth.Provider
-	runtime   Runtime
+	users        userRepo
+	oauths       oauthRepo
+	jwtSecret    []byte
+	jwtTTL       time.Duration
+	providers    map[string]oauth.Provider
+	registration *RegistrationService
+	runtime      Runtime
 }
 
 func NewOAuthService(
@@ -30,6 +33,7 @@ func NewOAuthService(
 	secret []byte,
 	ttl time.Duration,
 	providers map[string]oauth.Provider,
+	registration *RegistrationService,
 	runtime Runtime,
 ) *OAuthService {
 	if providers == nil {
@@ -39,39 +43,57 @@ func NewOAuthService(
 	return &OAuthService{
 		users: users, oauths: oauths,
 		jwtSecret: secret, jwtTTL: ttl,
-		providers: providers,
-		runtime:   runtime,
+		providers:    providers,
+		registration: registration,
+		runtime:      runtime,
 	}
 }
 
-func (s *OAuthService) GetAuthURL(provider, state string) (string, error) {
+func (s *OAuthService) GetAuthURL(ctx context.Context, provider, state string) (string, error) {
 	impl := s.providers[strings.ToLower(provider)]
 	if impl == nil {
 		return "", appErr.ErrInvalid
 	}
-	url, err := impl.AuthURL(state)
+	var url string
+	var err error
+	if pkce, ok := impl.(oauth.PKCEProvider); ok {
+		url, err = pkce.AuthURLWithPKCE(ctx, state, s.pkceVerifier(state))
+	} else {
+		url, err = impl.AuthURL(state)
+	}
 	if err != nil {
 		return "", fmt.Errorf("get auth url: %w", err)
 	}
 	return url, nil
 }
 
+// ExchangeCode trades the authorization code for a profile. verifier is the
+// CodeVerifier of the consumed state; providers without PKCE ignore it.
 func (s *OAuthService) ExchangeCode(
-	ctx context.Context, provider, code string,
+	ctx context.Context, provider, code, verifier string,
 ) (*oauth.Profile, error) {
 	impl := s.providers[strings.ToLower(provider)]
 	if impl == nil {
 		return nil, appErr.ErrInvalid
 	}
-	profile, err := impl.ExchangeCode(ctx, code)
+	var profile *oauth.Profile
+	var err error
+	if pkce, ok := impl.(oauth.PKCEProvider); ok {
+		profile, err = pkce.ExchangeCodeWithPKCE(ctx, code, verifier)
+	} else {
+		profile, err = impl.ExchangeCode(ctx, code)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("exchange code: %w", err)
 	}
 	return profile, nil
 }
 
+// LoginOrCreate signs in the user bound to profile, or creates a new account
+// when the registration policy admits it. inviteDigest is the InviteDigest
+// of the invite carried by the login state, if any.
 func (s *OAuthService) LoginOrCreate(
-	ctx context.Context, profile *oauth.Profile,
+	ctx context.Context, profile *oauth.Profile, inviteDigest string,
 ) (*model.User, string, error) {
 	if profile == nil || profile.ProviderUserID == "" || profile.Provider == "" {
 		return nil, "", appErr.ErrInvalid
@@ -98,8 +120,11 @@ func (s *OAuthService) LoginOrCreate(
 	if exists {
 		return nil, "", appErr.ErrConflict
 	}
+	if err := s.registration.Admit(ctx, normalized, inviteDigest); err != nil {
+		return nil, "", err
+	}
 
-	return s.createOAuthUser(ctx, profile)
+	return s.createOAuthUser(ctx, profile, inviteDigest)
 }
 
 func (s *OAuthService) tryExistingOAuth(
@@ -119,7 +144,7 @@ func (s *OAuthService) tryExistingOAuth(
 }
 
 func (s *OAuthService) createOAuthUser(
-	ctx context.Context, profile *oauth.Profile,
+	ctx context.Context, profile *oauth.Profile, inviteDigest string,
 ) (*model.User, string, error) {
 	userID, err := s.runtime.IDs.ID()
 	if err != nil {
@@ -157,7 +182,7 @@ func (s *OAuthService) createOAuthUser(
 		if err := s.oauths.Create(txCtx, account); err != nil {
 			return fmt.Errorf("create oauth account: %w", err)
 		}
-		return nil
+		return s.registration.Redeem(txCtx, inviteDigest, user.ID)
 	}); err != nil {
 		return nil, "", fmt.Errorf("create oauth user transaction: %w", err)
 	}
@@ -253,10 +278,12 @@ func (s *OAuthService) Unbind(ctx context.Context, userID, provider string) erro
 }
 
 type OAuthState struct {
-	Provider string
-	Purpose  string
-	UserID   string
-	ReturnTo string
+	Provider     string
+	Purpose      string
+	UserID       string
+	ReturnTo     string
+	CodeVerifier string
+	InviteDigest string
 }
 
 type OAuthExchange struct {
@@ -264,8 +291,11 @@ type OAuthExchange struct {
 	Email string
 }
 
+// CreateState stores a login or bind state. invite is an invite code given
+// on the login URL; it is kept with the state so that a new account created
+// by the callback can redeem it.
 func (s *OAuthService) CreateState(
-	ctx context.Context, provider, purpose, userID, returnTo string,
+	ctx context.Context, provider, purpose, userID, returnTo, invite string,
 ) (string, error) {
 	provider = strings.ToLower(strings.TrimSpace(provider))
 	if s.providers[provider] == nil ||
@@ -281,7 +311,8 @@ func (s *OAuthService) CreateState(
 	if err := s.oauths.CreateOneTimeToken(ctx, &model.OAuthOneTimeToken{
 		Kind: "state", Digest: oauthTokenDigest(raw), Purpose: purpose,
 		Provider: provider, UserID: userID, ReturnTo: returnTo,
-		ExpiresAt: now + 10*60, Ctime: now,
+		InviteDigest: InviteDigest(invite),
+		ExpiresAt:    now + 10*60, Ctime: now,
 	}); err != nil {
 		return "", fmt.Errorf("store oauth state: %w", err)
 	}
@@ -304,6 +335,8 @@ func (s *OAuthService) ConsumeState(ctx context.Context, raw string) (*OAuthStat
 	return &OAuthState{
 		Provider: item.Provider, Purpose: item.Purpose,
 		UserID: item.UserID, ReturnTo: item.ReturnTo,
+		CodeVerifier: s.pkceVerifier(raw),
+		InviteDigest: item.InviteDigest,
 	}, nil
 }
 
@@ -343,6 +376,16 @@ func (s *OAuthService) ConsumeExchange(ctx context.Context, raw string) (*OAuthE
 		}
 		return nil, fmt.Errorf("consume oauth exchange: %w", err)
 	}
+	user, err := s.users.GetByID(ctx, item.UserID)
+	if err != nil {
+		if errors.Is(err, appErr.ErrNotFound) {
+			return nil, appErr.ErrInvalid
+		}
+		return nil, fmt.Errorf("get user: %w", err)
+	}
+	if user.Disabled != 0 {
+		return nil, appErr.ErrForbidden
+	}
 	token, err := jwt.GenerateToken(item.UserID, item.EmailNormalized, s.jwtSecret, s.jwtTTL)
 	if err != nil {
 		return nil, fmt.Errorf("generate token: %w", err)
@@ -350,6 +393,15 @@ func (s *OAuthService) ConsumeExchange(ctx context.Context, raw string) (*OAuthE
 	return &OAuthExchange{Token: token, Email: item.EmailNormalized}, nil
 }
 
+// pkceVerifier derives the PKCE code verifier from the raw state with the
+// server secret, so it needs no storage and cannot be computed by someone
+// who only sees the state in the browser.
+func (s *OAuthService) pkceVerifier(state string) string {
+	mac := hmac.New(sha256.New, s.jwtSecret)
+	_, _ = mac.Write([]byte("oauth-pkce:" + state))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
 func oauthTokenDigest(raw string) string {
 	sum := sha256.Sum256([]byte(raw))
 	return hex.EncodeToString(sum[:])
@@ -87,7 +87,7 @@ func newOAuthSvc(users userRepo, oauths oauthRepo, providers map[string]oauth.Pr
 	if users == nil {
 		users = &mockUserRepo{}
 	}
-	return NewOAuthService(users, oauths, []byte("test-jwt"), time.Hour, providers, testRuntime())
+	return NewOAuthService(users, oauths, []byte("test-jwt"), time.Hour, providers, nil, testRuntime())
 }
 
 func TestOAuthService_GetAuthURL(t *testing.T) {
@@ -98,14 +98,14 @@ func TestOAuthService_GetAuthURL(t *testing.T) {
 			},
 		}
 		svc := newOAuthSvc(nil, nil, map[string]oauth.Provider{"github": p})
-		url, err := svc.GetAuthURL("github", "abc")
+		url, err := svc.GetAuthURL(context.Background(), "github", "abc")
 		require.NoError(t, err)
 		assert.Contains(t, url, "abc")
 	})
 
 	t.Run("unknown_provider", func(t *testing.T) {
 		svc := newOAuthSvc(nil, nil, nil)
-		_, err := svc.GetAuthURL("unknown", "abc")
+		_, err := svc.GetAuthURL(context.Background(), "unknown", "abc")
 		assert.ErrorIs(t, err, appErr.ErrInvalid)
 	})
 
@@ -114,11 +114,50 @@ func TestOAuthService_GetAuthURL(t *testing.T) {
 			authURLFn: func(_ string) (string, error) { return "", errors.New("fail") },
 		}
 		svc := newOAuthSvc(nil, nil, map[string]oauth.Provider{"github": p})
-		_, err := svc.GetAuthURL("github", "abc")
+		_, err := svc.GetAuthURL(context.Background(), "github", "abc")
 		assert.Error(t, err)
 	})
 }
 
+type mockPKCEProvider struct {
+	mockOAuthProvider
+	authVerifier     string
+	exchangeVerifier string
+}
+
+func (m *mockPKCEProvider) AuthURLWithPKCE(_ context.Context, state, verifier string) (string, error) {
+	m.authVerifier = verifier
+	return "https://idp.example.com/auth?state=" + state, nil
+}
+
+func (m *mockPKCEProvider) ExchangeCodeWithPKCE(_ context.Context, _, verifier string) (*oauth.Profile, error) {
+	m.exchangeVerifier = verifier
+	return &oauth.Profile{Provider: "corp", ProviderUserID: "s1", Email: "a@b.com"}, nil
+}
+
+func TestOAuthService_PKCEVerifierFollowsState(t *testing.T) {
+	p := &mockPKCEProvider{}
+	repo := &mockOAuthRepo{
+		consumeOneTimeTokenFn: func(context.Context, string, string, int64) (*model.OAuthOneTimeToken, error) {
+			return &model.OAuthOneTimeToken{Provider: "corp", Purpose: "login"}, nil
+		},
+	}
+	svc := newOAuthSvc(nil, repo, map[string]oauth.Provider{"corp": p})
+	ctx := context.Background()
+
+	_, err := svc.GetAuthURL(ctx, "corp", "state-1")
+	require.NoError(t, err)
+	state, err := svc.ConsumeState(ctx, "state-1")
+	require.NoError(t, err)
+	_, err = svc.ExchangeCode(ctx, "corp", "code", state.CodeVerifier)
+	require.NoError(t, err)
+
+	assert.Len(t, p.authVerifier, 43)
+	assert.Equal(t, p.authVerifier, p.exchangeVerifier)
+	assert.NotContains(t, p.authVerifier, "state-1")
+	assert.NotEqual(t, p.authVerifier, svc.pkceVerifier("state-2"))
+}
+
 func TestOAuthService_ExchangeCode(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		profile := &oauth.Profile{Provider: "github", Email: "a@b.com", ProviderUserID: "g123"}
@@ -128,18 +167,66 @@ func TestOAuthService_ExchangeCode(t *testing.T) {
 			},
 		}
 		svc := newOAuthSvc(nil, nil, map[string]oauth.Provider{"github": p})
-		result, err := svc.ExchangeCode(context.Background(), "github", "code123")
+		result, err := svc.ExchangeCode(context.Background(), "github", "code123", "")
 		require.NoError(t, err)
 		assert.Equal(t, "a@b.com", result.Email)
 	})
 
 	t.Run("unknown_provider", func(t *testing.T) {
 		svc := newOAuthSvc(nil, nil, nil)
-		_, err := svc.ExchangeCode(context.Background(), "unknown", "code")
+		_, err := svc.ExchangeCode(context.Background(), "unknown", "code", "")
 		assert.ErrorIs(t, err, appErr.ErrInvalid)
 	})
 }
 
+func TestOAuthService_LoginOrCreatePolicy(t *testing.T) {
+	ctx := context.Background()
+	profile := &oauth.Profile{Provider: "github", Email: "a@b.com", ProviderUserID: "g123"}
+	newAccounts := &mockOAuthRepo{
+		getByProviderUserIDFn: func(context.Context, string, string) (*model.OAuthAccount, error) {
+			return nil, appErr.ErrNotFound
+		},
+		createFn: func(context.Context, *model.OAuthAccount) error { return nil },
+	}
+	users := &mockUserRepo{createFn: func(context.Context, *model.User) error { return nil }}
+
+	closed := NewRegistrationService(RegistrationPolicy{Mode: RegistrationModeClosed}, nil, testRuntime())
+	svc := NewOAuthService(users, newAccounts, []byte("test-jwt"), time.Hour, nil, closed, testRuntime())
+	_, _, err := svc.LoginOrCreate(ctx, profile, "")
+	assert.ErrorIs(t, err, appErr.ErrForbidden)
+
+	bound := &mockOAuthRepo{
+		getByProviderUserIDFn: func(context.Context, string, string) (*model.OAuthAccount, error) {
+			return &model.OAuthAccount{UserID: "u1"}, nil
+		},
+	}
+	svc = NewOAuthService(users, bound, []byte("test-jwt"), time.Hour, nil, closed, testRuntime())
+	user, _, err := svc.LoginOrCreate(ctx, profile, "")
+	require.NoError(t, err, "existing accounts sign in under any policy")
+	assert.Equal(t, "u1", user.ID)
+
+	invites := &memoryInviteRepo{}
+	inviteOnly := NewRegistrationService(RegistrationPolicy{Mode: RegistrationModeInvite}, invites, testRuntime())
+	_, code, err := inviteOnly.CreateInvite(ctx, "admin", "", 0)
+	require.NoError(t, err)
+	var stored *model.OAuthOneTimeToken
+	newAccounts.createOneTimeTokenFn = func(_ context.Context, token *model.OAuthOneTimeToken) error {
+		stored = token
+		return nil
+	}
+	svc = NewOAuthService(users, newAccounts, []byte("test-jwt"), time.Hour,
+		map[string]oauth.Provider{"github": &mockOAuthProvider{}}, inviteOnly, testRuntime())
+	_, err = svc.CreateState(ctx, "github", "login", "", "/docs", code)
+	require.NoError(t, err)
+	assert.Equal(t, InviteDigest(code), stored.InviteDigest)
+
+	_, _, err = svc.LoginOrCreate(ctx, profile, "")
+	assert.ErrorIs(t, err, appErr.ErrForbidden)
+	user, _, err = svc.LoginOrCreate(ctx, profile, stored.InviteDigest)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, invites.invites[0].UsedBy)
+}
+
 func TestOAuthService_LoginOrCreate(t *testing.T) {
 	profile := &oauth.Profile{Provider: "github", Email: "a@b.com", ProviderUserID: "g123"}
 
@@ -155,7 +242,7 @@ func TestOAuthService_LoginOrCreate(t *testing.T) {
 			},
 		}
 		svc := newOAuthSvc(users, oauths, nil)
-		user, token, err := svc.LoginOrCreate(context.Background(), profile)
+		user, token, err := svc.LoginOrCreate(context.Background(), profile, "")
 		require.NoError(t, err)
 		assert.Equal(t, "u1", user.ID)
 		assert.Empty(t, token, "JWT is issued only after consuming the exchange code")
@@ -175,7 +262,7 @@ func TestOAuthService_LoginOrCreate(t *testing.T) {
 			createFn: func(context.Context, *model.User) error { return nil },
 		}
 		svc := newOAuthSvc(users, oauths, nil)
-		user, token, err := svc.LoginOrCreate(context.Background(), profile)
+		user, token, err := svc.LoginOrCreate(context.Background(), profile, "")
 		require.NoError(t, err)
 		assert.NotEmpty(t, user.ID)
 		assert.Empty(t, token, "JWT is issued only after consuming the exchange code")
@@ -193,19 +280,19 @@ func TestOAuthService_LoginOrCreate(t *testing.T) {
 			},
 		}
 		svc := newOAuthSvc(users, oauths, nil)
-		_, _, err := svc.LoginOrCreate(context.Background(), profile)
+		_, _, err := svc.LoginOrCreate(context.Background(), profile, "")
 		assert.ErrorIs(t, err, appErr.ErrConflict)
 	})
 
 	t.Run("nil_profile", func(t *testing.T) {
 		svc := newOAuthSvc(nil, nil, nil)
-		_, _, err := svc.LoginOrCreate(context.Background(), nil)
+		_, _, err := svc.LoginOrCreate(context.Background(), nil, "")
 		assert.ErrorIs(t, err, appErr.ErrInvalid)
 	})
 
 	t.Run("empty_provider_user_id", func(t *testing.T) {
 		svc := newOAuthSvc(nil, nil, nil)
-		_, _, err := svc.LoginOrCreate(context.Background(), &oauth.Profile{Email: "a@b.com", Provider: "github"})
+		_, _, err := svc.LoginOrCreate(context.Background(), &oauth.Profile{Email: "a@b.com", Provider: "github"}, "")
 		assert.ErrorIs(t, err, appErr.ErrInvalid)
 	})
 }
@@ -378,7 +465,7 @@ func TestOAuthService_ExchangeCode_ProviderError(t *testing.T) {
 		},
 	}
 	svc := newOAuthSvc(nil, nil, map[string]oauth.Provider{"github": p})
-	_, err := svc.ExchangeCode(context.Background(), "github", "code")
+	_, err := svc.ExchangeCode(context.Background(), "github", "code", "")
 	assert.Error(t, err)
 }
 
@@ -390,7 +477,7 @@ func TestOAuthService_LoginOrCreate_LookupError(t *testing.T) {
 		},
 	}
 	svc := newOAuthSvc(nil, oauths, nil)
-	_, _, err := svc.LoginOrCreate(context.Background(), profile)
+	_, _, err := svc.LoginOrCreate(context.Background(), profile, "")
 	assert.Error(t, err)
 }
 
@@ -405,7 +492,7 @@ func TestOAuthService_LoginOrCreate_CheckEmailError(t *testing.T) {
 		getByEmailFn: func(context.Context, string) (*model.User, error) { return nil, errors.New("db error") },
 	}
 	svc := newOAuthSvc(users, oauths, nil)
-	_, _, err := svc.LoginOrCreate(context.Background(), profile)
+	_, _, err := svc.LoginOrCreate(context.Background(), profile, "")
 	assert.Error(t, err)
 }
 
@@ -421,7 +508,7 @@ func TestOAuthService_LoginOrCreate_CreateUserError(t *testing.T) {
 		createFn:     func(context.Context, *model.User) error { return errors.New("db error") },
 	}
 	svc := newOAuthSvc(users, oauths, nil)
-	_, _, err := svc.LoginOrCreate(context.Background(), profile)
+	_, _, err := svc.LoginOrCreate(context.Background(), profile, "")
 	assert.Error(t, err)
 }
 
@@ -438,7 +525,7 @@ func TestOAuthService_LoginOrCreate_CreateOAuthError(t *testing.T) {
 		createFn:     func(context.Context, *model.User) error { return nil },
 	}
 	svc := newOAuthSvc(users, oauths, nil)
-	_, _, err := svc.LoginOrCreate(context.Background(), profile)
+	_, _, err := svc.LoginOrCreate(context.Background(), profile, "")
 	assert.Error(t, err)
 }
 
@@ -453,7 +540,7 @@ func TestOAuthService_TryExistingOAuth_UserError(t *testing.T) {
 		getByIDFn: func(context.Context, string) (*model.User, error) { return nil, errors.New("db error") },
 	}
 	svc := newOAuthSvc(users, oauths, nil)
-	_, _, err := svc.LoginOrCreate(context.Background(), profile)
+	_, _, err := svc.LoginOrCreate(context.Background(), profile, "")
 	assert.Error(t, err)
 }
 
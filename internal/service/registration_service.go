@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+const (
+	RegistrationModeOpen   = "open"
+	RegistrationModeInvite = "invite"
+	RegistrationModeClosed = "closed"
+
+	inviteCodeBytes = 12
+	maxInviteNote   = 200
+	maxInviteTTL    = 365 * 24 * time.Hour
+)
+
+// RegistrationPolicy decides who may create an account. An empty Mode is
+// treated as open. AllowedDomains, when set, lists the email domains that
+// may register; subdomains are not included.
+type RegistrationPolicy struct {
+	Mode           string
+	AllowedDomains []string
+}
+
+// RegistrationService enforces the registration policy for password and
+// OAuth sign-ups and manages invite codes. A nil service admits everyone.
+type RegistrationService struct {
+	policy  RegistrationPolicy
+	invites inviteRepo
+	runtime Runtime
+}
+
+func NewRegistrationService(policy RegistrationPolicy, invites inviteRepo, runtime Runtime) *RegistrationService {
+	runtime = prepareRuntime(runtime)
+	if policy.Mode == "" {
+		policy.Mode = RegistrationModeOpen
+	}
+	return &RegistrationService{policy: policy, invites: invites, runtime: runtime}
+}
+
+// Mode returns the active registration mode.
+func (s *RegistrationService) Mode() string {
+	if s == nil {
+		return RegistrationModeOpen
+	}
+	return s.policy.Mode
+}
+
+// CheckEmail rejects emails the policy never admits, before an invite is
+// looked at. email must already be normalized.
+func (s *RegistrationService) CheckEmail(email string) error {
+	if s == nil {
+		return nil
+	}
+	if s.policy.Mode == RegistrationModeClosed {
+		return appErr.ErrForbidden
+	}
+	if len(s.policy.AllowedDomains) == 0 {
+		return nil
+	}
+	_, domain, _ := strings.Cut(email, "@")
+	if !slices.Contains(s.policy.AllowedDomains, domain) {
+		return appErr.ErrForbidden
+	}
+	return nil
+}
+
+// Admit checks whether a new account for email may be created with the
+// invite identified by inviteDigest. It does not consume the invite; Redeem
+// does that inside the transaction that creates the user.
+func (s *RegistrationService) Admit(ctx context.Context, email, inviteDigest string) error {
+	if err := s.CheckEmail(email); err != nil {
+		return err
+	}
+	if s == nil || s.policy.Mode != RegistrationModeInvite {
+		return nil
+	}
+	if inviteDigest == "" {
+		return appErr.ErrForbidden
+	}
+	if _, err := s.invites.GetUsable(ctx, inviteDigest, s.runtime.Clock.Now().Unix()); err != nil {
+		if errors.Is(err, appErr.ErrNotFound) {
+			return appErr.ErrInvalid
+		}
+		return fmt.Errorf("get invite: %w", err)
+	}
+	return nil
+}
+
+// Redeem marks the invite used by userID. It is a no-op unless the policy
+// requires invites, and fails with ErrInvalid when the invite was used or
+// expired in the meantime.
+func (s *RegistrationService) Redeem(ctx context.Context, inviteDigest, userID string) error {
+	if s == nil || s.policy.Mode != RegistrationModeInvite {
+		return nil
+	}
+	if err := s.invites.Redeem(ctx, inviteDigest, userID, s.runtime.Clock.Now().Unix()); err != nil {
+		if errors.Is(err, appErr.ErrNotFound) {
+			return appErr.ErrInvalid
+		}
+		return fmt.Errorf("redeem invite: %w", err)
+	}
+	return nil
+}
+
+// CreateInvite creates a single-use invite code and returns it with the
+// plain code, which is not stored and cannot be shown again. A zero ttl
+// creates an invite that does not expire.
+func (s *RegistrationService) CreateInvite(
+	ctx context.Context, createdBy, note string, ttl time.Duration,
+) (*model.InviteCode, string, error) {
+	note = strings.TrimSpace(note)
+	if ttl < 0 || ttl > maxInviteTTL || utf8.RuneCountInString(note) > maxInviteNote {
+		return nil, "", appErr.ErrInvalid
+	}
+	id, err := s.runtime.IDs.ID()
+	if err != nil {
+		return nil, "", fmt.Errorf("generate invite id: %w", err)
+	}
+	code, err := s.runtime.IDs.Token(inviteCodeBytes)
+	if err != nil {
+		return nil, "", fmt.Errorf("generate invite code: %w", err)
+	}
+	now := s.runtime.Clock.Now()
+	invite := &model.InviteCode{
+		ID:         id,
+		CodeDigest: InviteDigest(code),
+		CreatedBy:  createdBy,
+		Note:       note,
+		Ctime:      now.Unix(),
+	}
+	if ttl > 0 {
+		invite.ExpiresAt = now.Add(ttl).Unix()
+	}
+	if err := s.invites.Create(ctx, invite); err != nil {
+		return nil, "", fmt.Errorf("create invite: %w", err)
+	}
+	return invite, code, nil
+}
+
+func (s *RegistrationService) ListInvites(ctx context.Context) ([]model.InviteCode, error) {
+	invites, err := s.invites.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list invites: %w", err)
+	}
+	return invites, nil
+}
+
+func (s *RegistrationService) DeleteInvite(ctx context.Context, id string) error {
+	if err := s.invites.Delete(ctx, id); err != nil {
+		return fmt.Errorf("delete invite: %w", err)
+	}
+	return nil
+}
+
+// InviteDigest returns the stored form of an invite code, or "" when no
+// code was given.
+func InviteDigest(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	if code == "" {
+		return ""
+	}
+	sum := sha256.Sum256([]byte("invite:" + code))
+	return hex.EncodeToString(sum[:])
+}
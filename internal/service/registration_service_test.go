@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+// memoryInviteRepo keeps invites in memory with the usable and redeem rules
+// of the SQL repo.
+type memoryInviteRepo struct {
+	invites []*model.InviteCode
+}
+
+func (m *memoryInviteRepo) Create(_ context.Context, invite *model.InviteCode) error {
+	copied := *invite
+	m.invites = append(m.invites, &copied)
+	return nil
+}
+
+func (m *memoryInviteRepo) usable(digest string, now int64) *model.InviteCode {
+	for _, invite := range m.invites {
+		if invite.CodeDigest == digest && invite.UsedAt == 0 && (invite.ExpiresAt == 0 || invite.ExpiresAt > now) {
+			return invite
+		}
+	}
+	return nil
+}
+
+func (m *memoryInviteRepo) GetUsable(_ context.Context, digest string, now int64) (*model.InviteCode, error) {
+	invite := m.usable(digest, now)
+	if invite == nil {
+		return nil, appErr.ErrNotFound
+	}
+	copied := *invite
+	return &copied, nil
+}
+
+func (m *memoryInviteRepo) Redeem(_ context.Context, digest, userID string, now int64) error {
+	invite := m.usable(digest, now)
+	if invite == nil {
+		return appErr.ErrNotFound
+	}
+	invite.UsedBy = userID
+	invite.UsedAt = now
+	return nil
+}
+
+func (m *memoryInviteRepo) List(context.Context) ([]model.InviteCode, error) {
+	items := make([]model.InviteCode, 0, len(m.invites))
+	for _, invite := range m.invites {
+		items = append(items, *invite)
+	}
+	return items, nil
+}
+
+func (m *memoryInviteRepo) Delete(_ context.Context, id string) error {
+	for index, invite := range m.invites {
+		if invite.ID == id {
+			m.invites = append(m.invites[:index], m.invites[index+1:]...)
+			return nil
+		}
+	}
+	return appErr.ErrNotFound
+}
+
+func TestRegistrationService_NilAdmitsEveryone(t *testing.T) {
+	var svc *RegistrationService
+	assert.Equal(t, RegistrationModeOpen, svc.Mode())
+	require.NoError(t, svc.Admit(context.Background(), "a@example.com", ""))
+	require.NoError(t, svc.Redeem(context.Background(), "", "u1"))
+}
+
+func TestRegistrationService_Admit(t *testing.T) {
+	ctx := context.Background()
+
+	closed := NewRegistrationService(RegistrationPolicy{Mode: RegistrationModeClosed}, nil, testRuntime())
+	assert.ErrorIs(t, closed.Admit(ctx, "a@example.com", ""), appErr.ErrForbidden)
+
+	domains := NewRegistrationService(RegistrationPolicy{AllowedDomains: []string{"example.com"}}, nil, testRuntime())
+	assert.Equal(t, RegistrationModeOpen, domains.Mode())
+	require.NoError(t, domains.Admit(ctx, "a@example.com", ""))
+	assert.ErrorIs(t, domains.Admit(ctx, "a@sub.example.com", ""), appErr.ErrForbidden)
+	assert.ErrorIs(t, domains.Admit(ctx, "a@example.org", ""), appErr.ErrForbidden)
+}
+
+func TestRegistrationService_InviteLifecycle(t *testing.T) {
+	ctx := context.Background()
+	invites := &memoryInviteRepo{}
+	svc := NewRegistrationService(RegistrationPolicy{Mode: RegistrationModeInvite}, invites, testRuntimeAt(1000))
+
+	invite, code, err := svc.CreateInvite(ctx, "admin", " for bob ", time.Hour)
+	require.NoError(t, err)
+	assert.Len(t, code, inviteCodeBytes*2)
+	assert.Equal(t, "for bob", invite.Note)
+	assert.Equal(t, int64(1000+3600), invite.ExpiresAt)
+	assert.Equal(t, InviteDigest(code), invite.CodeDigest)
+	assert.Equal(t, InviteDigest(code), InviteDigest(" "+code+" "))
+
+	assert.ErrorIs(t, svc.Admit(ctx, "bob@example.com", ""), appErr.ErrForbidden)
+	assert.ErrorIs(t, svc.Admit(ctx, "bob@example.com", InviteDigest("wrong")), appErr.ErrInvalid)
+	require.NoError(t, svc.Admit(ctx, "bob@example.com", InviteDigest(code)))
+	require.NoError(t, svc.Redeem(ctx, InviteDigest(code), "u2"))
+	assert.ErrorIs(t, svc.Redeem(ctx, InviteDigest(code), "u3"), appErr.ErrInvalid)
+	assert.ErrorIs(t, svc.Admit(ctx, "eve@example.com", InviteDigest(code)), appErr.ErrInvalid)
+
+	items, err := svc.ListInvites(ctx)
+	require.NoError(t, err)
+	require.Len(t, items, 1)
+	assert.Equal(t, "u2", items[0].UsedBy)
+	require.NoError(t, svc.DeleteInvite(ctx, invite.ID))
+	assert.ErrorIs(t, svc.DeleteInvite(ctx, invite.ID), appErr.ErrNotFound)
+}
+
+func TestRegistrationService_InviteExpires(t *testing.T) {
+	ctx := context.Background()
+	invites := &memoryInviteRepo{}
+	_, code, err := NewRegistrationService(
+		RegistrationPolicy{Mode: RegistrationModeInvite}, invites, testRuntimeAt(1000),
+	).CreateInvite(ctx, "admin", "", time.Minute)
+	require.NoError(t, err)
+
+	later := NewRegistrationService(RegistrationPolicy{Mode: RegistrationModeInvite}, invites, testRuntimeAt(1060))
+	assert.ErrorIs(t, later.Admit(ctx, "bob@example.com", InviteDigest(code)), appErr.ErrInvalid)
+}
+
+func TestRegistrationService_CreateInviteValidation(t *testing.T) {
+	svc := NewRegistrationService(RegistrationPolicy{}, &memoryInviteRepo{}, testRuntime())
+	_, _, err := svc.CreateInvite(context.Background(), "admin", "", -time.Hour)
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+	_, _, err = svc.CreateInvite(context.Background(), "admin", "", 2*maxInviteTTL)
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+	invite, _, err := svc.CreateInvite(context.Background(), "admin", "", 0)
+	require.NoError(t, err)
+	assert.Zero(t, invite.ExpiresAt)
+}
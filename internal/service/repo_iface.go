@@ -11,19 +11,26 @@ type todoWriteRepo interface {
 	Create(ctx context.Context, todo *model.Todo) error
 	Update(ctx context.Context, todo *model.Todo) error
 	UpdateDone(ctx context.Context, userID, todoID string, done int, mtime int64) error
+	SetNextID(ctx context.Context, userID, todoID, nextID string, mtime int64) (bool, error)
 	Delete(ctx context.Context, userID, todoID string) error
+	DeleteByParent(ctx context.Context, userID, parentID string) error
 }
 
 type todoRepo interface {
 	todoWriteRepo
 	GetByID(ctx context.Context, userID, todoID string) (*model.Todo, error)
 	ListByDateRange(ctx context.Context, userID, startDate, endDate string) ([]model.Todo, error)
+	ListRecurringHeads(ctx context.Context, userID, endDate string) ([]model.Todo, error)
+	List(ctx context.Context, userID string, query model.TodoListQuery) ([]model.Todo, error)
+	CountSubtasks(ctx context.Context, userID string, parentIDs []string) (map[string]model.TodoProgress, error)
 }
 
 type tagWriteRepo interface {
 	Create(ctx context.Context, tag *model.Tag) error
 	CreateBatch(ctx context.Context, tags []model.Tag) error
 	UpdatePinned(ctx context.Context, userID, tagID string, pinned int, mtime int64) error
+	UpdateName(ctx context.Context, userID, tagID, name string, mtime int64) error
+	UpdateAppearance(ctx context.Context, tag *model.Tag) error
 	Delete(ctx context.Context, userID, tagID string) error
 }
 
@@ -40,10 +47,31 @@ type tagRepo interface {
 	tagListRepo
 }
 
+type folderWriteRepo interface {
+	Create(ctx context.Context, folder *model.Folder) error
+	CreateIfAbsent(ctx context.Context, folder *model.Folder) (bool, error)
+	Update(ctx context.Context, folder *model.Folder) error
+	Reorder(ctx context.Context, userID, parentID string, folderIDs []string, mtime int64) error
+	Delete(ctx context.Context, userID string, folderIDs []string, parentID string) error
+}
+
+type folderQueryRepo interface {
+	GetByID(ctx context.Context, userID, folderID string) (*model.Folder, error)
+	GetByName(ctx context.Context, userID, parentID, name string) (*model.Folder, error)
+	List(ctx context.Context, userID string) ([]model.Folder, error)
+	CountDocuments(ctx context.Context, userID string) (map[string]int, error)
+}
+
+type folderRepo interface {
+	folderWriteRepo
+	folderQueryRepo
+}
+
 type documentTagWriteRepo interface {
 	Add(ctx context.Context, docTag *model.DocumentTag) error
 	DeleteByDoc(ctx context.Context, userID, docID string) error
 	DeleteByTag(ctx context.Context, userID, tagID string) error
+	ReassignTag(ctx context.Context, userID, fromTagID, toTagID string) error
 }
 
 type documentTagRepo interface {
@@ -69,6 +97,19 @@ type userRepo interface {
 	UpdatePassword(ctx context.Context, id, passwordHash string, mtime int64) error
 }
 
+type adminUserRepo interface {
+	userRepo
+	List(ctx context.Context) ([]model.User, error)
+	UpdateDisabled(ctx context.Context, userID string, disabled int, mtime int64) error
+	UpdateRole(ctx context.Context, userID, role string, mtime int64) error
+}
+
+type adminDataRepo interface {
+	UserStats(ctx context.Context) ([]model.UserStats, error)
+	DeleteUserData(ctx context.Context, userID string) ([]string, error)
+	VacuumVersions(ctx context.Context, keep int, userID string) (int64, error)
+}
+
 type emailVerificationRepo interface {
 	Create(ctx context.Context, v *model.EmailVerificationCode) error
 	LatestByEmail(ctx context.Context, email, purpose string) (*model.EmailVerificationCode, error)
@@ -90,6 +131,28 @@ type templateRepo interface {
 	CountByUser(ctx context.Context, userID, query string) (int, error)
 }
 
+type templateGalleryRepo interface {
+	Upsert(ctx context.Context, item *model.GalleryTemplate) (bool, error)
+	DeleteExcept(ctx context.Context, slugs []string) (int64, error)
+	List(ctx context.Context) ([]model.GalleryTemplate, error)
+	GetBySlug(ctx context.Context, slug string) (*model.GalleryTemplate, error)
+}
+
+type templateScheduleRepo interface {
+	Create(ctx context.Context, item *model.TemplateSchedule) error
+	Update(ctx context.Context, item *model.TemplateSchedule) error
+	AdvanceRun(ctx context.Context, item *model.TemplateSchedule, expectedNextRunAt int64) (bool, error)
+	Delete(ctx context.Context, userID, id string) error
+	GetByID(ctx context.Context, userID, id string) (*model.TemplateSchedule, error)
+	ListByUser(ctx context.Context, userID string) ([]model.TemplateSchedule, error)
+	ListDue(ctx context.Context, now int64, limit int) ([]model.TemplateSchedule, error)
+}
+
+type tagTemplateRepo interface {
+	ListByUser(ctx context.Context, userID string) ([]model.Template, error)
+	Update(ctx context.Context, tpl *model.Template) error
+}
+
 type assetRepo interface {
 	UpsertByFileKey(ctx context.Context, asset *model.Asset) error
 	ListByUser(ctx context.Context, userID, query string, limit, offset uint) ([]model.Asset, error)
@@ -124,16 +187,20 @@ type documentListRepo interface {
 		limit, offset uint, orderBy string) ([]model.Document, error)
 	ListAllByUser(ctx context.Context, userID string) ([]model.Document, error)
 	Count(ctx context.Context, userID string, starred *int) (int, error)
-	SearchLike(ctx context.Context, userID, query, tagID string,
-		starred *int, limit, offset uint, orderBy string) ([]model.Document, error)
+	SumStats(ctx context.Context, userID string, since int64) (*model.DocumentStatsTotals, error)
+	SearchLike(ctx context.Context, userID, query, tagID string, starred *int,
+		props []model.PropertyFilter, folder *model.FolderFilter,
+		limit, offset uint, orderBy string) ([]model.Document, error)
 }
 
 type documentRelationRepo interface {
 	UpdatePinned(ctx context.Context, userID, docID string, pinned int) error
 	UpdateStarred(ctx context.Context, userID, docID string, starred int) error
 	UpdateLinks(ctx context.Context, userID, sourceID string,
-		targetIDs []string, mtime int64) error
+		targets []model.LinkTarget, mtime int64) error
 	GetBacklinks(ctx context.Context, userID, targetID string) ([]model.Document, error)
+	ListAllLinks(ctx context.Context, userID string) ([]model.DocumentLink, error)
+	ListGraphDocuments(ctx context.Context, userID string) ([]model.GraphDocument, error)
 	ListLinks(
 		ctx context.Context,
 		userID string,
@@ -142,11 +209,36 @@ type documentRelationRepo interface {
 	) (*model.DocumentLinksResult, error)
 }
 
+type documentWikilinkRepo interface {
+	ListByTitleKeys(ctx context.Context, userID string, keys []string) ([]model.DocumentTitle, error)
+	ReplaceWikilinks(ctx context.Context, userID, sourceID string, links []model.DocumentWikilink) error
+	ListWikilinks(ctx context.Context, userID, sourceID string) ([]model.DocumentWikilink, error)
+	ListWikilinkSources(ctx context.Context, userID string, titleKeys []string, targetID string) ([]string, error)
+	ReplaceBrokenLinks(ctx context.Context, userID, sourceID string, targetIDs []string, ctime int64) error
+	ListBrokenLinks(ctx context.Context, userID string, limit, offset int) ([]model.BrokenLink, error)
+	ListMentionCandidates(
+		ctx context.Context, userID, targetID string, terms []string, limit uint,
+	) ([]model.Document, error)
+}
+
+type documentPropertyRepo interface {
+	ReplaceProperties(ctx context.Context, userID, docID string, props []model.DocumentProperty, ctime int64) error
+	ListProperties(ctx context.Context, userID, docID string) ([]model.DocumentProperty, error)
+}
+
+type documentTaskRepo interface {
+	ListTasks(ctx context.Context, userID, docID string) ([]model.Todo, error)
+	ReplaceTasks(ctx context.Context, userID, docID string, tasks []model.Todo) error
+}
+
 type documentRepo interface {
 	documentWriteRepo
 	documentLookupRepo
 	documentListRepo
 	documentRelationRepo
+	documentWikilinkRepo
+	documentPropertyRepo
+	documentTaskRepo
 }
 
 type versionRepo interface {
@@ -154,6 +246,7 @@ type versionRepo interface {
 	GetByVersion(ctx context.Context, userID, docID string, version int) (*model.DocumentVersion, error)
 	ListSummaries(ctx context.Context, userID, docID string) ([]model.DocumentVersionSummary, error)
 	ListByUser(ctx context.Context, userID string) ([]model.DocumentVersion, error)
+	ListActivity(ctx context.Context, userID string, from, to int64) ([]model.VersionActivity, error)
 	DeleteOldVersions(ctx context.Context, userID, docID string, keep int) error
 }
 
@@ -184,10 +277,19 @@ type shareCommentListRepo interface {
 		limit, offset int) ([]model.ShareComment, error)
 }
 
+type shareCollaboratorRepo interface {
+	UpsertCollaborator(ctx context.Context, collaborator *model.DocumentCollaborator) error
+	GetCollaborator(ctx context.Context, docID, userID string) (*model.DocumentCollaborator, error)
+	ListCollaborators(ctx context.Context, ownerID, docID string) ([]model.DocumentCollaborator, error)
+	DeleteCollaborator(ctx context.Context, docID, userID string) error
+	ListCollaboratedDocuments(ctx context.Context, userID string) ([]repo.CollaboratedDocument, error)
+}
+
 type shareRepo interface {
 	shareConfigRepo
 	shareCommentWriteRepo
 	shareCommentListRepo
+	shareCollaboratorRepo
 	ListActiveDocuments(ctx context.Context, userID, query string, now int64) ([]repo.SharedDocument, error)
 }
 
@@ -271,3 +373,27 @@ type importJobNoteRepo interface {
 type embeddingChunker interface {
 	Chunk(ctx context.Context, markdown string) ([]*model.ChunkEmbedding, error)
 }
+
+type inviteRepo interface {
+	Create(ctx context.Context, invite *model.InviteCode) error
+	GetUsable(ctx context.Context, digest string, now int64) (*model.InviteCode, error)
+	Redeem(ctx context.Context, digest, userID string, now int64) error
+	List(ctx context.Context) ([]model.InviteCode, error)
+	Delete(ctx context.Context, id string) error
+}
+
+type workspaceRepo interface {
+	Create(ctx context.Context, workspace *model.Workspace) error
+	GetByID(ctx context.Context, id string) (*model.Workspace, error)
+	GetByIDForUpdate(ctx context.Context, id string) (*model.Workspace, error)
+	ListByUser(ctx context.Context, userID string) ([]model.Workspace, error)
+	UpdateName(ctx context.Context, id, name string, mtime int64) error
+	Delete(ctx context.Context, id string) error
+	HasContent(ctx context.Context, id string) (bool, error)
+	AddMember(ctx context.Context, member *model.WorkspaceMember) error
+	GetMember(ctx context.Context, workspaceID, userID string) (*model.WorkspaceMember, error)
+	ListMembers(ctx context.Context, workspaceID string) ([]model.WorkspaceMember, error)
+	UpdateMemberRole(ctx context.Context, workspaceID, userID, role string, mtime int64) error
+	RemoveMember(ctx context.Context, workspaceID, userID string) error
+	CountOwners(ctx context.Context, workspaceID string) (int, error)
+}
@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 
 	"github.com/xxxsen/mnote/internal/model"
@@ -260,17 +261,19 @@ func TestResolveSystemVariable_Now(t *testing.T) {
 	assert.Equal(t, "", resolveSystemVariable("sys:unknown", now))
 }
 
-func TestApplyTemplateVariables_CustomValues(t *testing.T) {
+func TestRenderTemplateContent_CustomValues(t *testing.T) {
 	content := "Name: {{NAME}} Age: {{AGE}}"
-	result := applyTemplateVariables(content, map[string]string{
+	result, err := renderTemplateContent(content, templateRenderContext{values: map[string]string{
 		"NAME": "Alice",
 		"AGE":  "30",
-	})
+	}})
+	require.NoError(t, err)
 	assert.Equal(t, "Name: Alice Age: 30", result)
 }
 
-func TestApplyTemplateVariables_UnknownVar(t *testing.T) {
+func TestRenderTemplateContent_UnknownVar(t *testing.T) {
 	content := "Val: {{UNKNOWN}}"
-	result := applyTemplateVariables(content, map[string]string{})
+	result, err := renderTemplateContent(content, templateRenderContext{values: map[string]string{}})
+	require.NoError(t, err)
 	assert.Equal(t, "Val: ", result)
 }
@@ -3,6 +3,8 @@ package service
 import (
 	"context"
 	"fmt"
+	"regexp"
+	"sort"
 	"strings"
 	"unicode/utf8"
 
@@ -15,19 +17,73 @@ type TagService struct {
 	transactor Transactor
 	tags       tagRepo
 	docTags    documentTagRepo
+	templates  tagTemplateRepo
 	runtime    Runtime
 }
 
 func NewTagService(
-	runtime Runtime, tags tagRepo, docTags documentTagRepo,
+	runtime Runtime, tags tagRepo, docTags documentTagRepo, templates tagTemplateRepo,
 ) *TagService {
 	runtime = prepareRuntime(runtime)
 	return &TagService{
 		transactor: runtime.Transactor, tags: tags, docTags: docTags,
-		runtime: runtime,
+		templates: templates, runtime: runtime,
 	}
 }
 
+const (
+	maxTagNameRunes        = 64
+	maxTagDescriptionRunes = 200
+	maxTagIconRunes        = 32
+)
+
+var tagColorRegex = regexp.MustCompile(`^#([0-9a-f]{3}|[0-9a-f]{6})$`)
+
+type TagUpdateInput struct {
+	Color       string
+	Description string
+	Icon        string
+}
+
+// normalizeTagAppearance trims the appearance fields and lowercases the color.
+// An empty field clears the corresponding value.
+func normalizeTagAppearance(input TagUpdateInput) (TagUpdateInput, error) {
+	input.Color = strings.ToLower(strings.TrimSpace(input.Color))
+	input.Description = strings.TrimSpace(input.Description)
+	input.Icon = strings.TrimSpace(input.Icon)
+	if input.Color != "" && !tagColorRegex.MatchString(input.Color) {
+		return TagUpdateInput{}, appErr.ErrInvalid
+	}
+	if utf8.RuneCountInString(input.Description) > maxTagDescriptionRunes ||
+		utf8.RuneCountInString(input.Icon) > maxTagIconRunes {
+		return TagUpdateInput{}, appErr.ErrInvalid
+	}
+	return input, nil
+}
+
+// normalizeTagName trims every "/"-separated segment of a hierarchical tag
+// name and drops empty segments, so " project / mnote/" becomes "project/mnote".
+func normalizeTagName(name string) string {
+	parts := strings.Split(name, "/")
+	segments := make([]string, 0, len(parts))
+	for _, part := range parts {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		segments = append(segments, part)
+	}
+	return strings.Join(segments, "/")
+}
+
+func validTagName(name string) bool {
+	return name != "" && utf8.RuneCountInString(name) <= maxTagNameRunes
+}
+
+func isTagDescendant(name, parent string) bool {
+	return strings.HasPrefix(name, parent+"/")
+}
+
 func (s *TagService) runInTx(ctx context.Context, fn func(ctx context.Context) error) error {
 	if err := s.transactor.WithinTransaction(ctx, fn); err != nil {
 		return fmt.Errorf("run in tx: %w", err)
@@ -36,8 +92,11 @@ func (s *TagService) runInTx(ctx context.Context, fn func(ctx context.Context) e
 }
 
 func (s *TagService) Create(ctx context.Context, userID, name string) (*model.Tag, error) {
-	name = strings.TrimSpace(name)
-	if name == "" || utf8.RuneCountInString(name) > 64 {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return nil, err
+	}
+	name = normalizeTagName(name)
+	if !validTagName(name) {
 		return nil, appErr.ErrInvalid
 	}
 	id, err := s.runtime.IDs.ID()
@@ -66,6 +125,9 @@ func (s *TagService) Create(ctx context.Context, userID, name string) (*model.Ta
 }
 
 func (s *TagService) CreateBatch(ctx context.Context, userID string, names []string) ([]model.Tag, error) {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return nil, err
+	}
 	if len(names) == 0 {
 		return []model.Tag{}, nil
 	}
@@ -76,11 +138,11 @@ func (s *TagService) CreateBatch(ctx context.Context, userID string, names []str
 	unique := make([]string, 0, len(names))
 	seen := make(map[string]bool)
 	for _, name := range names {
-		trimmed := strings.TrimSpace(name)
+		trimmed := normalizeTagName(name)
 		if trimmed == "" {
 			continue
 		}
-		if utf8.RuneCountInString(trimmed) > 64 {
+		if utf8.RuneCountInString(trimmed) > maxTagNameRunes {
 			return nil, appErr.ErrInvalid
 		}
 		key := strings.ToLower(trimmed)
@@ -178,6 +240,9 @@ func (s *TagService) ListByIDs(ctx context.Context, userID string, ids []string)
 }
 
 func (s *TagService) Delete(ctx context.Context, userID, tagID string) error {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return err
+	}
 	return s.runInTx(ctx, func(txCtx context.Context) error {
 		if err := s.docTags.DeleteByTag(txCtx, userID, tagID); err != nil {
 			return fmt.Errorf("delete by tag: %w", err)
@@ -190,6 +255,9 @@ func (s *TagService) Delete(ctx context.Context, userID, tagID string) error {
 }
 
 func (s *TagService) UpdatePinned(ctx context.Context, userID, tagID string, pinned int) error {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return err
+	}
 	if pinned != 0 && pinned != 1 {
 		return appErr.ErrInvalid
 	}
@@ -198,3 +266,225 @@ func (s *TagService) UpdatePinned(ctx context.Context, userID, tagID string, pin
 	}
 	return nil
 }
+
+func (s *TagService) Update(ctx context.Context, userID, tagID string, input TagUpdateInput) (*model.Tag, error) {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return nil, err
+	}
+	input, err := normalizeTagAppearance(input)
+	if err != nil {
+		return nil, err
+	}
+	tag := &model.Tag{
+		ID: tagID, UserID: userID,
+		Color: input.Color, Description: input.Description, Icon: input.Icon,
+		Mtime: timeutil.NowUnix(),
+	}
+	if err := s.tags.UpdateAppearance(ctx, tag); err != nil {
+		return nil, fmt.Errorf("update appearance: %w", err)
+	}
+	items, err := s.tags.ListByIDs(ctx, userID, []string{tagID})
+	if err != nil {
+		return nil, fmt.Errorf("list by ids: %w", err)
+	}
+	if len(items) == 0 {
+		return nil, appErr.ErrNotFound
+	}
+	return &items[0], nil
+}
+
+// Rename changes a tag's name and carries its descendants along, so renaming
+// "work" to "archive/work" also turns "work/2024" into "archive/work/2024".
+func (s *TagService) Rename(ctx context.Context, userID, tagID, name string) (*model.Tag, error) {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return nil, err
+	}
+	name = normalizeTagName(name)
+	if !validTagName(name) {
+		return nil, appErr.ErrInvalid
+	}
+	var renamed *model.Tag
+	err := s.runInTx(ctx, func(txCtx context.Context) error {
+		all, err := s.tags.List(txCtx, userID)
+		if err != nil {
+			return fmt.Errorf("list tags: %w", err)
+		}
+		tag := findTagByID(all, tagID)
+		if tag == nil {
+			return appErr.ErrNotFound
+		}
+		oldName := tag.Name
+		if oldName == name {
+			renamed = tag
+			return nil
+		}
+		now := timeutil.NowUnix()
+		for _, item := range tagSubtree(all, oldName) {
+			newName := name + strings.TrimPrefix(item.Name, oldName)
+			if !validTagName(newName) {
+				return appErr.ErrInvalid
+			}
+			if err := s.tags.UpdateName(txCtx, userID, item.ID, newName, now); err != nil {
+				return fmt.Errorf("update name: %w", err)
+			}
+		}
+		tag.Name = name
+		tag.Mtime = now
+		renamed = tag
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return renamed, nil
+}
+
+// Merge folds the source tag into the target: documents and template default
+// tags are moved to the target, descendants are re-parented under it (merging
+// with same-named tags already there), and the source tag is removed.
+func (s *TagService) Merge(ctx context.Context, userID, sourceID, targetID string) (*model.Tag, error) {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return nil, err
+	}
+	if sourceID == "" || targetID == "" || sourceID == targetID {
+		return nil, appErr.ErrInvalid
+	}
+	var merged *model.Tag
+	err := s.runInTx(ctx, func(txCtx context.Context) error {
+		all, err := s.tags.List(txCtx, userID)
+		if err != nil {
+			return fmt.Errorf("list tags: %w", err)
+		}
+		source := findTagByID(all, sourceID)
+		target := findTagByID(all, targetID)
+		if source == nil || target == nil {
+			return appErr.ErrNotFound
+		}
+		if isTagDescendant(target.Name, source.Name) {
+			return appErr.ErrInvalid
+		}
+		mapping, err := s.mergeDescendants(txCtx, userID, all, source.Name, target.Name)
+		if err != nil {
+			return err
+		}
+		mapping[source.ID] = target.ID
+		if err := s.rewriteTemplateTags(txCtx, userID, mapping); err != nil {
+			return err
+		}
+		fromIDs := make([]string, 0, len(mapping))
+		for fromID := range mapping {
+			fromIDs = append(fromIDs, fromID)
+		}
+		sort.Strings(fromIDs)
+		for _, fromID := range fromIDs {
+			if err := s.docTags.ReassignTag(txCtx, userID, fromID, mapping[fromID]); err != nil {
+				return fmt.Errorf("reassign tag: %w", err)
+			}
+			if err := s.tags.Delete(txCtx, userID, fromID); err != nil {
+				return fmt.Errorf("delete merged tag: %w", err)
+			}
+		}
+		merged = target
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return merged, nil
+}
+
+// mergeDescendants moves the descendants of source under target. Children
+// whose new name is already taken are returned as a from->to mapping so the
+// caller can merge them like the source itself.
+func (s *TagService) mergeDescendants(
+	ctx context.Context, userID string, all []model.Tag, source, target string,
+) (map[string]string, error) {
+	byName := make(map[string]string, len(all))
+	for _, tag := range all {
+		byName[tag.Name] = tag.ID
+	}
+	mapping := make(map[string]string)
+	now := timeutil.NowUnix()
+	for _, child := range tagSubtree(all, source) {
+		if child.Name == source {
+			continue
+		}
+		newName := target + strings.TrimPrefix(child.Name, source)
+		if existingID, ok := byName[newName]; ok {
+			mapping[child.ID] = existingID
+			continue
+		}
+		if !validTagName(newName) {
+			return nil, appErr.ErrInvalid
+		}
+		if err := s.tags.UpdateName(ctx, userID, child.ID, newName, now); err != nil {
+			return nil, fmt.Errorf("update name: %w", err)
+		}
+		byName[newName] = child.ID
+	}
+	return mapping, nil
+}
+
+func (s *TagService) rewriteTemplateTags(ctx context.Context, userID string, mapping map[string]string) error {
+	if s.templates == nil {
+		return nil
+	}
+	templates, err := s.templates.ListByUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("list templates: %w", err)
+	}
+	now := timeutil.NowUnix()
+	for i := range templates {
+		tpl := &templates[i]
+		changed := false
+		ids := make([]string, 0, len(tpl.DefaultTagIDs))
+		for _, id := range tpl.DefaultTagIDs {
+			if toID, ok := mapping[id]; ok {
+				id = toID
+				changed = true
+			}
+			ids = append(ids, id)
+		}
+		for j := range tpl.Variables {
+			variable := &tpl.Variables[j]
+			if variable.Type != model.TemplateVariableTag {
+				continue
+			}
+			if toID, ok := mapping[variable.Default]; ok {
+				variable.Default = toID
+				changed = true
+			}
+		}
+		if !changed {
+			continue
+		}
+		tpl.DefaultTagIDs = uniqueStringSlice(ids)
+		tpl.Mtime = now
+		if err := s.templates.Update(ctx, tpl); err != nil {
+			return fmt.Errorf("update template %s: %w", tpl.ID, err)
+		}
+	}
+	return nil
+}
+
+func findTagByID(tags []model.Tag, id string) *model.Tag {
+	for i := range tags {
+		if tags[i].ID == id {
+			tag := tags[i]
+			return &tag
+		}
+	}
+	return nil
+}
+
+// tagSubtree returns the tag named root and its descendants, parents first.
+func tagSubtree(tags []model.Tag, root string) []model.Tag {
+	result := make([]model.Tag, 0)
+	for _, tag := range tags {
+		if tag.Name == root || isTagDescendant(tag.Name, root) {
+			result = append(result, tag)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
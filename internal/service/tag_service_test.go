@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -21,6 +22,8 @@ type mockTagRepo struct {
 	listByNamesFn  func(ctx context.Context, userID string, names []string) ([]model.Tag, error)
 	listByIDsFn    func(ctx context.Context, userID string, ids []string) ([]model.Tag, error)
 	updatePinnedFn func(ctx context.Context, userID, tagID string, pinned int, mtime int64) error
+	updateNameFn   func(ctx context.Context, userID, tagID, name string, mtime int64) error
+	updateAppearFn func(ctx context.Context, tag *model.Tag) error
 	deleteFn       func(ctx context.Context, userID, tagID string) error
 }
 
@@ -60,6 +63,14 @@ func (m *mockTagRepo) UpdatePinned(ctx context.Context, userID, tagID string, pi
 	return m.updatePinnedFn(ctx, userID, tagID, pinned, mtime)
 }
 
+func (m *mockTagRepo) UpdateName(ctx context.Context, userID, tagID, name string, mtime int64) error {
+	return m.updateNameFn(ctx, userID, tagID, name, mtime)
+}
+
+func (m *mockTagRepo) UpdateAppearance(ctx context.Context, tag *model.Tag) error {
+	return m.updateAppearFn(ctx, tag)
+}
+
 func (m *mockTagRepo) Delete(ctx context.Context, userID, tagID string) error {
 	return m.deleteFn(ctx, userID, tagID)
 }
@@ -68,6 +79,7 @@ type mockDocumentTagRepo struct {
 	addFn                func(ctx context.Context, docTag *model.DocumentTag) error
 	deleteByDocFn        func(ctx context.Context, userID, docID string) error
 	deleteByTagFn        func(ctx context.Context, userID, tagID string) error
+	reassignTagFn        func(ctx context.Context, userID, fromTagID, toTagID string) error
 	listTagIDsFn         func(ctx context.Context, userID, docID string) ([]string, error)
 	listDocIDsByTagFn    func(ctx context.Context, userID, tagID string) ([]string, error)
 	listByUserFn         func(ctx context.Context, userID string) ([]model.DocumentTag, error)
@@ -86,6 +98,10 @@ func (m *mockDocumentTagRepo) DeleteByTag(ctx context.Context, userID, tagID str
 	return m.deleteByTagFn(ctx, userID, tagID)
 }
 
+func (m *mockDocumentTagRepo) ReassignTag(ctx context.Context, userID, fromTagID, toTagID string) error {
+	return m.reassignTagFn(ctx, userID, fromTagID, toTagID)
+}
+
 func (m *mockDocumentTagRepo) ListTagIDs(ctx context.Context, userID, docID string) ([]string, error) {
 	return m.listTagIDsFn(ctx, userID, docID)
 }
@@ -111,7 +127,7 @@ func TestTagService_Create(t *testing.T) {
 				return nil
 			},
 		}
-		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{})
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
 		tag, err := svc.Create(context.Background(), "u1", "golang")
 		require.NoError(t, err)
 		assert.Equal(t, "golang", tag.Name)
@@ -127,7 +143,7 @@ func TestTagService_Create(t *testing.T) {
 				return []model.Tag{{ID: "existing-id", Name: names[0]}}, nil
 			},
 		}
-		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{})
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
 		tag, err := svc.Create(context.Background(), "u1", "golang")
 		require.NoError(t, err)
 		assert.Equal(t, "existing-id", tag.ID)
@@ -142,7 +158,7 @@ func TestTagService_Create(t *testing.T) {
 				return nil, nil
 			},
 		}
-		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{})
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
 		_, err := svc.Create(context.Background(), "u1", "golang")
 		assert.ErrorIs(t, err, appErr.ErrConflict)
 	})
@@ -153,7 +169,7 @@ func TestTagService_Create(t *testing.T) {
 				return errors.New("db error")
 			},
 		}
-		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{})
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
 		_, err := svc.Create(context.Background(), "u1", "golang")
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "create tag")
@@ -168,14 +184,14 @@ func TestTagService_CreateBatch(t *testing.T) {
 				return nil
 			},
 		}
-		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{})
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
 		result, err := svc.CreateBatch(context.Background(), "u1", []string{"go", "rust"})
 		require.NoError(t, err)
 		assert.Len(t, result, 2)
 	})
 
 	t.Run("empty_names", func(t *testing.T) {
-		svc := NewTagService(testRuntime(), &mockTagRepo{}, &mockDocumentTagRepo{})
+		svc := NewTagService(testRuntime(), &mockTagRepo{}, &mockDocumentTagRepo{}, nil)
 		result, err := svc.CreateBatch(context.Background(), "u1", nil)
 		require.NoError(t, err)
 		assert.Empty(t, result)
@@ -188,14 +204,14 @@ func TestTagService_CreateBatch(t *testing.T) {
 				return nil
 			},
 		}
-		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{})
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
 		result, err := svc.CreateBatch(context.Background(), "u1", []string{"Go", "go", " go "})
 		require.NoError(t, err)
 		assert.Len(t, result, 1)
 	})
 
 	t.Run("all_blank", func(t *testing.T) {
-		svc := NewTagService(testRuntime(), &mockTagRepo{}, &mockDocumentTagRepo{})
+		svc := NewTagService(testRuntime(), &mockTagRepo{}, &mockDocumentTagRepo{}, nil)
 		result, err := svc.CreateBatch(context.Background(), "u1", []string{"", " ", "  "})
 		require.NoError(t, err)
 		assert.Empty(t, result)
@@ -207,7 +223,7 @@ func TestTagService_CreateBatch(t *testing.T) {
 				return appErr.ErrConflict
 			},
 		}
-		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{})
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
 		_, err := svc.CreateBatch(context.Background(), "u1", []string{"go"})
 		assert.ErrorIs(t, err, appErr.ErrConflict)
 	})
@@ -218,7 +234,7 @@ func TestTagService_CreateBatch(t *testing.T) {
 				return errors.New("db error")
 			},
 		}
-		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{})
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
 		_, err := svc.CreateBatch(context.Background(), "u1", []string{"go"})
 		assert.Error(t, err)
 	})
@@ -231,7 +247,7 @@ func TestTagService_List(t *testing.T) {
 				return []model.Tag{{ID: "t1", Name: "go"}}, nil
 			},
 		}
-		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{})
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
 		result, err := svc.List(context.Background(), "u1")
 		require.NoError(t, err)
 		assert.Len(t, result, 1)
@@ -243,7 +259,7 @@ func TestTagService_List(t *testing.T) {
 				return nil, errors.New("db error")
 			},
 		}
-		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{})
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
 		_, err := svc.List(context.Background(), "u1")
 		assert.Error(t, err)
 	})
@@ -259,7 +275,7 @@ func TestTagService_ListPage(t *testing.T) {
 				return []model.Tag{{Name: "golang"}}, nil
 			},
 		}
-		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{})
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
 		result, err := svc.ListPage(context.Background(), "u1", "go", 10, 0)
 		require.NoError(t, err)
 		assert.Len(t, result, 1)
@@ -271,7 +287,7 @@ func TestTagService_ListPage(t *testing.T) {
 				return nil, errors.New("db error")
 			},
 		}
-		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{})
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
 		_, err := svc.ListPage(context.Background(), "u1", "", 10, 0)
 		assert.Error(t, err)
 	})
@@ -284,7 +300,7 @@ func TestTagService_ListSummary(t *testing.T) {
 				return []model.TagSummary{{Name: "go", Count: 5}}, nil
 			},
 		}
-		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{})
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
 		result, err := svc.ListSummary(context.Background(), "u1", "", 10, 0)
 		require.NoError(t, err)
 		assert.Len(t, result, 1)
@@ -297,7 +313,7 @@ func TestTagService_ListSummary(t *testing.T) {
 				return nil, errors.New("db error")
 			},
 		}
-		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{})
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
 		_, err := svc.ListSummary(context.Background(), "u1", "", 10, 0)
 		assert.Error(t, err)
 	})
@@ -311,7 +327,7 @@ func TestTagService_ListByNames(t *testing.T) {
 				return []model.Tag{{Name: "go"}, {Name: "rust"}}, nil
 			},
 		}
-		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{})
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
 		result, err := svc.ListByNames(context.Background(), "u1", []string{"go", "rust"})
 		require.NoError(t, err)
 		assert.Len(t, result, 2)
@@ -323,7 +339,7 @@ func TestTagService_ListByNames(t *testing.T) {
 				return nil, errors.New("db error")
 			},
 		}
-		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{})
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
 		_, err := svc.ListByNames(context.Background(), "u1", []string{"go"})
 		assert.Error(t, err)
 	})
@@ -336,7 +352,7 @@ func TestTagService_ListByIDs(t *testing.T) {
 				return []model.Tag{{ID: "t1"}}, nil
 			},
 		}
-		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{})
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
 		result, err := svc.ListByIDs(context.Background(), "u1", []string{"t1"})
 		require.NoError(t, err)
 		assert.Len(t, result, 1)
@@ -348,7 +364,7 @@ func TestTagService_ListByIDs(t *testing.T) {
 				return nil, errors.New("db error")
 			},
 		}
-		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{})
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
 		_, err := svc.ListByIDs(context.Background(), "u1", []string{"t1"})
 		assert.Error(t, err)
 	})
@@ -374,7 +390,7 @@ func TestTagService_Delete(t *testing.T) {
 				return nil
 			},
 		}
-		svc := NewTagService(testRuntime(), tags, docTags)
+		svc := NewTagService(testRuntime(), tags, docTags, nil)
 		err := svc.Delete(context.Background(), "u1", "t1")
 		require.NoError(t, err)
 		assert.True(t, docTagsCalled)
@@ -388,7 +404,7 @@ func TestTagService_Delete(t *testing.T) {
 				return errors.New("db error")
 			},
 		}
-		svc := NewTagService(testRuntime(), tags, docTags)
+		svc := NewTagService(testRuntime(), tags, docTags, nil)
 		err := svc.Delete(context.Background(), "u1", "t1")
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "delete by tag")
@@ -403,7 +419,7 @@ func TestTagService_Delete(t *testing.T) {
 		docTags := &mockDocumentTagRepo{
 			deleteByTagFn: func(context.Context, string, string) error { return nil },
 		}
-		svc := NewTagService(testRuntime(), tags, docTags)
+		svc := NewTagService(testRuntime(), tags, docTags, nil)
 		err := svc.Delete(context.Background(), "u1", "t1")
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "delete")
@@ -420,13 +436,13 @@ func TestTagService_UpdatePinned(t *testing.T) {
 				return nil
 			},
 		}
-		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{})
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
 		err := svc.UpdatePinned(context.Background(), "u1", "t1", 1)
 		require.NoError(t, err)
 	})
 
 	t.Run("invalid_value", func(t *testing.T) {
-		svc := NewTagService(testRuntime(), &mockTagRepo{}, &mockDocumentTagRepo{})
+		svc := NewTagService(testRuntime(), &mockTagRepo{}, &mockDocumentTagRepo{}, nil)
 		err := svc.UpdatePinned(context.Background(), "u1", "t1", 2)
 		assert.ErrorIs(t, err, appErr.ErrInvalid)
 	})
@@ -437,8 +453,241 @@ func TestTagService_UpdatePinned(t *testing.T) {
 				return errors.New("db error")
 			},
 		}
-		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{})
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
 		err := svc.UpdatePinned(context.Background(), "u1", "t1", 0)
 		assert.Error(t, err)
 	})
 }
+
+func TestNormalizeTagName(t *testing.T) {
+	assert.Equal(t, "project/mnote/backend", normalizeTagName(" project / mnote//backend/ "))
+	assert.Equal(t, "go", normalizeTagName("go"))
+	assert.Equal(t, "", normalizeTagName(" / "))
+}
+
+func hierarchyTags() []model.Tag {
+	return []model.Tag{
+		{ID: "t1", UserID: "u1", Name: "work"},
+		{ID: "t2", UserID: "u1", Name: "work/2024"},
+		{ID: "t3", UserID: "u1", Name: "work/2024/q1"},
+		{ID: "t4", UserID: "u1", Name: "workshop"},
+		{ID: "t5", UserID: "u1", Name: "archive"},
+		{ID: "t6", UserID: "u1", Name: "archive/2024"},
+	}
+}
+
+func TestTagService_Rename(t *testing.T) {
+	t.Run("renames_descendants", func(t *testing.T) {
+		renamed := map[string]string{}
+		tags := &mockTagRepo{
+			listFn: func(context.Context, string) ([]model.Tag, error) { return hierarchyTags(), nil },
+			updateNameFn: func(_ context.Context, userID, tagID, name string, _ int64) error {
+				assert.Equal(t, "u1", userID)
+				renamed[tagID] = name
+				return nil
+			},
+		}
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
+		tag, err := svc.Rename(context.Background(), "u1", "t1", " job / current ")
+		require.NoError(t, err)
+		assert.Equal(t, "job/current", tag.Name)
+		assert.Equal(t, map[string]string{
+			"t1": "job/current",
+			"t2": "job/current/2024",
+			"t3": "job/current/2024/q1",
+		}, renamed)
+	})
+
+	t.Run("same_name_noop", func(t *testing.T) {
+		tags := &mockTagRepo{
+			listFn: func(context.Context, string) ([]model.Tag, error) { return hierarchyTags(), nil },
+		}
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
+		tag, err := svc.Rename(context.Background(), "u1", "t4", "workshop")
+		require.NoError(t, err)
+		assert.Equal(t, "t4", tag.ID)
+	})
+
+	t.Run("invalid_name", func(t *testing.T) {
+		svc := NewTagService(testRuntime(), &mockTagRepo{}, &mockDocumentTagRepo{}, nil)
+		_, err := svc.Rename(context.Background(), "u1", "t1", " / ")
+		assert.ErrorIs(t, err, appErr.ErrInvalid)
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		tags := &mockTagRepo{
+			listFn: func(context.Context, string) ([]model.Tag, error) { return hierarchyTags(), nil },
+		}
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
+		_, err := svc.Rename(context.Background(), "u1", "missing", "x")
+		assert.ErrorIs(t, err, appErr.ErrNotFound)
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		tags := &mockTagRepo{
+			listFn: func(context.Context, string) ([]model.Tag, error) { return hierarchyTags(), nil },
+			updateNameFn: func(context.Context, string, string, string, int64) error {
+				return appErr.ErrConflict
+			},
+		}
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
+		_, err := svc.Rename(context.Background(), "u1", "t1", "archive")
+		assert.ErrorIs(t, err, appErr.ErrConflict)
+	})
+}
+
+func TestTagService_Merge(t *testing.T) {
+	t.Run("merges_documents_templates_and_children", func(t *testing.T) {
+		renamed := map[string]string{}
+		deleted := make([]string, 0)
+		reassigned := map[string]string{}
+		tags := &mockTagRepo{
+			listFn: func(context.Context, string) ([]model.Tag, error) { return hierarchyTags(), nil },
+			updateNameFn: func(_ context.Context, _, tagID, name string, _ int64) error {
+				renamed[tagID] = name
+				return nil
+			},
+			deleteFn: func(_ context.Context, _, tagID string) error {
+				deleted = append(deleted, tagID)
+				return nil
+			},
+		}
+		docTags := &mockDocumentTagRepo{
+			reassignTagFn: func(_ context.Context, _, fromTagID, toTagID string) error {
+				reassigned[fromTagID] = toTagID
+				return nil
+			},
+		}
+		updated := make([]model.Template, 0)
+		templates := &mockTemplateRepo{
+			listByUserFn: func(context.Context, string) ([]model.Template, error) {
+				return []model.Template{
+					{ID: "tpl1", UserID: "u1", DefaultTagIDs: []string{"t1", "t5"}},
+					{ID: "tpl2", UserID: "u1", DefaultTagIDs: []string{"t4"}},
+					{ID: "tpl3", UserID: "u1", DefaultTagIDs: []string{"t2"}},
+					{ID: "tpl4", UserID: "u1", Variables: []model.TemplateVariable{
+						{Key: "AREA", Type: model.TemplateVariableTag, Default: "t1"},
+					}},
+				}, nil
+			},
+			updateFn: func(_ context.Context, tpl *model.Template) error {
+				updated = append(updated, *tpl)
+				return nil
+			},
+		}
+		svc := NewTagService(testRuntime(), tags, docTags, templates)
+		target, err := svc.Merge(context.Background(), "u1", "t1", "t5")
+		require.NoError(t, err)
+		assert.Equal(t, "t5", target.ID)
+		assert.Equal(t, map[string]string{"t3": "archive/2024/q1"}, renamed)
+		assert.Equal(t, map[string]string{"t1": "t5", "t2": "t6"}, reassigned)
+		assert.Equal(t, []string{"t1", "t2"}, deleted)
+		require.Len(t, updated, 3)
+		assert.Equal(t, []string{"t5"}, updated[0].DefaultTagIDs)
+		assert.Equal(t, []string{"t6"}, updated[1].DefaultTagIDs)
+		assert.Equal(t, "t5", updated[2].Variables[0].Default)
+	})
+
+	t.Run("same_tag", func(t *testing.T) {
+		svc := NewTagService(testRuntime(), &mockTagRepo{}, &mockDocumentTagRepo{}, nil)
+		_, err := svc.Merge(context.Background(), "u1", "t1", "t1")
+		assert.ErrorIs(t, err, appErr.ErrInvalid)
+	})
+
+	t.Run("into_descendant", func(t *testing.T) {
+		tags := &mockTagRepo{
+			listFn: func(context.Context, string) ([]model.Tag, error) { return hierarchyTags(), nil },
+		}
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
+		_, err := svc.Merge(context.Background(), "u1", "t1", "t2")
+		assert.ErrorIs(t, err, appErr.ErrInvalid)
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		tags := &mockTagRepo{
+			listFn: func(context.Context, string) ([]model.Tag, error) { return hierarchyTags(), nil },
+		}
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
+		_, err := svc.Merge(context.Background(), "u1", "t1", "missing")
+		assert.ErrorIs(t, err, appErr.ErrNotFound)
+	})
+
+	t.Run("reassign_error", func(t *testing.T) {
+		tags := &mockTagRepo{
+			listFn: func(context.Context, string) ([]model.Tag, error) { return hierarchyTags(), nil },
+		}
+		docTags := &mockDocumentTagRepo{
+			reassignTagFn: func(context.Context, string, string, string) error {
+				return errors.New("db error")
+			},
+		}
+		svc := NewTagService(testRuntime(), tags, docTags, nil)
+		_, err := svc.Merge(context.Background(), "u1", "t4", "t5")
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "reassign tag")
+	})
+}
+
+func TestTagService_Update(t *testing.T) {
+	t.Run("success", func(t *testing.T) {
+		var saved *model.Tag
+		tags := &mockTagRepo{
+			updateAppearFn: func(_ context.Context, tag *model.Tag) error {
+				saved = tag
+				return nil
+			},
+			listByIDsFn: func(_ context.Context, userID string, ids []string) ([]model.Tag, error) {
+				return []model.Tag{{
+					ID: ids[0], UserID: userID, Name: "go",
+					Color: saved.Color, Description: saved.Description, Icon: saved.Icon,
+				}}, nil
+			},
+		}
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
+		tag, err := svc.Update(context.Background(), "u1", "t1", TagUpdateInput{
+			Color: " #00ADD8 ", Description: " Go notes ", Icon: "🐹",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "u1", saved.UserID)
+		assert.Equal(t, "#00add8", tag.Color)
+		assert.Equal(t, "Go notes", tag.Description)
+		assert.Equal(t, "🐹", tag.Icon)
+		assert.Equal(t, "go", tag.Name)
+	})
+
+	t.Run("clear_fields", func(t *testing.T) {
+		tags := &mockTagRepo{
+			updateAppearFn: func(_ context.Context, tag *model.Tag) error {
+				assert.Empty(t, tag.Color)
+				assert.Empty(t, tag.Icon)
+				return nil
+			},
+		}
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
+		_, err := svc.Update(context.Background(), "u1", "t1", TagUpdateInput{})
+		require.NoError(t, err)
+	})
+
+	t.Run("invalid_color", func(t *testing.T) {
+		svc := NewTagService(testRuntime(), &mockTagRepo{}, &mockDocumentTagRepo{}, nil)
+		_, err := svc.Update(context.Background(), "u1", "t1", TagUpdateInput{Color: "red"})
+		assert.ErrorIs(t, err, appErr.ErrInvalid)
+	})
+
+	t.Run("description_too_long", func(t *testing.T) {
+		svc := NewTagService(testRuntime(), &mockTagRepo{}, &mockDocumentTagRepo{}, nil)
+		_, err := svc.Update(context.Background(), "u1", "t1", TagUpdateInput{
+			Description: strings.Repeat("x", maxTagDescriptionRunes+1),
+		})
+		assert.ErrorIs(t, err, appErr.ErrInvalid)
+	})
+
+	t.Run("not_found", func(t *testing.T) {
+		tags := &mockTagRepo{
+			updateAppearFn: func(context.Context, *model.Tag) error { return appErr.ErrNotFound },
+		}
+		svc := NewTagService(testRuntime(), tags, &mockDocumentTagRepo{}, nil)
+		_, err := svc.Update(context.Background(), "u1", "t1", TagUpdateInput{Color: "#fff"})
+		assert.ErrorIs(t, err, appErr.ErrNotFound)
+	})
+}
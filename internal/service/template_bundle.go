@@ -0,0 +1,355 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+const maxTemplateBundleItems = 100
+
+const (
+	TemplateImportCreated = "created"
+	TemplateImportSkipped = "skipped"
+)
+
+type TemplateImportItemResult struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	TemplateID string `json:"template_id,omitempty"`
+}
+
+type TemplateImportResult struct {
+	Created int                        `json:"created"`
+	Skipped int                        `json:"skipped"`
+	Items   []TemplateImportItemResult `json:"items"`
+}
+
+// ExportBundle returns the given templates, or all templates of the user
+// when ids is empty, in portable form.
+func (s *TemplateService) ExportBundle(
+	ctx context.Context, userID string, ids []string,
+) (*model.TemplateBundle, error) {
+	ids = uniqueStringSlice(ids)
+	if len(ids) > maxTemplateBundleItems {
+		return nil, appErr.ErrInvalid
+	}
+	var templates []model.Template
+	if len(ids) == 0 {
+		items, err := s.templates.ListByUser(ctx, userID)
+		if err != nil {
+			return nil, fmt.Errorf("list by user: %w", err)
+		}
+		templates = items
+	}
+	for _, id := range ids {
+		tpl, err := s.templates.GetByID(ctx, userID, id)
+		if err != nil {
+			return nil, fmt.Errorf("get by id: %w", err)
+		}
+		templates = append(templates, *tpl)
+	}
+	names, err := s.templateTagNames(ctx, userID, templates)
+	if err != nil {
+		return nil, err
+	}
+	bundle := &model.TemplateBundle{
+		Format:    model.TemplateBundleFormat,
+		Version:   model.TemplateBundleVersion,
+		Templates: make([]model.TemplateBundleItem, 0, len(templates)),
+	}
+	for _, tpl := range templates {
+		item := model.TemplateBundleItem{
+			Name:        tpl.Name,
+			Description: tpl.Description,
+			Content:     tpl.Content,
+			Variables:   make([]model.TemplateVariable, 0, len(tpl.Variables)),
+			Tags:        make([]string, 0, len(tpl.DefaultTagIDs)),
+		}
+		for _, variable := range tpl.Variables {
+			if variable.Type == model.TemplateVariableTag {
+				variable.Default = names[variable.Default]
+			}
+			item.Variables = append(item.Variables, variable)
+		}
+		// Tags deleted since the template was saved are left out.
+		for _, id := range tpl.DefaultTagIDs {
+			if name, ok := names[id]; ok {
+				item.Tags = append(item.Tags, name)
+			}
+		}
+		bundle.Templates = append(bundle.Templates, item)
+	}
+	return bundle, nil
+}
+
+func (s *TemplateService) templateTagNames(
+	ctx context.Context, userID string, templates []model.Template,
+) (map[string]string, error) {
+	ids := make([]string, 0)
+	for _, tpl := range templates {
+		ids = append(ids, tpl.DefaultTagIDs...)
+		for _, variable := range tpl.Variables {
+			if variable.Type == model.TemplateVariableTag && variable.Default != "" {
+				ids = append(ids, variable.Default)
+			}
+		}
+	}
+	ids = uniqueStringSlice(ids)
+	names := make(map[string]string, len(ids))
+	if len(ids) == 0 || s.tags == nil {
+		return names, nil
+	}
+	tags, err := s.tags.ListByIDs(ctx, userID, ids)
+	if err != nil {
+		return nil, fmt.Errorf("list template tags: %w", err)
+	}
+	for _, tag := range tags {
+		names[tag.ID] = tag.Name
+	}
+	return names, nil
+}
+
+// ImportBundle creates the templates of a bundle for the user. Tags are
+// matched by name and created when missing. A template whose name is
+// already taken is skipped, so importing the same bundle twice is harmless.
+// The bundle is validated as a whole before anything is written.
+func (s *TemplateService) ImportBundle(
+	ctx context.Context, userID string, bundle *model.TemplateBundle,
+) (*TemplateImportResult, error) {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return nil, err
+	}
+	if bundle == nil || bundle.Format != model.TemplateBundleFormat ||
+		bundle.Version <= 0 || bundle.Version > model.TemplateBundleVersion ||
+		len(bundle.Templates) == 0 || len(bundle.Templates) > maxTemplateBundleItems {
+		return nil, appErr.ErrInvalid
+	}
+	for _, item := range bundle.Templates {
+		if err := s.validateTemplate(item.Name, item.Description, item.Content, item.Tags); err != nil {
+			return nil, appErr.ErrInvalid
+		}
+	}
+	result := &TemplateImportResult{Items: make([]TemplateImportItemResult, 0, len(bundle.Templates))}
+	err := s.runtime.Transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+		existing, err := s.templates.ListByUser(txCtx, userID)
+		if err != nil {
+			return fmt.Errorf("list by user: %w", err)
+		}
+		taken := make(map[string]struct{}, len(existing))
+		for _, tpl := range existing {
+			taken[tpl.Name] = struct{}{}
+		}
+		pending := make([]model.TemplateBundleItem, 0, len(bundle.Templates))
+		for _, item := range bundle.Templates {
+			name := strings.TrimSpace(item.Name)
+			if _, ok := taken[name]; ok {
+				result.Skipped++
+				result.Items = append(result.Items, TemplateImportItemResult{Name: name, Status: TemplateImportSkipped})
+				continue
+			}
+			taken[name] = struct{}{}
+			pending = append(pending, item)
+		}
+		tagIDs, err := s.ensureTemplateTags(txCtx, userID, bundleTagNames(pending))
+		if err != nil {
+			return err
+		}
+		for _, item := range pending {
+			tpl, err := s.create(txCtx, userID, bundleTemplateInput(item, tagIDs), nil)
+			if err != nil {
+				return fmt.Errorf("import template %q: %w", item.Name, err)
+			}
+			result.Created++
+			result.Items = append(result.Items, TemplateImportItemResult{
+				Name: tpl.Name, Status: TemplateImportCreated, TemplateID: tpl.ID,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ListGallery returns the built-in templates users can copy.
+func (s *TemplateService) ListGallery(ctx context.Context) ([]model.GalleryTemplate, error) {
+	if s.gallery == nil {
+		return []model.GalleryTemplate{}, nil
+	}
+	items, err := s.gallery.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list gallery: %w", err)
+	}
+	return items, nil
+}
+
+// CopyFromGallery creates a template of the user from a built-in one. The
+// copy is the user's own; it keeps the slug and version of its source so
+// clients can offer to copy again after an upgrade. An empty name keeps the
+// built-in name.
+func (s *TemplateService) CopyFromGallery(
+	ctx context.Context, userID, slug, name string,
+) (*model.Template, error) {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return nil, err
+	}
+	if s.gallery == nil {
+		return nil, appErr.ErrNotFound
+	}
+	source, err := s.gallery.GetBySlug(ctx, slug)
+	if err != nil {
+		return nil, fmt.Errorf("get gallery template: %w", err)
+	}
+	item := model.TemplateBundleItem{
+		Name:        source.Name,
+		Description: source.Description,
+		Content:     source.Content,
+		Variables:   source.Variables,
+		Tags:        source.Tags,
+	}
+	if strings.TrimSpace(name) != "" {
+		item.Name = name
+	}
+	var tpl *model.Template
+	err = s.runtime.Transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+		tagIDs, err := s.ensureTemplateTags(txCtx, userID, bundleTagNames([]model.TemplateBundleItem{item}))
+		if err != nil {
+			return err
+		}
+		tpl, err = s.create(txCtx, userID, bundleTemplateInput(item, tagIDs), source)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tpl, nil
+}
+
+// SeedGallery stores the built-in templates shipped with the binary. A
+// stored template is only replaced by a higher version, and templates no
+// longer shipped are removed. It returns how many templates were written.
+func (s *TemplateService) SeedGallery(ctx context.Context, items []model.GalleryTemplate) (int, error) {
+	if s.gallery == nil {
+		return 0, nil
+	}
+	slugs := make([]string, 0, len(items))
+	for _, item := range items {
+		if item.Slug == "" || item.Version <= 0 {
+			return 0, fmt.Errorf("gallery template %q: %w", item.Slug, appErr.ErrInvalid)
+		}
+		if err := s.validateTemplate(item.Name, item.Description, item.Content, item.Tags); err != nil {
+			return 0, fmt.Errorf("gallery template %s: %w", item.Slug, appErr.ErrInvalid)
+		}
+		slugs = append(slugs, item.Slug)
+	}
+	now := s.runtime.Clock.Now().Unix()
+	written := 0
+	err := s.runtime.Transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+		for i := range items {
+			item := items[i]
+			item.Ctime = now
+			item.Mtime = now
+			ok, err := s.gallery.Upsert(txCtx, &item)
+			if err != nil {
+				return fmt.Errorf("upsert gallery template %s: %w", item.Slug, err)
+			}
+			if ok {
+				written++
+			}
+		}
+		if _, err := s.gallery.DeleteExcept(txCtx, slugs); err != nil {
+			return fmt.Errorf("delete retired gallery templates: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return written, nil
+}
+
+// ensureTemplateTags maps the lower-cased normalized tag names to the IDs of
+// the user's tags, creating the missing ones.
+func (s *TemplateService) ensureTemplateTags(
+	ctx context.Context, userID string, names []string,
+) (map[string]string, error) {
+	cleaned := normalizeTags(names)
+	ids := make(map[string]string, len(cleaned))
+	if len(cleaned) == 0 {
+		return ids, nil
+	}
+	if s.tags == nil {
+		return nil, appErr.ErrInvalid
+	}
+	for _, name := range cleaned {
+		if !validTagName(name) {
+			return nil, appErr.ErrInvalid
+		}
+	}
+	existing, err := s.tags.ListByNames(ctx, userID, cleaned)
+	if err != nil {
+		return nil, fmt.Errorf("list by names: %w", err)
+	}
+	for _, tag := range existing {
+		ids[strings.ToLower(tag.Name)] = tag.ID
+	}
+	now := s.runtime.Clock.Now().Unix()
+	missing := make([]model.Tag, 0)
+	for _, name := range cleaned {
+		if _, ok := ids[strings.ToLower(name)]; ok {
+			continue
+		}
+		id, err := s.runtime.IDs.ID()
+		if err != nil {
+			return nil, fmt.Errorf("generate tag id: %w", err)
+		}
+		missing = append(missing, model.Tag{ID: id, UserID: userID, Name: name, Ctime: now, Mtime: now})
+		ids[strings.ToLower(name)] = id
+	}
+	if len(missing) > 0 {
+		if err := s.tags.CreateBatch(ctx, missing); err != nil {
+			return nil, fmt.Errorf("create batch: %w", err)
+		}
+	}
+	return ids, nil
+}
+
+func bundleTagNames(items []model.TemplateBundleItem) []string {
+	names := make([]string, 0)
+	for _, item := range items {
+		names = append(names, item.Tags...)
+		for _, variable := range item.Variables {
+			if strings.EqualFold(strings.TrimSpace(variable.Type), model.TemplateVariableTag) {
+				names = append(names, variable.Default)
+			}
+		}
+	}
+	return names
+}
+
+func bundleTemplateInput(item model.TemplateBundleItem, tagIDs map[string]string) CreateTemplateInput {
+	input := CreateTemplateInput{
+		Name:          item.Name,
+		Description:   item.Description,
+		Content:       item.Content,
+		Variables:     make([]model.TemplateVariable, 0, len(item.Variables)),
+		DefaultTagIDs: make([]string, 0, len(item.Tags)),
+	}
+	for _, variable := range item.Variables {
+		if strings.EqualFold(strings.TrimSpace(variable.Type), model.TemplateVariableTag) {
+			variable.Default = tagIDs[strings.ToLower(normalizeTagName(variable.Default))]
+		}
+		input.Variables = append(input.Variables, variable)
+	}
+	for _, name := range item.Tags {
+		if id, ok := tagIDs[strings.ToLower(normalizeTagName(name))]; ok {
+			input.DefaultTagIDs = append(input.DefaultTagIDs, id)
+		}
+	}
+	return input
+}
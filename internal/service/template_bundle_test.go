@@ -0,0 +1,240 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/gallery"
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+type mockTemplateGalleryRepo struct {
+	items   map[string]model.GalleryTemplate
+	kept    []string
+	written []string
+}
+
+func (m *mockTemplateGalleryRepo) Upsert(_ context.Context, item *model.GalleryTemplate) (bool, error) {
+	if stored, ok := m.items[item.Slug]; ok && stored.Version >= item.Version {
+		return false, nil
+	}
+	m.items[item.Slug] = *item
+	m.written = append(m.written, item.Slug)
+	return true, nil
+}
+
+func (m *mockTemplateGalleryRepo) DeleteExcept(_ context.Context, slugs []string) (int64, error) {
+	m.kept = slugs
+	return 0, nil
+}
+
+func (m *mockTemplateGalleryRepo) List(context.Context) ([]model.GalleryTemplate, error) {
+	out := make([]model.GalleryTemplate, 0, len(m.items))
+	for _, item := range m.items {
+		out = append(out, item)
+	}
+	return out, nil
+}
+
+func (m *mockTemplateGalleryRepo) GetBySlug(_ context.Context, slug string) (*model.GalleryTemplate, error) {
+	item, ok := m.items[slug]
+	if !ok {
+		return nil, appErr.ErrNotFound
+	}
+	return &item, nil
+}
+
+// memoryTagRepo keeps the tags of a single user in memory.
+func memoryTagRepo(existing ...model.Tag) (*mockTagRepo, *[]model.Tag) {
+	tags := append([]model.Tag{}, existing...)
+	repo := &mockTagRepo{
+		listByNamesFn: func(_ context.Context, _ string, names []string) ([]model.Tag, error) {
+			out := []model.Tag{}
+			for _, tag := range tags {
+				for _, name := range names {
+					if strings.EqualFold(tag.Name, name) {
+						out = append(out, tag)
+					}
+				}
+			}
+			return out, nil
+		},
+		listByIDsFn: func(_ context.Context, _ string, ids []string) ([]model.Tag, error) {
+			out := []model.Tag{}
+			for _, tag := range tags {
+				for _, id := range ids {
+					if tag.ID == id {
+						out = append(out, tag)
+					}
+				}
+			}
+			return out, nil
+		},
+		createBatchFn: func(_ context.Context, created []model.Tag) error {
+			tags = append(tags, created...)
+			return nil
+		},
+	}
+	return repo, &tags
+}
+
+func TestTemplateService_ExportBundle(t *testing.T) {
+	repo := &mockTemplateRepo{
+		getByIDFn: func(_ context.Context, _, id string) (*model.Template, error) {
+			return &model.Template{
+				ID: id, Name: "Meeting", Content: "# {{TOPIC}}",
+				Variables: []model.TemplateVariable{
+					{Key: "TOPIC", Type: model.TemplateVariableText},
+					{Key: "TEAM", Type: model.TemplateVariableTag, Default: "t2"},
+				},
+				DefaultTagIDs: []string{"t1", "gone"},
+			}, nil
+		},
+	}
+	tags, _ := memoryTagRepo(model.Tag{ID: "t1", Name: "work"}, model.Tag{ID: "t2", Name: "team/core"})
+	svc := NewTemplateService(repo, nil, tags, nil, nil, testRuntime())
+
+	bundle, err := svc.ExportBundle(context.Background(), "u1", []string{"tpl1"})
+	require.NoError(t, err)
+	assert.Equal(t, model.TemplateBundleFormat, bundle.Format)
+	require.Len(t, bundle.Templates, 1)
+	item := bundle.Templates[0]
+	assert.Equal(t, []string{"work"}, item.Tags)
+	assert.Equal(t, "team/core", item.Variables[1].Default)
+}
+
+func TestTemplateService_ImportBundle(t *testing.T) {
+	var created []*model.Template
+	repo := &mockTemplateRepo{
+		listByUserFn: func(context.Context, string) ([]model.Template, error) {
+			return []model.Template{{ID: "old", Name: "Daily"}}, nil
+		},
+		createFn: func(_ context.Context, tpl *model.Template) error {
+			created = append(created, tpl)
+			return nil
+		},
+	}
+	tags, stored := memoryTagRepo(model.Tag{ID: "t1", Name: "Work"})
+	svc := NewTemplateService(repo, nil, tags, nil, nil, testRuntime())
+
+	result, err := svc.ImportBundle(context.Background(), "u1", &model.TemplateBundle{
+		Format: model.TemplateBundleFormat, Version: model.TemplateBundleVersion,
+		Templates: []model.TemplateBundleItem{
+			{Name: "Daily", Content: "skipped"},
+			{
+				Name: "Meeting", Content: "# {{TOPIC}}", Tags: []string{"work", " new / tag "},
+				Variables: []model.TemplateVariable{{Key: "team", Type: "tag", Default: "Team"}},
+			},
+			{Name: "Meeting", Content: "duplicate in bundle"},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Created)
+	assert.Equal(t, 2, result.Skipped)
+	require.Len(t, created, 1)
+	tpl := created[0]
+	assert.Equal(t, 0, tpl.BuiltIn)
+	require.Len(t, *stored, 3, "missing tags are created")
+	names := map[string]string{}
+	for _, tag := range *stored {
+		names[tag.ID] = tag.Name
+	}
+	require.Len(t, tpl.DefaultTagIDs, 2)
+	assert.Equal(t, "Work", names[tpl.DefaultTagIDs[0]])
+	assert.Equal(t, "new/tag", names[tpl.DefaultTagIDs[1]])
+	assert.Equal(t, "Team", names[tpl.Variables[0].Default])
+}
+
+func TestTemplateService_ImportBundle_Invalid(t *testing.T) {
+	svc := NewTemplateService(&mockTemplateRepo{}, nil, nil, nil, nil, testRuntime())
+	cases := map[string]*model.TemplateBundle{
+		"nil":     nil,
+		"format":  {Format: "other", Version: 1, Templates: []model.TemplateBundleItem{{Name: "a", Content: "b"}}},
+		"version": {Format: model.TemplateBundleFormat, Version: 99, Templates: []model.TemplateBundleItem{{Name: "a", Content: "b"}}},
+		"empty":   {Format: model.TemplateBundleFormat, Version: 1},
+		"content": {Format: model.TemplateBundleFormat, Version: 1, Templates: []model.TemplateBundleItem{{Name: "a"}}},
+	}
+	for name, bundle := range cases {
+		t.Run(name, func(t *testing.T) {
+			_, err := svc.ImportBundle(context.Background(), "u1", bundle)
+			assert.ErrorIs(t, err, appErr.ErrInvalid)
+		})
+	}
+}
+
+func TestTemplateService_CopyFromGallery(t *testing.T) {
+	var created *model.Template
+	repo := &mockTemplateRepo{createFn: func(_ context.Context, tpl *model.Template) error {
+		created = tpl
+		return nil
+	}}
+	galleryRepo := &mockTemplateGalleryRepo{items: map[string]model.GalleryTemplate{
+		"daily-note": {Slug: "daily-note", Version: 3, Name: "Daily note", Content: "# {{sys:today}}", Tags: []string{"journal"}},
+	}}
+	tags, stored := memoryTagRepo()
+	svc := NewTemplateService(repo, nil, tags, nil, galleryRepo, testRuntime())
+
+	tpl, err := svc.CopyFromGallery(context.Background(), "u1", "daily-note", "")
+	require.NoError(t, err)
+	assert.Same(t, created, tpl)
+	assert.Equal(t, "Daily note", tpl.Name)
+	assert.Equal(t, 1, tpl.BuiltIn)
+	assert.Equal(t, "daily-note", tpl.SourceSlug)
+	assert.Equal(t, 3, tpl.SourceVersion)
+	require.Len(t, *stored, 1)
+	assert.Equal(t, []string{(*stored)[0].ID}, tpl.DefaultTagIDs)
+
+	_, err = svc.CopyFromGallery(context.Background(), "u1", "missing", "")
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
+
+func TestTemplateService_SeedGallery(t *testing.T) {
+	galleryRepo := &mockTemplateGalleryRepo{items: map[string]model.GalleryTemplate{
+		"daily-note": {Slug: "daily-note", Version: 2},
+	}}
+	svc := NewTemplateService(&mockTemplateRepo{}, nil, nil, nil, galleryRepo, testRuntimeAt(100))
+
+	written, err := svc.SeedGallery(context.Background(), []model.GalleryTemplate{
+		{Slug: "daily-note", Version: 2, Name: "Daily note", Content: "unchanged"},
+		{Slug: "weekly-review", Version: 1, Name: "Weekly review", Content: "new"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1, written)
+	assert.Equal(t, []string{"weekly-review"}, galleryRepo.written)
+	assert.Equal(t, []string{"daily-note", "weekly-review"}, galleryRepo.kept)
+	assert.Equal(t, int64(100), galleryRepo.items["weekly-review"].Mtime)
+
+	_, err = svc.SeedGallery(context.Background(), []model.GalleryTemplate{{Slug: "bad", Version: 1, Name: "Bad"}})
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+}
+
+// TestBuiltInGalleryTemplatesRender guards the templates shipped in the
+// gallery package against syntax the renderer rejects.
+func TestBuiltInGalleryTemplatesRender(t *testing.T) {
+	items, err := gallery.Load()
+	require.NoError(t, err)
+	svc := NewTemplateService(&mockTemplateRepo{}, nil, nil, nil, nil, testRuntime())
+	now := time.Date(2026, 4, 28, 10, 30, 0, 0, time.Local)
+	for _, item := range items {
+		t.Run(item.Slug, func(t *testing.T) {
+			require.NoError(t, svc.validateTemplate(item.Name, item.Description, item.Content, item.Tags))
+			variables, err := svc.normalizeVariables(context.Background(), "u1", item.Variables)
+			require.NoError(t, err)
+			values := map[string]string{}
+			_, err = svc.resolveVariableValues(context.Background(), "u1", variables, values, now)
+			require.NoError(t, err)
+			content, err := renderTemplateContent(
+				normalizeTemplateContentPlaceholders(item.Content),
+				templateRenderContext{values: values, now: now},
+			)
+			require.NoError(t, err)
+			assert.NotContains(t, strings.ToLower(content), "{{sys:")
+		})
+	}
+}
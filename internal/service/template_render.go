@@ -0,0 +1,396 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+const (
+	maxTemplateBlockDepth = 10
+	maxTemplateLoopItems  = 200
+)
+
+// templateTagRegex matches every `{{...}}` tag the renderer understands:
+// variables `{{KEY}}` / `{{KEY|format}}`, block openers `{{#if KEY}}` /
+// `{{#each KEY}}`, `{{else}}` and closers `{{/if}}` / `{{/each}}`. Anything
+// else between braces stays literal text.
+var templateTagRegex = regexp.MustCompile(
+	`\{\{\s*([#/]?)([a-zA-Z0-9_:+\-]+)(?:\s+([a-zA-Z0-9_:\-]+))?\s*(?:\|([^{}\r\n]*))?\}\}`,
+)
+
+var systemTimeVariableRegex = regexp.MustCompile(`^sys:([a-z]+)(?:([+-])(\d{1,4})([hdwmy]?))?$`)
+
+type templateNodeKind int
+
+const (
+	templateNodeText templateNodeKind = iota
+	templateNodeVariable
+	templateNodeIf
+	templateNodeEach
+)
+
+type templateNode struct {
+	kind     templateNodeKind
+	text     string
+	key      string
+	format   string
+	children []templateNode
+	orElse   []templateNode
+}
+
+type templateTag struct {
+	prefix string
+	name   string
+	arg    string
+	format string
+	hasFmt bool
+}
+
+func parseTemplateTag(match []string) templateTag {
+	return templateTag{
+		prefix: match[1],
+		name:   match[2],
+		arg:    match[3],
+		format: strings.TrimSpace(match[4]),
+		hasFmt: strings.Contains(match[0], "|"),
+	}
+}
+
+func (tag templateTag) block() string {
+	name := strings.ToLower(tag.name)
+	if name == "if" || name == "each" {
+		return name
+	}
+	return ""
+}
+
+func (tag templateTag) isElse() bool {
+	return tag.prefix == "" && tag.arg == "" && !tag.hasFmt && strings.EqualFold(tag.name, "else")
+}
+
+// parseTemplateNodes turns template content into a tree of text, variable
+// and block nodes. Unbalanced or misplaced block tags are reported as
+// ErrInvalid so broken templates are rejected when they are saved.
+func parseTemplateNodes(content string) ([]templateNode, error) {
+	type frame struct {
+		node   templateNode
+		inElse bool
+	}
+	root := []templateNode{}
+	stack := []*frame{}
+	appendNode := func(node templateNode) {
+		if len(stack) == 0 {
+			root = append(root, node)
+			return
+		}
+		top := stack[len(stack)-1]
+		if top.inElse {
+			top.node.orElse = append(top.node.orElse, node)
+			return
+		}
+		top.node.children = append(top.node.children, node)
+	}
+	last := 0
+	for _, loc := range templateTagRegex.FindAllStringSubmatchIndex(content, -1) {
+		match := make([]string, 5)
+		for i := range match {
+			if loc[2*i] >= 0 {
+				match[i] = content[loc[2*i]:loc[2*i+1]]
+			}
+		}
+		tag := parseTemplateTag(match)
+		if loc[0] > last {
+			appendNode(templateNode{kind: templateNodeText, text: content[last:loc[0]]})
+		}
+		last = loc[1]
+		switch {
+		case tag.prefix == "#" && tag.block() != "":
+			if tag.arg == "" || tag.hasFmt || len(stack) >= maxTemplateBlockDepth {
+				return nil, appErr.ErrInvalid
+			}
+			kind := templateNodeIf
+			if tag.block() == "each" {
+				kind = templateNodeEach
+			}
+			stack = append(stack, &frame{node: templateNode{kind: kind, key: strings.ToUpper(tag.arg)}})
+		case tag.prefix == "/" && tag.block() != "":
+			if len(stack) == 0 || tag.arg != "" {
+				return nil, appErr.ErrInvalid
+			}
+			top := stack[len(stack)-1]
+			if (tag.block() == "if") != (top.node.kind == templateNodeIf) {
+				return nil, appErr.ErrInvalid
+			}
+			stack = stack[:len(stack)-1]
+			appendNode(top.node)
+		case tag.isElse():
+			if len(stack) == 0 {
+				return nil, appErr.ErrInvalid
+			}
+			top := stack[len(stack)-1]
+			if top.node.kind != templateNodeIf || top.inElse {
+				return nil, appErr.ErrInvalid
+			}
+			top.inElse = true
+		case tag.prefix == "" && tag.arg == "":
+			appendNode(templateNode{
+				kind:   templateNodeVariable,
+				key:    strings.ToUpper(tag.name),
+				format: tag.format,
+			})
+		default:
+			appendNode(templateNode{kind: templateNodeText, text: match[0]})
+		}
+	}
+	if len(stack) > 0 {
+		return nil, appErr.ErrInvalid
+	}
+	if last < len(content) {
+		appendNode(templateNode{kind: templateNodeText, text: content[last:]})
+	}
+	return root, nil
+}
+
+// templateRenderContext carries the resolved variable values keyed by the
+// upper-case variable key. Values that are YYYY-MM-DD dates may be
+// reformatted with `{{KEY|format}}`.
+type templateRenderContext struct {
+	values map[string]string
+	now    time.Time
+	user   string
+	loop   []templateLoopItem
+}
+
+type templateLoopItem struct {
+	value string
+	index int
+}
+
+// renderTemplateContent substitutes variables and expands blocks in a single
+// pass. Substituted values are never parsed again, so a value containing
+// `{{...}}` is inserted literally.
+func renderTemplateContent(content string, rc templateRenderContext) (string, error) {
+	nodes, err := parseTemplateNodes(content)
+	if err != nil {
+		return "", err
+	}
+	var out strings.Builder
+	rc.renderNodes(&out, nodes)
+	return out.String(), nil
+}
+
+func (rc templateRenderContext) renderNodes(out *strings.Builder, nodes []templateNode) {
+	for _, node := range nodes {
+		switch node.kind {
+		case templateNodeText:
+			out.WriteString(node.text)
+		case templateNodeVariable:
+			out.WriteString(rc.resolve(node.key, node.format))
+		case templateNodeIf:
+			if strings.TrimSpace(rc.resolve(node.key, "")) != "" {
+				rc.renderNodes(out, node.children)
+			} else {
+				rc.renderNodes(out, node.orElse)
+			}
+		case templateNodeEach:
+			for index, item := range splitTemplateLoopItems(rc.resolve(node.key, "")) {
+				inner := rc
+				inner.loop = append(append([]templateLoopItem{}, rc.loop...), templateLoopItem{
+					value: item, index: index + 1,
+				})
+				inner.renderNodes(out, node.children)
+			}
+		}
+	}
+}
+
+func (rc templateRenderContext) resolve(key, format string) string {
+	if len(rc.loop) > 0 {
+		item := rc.loop[len(rc.loop)-1]
+		switch key {
+		case "THIS":
+			return item.value
+		case "INDEX":
+			return strconv.Itoa(item.index)
+		}
+	}
+	if strings.HasPrefix(key, "SYS:") {
+		lower := strings.ToLower(key)
+		if lower == "sys:user" {
+			return rc.user
+		}
+		if format == "" {
+			return resolveSystemVariable(lower, rc.now)
+		}
+		at, _, ok := systemTimeVariable(lower, rc.now)
+		if !ok {
+			return ""
+		}
+		return formatTemplateTime(at, format)
+	}
+	value := rc.values[key]
+	if format == "" || value == "" {
+		return value
+	}
+	date, err := time.Parse(todoDateLayout, value)
+	if err != nil {
+		return value
+	}
+	return formatTemplateTime(date, format)
+}
+
+func splitTemplateLoopItems(value string) []string {
+	items := make([]string, 0)
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		items = append(items, line)
+		if len(items) >= maxTemplateLoopItems {
+			break
+		}
+	}
+	return items
+}
+
+// templateVariableKeys lists the user variable keys referenced by content in
+// order of first appearance, including keys used by block tags.
+func templateVariableKeys(content string) []string {
+	keys := make([]string, 0)
+	seen := map[string]struct{}{}
+	for _, match := range templateTagRegex.FindAllStringSubmatch(content, -1) {
+		tag := parseTemplateTag(match)
+		key := ""
+		switch {
+		case tag.prefix == "#" && tag.block() != "":
+			key = strings.ToUpper(tag.arg)
+		case tag.prefix == "" && tag.arg == "" && !tag.isElse():
+			key = strings.ToUpper(tag.name)
+		}
+		if key == "" || key == "THIS" || key == "INDEX" || strings.HasPrefix(key, "SYS:") {
+			continue
+		}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func normalizeTemplateContentPlaceholders(content string) string {
+	return templateTagRegex.ReplaceAllStringFunc(content, func(token string) string {
+		match := templateTagRegex.FindStringSubmatch(token)
+		if len(match) < 5 {
+			return token
+		}
+		tag := parseTemplateTag(match)
+		switch {
+		case tag.prefix == "#" && tag.block() != "" && tag.arg != "":
+			return "{{#" + tag.block() + " " + strings.ToUpper(tag.arg) + "}}"
+		case tag.prefix == "/" && tag.block() != "" && tag.arg == "":
+			return "{{/" + tag.block() + "}}"
+		case tag.isElse():
+			return "{{else}}"
+		case tag.prefix == "" && tag.arg == "":
+			if tag.format != "" {
+				return "{{" + strings.ToUpper(tag.name) + "|" + tag.format + "}}"
+			}
+			return "{{" + strings.ToUpper(tag.name) + "}}"
+		default:
+			return token
+		}
+	})
+}
+
+// systemTimeVariable resolves `sys:<name>[±N<unit>]` to the instant it
+// refers to and the default format of that name. Units are h, d (default),
+// w, m and y.
+func systemTimeVariable(key string, now time.Time) (time.Time, string, bool) {
+	match := systemTimeVariableRegex.FindStringSubmatch(strings.ToLower(strings.TrimSpace(key)))
+	if match == nil {
+		return time.Time{}, "", false
+	}
+	at := now
+	switch match[1] {
+	case "yesterday":
+		at = at.AddDate(0, 0, -1)
+	case "tomorrow":
+		at = at.AddDate(0, 0, 1)
+	}
+	format, ok := systemTimeFormats[match[1]]
+	if !ok {
+		return time.Time{}, "", false
+	}
+	if match[2] != "" {
+		amount, err := strconv.Atoi(match[3])
+		if err != nil {
+			return time.Time{}, "", false
+		}
+		if match[2] == "-" {
+			amount = -amount
+		}
+		switch match[4] {
+		case "h":
+			at = at.Add(time.Duration(amount) * time.Hour)
+		case "", "d":
+			at = at.AddDate(0, 0, amount)
+		case "w":
+			at = at.AddDate(0, 0, 7*amount)
+		case "m":
+			at = at.AddDate(0, amount, 0)
+		case "y":
+			at = at.AddDate(amount, 0, 0)
+		}
+	}
+	return at, format, true
+}
+
+var systemTimeFormats = map[string]string{
+	"today":     "YYYY-MM-DD",
+	"date":      "YYYY-MM-DD",
+	"yesterday": "YYYY-MM-DD",
+	"tomorrow":  "YYYY-MM-DD",
+	"time":      "HH:mm",
+	"datetime":  "YYYY-MM-DD HH:mm",
+	"now":       "YYYY-MM-DD HH:mm",
+	"week":      "GGGG-Www",
+	"weekday":   "dddd",
+	"month":     "YYYY-MM",
+	"year":      "YYYY",
+}
+
+func resolveSystemVariable(key string, now time.Time) string {
+	at, format, ok := systemTimeVariable(key, now)
+	if !ok {
+		return ""
+	}
+	return formatTemplateTime(at, format)
+}
+
+// formatTemplateTime renders a format made of YYYY, YY, MM, DD, HH, mm, ss,
+// dddd (weekday), ddd (short weekday), GGGG (ISO week year) and ww (ISO
+// week); everything else is copied verbatim.
+func formatTemplateTime(at time.Time, format string) string {
+	isoYear, isoWeek := at.ISOWeek()
+	return strings.NewReplacer(
+		"YYYY", at.Format("2006"),
+		"GGGG", strconv.Itoa(isoYear),
+		"dddd", at.Format("Monday"),
+		"ddd", at.Format("Mon"),
+		"YY", at.Format("06"),
+		"MM", at.Format("01"),
+		"DD", at.Format("02"),
+		"HH", at.Format("15"),
+		"mm", at.Format("04"),
+		"ss", at.Format("05"),
+		"ww", fmt.Sprintf("%02d", isoWeek),
+	).Replace(format)
+}
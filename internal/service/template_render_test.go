@@ -0,0 +1,97 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+func TestRenderTemplateContent_Blocks(t *testing.T) {
+	content := "{{#if NAME}}Hi {{NAME}}{{else}}Hi there{{/if}}\n" +
+		"{{#each ITEMS}}- [ ] {{INDEX}}. {{THIS}}\n{{/each}}"
+	rc := templateRenderContext{values: map[string]string{"NAME": "Ann", "ITEMS": "milk\n\n eggs "}}
+	out, err := renderTemplateContent(content, rc)
+	require.NoError(t, err)
+	assert.Equal(t, "Hi Ann\n- [ ] 1. milk\n- [ ] 2. eggs\n", out)
+
+	out, err = renderTemplateContent(content, templateRenderContext{values: map[string]string{}})
+	require.NoError(t, err)
+	assert.Equal(t, "Hi there\n", out)
+}
+
+func TestRenderTemplateContent_NestedLoopShadowsItem(t *testing.T) {
+	content := "{{#each OUTER}}{{THIS}}:{{#each INNER}}{{THIS}}{{/each}};{{/each}}"
+	out, err := renderTemplateContent(content, templateRenderContext{values: map[string]string{
+		"OUTER": "a\nb", "INNER": "1\n2",
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "a:12;b:12;", out)
+}
+
+func TestRenderTemplateContent_ValuesAreLiteral(t *testing.T) {
+	out, err := renderTemplateContent("{{A}}", templateRenderContext{values: map[string]string{
+		"A": "{{SYS:DATE}} {{#if A}}",
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, "{{SYS:DATE}} {{#if A}}", out)
+}
+
+func TestRenderTemplateContent_SystemVariables(t *testing.T) {
+	now := time.Date(2026, 4, 28, 10, 30, 0, 0, time.UTC)
+	content := "{{SYS:YESTERDAY}} {{SYS:TOMORROW}} {{SYS:WEEK}} {{SYS:WEEKDAY}} " +
+		"{{SYS:TODAY+7D}} {{SYS:TODAY-1M|YYYY/MM/DD}} {{SYS:NOW+2H|HH:mm}} {{SYS:USER}} {{DUE|DD.MM.YY}}"
+	out, err := renderTemplateContent(content, templateRenderContext{
+		values: map[string]string{"DUE": "2026-05-01"}, now: now, user: "ann@example.com",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "2026-04-27 2026-04-29 2026-W18 Tuesday 2026-05-05 2026/03/28 12:30 ann@example.com 01.05.26", out)
+}
+
+func TestRenderTemplateContent_Invalid(t *testing.T) {
+	for _, content := range []string{
+		"{{#if A}}open",
+		"{{/if}}",
+		"{{#if A}}{{/each}}",
+		"{{#each A}}{{else}}{{/each}}",
+		"{{#if A}}{{else}}{{else}}{{/if}}",
+		"{{#if}}{{/if}}",
+	} {
+		_, err := renderTemplateContent(content, templateRenderContext{})
+		assert.ErrorIs(t, err, appErr.ErrInvalid, content)
+	}
+}
+
+func TestRenderTemplateContent_UnknownTagsStayLiteral(t *testing.T) {
+	out, err := renderTemplateContent("{{#note x}} {{a b}} {{ }}", templateRenderContext{})
+	require.NoError(t, err)
+	assert.Equal(t, "{{#note x}} {{a b}} {{ }}", out)
+}
+
+func TestNormalizeTemplateContentPlaceholders_Blocks(t *testing.T) {
+	out := normalizeTemplateContentPlaceholders("{{ #IF name }}{{ Else }}{{/If}}{{ due | DD/MM }}")
+	assert.Equal(t, "{{#if NAME}}{{else}}{{/if}}{{DUE|DD/MM}}", out)
+}
+
+func TestTemplateVariableKeys(t *testing.T) {
+	keys := templateVariableKeys("{{#each ITEMS}}{{THIS}}{{INDEX}}{{/each}}{{NAME}}{{SYS:DATE}}{{#if NAME}}{{/if}}")
+	assert.Equal(t, []string{"ITEMS", "NAME"}, keys)
+}
+
+func TestSystemTimeVariable(t *testing.T) {
+	now := time.Date(2026, 1, 31, 8, 0, 0, 0, time.UTC)
+	at, format, ok := systemTimeVariable("sys:date+1w", now)
+	require.True(t, ok)
+	assert.Equal(t, "YYYY-MM-DD", format)
+	assert.Equal(t, time.Date(2026, 2, 7, 8, 0, 0, 0, time.UTC), at)
+
+	assert.Equal(t, "2025", resolveSystemVariable("sys:year-1y", now))
+	assert.Equal(t, "2026-01", resolveSystemVariable("sys:month", now))
+	_, _, ok = systemTimeVariable("sys:today+1q", now)
+	assert.False(t, ok)
+	_, _, ok = systemTimeVariable("sys:user", now)
+	assert.False(t, ok)
+}
@@ -0,0 +1,326 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/robfig/cron/v3"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+const (
+	maxTemplateSchedulesPerUser = 50
+	maxTemplateScheduleCatchUp  = 100000
+	templateScheduleDueBatch    = 100
+)
+
+// errTemplateScheduleTaken rolls back a run whose slot was claimed by a
+// concurrent run in the meantime.
+var errTemplateScheduleTaken = errors.New("template schedule slot already taken")
+
+type scheduledTemplateService interface {
+	Get(ctx context.Context, userID, templateID string) (*model.Template, error)
+	CreateDocumentFromTemplate(
+		ctx context.Context, userID string, input CreateDocumentFromTemplateInput,
+	) (*model.Document, error)
+}
+
+// TemplateScheduleService manages per-user schedules that create documents
+// from templates, such as daily notes or weekly reviews, and runs the due
+// ones.
+type TemplateScheduleService struct {
+	schedules templateScheduleRepo
+	templates scheduledTemplateService
+	tags      tagListRepo
+	runtime   Runtime
+}
+
+type TemplateScheduleInput struct {
+	TemplateID   string
+	CronSpec     string
+	TitlePattern string
+	TagIDs       []string
+	Enabled      bool
+}
+
+func NewTemplateScheduleService(
+	schedules templateScheduleRepo, templates scheduledTemplateService, tags tagListRepo, runtime Runtime,
+) *TemplateScheduleService {
+	return &TemplateScheduleService{
+		schedules: schedules, templates: templates, tags: tags,
+		runtime: prepareRuntime(runtime),
+	}
+}
+
+func (s *TemplateScheduleService) List(ctx context.Context, userID string) ([]model.TemplateSchedule, error) {
+	items, err := s.schedules.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list template schedules: %w", err)
+	}
+	return items, nil
+}
+
+func (s *TemplateScheduleService) Create(
+	ctx context.Context, userID string, input TemplateScheduleInput,
+) (*model.TemplateSchedule, error) {
+	existing, err := s.schedules.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list template schedules: %w", err)
+	}
+	if len(existing) >= maxTemplateSchedulesPerUser {
+		return nil, appErr.ErrInvalid
+	}
+	id, err := s.runtime.IDs.ID()
+	if err != nil {
+		return nil, fmt.Errorf("generate template schedule id: %w", err)
+	}
+	now := s.runtime.Clock.Now()
+	item := &model.TemplateSchedule{ID: id, UserID: userID, Ctime: now.Unix()}
+	if err := s.apply(ctx, item, input, now); err != nil {
+		return nil, err
+	}
+	if err := s.schedules.Create(ctx, item); err != nil {
+		return nil, fmt.Errorf("create template schedule: %w", err)
+	}
+	return item, nil
+}
+
+// Update replaces the schedule settings. The next slot is computed again
+// from now, so slots that already passed are never produced by an edit.
+func (s *TemplateScheduleService) Update(
+	ctx context.Context, userID, scheduleID string, input TemplateScheduleInput,
+) (*model.TemplateSchedule, error) {
+	item, err := s.schedules.GetByID(ctx, userID, scheduleID)
+	if err != nil {
+		return nil, fmt.Errorf("get template schedule: %w", err)
+	}
+	if err := s.apply(ctx, item, input, s.runtime.Clock.Now()); err != nil {
+		return nil, err
+	}
+	if err := s.schedules.Update(ctx, item); err != nil {
+		return nil, fmt.Errorf("update template schedule: %w", err)
+	}
+	return item, nil
+}
+
+func (s *TemplateScheduleService) Delete(ctx context.Context, userID, scheduleID string) error {
+	if err := s.schedules.Delete(ctx, userID, scheduleID); err != nil {
+		return fmt.Errorf("delete template schedule: %w", err)
+	}
+	return nil
+}
+
+func (s *TemplateScheduleService) apply(
+	ctx context.Context, item *model.TemplateSchedule, input TemplateScheduleInput, now time.Time,
+) error {
+	spec := strings.TrimSpace(input.CronSpec)
+	pattern := strings.TrimSpace(input.TitlePattern)
+	if spec == "" || len(spec) > 200 || utf8.RuneCountInString(pattern) > 200 {
+		return appErr.ErrInvalid
+	}
+	schedule, err := parseTemplateScheduleSpec(spec)
+	if err != nil {
+		return err
+	}
+	if _, err := parseTemplateNodes(pattern); err != nil {
+		return err
+	}
+	tagIDs := uniqueStringSlice(input.TagIDs)
+	if len(tagIDs) > 100 {
+		return appErr.ErrInvalid
+	}
+	owned, err := s.ownedTagIDs(ctx, item.UserID, tagIDs)
+	if err != nil {
+		return err
+	}
+	if len(owned) != len(tagIDs) {
+		return appErr.ErrInvalid
+	}
+	if _, err := s.templates.Get(ctx, item.UserID, strings.TrimSpace(input.TemplateID)); err != nil {
+		return fmt.Errorf("get template: %w", err)
+	}
+	item.TemplateID = strings.TrimSpace(input.TemplateID)
+	item.CronSpec = spec
+	item.TitlePattern = pattern
+	item.TagIDs = tagIDs
+	item.Enabled = 0
+	item.NextRunAt = 0
+	if input.Enabled {
+		item.Enabled = 1
+		item.NextRunAt = nextTemplateScheduleSlot(schedule, now)
+	}
+	item.Mtime = now.Unix()
+	return nil
+}
+
+// RunDue creates the documents of every schedule whose next slot has
+// passed. Only the latest passed slot of a schedule is produced, so a server
+// that was down for a week creates one daily note, not seven. Each slot is
+// claimed in the transaction that creates its document, so a retry, a
+// restart or a second instance never creates it twice.
+func (s *TemplateScheduleService) RunDue(ctx context.Context) error {
+	now := s.runtime.Clock.Now()
+	items, err := s.schedules.ListDue(ctx, now.Unix(), templateScheduleDueBatch)
+	if err != nil {
+		return fmt.Errorf("list due template schedules: %w", err)
+	}
+	var errs []error
+	for i := range items {
+		if err := ctx.Err(); err != nil {
+			return fmt.Errorf("run template schedules canceled: %w", err)
+		}
+		if err := s.run(ctx, &items[i], now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *TemplateScheduleService) run(ctx context.Context, item *model.TemplateSchedule, now time.Time) error {
+	expected := item.NextRunAt
+	next := *item
+	next.Mtime = now.Unix()
+	schedule, err := parseTemplateScheduleSpec(item.CronSpec)
+	if err != nil {
+		next.Enabled = 0
+		next.LastError = "invalid cron spec"
+		return s.recordFailure(ctx, &next, expected)
+	}
+	slot := latestTemplateScheduleSlot(schedule, time.Unix(expected, 0), now)
+	next.LastRunAt = slot.Unix()
+	next.NextRunAt = nextTemplateScheduleSlot(schedule, now)
+	err = s.runtime.Transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+		doc, err := s.createScheduledDocument(txCtx, item, slot)
+		if err != nil {
+			return err
+		}
+		next.LastDocumentID = doc.ID
+		next.LastError = ""
+		claimed, err := s.schedules.AdvanceRun(txCtx, &next, expected)
+		if err != nil {
+			return fmt.Errorf("advance template schedule: %w", err)
+		}
+		if !claimed {
+			return errTemplateScheduleTaken
+		}
+		return nil
+	})
+	switch {
+	case err == nil, errors.Is(err, errTemplateScheduleTaken):
+		return nil
+	case errors.Is(err, appErr.ErrNotFound):
+		// The template is gone; stop the schedule instead of failing on
+		// every slot.
+		next.Enabled = 0
+		next.LastDocumentID = item.LastDocumentID
+		next.LastError = "template not found"
+		return s.recordFailure(ctx, &next, expected)
+	case errors.Is(err, appErr.ErrInvalid):
+		// The template no longer renders; skip this slot and keep the reason
+		// so the user can fix it.
+		next.LastDocumentID = item.LastDocumentID
+		next.LastError = "template could not be rendered"
+		return s.recordFailure(ctx, &next, expected)
+	default:
+		return fmt.Errorf("run template schedule %s: %w", item.ID, err)
+	}
+}
+
+func (s *TemplateScheduleService) createScheduledDocument(
+	ctx context.Context, item *model.TemplateSchedule, slot time.Time,
+) (*model.Document, error) {
+	title, err := renderTemplateContent(item.TitlePattern, templateRenderContext{
+		values: map[string]string{}, now: slot,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Tags deleted since the schedule was saved are dropped rather than
+	// failing every run.
+	tagIDs, err := s.ownedTagIDs(ctx, item.UserID, item.TagIDs)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := s.templates.CreateDocumentFromTemplate(ctx, item.UserID, CreateDocumentFromTemplateInput{
+		TemplateID: item.TemplateID,
+		Title:      strings.TrimSpace(title),
+		TagIDs:     tagIDs,
+		At:         slot,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create document from template: %w", err)
+	}
+	return doc, nil
+}
+
+func (s *TemplateScheduleService) recordFailure(
+	ctx context.Context, item *model.TemplateSchedule, expected int64,
+) error {
+	if _, err := s.schedules.AdvanceRun(ctx, item, expected); err != nil {
+		return fmt.Errorf("record template schedule %s failure: %w", item.ID, err)
+	}
+	return nil
+}
+
+func (s *TemplateScheduleService) ownedTagIDs(ctx context.Context, userID string, ids []string) ([]string, error) {
+	if len(ids) == 0 {
+		return []string{}, nil
+	}
+	tags, err := s.tags.ListByIDs(ctx, userID, ids)
+	if err != nil {
+		return nil, fmt.Errorf("list schedule tags: %w", err)
+	}
+	owned := make(map[string]struct{}, len(tags))
+	for _, tag := range tags {
+		owned[tag.ID] = struct{}{}
+	}
+	out := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if _, ok := owned[id]; ok {
+			out = append(out, id)
+		}
+	}
+	return out, nil
+}
+
+// parseTemplateScheduleSpec accepts five field cron specs, descriptors such
+// as `@daily` and an optional `CRON_TZ=<zone>` prefix; without a zone the
+// server's local time is used.
+func parseTemplateScheduleSpec(spec string) (cron.Schedule, error) {
+	schedule, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, appErr.ErrInvalid
+	}
+	return schedule, nil
+}
+
+func nextTemplateScheduleSlot(schedule cron.Schedule, now time.Time) int64 {
+	next := schedule.Next(now.In(time.Local))
+	if next.IsZero() {
+		return 0
+	}
+	return next.Unix()
+}
+
+// latestTemplateScheduleSlot walks from the first pending slot to the last
+// one at or before now.
+func latestTemplateScheduleSlot(schedule cron.Schedule, first, now time.Time) time.Time {
+	slot := schedule.Next(first.In(time.Local).Add(-time.Second))
+	if slot.IsZero() || slot.After(now) {
+		slot = first.In(time.Local)
+	}
+	for range maxTemplateScheduleCatchUp {
+		next := schedule.Next(slot)
+		if next.IsZero() || next.After(now) {
+			break
+		}
+		slot = next
+	}
+	return slot
+}
@@ -0,0 +1,266 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+type mockTemplateScheduleRepo struct {
+	createFn     func(ctx context.Context, item *model.TemplateSchedule) error
+	updateFn     func(ctx context.Context, item *model.TemplateSchedule) error
+	advanceRunFn func(ctx context.Context, item *model.TemplateSchedule, expected int64) (bool, error)
+	deleteFn     func(ctx context.Context, userID, id string) error
+	getByIDFn    func(ctx context.Context, userID, id string) (*model.TemplateSchedule, error)
+	listByUserFn func(ctx context.Context, userID string) ([]model.TemplateSchedule, error)
+	listDueFn    func(ctx context.Context, now int64, limit int) ([]model.TemplateSchedule, error)
+}
+
+func (m *mockTemplateScheduleRepo) Create(ctx context.Context, item *model.TemplateSchedule) error {
+	return m.createFn(ctx, item)
+}
+
+func (m *mockTemplateScheduleRepo) Update(ctx context.Context, item *model.TemplateSchedule) error {
+	return m.updateFn(ctx, item)
+}
+
+func (m *mockTemplateScheduleRepo) AdvanceRun(
+	ctx context.Context, item *model.TemplateSchedule, expected int64,
+) (bool, error) {
+	return m.advanceRunFn(ctx, item, expected)
+}
+
+func (m *mockTemplateScheduleRepo) Delete(ctx context.Context, userID, id string) error {
+	return m.deleteFn(ctx, userID, id)
+}
+
+func (m *mockTemplateScheduleRepo) GetByID(
+	ctx context.Context, userID, id string,
+) (*model.TemplateSchedule, error) {
+	return m.getByIDFn(ctx, userID, id)
+}
+
+func (m *mockTemplateScheduleRepo) ListByUser(ctx context.Context, userID string) ([]model.TemplateSchedule, error) {
+	if m.listByUserFn == nil {
+		return []model.TemplateSchedule{}, nil
+	}
+	return m.listByUserFn(ctx, userID)
+}
+
+func (m *mockTemplateScheduleRepo) ListDue(
+	ctx context.Context, now int64, limit int,
+) ([]model.TemplateSchedule, error) {
+	return m.listDueFn(ctx, now, limit)
+}
+
+type stubScheduledTemplates struct {
+	getErr    error
+	createErr error
+	inputs    []CreateDocumentFromTemplateInput
+}
+
+func (s *stubScheduledTemplates) Get(_ context.Context, _, templateID string) (*model.Template, error) {
+	if s.getErr != nil {
+		return nil, s.getErr
+	}
+	return &model.Template{ID: templateID}, nil
+}
+
+func (s *stubScheduledTemplates) CreateDocumentFromTemplate(
+	_ context.Context, _ string, input CreateDocumentFromTemplateInput,
+) (*model.Document, error) {
+	if s.createErr != nil {
+		return nil, s.createErr
+	}
+	s.inputs = append(s.inputs, input)
+	return &model.Document{ID: "doc-" + input.Title}, nil
+}
+
+func ownedTagsRepo(ids ...string) *mockTagRepo {
+	return &mockTagRepo{
+		listByIDsFn: func(_ context.Context, _ string, requested []string) ([]model.Tag, error) {
+			out := []model.Tag{}
+			for _, id := range requested {
+				for _, owned := range ids {
+					if id == owned {
+						out = append(out, model.Tag{ID: id})
+					}
+				}
+			}
+			return out, nil
+		},
+	}
+}
+
+func localUnix(year int, month time.Month, day, hour int) int64 {
+	return time.Date(year, month, day, hour, 0, 0, 0, time.Local).Unix()
+}
+
+func TestTemplateScheduleService_Create(t *testing.T) {
+	var created *model.TemplateSchedule
+	repo := &mockTemplateScheduleRepo{createFn: func(_ context.Context, item *model.TemplateSchedule) error {
+		created = item
+		return nil
+	}}
+	svc := NewTemplateScheduleService(
+		repo, &stubScheduledTemplates{}, ownedTagsRepo("t1"), testRuntimeAt(localUnix(2026, 3, 2, 10)),
+	)
+
+	item, err := svc.Create(context.Background(), "u1", TemplateScheduleInput{
+		TemplateID: "tpl1", CronSpec: " 0 9 * * * ", TitlePattern: "Daily {{sys:today}}",
+		TagIDs: []string{"t1", "t1"}, Enabled: true,
+	})
+	require.NoError(t, err)
+	assert.Same(t, created, item)
+	assert.Equal(t, "0 9 * * *", item.CronSpec)
+	assert.Equal(t, []string{"t1"}, item.TagIDs)
+	assert.Equal(t, 1, item.Enabled)
+	assert.Equal(t, localUnix(2026, 3, 3, 9), item.NextRunAt)
+}
+
+func TestTemplateScheduleService_Create_Invalid(t *testing.T) {
+	repo := &mockTemplateScheduleRepo{}
+	cases := []struct {
+		name      string
+		input     TemplateScheduleInput
+		templates *stubScheduledTemplates
+		want      error
+	}{
+		{"bad spec", TemplateScheduleInput{TemplateID: "tpl1", CronSpec: "every day"},
+			&stubScheduledTemplates{}, appErr.ErrInvalid},
+		{"bad pattern", TemplateScheduleInput{TemplateID: "tpl1", CronSpec: "@daily", TitlePattern: "{{#if A}}"},
+			&stubScheduledTemplates{}, appErr.ErrInvalid},
+		{"foreign tag", TemplateScheduleInput{TemplateID: "tpl1", CronSpec: "@daily", TagIDs: []string{"t2"}},
+			&stubScheduledTemplates{}, appErr.ErrInvalid},
+		{"missing template", TemplateScheduleInput{TemplateID: "tpl1", CronSpec: "@daily"},
+			&stubScheduledTemplates{getErr: appErr.ErrNotFound}, appErr.ErrNotFound},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			svc := NewTemplateScheduleService(repo, tc.templates, ownedTagsRepo("t1"), testRuntime())
+			_, err := svc.Create(context.Background(), "u1", tc.input)
+			assert.ErrorIs(t, err, tc.want)
+		})
+	}
+}
+
+func TestTemplateScheduleService_Update_Disable(t *testing.T) {
+	repo := &mockTemplateScheduleRepo{
+		getByIDFn: func(_ context.Context, _, id string) (*model.TemplateSchedule, error) {
+			return &model.TemplateSchedule{ID: id, UserID: "u1", Enabled: 1, NextRunAt: 100, LastDocumentID: "d1"}, nil
+		},
+		updateFn: func(context.Context, *model.TemplateSchedule) error { return nil },
+	}
+	svc := NewTemplateScheduleService(repo, &stubScheduledTemplates{}, ownedTagsRepo(), testRuntime())
+
+	item, err := svc.Update(context.Background(), "u1", "s1", TemplateScheduleInput{
+		TemplateID: "tpl1", CronSpec: "@weekly",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 0, item.Enabled)
+	assert.Equal(t, int64(0), item.NextRunAt)
+	assert.Equal(t, "d1", item.LastDocumentID)
+}
+
+func TestTemplateScheduleService_RunDue_CreatesLatestSlotOnce(t *testing.T) {
+	first := localUnix(2026, 3, 1, 9)
+	var advanced []model.TemplateSchedule
+	var expectations []int64
+	claim := true
+	repo := &mockTemplateScheduleRepo{
+		listDueFn: func(_ context.Context, now int64, limit int) ([]model.TemplateSchedule, error) {
+			assert.Equal(t, localUnix(2026, 3, 4, 10), now)
+			assert.Equal(t, templateScheduleDueBatch, limit)
+			return []model.TemplateSchedule{{
+				ID: "s1", UserID: "u1", TemplateID: "tpl1", CronSpec: "0 9 * * *",
+				TitlePattern: "Daily {{sys:today}}", TagIDs: []string{"t1", "gone"},
+				Enabled: 1, NextRunAt: first,
+			}}, nil
+		},
+		advanceRunFn: func(_ context.Context, item *model.TemplateSchedule, expected int64) (bool, error) {
+			advanced = append(advanced, *item)
+			expectations = append(expectations, expected)
+			return claim, nil
+		},
+	}
+	templates := &stubScheduledTemplates{}
+	svc := NewTemplateScheduleService(repo, templates, ownedTagsRepo("t1"), testRuntimeAt(localUnix(2026, 3, 4, 10)))
+
+	require.NoError(t, svc.RunDue(context.Background()))
+	require.Len(t, templates.inputs, 1)
+	input := templates.inputs[0]
+	assert.Equal(t, "tpl1", input.TemplateID)
+	assert.Equal(t, "Daily 2026-03-04", input.Title)
+	assert.Equal(t, []string{"t1"}, input.TagIDs)
+	assert.Equal(t, localUnix(2026, 3, 4, 9), input.At.Unix())
+	require.Len(t, advanced, 1)
+	assert.Equal(t, first, expectations[0])
+	assert.Equal(t, localUnix(2026, 3, 4, 9), advanced[0].LastRunAt)
+	assert.Equal(t, localUnix(2026, 3, 5, 9), advanced[0].NextRunAt)
+	assert.Equal(t, "doc-Daily 2026-03-04", advanced[0].LastDocumentID)
+
+	// A run that loses the slot to another run succeeds without recording.
+	claim = false
+	require.NoError(t, svc.RunDue(context.Background()))
+	assert.Len(t, advanced, 2)
+}
+
+func TestTemplateScheduleService_RunDue_TemplateMissing(t *testing.T) {
+	var advanced *model.TemplateSchedule
+	repo := &mockTemplateScheduleRepo{
+		listDueFn: func(context.Context, int64, int) ([]model.TemplateSchedule, error) {
+			return []model.TemplateSchedule{{
+				ID: "s1", UserID: "u1", TemplateID: "tpl1", CronSpec: "@daily", Enabled: 1,
+				NextRunAt: localUnix(2026, 3, 4, 0), LastDocumentID: "d0",
+			}}, nil
+		},
+		advanceRunFn: func(_ context.Context, item *model.TemplateSchedule, _ int64) (bool, error) {
+			advanced = item
+			return true, nil
+		},
+	}
+	templates := &stubScheduledTemplates{createErr: appErr.ErrNotFound}
+	svc := NewTemplateScheduleService(repo, templates, ownedTagsRepo(), testRuntimeAt(localUnix(2026, 3, 4, 10)))
+
+	require.NoError(t, svc.RunDue(context.Background()))
+	require.NotNil(t, advanced)
+	assert.Equal(t, 0, advanced.Enabled)
+	assert.Equal(t, "template not found", advanced.LastError)
+	assert.Equal(t, "d0", advanced.LastDocumentID)
+}
+
+func TestTemplateScheduleService_RunDue_TransientErrorKeepsSlot(t *testing.T) {
+	repo := &mockTemplateScheduleRepo{
+		listDueFn: func(context.Context, int64, int) ([]model.TemplateSchedule, error) {
+			return []model.TemplateSchedule{{
+				ID: "s1", UserID: "u1", TemplateID: "tpl1", CronSpec: "@daily", Enabled: 1,
+				NextRunAt: localUnix(2026, 3, 4, 0),
+			}}, nil
+		},
+		advanceRunFn: func(context.Context, *model.TemplateSchedule, int64) (bool, error) {
+			t.Fatal("slot must stay pending after a transient error")
+			return false, nil
+		},
+	}
+	templates := &stubScheduledTemplates{createErr: errors.New("db down")}
+	svc := NewTemplateScheduleService(repo, templates, ownedTagsRepo(), testRuntimeAt(localUnix(2026, 3, 4, 10)))
+
+	err := svc.RunDue(context.Background())
+	assert.ErrorContains(t, err, "run template schedule s1")
+}
+
+func TestLatestTemplateScheduleSlot(t *testing.T) {
+	schedule, err := parseTemplateScheduleSpec("0 9 * * 1")
+	require.NoError(t, err)
+	first := time.Unix(localUnix(2026, 3, 2, 9), 0)
+	now := time.Unix(localUnix(2026, 3, 20, 8), 0)
+	assert.Equal(t, localUnix(2026, 3, 16, 9), latestTemplateScheduleSlot(schedule, first, now).Unix())
+	assert.Equal(t, first.Unix(), latestTemplateScheduleSlot(schedule, first, first).Unix())
+}
@@ -3,7 +3,6 @@ package service
 import (
 	"context"
 	"fmt"
-	"regexp"
 	"strings"
 	"time"
 	"unicode/utf8"
@@ -17,9 +16,15 @@ type TemplateService struct {
 	templates templateRepo
 	documents templateDocumentService
 	tags      tagRepo
+	users     templateUserRepo
+	gallery   templateGalleryRepo
 	runtime   Runtime
 }
 
+type templateUserRepo interface {
+	GetByID(ctx context.Context, id string) (*model.User, error)
+}
+
 type templateDocumentService interface {
 	Create(ctx context.Context, userID string, input DocumentCreateInput) (*model.Document, error)
 	ValidateOwnedTagIDs(ctx context.Context, userID string, tagIDs []string) ([]string, error)
@@ -29,6 +34,7 @@ type CreateTemplateInput struct {
 	Name          string
 	Description   string
 	Content       string
+	Variables     []model.TemplateVariable
 	DefaultTagIDs []string
 }
 
@@ -36,13 +42,19 @@ type UpdateTemplateInput struct {
 	Name          string
 	Description   string
 	Content       string
+	Variables     []model.TemplateVariable
 	DefaultTagIDs []string
 }
 
+// CreateDocumentFromTemplateInput describes a document to create. TagIDs
+// are added to the template's default tags and At, when set, replaces the
+// current time for `sys:` variables.
 type CreateDocumentFromTemplateInput struct {
 	TemplateID string
 	Title      string
 	Variables  map[string]string
+	TagIDs     []string
+	At         time.Time
 }
 
 type TemplateMetaListResult struct {
@@ -51,10 +63,15 @@ type TemplateMetaListResult struct {
 }
 
 func NewTemplateService(
-	templates templateRepo, documents templateDocumentService, tags tagRepo, runtime Runtime,
+	templates templateRepo,
+	documents templateDocumentService,
+	tags tagRepo,
+	users templateUserRepo,
+	gallery templateGalleryRepo,
+	runtime Runtime,
 ) *TemplateService {
 	return &TemplateService{
-		templates: templates, documents: documents, tags: tags,
+		templates: templates, documents: documents, tags: tags, users: users, gallery: gallery,
 		runtime: prepareRuntime(runtime),
 	}
 }
@@ -102,11 +119,14 @@ func (
 	}, nil
 }
 
+// Get returns the template with its full variable list: declared variables
+// first, then variables only referenced in the content as plain text ones.
 func (s *TemplateService) Get(ctx context.Context, userID, templateID string) (*model.Template, error) {
 	v0, err := s.templates.GetByID(ctx, userID, templateID)
 	if err != nil {
 		return nil, fmt.Errorf("get by id: %w", err)
 	}
+	v0.Variables = describeTemplateVariables(v0.Content, v0.Variables)
 	return v0, nil
 }
 
@@ -116,6 +136,17 @@ func (
 	input CreateTemplateInput) (*model.Template,
 	error,
 ) {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return nil, err
+	}
+	return s.create(ctx, userID, input, nil)
+}
+
+// create stores a new template; source is the gallery template it was
+// copied from, if any.
+func (s *TemplateService) create(
+	ctx context.Context, userID string, input CreateTemplateInput, source *model.GalleryTemplate,
+) (*model.Template, error) {
 	if err := s.validateTemplate(
 		input.Name, input.Description, input.Content, input.DefaultTagIDs,
 	); err != nil {
@@ -126,6 +157,10 @@ func (
 	if err != nil {
 		return nil, err
 	}
+	variables, err := s.normalizeVariables(ctx, userID, input.Variables)
+	if err != nil {
+		return nil, err
+	}
 	id, err := s.runtime.IDs.ID()
 	if err != nil {
 		return nil, fmt.Errorf("generate template id: %w", err)
@@ -137,11 +172,17 @@ func (
 		Name:          strings.TrimSpace(input.Name),
 		Description:   strings.TrimSpace(input.Description),
 		Content:       normalizedContent,
+		Variables:     variables,
 		DefaultTagIDs: tagIDs,
 		BuiltIn:       0,
 		Ctime:         now,
 		Mtime:         now,
 	}
+	if source != nil {
+		tpl.BuiltIn = 1
+		tpl.SourceSlug = source.Slug
+		tpl.SourceVersion = source.Version
+	}
 	if err := s.templates.Create(ctx, tpl); err != nil {
 		return nil, fmt.Errorf("create: %w", err)
 	}
@@ -149,6 +190,9 @@ func (
 }
 
 func (s *TemplateService) Update(ctx context.Context, userID, templateID string, input UpdateTemplateInput) error {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return err
+	}
 	if err := s.validateTemplate(
 		input.Name, input.Description, input.Content, input.DefaultTagIDs,
 	); err != nil {
@@ -159,12 +203,17 @@ func (s *TemplateService) Update(ctx context.Context, userID, templateID string,
 	if err != nil {
 		return err
 	}
+	variables, err := s.normalizeVariables(ctx, userID, input.Variables)
+	if err != nil {
+		return err
+	}
 	tpl := &model.Template{
 		ID:            templateID,
 		UserID:        userID,
 		Name:          strings.TrimSpace(input.Name),
 		Description:   strings.TrimSpace(input.Description),
 		Content:       normalizedContent,
+		Variables:     variables,
 		DefaultTagIDs: tagIDs,
 		Mtime:         timeutil.NowUnix(),
 	}
@@ -185,10 +234,16 @@ func (s *TemplateService) validateTemplate(
 		len(uniqueStringSlice(tagIDs)) > 100 {
 		return appErr.ErrInvalid
 	}
+	if _, err := parseTemplateNodes(content); err != nil {
+		return err
+	}
 	return nil
 }
 
 func (s *TemplateService) Delete(ctx context.Context, userID, templateID string) error {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return err
+	}
 	if err := s.templates.Delete(ctx, userID, templateID); err != nil {
 		return fmt.Errorf("delete: %w", err)
 	}
@@ -201,27 +256,52 @@ func (
 	input CreateDocumentFromTemplateInput) (*model.Document,
 	error,
 ) {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return nil, err
+	}
 	tpl, err := s.templates.GetByID(ctx, userID, input.TemplateID)
 	if err != nil {
 		return nil, fmt.Errorf("get by id: %w", err)
 	}
-	variables := map[string]string{}
+	values := map[string]string{}
 	for k, v := range input.Variables {
 		key := strings.ToUpper(strings.TrimSpace(k))
 		if key == "" {
 			continue
 		}
-		variables[key] = strings.TrimSpace(v)
+		values[key] = strings.TrimSpace(v)
+	}
+	now := s.runtime.Clock.Now().In(time.Local)
+	if !input.At.IsZero() {
+		now = input.At
+	}
+	variableTagIDs, err := s.resolveVariableValues(ctx, userID, tpl.Variables, values, now)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.templateUserLabel(ctx, userID, tpl.Content)
+	if err != nil {
+		return nil, err
+	}
+	content, err := renderTemplateContent(tpl.Content, templateRenderContext{
+		values: values, now: now, user: user,
+	})
+	if err != nil {
+		return nil, err
 	}
-	content := applyTemplateVariables(tpl.Content, variables)
 	title := strings.TrimSpace(input.Title)
 	if title == "" {
 		title = inferTemplateTitle(content, tpl.Name)
 	}
-	tagIDs, err := s.validateOwnedTagIDs(ctx, userID, tpl.DefaultTagIDs)
+	requested := append(append([]string{}, tpl.DefaultTagIDs...), input.TagIDs...)
+	tagIDs, err := s.validateOwnedTagIDs(ctx, userID, requested)
 	if err != nil {
 		return nil, err
 	}
+	tagIDs = uniqueStringSlice(append(tagIDs, variableTagIDs...))
+	if len(tagIDs) > 100 {
+		return nil, appErr.ErrInvalid
+	}
 	doc, err := s.documents.Create(ctx, userID, DocumentCreateInput{
 		Title:   title,
 		Content: content,
@@ -269,54 +349,6 @@ func (s *TemplateService) validateOwnedTagIDs(
 	return unique, nil
 }
 
-var builtInTemplateVarsRegex = regexp.MustCompile(`\{\{\s*([a-zA-Z0-9_:\-]+)\s*\}\}`)
-
-func applyTemplateVariables(content string, values map[string]string) string {
-	now := time.Unix(timeutil.NowUnix(), 0).In(time.Local)
-	return builtInTemplateVarsRegex.ReplaceAllStringFunc(content, func(token string) string {
-		match := builtInTemplateVarsRegex.FindStringSubmatch(token)
-		if len(match) < 2 {
-			return token
-		}
-		key := strings.ToUpper(strings.TrimSpace(match[1]))
-		keyLower := strings.ToLower(key)
-		if strings.HasPrefix(keyLower, "sys:") {
-			return resolveSystemVariable(keyLower, now)
-		}
-		if value, ok := values[key]; ok {
-			return value
-		}
-		return ""
-	})
-}
-
-func normalizeTemplateContentPlaceholders(content string) string {
-	return builtInTemplateVarsRegex.ReplaceAllStringFunc(content, func(token string) string {
-		match := builtInTemplateVarsRegex.FindStringSubmatch(token)
-		if len(match) < 2 {
-			return token
-		}
-		key := strings.ToUpper(strings.TrimSpace(match[1]))
-		if key == "" {
-			return token
-		}
-		return "{{" + key + "}}"
-	})
-}
-
-func resolveSystemVariable(key string, now time.Time) string {
-	switch strings.ToLower(strings.TrimSpace(key)) {
-	case "sys:today", "sys:date":
-		return now.Format("2006-01-02")
-	case "sys:time":
-		return now.Format("15:04")
-	case "sys:datetime", "sys:now":
-		return now.Format("2006-01-02 15:04")
-	default:
-		return ""
-	}
-}
-
 func inferTemplateTitle(content, fallback string) string {
 	for _, line := range strings.Split(content, "\n") {
 		trimmed := strings.TrimSpace(line)
@@ -9,6 +9,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/xxxsen/mnote/internal/gallery"
 	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
 	"github.com/xxxsen/mnote/internal/repo"
 	"github.com/xxxsen/mnote/internal/service"
@@ -32,7 +33,7 @@ func TestTemplateServiceSystemVariables(t *testing.T) {
 		runtime, docRepo, versionRepo, docTagRepo, shareRepo,
 		tagRepo, userRepo, nil, 10, nil)
 
-	templates := service.NewTemplateService(templateRepo, docs, tagRepo, runtime)
+	templates := service.NewTemplateService(templateRepo, docs, tagRepo, userRepo, nil, runtime)
 
 	tpl, err := templates.Create(context.Background(), "user-1", service.CreateTemplateInput{
 		Name:    "tpl",
@@ -69,8 +70,8 @@ func TestTemplateServiceCreateRejectsDeletedDefaultTags(t *testing.T) {
 		runtime, docRepo, versionRepo, docTagRepo, shareRepo,
 		tagRepo, userRepo, nil, 10, nil)
 
-	templates := service.NewTemplateService(templateRepo, docs, tagRepo, runtime)
-	tags := service.NewTagService(runtime, tagRepo, docTagRepo)
+	templates := service.NewTemplateService(templateRepo, docs, tagRepo, userRepo, nil, runtime)
+	tags := service.NewTagService(runtime, tagRepo, docTagRepo, templateRepo)
 
 	tag, err := tags.Create(context.Background(), "user-1", "MyTag")
 	require.NoError(t, err)
@@ -83,3 +84,39 @@ func TestTemplateServiceCreateRejectsDeletedDefaultTags(t *testing.T) {
 	})
 	require.ErrorIs(t, err, appErr.ErrInvalid)
 }
+
+func TestTemplateServiceGalleryAndBundles(t *testing.T) {
+	db, cleanup := testutil.OpenTestDB(t)
+	defer cleanup()
+
+	tagRepo := repo.NewTagRepo(db)
+	templateRepo := repo.NewTemplateRepo(db)
+	runtime := service.NewRuntime(repo.NewTransactor(db))
+	templates := service.NewTemplateService(
+		templateRepo, nil, tagRepo, nil, repo.NewTemplateGalleryRepo(db), runtime,
+	)
+	ctx := context.Background()
+
+	items, err := gallery.Load()
+	require.NoError(t, err)
+	_, err = templates.SeedGallery(ctx, items)
+	require.NoError(t, err)
+	written, err := templates.SeedGallery(ctx, items)
+	require.NoError(t, err)
+	require.Zero(t, written, "seeding the same versions again is a no-op")
+
+	copied, err := templates.CopyFromGallery(ctx, "user-1", items[0].Slug, "")
+	require.NoError(t, err)
+	require.Equal(t, items[0].Slug, copied.SourceSlug)
+	_, err = templates.CopyFromGallery(ctx, "user-1", items[0].Slug, "")
+	require.ErrorIs(t, err, appErr.ErrConflict)
+
+	bundle, err := templates.ExportBundle(ctx, "user-1", nil)
+	require.NoError(t, err)
+	result, err := templates.ImportBundle(ctx, "user-2", bundle)
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Created)
+	result, err = templates.ImportBundle(ctx, "user-2", bundle)
+	require.NoError(t, err)
+	require.Equal(t, 1, result.Skipped)
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -64,7 +65,7 @@ func TestTemplateService_List(t *testing.T) {
 				return []model.Template{{ID: "t1", Name: "Note"}}, nil
 			},
 		}
-		svc := NewTemplateService(repo, nil, nil, testRuntime())
+		svc := NewTemplateService(repo, nil, nil, nil, nil, testRuntime())
 		result, err := svc.List(context.Background(), "u1")
 		require.NoError(t, err)
 		assert.Len(t, result, 1)
@@ -76,7 +77,7 @@ func TestTemplateService_List(t *testing.T) {
 				return nil, errors.New("db error")
 			},
 		}
-		svc := NewTemplateService(repo, nil, nil, testRuntime())
+		svc := NewTemplateService(repo, nil, nil, nil, nil, testRuntime())
 		_, err := svc.List(context.Background(), "u1")
 		assert.Error(t, err)
 	})
@@ -93,7 +94,7 @@ func TestTemplateService_ListMeta(t *testing.T) {
 				return []model.TemplateMeta{{ID: "t1"}}, nil
 			},
 		}
-		svc := NewTemplateService(repo, nil, nil, testRuntime())
+		svc := NewTemplateService(repo, nil, nil, nil, nil, testRuntime())
 		result, err := svc.ListMeta(context.Background(), "u1", "", 0, -1)
 		require.NoError(t, err)
 		assert.Equal(t, 5, result.Total)
@@ -108,7 +109,7 @@ func TestTemplateService_ListMeta(t *testing.T) {
 				return nil, nil
 			},
 		}
-		svc := NewTemplateService(repo, nil, nil, testRuntime())
+		svc := NewTemplateService(repo, nil, nil, nil, nil, testRuntime())
 		_, err := svc.ListMeta(context.Background(), "u1", "", 999, 0)
 		require.NoError(t, err)
 	})
@@ -119,7 +120,7 @@ func TestTemplateService_ListMeta(t *testing.T) {
 				return 0, errors.New("db error")
 			},
 		}
-		svc := NewTemplateService(repo, nil, nil, testRuntime())
+		svc := NewTemplateService(repo, nil, nil, nil, nil, testRuntime())
 		_, err := svc.ListMeta(context.Background(), "u1", "", 10, 0)
 		assert.Error(t, err)
 	})
@@ -131,7 +132,7 @@ func TestTemplateService_ListMeta(t *testing.T) {
 				return nil, errors.New("db error")
 			},
 		}
-		svc := NewTemplateService(repo, nil, nil, testRuntime())
+		svc := NewTemplateService(repo, nil, nil, nil, nil, testRuntime())
 		_, err := svc.ListMeta(context.Background(), "u1", "", 10, 0)
 		assert.Error(t, err)
 	})
@@ -153,7 +154,7 @@ func TestTemplateService_ListMeta(t *testing.T) {
 				return []model.TemplateMeta{{ID: "daily"}}, nil
 			},
 		}
-		svc := NewTemplateService(repo, nil, nil, testRuntime())
+		svc := NewTemplateService(repo, nil, nil, nil, nil, testRuntime())
 		result, err := svc.ListMeta(context.Background(), "u1", "  Daily  ", 20, 0)
 		require.NoError(t, err)
 		assert.Equal(t, 1, result.Total)
@@ -161,7 +162,7 @@ func TestTemplateService_ListMeta(t *testing.T) {
 	})
 
 	t.Run("reject_search_over_200_unicode_characters", func(t *testing.T) {
-		svc := NewTemplateService(&mockTemplateRepo{}, nil, nil, testRuntime())
+		svc := NewTemplateService(&mockTemplateRepo{}, nil, nil, nil, nil, testRuntime())
 		_, err := svc.ListMeta(context.Background(), "u1", strings.Repeat("界", 201), 20, 0)
 		assert.ErrorIs(t, err, appErr.ErrInvalid)
 	})
@@ -174,7 +175,7 @@ func TestTemplateService_Get(t *testing.T) {
 				return &model.Template{ID: "t1", Name: "My Template"}, nil
 			},
 		}
-		svc := NewTemplateService(repo, nil, nil, testRuntime())
+		svc := NewTemplateService(repo, nil, nil, nil, nil, testRuntime())
 		tpl, err := svc.Get(context.Background(), "u1", "t1")
 		require.NoError(t, err)
 		assert.Equal(t, "My Template", tpl.Name)
@@ -186,7 +187,7 @@ func TestTemplateService_Get(t *testing.T) {
 				return nil, errors.New("db error")
 			},
 		}
-		svc := NewTemplateService(repo, nil, nil, testRuntime())
+		svc := NewTemplateService(repo, nil, nil, nil, nil, testRuntime())
 		_, err := svc.Get(context.Background(), "u1", "t1")
 		assert.Error(t, err)
 	})
@@ -201,7 +202,7 @@ func TestTemplateService_Create(t *testing.T) {
 				return nil
 			},
 		}
-		svc := NewTemplateService(repo, nil, nil, testRuntime())
+		svc := NewTemplateService(repo, nil, nil, nil, nil, testRuntime())
 		tpl, err := svc.Create(context.Background(), "u1", CreateTemplateInput{
 			Name:    "Note",
 			Content: "# Hello",
@@ -211,13 +212,13 @@ func TestTemplateService_Create(t *testing.T) {
 	})
 
 	t.Run("empty_name", func(t *testing.T) {
-		svc := NewTemplateService(&mockTemplateRepo{}, nil, nil, testRuntime())
+		svc := NewTemplateService(&mockTemplateRepo{}, nil, nil, nil, nil, testRuntime())
 		_, err := svc.Create(context.Background(), "u1", CreateTemplateInput{Name: "", Content: "hello"})
 		assert.ErrorIs(t, err, appErr.ErrInvalid)
 	})
 
 	t.Run("empty_content", func(t *testing.T) {
-		svc := NewTemplateService(&mockTemplateRepo{}, nil, nil, testRuntime())
+		svc := NewTemplateService(&mockTemplateRepo{}, nil, nil, nil, nil, testRuntime())
 		_, err := svc.Create(context.Background(), "u1", CreateTemplateInput{Name: "Note", Content: "  "})
 		assert.ErrorIs(t, err, appErr.ErrInvalid)
 	})
@@ -229,7 +230,7 @@ func TestTemplateService_Create(t *testing.T) {
 				return nil
 			},
 		}
-		svc := NewTemplateService(repo, nil, nil, testRuntime())
+		svc := NewTemplateService(repo, nil, nil, nil, nil, testRuntime())
 		_, err := svc.Create(context.Background(), "u1", CreateTemplateInput{
 			Name:    "Note",
 			Content: "{{ title }}",
@@ -241,7 +242,7 @@ func TestTemplateService_Create(t *testing.T) {
 		repo := &mockTemplateRepo{
 			createFn: func(context.Context, *model.Template) error { return errors.New("db error") },
 		}
-		svc := NewTemplateService(repo, nil, nil, testRuntime())
+		svc := NewTemplateService(repo, nil, nil, nil, nil, testRuntime())
 		_, err := svc.Create(context.Background(), "u1", CreateTemplateInput{Name: "N", Content: "C"})
 		assert.Error(t, err)
 	})
@@ -257,7 +258,7 @@ func TestTemplateService_Create(t *testing.T) {
 			listByIDsFn: func(_ context.Context, userID string, _ []string) ([]model.Tag, error) {
 				return []model.Tag{{ID: "t1", UserID: userID}}, nil
 			},
-		}, testRuntime())
+		}, nil, nil, testRuntime())
 
 		_, err := svc.Create(context.Background(), "u1", CreateTemplateInput{
 			Name:          "Note",
@@ -277,7 +278,7 @@ func TestTemplateService_Update(t *testing.T) {
 				return nil
 			},
 		}
-		svc := NewTemplateService(repo, nil, nil, testRuntime())
+		svc := NewTemplateService(repo, nil, nil, nil, nil, testRuntime())
 		err := svc.Update(context.Background(), "u1", "tpl1", UpdateTemplateInput{
 			Name: "Updated", Content: "content",
 		})
@@ -285,7 +286,7 @@ func TestTemplateService_Update(t *testing.T) {
 	})
 
 	t.Run("empty_name", func(t *testing.T) {
-		svc := NewTemplateService(&mockTemplateRepo{}, nil, nil, testRuntime())
+		svc := NewTemplateService(&mockTemplateRepo{}, nil, nil, nil, nil, testRuntime())
 		err := svc.Update(context.Background(), "u1", "tpl1", UpdateTemplateInput{Content: "c"})
 		assert.ErrorIs(t, err, appErr.ErrInvalid)
 	})
@@ -294,7 +295,7 @@ func TestTemplateService_Update(t *testing.T) {
 		repo := &mockTemplateRepo{
 			updateFn: func(context.Context, *model.Template) error { return errors.New("db error") },
 		}
-		svc := NewTemplateService(repo, nil, nil, testRuntime())
+		svc := NewTemplateService(repo, nil, nil, nil, nil, testRuntime())
 		err := svc.Update(context.Background(), "u1", "tpl1", UpdateTemplateInput{Name: "N", Content: "C"})
 		assert.Error(t, err)
 	})
@@ -305,7 +306,7 @@ func TestTemplateService_Delete(t *testing.T) {
 		repo := &mockTemplateRepo{
 			deleteFn: func(context.Context, string, string) error { return nil },
 		}
-		svc := NewTemplateService(repo, nil, nil, testRuntime())
+		svc := NewTemplateService(repo, nil, nil, nil, nil, testRuntime())
 		err := svc.Delete(context.Background(), "u1", "tpl1")
 		require.NoError(t, err)
 	})
@@ -314,7 +315,7 @@ func TestTemplateService_Delete(t *testing.T) {
 		repo := &mockTemplateRepo{
 			deleteFn: func(context.Context, string, string) error { return errors.New("db error") },
 		}
-		svc := NewTemplateService(repo, nil, nil, testRuntime())
+		svc := NewTemplateService(repo, nil, nil, nil, nil, testRuntime())
 		err := svc.Delete(context.Background(), "u1", "tpl1")
 		assert.Error(t, err)
 	})
@@ -334,7 +335,7 @@ func TestTemplateService_CreateDocumentFromTemplate(t *testing.T) {
 		}
 		docRepo := &mockDocumentRepo{
 			createFn:      func(context.Context, *model.Document) error { return nil },
-			updateLinksFn: func(context.Context, string, string, []string, int64) error { return nil },
+			updateLinksFn: func(context.Context, string, string, []model.LinkTarget, int64) error { return nil },
 		}
 		versions := &mockVersionRepo{
 			createFn:            func(context.Context, *model.DocumentVersion) error { return nil },
@@ -351,7 +352,7 @@ func TestTemplateService_CreateDocumentFromTemplate(t *testing.T) {
 				return []model.Tag{{ID: "t1", Name: "go"}}, nil
 			},
 		}
-		svc := NewTemplateService(tplRepo, docSvc, tagRepoMock, testRuntime())
+		svc := NewTemplateService(tplRepo, docSvc, tagRepoMock, nil, nil, testRuntime())
 		doc, err := svc.CreateDocumentFromTemplate(context.Background(), "u1", CreateDocumentFromTemplateInput{
 			TemplateID: "tpl1",
 			Title:      "Custom Title",
@@ -377,7 +378,7 @@ func TestTemplateService_CreateDocumentFromTemplate(t *testing.T) {
 				capturedTitle = doc.Title
 				return nil
 			},
-			updateLinksFn: func(context.Context, string, string, []string, int64) error { return nil },
+			updateLinksFn: func(context.Context, string, string, []model.LinkTarget, int64) error { return nil },
 		}
 		versions := &mockVersionRepo{
 			createFn:            func(context.Context, *model.DocumentVersion) error { return nil },
@@ -389,7 +390,7 @@ func TestTemplateService_CreateDocumentFromTemplate(t *testing.T) {
 		}
 		docSvc := newDocSvc(docRepo, versions, dtags, nil)
 
-		svc := NewTemplateService(tplRepo, docSvc, nil, testRuntime())
+		svc := NewTemplateService(tplRepo, docSvc, nil, nil, nil, testRuntime())
 		doc, err := svc.CreateDocumentFromTemplate(context.Background(), "u1", CreateDocumentFromTemplateInput{
 			TemplateID: "tpl1",
 		})
@@ -404,7 +405,7 @@ func TestTemplateService_CreateDocumentFromTemplate(t *testing.T) {
 				return nil, appErr.ErrNotFound
 			},
 		}
-		svc := NewTemplateService(tplRepo, nil, nil, testRuntime())
+		svc := NewTemplateService(tplRepo, nil, nil, nil, nil, testRuntime())
 		_, err := svc.CreateDocumentFromTemplate(context.Background(), "u1", CreateDocumentFromTemplateInput{
 			TemplateID: "bad",
 		})
@@ -425,7 +426,7 @@ func TestTemplateService_CreateDocumentFromTemplate(t *testing.T) {
 		var addedTagIDs []string
 		docRepo := &mockDocumentRepo{
 			createFn:      func(context.Context, *model.Document) error { return nil },
-			updateLinksFn: func(context.Context, string, string, []string, int64) error { return nil },
+			updateLinksFn: func(context.Context, string, string, []model.LinkTarget, int64) error { return nil },
 		}
 		versions := &mockVersionRepo{
 			createFn:            func(context.Context, *model.DocumentVersion) error { return nil },
@@ -445,7 +446,7 @@ func TestTemplateService_CreateDocumentFromTemplate(t *testing.T) {
 				return []model.Tag{{ID: "t1"}, {ID: "t3"}}, nil
 			},
 		}
-		svc := NewTemplateService(tplRepo, docSvc, tagRepoMock, testRuntime())
+		svc := NewTemplateService(tplRepo, docSvc, tagRepoMock, nil, nil, testRuntime())
 		_, err := svc.CreateDocumentFromTemplate(context.Background(), "u1", CreateDocumentFromTemplateInput{
 			TemplateID: "tpl1",
 			Title:      "Title",
@@ -454,3 +455,206 @@ func TestTemplateService_CreateDocumentFromTemplate(t *testing.T) {
 		assert.Empty(t, addedTagIDs)
 	})
 }
+
+type captureTemplateDocService struct {
+	input DocumentCreateInput
+}
+
+func (c *captureTemplateDocService) Create(
+	_ context.Context, _ string, input DocumentCreateInput,
+) (*model.Document, error) {
+	c.input = input
+	return &model.Document{ID: "d1", Title: input.Title, Content: input.Content}, nil
+}
+
+func (c *captureTemplateDocService) ValidateOwnedTagIDs(_ context.Context, _ string, ids []string) ([]string, error) {
+	return ids, nil
+}
+
+func TestTemplateService_Create_Variables(t *testing.T) {
+	tags := &mockTagRepo{
+		listByIDsFn: func(_ context.Context, _ string, ids []string) ([]model.Tag, error) {
+			items := make([]model.Tag, 0, len(ids))
+			for _, id := range ids {
+				if id == "t1" {
+					items = append(items, model.Tag{ID: id})
+				}
+			}
+			return items, nil
+		},
+	}
+
+	t.Run("normalizes", func(t *testing.T) {
+		var created *model.Template
+		repo := &mockTemplateRepo{
+			createFn: func(_ context.Context, tpl *model.Template) error {
+				created = tpl
+				return nil
+			},
+		}
+		svc := NewTemplateService(repo, nil, tags, nil, nil, testRuntime())
+		_, err := svc.Create(context.Background(), "u1", CreateTemplateInput{
+			Name: "Daily", Content: "{{#if mood}}{{mood}}{{/if}}",
+			Variables: []model.TemplateVariable{
+				{Key: " mood ", Type: "SELECT", Options: []string{"ok", "great", "ok"}, Default: "ok"},
+				{Key: "due", Type: "date", Default: "SYS:Today+1d"},
+				{Key: "area", Type: "tag", Default: "t1"},
+				{Key: "note"},
+			},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "{{#if MOOD}}{{MOOD}}{{/if}}", created.Content)
+		assert.Equal(t, []model.TemplateVariable{
+			{Key: "MOOD", Type: "select", Default: "ok", Options: []string{"ok", "great"}},
+			{Key: "DUE", Type: "date", Default: "sys:today+1d"},
+			{Key: "AREA", Type: "tag", Default: "t1"},
+			{Key: "NOTE", Type: "text"},
+		}, created.Variables)
+	})
+
+	invalid := map[string]CreateTemplateInput{
+		"unbalanced_block": {Name: "x", Content: "{{#if A}}"},
+		"duplicate_key": {Name: "x", Content: "x", Variables: []model.TemplateVariable{
+			{Key: "a"}, {Key: "A"},
+		}},
+		"system_key":     {Name: "x", Content: "x", Variables: []model.TemplateVariable{{Key: "sys:date"}}},
+		"reserved_key":   {Name: "x", Content: "x", Variables: []model.TemplateVariable{{Key: "this"}}},
+		"unknown_type":   {Name: "x", Content: "x", Variables: []model.TemplateVariable{{Key: "A", Type: "number"}}},
+		"select_options": {Name: "x", Content: "x", Variables: []model.TemplateVariable{{Key: "A", Type: "select"}}},
+		"select_default": {Name: "x", Content: "x", Variables: []model.TemplateVariable{
+			{Key: "A", Type: "select", Options: []string{"a"}, Default: "b"},
+		}},
+		"date_default": {Name: "x", Content: "x", Variables: []model.TemplateVariable{
+			{Key: "A", Type: "date", Default: "next week"},
+		}},
+		"foreign_tag": {Name: "x", Content: "x", Variables: []model.TemplateVariable{
+			{Key: "A", Type: "tag", Default: "t9"},
+		}},
+	}
+	for name, input := range invalid {
+		t.Run(name, func(t *testing.T) {
+			svc := NewTemplateService(&mockTemplateRepo{}, nil, tags, nil, nil, testRuntime())
+			_, err := svc.Create(context.Background(), "u1", input)
+			assert.ErrorIs(t, err, appErr.ErrInvalid)
+		})
+	}
+}
+
+func TestTemplateService_Get_DescribesVariables(t *testing.T) {
+	repo := &mockTemplateRepo{
+		getByIDFn: func(context.Context, string, string) (*model.Template, error) {
+			return &model.Template{
+				ID:        "tpl1",
+				Content:   "{{NAME}} {{#each ITEMS}}{{THIS}}{{/each}} {{DUE}} {{SYS:DATE}}",
+				Variables: []model.TemplateVariable{{Key: "DUE", Type: model.TemplateVariableDate}},
+			}, nil
+		},
+	}
+	svc := NewTemplateService(repo, nil, nil, nil, nil, testRuntime())
+	tpl, err := svc.Get(context.Background(), "u1", "tpl1")
+	require.NoError(t, err)
+	assert.Equal(t, []model.TemplateVariable{
+		{Key: "DUE", Type: "date"},
+		{Key: "NAME", Type: "text"},
+		{Key: "ITEMS", Type: "text"},
+	}, tpl.Variables)
+}
+
+func TestTemplateService_CreateDocumentFromTemplate_TypedVariables(t *testing.T) {
+	tpl := &model.Template{
+		ID:   "tpl1",
+		Name: "Meeting",
+		Content: "# {{TOPIC}} {{DUE|DD/MM}}\n{{#if MOOD}}mood: {{MOOD}}\n{{/if}}" +
+			"area: {{AREA}} by {{SYS:USER}}",
+		DefaultTagIDs: []string{"t0"},
+		Variables: []model.TemplateVariable{
+			{Key: "DUE", Type: model.TemplateVariableDate, Default: "sys:today+1d"},
+			{Key: "MOOD", Type: model.TemplateVariableSelect, Options: []string{"ok", "great"}},
+			{Key: "AREA", Type: model.TemplateVariableTag, Default: "t-gone"},
+		},
+	}
+	repo := &mockTemplateRepo{
+		getByIDFn: func(context.Context, string, string) (*model.Template, error) {
+			copied := *tpl
+			return &copied, nil
+		},
+	}
+	tags := &mockTagRepo{
+		listByIDsFn: func(_ context.Context, _ string, ids []string) ([]model.Tag, error) {
+			items := make([]model.Tag, 0, len(ids))
+			for _, id := range ids {
+				switch id {
+				case "t0":
+					items = append(items, model.Tag{ID: "t0", Name: "meeting"})
+				case "t1":
+					items = append(items, model.Tag{ID: "t1", Name: "work"})
+				}
+			}
+			return items, nil
+		},
+	}
+	users := &mockUserRepo{
+		getByIDFn: func(context.Context, string) (*model.User, error) {
+			return &model.User{ID: "u1", Email: "ann@example.com"}, nil
+		},
+	}
+	runtime := testRuntimeAt(time.Date(2026, 4, 28, 12, 0, 0, 0, time.Local).Unix())
+
+	t.Run("defaults", func(t *testing.T) {
+		docs := &captureTemplateDocService{}
+		svc := NewTemplateService(repo, docs, tags, users, nil, runtime)
+		_, err := svc.CreateDocumentFromTemplate(context.Background(), "u1", CreateDocumentFromTemplateInput{
+			TemplateID: "tpl1",
+			Variables:  map[string]string{"topic": "Sync"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "# Sync 29/04\narea:  by ann@example.com", docs.input.Content)
+		assert.Equal(t, "Sync 29/04", docs.input.Title)
+		assert.Equal(t, []string{"t0"}, docs.input.TagIDs)
+	})
+
+	t.Run("explicit_values", func(t *testing.T) {
+		docs := &captureTemplateDocService{}
+		svc := NewTemplateService(repo, docs, tags, users, nil, runtime)
+		_, err := svc.CreateDocumentFromTemplate(context.Background(), "u1", CreateDocumentFromTemplateInput{
+			TemplateID: "tpl1",
+			Variables:  map[string]string{"TOPIC": "Plan", "DUE": "2026-05-03", "MOOD": "great", "AREA": "t1"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "# Plan 03/05\nmood: great\narea: work by ann@example.com", docs.input.Content)
+		assert.Equal(t, []string{"t0", "t1"}, docs.input.TagIDs)
+	})
+
+	t.Run("at_and_tags", func(t *testing.T) {
+		docs := &captureTemplateDocService{}
+		svc := NewTemplateService(repo, docs, tags, users, nil, runtime)
+		_, err := svc.CreateDocumentFromTemplate(context.Background(), "u1", CreateDocumentFromTemplateInput{
+			TemplateID: "tpl1",
+			Variables:  map[string]string{"TOPIC": "Review"},
+			TagIDs:     []string{"t1"},
+			At:         time.Date(2026, 6, 1, 9, 0, 0, 0, time.Local),
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "# Review 02/06\narea:  by ann@example.com", docs.input.Content)
+		assert.Equal(t, []string{"t0", "t1"}, docs.input.TagIDs)
+
+		_, err = svc.CreateDocumentFromTemplate(context.Background(), "u1", CreateDocumentFromTemplateInput{
+			TemplateID: "tpl1", TagIDs: []string{"t9"},
+		})
+		assert.ErrorIs(t, err, appErr.ErrInvalid)
+	})
+
+	for name, values := range map[string]map[string]string{
+		"bad_date":    {"DUE": "tomorrow"},
+		"bad_option":  {"MOOD": "meh"},
+		"foreign_tag": {"AREA": "t9"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			svc := NewTemplateService(repo, &captureTemplateDocService{}, tags, users, nil, runtime)
+			_, err := svc.CreateDocumentFromTemplate(context.Background(), "u1", CreateDocumentFromTemplateInput{
+				TemplateID: "tpl1", Variables: values,
+			})
+			assert.ErrorIs(t, err, appErr.ErrInvalid)
+		})
+	}
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+const (
+	maxTemplateVariables       = 50
+	maxTemplateVariableOptions = 50
+)
+
+var templateVariableKeyRegex = regexp.MustCompile(`^[A-Z0-9_\-]{1,64}$`)
+
+// normalizeVariables validates declared variables: keys are upper-cased and
+// unique, select variables need options containing their default, date
+// defaults are a date or a `sys:` expression and tag defaults must be tags
+// of the user.
+func (s *TemplateService) normalizeVariables(
+	ctx context.Context,
+	userID string,
+	variables []model.TemplateVariable,
+) ([]model.TemplateVariable, error) {
+	if len(variables) > maxTemplateVariables {
+		return nil, appErr.ErrInvalid
+	}
+	out := make([]model.TemplateVariable, 0, len(variables))
+	seen := make(map[string]struct{}, len(variables))
+	tagIDs := make([]string, 0)
+	for _, variable := range variables {
+		item := model.TemplateVariable{
+			Key:         strings.ToUpper(strings.TrimSpace(variable.Key)),
+			Type:        strings.ToLower(strings.TrimSpace(variable.Type)),
+			Default:     strings.TrimSpace(variable.Default),
+			Description: strings.TrimSpace(variable.Description),
+		}
+		if item.Type == "" {
+			item.Type = model.TemplateVariableText
+		}
+		if !templateVariableKeyRegex.MatchString(item.Key) ||
+			item.Key == "THIS" || item.Key == "INDEX" || item.Key == "ELSE" {
+			return nil, appErr.ErrInvalid
+		}
+		if _, ok := seen[item.Key]; ok {
+			return nil, appErr.ErrInvalid
+		}
+		seen[item.Key] = struct{}{}
+		if utf8.RuneCountInString(item.Description) > 500 || utf8.RuneCountInString(item.Default) > 1000 {
+			return nil, appErr.ErrInvalid
+		}
+		switch item.Type {
+		case model.TemplateVariableText:
+		case model.TemplateVariableDate:
+			if item.Default != "" && !validTemplateDateDefault(item.Default, s.runtime.Clock.Now()) {
+				return nil, appErr.ErrInvalid
+			}
+			if strings.HasPrefix(strings.ToLower(item.Default), "sys:") {
+				item.Default = strings.ToLower(item.Default)
+			}
+		case model.TemplateVariableSelect:
+			item.Options = uniqueStringSlice(variable.Options)
+			if len(item.Options) == 0 || len(item.Options) > maxTemplateVariableOptions {
+				return nil, appErr.ErrInvalid
+			}
+			for _, option := range item.Options {
+				if utf8.RuneCountInString(option) > 200 {
+					return nil, appErr.ErrInvalid
+				}
+			}
+			if item.Default != "" && !slices.Contains(item.Options, item.Default) {
+				return nil, appErr.ErrInvalid
+			}
+		case model.TemplateVariableTag:
+			if item.Default != "" {
+				tagIDs = append(tagIDs, item.Default)
+			}
+		default:
+			return nil, appErr.ErrInvalid
+		}
+		out = append(out, item)
+	}
+	if _, err := s.validateOwnedTagIDs(ctx, userID, tagIDs); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// validTemplateDateDefault reports whether value is a date or a `sys:`
+// expression that resolves at now.
+func validTemplateDateDefault(value string, now time.Time) bool {
+	if _, err := time.Parse(todoDateLayout, value); err == nil {
+		return true
+	}
+	_, _, ok := systemTimeVariable(value, now)
+	return ok
+}
+
+// describeTemplateVariables appends the variables referenced by content but
+// not declared as plain text variables, so clients can prompt for all of
+// them.
+func describeTemplateVariables(content string, declared []model.TemplateVariable) []model.TemplateVariable {
+	out := make([]model.TemplateVariable, 0, len(declared))
+	seen := make(map[string]struct{}, len(declared))
+	for _, variable := range declared {
+		seen[variable.Key] = struct{}{}
+		out = append(out, variable)
+	}
+	for _, key := range templateVariableKeys(content) {
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		out = append(out, model.TemplateVariable{Key: key, Type: model.TemplateVariableText})
+	}
+	return out
+}
+
+// resolveVariableValues fills defaults into values and checks them against
+// the declared types. Date values are normalized to YYYY-MM-DD and tag values
+// are replaced by the tag name; the IDs of the chosen tags are returned so
+// they can be attached to the new document.
+func (s *TemplateService) resolveVariableValues(
+	ctx context.Context,
+	userID string,
+	declared []model.TemplateVariable,
+	values map[string]string,
+	now time.Time,
+) ([]string, error) {
+	tagKeys := map[string]string{}
+	defaultTags := map[string]struct{}{}
+	for _, variable := range declared {
+		value := values[variable.Key]
+		if value == "" {
+			value = variable.Default
+			if variable.Type == model.TemplateVariableTag {
+				defaultTags[variable.Key] = struct{}{}
+			}
+		}
+		switch variable.Type {
+		case model.TemplateVariableDate:
+			if strings.HasPrefix(strings.ToLower(value), "sys:") {
+				at, _, ok := systemTimeVariable(value, now)
+				if !ok {
+					return nil, appErr.ErrInvalid
+				}
+				value = formatTemplateTime(at, "YYYY-MM-DD")
+			}
+			if value != "" {
+				if _, err := time.Parse(todoDateLayout, value); err != nil {
+					return nil, appErr.ErrInvalid
+				}
+			}
+		case model.TemplateVariableSelect:
+			if value != "" && !slices.Contains(variable.Options, value) {
+				return nil, appErr.ErrInvalid
+			}
+		case model.TemplateVariableTag:
+			if value != "" {
+				tagKeys[variable.Key] = value
+			}
+		}
+		values[variable.Key] = value
+	}
+	if len(tagKeys) == 0 {
+		return []string{}, nil
+	}
+	if s.tags == nil {
+		return nil, appErr.ErrInvalid
+	}
+	ids := make([]string, 0, len(tagKeys))
+	for _, id := range tagKeys {
+		ids = append(ids, id)
+	}
+	ids = uniqueStringSlice(ids)
+	tags, err := s.tags.ListByIDs(ctx, userID, ids)
+	if err != nil {
+		return nil, fmt.Errorf("list variable tags: %w", err)
+	}
+	names := make(map[string]string, len(tags))
+	for _, tag := range tags {
+		names[tag.ID] = tag.Name
+	}
+	chosen := make([]string, 0, len(tagKeys))
+	for key, id := range tagKeys {
+		name, ok := names[id]
+		if !ok {
+			// A default pointing at a since deleted tag is dropped; an
+			// explicit value must be a tag of the user.
+			if _, isDefault := defaultTags[key]; !isDefault {
+				return nil, appErr.ErrInvalid
+			}
+			values[key] = ""
+			continue
+		}
+		values[key] = name
+		chosen = append(chosen, id)
+	}
+	chosen = uniqueStringSlice(chosen)
+	slices.Sort(chosen)
+	return chosen, nil
+}
+
+// templateUserLabel returns the value of `{{SYS:USER}}`, loading the user
+// only when the content uses it. In a team workspace it names the member
+// acting, not the workspace.
+func (s *TemplateService) templateUserLabel(ctx context.Context, userID, content string) (string, error) {
+	if s.users == nil || !strings.Contains(strings.ToUpper(content), "SYS:USER") {
+		return "", nil
+	}
+	user, err := s.users.GetByID(ctx, actorID(ctx, userID))
+	if err != nil {
+		return "", fmt.Errorf("get user: %w", err)
+	}
+	return user.Email, nil
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+const (
+	todoDateLayout          = "2006-01-02"
+	maxTodoRepeatInterval   = 366
+	maxTodoRepeatCount      = 1000
+	maxTodoVirtualPerSeries = 400
+	maxTodoExpandSteps      = 5000
+)
+
+// normalizeTodoRecurrence validates a rule against the first due date and
+// fills the anchors that keep later occurrences stable: a weekly rule without
+// weekdays repeats on the due date's weekday, and a monthly rule without a
+// day repeats on the due date's day of month.
+func normalizeTodoRecurrence(rule *model.TodoRecurrence, dueDate string) (*model.TodoRecurrence, error) {
+	if rule == nil {
+		return nil, nil
+	}
+	due, err := time.Parse(todoDateLayout, dueDate)
+	if err != nil {
+		return nil, appErr.ErrInvalid
+	}
+	out := *rule
+	out.Freq = strings.ToLower(strings.TrimSpace(out.Freq))
+	if out.Interval == 0 {
+		out.Interval = 1
+	}
+	if out.Interval < 1 || out.Interval > maxTodoRepeatInterval {
+		return nil, appErr.ErrInvalid
+	}
+	if out.Count < 0 || out.Count > maxTodoRepeatCount {
+		return nil, appErr.ErrInvalid
+	}
+	if out.Until != "" {
+		until, err := time.Parse(todoDateLayout, out.Until)
+		if err != nil || until.Before(due) {
+			return nil, appErr.ErrInvalid
+		}
+	}
+	switch out.Freq {
+	case model.TodoRepeatDaily:
+		out.Weekdays = nil
+		out.MonthDay = 0
+	case model.TodoRepeatWeekly:
+		out.MonthDay = 0
+		if len(out.Weekdays) == 0 {
+			out.Weekdays = []int{int(due.Weekday())}
+		}
+		seen := make(map[int]struct{}, len(out.Weekdays))
+		weekdays := make([]int, 0, len(out.Weekdays))
+		for _, day := range out.Weekdays {
+			if day < 0 || day > 6 {
+				return nil, appErr.ErrInvalid
+			}
+			if _, ok := seen[day]; ok {
+				continue
+			}
+			seen[day] = struct{}{}
+			weekdays = append(weekdays, day)
+		}
+		sort.Ints(weekdays)
+		out.Weekdays = weekdays
+	case model.TodoRepeatMonthly:
+		out.Weekdays = nil
+		if out.MonthDay == 0 {
+			out.MonthDay = due.Day()
+		}
+		if out.MonthDay < 1 || out.MonthDay > 31 {
+			return nil, appErr.ErrInvalid
+		}
+	default:
+		return nil, appErr.ErrInvalid
+	}
+	return &out, nil
+}
+
+// nextTodoOccurrence returns the due date of occurrence+1 after dueDate, or
+// false when the rule has ended by count or until date.
+func nextTodoOccurrence(rule *model.TodoRecurrence, dueDate string, occurrence int) (string, bool) {
+	if rule == nil {
+		return "", false
+	}
+	if rule.Count > 0 && occurrence >= rule.Count {
+		return "", false
+	}
+	current, err := time.Parse(todoDateLayout, dueDate)
+	if err != nil {
+		return "", false
+	}
+	interval := max(rule.Interval, 1)
+	var next time.Time
+	switch rule.Freq {
+	case model.TodoRepeatDaily:
+		next = current.AddDate(0, 0, interval)
+	case model.TodoRepeatWeekly:
+		next = nextWeeklyOccurrence(current, rule.Weekdays, interval)
+	case model.TodoRepeatMonthly:
+		next = nextMonthlyOccurrence(current, rule.MonthDay, interval)
+	default:
+		return "", false
+	}
+	if next.IsZero() {
+		return "", false
+	}
+	if rule.Until != "" {
+		until, err := time.Parse(todoDateLayout, rule.Until)
+		if err != nil || next.After(until) {
+			return "", false
+		}
+	}
+	return next.Format(todoDateLayout), true
+}
+
+// nextWeeklyOccurrence walks forward day by day; weeks start on Monday and
+// only every interval-th week counted from the current one is eligible.
+func nextWeeklyOccurrence(current time.Time, weekdays []int, interval int) time.Time {
+	if len(weekdays) == 0 {
+		weekdays = []int{int(current.Weekday())}
+	}
+	allowed := make(map[time.Weekday]struct{}, len(weekdays))
+	for _, day := range weekdays {
+		allowed[time.Weekday(day)] = struct{}{}
+	}
+	base := mondayOf(current)
+	for offset := 1; offset <= 7*interval+7; offset++ {
+		candidate := current.AddDate(0, 0, offset)
+		if _, ok := allowed[candidate.Weekday()]; !ok {
+			continue
+		}
+		weeks := int(mondayOf(candidate).Sub(base).Hours() / (24 * 7))
+		if weeks%interval == 0 {
+			return candidate
+		}
+	}
+	return time.Time{}
+}
+
+func mondayOf(day time.Time) time.Time {
+	shift := (int(day.Weekday()) + 6) % 7
+	return day.AddDate(0, 0, -shift)
+}
+
+// nextMonthlyOccurrence clamps monthDay to the length of the target month, so
+// a rule on the 31st lands on the last day of shorter months without
+// drifting afterwards.
+func nextMonthlyOccurrence(current time.Time, monthDay, interval int) time.Time {
+	if monthDay < 1 {
+		monthDay = current.Day()
+	}
+	first := time.Date(current.Year(), current.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, interval, 0)
+	lastDay := first.AddDate(0, 1, -1).Day()
+	return first.AddDate(0, 0, min(monthDay, lastDay)-1)
+}
+
+// expandTodoOccurrences projects the series head forward into read-only
+// virtual occurrences that fall inside [startDate, endDate].
+func expandTodoOccurrences(head model.Todo, startDate, endDate string) []model.Todo {
+	items := make([]model.Todo, 0)
+	dueDate, occurrence := head.DueDate, max(head.Occurrence, 1)
+	for range maxTodoExpandSteps {
+		if len(items) >= maxTodoVirtualPerSeries {
+			break
+		}
+		next, ok := nextTodoOccurrence(head.Recurrence, dueDate, occurrence)
+		if !ok || next > endDate {
+			break
+		}
+		dueDate, occurrence = next, occurrence+1
+		if next < startDate {
+			continue
+		}
+		virtual := head
+		virtual.ID = head.ID + "@" + next
+		virtual.DueDate = next
+		virtual.Done = 0
+		virtual.Occurrence = occurrence
+		virtual.NextID = ""
+		virtual.Virtual = true
+		items = append(items, virtual)
+	}
+	return items
+}
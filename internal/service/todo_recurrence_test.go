@@ -0,0 +1,111 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+func TestNormalizeTodoRecurrence(t *testing.T) {
+	rule, err := normalizeTodoRecurrence(nil, "")
+	require.NoError(t, err)
+	assert.Nil(t, rule)
+
+	// 2026-04-27 is a Monday.
+	rule, err = normalizeTodoRecurrence(&model.TodoRecurrence{Freq: " Weekly "}, "2026-04-27")
+	require.NoError(t, err)
+	assert.Equal(t, &model.TodoRecurrence{Freq: model.TodoRepeatWeekly, Interval: 1, Weekdays: []int{1}}, rule)
+
+	rule, err = normalizeTodoRecurrence(
+		&model.TodoRecurrence{Freq: "weekly", Weekdays: []int{5, 1, 5}},
+		"2026-04-27",
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 5}, rule.Weekdays)
+
+	rule, err = normalizeTodoRecurrence(&model.TodoRecurrence{Freq: "monthly"}, "2026-01-31")
+	require.NoError(t, err)
+	assert.Equal(t, 31, rule.MonthDay)
+
+	for _, invalid := range []*model.TodoRecurrence{
+		{Freq: "yearly"},
+		{Freq: "daily", Interval: -1},
+		{Freq: "daily", Interval: maxTodoRepeatInterval + 1},
+		{Freq: "daily", Count: -1},
+		{Freq: "daily", Until: "2026-04-01"},
+		{Freq: "weekly", Weekdays: []int{7}},
+		{Freq: "monthly", MonthDay: 32},
+	} {
+		_, err := normalizeTodoRecurrence(invalid, "2026-04-27")
+		assert.ErrorIs(t, err, appErr.ErrInvalid, "%+v", invalid)
+	}
+	_, err = normalizeTodoRecurrence(&model.TodoRecurrence{Freq: "daily"}, "")
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+}
+
+func TestNextTodoOccurrence(t *testing.T) {
+	cases := []struct {
+		name       string
+		rule       model.TodoRecurrence
+		due        string
+		occurrence int
+		want       string
+		ok         bool
+	}{
+		{"every_3_days", model.TodoRecurrence{Freq: "daily", Interval: 3}, "2026-02-27", 1, "2026-03-02", true},
+		{"weekdays_same_week", model.TodoRecurrence{Freq: "weekly", Interval: 1, Weekdays: []int{1, 4}},
+			"2026-04-27", 1, "2026-04-30", true},
+		{"weekdays_next_week", model.TodoRecurrence{Freq: "weekly", Interval: 1, Weekdays: []int{1, 4}},
+			"2026-04-30", 2, "2026-05-04", true},
+		{"biweekly_skips_week", model.TodoRecurrence{Freq: "weekly", Interval: 2, Weekdays: []int{1, 4}},
+			"2026-04-30", 2, "2026-05-11", true},
+		{"sunday_belongs_to_week", model.TodoRecurrence{Freq: "weekly", Interval: 2, Weekdays: []int{0, 1}},
+			"2026-04-27", 1, "2026-05-03", true},
+		{"monthly_clamps", model.TodoRecurrence{Freq: "monthly", Interval: 1, MonthDay: 31},
+			"2026-01-31", 1, "2026-02-28", true},
+		{"monthly_recovers", model.TodoRecurrence{Freq: "monthly", Interval: 1, MonthDay: 31},
+			"2026-02-28", 2, "2026-03-31", true},
+		{"quarterly", model.TodoRecurrence{Freq: "monthly", Interval: 3, MonthDay: 15},
+			"2026-11-15", 1, "2027-02-15", true},
+		{"count_reached", model.TodoRecurrence{Freq: "daily", Interval: 1, Count: 2}, "2026-04-28", 2, "", false},
+		{"until_passed", model.TodoRecurrence{Freq: "daily", Interval: 7, Until: "2026-05-04"},
+			"2026-04-28", 1, "", false},
+		{"until_inclusive", model.TodoRecurrence{Freq: "daily", Interval: 6, Until: "2026-05-04"},
+			"2026-04-28", 1, "2026-05-04", true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule := tc.rule
+			got, ok := nextTodoOccurrence(&rule, tc.due, tc.occurrence)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+	_, ok := nextTodoOccurrence(nil, "2026-04-28", 1)
+	assert.False(t, ok)
+}
+
+func TestExpandTodoOccurrences(t *testing.T) {
+	head := model.Todo{
+		ID:         "t1",
+		Content:    "standup",
+		DueDate:    "2026-04-20",
+		Done:       1,
+		Recurrence: &model.TodoRecurrence{Freq: "daily", Interval: 2, Count: 6},
+		SeriesID:   "t1",
+		Occurrence: 1,
+	}
+	items := expandTodoOccurrences(head, "2026-04-25", "2026-05-31")
+	require.Len(t, items, 3)
+	assert.Equal(t, "2026-04-26", items[0].DueDate)
+	assert.Equal(t, "t1@2026-04-26", items[0].ID)
+	assert.Equal(t, 4, items[0].Occurrence)
+	assert.True(t, items[0].Virtual)
+	assert.Zero(t, items[0].Done)
+	assert.Equal(t, "2026-04-30", items[2].DueDate)
+	assert.Equal(t, 6, items[2].Occurrence)
+}
@@ -3,7 +3,9 @@ package service
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/xxxsen/mnote/internal/model"
@@ -11,22 +13,89 @@ import (
 	"github.com/xxxsen/mnote/internal/pkg/timeutil"
 )
 
+const (
+	maxTodoListLimit    = 500
+	maxTodoContentRunes = 500
+)
+
+type todoDocumentRepo interface {
+	GetByID(ctx context.Context, userID, docID string) (*model.Document, error)
+}
+
+// todoDocumentWriter saves a document the way the editor does, so a task
+// ticked on the todo list gets a version and its todos re-synced.
+type todoDocumentWriter interface {
+	Save(ctx context.Context, userID, docID string, input DocumentUpdateInput) (*model.SaveDocumentResult, error)
+}
+
 type TodoService struct {
 	todos   todoRepo
+	docs    todoDocumentRepo
+	writer  todoDocumentWriter
 	runtime Runtime
 }
 
-func NewTodoService(todos todoRepo, runtime Runtime) *TodoService {
-	return &TodoService{todos: todos, runtime: prepareRuntime(runtime)}
+// TodoCreateInput describes a new todo. DueDate may be empty for undated
+// todos; ParentID makes it a subtask of a top-level todo.
+type TodoCreateInput struct {
+	Content    string
+	DueDate    string
+	Done       bool
+	Priority   int
+	DocumentID string
+	ParentID   string
+	Recurrence *model.TodoRecurrence
+}
+
+// TodoAttributesInput updates priority and the linked document; nil fields
+// are left unchanged and an empty DocumentID removes the link.
+type TodoAttributesInput struct {
+	Priority   *int
+	DocumentID *string
+}
+
+// NewTodoService builds the todo service. writer may be nil, in which case
+// todos synced from documents are toggled without touching the document.
+func NewTodoService(
+	todos todoRepo, docs todoDocumentRepo, writer todoDocumentWriter, runtime Runtime,
+) *TodoService {
+	return &TodoService{todos: todos, docs: docs, writer: writer, runtime: prepareRuntime(runtime)}
 }
 
-func (s *TodoService) CreateTodo(ctx context.Context, userID, content, dueDate string, done bool) (*model.Todo, error) {
-	content = strings.TrimSpace(content)
-	if content == "" || utf8.RuneCountInString(content) > 500 {
+func (s *TodoService) CreateTodo(ctx context.Context, userID string, input TodoCreateInput) (*model.Todo, error) {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return nil, err
+	}
+	content := strings.TrimSpace(input.Content)
+	if content == "" || utf8.RuneCountInString(content) > maxTodoContentRunes {
 		return nil, appErr.ErrInvalid
 	}
+	if !validTodoPriority(input.Priority) {
+		return nil, appErr.ErrInvalid
+	}
+	rule, err := normalizeTodoRecurrence(input.Recurrence, input.DueDate)
+	if err != nil {
+		return nil, err
+	}
+	documentID, err := s.resolveDocumentID(ctx, userID, input.DocumentID)
+	if err != nil {
+		return nil, err
+	}
+	parentID := strings.TrimSpace(input.ParentID)
+	if parentID != "" {
+		if rule != nil {
+			return nil, appErr.ErrInvalid
+		}
+		parent, err := s.todos.GetByID(ctx, userID, parentID)
+		if err != nil {
+			return nil, fmt.Errorf("get parent todo: %w", err)
+		}
+		if parent.ParentID != "" {
+			return nil, appErr.ErrInvalid
+		}
+	}
 	doneVal := 0
-	if done {
+	if input.Done {
 		doneVal = 1
 	}
 	id, err := s.runtime.IDs.ID()
@@ -35,13 +104,21 @@ func (s *TodoService) CreateTodo(ctx context.Context, userID, content, dueDate s
 	}
 	now := timeutil.NowUnix()
 	todo := &model.Todo{
-		ID:      id,
-		UserID:  userID,
-		Content: content,
-		DueDate: dueDate,
-		Done:    doneVal,
-		Ctime:   now,
-		Mtime:   now,
+		ID:         id,
+		UserID:     userID,
+		Content:    content,
+		DueDate:    input.DueDate,
+		Done:       doneVal,
+		Priority:   input.Priority,
+		DocumentID: documentID,
+		ParentID:   parentID,
+		Recurrence: rule,
+		Ctime:      now,
+		Mtime:      now,
+	}
+	if rule != nil {
+		todo.SeriesID = id
+		todo.Occurrence = 1
 	}
 	if err := s.todos.Create(ctx, todo); err != nil {
 		return nil, fmt.Errorf("create todo: %w", err)
@@ -49,21 +126,221 @@ func (s *TodoService) CreateTodo(ctx context.Context, userID, content, dueDate s
 	return todo, nil
 }
 
+// ToggleDone marks a todo done or not done. Completing a recurring todo also
+// creates the next occurrence of its series, exactly once: reopening and
+// completing it again does not generate a second successor. For subtasks the
+// parent follows its children: it is completed when the last open subtask is
+// done and reopened when a subtask is reopened. A todo synced from a document
+// is toggled by saving the document with its checkbox changed.
 func (s *TodoService) ToggleDone(ctx context.Context, userID, todoID string, done bool) error {
-	doneVal := 0
-	if done {
-		doneVal = 1
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return err
 	}
-	now := timeutil.NowUnix()
-	if err := s.todos.UpdateDone(ctx, userID, todoID, doneVal, now); err != nil {
+	return s.runInTx(ctx, func(txCtx context.Context) error {
+		todo, err := s.todos.GetByID(txCtx, userID, todoID)
+		if err != nil {
+			return fmt.Errorf("get todo: %w", err)
+		}
+		now := timeutil.NowUnix()
+		if err := s.setDone(txCtx, userID, todo, done, now); err != nil {
+			return err
+		}
+		if todo.ParentID == "" {
+			return nil
+		}
+		return s.rollUpParent(txCtx, userID, todo.ParentID, now)
+	})
+}
+
+func (s *TodoService) setDone(ctx context.Context, userID string, todo *model.Todo, done bool, now int64) error {
+	if written, err := s.writeBackTask(ctx, userID, todo, done); err != nil || written {
+		return err
+	}
+	if !done {
+		if err := s.todos.UpdateDone(ctx, userID, todo.ID, 0, now); err != nil {
+			return fmt.Errorf("update done: %w", err)
+		}
+		return nil
+	}
+	if err := s.todos.UpdateDone(ctx, userID, todo.ID, 1, now); err != nil {
 		return fmt.Errorf("update done: %w", err)
 	}
+	if todo.NextID != "" {
+		return nil
+	}
+	nextDate, ok := nextTodoOccurrence(todo.Recurrence, todo.DueDate, max(todo.Occurrence, 1))
+	if !ok {
+		return nil
+	}
+	nextID, err := s.runtime.IDs.ID()
+	if err != nil {
+		return fmt.Errorf("generate todo id: %w", err)
+	}
+	claimed, err := s.todos.SetNextID(ctx, userID, todo.ID, nextID, now)
+	if err != nil {
+		return fmt.Errorf("set next id: %w", err)
+	}
+	if !claimed {
+		return nil
+	}
+	seriesID := todo.SeriesID
+	if seriesID == "" {
+		seriesID = todo.ID
+	}
+	next := &model.Todo{
+		ID:         nextID,
+		UserID:     userID,
+		Content:    todo.Content,
+		DueDate:    nextDate,
+		Priority:   todo.Priority,
+		DocumentID: todo.DocumentID,
+		Recurrence: todo.Recurrence,
+		SeriesID:   seriesID,
+		Occurrence: max(todo.Occurrence, 1) + 1,
+		Ctime:      now,
+		Mtime:      now,
+	}
+	if err := s.todos.Create(ctx, next); err != nil {
+		return fmt.Errorf("create next occurrence: %w", err)
+	}
 	return nil
 }
 
+// writeBackTask ticks or clears the checkbox of a synced todo in its document
+// and saves the document, whose task sync then updates the todo. It reports
+// false when there is nothing to write: the todo is not synced, or its item
+// is gone or already in that state, and the todo is updated directly.
+func (s *TodoService) writeBackTask(ctx context.Context, userID string, todo *model.Todo, done bool) (bool, error) {
+	if s.writer == nil || todo.SourceAnchor == "" || todo.DocumentID == "" {
+		return false, nil
+	}
+	doc, err := s.docs.GetByID(ctx, userID, todo.DocumentID)
+	if err != nil {
+		return false, fmt.Errorf("get task document: %w", err)
+	}
+	for _, task := range documentTasks(doc.Content) {
+		if task.Anchor != todo.SourceAnchor {
+			continue
+		}
+		if task.Done == done {
+			return false, nil
+		}
+		result, err := s.writer.Save(ctx, userID, doc.ID, DocumentUpdateInput{
+			Title: doc.Title, Content: setTaskMark(doc.Content, task, done), BaseRevision: doc.ContentRevision,
+		})
+		if err != nil {
+			return false, fmt.Errorf("save task document: %w", err)
+		}
+		if !result.Accepted {
+			return false, appErr.ErrConflict
+		}
+		return true, nil
+	}
+	return false, nil
+}
+
+// rollUpParent completes parentID when all of its subtasks are done and
+// reopens it otherwise. A recurring parent is left alone, since completing it
+// would start its next occurrence without the subtasks, and so is a parent
+// whose last subtask was deleted.
+func (s *TodoService) rollUpParent(ctx context.Context, userID, parentID string, now int64) error {
+	parent, err := s.todos.GetByID(ctx, userID, parentID)
+	if err != nil {
+		return fmt.Errorf("get parent todo: %w", err)
+	}
+	if parent.Recurrence != nil {
+		return nil
+	}
+	counts, err := s.todos.CountSubtasks(ctx, userID, []string{parentID})
+	if err != nil {
+		return fmt.Errorf("count subtasks: %w", err)
+	}
+	progress := counts[parentID]
+	if progress.Total == 0 {
+		return nil
+	}
+	allDone := progress.Done == progress.Total
+	if allDone == (parent.Done == 1) {
+		return nil
+	}
+	return s.setDone(ctx, userID, parent, allDone, now)
+}
+
+// UpdateRecurrence sets or clears (nil) the rule on a series head. Only the
+// latest occurrence of a series can change its rule. Subtasks and todos
+// synced from a document cannot recur: the next occurrence would have no
+// task item to follow.
+func (s *TodoService) UpdateRecurrence(
+	ctx context.Context,
+	userID, todoID string,
+	recurrence *model.TodoRecurrence,
+) (*model.Todo, error) {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return nil, err
+	}
+	todo, err := s.todos.GetByID(ctx, userID, todoID)
+	if err != nil {
+		return nil, fmt.Errorf("get todo: %w", err)
+	}
+	if todo.NextID != "" || (recurrence != nil && (todo.ParentID != "" || todo.SourceAnchor != "")) {
+		return nil, appErr.ErrInvalid
+	}
+	rule, err := normalizeTodoRecurrence(recurrence, todo.DueDate)
+	if err != nil {
+		return nil, err
+	}
+	todo.Recurrence = rule
+	if rule != nil && todo.SeriesID == "" {
+		todo.SeriesID = todo.ID
+		todo.Occurrence = 1
+	}
+	todo.Mtime = timeutil.NowUnix()
+	if err := s.todos.Update(ctx, todo); err != nil {
+		return nil, fmt.Errorf("update todo: %w", err)
+	}
+	return todo, nil
+}
+
+func (s *TodoService) UpdateAttributes(
+	ctx context.Context,
+	userID, todoID string,
+	input TodoAttributesInput,
+) (*model.Todo, error) {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return nil, err
+	}
+	if input.Priority != nil && !validTodoPriority(*input.Priority) {
+		return nil, appErr.ErrInvalid
+	}
+	todo, err := s.todos.GetByID(ctx, userID, todoID)
+	if err != nil {
+		return nil, fmt.Errorf("get todo: %w", err)
+	}
+	if input.Priority != nil {
+		todo.Priority = *input.Priority
+	}
+	if input.DocumentID != nil {
+		documentID, err := s.resolveDocumentID(ctx, userID, *input.DocumentID)
+		if err != nil {
+			return nil, err
+		}
+		todo.DocumentID = documentID
+	}
+	todo.Mtime = timeutil.NowUnix()
+	if err := s.todos.Update(ctx, todo); err != nil {
+		return nil, fmt.Errorf("update todo: %w", err)
+	}
+	return todo, nil
+}
+
+// UpdateContent rewrites the text of a todo. A todo synced from a document
+// takes its text from the task item, so it is edited in the document.
 func (s *TodoService) UpdateContent(ctx context.Context, userID, todoID, content string) (*model.Todo, error) {
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return nil, err
+	}
 	newContent := strings.TrimSpace(content)
-	if newContent == "" || utf8.RuneCountInString(newContent) > 500 {
+	if newContent == "" || utf8.RuneCountInString(newContent) > maxTodoContentRunes {
 		return nil, appErr.ErrInvalid
 	}
 
@@ -75,6 +352,9 @@ func (s *TodoService) UpdateContent(ctx context.Context, userID, todoID, content
 	if todo.Content == newContent {
 		return todo, nil
 	}
+	if todo.SourceAnchor != "" {
+		return nil, appErr.ErrInvalid
+	}
 
 	now := timeutil.NowUnix()
 	todo.Content = newContent
@@ -86,12 +366,143 @@ func (s *TodoService) UpdateContent(ctx context.Context, userID, todoID, content
 	return todo, nil
 }
 
+// ListByDateRange returns stored todos in the range plus virtual occurrences
+// projected from recurring series heads, ordered by due date.
 func (s *TodoService) ListByDateRange(ctx context.Context, userID, startDate, endDate string) ([]model.Todo, error) {
-	v0, err := s.todos.ListByDateRange(ctx, userID, startDate, endDate)
+	items, err := s.todos.ListByDateRange(ctx, userID, startDate, endDate)
 	if err != nil {
 		return nil, fmt.Errorf("list by date range: %w", err)
 	}
-	return v0, nil
+	items, err = s.appendVirtualOccurrences(ctx, userID, items, model.TodoListQuery{
+		StartDate: startDate, EndDate: endDate,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.attachSubtaskProgress(ctx, userID, items)
+}
+
+// ListTodos serves the filtered todo list. A query with only a date range
+// behaves like ListByDateRange; other filters narrow both stored todos and
+// virtual occurrences.
+func (s *TodoService) ListTodos(ctx context.Context, userID string, query model.TodoListQuery) ([]model.Todo, error) {
+	query, err := s.normalizeTodoListQuery(query)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.todos.List(ctx, userID, query)
+	if err != nil {
+		return nil, fmt.Errorf("list todos: %w", err)
+	}
+	if query.StartDate != "" && query.EndDate != "" {
+		items, err = s.appendVirtualOccurrences(ctx, userID, items, query)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return s.attachSubtaskProgress(ctx, userID, items)
+}
+
+func (s *TodoService) normalizeTodoListQuery(query model.TodoListQuery) (model.TodoListQuery, error) {
+	for _, date := range []string{query.StartDate, query.EndDate, query.Today} {
+		if date == "" {
+			continue
+		}
+		if _, err := time.Parse(todoDateLayout, date); err != nil {
+			return query, appErr.ErrInvalid
+		}
+	}
+	if (query.StartDate == "") != (query.EndDate == "") || query.StartDate > query.EndDate {
+		return query, appErr.ErrInvalid
+	}
+	hasRange := query.StartDate != ""
+	if (query.Undated && (hasRange || query.Overdue)) || (query.Overdue && hasRange) {
+		return query, appErr.ErrInvalid
+	}
+	for _, priority := range query.Priorities {
+		if !validTodoPriority(priority) {
+			return query, appErr.ErrInvalid
+		}
+	}
+	query.Priorities = uniqueInts(query.Priorities)
+	query.DocumentID = strings.TrimSpace(query.DocumentID)
+	query.ParentID = strings.TrimSpace(query.ParentID)
+	if query.Overdue && query.Today == "" {
+		query.Today = s.runtime.Clock.Now().Format(todoDateLayout)
+	}
+	if !hasRange {
+		query.Limit = maxTodoListLimit
+	}
+	return query, nil
+}
+
+func (s *TodoService) appendVirtualOccurrences(
+	ctx context.Context,
+	userID string,
+	items []model.Todo,
+	query model.TodoListQuery,
+) ([]model.Todo, error) {
+	heads, err := s.todos.ListRecurringHeads(ctx, userID, query.EndDate)
+	if err != nil {
+		return nil, fmt.Errorf("list recurring heads: %w", err)
+	}
+	if len(heads) == 0 {
+		return items, nil
+	}
+	for _, head := range heads {
+		if !todoMatchesQuery(head, query) {
+			continue
+		}
+		items = append(items, expandTodoOccurrences(head, query.StartDate, query.EndDate)...)
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].DueDate < items[j].DueDate
+	})
+	return items, nil
+}
+
+func (s *TodoService) attachSubtaskProgress(
+	ctx context.Context,
+	userID string,
+	items []model.Todo,
+) ([]model.Todo, error) {
+	parentIDs := make([]string, 0)
+	for _, item := range items {
+		if item.ParentID == "" && !item.Virtual {
+			parentIDs = append(parentIDs, item.ID)
+		}
+	}
+	if len(parentIDs) == 0 {
+		return items, nil
+	}
+	counts, err := s.todos.CountSubtasks(ctx, userID, parentIDs)
+	if err != nil {
+		return nil, fmt.Errorf("count subtasks: %w", err)
+	}
+	for index := range items {
+		if progress, ok := counts[items[index].ID]; ok && !items[index].Virtual {
+			items[index].Subtasks = &progress
+		}
+	}
+	return items, nil
+}
+
+func (s *TodoService) resolveDocumentID(ctx context.Context, userID, documentID string) (string, error) {
+	documentID = strings.TrimSpace(documentID)
+	if documentID == "" {
+		return "", nil
+	}
+	if _, err := s.docs.GetByID(ctx, userID, documentID); err != nil {
+		return "", fmt.Errorf("get linked document: %w", err)
+	}
+	return documentID, nil
+}
+
+func (s *TodoService) runInTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := s.runtime.Transactor.WithinTransaction(ctx, fn); err != nil {
+		return fmt.Errorf("run in tx: %w", err)
+	}
+	return nil
 }
 
 func (s *TodoService) GetByID(ctx context.Context, userID, todoID string) (*model.Todo, error) {
@@ -102,9 +513,65 @@ func (s *TodoService) GetByID(ctx context.Context, userID, todoID string) (*mode
 	return v0, nil
 }
 
+// DeleteTodo removes a todo together with its subtasks. Deleting a subtask
+// rolls its parent up as toggling one does, so removing the last open
+// subtask completes the parent.
 func (s *TodoService) DeleteTodo(ctx context.Context, userID, todoID string) error {
-	if err := s.todos.Delete(ctx, userID, todoID); err != nil {
-		return fmt.Errorf("delete: %w", err)
+	if err := requireWorkspaceWrite(ctx); err != nil {
+		return err
 	}
-	return nil
+	return s.runInTx(ctx, func(txCtx context.Context) error {
+		todo, err := s.todos.GetByID(txCtx, userID, todoID)
+		if err != nil {
+			return fmt.Errorf("get todo: %w", err)
+		}
+		if err := s.todos.DeleteByParent(txCtx, userID, todoID); err != nil {
+			return fmt.Errorf("delete subtasks: %w", err)
+		}
+		if err := s.todos.Delete(txCtx, userID, todoID); err != nil {
+			return fmt.Errorf("delete: %w", err)
+		}
+		if todo.ParentID == "" {
+			return nil
+		}
+		return s.rollUpParent(txCtx, userID, todo.ParentID, timeutil.NowUnix())
+	})
+}
+
+func validTodoPriority(priority int) bool {
+	return priority >= model.TodoPriorityNone && priority <= model.TodoPriorityHigh
+}
+
+func todoMatchesQuery(todo model.Todo, query model.TodoListQuery) bool {
+	if query.DocumentID != "" && todo.DocumentID != query.DocumentID {
+		return false
+	}
+	if query.ParentID != "" && todo.ParentID != query.ParentID {
+		return false
+	}
+	if len(query.Priorities) == 0 {
+		return true
+	}
+	for _, priority := range query.Priorities {
+		if todo.Priority == priority {
+			return true
+		}
+	}
+	return false
+}
+
+func uniqueInts(values []int) []int {
+	if len(values) == 0 {
+		return nil
+	}
+	seen := make(map[int]struct{}, len(values))
+	out := make([]int, 0, len(values))
+	for _, value := range values {
+		if _, ok := seen[value]; ok {
+			continue
+		}
+		seen[value] = struct{}{}
+		out = append(out, value)
+	}
+	return out
 }
@@ -18,7 +18,12 @@ type mockTodoRepo struct {
 	updateDoneFn      func(ctx context.Context, userID, todoID string, done int, mtime int64) error
 	getByIDFn         func(ctx context.Context, userID, todoID string) (*model.Todo, error)
 	listByDateRangeFn func(ctx context.Context, userID, startDate, endDate string) ([]model.Todo, error)
+	listHeadsFn       func(ctx context.Context, userID, endDate string) ([]model.Todo, error)
+	setNextIDFn       func(ctx context.Context, userID, todoID, nextID string, mtime int64) (bool, error)
+	listFn            func(ctx context.Context, userID string, query model.TodoListQuery) ([]model.Todo, error)
+	countSubtasksFn   func(ctx context.Context, userID string, parentIDs []string) (map[string]model.TodoProgress, error)
 	deleteFn          func(ctx context.Context, userID, todoID string) error
+	deleteByParentFn  func(ctx context.Context, userID, parentID string) error
 }
 
 func (m *mockTodoRepo) Create(ctx context.Context, todo *model.Todo) error {
@@ -41,10 +46,43 @@ func (m *mockTodoRepo) ListByDateRange(ctx context.Context, userID, startDate, e
 	return m.listByDateRangeFn(ctx, userID, startDate, endDate)
 }
 
+func (m *mockTodoRepo) ListRecurringHeads(ctx context.Context, userID, endDate string) ([]model.Todo, error) {
+	if m.listHeadsFn == nil {
+		return nil, nil
+	}
+	return m.listHeadsFn(ctx, userID, endDate)
+}
+
+func (m *mockTodoRepo) SetNextID(ctx context.Context, userID, todoID, nextID string, mtime int64) (bool, error) {
+	return m.setNextIDFn(ctx, userID, todoID, nextID, mtime)
+}
+
+func (m *mockTodoRepo) List(ctx context.Context, userID string, query model.TodoListQuery) ([]model.Todo, error) {
+	return m.listFn(ctx, userID, query)
+}
+
+func (m *mockTodoRepo) CountSubtasks(
+	ctx context.Context,
+	userID string,
+	parentIDs []string,
+) (map[string]model.TodoProgress, error) {
+	if m.countSubtasksFn == nil {
+		return map[string]model.TodoProgress{}, nil
+	}
+	return m.countSubtasksFn(ctx, userID, parentIDs)
+}
+
 func (m *mockTodoRepo) Delete(ctx context.Context, userID, todoID string) error {
 	return m.deleteFn(ctx, userID, todoID)
 }
 
+func (m *mockTodoRepo) DeleteByParent(ctx context.Context, userID, parentID string) error {
+	if m.deleteByParentFn == nil {
+		return nil
+	}
+	return m.deleteByParentFn(ctx, userID, parentID)
+}
+
 func TestTodoService_CreateTodo(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		repo := &mockTodoRepo{
@@ -56,8 +94,8 @@ func TestTodoService_CreateTodo(t *testing.T) {
 				return nil
 			},
 		}
-		svc := NewTodoService(repo, testRuntime())
-		todo, err := svc.CreateTodo(context.Background(), "u1", "buy milk", "2026-04-28", false)
+		svc := NewTodoService(repo, &mockDocumentRepo{}, nil, testRuntime())
+		todo, err := svc.CreateTodo(context.Background(), "u1", TodoCreateInput{Content: "buy milk", DueDate: "2026-04-28"})
 		require.NoError(t, err)
 		assert.Equal(t, "buy milk", todo.Content)
 		assert.Equal(t, "u1", todo.UserID)
@@ -71,15 +109,15 @@ func TestTodoService_CreateTodo(t *testing.T) {
 				return nil
 			},
 		}
-		svc := NewTodoService(repo, testRuntime())
-		todo, err := svc.CreateTodo(context.Background(), "u1", "done item", "", true)
+		svc := NewTodoService(repo, &mockDocumentRepo{}, nil, testRuntime())
+		todo, err := svc.CreateTodo(context.Background(), "u1", TodoCreateInput{Content: "done item", Done: true})
 		require.NoError(t, err)
 		assert.Equal(t, 1, todo.Done)
 	})
 
 	t.Run("empty_content", func(t *testing.T) {
-		svc := NewTodoService(&mockTodoRepo{}, testRuntime())
-		_, err := svc.CreateTodo(context.Background(), "u1", "", "", false)
+		svc := NewTodoService(&mockTodoRepo{}, &mockDocumentRepo{}, nil, testRuntime())
+		_, err := svc.CreateTodo(context.Background(), "u1", TodoCreateInput{})
 		assert.ErrorIs(t, err, appErr.ErrInvalid)
 	})
 
@@ -89,8 +127,8 @@ func TestTodoService_CreateTodo(t *testing.T) {
 				return errors.New("db error")
 			},
 		}
-		svc := NewTodoService(repo, testRuntime())
-		_, err := svc.CreateTodo(context.Background(), "u1", "buy milk", "", false)
+		svc := NewTodoService(repo, &mockDocumentRepo{}, nil, testRuntime())
+		_, err := svc.CreateTodo(context.Background(), "u1", TodoCreateInput{Content: "buy milk"})
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "create todo")
 	})
@@ -99,6 +137,9 @@ func TestTodoService_CreateTodo(t *testing.T) {
 func TestTodoService_ToggleDone(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		repo := &mockTodoRepo{
+			getByIDFn: func(context.Context, string, string) (*model.Todo, error) {
+				return &model.Todo{ID: "t1", DueDate: "2026-04-28"}, nil
+			},
 			updateDoneFn: func(_ context.Context, userID, todoID string, done int, _ int64) error {
 				assert.Equal(t, "u1", userID)
 				assert.Equal(t, "t1", todoID)
@@ -106,23 +147,97 @@ func TestTodoService_ToggleDone(t *testing.T) {
 				return nil
 			},
 		}
-		svc := NewTodoService(repo, testRuntime())
+		svc := NewTodoService(repo, &mockDocumentRepo{}, nil, testRuntime())
 		err := svc.ToggleDone(context.Background(), "u1", "t1", true)
 		require.NoError(t, err)
 	})
 
 	t.Run("repo_error", func(t *testing.T) {
 		repo := &mockTodoRepo{
+			getByIDFn: func(context.Context, string, string) (*model.Todo, error) {
+				return &model.Todo{ID: "t1", UserID: "u1"}, nil
+			},
 			updateDoneFn: func(context.Context, string, string, int, int64) error {
 				return errors.New("db error")
 			},
 		}
-		svc := NewTodoService(repo, testRuntime())
+		svc := NewTodoService(repo, &mockDocumentRepo{}, nil, testRuntime())
 		err := svc.ToggleDone(context.Background(), "u1", "t1", false)
 		assert.Error(t, err)
 	})
 }
 
+type fakeTodoDocumentWriter struct {
+	inputs []DocumentUpdateInput
+	result *model.SaveDocumentResult
+}
+
+func (w *fakeTodoDocumentWriter) Save(
+	_ context.Context, _, _ string, input DocumentUpdateInput,
+) (*model.SaveDocumentResult, error) {
+	w.inputs = append(w.inputs, input)
+	return w.result, nil
+}
+
+func TestTodoService_ToggleDone_DocumentTask(t *testing.T) {
+	content := "# Plan\n- [ ] write @2025-11-01\n- [x] review\n"
+	tasks := documentTasks(content)
+	docs := &mockDocumentRepo{
+		getByIDFn: func(_ context.Context, _, docID string) (*model.Document, error) {
+			return &model.Document{ID: docID, Title: "Plan", Content: content, ContentRevision: 4}, nil
+		},
+	}
+	newRepo := func(anchor string, updated *int) *mockTodoRepo {
+		return &mockTodoRepo{
+			getByIDFn: func(context.Context, string, string) (*model.Todo, error) {
+				return &model.Todo{ID: "t1", DocumentID: "d1", SourceAnchor: anchor}, nil
+			},
+			updateDoneFn: func(context.Context, string, string, int, int64) error {
+				*updated++
+				return nil
+			},
+		}
+	}
+
+	t.Run("writes_checkbox", func(t *testing.T) {
+		updated := 0
+		writer := &fakeTodoDocumentWriter{result: &model.SaveDocumentResult{Accepted: true}}
+		svc := NewTodoService(newRepo(tasks[0].Anchor, &updated), docs, writer, testRuntime())
+		require.NoError(t, svc.ToggleDone(context.Background(), "u1", "t1", true))
+		require.Len(t, writer.inputs, 1)
+		assert.Equal(t, "# Plan\n- [x] write @2025-11-01\n- [x] review\n", writer.inputs[0].Content)
+		assert.Equal(t, "Plan", writer.inputs[0].Title)
+		assert.Equal(t, int64(4), writer.inputs[0].BaseRevision)
+		assert.Zero(t, updated, "the save syncs the todo")
+	})
+
+	t.Run("already_in_state", func(t *testing.T) {
+		updated := 0
+		writer := &fakeTodoDocumentWriter{}
+		svc := NewTodoService(newRepo(tasks[1].Anchor, &updated), docs, writer, testRuntime())
+		require.NoError(t, svc.ToggleDone(context.Background(), "u1", "t1", true))
+		assert.Empty(t, writer.inputs)
+		assert.Equal(t, 1, updated)
+	})
+
+	t.Run("item_gone", func(t *testing.T) {
+		updated := 0
+		writer := &fakeTodoDocumentWriter{}
+		svc := NewTodoService(newRepo("gone", &updated), docs, writer, testRuntime())
+		require.NoError(t, svc.ToggleDone(context.Background(), "u1", "t1", false))
+		assert.Empty(t, writer.inputs)
+		assert.Equal(t, 1, updated)
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		updated := 0
+		writer := &fakeTodoDocumentWriter{result: &model.SaveDocumentResult{Accepted: false}}
+		svc := NewTodoService(newRepo(tasks[0].Anchor, &updated), docs, writer, testRuntime())
+		err := svc.ToggleDone(context.Background(), "u1", "t1", true)
+		assert.ErrorIs(t, err, appErr.ErrConflict)
+	})
+}
+
 func TestTodoService_UpdateContent(t *testing.T) {
 	t.Run("success", func(t *testing.T) {
 		repo := &mockTodoRepo{
@@ -134,7 +249,7 @@ func TestTodoService_UpdateContent(t *testing.T) {
 				return nil
 			},
 		}
-		svc := NewTodoService(repo, testRuntime())
+		svc := NewTodoService(repo, &mockDocumentRepo{}, nil, testRuntime())
 		todo, err := svc.UpdateContent(context.Background(), "u1", "t1", "new content")
 		require.NoError(t, err)
 		assert.Equal(t, "new content", todo.Content)
@@ -146,25 +261,45 @@ func TestTodoService_UpdateContent(t *testing.T) {
 				return &model.Todo{ID: "t1", Content: "same"}, nil
 			},
 		}
-		svc := NewTodoService(repo, testRuntime())
+		svc := NewTodoService(repo, &mockDocumentRepo{}, nil, testRuntime())
 		todo, err := svc.UpdateContent(context.Background(), "u1", "t1", "same")
 		require.NoError(t, err)
 		assert.Equal(t, "same", todo.Content)
 	})
 
 	t.Run("empty_content", func(t *testing.T) {
-		svc := NewTodoService(&mockTodoRepo{}, testRuntime())
+		svc := NewTodoService(&mockTodoRepo{}, &mockDocumentRepo{}, nil, testRuntime())
 		_, err := svc.UpdateContent(context.Background(), "u1", "t1", "   ")
 		assert.ErrorIs(t, err, appErr.ErrInvalid)
 	})
 
+	t.Run("synced_todo", func(t *testing.T) {
+		repo := &mockTodoRepo{
+			getByIDFn: func(context.Context, string, string) (*model.Todo, error) {
+				return &model.Todo{ID: "t1", Content: "old", DocumentID: "d1", SourceAnchor: "a1"}, nil
+			},
+		}
+		svc := NewTodoService(repo, &mockDocumentRepo{}, nil, testRuntime())
+		_, err := svc.UpdateContent(context.Background(), "u1", "t1", "new content")
+		assert.ErrorIs(t, err, appErr.ErrInvalid)
+	})
+
+	t.Run("read_only_workspace", func(t *testing.T) {
+		svc := NewTodoService(&mockTodoRepo{}, &mockDocumentRepo{}, nil, testRuntime())
+		ctx := WithWorkspaceAccess(context.Background(), WorkspaceAccess{
+			WorkspaceID: "w1", UserID: "u2", Role: model.WorkspaceRoleViewer,
+		})
+		_, err := svc.UpdateContent(ctx, "w1", "t1", "new content")
+		assert.ErrorIs(t, err, appErr.ErrForbidden)
+	})
+
 	t.Run("get_error", func(t *testing.T) {
 		repo := &mockTodoRepo{
 			getByIDFn: func(context.Context, string, string) (*model.Todo, error) {
 				return nil, errors.New("not found")
 			},
 		}
-		svc := NewTodoService(repo, testRuntime())
+		svc := NewTodoService(repo, &mockDocumentRepo{}, nil, testRuntime())
 		_, err := svc.UpdateContent(context.Background(), "u1", "t1", "new")
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "get todo")
@@ -179,7 +314,7 @@ func TestTodoService_UpdateContent(t *testing.T) {
 				return errors.New("db error")
 			},
 		}
-		svc := NewTodoService(repo, testRuntime())
+		svc := NewTodoService(repo, &mockDocumentRepo{}, nil, testRuntime())
 		_, err := svc.UpdateContent(context.Background(), "u1", "t1", "new")
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "update todo")
@@ -194,7 +329,7 @@ func TestTodoService_ListByDateRange(t *testing.T) {
 				return []model.Todo{{ID: "t1"}}, nil
 			},
 		}
-		svc := NewTodoService(repo, testRuntime())
+		svc := NewTodoService(repo, &mockDocumentRepo{}, nil, testRuntime())
 		list, err := svc.ListByDateRange(context.Background(), "u1", "2026-01-01", "2026-12-31")
 		require.NoError(t, err)
 		assert.Len(t, list, 1)
@@ -206,7 +341,7 @@ func TestTodoService_ListByDateRange(t *testing.T) {
 				return nil, errors.New("db error")
 			},
 		}
-		svc := NewTodoService(repo, testRuntime())
+		svc := NewTodoService(repo, &mockDocumentRepo{}, nil, testRuntime())
 		_, err := svc.ListByDateRange(context.Background(), "u1", "2026-01-01", "2026-12-31")
 		assert.Error(t, err)
 	})
@@ -219,7 +354,7 @@ func TestTodoService_GetByID(t *testing.T) {
 				return &model.Todo{ID: "t1", Content: "hello"}, nil
 			},
 		}
-		svc := NewTodoService(repo, testRuntime())
+		svc := NewTodoService(repo, &mockDocumentRepo{}, nil, testRuntime())
 		todo, err := svc.GetByID(context.Background(), "u1", "t1")
 		require.NoError(t, err)
 		assert.Equal(t, "t1", todo.ID)
@@ -231,35 +366,428 @@ func TestTodoService_GetByID(t *testing.T) {
 				return nil, appErr.ErrNotFound
 			},
 		}
-		svc := NewTodoService(repo, testRuntime())
+		svc := NewTodoService(repo, &mockDocumentRepo{}, nil, testRuntime())
 		_, err := svc.GetByID(context.Background(), "u1", "t1")
 		assert.ErrorIs(t, err, appErr.ErrNotFound)
 	})
 }
 
 func TestTodoService_DeleteTodo(t *testing.T) {
+	getTodo := func(_ context.Context, _, todoID string) (*model.Todo, error) {
+		return &model.Todo{ID: todoID}, nil
+	}
+
 	t.Run("success", func(t *testing.T) {
 		repo := &mockTodoRepo{
+			getByIDFn: getTodo,
 			deleteFn: func(_ context.Context, userID, todoID string) error {
 				assert.Equal(t, "u1", userID)
 				assert.Equal(t, "t1", todoID)
 				return nil
 			},
 		}
-		svc := NewTodoService(repo, testRuntime())
+		svc := NewTodoService(repo, &mockDocumentRepo{}, nil, testRuntime())
 		err := svc.DeleteTodo(context.Background(), "u1", "t1")
 		require.NoError(t, err)
 	})
 
 	t.Run("repo_error", func(t *testing.T) {
 		repo := &mockTodoRepo{
+			getByIDFn: getTodo,
 			deleteFn: func(context.Context, string, string) error {
 				return errors.New("db error")
 			},
 		}
-		svc := NewTodoService(repo, testRuntime())
+		svc := NewTodoService(repo, &mockDocumentRepo{}, nil, testRuntime())
 		err := svc.DeleteTodo(context.Background(), "u1", "t1")
 		assert.Error(t, err)
 		assert.Contains(t, err.Error(), "delete")
 	})
+
+	t.Run("last_open_subtask_completes_parent", func(t *testing.T) {
+		updates := map[string]int{}
+		repo := &mockTodoRepo{
+			getByIDFn: func(_ context.Context, _, todoID string) (*model.Todo, error) {
+				if todoID == "p1" {
+					return &model.Todo{ID: "p1"}, nil
+				}
+				return &model.Todo{ID: todoID, ParentID: "p1"}, nil
+			},
+			deleteFn: func(context.Context, string, string) error { return nil },
+			updateDoneFn: func(_ context.Context, _, todoID string, done int, _ int64) error {
+				updates[todoID] = done
+				return nil
+			},
+			countSubtasksFn: func(context.Context, string, []string) (map[string]model.TodoProgress, error) {
+				return map[string]model.TodoProgress{"p1": {Total: 1, Done: 1}}, nil
+			},
+		}
+		svc := NewTodoService(repo, &mockDocumentRepo{}, nil, testRuntime())
+		require.NoError(t, svc.DeleteTodo(context.Background(), "u1", "c2"))
+		assert.Equal(t, map[string]int{"p1": 1}, updates)
+	})
+
+	t.Run("last_subtask_keeps_parent", func(t *testing.T) {
+		repo := &mockTodoRepo{
+			getByIDFn: func(_ context.Context, _, todoID string) (*model.Todo, error) {
+				if todoID == "p1" {
+					return &model.Todo{ID: "p1", Done: 1}, nil
+				}
+				return &model.Todo{ID: todoID, ParentID: "p1"}, nil
+			},
+			deleteFn: func(context.Context, string, string) error { return nil },
+			countSubtasksFn: func(context.Context, string, []string) (map[string]model.TodoProgress, error) {
+				return map[string]model.TodoProgress{}, nil
+			},
+		}
+		svc := NewTodoService(repo, &mockDocumentRepo{}, nil, testRuntime())
+		require.NoError(t, svc.DeleteTodo(context.Background(), "u1", "c1"))
+	})
+}
+
+func TestTodoService_CreateTodo_Recurring(t *testing.T) {
+	var created *model.Todo
+	repo := &mockTodoRepo{
+		createFn: func(_ context.Context, todo *model.Todo) error {
+			created = todo
+			return nil
+		},
+	}
+	svc := NewTodoService(repo, &mockDocumentRepo{}, nil, testRuntime())
+	todo, err := svc.CreateTodo(context.Background(), "u1", TodoCreateInput{
+		Content: "review", DueDate: "2026-04-27", Recurrence: &model.TodoRecurrence{Freq: "weekly"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, todo.ID, created.SeriesID)
+	assert.Equal(t, 1, created.Occurrence)
+	assert.Equal(t, []int{1}, created.Recurrence.Weekdays)
+
+	_, err = svc.CreateTodo(context.Background(), "u1", TodoCreateInput{
+		Content: "review", Recurrence: &model.TodoRecurrence{Freq: "daily"},
+	})
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+}
+
+func TestTodoService_ToggleDone_Recurring(t *testing.T) {
+	rule := &model.TodoRecurrence{Freq: "daily", Interval: 7, Count: 3}
+	newRepo := func(todo *model.Todo, claimed bool, created *[]model.Todo) *mockTodoRepo {
+		return &mockTodoRepo{
+			getByIDFn: func(context.Context, string, string) (*model.Todo, error) {
+				return todo, nil
+			},
+			updateDoneFn: func(context.Context, string, string, int, int64) error { return nil },
+			setNextIDFn: func(_ context.Context, _, todoID, _ string, _ int64) (bool, error) {
+				assert.Equal(t, todo.ID, todoID)
+				return claimed, nil
+			},
+			createFn: func(_ context.Context, next *model.Todo) error {
+				*created = append(*created, *next)
+				return nil
+			},
+		}
+	}
+
+	t.Run("generates_next", func(t *testing.T) {
+		var created []model.Todo
+		head := &model.Todo{
+			ID: "t1", Content: "water plants", DueDate: "2026-04-28",
+			Recurrence: rule, SeriesID: "t1", Occurrence: 1,
+		}
+		svc := NewTodoService(newRepo(head, true, &created), &mockDocumentRepo{}, nil, testRuntime())
+		require.NoError(t, svc.ToggleDone(context.Background(), "u1", "t1", true))
+		require.Len(t, created, 1)
+		assert.Equal(t, "2026-05-05", created[0].DueDate)
+		assert.Equal(t, "water plants", created[0].Content)
+		assert.Equal(t, "t1", created[0].SeriesID)
+		assert.Equal(t, 2, created[0].Occurrence)
+		assert.Equal(t, rule, created[0].Recurrence)
+		assert.Zero(t, created[0].Done)
+	})
+
+	t.Run("already_generated", func(t *testing.T) {
+		var created []model.Todo
+		head := &model.Todo{ID: "t1", DueDate: "2026-04-28", Recurrence: rule, Occurrence: 1, NextID: "t2"}
+		svc := NewTodoService(newRepo(head, true, &created), &mockDocumentRepo{}, nil, testRuntime())
+		require.NoError(t, svc.ToggleDone(context.Background(), "u1", "t1", true))
+		assert.Empty(t, created)
+	})
+
+	t.Run("lost_claim", func(t *testing.T) {
+		var created []model.Todo
+		head := &model.Todo{ID: "t1", DueDate: "2026-04-28", Recurrence: rule, Occurrence: 1}
+		svc := NewTodoService(newRepo(head, false, &created), &mockDocumentRepo{}, nil, testRuntime())
+		require.NoError(t, svc.ToggleDone(context.Background(), "u1", "t1", true))
+		assert.Empty(t, created)
+	})
+
+	t.Run("series_ended", func(t *testing.T) {
+		var created []model.Todo
+		head := &model.Todo{ID: "t3", DueDate: "2026-05-12", Recurrence: rule, Occurrence: 3}
+		svc := NewTodoService(newRepo(head, true, &created), &mockDocumentRepo{}, nil, testRuntime())
+		require.NoError(t, svc.ToggleDone(context.Background(), "u1", "t3", true))
+		assert.Empty(t, created)
+	})
+}
+
+func TestTodoService_ListByDateRange_ExpandsRecurring(t *testing.T) {
+	repo := &mockTodoRepo{
+		listByDateRangeFn: func(context.Context, string, string, string) ([]model.Todo, error) {
+			return []model.Todo{
+				{ID: "t1", DueDate: "2026-05-01", Recurrence: &model.TodoRecurrence{Freq: "daily", Interval: 2},
+					Occurrence: 1},
+				{ID: "t2", DueDate: "2026-05-02"},
+			}, nil
+		},
+		listHeadsFn: func(_ context.Context, _, endDate string) ([]model.Todo, error) {
+			assert.Equal(t, "2026-05-05", endDate)
+			return []model.Todo{
+				{ID: "t1", DueDate: "2026-05-01", Recurrence: &model.TodoRecurrence{Freq: "daily", Interval: 2},
+					Occurrence: 1},
+			}, nil
+		},
+	}
+	svc := NewTodoService(repo, &mockDocumentRepo{}, nil, testRuntime())
+	items, err := svc.ListByDateRange(context.Background(), "u1", "2026-05-01", "2026-05-05")
+	require.NoError(t, err)
+	ids := make([]string, 0, len(items))
+	for _, item := range items {
+		ids = append(ids, item.ID)
+	}
+	assert.Equal(t, []string{"t1", "t2", "t1@2026-05-03", "t1@2026-05-05"}, ids)
+}
+
+func TestTodoService_UpdateRecurrence(t *testing.T) {
+	t.Run("sets_rule", func(t *testing.T) {
+		var updated *model.Todo
+		repo := &mockTodoRepo{
+			getByIDFn: func(context.Context, string, string) (*model.Todo, error) {
+				return &model.Todo{ID: "t1", DueDate: "2026-04-15"}, nil
+			},
+			updateFn: func(_ context.Context, todo *model.Todo) error {
+				updated = todo
+				return nil
+			},
+		}
+		svc := NewTodoService(repo, &mockDocumentRepo{}, nil, testRuntime())
+		_, err := svc.UpdateRecurrence(context.Background(), "u1", "t1", &model.TodoRecurrence{Freq: "monthly"})
+		require.NoError(t, err)
+		assert.Equal(t, 15, updated.Recurrence.MonthDay)
+		assert.Equal(t, "t1", updated.SeriesID)
+		assert.Equal(t, 1, updated.Occurrence)
+	})
+
+	t.Run("rejects_past_occurrence", func(t *testing.T) {
+		repo := &mockTodoRepo{
+			getByIDFn: func(context.Context, string, string) (*model.Todo, error) {
+				return &model.Todo{ID: "t1", DueDate: "2026-04-15", NextID: "t2"}, nil
+			},
+		}
+		svc := NewTodoService(repo, &mockDocumentRepo{}, nil, testRuntime())
+		_, err := svc.UpdateRecurrence(context.Background(), "u1", "t1", nil)
+		assert.ErrorIs(t, err, appErr.ErrInvalid)
+	})
+
+	t.Run("rejects_synced_todo", func(t *testing.T) {
+		repo := &mockTodoRepo{
+			getByIDFn: func(context.Context, string, string) (*model.Todo, error) {
+				return &model.Todo{ID: "t1", DueDate: "2026-04-15", DocumentID: "d1", SourceAnchor: "a1"}, nil
+			},
+		}
+		svc := NewTodoService(repo, &mockDocumentRepo{}, nil, testRuntime())
+		_, err := svc.UpdateRecurrence(context.Background(), "u1", "t1", &model.TodoRecurrence{Freq: "daily"})
+		assert.ErrorIs(t, err, appErr.ErrInvalid)
+	})
+}
+
+func TestTodoService_CreateTodo_LinksAndSubtasks(t *testing.T) {
+	docs := &mockDocumentRepo{
+		getByIDFn: func(_ context.Context, _, docID string) (*model.Document, error) {
+			if docID == "d1" {
+				return &model.Document{ID: "d1"}, nil
+			}
+			return nil, appErr.ErrNotFound
+		},
+	}
+	parents := map[string]*model.Todo{
+		"p1": {ID: "p1"},
+		"c1": {ID: "c1", ParentID: "p1"},
+	}
+	var created *model.Todo
+	repo := &mockTodoRepo{
+		getByIDFn: func(_ context.Context, _, todoID string) (*model.Todo, error) {
+			if todo, ok := parents[todoID]; ok {
+				return todo, nil
+			}
+			return nil, appErr.ErrNotFound
+		},
+		createFn: func(_ context.Context, todo *model.Todo) error {
+			created = todo
+			return nil
+		},
+	}
+	svc := NewTodoService(repo, docs, nil, testRuntime())
+	ctx := context.Background()
+
+	_, err := svc.CreateTodo(ctx, "u1", TodoCreateInput{
+		Content: "step", Priority: model.TodoPriorityHigh, DocumentID: "d1", ParentID: "p1",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, model.TodoPriorityHigh, created.Priority)
+	assert.Equal(t, "d1", created.DocumentID)
+	assert.Equal(t, "p1", created.ParentID)
+
+	_, err = svc.CreateTodo(ctx, "u1", TodoCreateInput{Content: "x", Priority: 4})
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+	_, err = svc.CreateTodo(ctx, "u1", TodoCreateInput{Content: "x", DocumentID: "other"})
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+	_, err = svc.CreateTodo(ctx, "u1", TodoCreateInput{Content: "x", ParentID: "c1"})
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+	_, err = svc.CreateTodo(ctx, "u1", TodoCreateInput{
+		Content: "x", DueDate: "2026-05-01", ParentID: "p1", Recurrence: &model.TodoRecurrence{Freq: "daily"},
+	})
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+}
+
+func TestTodoService_ToggleDone_RollsUpParent(t *testing.T) {
+	newRepo := func(parentDone int, progress model.TodoProgress, updates map[string]int) *mockTodoRepo {
+		return &mockTodoRepo{
+			getByIDFn: func(_ context.Context, _, todoID string) (*model.Todo, error) {
+				if todoID == "p1" {
+					return &model.Todo{ID: "p1", Done: parentDone}, nil
+				}
+				return &model.Todo{ID: todoID, ParentID: "p1"}, nil
+			},
+			updateDoneFn: func(_ context.Context, _, todoID string, done int, _ int64) error {
+				updates[todoID] = done
+				return nil
+			},
+			countSubtasksFn: func(_ context.Context, _ string, parentIDs []string) (map[string]model.TodoProgress, error) {
+				assert.Equal(t, []string{"p1"}, parentIDs)
+				return map[string]model.TodoProgress{"p1": progress}, nil
+			},
+		}
+	}
+
+	t.Run("last_child_completes_parent", func(t *testing.T) {
+		updates := map[string]int{}
+		svc := NewTodoService(newRepo(0, model.TodoProgress{Total: 2, Done: 2}, updates), &mockDocumentRepo{}, nil, testRuntime())
+		require.NoError(t, svc.ToggleDone(context.Background(), "u1", "c2", true))
+		assert.Equal(t, map[string]int{"c2": 1, "p1": 1}, updates)
+	})
+
+	t.Run("open_child_keeps_parent", func(t *testing.T) {
+		updates := map[string]int{}
+		svc := NewTodoService(newRepo(0, model.TodoProgress{Total: 2, Done: 1}, updates), &mockDocumentRepo{}, nil, testRuntime())
+		require.NoError(t, svc.ToggleDone(context.Background(), "u1", "c2", true))
+		assert.Equal(t, map[string]int{"c2": 1}, updates)
+	})
+
+	t.Run("recurring_parent_left_alone", func(t *testing.T) {
+		updates := map[string]int{}
+		repo := newRepo(0, model.TodoProgress{Total: 2, Done: 2}, updates)
+		repo.getByIDFn = func(_ context.Context, _, todoID string) (*model.Todo, error) {
+			if todoID == "p1" {
+				return &model.Todo{ID: "p1", DueDate: "2026-05-01", Recurrence: &model.TodoRecurrence{Freq: "daily"}}, nil
+			}
+			return &model.Todo{ID: todoID, ParentID: "p1"}, nil
+		}
+		svc := NewTodoService(repo, &mockDocumentRepo{}, nil, testRuntime())
+		require.NoError(t, svc.ToggleDone(context.Background(), "u1", "c2", true))
+		assert.Equal(t, map[string]int{"c2": 1}, updates)
+	})
+
+	t.Run("reopened_child_reopens_parent", func(t *testing.T) {
+		updates := map[string]int{}
+		svc := NewTodoService(newRepo(1, model.TodoProgress{Total: 2, Done: 1}, updates), &mockDocumentRepo{}, nil, testRuntime())
+		require.NoError(t, svc.ToggleDone(context.Background(), "u1", "c2", false))
+		assert.Equal(t, map[string]int{"c2": 0, "p1": 0}, updates)
+	})
+}
+
+func TestTodoService_UpdateAttributes(t *testing.T) {
+	var updated *model.Todo
+	repo := &mockTodoRepo{
+		getByIDFn: func(context.Context, string, string) (*model.Todo, error) {
+			return &model.Todo{ID: "t1", Priority: 1, DocumentID: "d1"}, nil
+		},
+		updateFn: func(_ context.Context, todo *model.Todo) error {
+			updated = todo
+			return nil
+		},
+	}
+	svc := NewTodoService(repo, &mockDocumentRepo{}, nil, testRuntime())
+	priority, unlink := 3, ""
+	_, err := svc.UpdateAttributes(context.Background(), "u1", "t1", TodoAttributesInput{
+		Priority: &priority, DocumentID: &unlink,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 3, updated.Priority)
+	assert.Empty(t, updated.DocumentID)
+
+	invalid := -1
+	_, err = svc.UpdateAttributes(context.Background(), "u1", "t1", TodoAttributesInput{Priority: &invalid})
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+}
+
+func TestTodoService_ListTodos(t *testing.T) {
+	t.Run("overdue_defaults_today", func(t *testing.T) {
+		repo := &mockTodoRepo{
+			listFn: func(_ context.Context, _ string, query model.TodoListQuery) ([]model.Todo, error) {
+				assert.True(t, query.Overdue)
+				assert.Equal(t, "2026-05-01", query.Today)
+				assert.Equal(t, []int{3}, query.Priorities)
+				assert.Equal(t, maxTodoListLimit, query.Limit)
+				return []model.Todo{{ID: "p1"}, {ID: "c1", ParentID: "p1"}}, nil
+			},
+			countSubtasksFn: func(_ context.Context, _ string, parentIDs []string) (map[string]model.TodoProgress, error) {
+				assert.Equal(t, []string{"p1"}, parentIDs)
+				return map[string]model.TodoProgress{"p1": {Total: 1}}, nil
+			},
+		}
+		svc := NewTodoService(repo, &mockDocumentRepo{}, nil, testRuntimeAt(1777636800))
+		items, err := svc.ListTodos(context.Background(), "u1", model.TodoListQuery{
+			Overdue: true, Priorities: []int{3, 3},
+		})
+		require.NoError(t, err)
+		require.Len(t, items, 2)
+		assert.Equal(t, &model.TodoProgress{Total: 1}, items[0].Subtasks)
+		assert.Nil(t, items[1].Subtasks)
+	})
+
+	t.Run("range_filters_virtual", func(t *testing.T) {
+		rule := &model.TodoRecurrence{Freq: "daily"}
+		repo := &mockTodoRepo{
+			listFn: func(context.Context, string, model.TodoListQuery) ([]model.Todo, error) {
+				return nil, nil
+			},
+			listHeadsFn: func(context.Context, string, string) ([]model.Todo, error) {
+				return []model.Todo{
+					{ID: "h1", DueDate: "2026-05-01", Recurrence: rule, Occurrence: 1, Priority: 3},
+					{ID: "h2", DueDate: "2026-05-01", Recurrence: rule, Occurrence: 1},
+				}, nil
+			},
+		}
+		svc := NewTodoService(repo, &mockDocumentRepo{}, nil, testRuntime())
+		items, err := svc.ListTodos(context.Background(), "u1", model.TodoListQuery{
+			StartDate: "2026-05-02", EndDate: "2026-05-02", Priorities: []int{3},
+		})
+		require.NoError(t, err)
+		require.Len(t, items, 1)
+		assert.Equal(t, "h1@2026-05-02", items[0].ID)
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		svc := NewTodoService(&mockTodoRepo{}, &mockDocumentRepo{}, nil, testRuntime())
+		for _, query := range []model.TodoListQuery{
+			{StartDate: "2026-05-01"},
+			{StartDate: "2026-05-02", EndDate: "2026-05-01"},
+			{Undated: true, Overdue: true},
+			{Overdue: true, StartDate: "2026-05-01", EndDate: "2026-05-02"},
+			{Priorities: []int{7}},
+			{Today: "tomorrow"},
+		} {
+			_, err := svc.ListTodos(context.Background(), "u1", query)
+			assert.ErrorIs(t, err, appErr.ErrInvalid, "%+v", query)
+		}
+	})
 }
@@ -0,0 +1,356 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+const maxWorkspaceName = 100
+
+// WorkspaceAccess is the workspace a request acts in. Content of a team
+// workspace is stored with the workspace ID in place of a user ID, so the
+// scoped services keep receiving a single owner ID; the access carried in the
+// context tells them who is acting and with which role.
+type WorkspaceAccess struct {
+	WorkspaceID string
+	UserID      string
+	Role        string
+}
+
+type workspaceAccessKey struct{}
+
+// WithWorkspaceAccess returns a context that carries access.
+func WithWorkspaceAccess(ctx context.Context, access WorkspaceAccess) context.Context {
+	return context.WithValue(ctx, workspaceAccessKey{}, access)
+}
+
+func workspaceAccessFrom(ctx context.Context) (WorkspaceAccess, bool) {
+	access, ok := ctx.Value(workspaceAccessKey{}).(WorkspaceAccess)
+	return access, ok
+}
+
+// requireWorkspaceWrite rejects changes by a viewer. Calls that carry no
+// access act in the caller's personal workspace and are always allowed.
+func requireWorkspaceWrite(ctx context.Context) error {
+	access, ok := workspaceAccessFrom(ctx)
+	if !ok || model.CanWriteWorkspace(access.Role) {
+		return nil
+	}
+	return appErr.ErrForbidden
+}
+
+// actorID returns the user acting in ctx, or ownerID when the call is not
+// scoped to a team workspace.
+func actorID(ctx context.Context, ownerID string) string {
+	if access, ok := workspaceAccessFrom(ctx); ok && access.UserID != "" {
+		return access.UserID
+	}
+	return ownerID
+}
+
+// WorkspaceService manages team workspaces and their members. The personal
+// workspace of a user is implicit: its ID is the user ID and the user is its
+// only owner.
+type WorkspaceService struct {
+	workspaces workspaceRepo
+	users      userIdentityRepo
+	runtime    Runtime
+}
+
+func NewWorkspaceService(workspaces workspaceRepo, users userIdentityRepo, runtime Runtime) *WorkspaceService {
+	runtime = prepareRuntime(runtime)
+	return &WorkspaceService{workspaces: workspaces, users: users, runtime: runtime}
+}
+
+func (s *WorkspaceService) now() int64 {
+	return s.runtime.Clock.Now().Unix()
+}
+
+// Authorize returns the access userID has to workspaceID. An empty ID or the
+// user's own ID selects the personal workspace. Workspaces the user is not a
+// member of are reported as not found.
+func (s *WorkspaceService) Authorize(ctx context.Context, userID, workspaceID string) (WorkspaceAccess, error) {
+	if workspaceID == "" || workspaceID == userID {
+		return WorkspaceAccess{WorkspaceID: userID, UserID: userID, Role: model.WorkspaceRoleOwner}, nil
+	}
+	member, err := s.workspaces.GetMember(ctx, workspaceID, userID)
+	if err != nil {
+		return WorkspaceAccess{}, fmt.Errorf("get workspace member: %w", err)
+	}
+	return WorkspaceAccess{WorkspaceID: workspaceID, UserID: userID, Role: member.Role}, nil
+}
+
+// List returns the personal workspace followed by the team workspaces the
+// user belongs to.
+func (s *WorkspaceService) List(ctx context.Context, userID string) ([]model.Workspace, error) {
+	teams, err := s.workspaces.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list workspaces: %w", err)
+	}
+	items := make([]model.Workspace, 0, len(teams)+1)
+	items = append(items, model.Workspace{
+		ID: userID, OwnerID: userID, Personal: true, Role: model.WorkspaceRoleOwner,
+	})
+	return append(items, teams...), nil
+}
+
+func (s *WorkspaceService) Create(ctx context.Context, userID, name string) (*model.Workspace, error) {
+	name, err := normalizeWorkspaceName(name)
+	if err != nil {
+		return nil, err
+	}
+	id, err := s.runtime.IDs.ID()
+	if err != nil {
+		return nil, fmt.Errorf("generate workspace id: %w", err)
+	}
+	now := s.now()
+	workspace := &model.Workspace{
+		ID: id, Name: name, OwnerID: userID, Role: model.WorkspaceRoleOwner, Ctime: now, Mtime: now,
+	}
+	if err := s.runtime.Transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+		if err := s.workspaces.Create(txCtx, workspace); err != nil {
+			return fmt.Errorf("create workspace: %w", err)
+		}
+		if err := s.workspaces.AddMember(txCtx, &model.WorkspaceMember{
+			WorkspaceID: id, UserID: userID, Role: model.WorkspaceRoleOwner, Ctime: now, Mtime: now,
+		}); err != nil {
+			return fmt.Errorf("add owner: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("create workspace: %w", err)
+	}
+	return workspace, nil
+}
+
+func (s *WorkspaceService) Rename(ctx context.Context, userID, workspaceID, name string) (*model.Workspace, error) {
+	name, err := normalizeWorkspaceName(name)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireOwner(ctx, userID, workspaceID); err != nil {
+		return nil, err
+	}
+	if err := s.workspaces.UpdateName(ctx, workspaceID, name, s.now()); err != nil {
+		return nil, fmt.Errorf("rename workspace: %w", err)
+	}
+	workspace, err := s.workspaces.GetByID(ctx, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("get workspace: %w", err)
+	}
+	workspace.Role = model.WorkspaceRoleOwner
+	return workspace, nil
+}
+
+// Delete removes an empty team workspace. Content has to be deleted first so
+// that no document is left without members who can reach it.
+func (s *WorkspaceService) Delete(ctx context.Context, userID, workspaceID string) error {
+	if err := s.requireOwner(ctx, userID, workspaceID); err != nil {
+		return err
+	}
+	if err := s.runtime.Transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+		if _, err := s.workspaces.GetByIDForUpdate(txCtx, workspaceID); err != nil {
+			return fmt.Errorf("lock workspace: %w", err)
+		}
+		hasContent, err := s.workspaces.HasContent(txCtx, workspaceID)
+		if err != nil {
+			return fmt.Errorf("check workspace content: %w", err)
+		}
+		if hasContent {
+			return appErr.ErrConflict
+		}
+		if err := s.workspaces.Delete(txCtx, workspaceID); err != nil {
+			return fmt.Errorf("delete workspace: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("delete workspace: %w", err)
+	}
+	return nil
+}
+
+// ListMembers returns the members of a team workspace to any of its members.
+func (s *WorkspaceService) ListMembers(
+	ctx context.Context, userID, workspaceID string,
+) ([]model.WorkspaceMember, error) {
+	if workspaceID == userID {
+		return nil, appErr.ErrInvalid
+	}
+	if _, err := s.Authorize(ctx, userID, workspaceID); err != nil {
+		return nil, err
+	}
+	members, err := s.workspaces.ListMembers(ctx, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("list members: %w", err)
+	}
+	return members, nil
+}
+
+// AddMember adds the registered user with email to the workspace.
+func (s *WorkspaceService) AddMember(
+	ctx context.Context, userID, workspaceID, email, role string,
+) (*model.WorkspaceMember, error) {
+	if !validWorkspaceRole(role) {
+		return nil, appErr.ErrInvalid
+	}
+	if err := s.requireOwner(ctx, userID, workspaceID); err != nil {
+		return nil, err
+	}
+	user, err := lookupUserByEmail(ctx, s.users, email)
+	if err != nil {
+		return nil, err
+	}
+	now := s.now()
+	if err := s.workspaces.AddMember(ctx, &model.WorkspaceMember{
+		WorkspaceID: workspaceID, UserID: user.ID, Role: role, Ctime: now, Mtime: now,
+	}); err != nil {
+		return nil, fmt.Errorf("add member: %w", err)
+	}
+	return s.getMember(ctx, workspaceID, user.ID)
+}
+
+// UpdateMemberRole changes the role of a member. The last owner cannot be
+// demoted.
+func (s *WorkspaceService) UpdateMemberRole(
+	ctx context.Context, userID, workspaceID, memberID, role string,
+) (*model.WorkspaceMember, error) {
+	if !validWorkspaceRole(role) {
+		return nil, appErr.ErrInvalid
+	}
+	if err := s.requireOwner(ctx, userID, workspaceID); err != nil {
+		return nil, err
+	}
+	if err := s.runtime.Transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+		member, err := s.lockMember(txCtx, workspaceID, memberID)
+		if err != nil {
+			return err
+		}
+		if member.Role == model.WorkspaceRoleOwner && role != model.WorkspaceRoleOwner {
+			if err := s.keepOwner(txCtx, workspaceID); err != nil {
+				return err
+			}
+		}
+		if err := s.workspaces.UpdateMemberRole(txCtx, workspaceID, memberID, role, s.now()); err != nil {
+			return fmt.Errorf("update member role: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, fmt.Errorf("update member role: %w", err)
+	}
+	return s.getMember(ctx, workspaceID, memberID)
+}
+
+// RemoveMember removes a member. Owners may remove anyone and members may
+// leave on their own; the last owner can do neither.
+func (s *WorkspaceService) RemoveMember(ctx context.Context, userID, workspaceID, memberID string) error {
+	if memberID != userID {
+		if err := s.requireOwner(ctx, userID, workspaceID); err != nil {
+			return err
+		}
+	} else if workspaceID == userID {
+		return appErr.ErrInvalid
+	}
+	if err := s.runtime.Transactor.WithinTransaction(ctx, func(txCtx context.Context) error {
+		member, err := s.lockMember(txCtx, workspaceID, memberID)
+		if err != nil {
+			return err
+		}
+		if member.Role == model.WorkspaceRoleOwner {
+			if err := s.keepOwner(txCtx, workspaceID); err != nil {
+				return err
+			}
+		}
+		if err := s.workspaces.RemoveMember(txCtx, workspaceID, memberID); err != nil {
+			return fmt.Errorf("remove member: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("remove member: %w", err)
+	}
+	return nil
+}
+
+// requireOwner checks that userID owns the team workspace. The personal
+// workspace has no members to manage and is rejected as invalid.
+func (s *WorkspaceService) requireOwner(ctx context.Context, userID, workspaceID string) error {
+	if workspaceID == "" || workspaceID == userID {
+		return appErr.ErrInvalid
+	}
+	access, err := s.Authorize(ctx, userID, workspaceID)
+	if err != nil {
+		return err
+	}
+	if access.Role != model.WorkspaceRoleOwner {
+		return appErr.ErrForbidden
+	}
+	return nil
+}
+
+// lockMember locks the workspace row and returns the member so that role
+// changes of one workspace see a consistent owner count.
+func (s *WorkspaceService) lockMember(
+	ctx context.Context, workspaceID, memberID string,
+) (*model.WorkspaceMember, error) {
+	if _, err := s.workspaces.GetByIDForUpdate(ctx, workspaceID); err != nil {
+		return nil, fmt.Errorf("lock workspace: %w", err)
+	}
+	return s.getMember(ctx, workspaceID, memberID)
+}
+
+func (s *WorkspaceService) keepOwner(ctx context.Context, workspaceID string) error {
+	owners, err := s.workspaces.CountOwners(ctx, workspaceID)
+	if err != nil {
+		return fmt.Errorf("count owners: %w", err)
+	}
+	if owners <= 1 {
+		return appErr.ErrConflict
+	}
+	return nil
+}
+
+func (s *WorkspaceService) getMember(ctx context.Context, workspaceID, userID string) (*model.WorkspaceMember, error) {
+	member, err := s.workspaces.GetMember(ctx, workspaceID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("get member: %w", err)
+	}
+	return member, nil
+}
+
+// lookupUserByEmail finds the registered user an invitation addresses,
+// falling back to legacy accounts whose email was never normalized.
+func lookupUserByEmail(ctx context.Context, users userIdentityRepo, email string) (*model.User, error) {
+	normalized, err := NormalizeEmail(email)
+	if err != nil {
+		return nil, err
+	}
+	user, err := users.GetByNormalizedEmail(ctx, normalized)
+	if errors.Is(err, appErr.ErrNotFound) {
+		user, err = users.GetLegacyByExactEmail(ctx, strings.TrimSpace(email))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get user by email: %w", err)
+	}
+	return user, nil
+}
+
+func normalizeWorkspaceName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxWorkspaceName {
+		return "", appErr.ErrInvalid
+	}
+	return name, nil
+}
+
+func validWorkspaceRole(role string) bool {
+	switch role {
+	case model.WorkspaceRoleOwner, model.WorkspaceRoleEditor, model.WorkspaceRoleViewer:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/xxxsen/mnote/internal/model"
+	appErr "github.com/xxxsen/mnote/internal/pkg/errors"
+)
+
+// memoryWorkspaceRepo keeps workspaces and members in memory with the
+// not-found and conflict rules of the SQL repo.
+type memoryWorkspaceRepo struct {
+	workspaces map[string]*model.Workspace
+	members    map[string]map[string]*model.WorkspaceMember
+	content    map[string]bool
+}
+
+func newMemoryWorkspaceRepo() *memoryWorkspaceRepo {
+	return &memoryWorkspaceRepo{
+		workspaces: map[string]*model.Workspace{},
+		members:    map[string]map[string]*model.WorkspaceMember{},
+		content:    map[string]bool{},
+	}
+}
+
+func (m *memoryWorkspaceRepo) Create(_ context.Context, workspace *model.Workspace) error {
+	copied := *workspace
+	copied.Role = ""
+	m.workspaces[workspace.ID] = &copied
+	m.members[workspace.ID] = map[string]*model.WorkspaceMember{}
+	return nil
+}
+
+func (m *memoryWorkspaceRepo) GetByID(_ context.Context, id string) (*model.Workspace, error) {
+	workspace, ok := m.workspaces[id]
+	if !ok {
+		return nil, appErr.ErrNotFound
+	}
+	copied := *workspace
+	return &copied, nil
+}
+
+func (m *memoryWorkspaceRepo) GetByIDForUpdate(ctx context.Context, id string) (*model.Workspace, error) {
+	return m.GetByID(ctx, id)
+}
+
+func (m *memoryWorkspaceRepo) ListByUser(_ context.Context, userID string) ([]model.Workspace, error) {
+	items := make([]model.Workspace, 0)
+	for id, members := range m.members {
+		if member, ok := members[userID]; ok {
+			workspace := *m.workspaces[id]
+			workspace.Role = member.Role
+			items = append(items, workspace)
+		}
+	}
+	return items, nil
+}
+
+func (m *memoryWorkspaceRepo) UpdateName(_ context.Context, id, name string, mtime int64) error {
+	workspace, ok := m.workspaces[id]
+	if !ok {
+		return appErr.ErrNotFound
+	}
+	workspace.Name = name
+	workspace.Mtime = mtime
+	return nil
+}
+
+func (m *memoryWorkspaceRepo) Delete(_ context.Context, id string) error {
+	if _, ok := m.workspaces[id]; !ok {
+		return appErr.ErrNotFound
+	}
+	delete(m.workspaces, id)
+	delete(m.members, id)
+	return nil
+}
+
+func (m *memoryWorkspaceRepo) HasContent(_ context.Context, id string) (bool, error) {
+	return m.content[id], nil
+}
+
+func (m *memoryWorkspaceRepo) AddMember(_ context.Context, member *model.WorkspaceMember) error {
+	members, ok := m.members[member.WorkspaceID]
+	if !ok {
+		return appErr.ErrNotFound
+	}
+	if _, exists := members[member.UserID]; exists {
+		return appErr.ErrConflict
+	}
+	copied := *member
+	members[member.UserID] = &copied
+	return nil
+}
+
+func (m *memoryWorkspaceRepo) GetMember(_ context.Context, workspaceID, userID string) (*model.WorkspaceMember, error) {
+	member, ok := m.members[workspaceID][userID]
+	if !ok {
+		return nil, appErr.ErrNotFound
+	}
+	copied := *member
+	return &copied, nil
+}
+
+func (m *memoryWorkspaceRepo) ListMembers(_ context.Context, workspaceID string) ([]model.WorkspaceMember, error) {
+	items := make([]model.WorkspaceMember, 0)
+	for _, member := range m.members[workspaceID] {
+		items = append(items, *member)
+	}
+	return items, nil
+}
+
+func (m *memoryWorkspaceRepo) UpdateMemberRole(_ context.Context, workspaceID, userID, role string, mtime int64) error {
+	member, ok := m.members[workspaceID][userID]
+	if !ok {
+		return appErr.ErrNotFound
+	}
+	member.Role = role
+	member.Mtime = mtime
+	return nil
+}
+
+func (m *memoryWorkspaceRepo) RemoveMember(_ context.Context, workspaceID, userID string) error {
+	if _, ok := m.members[workspaceID][userID]; !ok {
+		return appErr.ErrNotFound
+	}
+	delete(m.members[workspaceID], userID)
+	return nil
+}
+
+func (m *memoryWorkspaceRepo) CountOwners(_ context.Context, workspaceID string) (int, error) {
+	count := 0
+	for _, member := range m.members[workspaceID] {
+		if member.Role == model.WorkspaceRoleOwner {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func newWorkspaceSvc(t *testing.T) (*WorkspaceService, *memoryWorkspaceRepo, *model.Workspace) {
+	t.Helper()
+	repo := newMemoryWorkspaceRepo()
+	users := &mockUserRepo{getByEmailFn: func(_ context.Context, email string) (*model.User, error) {
+		switch email {
+		case "b@example.com":
+			return &model.User{ID: "u2", Email: email}, nil
+		case "c@example.com":
+			return &model.User{ID: "u3", Email: email}, nil
+		}
+		return nil, appErr.ErrNotFound
+	}}
+	svc := NewWorkspaceService(repo, users, testRuntimeAt(1000))
+	workspace, err := svc.Create(context.Background(), "u1", " Team ")
+	require.NoError(t, err)
+	return svc, repo, workspace
+}
+
+func TestWorkspaceService_CreateAndList(t *testing.T) {
+	svc, _, workspace := newWorkspaceSvc(t)
+	assert.Equal(t, "Team", workspace.Name)
+
+	items, err := svc.List(context.Background(), "u1")
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.True(t, items[0].Personal)
+	assert.Equal(t, "u1", items[0].ID)
+	assert.Equal(t, workspace.ID, items[1].ID)
+	assert.Equal(t, model.WorkspaceRoleOwner, items[1].Role)
+
+	_, err = svc.Create(context.Background(), "u1", "  ")
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+}
+
+func TestWorkspaceService_Authorize(t *testing.T) {
+	svc, _, workspace := newWorkspaceSvc(t)
+	ctx := context.Background()
+
+	access, err := svc.Authorize(ctx, "u1", "")
+	require.NoError(t, err)
+	assert.Equal(t, WorkspaceAccess{WorkspaceID: "u1", UserID: "u1", Role: model.WorkspaceRoleOwner}, access)
+
+	_, err = svc.AddMember(ctx, "u1", workspace.ID, "b@example.com", model.WorkspaceRoleViewer)
+	require.NoError(t, err)
+	access, err = svc.Authorize(ctx, "u2", workspace.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.WorkspaceRoleViewer, access.Role)
+
+	_, err = svc.Authorize(ctx, "u3", workspace.ID)
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
+
+func TestWorkspaceService_MemberManagementRequiresOwner(t *testing.T) {
+	svc, _, workspace := newWorkspaceSvc(t)
+	ctx := context.Background()
+	_, err := svc.AddMember(ctx, "u1", workspace.ID, "b@example.com", model.WorkspaceRoleEditor)
+	require.NoError(t, err)
+
+	_, err = svc.AddMember(ctx, "u2", workspace.ID, "c@example.com", model.WorkspaceRoleViewer)
+	assert.ErrorIs(t, err, appErr.ErrForbidden)
+	_, err = svc.Rename(ctx, "u2", workspace.ID, "Other")
+	assert.ErrorIs(t, err, appErr.ErrForbidden)
+	_, err = svc.AddMember(ctx, "u1", workspace.ID, "b@example.com", model.WorkspaceRoleViewer)
+	assert.ErrorIs(t, err, appErr.ErrConflict)
+	_, err = svc.AddMember(ctx, "u1", workspace.ID, "b@example.com", "admin")
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+	_, err = svc.AddMember(ctx, "u1", "u1", "b@example.com", model.WorkspaceRoleViewer)
+	assert.ErrorIs(t, err, appErr.ErrInvalid)
+
+	require.NoError(t, svc.RemoveMember(ctx, "u2", workspace.ID, "u2"))
+	_, err = svc.Authorize(ctx, "u2", workspace.ID)
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
+
+func TestWorkspaceService_KeepsLastOwner(t *testing.T) {
+	svc, _, workspace := newWorkspaceSvc(t)
+	ctx := context.Background()
+
+	_, err := svc.UpdateMemberRole(ctx, "u1", workspace.ID, "u1", model.WorkspaceRoleEditor)
+	assert.ErrorIs(t, err, appErr.ErrConflict)
+	assert.ErrorIs(t, svc.RemoveMember(ctx, "u1", workspace.ID, "u1"), appErr.ErrConflict)
+
+	_, err = svc.AddMember(ctx, "u1", workspace.ID, "b@example.com", model.WorkspaceRoleOwner)
+	require.NoError(t, err)
+	member, err := svc.UpdateMemberRole(ctx, "u2", workspace.ID, "u1", model.WorkspaceRoleEditor)
+	require.NoError(t, err)
+	assert.Equal(t, model.WorkspaceRoleEditor, member.Role)
+}
+
+func TestWorkspaceService_DeleteRequiresEmptyWorkspace(t *testing.T) {
+	svc, repo, workspace := newWorkspaceSvc(t)
+	ctx := context.Background()
+
+	repo.content[workspace.ID] = true
+	assert.ErrorIs(t, svc.Delete(ctx, "u1", workspace.ID), appErr.ErrConflict)
+
+	repo.content[workspace.ID] = false
+	require.NoError(t, svc.Delete(ctx, "u1", workspace.ID))
+	_, err := svc.Authorize(ctx, "u1", workspace.ID)
+	assert.ErrorIs(t, err, appErr.ErrNotFound)
+}
+
+func TestRequireWorkspaceWrite(t *testing.T) {
+	ctx := context.Background()
+	require.NoError(t, requireWorkspaceWrite(ctx))
+	assert.Equal(t, "u1", actorID(ctx, "u1"))
+
+	editor := WithWorkspaceAccess(ctx, WorkspaceAccess{WorkspaceID: "w1", UserID: "u2", Role: model.WorkspaceRoleEditor})
+	require.NoError(t, requireWorkspaceWrite(editor))
+	assert.Equal(t, "u2", actorID(editor, "w1"))
+
+	viewer := WithWorkspaceAccess(ctx, WorkspaceAccess{WorkspaceID: "w1", UserID: "u3", Role: model.WorkspaceRoleViewer})
+	assert.ErrorIs(t, requireWorkspaceWrite(viewer), appErr.ErrForbidden)
+
+	svc := newDocSvc(nil, nil, nil, nil)
+	_, err := svc.Create(viewer, "w1", DocumentCreateInput{Title: "t", Content: "c"})
+	assert.ErrorIs(t, err, appErr.ErrForbidden)
+	assert.ErrorIs(t, svc.Delete(viewer, "w1", "d1"), appErr.ErrForbidden)
+}